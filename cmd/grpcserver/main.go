@@ -6,12 +6,26 @@ import (
 	"fmt"
 	"github.com/ortymid/market/config"
 	"github.com/ortymid/market/grpc"
+	"github.com/ortymid/market/market/audit"
+	"github.com/ortymid/market/market/cart"
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/market/media"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/order"
 	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/review"
+	"github.com/ortymid/market/market/seller"
+	"github.com/ortymid/market/market/user"
 	"github.com/ortymid/market/storage/elasticsearch"
+	"github.com/ortymid/market/storage/fs"
+	"github.com/ortymid/market/storage/httpcrud"
+	"github.com/ortymid/market/storage/memory"
 	"github.com/ortymid/market/storage/mongo"
 	"github.com/ortymid/market/storage/postgres"
 	"github.com/ortymid/market/storage/redis"
+	"github.com/ortymid/market/storage/s3"
 	"log"
+	"net/url"
 	"strings"
 	"time"
 
@@ -30,15 +44,64 @@ func run() error {
 		return fmt.Errorf("getting config: %w", err)
 	}
 
-	productStorage, err := getProductStorage(cfg)
+	stores, err := getStorages(cfg)
 	if err != nil {
-		return fmt.Errorf("unable to get product storage: %w", err)
+		return fmt.Errorf("unable to get storages: %w", err)
 	}
 
+	rates, err := getRateProvider(cfg)
+	if err != nil {
+		return fmt.Errorf("unable to get exchange rates: %w", err)
+	}
+
+	blobs, err := getBlobStore(cfg)
+	if err != nil {
+		return fmt.Errorf("unable to get media store: %w", err)
+	}
+
+	categoryService := &category.Service{
+		Storage: stores.categories,
+	}
+
+	productService := &product.Service{
+		Storage:      stores.products,
+		Categories:   categoryService,
+		Audit:        stores.audit,
+		AuditStorage: stores.audit,
+		Auditors:     cfg.Auditors,
+		Admins:       cfg.Admins,
+		Rates:        rates,
+
+		Blobs:        blobs,
+		MaxMediaSize: cfg.MediaMaxSize,
+	}
+	go productService.RunPurge(context.Background(), cfg.PurgeRetention, cfg.PurgeInterval)
+	go productService.RunScheduler(context.Background(), cfg.PublishInterval)
+
+	users := httpcrud.NewUserService(cfg.UserServiceURL)
+
 	grpcServer := grpc.Server{
-		AuthService: &grpc.UserIDAuthService{},
-		ProductService: &product.Service{
-			Storage: productStorage,
+		AuthService:     grpc.NewJWTAuthService(cfg.JWTServiceURL),
+		ProductService:  productService,
+		CategoryService: categoryService,
+		OrderService: &order.Service{
+			Storage:  stores.orders,
+			Products: productService,
+			Stock:    productService.Reservations(),
+			Users:    users,
+		},
+		CartService: &cart.Service{
+			Storage:  stores.carts,
+			Products: productService,
+		},
+		ReviewService: &review.Service{
+			Storage:  stores.reviews,
+			Products: productService,
+			Ratings:  stores.products,
+		},
+		SellerService: &seller.Service{
+			Users:    user.NewCachedService(users, cfg.UserCacheTTL),
+			Products: stores.products,
 		},
 	}
 
@@ -46,9 +109,64 @@ func run() error {
 	return grpcServer.Run(addr)
 }
 
-func getProductStorage(cfg *config.Config) (product.Storage, error) {
+// getRateProvider returns the cached rates of the rates file, it returns nil
+// if there is no file. The file is checked to fail early if it is invalid.
+func getRateProvider(cfg *config.Config) (money.RateProvider, error) {
+	if len(cfg.RatesFile) == 0 {
+		return nil, nil
+	}
+
+	if _, err := money.LoadRates(cfg.RatesFile); err != nil {
+		return nil, err
+	}
+	return money.NewCachedRates(money.NewFileRates(cfg.RatesFile), cfg.RatesTTL), nil
+}
+
+// getBlobStore returns the store of the media url, it returns nil if there is
+// no url.
+func getBlobStore(cfg *config.Config) (media.BlobStore, error) {
+	if len(cfg.MediaURL) == 0 {
+		return nil, nil
+	}
+
+	u, err := url.Parse(cfg.MediaURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "file":
+		return fs.NewBlobStore(u.Path)
+	case "s3":
+		s3cfg, err := s3.ConfigFromURL(cfg.MediaURL)
+		if err != nil {
+			return nil, err
+		}
+		return s3.NewBlobStore(s3cfg)
+	default:
+		return nil, errors.New("unknown store in media url")
+	}
+}
+
+// storages are the storages of all the services. They share a client of the
+// database.
+type storages struct {
+	products   product.Storage
+	categories category.Storage
+	orders     order.Storage
+	carts      cart.Storage
+	reviews    review.Storage
+	audit      audit.Storage
+}
+
+// getStorages returns the storages of the Elasticsearch url if it is set, or
+// of the database url otherwise.
+func getStorages(cfg *config.Config) (*storages, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
 	if len(cfg.ElasticsearchURL) != 0 {
-		return getElasticsearchProductStorage()
+		return getElasticsearchStorages(ctx)
 	}
 
 	if len(cfg.DatabaseURL) == 0 {
@@ -59,58 +177,117 @@ func getProductStorage(cfg *config.Config) (product.Storage, error) {
 
 	switch db {
 	case "redis":
-		return getRedisProductStorage(cfg.DatabaseURL)
+		return getRedisStorages(ctx, cfg.DatabaseURL)
 	case "postgres":
-		return getPostgresProductStorage(cfg.DatabaseURL)
+		return getPostgresStorages(cfg.DatabaseURL)
 	case "mongodb":
-		return getMongoProductStorage(cfg.DatabaseURL)
+		return getMongoStorages(ctx, cfg.DatabaseURL)
+	case "memory":
+		return &storages{
+			products:   memory.NewProductStorage(),
+			categories: memory.NewCategoryStorage(),
+			orders:     memory.NewOrderStorage(),
+			carts:      memory.NewCartStorage(),
+			reviews:    memory.NewReviewStorage(),
+			audit:      memory.NewAuditStorage(),
+		}, nil
 	default:
 		return nil, errors.New("unknown database in database url")
 	}
 }
 
-func getElasticsearchProductStorage() (*elasticsearch.ProductStorage, error) {
+func getElasticsearchStorages(ctx context.Context) (*storages, error) {
 	es, err := elasticsearch.NewDefaultClient()
 	if err != nil {
 		return nil, err
 	}
 
-	return elasticsearch.NewProductStorage(es, "products"), nil
+	products := elasticsearch.NewProductStorage(es, "products")
+	if err := products.CreateMapping(ctx); err != nil {
+		return nil, err
+	}
+
+	return &storages{
+		products:   products,
+		categories: elasticsearch.NewCategoryStorage(es, "categories"),
+		orders:     elasticsearch.NewOrderStorage(es, "orders"),
+		carts:      elasticsearch.NewCartStorage(es, "carts"),
+		reviews:    elasticsearch.NewReviewStorage(es, "reviews"),
+		audit:      elasticsearch.NewAuditStorage(es, "product_history"),
+	}, nil
 }
 
-func getRedisProductStorage(dbURL string) (*redis.ProductStorage, error) {
-	db, err := redis.NewClientFromURL(dbURL)
+func getRedisStorages(ctx context.Context, dbURL string) (*storages, error) {
+	rdb, err := redis.NewClientFromURL(dbURL)
 	if err != nil {
 		return nil, err
 	}
 
-	return redis.NewProductStorage(db, "products"), nil
+	products := redis.NewProductStorage(rdb, "products")
+	if err := products.IndexVisible(ctx); err != nil {
+		return nil, err
+	}
+
+	return &storages{
+		products:   products,
+		categories: redis.NewCategoryStorage(rdb, "categories"),
+		orders:     redis.NewOrderStorage(rdb, "orders"),
+		carts:      redis.NewCartStorage(rdb, "carts"),
+		reviews:    redis.NewReviewStorage(rdb, "reviews"),
+		audit:      redis.NewAuditStorage(rdb, "product_history"),
+	}, nil
 }
 
-func getPostgresProductStorage(dbURL string) (*postgres.ProductStorage, error) {
+func getPostgresStorages(dbURL string) (*storages, error) {
 	db, err := postgres.NewDBFromURL(dbURL)
 	if err != nil {
 		return nil, err
 	}
 
-	return postgres.NewProductStorage(db, "products"), nil
+	return &storages{
+		products:   postgres.NewProductStorage(db, "products"),
+		categories: postgres.NewCategoryStorage(db, "categories"),
+		orders:     postgres.NewOrderStorage(db, "orders"),
+		carts:      postgres.NewCartStorage(db, "carts"),
+		reviews:    postgres.NewReviewStorage(db, "reviews"),
+		audit:      postgres.NewAuditStorage(db, "product_history"),
+	}, nil
 }
 
-func getMongoProductStorage(dbURL string) (*mongo.ProductStorage, error) {
+func getMongoStorages(ctx context.Context, dbURL string) (*storages, error) {
 	client, err := mongo.NewClientFromURL(dbURL)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	err = client.Connect(ctx)
-	if err != nil {
+	if err := client.Connect(ctx); err != nil {
 		return nil, err
 	}
+	db := client.Database("market")
 
-	col := client.Database("market").Collection("products")
+	orders := mongo.NewOrderStorage(db.Collection("orders"))
+	if err := orders.CreateIndexes(ctx); err != nil {
+		return nil, err
+	}
+	carts := mongo.NewCartStorage(db.Collection("carts"))
+	if err := carts.CreateIndexes(ctx); err != nil {
+		return nil, err
+	}
+	reviews := mongo.NewReviewStorage(db.Collection("reviews"))
+	if err := reviews.CreateIndexes(ctx); err != nil {
+		return nil, err
+	}
+	history := mongo.NewAuditStorage(db.Collection("product_history"))
+	if err := history.CreateIndexes(ctx); err != nil {
+		return nil, err
+	}
 
-	return mongo.NewProductStorage(col), nil
+	return &storages{
+		products:   mongo.NewProductStorage(db.Collection("products")),
+		categories: mongo.NewCategoryStorage(db.Collection("categories")),
+		orders:     orders,
+		carts:      carts,
+		reviews:    reviews,
+		audit:      history,
+	}, nil
 }
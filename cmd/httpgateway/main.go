@@ -17,7 +17,7 @@ func main() {
 		log.Fatalf("getting config: %v", err)
 	}
 
-	productService := grpc.NewProductService(&grpc.UserIDAuthService{})
+	productService := grpc.NewProductService(grpc.NewJWTAuthService(cfg.JWTServiceURL))
 
 	grpcAddr := fmt.Sprintf("%s:%d", cfg.GRPCHost, cfg.GRPCPort)
 	err = productService.Connect(context.TODO(), grpcAddr)
@@ -25,9 +25,49 @@ func main() {
 		log.Fatalf("Unable to connect to gRPC product service at %v: %v", grpcAddr, err)
 	}
 
+	categoryService := grpc.NewCategoryService(grpc.NewJWTAuthService(cfg.JWTServiceURL))
+
+	err = categoryService.Connect(context.TODO(), grpcAddr)
+	if err != nil {
+		log.Fatalf("Unable to connect to gRPC category service at %v: %v", grpcAddr, err)
+	}
+
+	orderService := grpc.NewOrderService(grpc.NewJWTAuthService(cfg.JWTServiceURL))
+
+	err = orderService.Connect(context.TODO(), grpcAddr)
+	if err != nil {
+		log.Fatalf("Unable to connect to gRPC order service at %v: %v", grpcAddr, err)
+	}
+
+	cartService := grpc.NewCartService(grpc.NewJWTAuthService(cfg.JWTServiceURL))
+
+	err = cartService.Connect(context.TODO(), grpcAddr)
+	if err != nil {
+		log.Fatalf("Unable to connect to gRPC cart service at %v: %v", grpcAddr, err)
+	}
+
+	reviewService := grpc.NewReviewService(grpc.NewJWTAuthService(cfg.JWTServiceURL))
+
+	err = reviewService.Connect(context.TODO(), grpcAddr)
+	if err != nil {
+		log.Fatalf("Unable to connect to gRPC review service at %v: %v", grpcAddr, err)
+	}
+
+	sellerService := grpc.NewSellerService(grpc.NewJWTAuthService(cfg.JWTServiceURL))
+
+	err = sellerService.Connect(context.TODO(), grpcAddr)
+	if err != nil {
+		log.Fatalf("Unable to connect to gRPC seller service at %v: %v", grpcAddr, err)
+	}
+
 	httpServer := http.Server{
-		AuthService:    http.NewJWTAuthService(cfg.JWTServiceURL),
-		ProductService: productService,
+		AuthService:     http.NewJWTAuthService(cfg.JWTServiceURL),
+		ProductService:  productService,
+		CategoryService: categoryService,
+		OrderService:    orderService,
+		CartService:     cartService,
+		ReviewService:   reviewService,
+		SellerService:   sellerService,
 	}
 
 	httpAddr := fmt.Sprintf(":%d", cfg.HTTPPort)
@@ -1,13 +1,25 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"github.com/ortymid/market/config"
 	"github.com/ortymid/market/http"
+	"github.com/ortymid/market/market/cart"
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/order"
 	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/review"
+	"github.com/ortymid/market/market/seller"
+	"github.com/ortymid/market/market/user"
+	"github.com/ortymid/market/storage/fs"
+	"github.com/ortymid/market/storage/httpcrud"
 	"github.com/ortymid/market/storage/postgres"
+	"github.com/ortymid/market/storage/s3"
 	"log"
+	"strings"
 
 	_ "github.com/lib/pq"
 )
@@ -24,13 +36,71 @@ func main() {
 	}
 	log.Printf("Connected to postgres at %v", cfg.DatabaseURL)
 
+	categoryService := &category.Service{
+		Storage: postgres.NewCategoryStorage(db, "categories"),
+	}
+
+	productStorage := postgres.NewProductStorage(db, "products")
+	auditStorage := postgres.NewAuditStorage(db, "product_history")
+
 	productService := &product.Service{
-		Storage: postgres.NewProductStorage(db, "products"),
+		Storage:      productStorage,
+		Categories:   categoryService,
+		Audit:        auditStorage,
+		AuditStorage: auditStorage,
+		Auditors:     cfg.Auditors,
+		Admins:       cfg.Admins,
+	}
+	if len(cfg.RatesFile) > 0 {
+		if _, err := money.LoadRates(cfg.RatesFile); err != nil {
+			log.Fatalf("Unable to load exchange rates: %v", err)
+		}
+		productService.Rates = money.NewCachedRates(money.NewFileRates(cfg.RatesFile), cfg.RatesTTL)
+	}
+	switch {
+	case strings.HasPrefix(cfg.MediaURL, "file://"):
+		productService.Blobs, err = fs.NewBlobStore(strings.TrimPrefix(cfg.MediaURL, "file://"))
+	case strings.HasPrefix(cfg.MediaURL, "s3://"):
+		var s3cfg s3.Config
+		if s3cfg, err = s3.ConfigFromURL(cfg.MediaURL); err == nil {
+			productService.Blobs, err = s3.NewBlobStore(s3cfg)
+		}
+	case len(cfg.MediaURL) > 0:
+		err = fmt.Errorf("unknown store in media url %q", cfg.MediaURL)
+	}
+	if err != nil {
+		log.Fatalf("Unable to get media store: %v", err)
+	}
+	productService.MaxMediaSize = cfg.MediaMaxSize
+	go productService.RunPurge(context.Background(), cfg.PurgeRetention, cfg.PurgeInterval)
+
+	users := httpcrud.NewUserService(cfg.UserServiceURL)
+
+	orderService := &order.Service{
+		Storage:  postgres.NewOrderStorage(db, "orders"),
+		Products: productService,
+		Stock:    productService.Reservations(),
+		Users:    users,
 	}
 
 	httpServer := http.Server{
-		AuthService:    http.NewJWTAuthService(cfg.JWTServiceURL),
-		ProductService: productService,
+		AuthService:     http.NewJWTAuthService(cfg.JWTServiceURL),
+		ProductService:  productService,
+		CategoryService: categoryService,
+		OrderService:    orderService,
+		CartService: &cart.Service{
+			Storage:  postgres.NewCartStorage(db, "carts"),
+			Products: productService,
+		},
+		ReviewService: &review.Service{
+			Storage:  postgres.NewReviewStorage(db, "reviews"),
+			Products: productService,
+			Ratings:  productStorage,
+		},
+		SellerService: &seller.Service{
+			Users:    user.NewCachedService(users, cfg.UserCacheTTL),
+			Products: productStorage,
+		},
 	}
 
 	addr := fmt.Sprintf(":%d", cfg.HTTPPort)
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -14,9 +18,43 @@ type Config struct {
 	GRPCPort int
 
 	JWTServiceURL string
+	// UserServiceURL is the base URL of the users API of the service issuing
+	// the tokens, it is the scheme and the host of JWTServiceURL.
+	UserServiceURL string
+	// UserCacheTTL is how long the names of the sellers are kept before they
+	// are requested again.
+	UserCacheTTL time.Duration
 
 	DatabaseURL      string
 	ElasticsearchURL string
+
+	// Auditors are ids of the users allowed to see the history of any
+	// product.
+	Auditors []string
+	// Admins are ids of the users allowed to list deleted and unpublished
+	// products of any seller.
+	Admins []string
+
+	// PurgeRetention is how long deleted products are kept before they are
+	// purged. Deleted products are checked every PurgeInterval.
+	PurgeRetention time.Duration
+	PurgeInterval  time.Duration
+	// PublishInterval is how often scheduled drafts are checked to be
+	// published.
+	PublishInterval time.Duration
+
+	// RatesFile is the JSON file with exchange rates to show prices in other
+	// currencies, prices are not converted if it is empty. The rates are
+	// reread from the file every RatesTTL.
+	RatesFile string
+	RatesTTL  time.Duration
+
+	// MediaURL is where product media are stored, "file:///path" for a local
+	// directory or "s3://key:secret@host/bucket" for an S3-compatible store.
+	// Media are not supported if it is empty.
+	MediaURL string
+	// MediaMaxSize is the size in bytes uploaded media may have.
+	MediaMaxSize int64
 }
 
 func FromEnv() (*Config, error) {
@@ -38,10 +76,55 @@ func FromEnv() (*Config, error) {
 
 	jwtServiceURL := os.Getenv("MARKET_JWT_SERVICE_URL")
 
+	var userServiceURL string
+	if jwtServiceURL != "" {
+		u, err := url.Parse(jwtServiceURL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing JWT_SERVICE_URL: %w", err)
+		}
+		userServiceURL = (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+	}
+
+	userCacheTTL, err := durationFromEnv("MARKET_USER_CACHE_TTL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
 	databaseURL := os.Getenv("MARKET_DATABASE_URL")
 
 	elasticsearchURL := os.Getenv("ELASTICSEARCH_URL")
 
+	purgeRetention, err := durationFromEnv("MARKET_PURGE_RETENTION", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	purgeInterval, err := durationFromEnv("MARKET_PURGE_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	publishInterval, err := durationFromEnv("MARKET_PUBLISH_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	ratesTTL, err := durationFromEnv("MARKET_RATES_TTL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	var mediaMaxSize int64
+	if s := os.Getenv("MARKET_MEDIA_MAX_SIZE"); s != "" {
+		mediaMaxSize, err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing MEDIA_MAX_SIZE: %w", err)
+		}
+		if mediaMaxSize <= 0 {
+			return nil, errors.New("parsing MEDIA_MAX_SIZE: must be positive")
+		}
+	}
+
 	return &Config{
 		HTTPHost: httpHost,
 		HTTPPort: httpPort,
@@ -49,9 +132,54 @@ func FromEnv() (*Config, error) {
 		GRPCHost: grpcHost,
 		GRPCPort: grpcPort,
 
-		JWTServiceURL: jwtServiceURL,
+		JWTServiceURL:  jwtServiceURL,
+		UserServiceURL: userServiceURL,
+		UserCacheTTL:   userCacheTTL,
 
 		DatabaseURL:      databaseURL,
 		ElasticsearchURL: elasticsearchURL,
+
+		Auditors: idsFromEnv("MARKET_AUDITORS"),
+		Admins:   idsFromEnv("MARKET_ADMINS"),
+
+		PurgeRetention: purgeRetention,
+		PurgeInterval:  purgeInterval,
+
+		PublishInterval: publishInterval,
+
+		RatesFile: os.Getenv("MARKET_RATES_FILE"),
+		RatesTTL:  ratesTTL,
+
+		MediaURL:     os.Getenv("MARKET_MEDIA_URL"),
+		MediaMaxSize: mediaMaxSize,
 	}, nil
 }
+
+// idsFromEnv returns comma-separated ids of the variable.
+func idsFromEnv(name string) []string {
+	var ids []string
+	for _, id := range strings.Split(os.Getenv(name), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// durationFromEnv parses the duration of the variable, e.g. "720h". The
+// default is returned if the variable is empty.
+func durationFromEnv(name string, def time.Duration) (time.Duration, error) {
+	s := os.Getenv(name)
+	if s == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %s: %w", strings.TrimPrefix(name, "MARKET_"), err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("parsing %s: must be positive", strings.TrimPrefix(name, "MARKET_"))
+	}
+	return d, nil
+}
@@ -0,0 +1,57 @@
+package gql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+
+	"github.com/ortymid/market/gql/model"
+	"github.com/ortymid/market/market/cart"
+)
+
+func (r *mutationResolver) AddCartItem(ctx context.Context, input model.NewCartItem) (*model.Cart, error) {
+	req := cart.AddRequest{
+		Product:  input.Product,
+		Quantity: input.Quantity,
+	}
+
+	c, err := r.CartService.AddItem(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return cartToModel(c), nil
+}
+
+func (r *mutationResolver) UpdateCartItem(ctx context.Context, input model.UpdateCartItem) (*model.Cart, error) {
+	req := cart.UpdateRequest{
+		Product:  input.Product,
+		Quantity: input.Quantity,
+	}
+
+	c, err := r.CartService.UpdateItem(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return cartToModel(c), nil
+}
+
+func (r *mutationResolver) RemoveCartItem(ctx context.Context, product string) (*model.Cart, error) {
+	c, err := r.CartService.RemoveItem(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+
+	return cartToModel(c), nil
+}
+
+func (r *queryResolver) Cart(ctx context.Context) (*model.Cart, error) {
+	c, err := r.CartService.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return cartToModel(c), nil
+}
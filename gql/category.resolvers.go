@@ -0,0 +1,78 @@
+package gql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+
+	"github.com/ortymid/market/gql/model"
+	"github.com/ortymid/market/market/category"
+)
+
+func (r *mutationResolver) CreateCategory(ctx context.Context, input model.NewCategory) (*model.Category, error) {
+	req := category.CreateRequest{
+		Slug:   input.Slug,
+		Name:   input.Name,
+		Parent: input.Parent,
+	}
+
+	c, err := r.CategoryService.Create(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoryToModel(c), nil
+}
+
+func (r *mutationResolver) UpdateCategory(ctx context.Context, input model.UpdateCategory) (*model.Category, error) {
+	req := category.UpdateRequest{
+		ID:     input.ID,
+		Slug:   input.Slug,
+		Name:   input.Name,
+		Parent: input.Parent,
+	}
+
+	c, err := r.CategoryService.Update(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoryToModel(c), nil
+}
+
+func (r *mutationResolver) DeleteCategory(ctx context.Context, id string) (*model.Category, error) {
+	c, err := r.CategoryService.Delete(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoryToModel(c), nil
+}
+
+func (r *queryResolver) Categories(ctx context.Context) ([]*model.Category, error) {
+	cs, err := r.CategoryService.Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoriesToModel(cs), nil
+}
+
+func (r *queryResolver) Category(ctx context.Context, id string) (*model.Category, error) {
+	c, err := r.CategoryService.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoryToModel(c), nil
+}
+
+func (r *queryResolver) CategoryDescendants(ctx context.Context, id string) ([]*model.Category, error) {
+	cs, err := r.CategoryService.Descendants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoriesToModel(cs), nil
+}
@@ -0,0 +1,106 @@
+package gql
+
+import (
+	"context"
+	"github.com/ortymid/market/market/seller"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// loaderWait is how long a loader collects the ids of a batch before it is
+// fetched.
+const loaderWait = time.Millisecond
+
+type loaderKey struct{}
+
+// LoaderMiddleware gives every request its own loader of sellers, so that the
+// sellers of all the products in a response are fetched at once.
+func LoaderMiddleware(s seller.Interface, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), loaderKey{}, newSellerLoader(r.Context(), s))
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// sellerLoaderFromContext returns the loader of the request. It panics if the
+// context has none, the handler must be wrapped with LoaderMiddleware.
+func sellerLoaderFromContext(ctx context.Context) *sellerLoader {
+	l, ok := ctx.Value(loaderKey{}).(*sellerLoader)
+	if !ok {
+		panic("gql: no seller loader in context, LoaderMiddleware is missing")
+	}
+	return l
+}
+
+// sellerLoader batches the sellers requested during a short wait into a
+// single seller.Interface.Find call. Batches are fetched with the context of
+// the request, not of the resolver which started them, so that a cancelled
+// resolver does not fail the others. Loaded sellers are kept for the life of
+// the loader.
+type sellerLoader struct {
+	ctx     context.Context
+	service seller.Interface
+
+	mu      sync.Mutex
+	loaded  map[string]*sellerBatch
+	current *sellerBatch
+}
+
+func newSellerLoader(ctx context.Context, s seller.Interface) *sellerLoader {
+	return &sellerLoader{ctx: ctx, service: s, loaded: make(map[string]*sellerBatch)}
+}
+
+type sellerBatch struct {
+	ids     []string
+	sellers map[string]*seller.Seller
+	err     error
+	done    chan struct{}
+}
+
+// Load returns the seller with the id, waiting for its batch to be fetched.
+func (l *sellerLoader) Load(ctx context.Context, id string) (*seller.Seller, error) {
+	l.mu.Lock()
+	b, ok := l.loaded[id]
+	if !ok {
+		if l.current == nil || len(l.current.ids) >= seller.MaxFind {
+			l.current = &sellerBatch{done: make(chan struct{})}
+			go l.fetch(l.current)
+		}
+		b = l.current
+		b.ids = append(b.ids, id)
+		l.loaded[id] = b
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.sellers[id], nil
+}
+
+func (l *sellerLoader) fetch(b *sellerBatch) {
+	time.Sleep(loaderWait)
+
+	l.mu.Lock()
+	if l.current == b {
+		l.current = nil
+	}
+	ids := b.ids
+	l.mu.Unlock()
+
+	sellers, err := l.service.Find(l.ctx, ids)
+	if err == nil {
+		b.sellers = make(map[string]*seller.Seller, len(sellers))
+		for _, s := range sellers {
+			b.sellers[s.ID] = s
+		}
+	}
+	b.err = err
+	close(b.done)
+}
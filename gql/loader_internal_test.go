@@ -0,0 +1,52 @@
+package gql
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/ortymid/market/market/seller"
+	"github.com/ortymid/market/mock"
+	"reflect"
+	"testing"
+)
+
+func TestSellerLoader_LoadCancelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The batch is fetched with the context of the request, even though the
+	// resolver which started it is cancelled.
+	ss := mock.NewSellerService(ctrl)
+	ss.EXPECT().Find(gomock.Any(), []string{"1"}).DoAndReturn(func(ctx context.Context, ids []string) ([]*seller.Seller, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return []*seller.Seller{{ID: "1", Name: "Seller 1"}}, nil
+	})
+
+	l := newSellerLoader(context.Background(), ss)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Load(cancelled, "1"); err != context.Canceled {
+		t.Fatalf("Load() of cancelled error = %v, want %v", err, context.Canceled)
+	}
+
+	got, err := l.Load(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := &seller.Seller{ID: "1", Name: "Seller 1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() got = %v, want %v", got, want)
+	}
+}
+
+func TestSellerLoaderFromContext(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("sellerLoaderFromContext() did not panic without LoaderMiddleware")
+		}
+	}()
+
+	sellerLoaderFromContext(context.Background())
+}
@@ -0,0 +1,86 @@
+package gql_test
+
+import (
+	"encoding/json"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/golang/mock/gomock"
+	"github.com/ortymid/market/gql"
+	"github.com/ortymid/market/gql/gen"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/seller"
+	"github.com/ortymid/market/mock"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoaderMiddleware(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ps := mock.NewProductService(ctrl)
+	ps.EXPECT().Find(gomock.Any(), gomock.Any()).Return(&product.FindResult{Products: []*product.Product{
+		{ID: "1", Name: "Banana", Price: money.New(100, "USD"), Seller: "1"},
+		{ID: "2", Name: "Carrot", Price: money.New(100, "USD"), Seller: "2"},
+		{ID: "3", Name: "Date", Price: money.New(100, "USD"), Seller: "1"},
+	}}, nil)
+
+	// The sellers of all the products are found at once.
+	ss := mock.NewSellerService(ctrl)
+	ss.EXPECT().Find(gomock.Any(), gomock.Any()).DoAndReturn(func(_ interface{}, ids []string) ([]*seller.Seller, error) {
+		sellers := make([]*seller.Seller, len(ids))
+		for i, id := range ids {
+			sellers[i] = &seller.Seller{ID: id, Name: "Seller " + id, Products: 1}
+		}
+		return sellers, nil
+	})
+
+	srv := handler.NewDefaultServer(gen.NewExecutableSchema(gen.Config{Resolvers: &gql.Resolver{
+		ProductService: ps,
+		SellerService:  ss,
+	}}))
+	h := gql.LoaderMiddleware(ss, srv)
+
+	body := `{"query": "{ products(offset: 0, limit: 3) { id seller { id name productCount } } }"}`
+	req := httptest.NewRequest(http.MethodPost, "/gql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	type sellerData struct {
+		ID           string
+		Name         string
+		ProductCount int64
+	}
+	var got struct {
+		Data struct {
+			Products []struct {
+				ID     string
+				Seller sellerData
+			}
+		}
+		Errors []interface{}
+	}
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(got.Errors) != 0 {
+		t.Fatalf("errors = %v", got.Errors)
+	}
+
+	want := []sellerData{
+		{ID: "1", Name: "Seller 1", ProductCount: 1},
+		{ID: "2", Name: "Seller 2", ProductCount: 1},
+		{ID: "1", Name: "Seller 1", ProductCount: 1},
+	}
+	var sellers []sellerData
+	for _, p := range got.Data.Products {
+		sellers = append(sellers, p.Seller)
+	}
+	if !reflect.DeepEqual(sellers, want) {
+		t.Errorf("sellers = %v, want %v", sellers, want)
+	}
+}
@@ -0,0 +1,184 @@
+package gql
+
+import (
+	"github.com/ortymid/market/gql/model"
+	"github.com/ortymid/market/market/audit"
+	"github.com/ortymid/market/market/cart"
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/market/media"
+	"github.com/ortymid/market/market/order"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/review"
+	"net/url"
+	"time"
+)
+
+func productToModel(p *product.Product) *model.Product {
+	m := &model.Product{
+		ID:         p.ID,
+		Name:       p.Name,
+		Price:      moneyToModel(p.Price),
+		Seller:     &model.Seller{ID: p.Seller},
+		Categories: p.Categories,
+		Media:      mediaToModel(p.ID, p.Media),
+		Attributes: attributesToModel(p.Attributes),
+		Variants:   variantsToModel(p.Variants),
+		Rating:     &model.Rating{Average: p.Rating.Average, Count: p.Rating.Count},
+
+		Stock:             p.Stock,
+		Reserved:          p.Reserved,
+		LowStockThreshold: p.LowStockThreshold,
+		LowStock:          p.LowStock(),
+
+		Status:  statusesToModel[p.Status],
+		Version: p.Version,
+	}
+	if p.PublishAt != nil {
+		publishAt := p.PublishAt.Format(time.RFC3339Nano)
+		m.PublishAt = &publishAt
+	}
+	if p.DeletedAt != nil {
+		deletedAt := p.DeletedAt.Format(time.RFC3339Nano)
+		m.DeletedAt = &deletedAt
+	}
+	if p.DisplayPrice != nil {
+		m.DisplayPrice = moneyToModel(*p.DisplayPrice)
+	}
+	return m
+}
+
+// mediaToModel returns the media of the product with their paths in the REST
+// API.
+func mediaToModel(productID string, items []media.Media) []*model.Media {
+	ms := make([]*model.Media, len(items))
+	for i, m := range items {
+		path := "/products/" + url.PathEscape(productID) + "/media/" + url.PathEscape(m.ID)
+		ms[i] = &model.Media{
+			ID:           m.ID,
+			ContentType:  m.ContentType,
+			Size:         m.Size,
+			Width:        int64(m.Width),
+			Height:       int64(m.Height),
+			URL:          path,
+			ThumbnailURL: path + "/thumbnail",
+		}
+	}
+	return ms
+}
+
+func entryToModel(e *audit.Entry) *model.AuditEntry {
+	m := &model.AuditEntry{
+		ID:        e.ID,
+		Product:   e.Product,
+		Seller:    e.Seller,
+		Actor:     e.Actor,
+		Operation: string(e.Operation),
+		Version:   e.Version,
+		Changes:   make([]*model.AuditChange, len(e.Changes)),
+		CreatedAt: e.CreatedAt.Format(time.RFC3339Nano),
+	}
+	for i, c := range e.Changes {
+		m.Changes[i] = &model.AuditChange{Field: c.Field, Before: c.Before, After: c.After}
+	}
+	return m
+}
+
+func entriesToModel(es []*audit.Entry) []*model.AuditEntry {
+	ms := make([]*model.AuditEntry, len(es))
+	for i, e := range es {
+		ms[i] = entryToModel(e)
+	}
+	return ms
+}
+
+func categoryToModel(c *category.Category) *model.Category {
+	return &model.Category{
+		ID:     c.ID,
+		Slug:   c.Slug,
+		Name:   c.Name,
+		Parent: c.Parent,
+	}
+}
+
+func categoriesToModel(cs []*category.Category) []*model.Category {
+	ms := make([]*model.Category, len(cs))
+	for i, c := range cs {
+		ms[i] = categoryToModel(c)
+	}
+	return ms
+}
+
+func orderToModel(o *order.Order) *model.Order {
+	m := &model.Order{
+		ID:             o.ID,
+		Product:        o.Product,
+		Buyer:          o.Buyer,
+		Seller:         o.Seller,
+		Quantity:       o.Quantity,
+		Price:          o.Price,
+		Total:          o.Total,
+		Status:         string(o.Status),
+		IdempotencyKey: o.IdempotencyKey,
+		CreatedAt:      o.CreatedAt.Format(time.RFC3339Nano),
+	}
+	if o.Variant != "" {
+		variant := o.Variant
+		m.Variant = &variant
+	}
+	return m
+}
+
+func reviewToModel(rv *review.Review) *model.Review {
+	return &model.Review{
+		ID:        rv.ID,
+		Product:   rv.Product,
+		Author:    rv.Author,
+		Rating:    rv.Rating,
+		Text:      rv.Text,
+		CreatedAt: rv.CreatedAt.Format(time.RFC3339Nano),
+		UpdatedAt: rv.UpdatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+func reviewsToModel(rvs []*review.Review) []*model.Review {
+	ms := make([]*model.Review, len(rvs))
+	for i, rv := range rvs {
+		ms[i] = reviewToModel(rv)
+	}
+	return ms
+}
+
+func ordersToModel(os []*order.Order) []*model.Order {
+	ms := make([]*model.Order, len(os))
+	for i, o := range os {
+		ms[i] = orderToModel(o)
+	}
+	return ms
+}
+
+func cartToModel(c *cart.Summary) *model.Cart {
+	m := &model.Cart{
+		User:  c.User,
+		Items: make([]*model.CartItem, len(c.Lines)),
+		Total: c.Total,
+	}
+	if c.ExpiresAt != nil {
+		expiresAt := c.ExpiresAt.Format(time.RFC3339Nano)
+		m.ExpiresAt = &expiresAt
+	}
+
+	for i, l := range c.Lines {
+		m.Items[i] = &model.CartItem{
+			Product:      l.Product,
+			Quantity:     l.Quantity,
+			Price:        l.Price,
+			AddedAt:      l.AddedAt.Format(time.RFC3339Nano),
+			CurrentPrice: l.CurrentPrice,
+			Subtotal:     l.Subtotal,
+			Deleted:      l.Deleted,
+			Repriced:     l.Repriced,
+		}
+	}
+
+	return m
+}
@@ -2,20 +2,383 @@
 
 package model
 
-type NewProduct struct {
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type Attribute struct {
+	Name string        `json:"name"`
+	Type AttributeType `json:"type"`
+}
+
+type AttributeInput struct {
+	Name string        `json:"name"`
+	Type AttributeType `json:"type"`
+}
+
+type AttributeValue struct {
 	Name  string `json:"name"`
-	Price int64  `json:"price"`
+	Value string `json:"value"`
+}
+
+type AttributeValueInput struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type AuditChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type AuditEntry struct {
+	ID        string         `json:"id"`
+	Product   string         `json:"product"`
+	Seller    string         `json:"seller"`
+	Actor     string         `json:"actor"`
+	Operation string         `json:"operation"`
+	Version   int64          `json:"version"`
+	Changes   []*AuditChange `json:"changes"`
+	CreatedAt string         `json:"createdAt"`
+}
+
+type Cart struct {
+	User      string      `json:"user"`
+	Items     []*CartItem `json:"items"`
+	Total     int64       `json:"total"`
+	ExpiresAt *string     `json:"expiresAt"`
+}
+
+type CartItem struct {
+	Product      string `json:"product"`
+	Quantity     int64  `json:"quantity"`
+	Price        int64  `json:"price"`
+	AddedAt      string `json:"addedAt"`
+	CurrentPrice int64  `json:"currentPrice"`
+	Subtotal     int64  `json:"subtotal"`
+	Deleted      bool   `json:"deleted"`
+	Repriced     bool   `json:"repriced"`
+}
+
+type Category struct {
+	ID     string  `json:"id"`
+	Slug   string  `json:"slug"`
+	Name   string  `json:"name"`
+	Parent *string `json:"parent"`
+}
+
+type Media struct {
+	ID           string `json:"id"`
+	ContentType  string `json:"contentType"`
+	Size         int64  `json:"size"`
+	Width        int64  `json:"width"`
+	Height       int64  `json:"height"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnailUrl"`
+}
+
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type MoneyInput struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type NewCartItem struct {
+	Product  string `json:"product"`
+	Quantity int64  `json:"quantity"`
+}
+
+type NewCategory struct {
+	Slug   string  `json:"slug"`
+	Name   string  `json:"name"`
+	Parent *string `json:"parent"`
+}
+
+type NewProduct struct {
+	Name              string            `json:"name"`
+	Price             *MoneyInput       `json:"price"`
+	Categories        []string          `json:"categories"`
+	Stock             *int64            `json:"stock"`
+	LowStockThreshold *int64            `json:"lowStockThreshold"`
+	Status            *ProductStatus    `json:"status"`
+	PublishAt         *string           `json:"publishAt"`
+	Attributes        []*AttributeInput `json:"attributes"`
+	Variants          []*VariantInput   `json:"variants"`
+}
+
+type NewPurchase struct {
+	Product        string  `json:"product"`
+	Variant        *string `json:"variant"`
+	Quantity       int64   `json:"quantity"`
+	IdempotencyKey string  `json:"idempotencyKey"`
+}
+
+type NewReview struct {
+	Product string  `json:"product"`
+	Rating  int64   `json:"rating"`
+	Text    *string `json:"text"`
+}
+
+type Order struct {
+	ID             string  `json:"id"`
+	Product        string  `json:"product"`
+	Variant        *string `json:"variant"`
+	Buyer          string  `json:"buyer"`
+	Seller         string  `json:"seller"`
+	Quantity       int64   `json:"quantity"`
+	Price          int64   `json:"price"`
+	Total          int64   `json:"total"`
+	Status         string  `json:"status"`
+	IdempotencyKey string  `json:"idempotencyKey"`
+	CreatedAt      string  `json:"createdAt"`
+}
+
+type PageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor"`
 }
 
 type Product struct {
-	ID     string `json:"id"`
-	Name   string `json:"name"`
-	Price  int64  `json:"price"`
-	Seller string `json:"seller"`
+	ID                string        `json:"id"`
+	Name              string        `json:"name"`
+	Price             *Money        `json:"price"`
+	Seller            *Seller       `json:"seller"`
+	Categories        []string      `json:"categories"`
+	Stock             int64         `json:"stock"`
+	Reserved          int64         `json:"reserved"`
+	LowStockThreshold int64         `json:"lowStockThreshold"`
+	LowStock          bool          `json:"lowStock"`
+	Version           int64         `json:"version"`
+	DeletedAt         *string       `json:"deletedAt"`
+	Status            ProductStatus `json:"status"`
+	PublishAt         *string       `json:"publishAt"`
+	DisplayPrice      *Money        `json:"displayPrice"`
+	Media             []*Media      `json:"media"`
+	Attributes        []*Attribute  `json:"attributes"`
+	Variants          []*Variant    `json:"variants"`
+	Rating            *Rating       `json:"rating"`
+	Reviews           []*Review     `json:"reviews"`
+}
+
+type ProductConnection struct {
+	Edges      []*ProductEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	TotalCount int64          `json:"totalCount"`
+}
+
+type ProductEdge struct {
+	Node *Product `json:"node"`
+}
+
+type Rating struct {
+	Average float64 `json:"average"`
+	Count   int64   `json:"count"`
+}
+
+type Review struct {
+	ID        string `json:"id"`
+	Product   string `json:"product"`
+	Author    string `json:"author"`
+	Rating    int64  `json:"rating"`
+	Text      string `json:"text"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+type Seller struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Rating       *Rating    `json:"rating"`
+	ProductCount int64      `json:"productCount"`
+	Products     []*Product `json:"products"`
+}
+
+type Sort struct {
+	Key  SortKey `json:"key"`
+	Desc *bool   `json:"desc"`
+}
+
+type UpdateCartItem struct {
+	Product  string `json:"product"`
+	Quantity int64  `json:"quantity"`
+}
+
+type UpdateCategory struct {
+	ID     string  `json:"id"`
+	Slug   *string `json:"slug"`
+	Name   *string `json:"name"`
+	Parent *string `json:"parent"`
 }
 
 type UpdateProduct struct {
-	ID    string  `json:"id"`
-	Name  *string `json:"name"`
-	Price *int64  `json:"price"`
+	ID                string            `json:"id"`
+	Name              *string           `json:"name"`
+	Price             *MoneyInput       `json:"price"`
+	Categories        []string          `json:"categories"`
+	LowStockThreshold *int64            `json:"lowStockThreshold"`
+	Status            *ProductStatus    `json:"status"`
+	PublishAt         *string           `json:"publishAt"`
+	Attributes        []*AttributeInput `json:"attributes"`
+	Variants          []*VariantInput   `json:"variants"`
+}
+
+type UpdateReview struct {
+	Product string  `json:"product"`
+	ID      string  `json:"id"`
+	Rating  *int64  `json:"rating"`
+	Text    *string `json:"text"`
+}
+
+type Variant struct {
+	Sku        string            `json:"sku"`
+	Attributes []*AttributeValue `json:"attributes"`
+	Price      *Money            `json:"price"`
+	Stock      int64             `json:"stock"`
+}
+
+type VariantInput struct {
+	Sku        string                 `json:"sku"`
+	Attributes []*AttributeValueInput `json:"attributes"`
+	Price      *MoneyInput            `json:"price"`
+	Stock      *int64                 `json:"stock"`
+}
+
+type AttributeType string
+
+const (
+	AttributeTypeString AttributeType = "STRING"
+	AttributeTypeNumber AttributeType = "NUMBER"
+	AttributeTypeBool   AttributeType = "BOOL"
+)
+
+var AllAttributeType = []AttributeType{
+	AttributeTypeString,
+	AttributeTypeNumber,
+	AttributeTypeBool,
+}
+
+func (e AttributeType) IsValid() bool {
+	switch e {
+	case AttributeTypeString, AttributeTypeNumber, AttributeTypeBool:
+		return true
+	}
+	return false
+}
+
+func (e AttributeType) String() string {
+	return string(e)
+}
+
+func (e *AttributeType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AttributeType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AttributeType", str)
+	}
+	return nil
+}
+
+func (e AttributeType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ProductStatus string
+
+const (
+	ProductStatusDraft     ProductStatus = "DRAFT"
+	ProductStatusPublished ProductStatus = "PUBLISHED"
+	ProductStatusArchived  ProductStatus = "ARCHIVED"
+)
+
+var AllProductStatus = []ProductStatus{
+	ProductStatusDraft,
+	ProductStatusPublished,
+	ProductStatusArchived,
+}
+
+func (e ProductStatus) IsValid() bool {
+	switch e {
+	case ProductStatusDraft, ProductStatusPublished, ProductStatusArchived:
+		return true
+	}
+	return false
+}
+
+func (e ProductStatus) String() string {
+	return string(e)
+}
+
+func (e *ProductStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ProductStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ProductStatus", str)
+	}
+	return nil
+}
+
+func (e ProductStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SortKey string
+
+const (
+	SortKeyPrice     SortKey = "PRICE"
+	SortKeyName      SortKey = "NAME"
+	SortKeyCreated   SortKey = "CREATED"
+	SortKeyRelevance SortKey = "RELEVANCE"
+	SortKeyRating    SortKey = "RATING"
+)
+
+var AllSortKey = []SortKey{
+	SortKeyPrice,
+	SortKeyName,
+	SortKeyCreated,
+	SortKeyRelevance,
+	SortKeyRating,
+}
+
+func (e SortKey) IsValid() bool {
+	switch e {
+	case SortKeyPrice, SortKeyName, SortKeyCreated, SortKeyRelevance, SortKeyRating:
+		return true
+	}
+	return false
+}
+
+func (e SortKey) String() string {
+	return string(e)
+}
+
+func (e *SortKey) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SortKey(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SortKey", str)
+	}
+	return nil
+}
+
+func (e SortKey) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
 }
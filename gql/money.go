@@ -0,0 +1,19 @@
+package gql
+
+import (
+	"github.com/ortymid/market/gql/model"
+	"github.com/ortymid/market/market/money"
+)
+
+func moneyToModel(m money.Money) *model.Money {
+	return &model.Money{Amount: m.Amount, Currency: m.Currency}
+}
+
+// moneyFromModel returns the money of the input, it is nil if m is nil.
+func moneyFromModel(m *model.MoneyInput) *money.Money {
+	if m == nil {
+		return nil
+	}
+	v := money.New(m.Amount, m.Currency)
+	return &v
+}
@@ -0,0 +1,54 @@
+package gql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+
+	"github.com/ortymid/market/gql/model"
+	"github.com/ortymid/market/market/order"
+)
+
+func (r *mutationResolver) Purchase(ctx context.Context, input model.NewPurchase) (*model.Order, error) {
+	req := order.PurchaseRequest{
+		Product:        input.Product,
+		Quantity:       input.Quantity,
+		IdempotencyKey: input.IdempotencyKey,
+	}
+	if input.Variant != nil {
+		req.Variant = *input.Variant
+	}
+
+	o, err := r.OrderService.Purchase(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return orderToModel(o), nil
+}
+
+func (r *queryResolver) Orders(ctx context.Context, offset int64, limit int64, buyer *string, seller *string) ([]*model.Order, error) {
+	req := order.FindRequest{
+		Offset: offset,
+		Limit:  limit,
+		Buyer:  buyer,
+		Seller: seller,
+	}
+
+	os, err := r.OrderService.Find(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return ordersToModel(os), nil
+}
+
+func (r *queryResolver) Order(ctx context.Context, id string) (*model.Order, error) {
+	o, err := r.OrderService.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return orderToModel(o), nil
+}
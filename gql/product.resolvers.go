@@ -5,49 +5,79 @@ package gql
 
 import (
 	"context"
-	"github.com/ortymid/market/market/product"
 
 	"github.com/ortymid/market/gql/gen"
 	"github.com/ortymid/market/gql/model"
+	"github.com/ortymid/market/market/audit"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/review"
 )
 
 func (r *mutationResolver) CreateProduct(ctx context.Context, input model.NewProduct) (*model.Product, error) {
 	req := product.CreateRequest{
-		Name:  input.Name,
-		Price: input.Price,
+		Name:       input.Name,
+		Price:      *moneyFromModel(input.Price),
+		Categories: input.Categories,
+		Attributes: attributesFromModel(input.Attributes),
+		Variants:   variantsFromModel(input.Variants),
+	}
+	if input.Stock != nil {
+		req.Stock = *input.Stock
+	}
+	if input.LowStockThreshold != nil {
+		req.LowStockThreshold = *input.LowStockThreshold
 	}
+	if status := statusFromModel(input.Status); status != nil {
+		req.Status = *status
+	}
+	publishAt, err := publishAtFromModel(input.PublishAt)
+	if err != nil {
+		return nil, err
+	}
+	req.PublishAt = publishAt
 
 	p, err := r.ProductService.Create(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.Product{
-		ID:     p.ID,
-		Name:   p.Name,
-		Price:  p.Price,
-		Seller: p.Seller,
-	}, nil
+	return productToModel(p), nil
 }
 
-func (r *mutationResolver) UpdateProduct(ctx context.Context, input model.UpdateProduct) (*model.Product, error) {
+func (r *mutationResolver) UpdateProduct(ctx context.Context, input model.UpdateProduct, expectedVersion *int64) (*model.Product, error) {
 	req := product.UpdateRequest{
 		ID:    input.ID,
 		Name:  input.Name,
-		Price: input.Price,
+		Price: moneyFromModel(input.Price),
+
+		LowStockThreshold: input.LowStockThreshold,
+		ExpectedVersion:   expectedVersion,
+
+		Status: statusFromModel(input.Status),
+	}
+	if input.Categories != nil {
+		req.Categories = &input.Categories
+	}
+	if input.Attributes != nil {
+		attrs := attributesFromModel(input.Attributes)
+		req.Attributes = &attrs
+	}
+	if input.Variants != nil {
+		variants := variantsFromModel(input.Variants)
+		req.Variants = &variants
 	}
+	publishAt, err := publishAtFromModel(input.PublishAt)
+	if err != nil {
+		return nil, err
+	}
+	req.PublishAt = publishAt
 
 	p, err := r.ProductService.Update(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.Product{
-		ID:     p.ID,
-		Name:   p.Name,
-		Price:  p.Price,
-		Seller: p.Seller,
-	}, nil
+	return productToModel(p), nil
 }
 
 func (r *mutationResolver) DeleteProduct(ctx context.Context, id string) (*model.Product, error) {
@@ -56,56 +86,165 @@ func (r *mutationResolver) DeleteProduct(ctx context.Context, id string) (*model
 		return nil, err
 	}
 
-	return &model.Product{
-		ID:     p.ID,
-		Name:   p.Name,
-		Price:  p.Price,
-		Seller: p.Seller,
-	}, nil
+	return productToModel(p), nil
+}
+
+func (r *mutationResolver) RestoreProduct(ctx context.Context, id string) (*model.Product, error) {
+	p, err := r.ProductService.Restore(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return productToModel(p), nil
+}
+
+func (r *mutationResolver) AdjustStock(ctx context.Context, id string, delta int64, variant *string) (*model.Product, error) {
+	var sku string
+	if variant != nil {
+		sku = *variant
+	}
+
+	p, err := r.ProductService.AdjustStock(ctx, id, sku, delta)
+	if err != nil {
+		return nil, err
+	}
+
+	return productToModel(p), nil
+}
+
+func (r *mutationResolver) RemoveProductMedia(ctx context.Context, id string, mediaID string) (*model.Product, error) {
+	p, err := r.ProductService.RemoveMedia(ctx, id, mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	return productToModel(p), nil
+}
+
+func (r *productResolver) Reviews(ctx context.Context, obj *model.Product, offset int64, limit int64) ([]*model.Review, error) {
+	rvs, err := r.ReviewService.Find(ctx, review.FindRequest{Offset: offset, Limit: limit, Product: &obj.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	return reviewsToModel(rvs), nil
 }
 
-func (r *queryResolver) Products(ctx context.Context, offset int64, limit int64) ([]*model.Product, error) {
+func (r *queryResolver) Products(ctx context.Context, offset int64, limit int64, sort []*model.Sort, categories []string, includeDescendants *bool, inStock *bool, includeDeleted *bool, seller *string, status *model.ProductStatus, displayCurrency *string, attributes []*model.AttributeValueInput, minRating *float64) ([]*model.Product, error) {
 	req := product.FindRequest{
-		Offset: offset,
-		Limit:  limit,
+		Offset:     offset,
+		Limit:      limit,
+		Seller:     seller,
+		Categories: categories,
+		Attributes: attributeValuesFromModel(attributes),
+		InStock:    inStock,
+		MinRating:  minRating,
+		Status:     statusFromModel(status),
+		Sort:       sortsFromModel(sort),
+	}
+	if includeDescendants != nil {
+		req.IncludeDescendants = *includeDescendants
+	}
+	if includeDeleted != nil {
+		req.IncludeDeleted = *includeDeleted
+	}
+	if displayCurrency != nil {
+		req.DisplayCurrency = *displayCurrency
 	}
 
-	products, err := r.ProductService.Find(ctx, req)
+	res, err := r.ProductService.Find(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	ps := make([]*model.Product, len(products))
-	for i, p := range products {
-		ps[i] = &model.Product{
-			ID:     p.ID,
-			Name:   p.Name,
-			Price:  p.Price,
-			Seller: p.Seller,
-		}
+	ps := make([]*model.Product, len(res.Products))
+	for i, p := range res.Products {
+		ps[i] = productToModel(p)
 	}
 	return ps, nil
 }
 
-func (r *queryResolver) Product(ctx context.Context, id string) (*model.Product, error) {
-	p, err := r.ProductService.FindOne(ctx, id)
+func (r *queryResolver) ProductsConnection(ctx context.Context, first int64, after *string, sort []*model.Sort, categories []string, includeDescendants *bool, inStock *bool, includeDeleted *bool, seller *string, status *model.ProductStatus, displayCurrency *string, attributes []*model.AttributeValueInput, minRating *float64) (*model.ProductConnection, error) {
+	req := product.FindRequest{
+		Limit:      first,
+		Seller:     seller,
+		Categories: categories,
+		Attributes: attributeValuesFromModel(attributes),
+		InStock:    inStock,
+		MinRating:  minRating,
+		Status:     statusFromModel(status),
+		Sort:       sortsFromModel(sort),
+	}
+	if after != nil {
+		req.Cursor = *after
+	}
+	if includeDescendants != nil {
+		req.IncludeDescendants = *includeDescendants
+	}
+	if includeDeleted != nil {
+		req.IncludeDeleted = *includeDeleted
+	}
+	if displayCurrency != nil {
+		req.DisplayCurrency = *displayCurrency
+	}
+
+	res, err := r.ProductService.Find(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.Product{
-		ID:     p.ID,
-		Name:   p.Name,
-		Price:  p.Price,
-		Seller: p.Seller,
+	edges := make([]*model.ProductEdge, len(res.Products))
+	for i, p := range res.Products {
+		edges[i] = &model.ProductEdge{
+			Node: productToModel(p),
+		}
+	}
+
+	pageInfo := &model.PageInfo{HasNextPage: res.HasMore}
+	if res.NextCursor != "" {
+		pageInfo.EndCursor = &res.NextCursor
+	}
+
+	return &model.ProductConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo,
+		TotalCount: res.Total,
 	}, nil
 }
 
+func (r *queryResolver) Product(ctx context.Context, id string, displayCurrency *string) (*model.Product, error) {
+	var p *product.Product
+	var err error
+	if displayCurrency != nil {
+		p, err = r.ProductService.FindOneIn(ctx, id, *displayCurrency)
+	} else {
+		p, err = r.ProductService.FindOne(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return productToModel(p), nil
+}
+
+func (r *queryResolver) ProductHistory(ctx context.Context, id string, offset int64, limit int64) ([]*model.AuditEntry, error) {
+	es, err := r.ProductService.History(ctx, audit.FindRequest{Product: id, Offset: offset, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+
+	return entriesToModel(es), nil
+}
+
 // Mutation returns gen.MutationResolver implementation.
 func (r *Resolver) Mutation() gen.MutationResolver { return &mutationResolver{r} }
 
+// Product returns gen.ProductResolver implementation.
+func (r *Resolver) Product() gen.ProductResolver { return &productResolver{r} }
+
 // Query returns gen.QueryResolver implementation.
 func (r *Resolver) Query() gen.QueryResolver { return &queryResolver{r} }
 
 type mutationResolver struct{ *Resolver }
+type productResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
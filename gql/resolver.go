@@ -1,11 +1,23 @@
 package gql
 
-import "github.com/ortymid/market/market/product"
+import (
+	"github.com/ortymid/market/market/cart"
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/market/order"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/review"
+	"github.com/ortymid/market/market/seller"
+)
 
 // This file will not be regenerated automatically.
 //
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	ProductService product.Interface
+	ProductService  product.Interface
+	CategoryService category.Interface
+	OrderService    order.Interface
+	CartService     cart.Interface
+	ReviewService   review.Interface
+	SellerService   seller.Interface
 }
@@ -0,0 +1,78 @@
+package gql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+
+	"github.com/ortymid/market/gql/model"
+	"github.com/ortymid/market/market/review"
+)
+
+func (r *mutationResolver) CreateReview(ctx context.Context, input model.NewReview) (*model.Review, error) {
+	req := review.CreateRequest{
+		Product: input.Product,
+		Rating:  input.Rating,
+	}
+	if input.Text != nil {
+		req.Text = *input.Text
+	}
+
+	rv, err := r.ReviewService.Create(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return reviewToModel(rv), nil
+}
+
+func (r *mutationResolver) UpdateReview(ctx context.Context, input model.UpdateReview) (*model.Review, error) {
+	req := review.UpdateRequest{
+		Product: input.Product,
+		ID:      input.ID,
+		Rating:  input.Rating,
+		Text:    input.Text,
+	}
+
+	rv, err := r.ReviewService.Update(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return reviewToModel(rv), nil
+}
+
+func (r *mutationResolver) DeleteReview(ctx context.Context, product string, id string) (*model.Review, error) {
+	rv, err := r.ReviewService.Delete(ctx, product, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return reviewToModel(rv), nil
+}
+
+func (r *queryResolver) Reviews(ctx context.Context, offset int64, limit int64, product *string, author *string) ([]*model.Review, error) {
+	req := review.FindRequest{
+		Offset:  offset,
+		Limit:   limit,
+		Product: product,
+		Author:  author,
+	}
+
+	rvs, err := r.ReviewService.Find(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return reviewsToModel(rvs), nil
+}
+
+func (r *queryResolver) Review(ctx context.Context, product string, id string) (*model.Review, error) {
+	rv, err := r.ReviewService.FindOne(ctx, product, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return reviewToModel(rv), nil
+}
@@ -0,0 +1,66 @@
+package gql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+
+	"github.com/ortymid/market/gql/gen"
+	"github.com/ortymid/market/gql/model"
+	"github.com/ortymid/market/market/product"
+)
+
+func (r *queryResolver) Seller(ctx context.Context, id string) (*model.Seller, error) {
+	return &model.Seller{ID: id}, nil
+}
+
+func (r *sellerResolver) Name(ctx context.Context, obj *model.Seller) (string, error) {
+	s, err := sellerLoaderFromContext(ctx).Load(ctx, obj.ID)
+	if err != nil {
+		return "", err
+	}
+
+	return s.Name, nil
+}
+
+func (r *sellerResolver) Rating(ctx context.Context, obj *model.Seller) (*model.Rating, error) {
+	s, err := sellerLoaderFromContext(ctx).Load(ctx, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Rating{Average: s.Rating.Average, Count: s.Rating.Count}, nil
+}
+
+func (r *sellerResolver) ProductCount(ctx context.Context, obj *model.Seller) (int64, error) {
+	s, err := sellerLoaderFromContext(ctx).Load(ctx, obj.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.Products, nil
+}
+
+func (r *sellerResolver) Products(ctx context.Context, obj *model.Seller, offset int64, limit int64, sort []*model.Sort) ([]*model.Product, error) {
+	res, err := r.ProductService.Find(ctx, product.FindRequest{
+		Offset: offset,
+		Limit:  limit,
+		Seller: &obj.ID,
+		Sort:   sortsFromModel(sort),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ps := make([]*model.Product, len(res.Products))
+	for i, p := range res.Products {
+		ps[i] = productToModel(p)
+	}
+	return ps, nil
+}
+
+// Seller returns gen.SellerResolver implementation.
+func (r *Resolver) Seller() gen.SellerResolver { return &sellerResolver{r} }
+
+type sellerResolver struct{ *Resolver }
@@ -0,0 +1,29 @@
+package gql
+
+import (
+	"github.com/ortymid/market/gql/model"
+	"github.com/ortymid/market/market/product"
+)
+
+var sortKeysFromModel = map[model.SortKey]product.SortKey{
+	model.SortKeyPrice:     product.SortKeyPrice,
+	model.SortKeyName:      product.SortKeyName,
+	model.SortKeyCreated:   product.SortKeyCreated,
+	model.SortKeyRelevance: product.SortKeyRelevance,
+	model.SortKeyRating:    product.SortKeyRating,
+}
+
+func sortsFromModel(ms []*model.Sort) []product.Sort {
+	if len(ms) == 0 {
+		return nil
+	}
+
+	sorts := make([]product.Sort, len(ms))
+	for i, m := range ms {
+		sorts[i] = product.Sort{Key: sortKeysFromModel[m.Key]}
+		if m.Desc != nil {
+			sorts[i].Desc = *m.Desc
+		}
+	}
+	return sorts
+}
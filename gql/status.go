@@ -0,0 +1,40 @@
+package gql
+
+import (
+	"github.com/ortymid/market/gql/model"
+	"github.com/ortymid/market/market/product"
+	"time"
+)
+
+var statusesFromModel = map[model.ProductStatus]product.Status{
+	model.ProductStatusDraft:     product.StatusDraft,
+	model.ProductStatusPublished: product.StatusPublished,
+	model.ProductStatusArchived:  product.StatusArchived,
+}
+
+var statusesToModel = map[product.Status]model.ProductStatus{
+	product.StatusDraft:     model.ProductStatusDraft,
+	product.StatusPublished: model.ProductStatusPublished,
+	product.StatusArchived:  model.ProductStatusArchived,
+}
+
+// statusFromModel returns the status, it is nil if m is nil.
+func statusFromModel(m *model.ProductStatus) *product.Status {
+	if m == nil {
+		return nil
+	}
+	s := statusesFromModel[*m]
+	return &s
+}
+
+// publishAtFromModel parses the RFC 3339 publish time, it is nil if s is nil.
+func publishAtFromModel(s *string) (*time.Time, error) {
+	if s == nil {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, *s)
+	if err != nil {
+		return nil, product.ErrValidation{Resource: product.Resource, Field: "publish_at", Reason: "must be RFC 3339 time"}
+	}
+	return &t, nil
+}
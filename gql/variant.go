@@ -0,0 +1,94 @@
+package gql
+
+import (
+	"github.com/ortymid/market/gql/model"
+	"github.com/ortymid/market/market/product"
+	"sort"
+)
+
+var attributeTypesFromModel = map[model.AttributeType]product.AttributeType{
+	model.AttributeTypeString: product.AttributeString,
+	model.AttributeTypeNumber: product.AttributeNumber,
+	model.AttributeTypeBool:   product.AttributeBool,
+}
+
+var attributeTypesToModel = map[product.AttributeType]model.AttributeType{
+	product.AttributeString: model.AttributeTypeString,
+	product.AttributeNumber: model.AttributeTypeNumber,
+	product.AttributeBool:   model.AttributeTypeBool,
+}
+
+func attributesToModel(attrs []product.Attribute) []*model.Attribute {
+	ms := make([]*model.Attribute, len(attrs))
+	for i, a := range attrs {
+		ms[i] = &model.Attribute{Name: a.Name, Type: attributeTypesToModel[a.Type]}
+	}
+	return ms
+}
+
+// attributesFromModel returns the attributes, it is nil if ms is nil.
+func attributesFromModel(ms []*model.AttributeInput) []product.Attribute {
+	if ms == nil {
+		return nil
+	}
+	attrs := make([]product.Attribute, len(ms))
+	for i, m := range ms {
+		attrs[i] = product.Attribute{Name: m.Name, Type: attributeTypesFromModel[m.Type]}
+	}
+	return attrs
+}
+
+func variantsToModel(variants []product.Variant) []*model.Variant {
+	ms := make([]*model.Variant, len(variants))
+	for i, v := range variants {
+		ms[i] = &model.Variant{
+			Sku:        v.SKU,
+			Attributes: attributeValuesToModel(v.Attributes),
+			Price:      moneyToModel(v.Price),
+			Stock:      v.Stock,
+		}
+	}
+	return ms
+}
+
+// variantsFromModel returns the variants, it is nil if ms is nil.
+func variantsFromModel(ms []*model.VariantInput) []product.Variant {
+	if ms == nil {
+		return nil
+	}
+	variants := make([]product.Variant, len(ms))
+	for i, m := range ms {
+		variants[i] = product.Variant{
+			SKU:        m.Sku,
+			Attributes: attributeValuesFromModel(m.Attributes),
+			Price:      *moneyFromModel(m.Price),
+		}
+		if m.Stock != nil {
+			variants[i].Stock = *m.Stock
+		}
+	}
+	return variants
+}
+
+// attributeValuesToModel returns the values sorted by name.
+func attributeValuesToModel(values map[string]string) []*model.AttributeValue {
+	ms := make([]*model.AttributeValue, 0, len(values))
+	for name, value := range values {
+		ms = append(ms, &model.AttributeValue{Name: name, Value: value})
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Name < ms[j].Name })
+	return ms
+}
+
+// attributeValuesFromModel returns the values by name, it is nil if there are
+// no values.
+func attributeValuesFromModel(ms []*model.AttributeValueInput) map[string]string {
+	if len(ms) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(ms))
+	for _, m := range ms {
+		values[m.Name] = m.Value
+	}
+	return values
+}
@@ -2,9 +2,12 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"github.com/ortymid/market/jwt"
+	"github.com/ortymid/market/market/auth"
 	"github.com/ortymid/market/market/user"
 	"google.golang.org/grpc/metadata"
+	"strings"
 )
 
 //go:generate mockgen -destination=../mock/grpc_auth_service.go -package mock -mock_names=AuthService=GRPCAuthService . AuthService
@@ -14,6 +17,8 @@ type AuthService interface {
 	MetadataWithAuthorization(ctx context.Context, u *user.User) (metadata.MD, error)
 }
 
+// JWTAuthService forwards the caller's JWT in the authorization metadata
+// and validates it on the server side.
 type JWTAuthService struct {
 	jwtService jwt.Service
 }
@@ -29,33 +34,35 @@ func (s *JWTAuthService) Authorize(ctx context.Context, md metadata.MD) (*user.U
 		return nil, nil
 	}
 
-	return s.jwtService.Authorize(ctx, values[0])
-}
-
-func (s *JWTAuthService) MetadataWithAuthorization(ctx context.Context, u *user.User) (metadata.MD, error) {
-	panic("not implemented")
-}
-
-type UserIDAuthService struct{}
+	authFields := strings.Fields(values[0])
+	if len(authFields) != 2 {
+		return nil, errors.New("malformed authorization metadata")
+	}
 
-func (s *UserIDAuthService) Authorize(ctx context.Context, md metadata.MD) (*user.User, error) {
-	values := md.Get("authorization")
-	if values == nil || len(values) == 0 {
-		// Anonymous call.
-		return nil, nil
+	typ := authFields[0]
+	if !strings.EqualFold(typ, "Bearer") {
+		return nil, errors.New("authorization type is not Bearer")
 	}
 
-	return &user.User{ID: values[0]}, nil
+	token := authFields[1]
+	return s.jwtService.Authorize(ctx, token)
 }
 
-func (s *UserIDAuthService) MetadataWithAuthorization(ctx context.Context, u *user.User) (metadata.MD, error) {
-	if u == nil {
+// MetadataWithAuthorization makes the authorization metadata from the token
+// stored in the context by auth.NewContextWithToken. The user is not used,
+// as it cannot be verified by the server without the token.
+func (s *JWTAuthService) MetadataWithAuthorization(ctx context.Context, u *user.User) (metadata.MD, error) {
+	token, ok := auth.TokenFromContext(ctx).(string)
+	if !ok || len(token) == 0 {
+		if u != nil {
+			return nil, errors.New("token not provided")
+		}
 		// Anonymous call.
 		return metadata.New(map[string]string{}), nil
 	}
 
 	md := metadata.New(map[string]string{
-		"authorization": u.ID,
+		"authorization": "Bearer " + token,
 	})
 	return md, nil
 }
@@ -0,0 +1,99 @@
+package grpc
+
+import (
+	"context"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/user"
+	"google.golang.org/grpc/metadata"
+	"reflect"
+	"testing"
+)
+
+func TestJWTAuthService_MetadataWithAuthorization(t *testing.T) {
+	type args struct {
+		ctx context.Context
+		u   *user.User
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    metadata.MD
+		wantErr bool
+	}{
+		{
+			name: "Should forward token",
+			args: args{
+				ctx: auth.NewContextWithToken(context.Background(), "token"),
+				u:   &user.User{ID: "1"},
+			},
+			want: metadata.Pairs("authorization", "Bearer token"),
+		},
+		{
+			name: "Should make empty metadata for anonymous call",
+			args: args{
+				ctx: context.Background(),
+				u:   nil,
+			},
+			want: metadata.MD{},
+		},
+		{
+			name: "Should error when user is provided without token",
+			args: args{
+				ctx: context.Background(),
+				u:   &user.User{ID: "1"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewJWTAuthService("")
+			got, err := s.MetadataWithAuthorization(tt.args.ctx, tt.args.u)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MetadataWithAuthorization() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MetadataWithAuthorization() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWTAuthService_Authorize(t *testing.T) {
+	tests := []struct {
+		name    string
+		md      metadata.MD
+		want    *user.User
+		wantErr bool
+	}{
+		{
+			name: "Should authorize anonymous call",
+			md:   metadata.MD{},
+			want: nil,
+		},
+		{
+			name:    "Should error when authorization is malformed",
+			md:      metadata.Pairs("authorization", "1"),
+			wantErr: true,
+		},
+		{
+			name:    "Should error when authorization type is not Bearer",
+			md:      metadata.Pairs("authorization", "Basic token"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewJWTAuthService("")
+			got, err := s.Authorize(context.Background(), tt.md)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Authorize() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Authorize() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
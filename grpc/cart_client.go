@@ -0,0 +1,114 @@
+package grpc
+
+import (
+	"context"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/cart"
+	"google.golang.org/grpc"
+)
+
+// CartService implements cart.Interface. It allows making calls to the
+// market gRPC server.
+type CartService struct {
+	AuthService AuthService
+
+	client pb.CartServiceClient
+}
+
+func NewCartService(auth AuthService) *CartService {
+	return &CartService{AuthService: auth}
+}
+
+// Connect must be called before any usage of Client. It connects to the
+// market gRPC server at the provided address.
+func (s *CartService) Connect(ctx context.Context, addr string) error {
+	auth := AuthInterceptor{AuthService: s.AuthService}
+
+	conn, err := grpc.DialContext(
+		ctx, addr,
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(auth.UnaryClientInterceptor()),
+	)
+	if err != nil {
+		return err
+	}
+
+	s.client = pb.NewCartServiceClient(conn)
+
+	return nil
+}
+
+func (s *CartService) Get(ctx context.Context) (*cart.Summary, error) {
+	rep, err := s.client.Get(ctx, &pb.GetCartRequest{})
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return cartFromPB(rep), nil
+}
+
+func (s *CartService) AddItem(ctx context.Context, r cart.AddRequest) (*cart.Summary, error) {
+	req := &pb.CartItemRequest{
+		Product:  r.Product,
+		Quantity: r.Quantity,
+	}
+
+	rep, err := s.client.AddItem(ctx, req)
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return cartFromPB(rep), nil
+}
+
+func (s *CartService) UpdateItem(ctx context.Context, r cart.UpdateRequest) (*cart.Summary, error) {
+	req := &pb.CartItemRequest{
+		Product:  r.Product,
+		Quantity: r.Quantity,
+	}
+
+	rep, err := s.client.UpdateItem(ctx, req)
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return cartFromPB(rep), nil
+}
+
+func (s *CartService) RemoveItem(ctx context.Context, product string) (*cart.Summary, error) {
+	rep, err := s.client.RemoveItem(ctx, &pb.RemoveCartItemRequest{Product: product})
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return cartFromPB(rep), nil
+}
+
+func cartFromPB(rep *pb.CartReply) *cart.Summary {
+	c := &cart.Summary{
+		User:  rep.User,
+		Lines: make([]cart.Line, len(rep.Items)),
+		Total: rep.Total,
+	}
+	if rep.ExpiresAt != nil {
+		expiresAt := fromMillis(*rep.ExpiresAt)
+		c.ExpiresAt = &expiresAt
+	}
+
+	for i, it := range rep.Items {
+		c.Lines[i] = cart.Line{
+			Item: cart.Item{
+				Product:  it.Product,
+				Quantity: it.Quantity,
+				Price:    it.Price,
+				AddedAt:  fromMillis(it.AddedAt),
+			},
+			CurrentPrice: it.CurrentPrice,
+			Subtotal:     it.Subtotal,
+			Deleted:      it.Deleted,
+			Repriced:     it.Repriced,
+		}
+	}
+
+	return c
+}
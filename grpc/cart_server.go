@@ -0,0 +1,86 @@
+package grpc
+
+import (
+	"context"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/cart"
+)
+
+// CartServer implements pb.CartServiceServer. It is registered by
+// Server.Run.
+type CartServer struct {
+	CartService cart.Interface
+}
+
+func (s *CartServer) Get(ctx context.Context, r *pb.GetCartRequest) (*pb.CartReply, error) {
+	c, err := s.CartService.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return cartToPB(c), nil
+}
+
+func (s *CartServer) AddItem(ctx context.Context, r *pb.CartItemRequest) (*pb.CartReply, error) {
+	ar := cart.AddRequest{
+		Product:  r.Product,
+		Quantity: r.Quantity,
+	}
+
+	c, err := s.CartService.AddItem(ctx, ar)
+	if err != nil {
+		return nil, err
+	}
+
+	return cartToPB(c), nil
+}
+
+func (s *CartServer) UpdateItem(ctx context.Context, r *pb.CartItemRequest) (*pb.CartReply, error) {
+	ur := cart.UpdateRequest{
+		Product:  r.Product,
+		Quantity: r.Quantity,
+	}
+
+	c, err := s.CartService.UpdateItem(ctx, ur)
+	if err != nil {
+		return nil, err
+	}
+
+	return cartToPB(c), nil
+}
+
+func (s *CartServer) RemoveItem(ctx context.Context, r *pb.RemoveCartItemRequest) (*pb.CartReply, error) {
+	c, err := s.CartService.RemoveItem(ctx, r.Product)
+	if err != nil {
+		return nil, err
+	}
+
+	return cartToPB(c), nil
+}
+
+func cartToPB(c *cart.Summary) *pb.CartReply {
+	rep := &pb.CartReply{
+		User:  c.User,
+		Items: make([]*pb.CartItemReply, len(c.Lines)),
+		Total: c.Total,
+	}
+	if c.ExpiresAt != nil {
+		expiresAt := toMillis(*c.ExpiresAt)
+		rep.ExpiresAt = &expiresAt
+	}
+
+	for i, l := range c.Lines {
+		rep.Items[i] = &pb.CartItemReply{
+			Product:      l.Product,
+			Quantity:     l.Quantity,
+			Price:        l.Price,
+			AddedAt:      toMillis(l.AddedAt),
+			CurrentPrice: l.CurrentPrice,
+			Subtotal:     l.Subtotal,
+			Deleted:      l.Deleted,
+			Repriced:     l.Repriced,
+		}
+	}
+
+	return rep
+}
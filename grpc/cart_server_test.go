@@ -0,0 +1,104 @@
+package grpc
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/cart"
+	"github.com/ortymid/market/mock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCartServer_AddItem(t *testing.T) {
+	addedAt := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := addedAt.Add(cart.DefaultTTL)
+
+	tests := []struct {
+		name       string
+		r          *pb.CartItemRequest
+		setupMocks func(cs *mock.CartService)
+		want       *pb.CartReply
+		wantErr    bool
+	}{
+		{
+			name: "Should add item",
+			r:    &pb.CartItemRequest{Product: "1", Quantity: 2},
+			setupMocks: func(cs *mock.CartService) {
+				cs.EXPECT().AddItem(
+					gomock.Any(),
+					cart.AddRequest{Product: "1", Quantity: 2},
+				).Return(&cart.Summary{
+					User: "2",
+					Lines: []cart.Line{{
+						Item:         cart.Item{Product: "1", Quantity: 2, Price: 100, AddedAt: addedAt},
+						CurrentPrice: 120,
+						Subtotal:     240,
+						Repriced:     true,
+					}},
+					Total:     240,
+					ExpiresAt: &expiresAt,
+				}, nil)
+			},
+			want: &pb.CartReply{
+				User: "2",
+				Items: []*pb.CartItemReply{{
+					Product: "1", Quantity: 2, Price: 100, AddedAt: toMillis(addedAt),
+					CurrentPrice: 120, Subtotal: 240, Repriced: true,
+				}},
+				Total:     240,
+				ExpiresAt: testInt64Ptr(toMillis(expiresAt)),
+			},
+		},
+		{
+			name: "Should return error for invalid quantity",
+			r:    &pb.CartItemRequest{Product: "1"},
+			setupMocks: func(cs *mock.CartService) {
+				cs.EXPECT().AddItem(gomock.Any(), gomock.Any()).Return(
+					nil, cart.ErrValidation{Resource: cart.Resource, Field: "quantity", Reason: "must be positive"},
+				)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cs := mock.NewCartService(ctrl)
+			tt.setupMocks(cs)
+
+			s := &CartServer{CartService: cs}
+			got, err := s.AddItem(context.Background(), tt.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AddItem() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AddItem() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCartFromPB(t *testing.T) {
+	addedAt := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := addedAt.Add(cart.DefaultTTL)
+
+	want := &cart.Summary{
+		User: "2",
+		Lines: []cart.Line{
+			{Item: cart.Item{Product: "1", Quantity: 2, Price: 100, AddedAt: addedAt}, CurrentPrice: 100, Subtotal: 200},
+			{Item: cart.Item{Product: "3", Quantity: 1, Price: 50, AddedAt: addedAt}, Deleted: true},
+		},
+		Total:     200,
+		ExpiresAt: &expiresAt,
+	}
+
+	got := cartFromPB(cartToPB(want))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cartFromPB() got = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,123 @@
+package grpc
+
+import (
+	"context"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/category"
+	"google.golang.org/grpc"
+)
+
+// CategoryService implements category.Interface. It allows making calls to
+// the market gRPC server.
+type CategoryService struct {
+	AuthService AuthService
+
+	client pb.CategoryServiceClient
+}
+
+func NewCategoryService(auth AuthService) *CategoryService {
+	return &CategoryService{AuthService: auth}
+}
+
+// Connect must be called before any usage of Client. It connects to the
+// market gRPC server at the provided address.
+func (s *CategoryService) Connect(ctx context.Context, addr string) error {
+	auth := AuthInterceptor{AuthService: s.AuthService}
+
+	conn, err := grpc.DialContext(
+		ctx, addr,
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(auth.UnaryClientInterceptor()),
+	)
+	if err != nil {
+		return err
+	}
+
+	s.client = pb.NewCategoryServiceClient(conn)
+
+	return nil
+}
+
+func (s *CategoryService) Find(ctx context.Context) ([]*category.Category, error) {
+	rep, err := s.client.Find(ctx, &pb.FindCategoriesRequest{})
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return categoriesFromPB(rep), nil
+}
+
+func (s *CategoryService) FindOne(ctx context.Context, id string) (*category.Category, error) {
+	rep, err := s.client.FindOne(ctx, &pb.FindOneCategoryRequest{Id: id})
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return categoryFromPB(rep), nil
+}
+
+func (s *CategoryService) Descendants(ctx context.Context, id string) ([]*category.Category, error) {
+	rep, err := s.client.Descendants(ctx, &pb.DescendantsRequest{Id: id})
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return categoriesFromPB(rep), nil
+}
+
+func (s *CategoryService) Create(ctx context.Context, r category.CreateRequest) (*category.Category, error) {
+	req := &pb.CreateCategoryRequest{
+		Slug:   r.Slug,
+		Name:   r.Name,
+		Parent: r.Parent,
+	}
+
+	rep, err := s.client.Create(ctx, req)
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return categoryFromPB(rep), nil
+}
+
+func (s *CategoryService) Update(ctx context.Context, r category.UpdateRequest) (*category.Category, error) {
+	req := &pb.UpdateCategoryRequest{
+		Id:     r.ID,
+		Slug:   r.Slug,
+		Name:   r.Name,
+		Parent: r.Parent,
+	}
+
+	rep, err := s.client.Update(ctx, req)
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return categoryFromPB(rep), nil
+}
+
+func (s *CategoryService) Delete(ctx context.Context, id string) (*category.Category, error) {
+	rep, err := s.client.Delete(ctx, &pb.DeleteCategoryRequest{Id: id})
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return categoryFromPB(rep), nil
+}
+
+func categoryFromPB(rep *pb.CategoryReply) *category.Category {
+	return &category.Category{
+		ID:     rep.Id,
+		Slug:   rep.Slug,
+		Name:   rep.Name,
+		Parent: rep.Parent,
+	}
+}
+
+func categoriesFromPB(rep *pb.CategoriesReply) []*category.Category {
+	cs := make([]*category.Category, len(rep.Categories))
+	for i, c := range rep.Categories {
+		cs[i] = categoryFromPB(c)
+	}
+	return cs
+}
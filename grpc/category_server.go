@@ -0,0 +1,97 @@
+package grpc
+
+import (
+	"context"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/category"
+)
+
+// CategoryServer implements pb.CategoryServiceServer. It is registered by
+// Server.Run.
+type CategoryServer struct {
+	CategoryService category.Interface
+}
+
+func (s *CategoryServer) Find(ctx context.Context, r *pb.FindCategoriesRequest) (*pb.CategoriesReply, error) {
+	cs, err := s.CategoryService.Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoriesToPB(cs), nil
+}
+
+func (s *CategoryServer) FindOne(ctx context.Context, r *pb.FindOneCategoryRequest) (*pb.CategoryReply, error) {
+	c, err := s.CategoryService.FindOne(ctx, r.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoryToPB(c), nil
+}
+
+func (s *CategoryServer) Descendants(ctx context.Context, r *pb.DescendantsRequest) (*pb.CategoriesReply, error) {
+	cs, err := s.CategoryService.Descendants(ctx, r.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoriesToPB(cs), nil
+}
+
+func (s *CategoryServer) Create(ctx context.Context, r *pb.CreateCategoryRequest) (*pb.CategoryReply, error) {
+	cr := category.CreateRequest{
+		Slug:   r.Slug,
+		Name:   r.Name,
+		Parent: r.Parent,
+	}
+
+	c, err := s.CategoryService.Create(ctx, cr)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoryToPB(c), nil
+}
+
+func (s *CategoryServer) Update(ctx context.Context, r *pb.UpdateCategoryRequest) (*pb.CategoryReply, error) {
+	ur := category.UpdateRequest{
+		ID:     r.Id,
+		Slug:   r.Slug,
+		Name:   r.Name,
+		Parent: r.Parent,
+	}
+
+	c, err := s.CategoryService.Update(ctx, ur)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoryToPB(c), nil
+}
+
+func (s *CategoryServer) Delete(ctx context.Context, r *pb.DeleteCategoryRequest) (*pb.CategoryReply, error) {
+	c, err := s.CategoryService.Delete(ctx, r.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoryToPB(c), nil
+}
+
+func categoryToPB(c *category.Category) *pb.CategoryReply {
+	return &pb.CategoryReply{
+		Id:     c.ID,
+		Slug:   c.Slug,
+		Name:   c.Name,
+		Parent: c.Parent,
+	}
+}
+
+func categoriesToPB(cs []*category.Category) *pb.CategoriesReply {
+	rep := &pb.CategoriesReply{Categories: make([]*pb.CategoryReply, len(cs))}
+	for i, c := range cs {
+		rep.Categories[i] = categoryToPB(c)
+	}
+	return rep
+}
@@ -0,0 +1,88 @@
+package grpc
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/mock"
+	"reflect"
+	"testing"
+)
+
+func TestCategoryServer_Find(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cs := mock.NewCategoryService(ctrl)
+	cs.EXPECT().Find(gomock.Any()).Return([]*category.Category{
+		{ID: "1", Slug: "food", Name: "Food"},
+		{ID: "2", Slug: "fruits", Name: "Fruits", Parent: testStringPtr("1")},
+	}, nil)
+
+	s := &CategoryServer{CategoryService: cs}
+	got, err := s.Find(context.Background(), &pb.FindCategoriesRequest{})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	want := &pb.CategoriesReply{Categories: []*pb.CategoryReply{
+		{Id: "1", Slug: "food", Name: "Food"},
+		{Id: "2", Slug: "fruits", Name: "Fruits", Parent: testStringPtr("1")},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() got = %v, want %v", got, want)
+	}
+}
+
+func TestCategoryServer_Update(t *testing.T) {
+	tests := []struct {
+		name       string
+		r          *pb.UpdateCategoryRequest
+		setupMocks func(cs *mock.CategoryService)
+		want       *pb.CategoryReply
+		wantErr    bool
+	}{
+		{
+			name: "Should move category to the root",
+			r:    &pb.UpdateCategoryRequest{Id: "2", Parent: testStringPtr("")},
+			setupMocks: func(cs *mock.CategoryService) {
+				cs.EXPECT().Update(
+					gomock.Any(),
+					category.UpdateRequest{ID: "2", Parent: testStringPtr("")},
+				).Return(&category.Category{ID: "2", Slug: "fruits", Name: "Fruits"}, nil)
+			},
+			want: &pb.CategoryReply{Id: "2", Slug: "fruits", Name: "Fruits"},
+		},
+		{
+			name: "Should return error for unknown category",
+			r:    &pb.UpdateCategoryRequest{Id: "3", Name: testStringPtr("Tools")},
+			setupMocks: func(cs *mock.CategoryService) {
+				cs.EXPECT().Update(
+					gomock.Any(),
+					category.UpdateRequest{ID: "3", Name: testStringPtr("Tools")},
+				).Return(nil, category.ErrNotFound)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cs := mock.NewCategoryService(ctrl)
+			tt.setupMocks(cs)
+
+			s := &CategoryServer{CategoryService: cs}
+			got, err := s.Update(context.Background(), tt.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Update() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,11 +2,14 @@ package grpc
 
 import (
 	"context"
+	"fmt"
 	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/audit"
 	"github.com/ortymid/market/market/product"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 	"io"
-	"log"
+	"strconv"
 )
 
 // ProductService implements product.Interface. It allows making calls to the market
@@ -41,24 +44,39 @@ func (s *ProductService) Connect(ctx context.Context, addr string) error {
 	return nil
 }
 
-func (s *ProductService) Find(ctx context.Context, r product.FindRequest) ([]*product.Product, error) {
+func (s *ProductService) Find(ctx context.Context, r product.FindRequest) (*product.FindResult, error) {
 	var priceRange *pb.PriceRange
 	if r.PriceRange != nil {
 		priceRange = &pb.PriceRange{
-			From: r.PriceRange.From,
-			To:   r.PriceRange.To,
+			From:     r.PriceRange.From,
+			To:       r.PriceRange.To,
+			Currency: r.PriceRange.Currency,
 		}
 	}
 	req := &pb.FindRequest{
-		Offset:     r.Offset,
-		Limit:      r.Limit,
-		Name:       r.Name,
-		PriceRange: priceRange,
+		Offset:             r.Offset,
+		Limit:              r.Limit,
+		Name:               r.Name,
+		PriceRange:         priceRange,
+		Seller:             r.Seller,
+		Sort:               sortsToPB(r.Sort),
+		PageToken:          r.Cursor,
+		Categories:         r.Categories,
+		IncludeDescendants: r.IncludeDescendants,
+		Attributes:         r.Attributes,
+		InStock:            r.InStock,
+		MinRating:          r.MinRating,
+		IncludeDeleted:     r.IncludeDeleted,
+		DisplayCurrency:    r.DisplayCurrency,
+	}
+	if r.Status != nil {
+		status := string(*r.Status)
+		req.Status = &status
 	}
 
 	stream, err := s.client.Find(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, errorFromStatus(err)
 	}
 
 	products := make([]*product.Product, 0, r.Limit)
@@ -68,58 +86,83 @@ func (s *ProductService) Find(ctx context.Context, r product.FindRequest) ([]*pr
 			break
 		}
 		if err != nil {
-			log.Println("receiving product:", err)
+			return nil, errorFromStatus(err)
 		}
 
-		p := &product.Product{
-			ID:     rep.Id,
-			Name:   rep.Name,
-			Price:  rep.Price,
-			Seller: rep.Seller,
-		}
+		p := productFromPB(rep)
 
 		products = append(products, p)
 	}
 
-	return products, nil
+	res := &product.FindResult{Products: products}
+	if err := resultFromTrailer(stream.Trailer(), res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// resultFromTrailer fills the listing metadata of the result from the Find
+// trailer.
+func resultFromTrailer(md metadata.MD, res *product.FindResult) error {
+	if vals := md.Get(totalCountKey); len(vals) > 0 {
+		total, err := strconv.ParseInt(vals[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("parsing %s trailer: %w", totalCountKey, err)
+		}
+		res.Total = total
+	}
+	if vals := md.Get(totalEstimatedKey); len(vals) > 0 {
+		res.TotalEstimated = vals[0] == "true"
+	}
+	if vals := md.Get(nextPageTokenKey); len(vals) > 0 {
+		res.NextCursor = vals[0]
+		res.HasMore = true
+	}
+	return nil
 }
 
 func (s *ProductService) FindOne(ctx context.Context, id string) (*product.Product, error) {
+	return s.FindOneIn(ctx, id, "")
+}
+
+// FindOneIn requests the product with the display price in the currency, no
+// display price is requested if the currency is empty.
+func (s *ProductService) FindOneIn(ctx context.Context, id string, currency string) (*product.Product, error) {
 	req := &pb.FindOneRequest{
-		Id: id,
+		Id:              id,
+		DisplayCurrency: currency,
 	}
 
 	rep, err := s.client.FindOne(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, errorFromStatus(err)
 	}
 
-	p := &product.Product{
-		ID:     rep.Id,
-		Name:   rep.Name,
-		Price:  rep.Price,
-		Seller: rep.Seller,
-	}
+	p := productFromPB(rep)
 	return p, nil
 }
 
 func (s *ProductService) Create(ctx context.Context, r product.CreateRequest) (*product.Product, error) {
 	req := &pb.CreateRequest{
-		Name:  r.Name,
-		Price: r.Price,
+		Name:       r.Name,
+		Price:      moneyToPB(r.Price),
+		Categories: r.Categories,
+		Attributes: attributesToPB(r.Attributes),
+		Variants:   variantsToPB(r.Variants),
+
+		Stock:             r.Stock,
+		LowStockThreshold: r.LowStockThreshold,
+
+		Status:    string(r.Status),
+		PublishAt: toMillisPtr(r.PublishAt),
 	}
 
 	rep, err := s.client.Create(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, errorFromStatus(err)
 	}
 
-	p := &product.Product{
-		ID:     rep.Id,
-		Name:   rep.Name,
-		Price:  rep.Price,
-		Seller: rep.Seller,
-	}
+	p := productFromPB(rep)
 	return p, nil
 }
 
@@ -127,23 +170,35 @@ func (s *ProductService) Update(ctx context.Context, r product.UpdateRequest) (*
 	req := &pb.UpdateRequest{
 		Id:   r.ID,
 		Name: r.Name,
+
+		LowStockThreshold: r.LowStockThreshold,
+		ExpectedVersion:   r.ExpectedVersion,
+
+		PublishAt: toMillisPtr(r.PublishAt),
+	}
+	if r.Status != nil {
+		status := string(*r.Status)
+		req.Status = &status
 	}
 	if r.Price != nil {
-		price := *r.Price
-		req.Price = &price
+		req.Price = moneyToPB(*r.Price)
+	}
+	if r.Categories != nil {
+		req.Categories = &pb.CategoryIds{Ids: *r.Categories}
+	}
+	if r.Attributes != nil {
+		req.Attributes = &pb.Attributes{Attributes: attributesToPB(*r.Attributes)}
+	}
+	if r.Variants != nil {
+		req.Variants = &pb.Variants{Variants: variantsToPB(*r.Variants)}
 	}
 
 	rep, err := s.client.Update(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, errorFromStatus(err)
 	}
 
-	p := &product.Product{
-		ID:     rep.Id,
-		Name:   rep.Name,
-		Price:  rep.Price,
-		Seller: rep.Seller,
-	}
+	p := productFromPB(rep)
 	return p, nil
 }
 
@@ -154,14 +209,99 @@ func (s *ProductService) Delete(ctx context.Context, id string) (*product.Produc
 
 	rep, err := s.client.Delete(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, errorFromStatus(err)
+	}
+
+	p := productFromPB(rep)
+	return p, nil
+}
+
+func (s *ProductService) Restore(ctx context.Context, id string) (*product.Product, error) {
+	req := &pb.RestoreRequest{
+		Id: id,
+	}
+
+	rep, err := s.client.Restore(ctx, req)
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	p := productFromPB(rep)
+	return p, nil
+}
+
+func (s *ProductService) AdjustStock(ctx context.Context, id string, variant string, delta int64) (*product.Product, error) {
+	req := &pb.AdjustStockRequest{
+		Id:      id,
+		Delta:   delta,
+		Variant: variant,
+	}
+
+	rep, err := s.client.AdjustStock(ctx, req)
+	if err != nil {
+		return nil, errorFromStatus(err)
 	}
 
+	p := productFromPB(rep)
+	return p, nil
+}
+
+func (s *ProductService) History(ctx context.Context, r audit.FindRequest) ([]*audit.Entry, error) {
+	rep, err := s.client.History(ctx, &pb.HistoryRequest{Id: r.Product, Offset: r.Offset, Limit: r.Limit})
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	es := make([]*audit.Entry, 0, len(rep.Entries))
+	for _, pe := range rep.Entries {
+		es = append(es, entryFromPB(pe))
+	}
+	return es, nil
+}
+
+func entryFromPB(pe *pb.AuditEntry) *audit.Entry {
+	e := &audit.Entry{
+		ID:        pe.Id,
+		Product:   pe.Product,
+		Seller:    pe.Seller,
+		Actor:     pe.Actor,
+		Operation: audit.Operation(pe.Operation),
+		Version:   pe.Version,
+		Changes:   make([]audit.Change, 0, len(pe.Changes)),
+		CreatedAt: fromMillis(pe.CreatedAt),
+	}
+	for _, c := range pe.Changes {
+		e.Changes = append(e.Changes, audit.Change{Field: c.Field, Before: c.Before, After: c.After})
+	}
+	return e
+}
+
+func productFromPB(rep *pb.ProductReply) *product.Product {
 	p := &product.Product{
 		ID:     rep.Id,
 		Name:   rep.Name,
-		Price:  rep.Price,
+		Price:  moneyFromPB(rep.Price),
 		Seller: rep.Seller,
+
+		DisplayPrice: moneyFromPBPtr(rep.DisplayPrice),
+
+		Stock:             rep.Stock,
+		Reserved:          rep.Reserved,
+		LowStockThreshold: rep.LowStockThreshold,
+
+		Status:    product.Status(rep.Status),
+		PublishAt: fromMillisPtr(rep.PublishAt),
+
+		Rating: product.Rating{Average: rep.GetRating().GetAverage(), Count: rep.GetRating().GetCount()},
+
+		Version:   rep.Version,
+		DeletedAt: fromMillisPtr(rep.DeletedAt),
 	}
-	return p, nil
+	if len(rep.Categories) > 0 {
+		p.Categories = rep.Categories
+	}
+	p.Media = mediaFromPB(rep.Media)
+	p.Attributes = attributesFromPB(rep.Attributes)
+	p.Variants = variantsFromPB(rep.Variants)
+	return p
 }
@@ -0,0 +1,50 @@
+package grpc
+
+import (
+	"github.com/ortymid/market/market/product"
+	"google.golang.org/grpc/metadata"
+	"reflect"
+	"testing"
+)
+
+func Test_resultFromTrailer(t *testing.T) {
+	tests := []struct {
+		name    string
+		md      metadata.MD
+		want    product.FindResult
+		wantErr bool
+	}{
+		{
+			name: "Should fill total of the last page",
+			md:   metadata.Pairs("total-count", "2"),
+			want: product.FindResult{Total: 2},
+		},
+		{
+			name: "Should fill estimated total and next page",
+			md: metadata.Pairs(
+				"total-count", "30",
+				"total-estimated", "true",
+				"next-page-token", "token",
+			),
+			want: product.FindResult{Total: 30, TotalEstimated: true, HasMore: true, NextCursor: "token"},
+		},
+		{
+			name:    "Should error for invalid total",
+			md:      metadata.Pairs("total-count", "many"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got product.FindResult
+			err := resultFromTrailer(tt.md, &got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resultFromTrailer() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resultFromTrailer() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
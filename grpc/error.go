@@ -0,0 +1,232 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/errs"
+	"github.com/ortymid/market/market/order"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/review"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log"
+)
+
+// errorDomain is the ErrorInfo domain of the errors returned by the server.
+const errorDomain = "market"
+
+// ErrorInfo reasons of the failed preconditions and aborted operations.
+const (
+	reasonInsufficientStock = "INSUFFICIENT_STOCK"
+	reasonInsufficientFunds = "INSUFFICIENT_FUNDS"
+	reasonVersionConflict   = "VERSION_CONFLICT"
+	reasonInProgress        = "PURCHASE_IN_PROGRESS"
+)
+
+// ErrorUnaryServerInterceptor translates errors returned by unary handlers
+// into gRPC status errors.
+func ErrorUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (resp interface{}, err error) {
+		resp, err = handler(ctx, req)
+		if err != nil {
+			return nil, statusFromError(err).Err()
+		}
+		return resp, nil
+	}
+}
+
+// ErrorStreamServerInterceptor translates errors returned by stream handlers
+// into gRPC status errors.
+func ErrorStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		err := handler(srv, ss)
+		if err != nil {
+			return statusFromError(err).Err()
+		}
+		return nil
+	}
+}
+
+// statusFromError maps market errors to gRPC status codes with the details
+// needed to restore them on the client side. Errors which are already
+// a gRPC status are returned as is.
+func statusFromError(err error) *status.Status {
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+	if errors.Is(err, context.Canceled) {
+		return status.New(codes.Canceled, err.Error())
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.New(codes.DeadlineExceeded, err.Error())
+	}
+
+	var errPermission auth.ErrPermission
+	var errNotFound errs.NotFound
+	var errValidation errs.Validation
+
+	switch {
+	case errors.As(err, &errNotFound):
+		st := status.New(codes.NotFound, err.Error())
+		return withDetails(st, &errdetails.ResourceInfo{
+			ResourceType: errNotFound.Resource,
+			Description:  err.Error(),
+		})
+	case errors.Is(err, review.ErrDuplicate):
+		st := status.New(codes.AlreadyExists, err.Error())
+		return withDetails(st, &errdetails.ResourceInfo{
+			ResourceType: review.Resource,
+			Description:  err.Error(),
+		})
+	case errors.Is(err, product.ErrInsufficientStock):
+		st := status.New(codes.FailedPrecondition, err.Error())
+		return withDetails(st, &errdetails.ErrorInfo{
+			Reason: reasonInsufficientStock,
+			Domain: errorDomain,
+		})
+	case errors.Is(err, order.ErrInsufficientFunds):
+		st := status.New(codes.FailedPrecondition, err.Error())
+		return withDetails(st, &errdetails.ErrorInfo{
+			Reason: reasonInsufficientFunds,
+			Domain: errorDomain,
+		})
+	case errors.Is(err, product.ErrConflict):
+		st := status.New(codes.Aborted, err.Error())
+		return withDetails(st, &errdetails.ErrorInfo{
+			Reason: reasonVersionConflict,
+			Domain: errorDomain,
+		})
+	case errors.Is(err, order.ErrInProgress):
+		st := status.New(codes.Aborted, err.Error())
+		return withDetails(st, &errdetails.ErrorInfo{
+			Reason: reasonInProgress,
+			Domain: errorDomain,
+		})
+	case errors.Is(err, auth.ErrNoUser):
+		return status.New(codes.Unauthenticated, err.Error())
+	case errors.As(err, &errPermission):
+		st := status.New(codes.PermissionDenied, err.Error())
+		return withDetails(st, &errdetails.ErrorInfo{
+			Reason:   "PERMISSION_DENIED",
+			Domain:   errorDomain,
+			Metadata: map[string]string{"reason": errPermission.Reason},
+		})
+	case errors.As(err, &errValidation):
+		st := status.New(codes.InvalidArgument, err.Error())
+		return withDetails(st, &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: errValidation.Field, Description: errValidation.Reason},
+			},
+		}, &errdetails.ResourceInfo{
+			ResourceType: errValidation.Resource,
+			Description:  err.Error(),
+		})
+	default:
+		// The error may carry details of the storage, so it is only logged.
+		log.Println("internal error:", err)
+		return status.New(codes.Internal, "internal error")
+	}
+}
+
+// withDetails attaches details to the status. The status is returned without
+// details if they cannot be attached.
+func withDetails(st *status.Status, details ...proto.Message) *status.Status {
+	std, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+// errorFromStatus restores market errors from gRPC status errors returned
+// by the server. Other errors are returned as is.
+func errorFromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		return errs.NotFound{Resource: resourceType(st)}
+	case codes.AlreadyExists:
+		if resourceType(st) == review.Resource {
+			return review.ErrDuplicate
+		}
+		return err
+	case codes.Unauthenticated:
+		return auth.ErrNoUser
+	case codes.PermissionDenied:
+		e := auth.ErrPermission{Reason: st.Message()}
+		for _, d := range st.Details() {
+			if info, ok := d.(*errdetails.ErrorInfo); ok && info.Domain == errorDomain {
+				e.Reason = info.Metadata["reason"]
+			}
+		}
+		return e
+	case codes.FailedPrecondition:
+		for _, d := range st.Details() {
+			info, ok := d.(*errdetails.ErrorInfo)
+			if !ok || info.Domain != errorDomain {
+				continue
+			}
+			switch info.Reason {
+			case reasonInsufficientStock:
+				return product.ErrInsufficientStock
+			case reasonInsufficientFunds:
+				return order.ErrInsufficientFunds
+			}
+		}
+		return err
+	case codes.Aborted:
+		for _, d := range st.Details() {
+			info, ok := d.(*errdetails.ErrorInfo)
+			if !ok || info.Domain != errorDomain {
+				continue
+			}
+			switch info.Reason {
+			case reasonVersionConflict:
+				return product.ErrConflict
+			case reasonInProgress:
+				return order.ErrInProgress
+			}
+		}
+		return err
+	case codes.InvalidArgument:
+		field, reason := "", st.Message()
+		for _, d := range st.Details() {
+			if br, ok := d.(*errdetails.BadRequest); ok && len(br.FieldViolations) > 0 {
+				field = br.FieldViolations[0].Field
+				reason = br.FieldViolations[0].Description
+			}
+		}
+		return errs.Validation{Resource: resourceType(st), Field: field, Reason: reason}
+	default:
+		return err
+	}
+}
+
+// resourceType returns the type of the resource the status is about. It is
+// the product if the status has no ResourceInfo details.
+func resourceType(st *status.Status) string {
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ResourceInfo); ok {
+			return info.ResourceType
+		}
+	}
+	return product.Resource
+}
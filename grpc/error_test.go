@@ -0,0 +1,173 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/cart"
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/market/order"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/review"
+	"github.com/ortymid/market/market/seller"
+	"github.com/ortymid/market/market/user"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"reflect"
+	"testing"
+)
+
+func Test_statusFromError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode codes.Code
+		wantErr  error
+	}{
+		{
+			name:     "Should map not found",
+			err:      fmt.Errorf("get product: %w", product.ErrNotFound),
+			wantCode: codes.NotFound,
+			wantErr:  product.ErrNotFound,
+		},
+		{
+			name:     "Should map media not found",
+			err:      fmt.Errorf("get media: %w", product.ErrMediaNotFound),
+			wantCode: codes.NotFound,
+			wantErr:  product.ErrMediaNotFound,
+		},
+		{
+			name:     "Should map missing user",
+			err:      fmt.Errorf("create product: %w", auth.ErrNoUser),
+			wantCode: codes.Unauthenticated,
+			wantErr:  auth.ErrNoUser,
+		},
+		{
+			name:     "Should map permission",
+			err:      fmt.Errorf("update product: %w", auth.ErrPermission{Reason: "reason"}),
+			wantCode: codes.PermissionDenied,
+			wantErr:  auth.ErrPermission{Reason: "reason"},
+		},
+		{
+			name:     "Should map validation",
+			err:      fmt.Errorf("create product: %w", product.ErrValidation{Resource: product.Resource, Field: "name", Reason: "reason"}),
+			wantCode: codes.InvalidArgument,
+			wantErr:  product.ErrValidation{Resource: product.Resource, Field: "name", Reason: "reason"},
+		},
+		{
+			name:     "Should map insufficient stock",
+			err:      fmt.Errorf("reserve stock: %w", product.ErrInsufficientStock),
+			wantCode: codes.FailedPrecondition,
+			wantErr:  product.ErrInsufficientStock,
+		},
+		{
+			name:     "Should map category not found",
+			err:      fmt.Errorf("get category: %w", category.ErrNotFound),
+			wantCode: codes.NotFound,
+			wantErr:  category.ErrNotFound,
+		},
+		{
+			name:     "Should map category validation",
+			err:      fmt.Errorf("create category: %w", category.ErrValidation{Resource: category.Resource, Field: "slug", Reason: "reason"}),
+			wantCode: codes.InvalidArgument,
+			wantErr:  category.ErrValidation{Resource: category.Resource, Field: "slug", Reason: "reason"},
+		},
+		{
+			name:     "Should map order not found",
+			err:      fmt.Errorf("get order: %w", order.ErrNotFound),
+			wantCode: codes.NotFound,
+			wantErr:  order.ErrNotFound,
+		},
+		{
+			name:     "Should map user not found",
+			err:      fmt.Errorf("purchase: %w", user.ErrNotFound),
+			wantCode: codes.NotFound,
+			wantErr:  user.ErrNotFound,
+		},
+		{
+			name:     "Should map insufficient funds",
+			err:      fmt.Errorf("purchase: %w", order.ErrInsufficientFunds),
+			wantCode: codes.FailedPrecondition,
+			wantErr:  order.ErrInsufficientFunds,
+		},
+		{
+			name:     "Should map version conflict",
+			err:      fmt.Errorf("update product: %w", product.ErrConflict),
+			wantCode: codes.Aborted,
+			wantErr:  product.ErrConflict,
+		},
+		{
+			name:     "Should map purchase in progress",
+			err:      fmt.Errorf("purchase: %w", order.ErrInProgress),
+			wantCode: codes.Aborted,
+			wantErr:  order.ErrInProgress,
+		},
+		{
+			name:     "Should map order validation",
+			err:      fmt.Errorf("purchase: %w", order.ErrValidation{Resource: order.Resource, Field: "quantity", Reason: "reason"}),
+			wantCode: codes.InvalidArgument,
+			wantErr:  order.ErrValidation{Resource: order.Resource, Field: "quantity", Reason: "reason"},
+		},
+		{
+			name:     "Should map cart item not found",
+			err:      fmt.Errorf("update cart item: %w", cart.ErrItemNotFound),
+			wantCode: codes.NotFound,
+			wantErr:  cart.ErrItemNotFound,
+		},
+		{
+			name:     "Should map cart validation",
+			err:      fmt.Errorf("add cart item: %w", cart.ErrValidation{Resource: cart.Resource, Field: "quantity", Reason: "reason"}),
+			wantCode: codes.InvalidArgument,
+			wantErr:  cart.ErrValidation{Resource: cart.Resource, Field: "quantity", Reason: "reason"},
+		},
+		{
+			name:     "Should map review not found",
+			err:      fmt.Errorf("get review: %w", review.ErrNotFound),
+			wantCode: codes.NotFound,
+			wantErr:  review.ErrNotFound,
+		},
+		{
+			name:     "Should map duplicate review",
+			err:      fmt.Errorf("create review: %w", review.ErrDuplicate),
+			wantCode: codes.AlreadyExists,
+			wantErr:  review.ErrDuplicate,
+		},
+		{
+			name:     "Should map review validation",
+			err:      fmt.Errorf("create review: %w", review.ErrValidation{Resource: review.Resource, Field: "rating", Reason: "reason"}),
+			wantCode: codes.InvalidArgument,
+			wantErr:  review.ErrValidation{Resource: review.Resource, Field: "rating", Reason: "reason"},
+		},
+		{
+			name:     "Should map seller validation",
+			err:      fmt.Errorf("find sellers: %w", seller.ErrValidation{Resource: seller.Resource, Field: "ids", Reason: "reason"}),
+			wantCode: codes.InvalidArgument,
+			wantErr:  seller.ErrValidation{Resource: seller.Resource, Field: "ids", Reason: "reason"},
+		},
+		{
+			name:     "Should keep status",
+			err:      status.Error(codes.Unavailable, "unavailable"),
+			wantCode: codes.Unavailable,
+			wantErr:  status.Error(codes.Unavailable, "unavailable"),
+		},
+		{
+			name:     "Should map unknown error to internal",
+			err:      errors.New("test error"),
+			wantCode: codes.Internal,
+			wantErr:  status.Error(codes.Internal, "internal error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := statusFromError(tt.err)
+			if st.Code() != tt.wantCode {
+				t.Errorf("statusFromError() code = %v, want %v", st.Code(), tt.wantCode)
+			}
+
+			got := errorFromStatus(st.Err())
+			if !reflect.DeepEqual(got, tt.wantErr) && got.Error() != tt.wantErr.Error() {
+				t.Errorf("errorFromStatus() = %v, want %v", got, tt.wantErr)
+			}
+		})
+	}
+}
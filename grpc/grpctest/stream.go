@@ -0,0 +1,49 @@
+// Package grpctest provides utilities for testing gRPC handlers.
+package grpctest
+
+import (
+	"context"
+	"github.com/ortymid/market/grpc/pb"
+	"google.golang.org/grpc/metadata"
+)
+
+// ProductService_ListRecorder implements pb.ProductService_FindServer
+// recording all sent replies and the trailer.
+type ProductService_ListRecorder struct {
+	Ctx     context.Context
+	Stream  []*pb.ProductReply
+	Trailer metadata.MD
+}
+
+func NewProductService_ListRecorder() *ProductService_ListRecorder {
+	return &ProductService_ListRecorder{Ctx: context.Background()}
+}
+
+func (r *ProductService_ListRecorder) Send(rep *pb.ProductReply) error {
+	r.Stream = append(r.Stream, rep)
+	return nil
+}
+
+func (r *ProductService_ListRecorder) SetHeader(metadata.MD) error {
+	return nil
+}
+
+func (r *ProductService_ListRecorder) SendHeader(metadata.MD) error {
+	return nil
+}
+
+func (r *ProductService_ListRecorder) SetTrailer(md metadata.MD) {
+	r.Trailer = metadata.Join(r.Trailer, md)
+}
+
+func (r *ProductService_ListRecorder) Context() context.Context {
+	return r.Ctx
+}
+
+func (r *ProductService_ListRecorder) SendMsg(m interface{}) error {
+	return nil
+}
+
+func (r *ProductService_ListRecorder) RecvMsg(m interface{}) error {
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"strings"
 )
 
 type AuthInterceptor struct {
@@ -30,7 +31,7 @@ func (s *AuthInterceptor) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
 			return nil, status.Errorf(codes.Unauthenticated, err.Error())
 		}
 
-		ctx = auth.NewContextWithUser(ctx, u)
+		ctx = auth.NewContextWithUser(contextWithToken(ctx, md), u)
 		return handler(ctx, req)
 	}
 }
@@ -54,11 +55,37 @@ func (s *AuthInterceptor) StreamServerInterceptor() grpc.StreamServerInterceptor
 			return status.Errorf(codes.Unauthenticated, err.Error())
 		}
 
-		ctx = auth.NewContextWithUser(ctx, u)
-		return handler(srv, ss)
+		ctx = auth.NewContextWithUser(contextWithToken(ctx, md), u)
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
 	}
 }
 
+// contextWithToken keeps the bearer token of the authorization metadata to be
+// able to forward it to other services.
+func contextWithToken(ctx context.Context, md metadata.MD) context.Context {
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ctx
+	}
+
+	fields := strings.Fields(values[0])
+	if len(fields) != 2 || !strings.EqualFold(fields[0], "Bearer") {
+		return ctx
+	}
+	return auth.NewContextWithToken(ctx, fields[1])
+}
+
+// serverStream is a grpc.ServerStream with the overridden context. It is used to
+// pass values from stream interceptors to handlers.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
 func (s *AuthInterceptor) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
 	return func(
 		ctx context.Context,
@@ -0,0 +1,96 @@
+package grpc
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/ortymid/market/grpc/grpctest"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/user"
+	"github.com/ortymid/market/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"reflect"
+	"testing"
+)
+
+func TestAuthInterceptor_StreamServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name       string
+		md         metadata.MD
+		setupMocks func(as *mock.GRPCAuthService)
+		wantUser   *user.User
+		wantToken  interface{}
+		wantErr    bool
+	}{
+		{
+			name: "Should pass user and token to handler",
+			md:   metadata.Pairs("authorization", "Bearer token"),
+			setupMocks: func(as *mock.GRPCAuthService) {
+				as.EXPECT().Authorize(gomock.Any(), metadata.Pairs("authorization", "Bearer token")).
+					Return(&user.User{ID: "1"}, nil)
+			},
+			wantUser:  &user.User{ID: "1"},
+			wantToken: "token",
+		},
+		{
+			name: "Should pass anonymous user to handler",
+			md:   metadata.MD{},
+			setupMocks: func(as *mock.GRPCAuthService) {
+				as.EXPECT().Authorize(gomock.Any(), metadata.MD{}).Return(nil, nil)
+			},
+			wantUser: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			as := mock.NewGRPCAuthService(ctrl)
+			if tt.setupMocks != nil {
+				tt.setupMocks(as)
+			}
+
+			stream := grpctest.NewProductService_ListRecorder()
+			stream.Ctx = metadata.NewIncomingContext(context.Background(), tt.md)
+
+			var gotUser *user.User
+			var gotToken interface{}
+			handler := func(srv interface{}, ss grpc.ServerStream) error {
+				u, err := auth.UserFromContext(ss.Context())
+				if err != nil {
+					return err
+				}
+				gotUser = u
+				gotToken = auth.TokenFromContext(ss.Context())
+				return nil
+			}
+
+			i := &AuthInterceptor{AuthService: as}
+			err := i.StreamServerInterceptor()(nil, stream, &grpc.StreamServerInfo{}, handler)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("StreamServerInterceptor() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotUser, tt.wantUser) {
+				t.Errorf("StreamServerInterceptor() user = %v, want %v", gotUser, tt.wantUser)
+			}
+			if gotToken != tt.wantToken {
+				t.Errorf("StreamServerInterceptor() token = %v, want %v", gotToken, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestRecoveryUnaryServerInterceptor(t *testing.T) {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("test panic")
+	}
+
+	_, err := RecoveryUnaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	if status.Code(err) != codes.Internal {
+		t.Errorf("RecoveryUnaryServerInterceptor() error = %v, want Internal", err)
+	}
+}
@@ -0,0 +1,243 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/media"
+	"github.com/ortymid/market/market/product"
+	"io"
+)
+
+// mediaChunkSize is the size of the chunks media are streamed in.
+const mediaChunkSize = 64 << 10
+
+// UploadMedia adds the image streamed after the media info to the product.
+func (s *Server) UploadMedia(stream pb.ProductService_UploadMediaServer) error {
+	req, err := stream.Recv()
+	if err == io.EOF {
+		return product.ErrValidation{Resource: product.Resource, Field: "info", Reason: "must be sent first"}
+	}
+	if err != nil {
+		return err
+	}
+	info := req.GetInfo()
+	if info == nil {
+		return product.ErrValidation{Resource: product.Resource, Field: "info", Reason: "must be sent first"}
+	}
+
+	p, err := s.ProductService.AddMedia(stream.Context(), product.AddMediaRequest{
+		Product:     info.ProductId,
+		ContentType: info.ContentType,
+		Body:        &uploadReader{stream: stream},
+	})
+	if err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(productToPB(p))
+}
+
+func (s *Server) DeleteMedia(ctx context.Context, r *pb.DeleteMediaRequest) (*pb.ProductReply, error) {
+	p, err := s.ProductService.RemoveMedia(ctx, r.ProductId, r.MediaId)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := productToPB(p)
+	return rep, nil
+}
+
+// DownloadMedia streams the image or its thumbnail. The content type is sent
+// with the first chunk.
+func (s *Server) DownloadMedia(r *pb.DownloadMediaRequest, stream pb.ProductService_DownloadMediaServer) error {
+	c, err := s.ProductService.OpenMedia(stream.Context(), r.ProductId, r.MediaId, r.Thumbnail)
+	if err != nil {
+		return err
+	}
+	defer c.Body.Close()
+
+	chunk := &pb.MediaChunk{ContentType: c.ContentType}
+	buf := make([]byte, mediaChunkSize)
+	for {
+		n, err := io.ReadFull(c.Body, buf)
+		if n > 0 || chunk.ContentType != "" {
+			chunk.Chunk = buf[:n]
+			if err := stream.Send(chunk); err != nil {
+				return err
+			}
+			chunk = &pb.MediaChunk{}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// uploadReader reads the chunks of an uploaded image from the stream.
+type uploadReader struct {
+	stream pb.ProductService_UploadMediaServer
+	buf    []byte
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.buf = req.GetChunk()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// AddMedia uploads the image in chunks.
+func (s *ProductService) AddMedia(ctx context.Context, r product.AddMediaRequest) (*product.Product, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := s.client.UploadMedia(ctx)
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+	// Send returns io.EOF if the server has ended the stream, its error is
+	// received then.
+	if err := sendMedia(stream, r); err != nil && err != io.EOF {
+		return nil, errorFromStatus(err)
+	}
+
+	rep, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	p := productFromPB(rep)
+	return p, nil
+}
+
+// sendMedia sends the media info followed by the chunks of the body.
+func sendMedia(stream pb.ProductService_UploadMediaClient, r product.AddMediaRequest) error {
+	info := &pb.MediaInfo{ProductId: r.Product, ContentType: r.ContentType}
+	if err := stream.Send(&pb.UploadMediaRequest{Data: &pb.UploadMediaRequest_Info{Info: info}}); err != nil {
+		return err
+	}
+
+	buf := make([]byte, mediaChunkSize)
+	for {
+		n, err := r.Body.Read(buf)
+		if n > 0 {
+			chunk := &pb.UploadMediaRequest_Chunk{Chunk: buf[:n]}
+			if err := stream.Send(&pb.UploadMediaRequest{Data: chunk}); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading media: %w", err)
+		}
+	}
+}
+
+func (s *ProductService) RemoveMedia(ctx context.Context, id string, mediaID string) (*product.Product, error) {
+	rep, err := s.client.DeleteMedia(ctx, &pb.DeleteMediaRequest{ProductId: id, MediaId: mediaID})
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	p := productFromPB(rep)
+	return p, nil
+}
+
+// OpenMedia starts downloading the image or its thumbnail. The download is
+// canceled when the body is closed.
+func (s *ProductService) OpenMedia(ctx context.Context, id string, mediaID string, thumbnail bool) (*media.Content, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := s.client.DownloadMedia(ctx, &pb.DownloadMediaRequest{
+		ProductId: id,
+		MediaId:   mediaID,
+		Thumbnail: thumbnail,
+	})
+	if err != nil {
+		cancel()
+		return nil, errorFromStatus(err)
+	}
+
+	// Errors of the server are received with the first chunk.
+	chunk, err := stream.Recv()
+	if err != nil {
+		cancel()
+		return nil, errorFromStatus(err)
+	}
+
+	body := &downloadReader{stream: stream, buf: chunk.Chunk, cancel: cancel}
+	return &media.Content{ContentType: chunk.ContentType, Body: body}, nil
+}
+
+// downloadReader reads the chunks of a downloaded image from the stream.
+type downloadReader struct {
+	stream pb.ProductService_DownloadMediaClient
+	buf    []byte
+	cancel context.CancelFunc
+}
+
+func (r *downloadReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		chunk, err := r.stream.Recv()
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, errorFromStatus(err)
+		}
+		r.buf = chunk.Chunk
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *downloadReader) Close() error {
+	r.cancel()
+	return nil
+}
+
+func mediaToPB(items []media.Media) []*pb.Media {
+	if len(items) == 0 {
+		return nil
+	}
+	pms := make([]*pb.Media, 0, len(items))
+	for _, m := range items {
+		pms = append(pms, &pb.Media{
+			Id:          m.ID,
+			ContentType: m.ContentType,
+			Size:        m.Size,
+			Width:       int32(m.Width),
+			Height:      int32(m.Height),
+		})
+	}
+	return pms
+}
+
+func mediaFromPB(pms []*pb.Media) []media.Media {
+	if len(pms) == 0 {
+		return nil
+	}
+	items := make([]media.Media, 0, len(pms))
+	for _, pm := range pms {
+		items = append(items, media.Media{
+			ID:          pm.Id,
+			ContentType: pm.ContentType,
+			Size:        pm.Size,
+			Width:       int(pm.Width),
+			Height:      int(pm.Height),
+		})
+	}
+	return items
+}
@@ -0,0 +1,142 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/media"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// testMediaClient returns a client of a server serving the product service
+// over an in-memory connection.
+func testMediaClient(t *testing.T, ps product.Interface) *ProductService {
+	ln := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(ErrorUnaryServerInterceptor()),
+		grpc.StreamInterceptor(ErrorStreamServerInterceptor()),
+	)
+	pb.RegisterProductServiceServer(srv, &Server{ProductService: ps})
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.Dial() }),
+	)
+	if err != nil {
+		t.Fatalf("dialing server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &ProductService{client: pb.NewProductServiceClient(conn)}
+}
+
+func TestProductService_AddMedia(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The image spans several chunks.
+	data := bytes.Repeat([]byte("image"), mediaChunkSize/2)
+	m := media.Media{ID: "a1", ContentType: "image/png", Size: int64(len(data)), Width: 2, Height: 1}
+
+	ps := mock.NewProductService(ctrl)
+	ps.EXPECT().AddMedia(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, r product.AddMediaRequest) (*product.Product, error) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				return nil, err
+			}
+			if r.Product != "1" || r.ContentType != "image/png" || !bytes.Equal(body, data) {
+				t.Errorf("AddMedia() got %s %s of %d bytes, want 1 image/png of %d bytes", r.Product, r.ContentType, len(body), len(data))
+			}
+			return &product.Product{ID: "1", Price: money.New(100, "USD"), Media: []media.Media{m}}, nil
+		},
+	)
+
+	s := testMediaClient(t, ps)
+	got, err := s.AddMedia(context.Background(), product.AddMediaRequest{Product: "1", ContentType: "image/png", Body: bytes.NewReader(data)})
+	if err != nil {
+		t.Fatalf("AddMedia() error = %v", err)
+	}
+	want := &product.Product{ID: "1", Price: money.New(100, "USD"), Media: []media.Media{m}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AddMedia() got = %v, want %v", got, want)
+	}
+}
+
+func TestProductService_AddMediaError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The server fails before the whole image is sent.
+	wantErr := product.ErrValidation{Resource: product.Resource, Field: "size", Reason: "must not be larger than 10 bytes"}
+	ps := mock.NewProductService(ctrl)
+	ps.EXPECT().AddMedia(gomock.Any(), gomock.Any()).Return(nil, wantErr)
+
+	s := testMediaClient(t, ps)
+	data := bytes.Repeat([]byte("image"), mediaChunkSize)
+	_, err := s.AddMedia(context.Background(), product.AddMediaRequest{Product: "1", Body: bytes.NewReader(data)})
+	if !errors.Is(err, wantErr) {
+		t.Errorf("AddMedia() error = %v, want %v", err, wantErr)
+	}
+}
+
+func TestProductService_OpenMedia(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	data := bytes.Repeat([]byte("thumbnail"), mediaChunkSize/3)
+	ps := mock.NewProductService(ctrl)
+	ps.EXPECT().OpenMedia(gomock.Any(), "1", "a1", true).Return(
+		&media.Content{ContentType: "image/jpeg", Body: ioutil.NopCloser(bytes.NewReader(data))}, nil,
+	)
+	ps.EXPECT().OpenMedia(gomock.Any(), "1", "b2", false).Return(nil, product.ErrMediaNotFound)
+
+	s := testMediaClient(t, ps)
+	c, err := s.OpenMedia(context.Background(), "1", "a1", true)
+	if err != nil {
+		t.Fatalf("OpenMedia() error = %v", err)
+	}
+	defer c.Body.Close()
+	body, err := ioutil.ReadAll(c.Body)
+	if err != nil {
+		t.Fatalf("reading media: %v", err)
+	}
+	if c.ContentType != "image/jpeg" || !bytes.Equal(body, data) {
+		t.Errorf("OpenMedia() got %s of %d bytes, want image/jpeg of %d bytes", c.ContentType, len(body), len(data))
+	}
+
+	if _, err := s.OpenMedia(context.Background(), "1", "b2", false); !errors.Is(err, product.ErrMediaNotFound) {
+		t.Errorf("OpenMedia() error = %v, want %v", err, product.ErrMediaNotFound)
+	}
+}
+
+func TestServer_DeleteMedia(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ps := mock.NewProductService(ctrl)
+	ps.EXPECT().RemoveMedia(gomock.Any(), "1", "a1").Return(&product.Product{ID: "1", Price: money.New(100, "USD")}, nil)
+
+	s := &Server{ProductService: ps}
+	got, err := s.DeleteMedia(context.Background(), &pb.DeleteMediaRequest{ProductId: "1", MediaId: "a1"})
+	if err != nil {
+		t.Fatalf("DeleteMedia() error = %v", err)
+	}
+	want := &pb.ProductReply{Id: "1", Price: &pb.Money{Amount: 100, Currency: "USD"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DeleteMedia() got = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,32 @@
+package grpc
+
+import (
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/money"
+)
+
+func moneyToPB(m money.Money) *pb.Money {
+	return &pb.Money{Amount: m.Amount, Currency: m.Currency}
+}
+
+// moneyToPBPtr returns the message of the money, it is nil if m is nil.
+func moneyToPBPtr(m *money.Money) *pb.Money {
+	if m == nil {
+		return nil
+	}
+	return moneyToPB(*m)
+}
+
+// moneyFromPB returns the money of the message, it is zero if m is nil.
+func moneyFromPB(m *pb.Money) money.Money {
+	return money.New(m.GetAmount(), m.GetCurrency())
+}
+
+// moneyFromPBPtr returns the money of the message, it is nil if m is nil.
+func moneyFromPBPtr(m *pb.Money) *money.Money {
+	if m == nil {
+		return nil
+	}
+	v := moneyFromPB(m)
+	return &v
+}
@@ -0,0 +1,104 @@
+package grpc
+
+import (
+	"context"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/order"
+	"google.golang.org/grpc"
+)
+
+// OrderService implements order.Interface. It allows making calls to the
+// market gRPC server.
+type OrderService struct {
+	AuthService AuthService
+
+	client pb.OrderServiceClient
+}
+
+func NewOrderService(auth AuthService) *OrderService {
+	return &OrderService{AuthService: auth}
+}
+
+// Connect must be called before any usage of Client. It connects to the
+// market gRPC server at the provided address.
+func (s *OrderService) Connect(ctx context.Context, addr string) error {
+	auth := AuthInterceptor{AuthService: s.AuthService}
+
+	conn, err := grpc.DialContext(
+		ctx, addr,
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(auth.UnaryClientInterceptor()),
+	)
+	if err != nil {
+		return err
+	}
+
+	s.client = pb.NewOrderServiceClient(conn)
+
+	return nil
+}
+
+func (s *OrderService) Purchase(ctx context.Context, r order.PurchaseRequest) (*order.Order, error) {
+	req := &pb.PurchaseRequest{
+		Product:        r.Product,
+		Variant:        r.Variant,
+		Quantity:       r.Quantity,
+		IdempotencyKey: r.IdempotencyKey,
+	}
+
+	rep, err := s.client.Purchase(ctx, req)
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return orderFromPB(rep), nil
+}
+
+func (s *OrderService) Find(ctx context.Context, r order.FindRequest) ([]*order.Order, error) {
+	req := &pb.FindOrdersRequest{
+		Offset: r.Offset,
+		Limit:  r.Limit,
+		Buyer:  r.Buyer,
+		Seller: r.Seller,
+	}
+
+	rep, err := s.client.Find(ctx, req)
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return ordersFromPB(rep), nil
+}
+
+func (s *OrderService) FindOne(ctx context.Context, id string) (*order.Order, error) {
+	rep, err := s.client.FindOne(ctx, &pb.FindOneOrderRequest{Id: id})
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return orderFromPB(rep), nil
+}
+
+func orderFromPB(rep *pb.OrderReply) *order.Order {
+	return &order.Order{
+		ID:             rep.Id,
+		Product:        rep.Product,
+		Variant:        rep.Variant,
+		Buyer:          rep.Buyer,
+		Seller:         rep.Seller,
+		Quantity:       rep.Quantity,
+		Price:          rep.Price,
+		Total:          rep.Total,
+		Status:         order.Status(rep.Status),
+		IdempotencyKey: rep.IdempotencyKey,
+		CreatedAt:      fromMillis(rep.CreatedAt),
+	}
+}
+
+func ordersFromPB(rep *pb.OrdersReply) []*order.Order {
+	os := make([]*order.Order, len(rep.Orders))
+	for i, o := range rep.Orders {
+		os[i] = orderFromPB(o)
+	}
+	return os
+}
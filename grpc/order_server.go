@@ -0,0 +1,78 @@
+package grpc
+
+import (
+	"context"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/order"
+)
+
+// OrderServer implements pb.OrderServiceServer. It is registered by
+// Server.Run.
+type OrderServer struct {
+	OrderService order.Interface
+}
+
+func (s *OrderServer) Purchase(ctx context.Context, r *pb.PurchaseRequest) (*pb.OrderReply, error) {
+	pr := order.PurchaseRequest{
+		Product:        r.Product,
+		Variant:        r.Variant,
+		Quantity:       r.Quantity,
+		IdempotencyKey: r.IdempotencyKey,
+	}
+
+	o, err := s.OrderService.Purchase(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	return orderToPB(o), nil
+}
+
+func (s *OrderServer) Find(ctx context.Context, r *pb.FindOrdersRequest) (*pb.OrdersReply, error) {
+	fr := order.FindRequest{
+		Offset: r.Offset,
+		Limit:  r.Limit,
+		Buyer:  r.Buyer,
+		Seller: r.Seller,
+	}
+
+	os, err := s.OrderService.Find(ctx, fr)
+	if err != nil {
+		return nil, err
+	}
+
+	return ordersToPB(os), nil
+}
+
+func (s *OrderServer) FindOne(ctx context.Context, r *pb.FindOneOrderRequest) (*pb.OrderReply, error) {
+	o, err := s.OrderService.FindOne(ctx, r.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return orderToPB(o), nil
+}
+
+func orderToPB(o *order.Order) *pb.OrderReply {
+	return &pb.OrderReply{
+		Id:             o.ID,
+		Product:        o.Product,
+		Variant:        o.Variant,
+		Buyer:          o.Buyer,
+		Seller:         o.Seller,
+		Quantity:       o.Quantity,
+		Price:          o.Price,
+		Total:          o.Total,
+		Status:         string(o.Status),
+		IdempotencyKey: o.IdempotencyKey,
+		CreatedAt:      toMillis(o.CreatedAt),
+	}
+}
+
+func ordersToPB(os []*order.Order) *pb.OrdersReply {
+	rep := &pb.OrdersReply{Orders: make([]*pb.OrderReply, len(os))}
+	for i, o := range os {
+		rep.Orders[i] = orderToPB(o)
+	}
+	return rep
+}
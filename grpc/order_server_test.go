@@ -0,0 +1,83 @@
+package grpc
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/order"
+	"github.com/ortymid/market/mock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOrderServer_Purchase(t *testing.T) {
+	createdAt := time.Date(2020, 10, 1, 12, 0, 0, int(5*time.Millisecond), time.UTC)
+
+	tests := []struct {
+		name       string
+		r          *pb.PurchaseRequest
+		setupMocks func(os *mock.OrderService)
+		want       *pb.OrderReply
+		wantErr    bool
+	}{
+		{
+			name: "Should purchase product",
+			r:    &pb.PurchaseRequest{Product: "1", Quantity: 2, IdempotencyKey: "k"},
+			setupMocks: func(os *mock.OrderService) {
+				os.EXPECT().Purchase(
+					gomock.Any(),
+					order.PurchaseRequest{Product: "1", Quantity: 2, IdempotencyKey: "k"},
+				).Return(&order.Order{
+					ID: "1", Product: "1", Buyer: "2", Seller: "1", Quantity: 2, Price: 100, Total: 200,
+					Status: order.StatusCompleted, IdempotencyKey: "k", CreatedAt: createdAt,
+				}, nil)
+			},
+			want: &pb.OrderReply{
+				Id: "1", Product: "1", Buyer: "2", Seller: "1", Quantity: 2, Price: 100, Total: 200,
+				Status: "completed", IdempotencyKey: "k", CreatedAt: createdAt.UnixNano() / int64(time.Millisecond),
+			},
+		},
+		{
+			name: "Should return error for insufficient funds",
+			r:    &pb.PurchaseRequest{Product: "1", Quantity: 2, IdempotencyKey: "k"},
+			setupMocks: func(os *mock.OrderService) {
+				os.EXPECT().Purchase(gomock.Any(), gomock.Any()).Return(nil, order.ErrInsufficientFunds)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			os := mock.NewOrderService(ctrl)
+			tt.setupMocks(os)
+
+			s := &OrderServer{OrderService: os}
+			got, err := s.Purchase(context.Background(), tt.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Purchase() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Purchase() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderFromPB(t *testing.T) {
+	want := &order.Order{
+		ID: "1", Product: "1", Buyer: "2", Seller: "1", Quantity: 2, Price: 100, Total: 200,
+		Status:         order.StatusCompleted,
+		IdempotencyKey: "k",
+		CreatedAt:      time.Date(2020, 10, 1, 12, 0, 0, int(5*time.Millisecond), time.UTC),
+	}
+
+	got := orderFromPB(orderToPB(want))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orderFromPB() got = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,403 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.23.0
+// 	protoc        v3.13.0
+// source: seller.proto
+
+package pb
+
+import (
+	context "context"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// This is a compile-time assertion that a sufficiently up-to-date version
+// of the legacy proto package is being used.
+const _ = proto.ProtoPackageIsVersion4
+
+type FindSellersRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ids []string `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+}
+
+func (x *FindSellersRequest) Reset() {
+	*x = FindSellersRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_seller_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FindSellersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindSellersRequest) ProtoMessage() {}
+
+func (x *FindSellersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_seller_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindSellersRequest.ProtoReflect.Descriptor instead.
+func (*FindSellersRequest) Descriptor() ([]byte, []int) {
+	return file_seller_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FindSellersRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+type SellerReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Empty if the user is not known to the user service.
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// Number of published products.
+	Products int64 `protobuf:"varint,3,opt,name=products,proto3" json:"products,omitempty"`
+	// Rating of all the reviews of the products, it is unset if there are none.
+	Rating *Rating `protobuf:"bytes,4,opt,name=rating,proto3" json:"rating,omitempty"`
+}
+
+func (x *SellerReply) Reset() {
+	*x = SellerReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_seller_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SellerReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SellerReply) ProtoMessage() {}
+
+func (x *SellerReply) ProtoReflect() protoreflect.Message {
+	mi := &file_seller_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SellerReply.ProtoReflect.Descriptor instead.
+func (*SellerReply) Descriptor() ([]byte, []int) {
+	return file_seller_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SellerReply) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SellerReply) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SellerReply) GetProducts() int64 {
+	if x != nil {
+		return x.Products
+	}
+	return 0
+}
+
+func (x *SellerReply) GetRating() *Rating {
+	if x != nil {
+		return x.Rating
+	}
+	return nil
+}
+
+type SellersReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Sellers []*SellerReply `protobuf:"bytes,1,rep,name=sellers,proto3" json:"sellers,omitempty"`
+}
+
+func (x *SellersReply) Reset() {
+	*x = SellersReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_seller_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SellersReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SellersReply) ProtoMessage() {}
+
+func (x *SellersReply) ProtoReflect() protoreflect.Message {
+	mi := &file_seller_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SellersReply.ProtoReflect.Descriptor instead.
+func (*SellersReply) Descriptor() ([]byte, []int) {
+	return file_seller_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SellersReply) GetSellers() []*SellerReply {
+	if x != nil {
+		return x.Sellers
+	}
+	return nil
+}
+
+var File_seller_proto protoreflect.FileDescriptor
+
+var file_seller_proto_rawDesc = []byte{
+	0x0a, 0x0c, 0x73, 0x65, 0x6c, 0x6c, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x02,
+	0x70, 0x62, 0x1a, 0x0d, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x22, 0x26, 0x0a, 0x12, 0x46, 0x69, 0x6e, 0x64, 0x53, 0x65, 0x6c, 0x6c, 0x65, 0x72, 0x73,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x69, 0x64, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x03, 0x69, 0x64, 0x73, 0x22, 0x71, 0x0a, 0x0b, 0x53, 0x65, 0x6c,
+	0x6c, 0x65, 0x72, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08,
+	0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08,
+	0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x73, 0x12, 0x22, 0x0a, 0x06, 0x72, 0x61, 0x74, 0x69,
+	0x6e, 0x67, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x61,
+	0x74, 0x69, 0x6e, 0x67, 0x52, 0x06, 0x72, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x22, 0x39, 0x0a, 0x0c,
+	0x53, 0x65, 0x6c, 0x6c, 0x65, 0x72, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x29, 0x0a, 0x07,
+	0x73, 0x65, 0x6c, 0x6c, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e,
+	0x70, 0x62, 0x2e, 0x53, 0x65, 0x6c, 0x6c, 0x65, 0x72, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x52, 0x07,
+	0x73, 0x65, 0x6c, 0x6c, 0x65, 0x72, 0x73, 0x32, 0x43, 0x0a, 0x0d, 0x53, 0x65, 0x6c, 0x6c, 0x65,
+	0x72, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x32, 0x0a, 0x04, 0x46, 0x69, 0x6e, 0x64,
+	0x12, 0x16, 0x2e, 0x70, 0x62, 0x2e, 0x46, 0x69, 0x6e, 0x64, 0x53, 0x65, 0x6c, 0x6c, 0x65, 0x72,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10, 0x2e, 0x70, 0x62, 0x2e, 0x53, 0x65,
+	0x6c, 0x6c, 0x65, 0x72, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x42, 0x09, 0x5a, 0x07,
+	0x2e, 0x2f, 0x70, 0x62, 0x3b, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_seller_proto_rawDescOnce sync.Once
+	file_seller_proto_rawDescData = file_seller_proto_rawDesc
+)
+
+func file_seller_proto_rawDescGZIP() []byte {
+	file_seller_proto_rawDescOnce.Do(func() {
+		file_seller_proto_rawDescData = protoimpl.X.CompressGZIP(file_seller_proto_rawDescData)
+	})
+	return file_seller_proto_rawDescData
+}
+
+var file_seller_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_seller_proto_goTypes = []interface{}{
+	(*FindSellersRequest)(nil), // 0: pb.FindSellersRequest
+	(*SellerReply)(nil),        // 1: pb.SellerReply
+	(*SellersReply)(nil),       // 2: pb.SellersReply
+	(*Rating)(nil),             // 3: pb.Rating
+}
+var file_seller_proto_depIdxs = []int32{
+	3, // 0: pb.SellerReply.rating:type_name -> pb.Rating
+	1, // 1: pb.SellersReply.sellers:type_name -> pb.SellerReply
+	0, // 2: pb.SellerService.Find:input_type -> pb.FindSellersRequest
+	2, // 3: pb.SellerService.Find:output_type -> pb.SellersReply
+	3, // [3:4] is the sub-list for method output_type
+	2, // [2:3] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_seller_proto_init() }
+func file_seller_proto_init() {
+	if File_seller_proto != nil {
+		return
+	}
+	file_product_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_seller_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FindSellersRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_seller_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SellerReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_seller_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SellersReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_seller_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_seller_proto_goTypes,
+		DependencyIndexes: file_seller_proto_depIdxs,
+		MessageInfos:      file_seller_proto_msgTypes,
+	}.Build()
+	File_seller_proto = out.File
+	file_seller_proto_rawDesc = nil
+	file_seller_proto_goTypes = nil
+	file_seller_proto_depIdxs = nil
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// SellerServiceClient is the client API for SellerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type SellerServiceClient interface {
+	// Find returns the sellers with the ids in the same order, up to 100 at
+	// once.
+	Find(ctx context.Context, in *FindSellersRequest, opts ...grpc.CallOption) (*SellersReply, error)
+}
+
+type sellerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSellerServiceClient(cc grpc.ClientConnInterface) SellerServiceClient {
+	return &sellerServiceClient{cc}
+}
+
+func (c *sellerServiceClient) Find(ctx context.Context, in *FindSellersRequest, opts ...grpc.CallOption) (*SellersReply, error) {
+	out := new(SellersReply)
+	err := c.cc.Invoke(ctx, "/pb.SellerService/Find", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SellerServiceServer is the server API for SellerService service.
+type SellerServiceServer interface {
+	// Find returns the sellers with the ids in the same order, up to 100 at
+	// once.
+	Find(context.Context, *FindSellersRequest) (*SellersReply, error)
+}
+
+// UnimplementedSellerServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedSellerServiceServer struct {
+}
+
+func (*UnimplementedSellerServiceServer) Find(context.Context, *FindSellersRequest) (*SellersReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Find not implemented")
+}
+
+func RegisterSellerServiceServer(s *grpc.Server, srv SellerServiceServer) {
+	s.RegisterService(&_SellerService_serviceDesc, srv)
+}
+
+func _SellerService_Find_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindSellersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SellerServiceServer).Find(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.SellerService/Find",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SellerServiceServer).Find(ctx, req.(*FindSellersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _SellerService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "pb.SellerService",
+	HandlerType: (*SellerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Find",
+			Handler:    _SellerService_Find_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "seller.proto",
+}
@@ -0,0 +1,128 @@
+package grpc
+
+import (
+	"context"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/review"
+	"google.golang.org/grpc"
+)
+
+// ReviewService implements review.Interface. It allows making calls to the
+// market gRPC server.
+type ReviewService struct {
+	AuthService AuthService
+
+	client pb.ReviewServiceClient
+}
+
+func NewReviewService(auth AuthService) *ReviewService {
+	return &ReviewService{AuthService: auth}
+}
+
+// Connect must be called before any usage of Client. It connects to the
+// market gRPC server at the provided address.
+func (s *ReviewService) Connect(ctx context.Context, addr string) error {
+	auth := AuthInterceptor{AuthService: s.AuthService}
+
+	conn, err := grpc.DialContext(
+		ctx, addr,
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(auth.UnaryClientInterceptor()),
+	)
+	if err != nil {
+		return err
+	}
+
+	s.client = pb.NewReviewServiceClient(conn)
+
+	return nil
+}
+
+// Find finds reviews of the product. The product filter is required by the
+// server, as reviews are served as a sub-resource of products.
+func (s *ReviewService) Find(ctx context.Context, r review.FindRequest) ([]*review.Review, error) {
+	req := &pb.FindReviewsRequest{
+		Offset: r.Offset,
+		Limit:  r.Limit,
+		Author: r.Author,
+	}
+	if r.Product != nil {
+		req.Product = *r.Product
+	}
+
+	rep, err := s.client.Find(ctx, req)
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return reviewsFromPB(rep), nil
+}
+
+func (s *ReviewService) FindOne(ctx context.Context, productID string, id string) (*review.Review, error) {
+	rep, err := s.client.FindOne(ctx, &pb.FindOneReviewRequest{Product: productID, Id: id})
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return reviewFromPB(rep), nil
+}
+
+func (s *ReviewService) Create(ctx context.Context, r review.CreateRequest) (*review.Review, error) {
+	req := &pb.CreateReviewRequest{
+		Product: r.Product,
+		Rating:  r.Rating,
+		Text:    r.Text,
+	}
+
+	rep, err := s.client.Create(ctx, req)
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return reviewFromPB(rep), nil
+}
+
+func (s *ReviewService) Update(ctx context.Context, r review.UpdateRequest) (*review.Review, error) {
+	req := &pb.UpdateReviewRequest{
+		Product: r.Product,
+		Id:      r.ID,
+		Rating:  r.Rating,
+		Text:    r.Text,
+	}
+
+	rep, err := s.client.Update(ctx, req)
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return reviewFromPB(rep), nil
+}
+
+func (s *ReviewService) Delete(ctx context.Context, productID string, id string) (*review.Review, error) {
+	rep, err := s.client.Delete(ctx, &pb.DeleteReviewRequest{Product: productID, Id: id})
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return reviewFromPB(rep), nil
+}
+
+func reviewFromPB(rep *pb.ReviewReply) *review.Review {
+	return &review.Review{
+		ID:        rep.Id,
+		Product:   rep.Product,
+		Author:    rep.Author,
+		Rating:    rep.Rating,
+		Text:      rep.Text,
+		CreatedAt: fromMillis(rep.CreatedAt),
+		UpdatedAt: fromMillis(rep.UpdatedAt),
+	}
+}
+
+func reviewsFromPB(rep *pb.ReviewsReply) []*review.Review {
+	rvs := make([]*review.Review, len(rep.Reviews))
+	for i, rv := range rep.Reviews {
+		rvs[i] = reviewFromPB(rv)
+	}
+	return rvs
+}
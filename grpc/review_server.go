@@ -0,0 +1,98 @@
+package grpc
+
+import (
+	"context"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/review"
+)
+
+// ReviewServer implements pb.ReviewServiceServer. It is registered by
+// Server.Run.
+type ReviewServer struct {
+	ReviewService review.Interface
+}
+
+func (s *ReviewServer) Find(ctx context.Context, r *pb.FindReviewsRequest) (*pb.ReviewsReply, error) {
+	fr := review.FindRequest{
+		Offset:  r.Offset,
+		Limit:   r.Limit,
+		Product: &r.Product,
+		Author:  r.Author,
+	}
+
+	rvs, err := s.ReviewService.Find(ctx, fr)
+	if err != nil {
+		return nil, err
+	}
+
+	return reviewsToPB(rvs), nil
+}
+
+func (s *ReviewServer) FindOne(ctx context.Context, r *pb.FindOneReviewRequest) (*pb.ReviewReply, error) {
+	rv, err := s.ReviewService.FindOne(ctx, r.Product, r.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return reviewToPB(rv), nil
+}
+
+func (s *ReviewServer) Create(ctx context.Context, r *pb.CreateReviewRequest) (*pb.ReviewReply, error) {
+	cr := review.CreateRequest{
+		Product: r.Product,
+		Rating:  r.Rating,
+		Text:    r.Text,
+	}
+
+	rv, err := s.ReviewService.Create(ctx, cr)
+	if err != nil {
+		return nil, err
+	}
+
+	return reviewToPB(rv), nil
+}
+
+func (s *ReviewServer) Update(ctx context.Context, r *pb.UpdateReviewRequest) (*pb.ReviewReply, error) {
+	ur := review.UpdateRequest{
+		Product: r.Product,
+		ID:      r.Id,
+		Rating:  r.Rating,
+		Text:    r.Text,
+	}
+
+	rv, err := s.ReviewService.Update(ctx, ur)
+	if err != nil {
+		return nil, err
+	}
+
+	return reviewToPB(rv), nil
+}
+
+func (s *ReviewServer) Delete(ctx context.Context, r *pb.DeleteReviewRequest) (*pb.ReviewReply, error) {
+	rv, err := s.ReviewService.Delete(ctx, r.Product, r.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return reviewToPB(rv), nil
+}
+
+func reviewToPB(rv *review.Review) *pb.ReviewReply {
+	return &pb.ReviewReply{
+		Id:        rv.ID,
+		Product:   rv.Product,
+		Author:    rv.Author,
+		Rating:    rv.Rating,
+		Text:      rv.Text,
+		CreatedAt: toMillis(rv.CreatedAt),
+		UpdatedAt: toMillis(rv.UpdatedAt),
+	}
+}
+
+func reviewsToPB(rvs []*review.Review) *pb.ReviewsReply {
+	rep := &pb.ReviewsReply{Reviews: make([]*pb.ReviewReply, len(rvs))}
+	for i, rv := range rvs {
+		rep.Reviews[i] = reviewToPB(rv)
+	}
+	return rep
+}
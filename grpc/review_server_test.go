@@ -0,0 +1,103 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/review"
+	"github.com/ortymid/market/mock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReviewServer_Find(t *testing.T) {
+	createdAt := time.Date(2020, 10, 1, 12, 0, 0, int(5*time.Millisecond), time.UTC)
+	millis := createdAt.UnixNano() / int64(time.Millisecond)
+	productID, author := "1", "2"
+
+	tests := []struct {
+		name       string
+		r          *pb.FindReviewsRequest
+		setupMocks func(rs *mock.ReviewService)
+		want       *pb.ReviewsReply
+		wantErr    bool
+	}{
+		{
+			name: "Should find reviews of product",
+			r:    &pb.FindReviewsRequest{Product: productID, Offset: 0, Limit: 10, Author: &author},
+			setupMocks: func(rs *mock.ReviewService) {
+				rs.EXPECT().Find(
+					gomock.Any(),
+					review.FindRequest{Offset: 0, Limit: 10, Product: &productID, Author: &author},
+				).Return([]*review.Review{{
+					ID: "1", Product: "1", Author: "2", Rating: 4, Text: "Tasty", CreatedAt: createdAt, UpdatedAt: createdAt,
+				}}, nil)
+			},
+			want: &pb.ReviewsReply{Reviews: []*pb.ReviewReply{{
+				Id: "1", Product: "1", Author: "2", Rating: 4, Text: "Tasty", CreatedAt: millis, UpdatedAt: millis,
+			}}},
+		},
+		{
+			name: "Should return error for invalid request",
+			r:    &pb.FindReviewsRequest{Product: productID, Limit: -1},
+			setupMocks: func(rs *mock.ReviewService) {
+				rs.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Return(nil, review.ErrValidation{Resource: review.Resource, Field: "limit", Reason: "must not be negative"})
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			rs := mock.NewReviewService(ctrl)
+			tt.setupMocks(rs)
+
+			s := &ReviewServer{ReviewService: rs}
+			got, err := s.Find(context.Background(), tt.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Find() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReviewServer_Update(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rating := int64(3)
+	rs := mock.NewReviewService(ctrl)
+	rs.EXPECT().Update(
+		gomock.Any(),
+		review.UpdateRequest{Product: "1", ID: "2", Rating: &rating},
+	).Return(nil, review.ErrNotFound)
+
+	s := &ReviewServer{ReviewService: rs}
+	_, err := s.Update(context.Background(), &pb.UpdateReviewRequest{Product: "1", Id: "2", Rating: &rating})
+	if !errors.Is(err, review.ErrNotFound) {
+		t.Errorf("Update() error = %v, want %v", err, review.ErrNotFound)
+	}
+}
+
+func TestReviewFromPB(t *testing.T) {
+	at := time.Date(2020, 10, 1, 12, 0, 0, int(5*time.Millisecond), time.UTC)
+	want := &review.Review{
+		ID: "1", Product: "1", Author: "2", Rating: 4, Text: "Tasty",
+		CreatedAt: at,
+		UpdatedAt: at.Add(time.Hour),
+	}
+
+	got := reviewFromPB(reviewToPB(want))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reviewFromPB() got = %v, want %v", got, want)
+	}
+}
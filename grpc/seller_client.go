@@ -0,0 +1,66 @@
+package grpc
+
+import (
+	"context"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/seller"
+	"google.golang.org/grpc"
+)
+
+// SellerService implements seller.Interface. It allows making calls to the
+// market gRPC server.
+type SellerService struct {
+	AuthService AuthService
+
+	client pb.SellerServiceClient
+}
+
+func NewSellerService(auth AuthService) *SellerService {
+	return &SellerService{AuthService: auth}
+}
+
+// Connect must be called before any usage of Client. It connects to the
+// market gRPC server at the provided address.
+func (s *SellerService) Connect(ctx context.Context, addr string) error {
+	auth := AuthInterceptor{AuthService: s.AuthService}
+
+	conn, err := grpc.DialContext(
+		ctx, addr,
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(auth.UnaryClientInterceptor()),
+	)
+	if err != nil {
+		return err
+	}
+
+	s.client = pb.NewSellerServiceClient(conn)
+
+	return nil
+}
+
+func (s *SellerService) Find(ctx context.Context, ids []string) ([]*seller.Seller, error) {
+	rep, err := s.client.Find(ctx, &pb.FindSellersRequest{Ids: ids})
+	if err != nil {
+		return nil, errorFromStatus(err)
+	}
+
+	return sellersFromPB(rep), nil
+}
+
+func sellerFromPB(rep *pb.SellerReply) *seller.Seller {
+	return &seller.Seller{
+		ID:       rep.Id,
+		Name:     rep.Name,
+		Products: rep.Products,
+		Rating:   product.Rating{Average: rep.GetRating().GetAverage(), Count: rep.GetRating().GetCount()},
+	}
+}
+
+func sellersFromPB(rep *pb.SellersReply) []*seller.Seller {
+	sellers := make([]*seller.Seller, len(rep.Sellers))
+	for i, sl := range rep.Sellers {
+		sellers[i] = sellerFromPB(sl)
+	}
+	return sellers
+}
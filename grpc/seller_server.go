@@ -0,0 +1,39 @@
+package grpc
+
+import (
+	"context"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/seller"
+)
+
+// SellerServer implements pb.SellerServiceServer. It is registered by
+// Server.Run.
+type SellerServer struct {
+	SellerService seller.Interface
+}
+
+func (s *SellerServer) Find(ctx context.Context, r *pb.FindSellersRequest) (*pb.SellersReply, error) {
+	sellers, err := s.SellerService.Find(ctx, r.Ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return sellersToPB(sellers), nil
+}
+
+func sellerToPB(sl *seller.Seller) *pb.SellerReply {
+	return &pb.SellerReply{
+		Id:       sl.ID,
+		Name:     sl.Name,
+		Products: sl.Products,
+		Rating:   ratingToPB(sl.Rating),
+	}
+}
+
+func sellersToPB(sellers []*seller.Seller) *pb.SellersReply {
+	rep := &pb.SellersReply{Sellers: make([]*pb.SellerReply, len(sellers))}
+	for i, sl := range sellers {
+		rep.Sellers[i] = sellerToPB(sl)
+	}
+	return rep
+}
@@ -0,0 +1,74 @@
+package grpc
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/seller"
+	"github.com/ortymid/market/mock"
+	"reflect"
+	"testing"
+)
+
+func TestSellerServer_Find(t *testing.T) {
+	tests := []struct {
+		name       string
+		r          *pb.FindSellersRequest
+		setupMocks func(ss *mock.SellerService)
+		want       *pb.SellersReply
+		wantErr    bool
+	}{
+		{
+			name: "Should find sellers",
+			r:    &pb.FindSellersRequest{Ids: []string{"1", "2"}},
+			setupMocks: func(ss *mock.SellerService) {
+				ss.EXPECT().Find(gomock.Any(), []string{"1", "2"}).Return([]*seller.Seller{
+					{ID: "1", Name: "Alice", Products: 2, Rating: product.Rating{Average: 4.5, Count: 2}},
+					{ID: "2"},
+				}, nil)
+			},
+			want: &pb.SellersReply{Sellers: []*pb.SellerReply{
+				{Id: "1", Name: "Alice", Products: 2, Rating: &pb.Rating{Average: 4.5, Count: 2}},
+				{Id: "2"},
+			}},
+		},
+		{
+			name: "Should return error for too many ids",
+			r:    &pb.FindSellersRequest{Ids: []string{"1"}},
+			setupMocks: func(ss *mock.SellerService) {
+				ss.EXPECT().Find(gomock.Any(), gomock.Any()).
+					Return(nil, seller.ErrValidation{Resource: seller.Resource, Field: "ids", Reason: "must not be more than 100"})
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ss := mock.NewSellerService(ctrl)
+			tt.setupMocks(ss)
+
+			s := &SellerServer{SellerService: ss}
+			got, err := s.Find(context.Background(), tt.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Find() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSellerFromPB(t *testing.T) {
+	want := &seller.Seller{ID: "1", Name: "Alice", Products: 2, Rating: product.Rating{Average: 4.5, Count: 2}}
+
+	got := sellerFromPB(sellerToPB(want))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sellerFromPB() got = %v, want %v", got, want)
+	}
+}
@@ -3,46 +3,82 @@ package grpc
 import (
 	"context"
 	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/audit"
+	"github.com/ortymid/market/market/cart"
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/market/order"
 	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/review"
+	"github.com/ortymid/market/market/seller"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 	"net"
+	"strconv"
+)
+
+// Trailer keys of the listing metadata sent by Find.
+const (
+	// nextPageTokenKey is the token continuing the listing, it is sent only if
+	// there are more products.
+	nextPageTokenKey = "next-page-token"
+	// totalCountKey is the number of products matching the request filters.
+	totalCountKey = "total-count"
+	// totalEstimatedKey is sent as "true" if the total count is an estimate.
+	totalEstimatedKey = "total-estimated"
 )
 
 type Server struct {
-	AuthService    AuthService
-	ProductService product.Interface
+	AuthService     AuthService
+	ProductService  product.Interface
+	CategoryService category.Interface
+	OrderService    order.Interface
+	CartService     cart.Interface
+	ReviewService   review.Interface
+	SellerService   seller.Interface
 }
 
 func (s *Server) Find(r *pb.FindRequest, stream pb.ProductService_FindServer) error {
-	ctx := context.TODO()
+	ctx := stream.Context()
 
 	var priceRange *product.PriceRange
 	if r.PriceRange != nil {
 		priceRange = &product.PriceRange{
-			From: r.PriceRange.From,
-			To:   r.PriceRange.To,
+			From:     r.PriceRange.From,
+			To:       r.PriceRange.To,
+			Currency: r.PriceRange.Currency,
 		}
 	}
 
 	fr := product.FindRequest{
-		Offset:     r.Offset,
-		Limit:      r.Limit,
-		Name:       r.Name,
-		PriceRange: priceRange,
+		Offset:             r.Offset,
+		Limit:              r.Limit,
+		Name:               r.Name,
+		PriceRange:         priceRange,
+		Seller:             r.Seller,
+		Categories:         r.Categories,
+		Attributes:         r.Attributes,
+		Sort:               sortsFromPB(r.Sort),
+		Cursor:             r.PageToken,
+		IncludeDescendants: r.IncludeDescendants,
+		InStock:            r.InStock,
+		MinRating:          r.MinRating,
+		IncludeDeleted:     r.IncludeDeleted,
+		DisplayCurrency:    r.DisplayCurrency,
+	}
+	if r.Status != nil {
+		status := product.Status(*r.Status)
+		fr.Status = &status
 	}
 
-	ps, err := s.ProductService.Find(ctx, fr)
+	res, err := s.ProductService.Find(ctx, fr)
 	if err != nil {
-		return nil
+		return err
 	}
 
-	for _, p := range ps {
-		rep := &pb.ProductReply{
-			Id:     p.ID,
-			Name:   p.Name,
-			Price:  p.Price,
-			Seller: p.Seller,
-		}
+	stream.SetTrailer(trailerFromResult(res))
+
+	for _, p := range res.Products {
+		rep := productToPB(p)
 		if err := stream.Send(rep); err != nil {
 			return err
 		}
@@ -51,25 +87,47 @@ func (s *Server) Find(r *pb.FindRequest, stream pb.ProductService_FindServer) er
 	return nil
 }
 
+// trailerFromResult makes the Find trailer with the listing metadata.
+func trailerFromResult(res *product.FindResult) metadata.MD {
+	md := metadata.Pairs(totalCountKey, strconv.FormatInt(res.Total, 10))
+	if res.TotalEstimated {
+		md.Set(totalEstimatedKey, "true")
+	}
+	if res.NextCursor != "" {
+		md.Set(nextPageTokenKey, res.NextCursor)
+	}
+	return md
+}
+
 func (s *Server) FindOne(ctx context.Context, r *pb.FindOneRequest) (*pb.ProductReply, error) {
-	p, err := s.ProductService.FindOne(ctx, r.Id)
+	var p *product.Product
+	var err error
+	if r.DisplayCurrency != "" {
+		p, err = s.ProductService.FindOneIn(ctx, r.Id, r.DisplayCurrency)
+	} else {
+		p, err = s.ProductService.FindOne(ctx, r.Id)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	rep := &pb.ProductReply{
-		Id:     p.ID,
-		Name:   p.Name,
-		Price:  p.Price,
-		Seller: p.Seller,
-	}
+	rep := productToPB(p)
 	return rep, nil
 }
 
 func (s *Server) Create(ctx context.Context, r *pb.CreateRequest) (*pb.ProductReply, error) {
 	cr := product.CreateRequest{
-		Name:  r.Name,
-		Price: r.Price,
+		Name:       r.Name,
+		Price:      moneyFromPB(r.Price),
+		Categories: r.Categories,
+		Attributes: attributesFromPB(r.Attributes),
+		Variants:   variantsFromPB(r.Variants),
+
+		Stock:             r.Stock,
+		LowStockThreshold: r.LowStockThreshold,
+
+		Status:    product.Status(r.Status),
+		PublishAt: fromMillisPtr(r.PublishAt),
 	}
 
 	p, err := s.ProductService.Create(ctx, cr)
@@ -77,12 +135,7 @@ func (s *Server) Create(ctx context.Context, r *pb.CreateRequest) (*pb.ProductRe
 		return nil, err
 	}
 
-	rep := &pb.ProductReply{
-		Id:     p.ID,
-		Name:   p.Name,
-		Price:  p.Price,
-		Seller: p.Seller,
-	}
+	rep := productToPB(p)
 	return rep, nil
 }
 
@@ -90,7 +143,37 @@ func (s *Server) Update(ctx context.Context, r *pb.UpdateRequest) (*pb.ProductRe
 	ur := product.UpdateRequest{
 		ID:    r.Id,
 		Name:  r.Name,
-		Price: r.Price,
+		Price: moneyFromPBPtr(r.Price),
+
+		LowStockThreshold: r.LowStockThreshold,
+		ExpectedVersion:   r.ExpectedVersion,
+
+		PublishAt: fromMillisPtr(r.PublishAt),
+	}
+	if r.Status != nil {
+		status := product.Status(*r.Status)
+		ur.Status = &status
+	}
+	if r.Categories != nil {
+		categories := r.Categories.Ids
+		if categories == nil {
+			categories = []string{}
+		}
+		ur.Categories = &categories
+	}
+	if r.Attributes != nil {
+		attrs := attributesFromPB(r.Attributes.Attributes)
+		if attrs == nil {
+			attrs = []product.Attribute{}
+		}
+		ur.Attributes = &attrs
+	}
+	if r.Variants != nil {
+		variants := variantsFromPB(r.Variants.Variants)
+		if variants == nil {
+			variants = []product.Variant{}
+		}
+		ur.Variants = &variants
 	}
 
 	p, err := s.ProductService.Update(ctx, ur)
@@ -98,12 +181,7 @@ func (s *Server) Update(ctx context.Context, r *pb.UpdateRequest) (*pb.ProductRe
 		return nil, err
 	}
 
-	rep := &pb.ProductReply{
-		Id:     p.ID,
-		Name:   p.Name,
-		Price:  p.Price,
-		Seller: p.Seller,
-	}
+	rep := productToPB(p)
 	return rep, nil
 }
 
@@ -113,23 +191,118 @@ func (s *Server) Delete(ctx context.Context, r *pb.DeleteRequest) (*pb.ProductRe
 		return nil, err
 	}
 
-	rep := &pb.ProductReply{
-		Id:     p.ID,
-		Name:   p.Name,
-		Price:  p.Price,
-		Seller: p.Seller,
+	rep := productToPB(p)
+	return rep, nil
+}
+
+func (s *Server) Restore(ctx context.Context, r *pb.RestoreRequest) (*pb.ProductReply, error) {
+	p, err := s.ProductService.Restore(ctx, r.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := productToPB(p)
+	return rep, nil
+}
+
+func (s *Server) AdjustStock(ctx context.Context, r *pb.AdjustStockRequest) (*pb.ProductReply, error) {
+	p, err := s.ProductService.AdjustStock(ctx, r.Id, r.Variant, r.Delta)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := productToPB(p)
+	return rep, nil
+}
+
+func (s *Server) History(ctx context.Context, r *pb.HistoryRequest) (*pb.HistoryReply, error) {
+	es, err := s.ProductService.History(ctx, audit.FindRequest{Product: r.Id, Offset: r.Offset, Limit: r.Limit})
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &pb.HistoryReply{Entries: make([]*pb.AuditEntry, 0, len(es))}
+	for _, e := range es {
+		rep.Entries = append(rep.Entries, entryToPB(e))
 	}
 	return rep, nil
 }
 
+func entryToPB(e *audit.Entry) *pb.AuditEntry {
+	pe := &pb.AuditEntry{
+		Id:        e.ID,
+		Product:   e.Product,
+		Seller:    e.Seller,
+		Actor:     e.Actor,
+		Operation: string(e.Operation),
+		Version:   e.Version,
+		Changes:   make([]*pb.AuditChange, 0, len(e.Changes)),
+		CreatedAt: toMillis(e.CreatedAt),
+	}
+	for _, c := range e.Changes {
+		pe.Changes = append(pe.Changes, &pb.AuditChange{Field: c.Field, Before: c.Before, After: c.After})
+	}
+	return pe
+}
+
+func productToPB(p *product.Product) *pb.ProductReply {
+	rep := &pb.ProductReply{
+		Id:         p.ID,
+		Name:       p.Name,
+		Price:      moneyToPB(p.Price),
+		Seller:     p.Seller,
+		Categories: p.Categories,
+		Media:      mediaToPB(p.Media),
+		Attributes: attributesToPB(p.Attributes),
+		Variants:   variantsToPB(p.Variants),
+
+		DisplayPrice: moneyToPBPtr(p.DisplayPrice),
+
+		Stock:             p.Stock,
+		Reserved:          p.Reserved,
+		LowStockThreshold: p.LowStockThreshold,
+		LowStock:          p.LowStock(),
+
+		Status:    string(p.Status),
+		PublishAt: toMillisPtr(p.PublishAt),
+
+		Rating: ratingToPB(p.Rating),
+
+		Version:   p.Version,
+		DeletedAt: toMillisPtr(p.DeletedAt),
+	}
+	return rep
+}
+
+// ratingToPB leaves the rating of unreviewed products unset.
+func ratingToPB(r product.Rating) *pb.Rating {
+	if r.Count == 0 {
+		return nil
+	}
+	return &pb.Rating{Average: r.Average, Count: r.Count}
+}
+
 func (s *Server) Run(addr string) error {
 	auth := AuthInterceptor{AuthService: s.AuthService}
 
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(auth.UnaryServerInterceptor()),
-		grpc.StreamInterceptor(auth.StreamServerInterceptor()),
+		grpc.ChainUnaryInterceptor(
+			RecoveryUnaryServerInterceptor(),
+			ErrorUnaryServerInterceptor(),
+			auth.UnaryServerInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			RecoveryStreamServerInterceptor(),
+			ErrorStreamServerInterceptor(),
+			auth.StreamServerInterceptor(),
+		),
 	)
 	pb.RegisterProductServiceServer(grpcServer, s)
+	pb.RegisterCategoryServiceServer(grpcServer, &CategoryServer{CategoryService: s.CategoryService})
+	pb.RegisterOrderServiceServer(grpcServer, &OrderServer{OrderService: s.OrderService})
+	pb.RegisterCartServiceServer(grpcServer, &CartServer{CartService: s.CartService})
+	pb.RegisterReviewServiceServer(grpcServer, &ReviewServer{ReviewService: s.ReviewService})
+	pb.RegisterSellerServiceServer(grpcServer, &SellerServer{SellerService: s.SellerService})
 
 	ln, err := net.Listen("tcp", addr)
 	if err != nil {
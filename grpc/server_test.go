@@ -2,24 +2,32 @@ package grpc
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/ortymid/market/grpc/grpctest"
 	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/audit"
+	"github.com/ortymid/market/market/money"
 	"github.com/ortymid/market/market/product"
 	"github.com/ortymid/market/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type setupMocks func(as *mock.GRPCAuthService, ps *mock.ProductService)
 
 func TestServer_List(t *testing.T) {
 	tests := []struct {
-		name       string
-		req        *pb.FindRequest
-		setupMocks setupMocks
-		wantStream []*pb.ProductReply
-		wantErr    bool
+		name        string
+		req         *pb.FindRequest
+		setupMocks  setupMocks
+		wantStream  []*pb.ProductReply
+		wantTrailer metadata.MD
+		wantErr     bool
 	}{
 		{
 			name: "Should stream products for offset=0 and limit=2",
@@ -31,17 +39,133 @@ func TestServer_List(t *testing.T) {
 				ps.EXPECT().Find(
 					gomock.Any(), product.FindRequest{Offset: 0, Limit: 2},
 				).Return(
-					[]*product.Product{
-						{ID: "1", Name: "p1", Price: 100, Seller: "1"},
-						{ID: "2", Name: "p2", Price: 200, Seller: "2"},
+					&product.FindResult{
+						Products: []*product.Product{
+							{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"},
+							{ID: "2", Name: "p2", Price: money.New(200, "USD"), Seller: "2"},
+						},
+						Total: 2,
+					},
+					nil,
+				)
+			},
+			wantStream: []*pb.ProductReply{
+				{Id: "1", Name: "p1", Price: &pb.Money{Amount: 100, Currency: "USD"}, Seller: "1"},
+				{Id: "2", Name: "p2", Price: &pb.Money{Amount: 200, Currency: "USD"}, Seller: "2"},
+			},
+			wantTrailer: metadata.Pairs("total-count", "2"),
+		},
+		{
+			name: "Should stream products for page token and send next page token",
+			req: &pb.FindRequest{
+				Limit:     1,
+				PageToken: "token1",
+			},
+			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
+				ps.EXPECT().Find(
+					gomock.Any(), product.FindRequest{Limit: 1, Cursor: "token1"},
+				).Return(
+					&product.FindResult{
+						Products: []*product.Product{
+							{ID: "2", Name: "p2", Price: money.New(200, "USD"), Seller: "2"},
+						},
+						Total:          3,
+						TotalEstimated: true,
+						HasMore:        true,
+						NextCursor:     "token2",
 					},
 					nil,
 				)
 			},
 			wantStream: []*pb.ProductReply{
-				{Id: "1", Name: "p1", Price: 100, Seller: "1"},
-				{Id: "2", Name: "p2", Price: 200, Seller: "2"},
+				{Id: "2", Name: "p2", Price: &pb.Money{Amount: 200, Currency: "USD"}, Seller: "2"},
+			},
+			wantTrailer: metadata.Pairs(
+				"total-count", "3",
+				"total-estimated", "true",
+				"next-page-token", "token2",
+			),
+		},
+		{
+			name: "Should stream rated products sorted by rating",
+			req: &pb.FindRequest{
+				Limit:     1,
+				MinRating: testFloat64Ptr(4),
+				Sort:      []*pb.Sort{{Key: pb.Sort_RATING, Desc: true}},
+			},
+			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
+				ps.EXPECT().Find(
+					gomock.Any(), product.FindRequest{
+						Limit:     1,
+						MinRating: testFloat64Ptr(4),
+						Sort:      []product.Sort{{Key: product.SortKeyRating, Desc: true}},
+					},
+				).Return(
+					&product.FindResult{
+						Products: []*product.Product{
+							{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", Rating: product.Rating{Average: 4.5, Count: 2}},
+						},
+						Total: 1,
+					},
+					nil,
+				)
+			},
+			wantStream: []*pb.ProductReply{
+				{
+					Id: "1", Name: "p1", Price: &pb.Money{Amount: 100, Currency: "USD"}, Seller: "1",
+					Rating: &pb.Rating{Average: 4.5, Count: 2},
+				},
+			},
+			wantTrailer: metadata.Pairs("total-count", "1"),
+		},
+		{
+			name: "Should stream products with display prices",
+			req: &pb.FindRequest{
+				Limit:           1,
+				PriceRange:      &pb.PriceRange{To: testInt64Ptr(100)},
+				DisplayCurrency: "EUR",
+			},
+			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
+				displayPrice := money.New(85, "EUR")
+				ps.EXPECT().Find(
+					gomock.Any(), product.FindRequest{
+						Limit:           1,
+						PriceRange:      &product.PriceRange{To: testInt64Ptr(100)},
+						DisplayCurrency: "EUR",
+					},
+				).Return(
+					&product.FindResult{
+						Products: []*product.Product{
+							{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", DisplayPrice: &displayPrice},
+						},
+						Total: 1,
+					},
+					nil,
+				)
+			},
+			wantStream: []*pb.ProductReply{
+				{
+					Id: "1", Name: "p1", Price: &pb.Money{Amount: 100, Currency: "USD"}, Seller: "1",
+					DisplayPrice: &pb.Money{Amount: 85, Currency: "EUR"},
+				},
+			},
+			wantTrailer: metadata.Pairs("total-count", "1"),
+		},
+		{
+			name: "Should return error when service fails",
+			req: &pb.FindRequest{
+				Offset: 0,
+				Limit:  2,
+			},
+			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
+				ps.EXPECT().Find(
+					gomock.Any(), product.FindRequest{Offset: 0, Limit: 2},
+				).Return(
+					nil,
+					errors.New("test error"),
+				)
 			},
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
@@ -70,6 +194,9 @@ func TestServer_List(t *testing.T) {
 			if !reflect.DeepEqual(stream.Stream, tt.wantStream) {
 				t.Errorf("Find() stream = %v, wantStream %v", stream.Stream, tt.wantStream)
 			}
+			if !reflect.DeepEqual(stream.Trailer, tt.wantTrailer) {
+				t.Errorf("Find() trailer = %v, wantTrailer %v", stream.Trailer, tt.wantTrailer)
+			}
 		})
 	}
 }
@@ -94,9 +221,25 @@ func TestServer_Get(t *testing.T) {
 			},
 			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
 				ps.EXPECT().FindOne(gomock.Any(), "1").
-					Return(&product.Product{ID: "1", Name: "p1", Price: 100, Seller: "1"}, nil)
+					Return(&product.Product{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"}, nil)
+			},
+			want: &pb.ProductReply{Id: "1", Name: "p1", Price: &pb.Money{Amount: 100, Currency: "USD"}, Seller: "1"},
+		},
+		{
+			name: "Should reply with product priced in display currency",
+			args: args{
+				ctx: context.Background(),
+				r:   &pb.FindOneRequest{Id: "1", DisplayCurrency: "EUR"},
+			},
+			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
+				displayPrice := money.New(85, "EUR")
+				ps.EXPECT().FindOneIn(gomock.Any(), "1", "EUR").
+					Return(&product.Product{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", DisplayPrice: &displayPrice}, nil)
+			},
+			want: &pb.ProductReply{
+				Id: "1", Name: "p1", Price: &pb.Money{Amount: 100, Currency: "USD"}, Seller: "1",
+				DisplayPrice: &pb.Money{Amount: 85, Currency: "EUR"},
 			},
-			want: &pb.ProductReply{Id: "1", Name: "p1", Price: 100, Seller: "1"},
 		},
 	}
 	for _, tt := range tests {
@@ -143,18 +286,54 @@ func TestServer_Create(t *testing.T) {
 			name: "Should create product",
 			args: args{
 				ctx: context.Background(),
-				r:   &pb.CreateRequest{Name: "p1", Price: 100},
+				r:   &pb.CreateRequest{Name: "p1", Price: &pb.Money{Amount: 100, Currency: "USD"}},
 			},
 			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
 				ps.EXPECT().Create(
 					gomock.Any(),
 					product.CreateRequest{
 						Name:  "p1",
-						Price: 100,
+						Price: money.New(100, "USD"),
 					}).
-					Return(&product.Product{ID: "1", Name: "p1", Price: 100, Seller: "1"}, nil)
+					Return(&product.Product{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"}, nil)
+			},
+			want: &pb.ProductReply{Id: "1", Name: "p1", Price: &pb.Money{Amount: 100, Currency: "USD"}, Seller: "1"},
+		},
+		{
+			name: "Should create product with variants",
+			args: args{
+				ctx: context.Background(),
+				r: &pb.CreateRequest{
+					Name:       "shirt",
+					Price:      &pb.Money{Amount: 100, Currency: "USD"},
+					Attributes: []*pb.Attribute{{Name: "size", Type: "number"}},
+					Variants: []*pb.Variant{
+						{Sku: "shirt-42", Attributes: map[string]string{"size": "42"}, Price: &pb.Money{Amount: 120, Currency: "USD"}, Stock: 2},
+					},
+				},
+			},
+			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
+				attrs := []product.Attribute{{Name: "size", Type: product.AttributeNumber}}
+				variants := []product.Variant{
+					{SKU: "shirt-42", Attributes: map[string]string{"size": "42"}, Price: money.New(120, "USD"), Stock: 2},
+				}
+				ps.EXPECT().Create(
+					gomock.Any(),
+					product.CreateRequest{
+						Name:       "shirt",
+						Price:      money.New(100, "USD"),
+						Attributes: attrs,
+						Variants:   variants,
+					}).
+					Return(&product.Product{ID: "1", Name: "shirt", Price: money.New(100, "USD"), Seller: "1", Attributes: attrs, Variants: variants}, nil)
+			},
+			want: &pb.ProductReply{
+				Id: "1", Name: "shirt", Price: &pb.Money{Amount: 100, Currency: "USD"}, Seller: "1",
+				Attributes: []*pb.Attribute{{Name: "size", Type: "number"}},
+				Variants: []*pb.Variant{
+					{Sku: "shirt-42", Attributes: map[string]string{"size": "42"}, Price: &pb.Money{Amount: 120, Currency: "USD"}, Stock: 2},
+				},
 			},
-			want: &pb.ProductReply{Id: "1", Name: "p1", Price: 100, Seller: "1"},
 		},
 	}
 	for _, tt := range tests {
@@ -210,9 +389,98 @@ func TestServer_Update(t *testing.T) {
 						ID:   "1",
 						Name: testStringPtr("p2"),
 					},
-				).Return(&product.Product{ID: "1", Name: "p2", Price: 100, Seller: "1"}, nil)
+				).Return(&product.Product{ID: "1", Name: "p2", Price: money.New(100, "USD"), Seller: "1"}, nil)
 			},
-			want: &pb.ProductReply{Id: "1", Name: "p2", Price: 100, Seller: "1"},
+			want: &pb.ProductReply{Id: "1", Name: "p2", Price: &pb.Money{Amount: 100, Currency: "USD"}, Seller: "1"},
+		},
+		{
+			name: "Should update product categories",
+			args: args{
+				ctx: context.Background(),
+				r:   &pb.UpdateRequest{Id: "1", Categories: &pb.CategoryIds{Ids: []string{"2"}}},
+			},
+			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
+				ps.EXPECT().Update(
+					gomock.Any(),
+					product.UpdateRequest{
+						ID:         "1",
+						Categories: &[]string{"2"},
+					},
+				).Return(&product.Product{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", Categories: []string{"2"}}, nil)
+			},
+			want: &pb.ProductReply{Id: "1", Name: "p1", Price: &pb.Money{Amount: 100, Currency: "USD"}, Seller: "1", Categories: []string{"2"}},
+		},
+		{
+			name: "Should remove product categories",
+			args: args{
+				ctx: context.Background(),
+				r:   &pb.UpdateRequest{Id: "1", Categories: &pb.CategoryIds{}},
+			},
+			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
+				ps.EXPECT().Update(
+					gomock.Any(),
+					product.UpdateRequest{
+						ID:         "1",
+						Categories: &[]string{},
+					},
+				).Return(&product.Product{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"}, nil)
+			},
+			want: &pb.ProductReply{Id: "1", Name: "p1", Price: &pb.Money{Amount: 100, Currency: "USD"}, Seller: "1"},
+		},
+		{
+			name: "Should update product of expected version",
+			args: args{
+				ctx: context.Background(),
+				r:   &pb.UpdateRequest{Id: "1", Name: testStringPtr("p2"), ExpectedVersion: testInt64Ptr(2)},
+			},
+			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
+				ps.EXPECT().Update(
+					gomock.Any(),
+					product.UpdateRequest{
+						ID:              "1",
+						Name:            testStringPtr("p2"),
+						ExpectedVersion: testInt64Ptr(2),
+					},
+				).Return(&product.Product{ID: "1", Name: "p2", Price: money.New(100, "USD"), Seller: "1", Version: 3}, nil)
+			},
+			want: &pb.ProductReply{Id: "1", Name: "p2", Price: &pb.Money{Amount: 100, Currency: "USD"}, Seller: "1", Version: 3},
+		},
+		{
+			name: "Should schedule draft",
+			args: args{
+				ctx: context.Background(),
+				r:   &pb.UpdateRequest{Id: "1", Status: testStringPtr("draft"), PublishAt: testInt64Ptr(1601553600000)},
+			},
+			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
+				publishAt := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+				draft := product.StatusDraft
+				ps.EXPECT().Update(
+					gomock.Any(),
+					product.UpdateRequest{ID: "1", Status: &draft, PublishAt: &publishAt},
+				).Return(&product.Product{
+					ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", Version: 2,
+					Status: draft, PublishAt: &publishAt,
+				}, nil)
+			},
+			want: &pb.ProductReply{
+				Id: "1", Name: "p1", Price: &pb.Money{Amount: 100, Currency: "USD"}, Seller: "1", Version: 2,
+				Status: "draft", PublishAt: testInt64Ptr(1601553600000),
+			},
+		},
+		{
+			name: "Should update price with currency",
+			args: args{
+				ctx: context.Background(),
+				r:   &pb.UpdateRequest{Id: "1", Price: &pb.Money{Amount: 1200, Currency: "EUR"}},
+			},
+			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
+				price := money.New(1200, "EUR")
+				ps.EXPECT().Update(
+					gomock.Any(),
+					product.UpdateRequest{ID: "1", Price: &price},
+				).Return(&product.Product{ID: "1", Name: "p1", Price: price, Seller: "1", Version: 2}, nil)
+			},
+			want: &pb.ProductReply{Id: "1", Name: "p1", Price: &pb.Money{Amount: 1200, Currency: "EUR"}, Seller: "1", Version: 2},
 		},
 	}
 	for _, tt := range tests {
@@ -262,10 +530,11 @@ func TestServer_Delete(t *testing.T) {
 				r:   &pb.DeleteRequest{Id: "1"},
 			},
 			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
+				deletedAt := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
 				ps.EXPECT().Delete(gomock.Any(), "1").
-					Return(&product.Product{ID: "1", Name: "p2", Price: 100, Seller: "1"}, nil)
+					Return(&product.Product{ID: "1", Name: "p2", Price: money.New(100, "USD"), Seller: "1", DeletedAt: &deletedAt}, nil)
 			},
-			want: &pb.ProductReply{Id: "1", Name: "p2", Price: 100, Seller: "1"},
+			want: &pb.ProductReply{Id: "1", Name: "p2", Price: &pb.Money{Amount: 100, Currency: "USD"}, Seller: "1", DeletedAt: testInt64Ptr(1601553600000)},
 		},
 	}
 	for _, tt := range tests {
@@ -296,6 +565,98 @@ func TestServer_Delete(t *testing.T) {
 	}
 }
 
+func TestServer_Restore(t *testing.T) {
+	type args struct {
+		ctx context.Context
+		r   *pb.RestoreRequest
+	}
+	tests := []struct {
+		name       string
+		args       args
+		setupMocks setupMocks
+		want       *pb.ProductReply
+		wantCode   codes.Code
+	}{
+		{
+			name: "Should restore product",
+			args: args{
+				ctx: context.Background(),
+				r:   &pb.RestoreRequest{Id: "1"},
+			},
+			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
+				ps.EXPECT().Restore(gomock.Any(), "1").
+					Return(&product.Product{ID: "1", Name: "p2", Price: money.New(100, "USD"), Seller: "1", Version: 1}, nil)
+			},
+			want:     &pb.ProductReply{Id: "1", Name: "p2", Price: &pb.Money{Amount: 100, Currency: "USD"}, Seller: "1", Version: 1},
+			wantCode: codes.OK,
+		},
+		{
+			name: "Should return not found for product which is not deleted",
+			args: args{
+				ctx: context.Background(),
+				r:   &pb.RestoreRequest{Id: "1"},
+			},
+			setupMocks: func(as *mock.GRPCAuthService, ps *mock.ProductService) {
+				ps.EXPECT().Restore(gomock.Any(), "1").
+					Return(nil, fmt.Errorf("restore product: %w", product.ErrNotFound))
+			},
+			wantCode: codes.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			as := mock.NewGRPCAuthService(ctrl)
+			ps := mock.NewProductService(ctrl)
+
+			if tt.setupMocks != nil {
+				tt.setupMocks(as, ps)
+			}
+
+			s := &Server{
+				AuthService:    as,
+				ProductService: ps,
+			}
+			got, err := s.Restore(tt.args.ctx, tt.args.r)
+			if code := statusFromError(err).Code(); code != tt.wantCode {
+				t.Errorf("Restore() code = %v, want %v", code, tt.wantCode)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Restore() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_History(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ps := mock.NewProductService(ctrl)
+	ps.EXPECT().History(gomock.Any(), audit.FindRequest{Product: "1", Offset: 0, Limit: 10}).Return([]*audit.Entry{{
+		ID: "1", Product: "1", Seller: "1", Actor: "1", Operation: audit.OperationUpdate, Version: 2,
+		Changes:   []audit.Change{{Field: "price", Before: "100", After: "150"}},
+		CreatedAt: time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC),
+	}}, nil)
+
+	s := &Server{ProductService: ps}
+	got, err := s.History(context.Background(), &pb.HistoryRequest{Id: "1", Limit: 10})
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	want := &pb.HistoryReply{Entries: []*pb.AuditEntry{{
+		Id: "1", Product: "1", Seller: "1", Actor: "1", Operation: "update", Version: 2,
+		Changes:   []*pb.AuditChange{{Field: "price", Before: "100", After: "150"}},
+		CreatedAt: 1601553600000,
+	}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("History() got = %v, want %v", got, want)
+	}
+}
+
 func testStringPtr(s string) *string {
 	return &s
 }
@@ -303,3 +664,7 @@ func testStringPtr(s string) *string {
 func testInt64Ptr(i int64) *int64 {
 	return &i
 }
+
+func testFloat64Ptr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,54 @@
+package grpc
+
+import (
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/product"
+)
+
+var sortKeysToPB = map[product.SortKey]pb.Sort_Key{
+	product.SortKeyPrice:     pb.Sort_PRICE,
+	product.SortKeyName:      pb.Sort_NAME,
+	product.SortKeyCreated:   pb.Sort_CREATED,
+	product.SortKeyRelevance: pb.Sort_RELEVANCE,
+	product.SortKeyRating:    pb.Sort_RATING,
+}
+
+var sortKeysFromPB = map[pb.Sort_Key]product.SortKey{
+	pb.Sort_PRICE:     product.SortKeyPrice,
+	pb.Sort_NAME:      product.SortKeyName,
+	pb.Sort_CREATED:   product.SortKeyCreated,
+	pb.Sort_RELEVANCE: product.SortKeyRelevance,
+	pb.Sort_RATING:    product.SortKeyRating,
+}
+
+func sortsToPB(sorts []product.Sort) []*pb.Sort {
+	if len(sorts) == 0 {
+		return nil
+	}
+
+	pbSorts := make([]*pb.Sort, len(sorts))
+	for i, s := range sorts {
+		pbSorts[i] = &pb.Sort{
+			Key:  sortKeysToPB[s.Key],
+			Desc: s.Desc,
+		}
+	}
+	return pbSorts
+}
+
+// sortsFromPB converts sorts leaving unknown keys empty, so they are
+// rejected by the request validation.
+func sortsFromPB(pbSorts []*pb.Sort) []product.Sort {
+	if len(pbSorts) == 0 {
+		return nil
+	}
+
+	sorts := make([]product.Sort, len(pbSorts))
+	for i, s := range pbSorts {
+		sorts[i] = product.Sort{
+			Key:  sortKeysFromPB[s.Key],
+			Desc: s.Desc,
+		}
+	}
+	return sorts
+}
@@ -0,0 +1,34 @@
+package grpc
+
+import "time"
+
+// toMillis returns the Unix time of t in milliseconds. Times are sent in
+// this form.
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// toMillisPtr returns the Unix time of t in milliseconds, it is nil if t is
+// nil.
+func toMillisPtr(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+	ms := toMillis(*t)
+	return &ms
+}
+
+// fromMillisPtr returns the UTC time of the Unix time in milliseconds, it is
+// nil if ms is nil.
+func fromMillisPtr(ms *int64) *time.Time {
+	if ms == nil {
+		return nil
+	}
+	t := fromMillis(*ms)
+	return &t
+}
+
+// fromMillis returns the UTC time of the Unix time in milliseconds.
+func fromMillis(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC()
+}
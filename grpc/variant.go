@@ -0,0 +1,60 @@
+package grpc
+
+import (
+	"github.com/ortymid/market/grpc/pb"
+	"github.com/ortymid/market/market/product"
+)
+
+func attributesToPB(attrs []product.Attribute) []*pb.Attribute {
+	if len(attrs) == 0 {
+		return nil
+	}
+	pas := make([]*pb.Attribute, 0, len(attrs))
+	for _, a := range attrs {
+		pas = append(pas, &pb.Attribute{Name: a.Name, Type: string(a.Type)})
+	}
+	return pas
+}
+
+func attributesFromPB(pas []*pb.Attribute) []product.Attribute {
+	if len(pas) == 0 {
+		return nil
+	}
+	attrs := make([]product.Attribute, 0, len(pas))
+	for _, pa := range pas {
+		attrs = append(attrs, product.Attribute{Name: pa.Name, Type: product.AttributeType(pa.Type)})
+	}
+	return attrs
+}
+
+func variantsToPB(variants []product.Variant) []*pb.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+	pvs := make([]*pb.Variant, 0, len(variants))
+	for _, v := range variants {
+		pvs = append(pvs, &pb.Variant{
+			Sku:        v.SKU,
+			Attributes: v.Attributes,
+			Price:      moneyToPB(v.Price),
+			Stock:      v.Stock,
+		})
+	}
+	return pvs
+}
+
+func variantsFromPB(pvs []*pb.Variant) []product.Variant {
+	if len(pvs) == 0 {
+		return nil
+	}
+	variants := make([]product.Variant, 0, len(pvs))
+	for _, pv := range pvs {
+		variants = append(variants, product.Variant{
+			SKU:        pv.Sku,
+			Attributes: pv.Attributes,
+			Price:      moneyFromPB(pv.Price),
+			Stock:      pv.Stock,
+		})
+	}
+	return variants
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/ortymid/market/market/cart"
+	"net/http"
+)
+
+type Cart struct {
+	CartService cart.Interface
+}
+
+func (h *Cart) Setup(r *mux.Router) {
+	// Get
+	r.HandleFunc("/cart", h.Get).Methods(http.MethodGet)
+	r.HandleFunc("/cart/", h.Get).Methods(http.MethodGet)
+	// AddItem
+	r.HandleFunc("/cart/items", h.AddItem).Methods(http.MethodPost)
+	r.HandleFunc("/cart/items/", h.AddItem).Methods(http.MethodPost)
+	// UpdateItem
+	r.HandleFunc("/cart/items/{product}", h.UpdateItem).Methods(http.MethodPatch)
+	r.HandleFunc("/cart/items/{product}/", h.UpdateItem).Methods(http.MethodPatch)
+	// RemoveItem
+	r.HandleFunc("/cart/items/{product}", h.RemoveItem).Methods(http.MethodDelete)
+	r.HandleFunc("/cart/items/{product}/", h.RemoveItem).Methods(http.MethodDelete)
+}
+
+func (h *Cart) Get(w http.ResponseWriter, r *http.Request) {
+	c, err := h.CartService.Get(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, c)
+}
+
+func (h *Cart) AddItem(w http.ResponseWriter, r *http.Request) {
+	var ar cart.AddRequest
+
+	err := json.NewDecoder(r.Body).Decode(&ar)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	c, err := h.CartService.AddItem(r.Context(), ar)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, c)
+}
+
+func (h *Cart) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	var ur cart.UpdateRequest
+
+	err := json.NewDecoder(r.Body).Decode(&ur)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	ur.Product = mux.Vars(r)["product"]
+
+	c, err := h.CartService.UpdateItem(r.Context(), ur)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, c)
+}
+
+func (h *Cart) RemoveItem(w http.ResponseWriter, r *http.Request) {
+	product := mux.Vars(r)["product"]
+
+	c, err := h.CartService.RemoveItem(r.Context(), product)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, c)
+}
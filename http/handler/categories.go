@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/ortymid/market/market/category"
+	"net/http"
+)
+
+type Categories struct {
+	CategoryService category.Interface
+}
+
+func (h *Categories) Setup(r *mux.Router) {
+	// Find
+	r.HandleFunc("/categories", h.Find).Methods(http.MethodGet)
+	r.HandleFunc("/categories/", h.Find).Methods(http.MethodGet)
+	// FindOne
+	r.HandleFunc("/categories/{id}", h.FindOne).Methods(http.MethodGet)
+	r.HandleFunc("/categories/{id}/", h.FindOne).Methods(http.MethodGet)
+	// Descendants
+	r.HandleFunc("/categories/{id}/descendants", h.Descendants).Methods(http.MethodGet)
+	r.HandleFunc("/categories/{id}/descendants/", h.Descendants).Methods(http.MethodGet)
+	// Create
+	r.HandleFunc("/categories", h.Create).Methods(http.MethodPost)
+	r.HandleFunc("/categories/", h.Create).Methods(http.MethodPost)
+	// Update
+	r.HandleFunc("/categories/{id}", h.Update).Methods(http.MethodPatch)
+	r.HandleFunc("/categories/{id}/", h.Update).Methods(http.MethodPatch)
+	// Delete
+	r.HandleFunc("/categories/{id}", h.Delete).Methods(http.MethodDelete)
+	r.HandleFunc("/categories/{id}/", h.Delete).Methods(http.MethodDelete)
+}
+
+func (h *Categories) Find(w http.ResponseWriter, r *http.Request) {
+	cs, err := h.CategoryService.Find(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, cs)
+}
+
+func (h *Categories) FindOne(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	c, err := h.CategoryService.FindOne(r.Context(), id)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, c)
+}
+
+func (h *Categories) Descendants(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	cs, err := h.CategoryService.Descendants(r.Context(), id)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, cs)
+}
+
+func (h *Categories) Create(w http.ResponseWriter, r *http.Request) {
+	var cr category.CreateRequest
+
+	err := json.NewDecoder(r.Body).Decode(&cr)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	c, err := h.CategoryService.Create(r.Context(), cr)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, c)
+}
+
+func (h *Categories) Update(w http.ResponseWriter, r *http.Request) {
+	var ur category.UpdateRequest
+
+	err := json.NewDecoder(r.Body).Decode(&ur)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	ur.ID = mux.Vars(r)["id"]
+
+	c, err := h.CategoryService.Update(r.Context(), ur)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, c)
+}
+
+func (h *Categories) Delete(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	c, err := h.CategoryService.Delete(r.Context(), id)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, c)
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Add("Content-Type", "application/json")
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/errs"
+	"github.com/ortymid/market/market/order"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/review"
+	"log"
+	"net/http"
+)
+
+// Error codes returned in the Problem.Code field. Clients should rely on them
+// rather than on the error messages.
+const (
+	CodeBadRequest        = "bad_request"
+	CodeUnauthenticated   = "unauthenticated"
+	CodePermissionDenied  = "permission_denied"
+	CodeNotFound          = "not_found"
+	CodeValidation        = "validation_failed"
+	CodeInsufficientStock = "insufficient_stock"
+	CodeInsufficientFunds = "insufficient_funds"
+	CodeInProgress        = "purchase_in_progress"
+	CodeVersionConflict   = "version_conflict"
+	CodeAlreadyReviewed   = "already_reviewed"
+	CodeInternal          = "internal"
+)
+
+// Problem is an error response body in the RFC 7807 format extended with
+// the machine-readable error code.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+}
+
+func NewProblem(status int, code string, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// ProblemFromError translates errors returned by the market services into
+// a Problem with the corresponding HTTP status. Unknown errors are reported
+// as internal without exposing their details.
+func ProblemFromError(err error) Problem {
+	var errPermission auth.ErrPermission
+	var errNotFound errs.NotFound
+	var errValidation errs.Validation
+
+	switch {
+	case errors.As(err, &errNotFound):
+		return NewProblem(http.StatusNotFound, CodeNotFound, err.Error())
+	case errors.Is(err, product.ErrInsufficientStock):
+		return NewProblem(http.StatusConflict, CodeInsufficientStock, err.Error())
+	case errors.Is(err, product.ErrConflict):
+		return NewProblem(http.StatusPreconditionFailed, CodeVersionConflict, err.Error())
+	case errors.Is(err, review.ErrDuplicate):
+		return NewProblem(http.StatusConflict, CodeAlreadyReviewed, err.Error())
+	case errors.Is(err, order.ErrInsufficientFunds):
+		return NewProblem(http.StatusPaymentRequired, CodeInsufficientFunds, err.Error())
+	case errors.Is(err, order.ErrInProgress):
+		return NewProblem(http.StatusConflict, CodeInProgress, err.Error())
+	case errors.Is(err, auth.ErrNoUser):
+		return NewProblem(http.StatusUnauthorized, CodeUnauthenticated, err.Error())
+	case errors.As(err, &errPermission):
+		return NewProblem(http.StatusForbidden, CodePermissionDenied, err.Error())
+	case errors.As(err, &errValidation):
+		return NewProblem(http.StatusUnprocessableEntity, CodeValidation, err.Error())
+	default:
+		log.Println("internal error:", err)
+		return NewProblem(http.StatusInternalServerError, CodeInternal, "")
+	}
+}
+
+// WriteProblem writes the Problem as an application/problem+json response.
+func WriteProblem(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		log.Println("writing problem:", err)
+	}
+}
+
+// WriteError writes the error as a Problem response.
+func WriteError(w http.ResponseWriter, err error) {
+	WriteProblem(w, ProblemFromError(err))
+}
+
+// writeBadRequest reports malformed request data.
+func writeBadRequest(w http.ResponseWriter, err error) {
+	WriteProblem(w, NewProblem(http.StatusBadRequest, CodeBadRequest, err.Error()))
+}
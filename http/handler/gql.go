@@ -7,20 +7,35 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/ortymid/market/gql"
 	"github.com/ortymid/market/gql/gen"
+	"github.com/ortymid/market/market/cart"
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/market/order"
 	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/review"
+	"github.com/ortymid/market/market/seller"
 )
 
 type GraphQL struct {
-	ProductService product.Interface
+	ProductService  product.Interface
+	CategoryService category.Interface
+	OrderService    order.Interface
+	CartService     cart.Interface
+	ReviewService   review.Interface
+	SellerService   seller.Interface
 }
 
 // Setup registers all available routes under the provided *mux.Router.
 func (g *GraphQL) Setup(r *mux.Router) {
 	gqlSrv := handler.NewDefaultServer(gen.NewExecutableSchema(gen.Config{Resolvers: &gql.Resolver{
-		ProductService: g.ProductService,
+		ProductService:  g.ProductService,
+		CategoryService: g.CategoryService,
+		OrderService:    g.OrderService,
+		CartService:     g.CartService,
+		ReviewService:   g.ReviewService,
+		SellerService:   g.SellerService,
 	}}))
 
-	rt := r.Handle("/gql", gqlSrv)
+	rt := r.Handle("/gql", gql.LoaderMiddleware(g.SellerService, gqlSrv))
 	url, err := rt.URL()
 	if err != nil {
 		panic(fmt.Errorf("obtaining GraphQL handler url: %w", err))
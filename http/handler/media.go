@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/ortymid/market/market/product"
+	"io"
+	"log"
+	"net/http"
+)
+
+// mediaFormField is the multipart form field holding the uploaded image.
+const mediaFormField = "file"
+
+// AddMedia adds the image uploaded as the file field of a multipart form. The
+// image is streamed to the service without buffering the form.
+func (h *Products) AddMedia(w http.ResponseWriter, r *http.Request) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			writeBadRequest(w, errors.New("no file field in the form"))
+			return
+		}
+		if err != nil {
+			writeBadRequest(w, err)
+			return
+		}
+		if part.FormName() != mediaFormField {
+			continue
+		}
+
+		p, err := h.ProductService.AddMedia(r.Context(), product.AddMediaRequest{
+			Product:     mux.Vars(r)["id"],
+			ContentType: part.Header.Get("Content-Type"),
+			Body:        part,
+		})
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		setETag(w, p)
+		writeJSON(w, p)
+		return
+	}
+}
+
+func (h *Products) RemoveMedia(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	p, err := h.ProductService.RemoveMedia(r.Context(), vars["id"], vars["media_id"])
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	setETag(w, p)
+	writeJSON(w, p)
+}
+
+func (h *Products) OpenMedia(w http.ResponseWriter, r *http.Request) {
+	h.writeMedia(w, r, false)
+}
+
+func (h *Products) OpenThumbnail(w http.ResponseWriter, r *http.Request) {
+	h.writeMedia(w, r, true)
+}
+
+// writeMedia writes the content of the media item or of its thumbnail.
+func (h *Products) writeMedia(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	vars := mux.Vars(r)
+
+	c, err := h.ProductService.OpenMedia(r.Context(), vars["id"], vars["media_id"], thumbnail)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	defer c.Body.Close()
+
+	w.Header().Set("Content-Type", c.ContentType)
+	if _, err := io.Copy(w, c.Body); err != nil {
+		log.Println("writing media:", err)
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/ortymid/market/market/order"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// idempotencyKeyHeader holds the idempotency key of a purchase. It takes
+// precedence over the key in the body.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+type Orders struct {
+	OrderService order.Interface
+}
+
+func (h *Orders) Setup(r *mux.Router) {
+	// Find
+	r.HandleFunc("/orders", h.Find).Methods(http.MethodGet)
+	r.HandleFunc("/orders/", h.Find).Methods(http.MethodGet)
+	// FindOne
+	r.HandleFunc("/orders/{id}", h.FindOne).Methods(http.MethodGet)
+	r.HandleFunc("/orders/{id}/", h.FindOne).Methods(http.MethodGet)
+	// Purchase
+	r.HandleFunc("/orders", h.Purchase).Methods(http.MethodPost)
+	r.HandleFunc("/orders/", h.Purchase).Methods(http.MethodPost)
+}
+
+func (h *Orders) Find(w http.ResponseWriter, r *http.Request) {
+	findReq, err := makeOrderFindRequestFromQuery(r.URL.Query())
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	os, err := h.OrderService.Find(r.Context(), findReq)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, os)
+}
+
+func makeOrderFindRequestFromQuery(query url.Values) (r order.FindRequest, err error) {
+	r.Offset, err = strconv.ParseInt(query.Get("offset"), 10, 64)
+	if err != nil {
+		return r, errors.New("valid offset query parameter required")
+	}
+
+	r.Limit, err = strconv.ParseInt(query.Get("limit"), 10, 64)
+	if err != nil {
+		return r, errors.New("valid limit query parameter required")
+	}
+
+	if buyers, ok := query["buyer"]; ok && len(buyers) > 0 {
+		r.Buyer = &buyers[0]
+	}
+	if sellers, ok := query["seller"]; ok && len(sellers) > 0 {
+		r.Seller = &sellers[0]
+	}
+
+	return r, nil
+}
+
+func (h *Orders) FindOne(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	o, err := h.OrderService.FindOne(r.Context(), id)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, o)
+}
+
+func (h *Orders) Purchase(w http.ResponseWriter, r *http.Request) {
+	var pr order.PurchaseRequest
+
+	err := json.NewDecoder(r.Body).Decode(&pr)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	if key := r.Header.Get(idempotencyKeyHeader); len(key) > 0 {
+		pr.IdempotencyKey = key
+	}
+
+	o, err := h.OrderService.Purchase(r.Context(), pr)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, o)
+}
@@ -5,12 +5,25 @@ import (
 	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
+	"github.com/ortymid/market/market/audit"
 	"github.com/ortymid/market/market/product"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 )
 
+// Conditional update headers. The entity tag of a product is its quoted
+// version.
+const (
+	etagHeader    = "ETag"
+	ifMatchHeader = "If-Match"
+)
+
+// attributeQueryPrefix prefixes the names of the attributes to find products
+// by in the query.
+const attributeQueryPrefix = "attr."
+
 type Products struct {
 	ProductService product.Interface
 }
@@ -31,36 +44,55 @@ func (h *Products) Setup(r *mux.Router) {
 	// Delete
 	r.HandleFunc("/products/{id}", h.Delete).Methods(http.MethodDelete)
 	r.HandleFunc("/products/{id}/", h.Delete).Methods(http.MethodDelete)
-
+	// Restore
+	r.HandleFunc("/products/{id}/restore", h.Restore).Methods(http.MethodPost)
+	r.HandleFunc("/products/{id}/restore/", h.Restore).Methods(http.MethodPost)
+	// Stock
+	r.HandleFunc("/products/{id}/stock", h.AdjustStock).Methods(http.MethodPost)
+	// History
+	r.HandleFunc("/products/{id}/history", h.History).Methods(http.MethodGet)
+	r.HandleFunc("/products/{id}/history/", h.History).Methods(http.MethodGet)
+	// Media
+	r.HandleFunc("/products/{id}/media", h.AddMedia).Methods(http.MethodPost)
+	r.HandleFunc("/products/{id}/media/", h.AddMedia).Methods(http.MethodPost)
+	r.HandleFunc("/products/{id}/media/{media_id}", h.RemoveMedia).Methods(http.MethodDelete)
+	r.HandleFunc("/products/{id}/media/{media_id}", h.OpenMedia).Methods(http.MethodGet)
+	r.HandleFunc("/products/{id}/media/{media_id}/thumbnail", h.OpenThumbnail).Methods(http.MethodGet)
 }
 
 func (h *Products) Find(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	findReq, err := makeFindRequestFromQuery(query)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeBadRequest(w, err)
 		return
 	}
 
-	p, err := h.ProductService.Find(r.Context(), findReq)
+	res, err := h.ProductService.Find(r.Context(), findReq)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteError(w, err)
 		return
 	}
 
 	w.Header().Add("Content-Type", "application/json")
 
-	err = json.NewEncoder(w).Encode(p)
+	err = json.NewEncoder(w).Encode(res)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, err)
 		return
 	}
 }
 
 func makeFindRequestFromQuery(query url.Values) (r product.FindRequest, err error) {
-	offset, err := strconv.ParseInt(query.Get("offset"), 10, 64)
-	if err != nil {
-		return r, errors.New("valid offset query parameter required")
+	// Offset is not needed to continue a listing with a cursor.
+	cursor := query.Get("cursor")
+
+	var offset int64
+	if len(cursor) == 0 || len(query.Get("offset")) > 0 {
+		offset, err = strconv.ParseInt(query.Get("offset"), 10, 64)
+		if err != nil {
+			return r, errors.New("valid offset query parameter required")
+		}
 	}
 
 	limit, err := strconv.ParseInt(query.Get("limit"), 10, 64)
@@ -73,6 +105,11 @@ func makeFindRequestFromQuery(query url.Values) (r product.FindRequest, err erro
 		name = &names[0]
 	}
 
+	var seller *string
+	if sellers, ok := query["seller"]; ok && len(sellers) > 0 {
+		seller = &sellers[0]
+	}
+
 	var priceFrom *int64
 	if pfs, ok := query["price_from"]; ok && len(pfs) > 0 {
 		p, err := strconv.ParseInt(pfs[0], 10, 64)
@@ -91,36 +128,115 @@ func makeFindRequestFromQuery(query url.Values) (r product.FindRequest, err erro
 		priceTo = &p
 	}
 
+	var includeDescendants bool
+	if ids, ok := query["include_descendants"]; ok && len(ids) > 0 {
+		includeDescendants, err = strconv.ParseBool(ids[0])
+		if err != nil {
+			return r, fmt.Errorf("invalid include_descendants: %w", err)
+		}
+	}
+
+	var inStock *bool
+	if iss, ok := query["in_stock"]; ok && len(iss) > 0 {
+		is, err := strconv.ParseBool(iss[0])
+		if err != nil {
+			return r, fmt.Errorf("invalid in_stock: %w", err)
+		}
+		inStock = &is
+	}
+
+	var minRating *float64
+	if mrs, ok := query["min_rating"]; ok && len(mrs) > 0 {
+		mr, err := strconv.ParseFloat(mrs[0], 64)
+		if err != nil {
+			return r, fmt.Errorf("invalid min_rating: %w", err)
+		}
+		minRating = &mr
+	}
+
+	var status *product.Status
+	if ss, ok := query["status"]; ok && len(ss) > 0 {
+		s := product.Status(ss[0])
+		status = &s
+	}
+
+	// Attribute values are given as attr.<name>=<value>, e.g. attr.color=red.
+	var attributes map[string]string
+	for key, values := range query {
+		if !strings.HasPrefix(key, attributeQueryPrefix) || len(values) == 0 {
+			continue
+		}
+		if attributes == nil {
+			attributes = make(map[string]string)
+		}
+		attributes[strings.TrimPrefix(key, attributeQueryPrefix)] = values[0]
+	}
+
+	var includeDeleted bool
+	if ids, ok := query["include_deleted"]; ok && len(ids) > 0 {
+		includeDeleted, err = strconv.ParseBool(ids[0])
+		if err != nil {
+			return r, fmt.Errorf("invalid include_deleted: %w", err)
+		}
+	}
+
+	sort, err := product.ParseSort(query.Get("sort"))
+	if err != nil {
+		return r, err
+	}
+
+	// The currency alone finds products priced in it, the limits require it
+	// unless they are in the display currency.
 	var priceRange *product.PriceRange
-	if priceFrom != nil || priceTo != nil {
+	currency := query.Get("currency")
+	if priceFrom != nil || priceTo != nil || len(currency) > 0 {
 		priceRange = &product.PriceRange{
-			From: priceFrom,
-			To:   priceTo,
+			From:     priceFrom,
+			To:       priceTo,
+			Currency: currency,
 		}
 	}
 
 	return product.FindRequest{
-		Offset:     offset,
-		Limit:      limit,
-		Name:       name,
-		PriceRange: priceRange,
+		Offset:             offset,
+		Limit:              limit,
+		Cursor:             cursor,
+		Name:               name,
+		PriceRange:         priceRange,
+		Seller:             seller,
+		Categories:         query["category"],
+		IncludeDescendants: includeDescendants,
+		Attributes:         attributes,
+		InStock:            inStock,
+		MinRating:          minRating,
+		Status:             status,
+		IncludeDeleted:     includeDeleted,
+		Sort:               sort,
+		DisplayCurrency:    query.Get("display_currency"),
 	}, nil
 }
 
 func (h *Products) FindOne(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 
-	p, err := h.ProductService.FindOne(r.Context(), id)
+	var p *product.Product
+	var err error
+	if currency := r.URL.Query().Get("display_currency"); len(currency) > 0 {
+		p, err = h.ProductService.FindOneIn(r.Context(), id, currency)
+	} else {
+		p, err = h.ProductService.FindOne(r.Context(), id)
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteError(w, err)
 		return
 	}
 
+	setETag(w, p)
 	w.Header().Add("Content-Type", "application/json")
 
 	err = json.NewEncoder(w).Encode(p)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, err)
 		return
 	}
 }
@@ -130,21 +246,22 @@ func (h *Products) Create(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&cr)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeBadRequest(w, err)
 		return
 	}
 
 	p, err := h.ProductService.Create(r.Context(), cr)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteError(w, err)
 		return
 	}
 
+	setETag(w, p)
 	w.Header().Add("Content-Type", "application/json")
 
 	err = json.NewEncoder(w).Encode(p)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, err)
 		return
 	}
 }
@@ -154,34 +271,65 @@ func (h *Products) Update(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&ur)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeBadRequest(w, err)
 		return
 	}
 
 	id := mux.Vars(r)["id"]
 	ur.ID = id
 
+	ur.ExpectedVersion, err = parseIfMatch(r.Header.Get(ifMatchHeader))
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
 	p, err := h.ProductService.Update(r.Context(), ur)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteError(w, err)
 		return
 	}
 
+	setETag(w, p)
 	w.Header().Add("Content-Type", "application/json")
 
 	err = json.NewEncoder(w).Encode(p)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, err)
 		return
 	}
 }
 
+// setETag sets the entity tag of the product.
+func setETag(w http.ResponseWriter, p *product.Product) {
+	w.Header().Set(etagHeader, strconv.Quote(strconv.FormatInt(p.Version, 10)))
+}
+
+// parseIfMatch returns the version expected by the If-Match header. It is nil
+// if the header is empty or matches any version.
+func parseIfMatch(h string) (*int64, error) {
+	h = strings.TrimSpace(h)
+	if h == "" || h == "*" {
+		return nil, nil
+	}
+
+	tag, err := strconv.Unquote(h)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header, a single quoted version required", ifMatchHeader)
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header, a single quoted version required", ifMatchHeader)
+	}
+	return &version, nil
+}
+
 func (h *Products) Delete(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 
 	p, err := h.ProductService.Delete(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteError(w, err)
 		return
 	}
 
@@ -189,7 +337,70 @@ func (h *Products) Delete(w http.ResponseWriter, r *http.Request) {
 
 	err = json.NewEncoder(w).Encode(p)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, err)
+		return
+	}
+}
+
+func (h *Products) Restore(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	p, err := h.ProductService.Restore(r.Context(), id)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	setETag(w, p)
+	writeJSON(w, p)
+}
+
+func (h *Products) History(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	hr := audit.FindRequest{Product: mux.Vars(r)["id"]}
+
+	var err error
+	hr.Offset, err = strconv.ParseInt(query.Get("offset"), 10, 64)
+	if err != nil {
+		writeBadRequest(w, errors.New("valid offset query parameter required"))
 		return
 	}
+	hr.Limit, err = strconv.ParseInt(query.Get("limit"), 10, 64)
+	if err != nil {
+		writeBadRequest(w, errors.New("valid limit query parameter required"))
+		return
+	}
+
+	es, err := h.ProductService.History(r.Context(), hr)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, es)
+}
+
+// stockRequest is the body of the stock requests.
+type stockRequest struct {
+	Delta int64 `json:"delta"`
+	// Variant is the SKU of the variant to adjust.
+	Variant string `json:"variant,omitempty"`
+}
+
+func (h *Products) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	var sr stockRequest
+
+	err := json.NewDecoder(r.Body).Decode(&sr)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	p, err := h.ProductService.AdjustStock(r.Context(), mux.Vars(r)["id"], sr.Variant, sr.Delta)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, p)
 }
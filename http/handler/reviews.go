@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/ortymid/market/market/review"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Reviews serves the reviews of products under /products/{id}/reviews.
+type Reviews struct {
+	ReviewService review.Interface
+}
+
+func (h *Reviews) Setup(r *mux.Router) {
+	// Find
+	r.HandleFunc("/products/{id}/reviews", h.Find).Methods(http.MethodGet)
+	r.HandleFunc("/products/{id}/reviews/", h.Find).Methods(http.MethodGet)
+	// FindOne
+	r.HandleFunc("/products/{id}/reviews/{review_id}", h.FindOne).Methods(http.MethodGet)
+	r.HandleFunc("/products/{id}/reviews/{review_id}/", h.FindOne).Methods(http.MethodGet)
+	// Create
+	r.HandleFunc("/products/{id}/reviews", h.Create).Methods(http.MethodPost)
+	r.HandleFunc("/products/{id}/reviews/", h.Create).Methods(http.MethodPost)
+	// Update
+	r.HandleFunc("/products/{id}/reviews/{review_id}", h.Update).Methods(http.MethodPatch)
+	r.HandleFunc("/products/{id}/reviews/{review_id}/", h.Update).Methods(http.MethodPatch)
+	// Delete
+	r.HandleFunc("/products/{id}/reviews/{review_id}", h.Delete).Methods(http.MethodDelete)
+	r.HandleFunc("/products/{id}/reviews/{review_id}/", h.Delete).Methods(http.MethodDelete)
+}
+
+func (h *Reviews) Find(w http.ResponseWriter, r *http.Request) {
+	findReq, err := makeReviewFindRequestFromQuery(r.URL.Query())
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	productID := mux.Vars(r)["id"]
+	findReq.Product = &productID
+
+	rvs, err := h.ReviewService.Find(r.Context(), findReq)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, rvs)
+}
+
+func makeReviewFindRequestFromQuery(query url.Values) (r review.FindRequest, err error) {
+	r.Offset, err = strconv.ParseInt(query.Get("offset"), 10, 64)
+	if err != nil {
+		return r, errors.New("valid offset query parameter required")
+	}
+
+	r.Limit, err = strconv.ParseInt(query.Get("limit"), 10, 64)
+	if err != nil {
+		return r, errors.New("valid limit query parameter required")
+	}
+
+	if authors, ok := query["author"]; ok && len(authors) > 0 {
+		r.Author = &authors[0]
+	}
+
+	return r, nil
+}
+
+func (h *Reviews) FindOne(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	rv, err := h.ReviewService.FindOne(r.Context(), vars["id"], vars["review_id"])
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, rv)
+}
+
+func (h *Reviews) Create(w http.ResponseWriter, r *http.Request) {
+	var cr review.CreateRequest
+
+	err := json.NewDecoder(r.Body).Decode(&cr)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	cr.Product = mux.Vars(r)["id"]
+
+	rv, err := h.ReviewService.Create(r.Context(), cr)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, rv)
+}
+
+func (h *Reviews) Update(w http.ResponseWriter, r *http.Request) {
+	var ur review.UpdateRequest
+
+	err := json.NewDecoder(r.Body).Decode(&ur)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	vars := mux.Vars(r)
+	ur.Product = vars["id"]
+	ur.ID = vars["review_id"]
+
+	rv, err := h.ReviewService.Update(r.Context(), ur)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, rv)
+}
+
+func (h *Reviews) Delete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	rv, err := h.ReviewService.Delete(r.Context(), vars["id"], vars["review_id"])
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, rv)
+}
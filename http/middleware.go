@@ -2,6 +2,7 @@ package http
 
 import (
 	"errors"
+	"github.com/ortymid/market/http/handler"
 	"github.com/ortymid/market/market/auth"
 	"net/http"
 	"strings"
@@ -10,22 +11,26 @@ import (
 func AuthMiddleware(s AuthService, h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Obtain token.
-		//token, err := tokenFromHeader(r)
-		//if err != nil {
-		//	http.Error(w, err.Error(), http.StatusBadRequest)
-		//}
-
-		// Create Authorize request.
-		//ctx := auth.NewContextWithToken(context.Background(), token)
+		token, err := tokenFromHeader(r)
+		if err != nil {
+			handler.WriteProblem(w, handler.NewProblem(http.StatusUnauthorized, handler.CodeUnauthenticated, err.Error()))
+			return
+		}
 
 		// Make Authorize request.
 		user, err := s.Authorize(r.Context(), r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			handler.WriteProblem(w, handler.NewProblem(http.StatusUnauthorized, handler.CodeUnauthenticated, err.Error()))
 			return
 		}
-		//
-		r = r.WithContext(auth.NewContextWithUser(r.Context(), user))
+
+		// Keep the token to be able to forward it to other services.
+		ctx := r.Context()
+		if len(token) != 0 {
+			ctx = auth.NewContextWithToken(ctx, token)
+		}
+		ctx = auth.NewContextWithUser(ctx, user)
+		r = r.WithContext(ctx)
 
 		h.ServeHTTP(w, r)
 	})
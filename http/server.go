@@ -3,7 +3,12 @@ package http
 import (
 	"context"
 	"github.com/ortymid/market/http/handler"
+	"github.com/ortymid/market/market/cart"
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/market/order"
 	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/review"
+	"github.com/ortymid/market/market/seller"
 	"github.com/rs/cors"
 	"log"
 	"net/http"
@@ -15,8 +20,13 @@ import (
 )
 
 type Server struct {
-	AuthService    AuthService
-	ProductService product.Interface
+	AuthService     AuthService
+	ProductService  product.Interface
+	CategoryService category.Interface
+	OrderService    order.Interface
+	CartService     cart.Interface
+	ReviewService   review.Interface
+	SellerService   seller.Interface
 }
 
 func (s *Server) Handler() http.Handler {
@@ -26,8 +36,31 @@ func (s *Server) Handler() http.Handler {
 	products := handler.Products{ProductService: s.ProductService}
 	products.Setup(r)
 
+	// Reviews
+	reviews := handler.Reviews{ReviewService: s.ReviewService}
+	reviews.Setup(r)
+
+	// Categories
+	categories := handler.Categories{CategoryService: s.CategoryService}
+	categories.Setup(r)
+
+	// Orders
+	orders := handler.Orders{OrderService: s.OrderService}
+	orders.Setup(r)
+
+	// Cart
+	carts := handler.Cart{CartService: s.CartService}
+	carts.Setup(r)
+
 	// GraphQL
-	gql := handler.GraphQL{ProductService: s.ProductService}
+	gql := handler.GraphQL{
+		ProductService:  s.ProductService,
+		CategoryService: s.CategoryService,
+		OrderService:    s.OrderService,
+		CartService:     s.CartService,
+		ReviewService:   s.ReviewService,
+		SellerService:   s.SellerService,
+	}
 	gql.Setup(r)
 
 	// CORS
@@ -2,17 +2,32 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/golang/mock/gomock"
+	"github.com/ortymid/market/http/handler"
+	"github.com/ortymid/market/market/audit"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/cart"
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/market/media"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/order"
 	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/review"
 	"github.com/ortymid/market/market/user"
 	"github.com/ortymid/market/mock"
 	"io/ioutil"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 type setupMocks func(as *mock.HTTPAuthService, ps *mock.ProductService)
@@ -36,17 +51,275 @@ func TestServer(t *testing.T) {
 					gomock.Any(),
 					product.FindRequest{Offset: 0, Limit: 2},
 				).Return(
-					[]*product.Product{
-						{ID: "1", Name: "p1", Price: 100, Seller: "1"},
-						{ID: "2", Name: "p2", Price: 200, Seller: "2"},
+					&product.FindResult{
+						Products: []*product.Product{
+							{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"},
+							{ID: "2", Name: "p2", Price: money.New(200, "USD"), Seller: "2"},
+						},
+						Total:      3,
+						HasMore:    true,
+						NextCursor: "cursor1",
 					},
 					nil,
 				)
 			},
 			wantStatus: http.StatusOK,
-			wantBody: testBody([]*product.Product{
-				{ID: "1", Name: "p1", Price: 100, Seller: "1"},
-				{ID: "2", Name: "p2", Price: 200, Seller: "2"},
+			wantBody: testBody(&product.FindResult{
+				Products: []*product.Product{
+					{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"},
+					{ID: "2", Name: "p2", Price: money.New(200, "USD"), Seller: "2"},
+				},
+				Total:      3,
+				HasMore:    true,
+				NextCursor: "cursor1",
+			}),
+		},
+		{
+			name: "Should return products for cursor without offset",
+			req:  httptest.NewRequest(http.MethodGet, "/products/?cursor=cursor1&limit=2", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				ps.EXPECT().Find(
+					gomock.Any(),
+					product.FindRequest{Cursor: "cursor1", Limit: 2},
+				).Return(
+					&product.FindResult{
+						Products: []*product.Product{
+							{ID: "3", Name: "p3", Price: money.New(300, "USD"), Seller: "1"},
+						},
+					},
+					nil,
+				)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: testBody(&product.FindResult{
+				Products: []*product.Product{
+					{ID: "3", Name: "p3", Price: money.New(300, "USD"), Seller: "1"},
+				},
+			}),
+		},
+
+		{
+			name: "Should return products for filters",
+			req:  httptest.NewRequest(http.MethodGet, "/products/?offset=0&limit=2&name=p&price_from=100&currency=EUR&seller=1", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				ps.EXPECT().Find(
+					gomock.Any(),
+					product.FindRequest{
+						Offset:     0,
+						Limit:      2,
+						Name:       testStringPtr("p"),
+						PriceRange: &product.PriceRange{From: testInt64Ptr(100), Currency: "EUR"},
+						Seller:     testStringPtr("1"),
+					},
+				).Return(
+					&product.FindResult{
+						Products: []*product.Product{
+							{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"},
+						},
+					},
+					nil,
+				)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: testBody(&product.FindResult{
+				Products: []*product.Product{
+					{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"},
+				},
+			}),
+		},
+		{
+			name: "Should return products in display currency",
+			req:  httptest.NewRequest(http.MethodGet, "/products/?offset=0&limit=2&price_to=5000&display_currency=EUR", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				ps.EXPECT().Find(
+					gomock.Any(),
+					product.FindRequest{
+						Offset:          0,
+						Limit:           2,
+						PriceRange:      &product.PriceRange{To: testInt64Ptr(5000)},
+						DisplayCurrency: "EUR",
+					},
+				).Return(
+					&product.FindResult{
+						Products: []*product.Product{
+							{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", DisplayPrice: testMoneyPtr(money.New(85, "EUR"))},
+						},
+					},
+					nil,
+				)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: testBody(&product.FindResult{
+				Products: []*product.Product{
+					{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", DisplayPrice: testMoneyPtr(money.New(85, "EUR"))},
+				},
+			}),
+		},
+		{
+			name: "Should return products including deleted",
+			req:  httptest.NewRequest(http.MethodGet, "/products/?offset=0&limit=2&seller=1&include_deleted=true", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+
+				ps.EXPECT().Find(
+					gomock.Any(),
+					product.FindRequest{
+						Offset:         0,
+						Limit:          2,
+						Seller:         testStringPtr("1"),
+						IncludeDeleted: true,
+					},
+				).Return(
+					&product.FindResult{
+						Products: []*product.Product{
+							{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", DeletedAt: &testDeletedAt},
+						},
+					},
+					nil,
+				)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: testBody(&product.FindResult{
+				Products: []*product.Product{
+					{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", DeletedAt: &testDeletedAt},
+				},
+			}),
+		},
+		{
+			name: "Should return drafts of the seller",
+			req:  httptest.NewRequest(http.MethodGet, "/products/?offset=0&limit=2&seller=1&status=draft", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+
+				ps.EXPECT().Find(
+					gomock.Any(),
+					product.FindRequest{
+						Offset: 0,
+						Limit:  2,
+						Seller: testStringPtr("1"),
+						Status: testStatusPtr(product.StatusDraft),
+					},
+				).Return(
+					&product.FindResult{
+						Products: []*product.Product{
+							{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", Status: product.StatusDraft},
+						},
+					},
+					nil,
+				)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: testBody(&product.FindResult{
+				Products: []*product.Product{
+					{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", Status: product.StatusDraft},
+				},
+			}),
+		},
+		{
+			name: "Should return bad request problem for invalid include_deleted",
+			req:  httptest.NewRequest(http.MethodGet, "/products/?offset=0&limit=2&include_deleted=maybe", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody: testBody(handler.NewProblem(
+				http.StatusBadRequest, handler.CodeBadRequest,
+				`invalid include_deleted: strconv.ParseBool: parsing "maybe": invalid syntax`,
+			)),
+		},
+		{
+			name: "Should return products for categories",
+			req:  httptest.NewRequest(http.MethodGet, "/products/?offset=0&limit=2&category=1&category=2&include_descendants=true", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				ps.EXPECT().Find(
+					gomock.Any(),
+					product.FindRequest{
+						Offset:             0,
+						Limit:              2,
+						Categories:         []string{"1", "2"},
+						IncludeDescendants: true,
+					},
+				).Return(
+					&product.FindResult{
+						Products: []*product.Product{
+							{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", Categories: []string{"3"}},
+						},
+					},
+					nil,
+				)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: testBody(&product.FindResult{
+				Products: []*product.Product{
+					{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", Categories: []string{"3"}},
+				},
+			}),
+		},
+		{
+			name: "Should return products for attributes",
+			req:  httptest.NewRequest(http.MethodGet, "/products/?offset=0&limit=2&attr.color=red&attr.size=42", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				ps.EXPECT().Find(
+					gomock.Any(),
+					product.FindRequest{
+						Offset:     0,
+						Limit:      2,
+						Attributes: map[string]string{"color": "red", "size": "42"},
+					},
+				).Return(
+					&product.FindResult{
+						Products: []*product.Product{
+							{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"},
+						},
+					},
+					nil,
+				)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: testBody(&product.FindResult{
+				Products: []*product.Product{
+					{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"},
+				},
+			}),
+		},
+		{
+			name: "Should return products for min rating sorted by rating",
+			req:  httptest.NewRequest(http.MethodGet, "/products/?offset=0&limit=2&min_rating=4.5&sort=-rating", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				ps.EXPECT().Find(
+					gomock.Any(),
+					product.FindRequest{
+						Offset:    0,
+						Limit:     2,
+						MinRating: testFloat64Ptr(4.5),
+						Sort:      []product.Sort{{Key: product.SortKeyRating, Desc: true}},
+					},
+				).Return(
+					&product.FindResult{
+						Products: []*product.Product{
+							{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", Rating: product.Rating{Average: 5, Count: 2}},
+						},
+					},
+					nil,
+				)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: testBody(&product.FindResult{
+				Products: []*product.Product{
+					{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", Rating: product.Rating{Average: 5, Count: 2}},
+				},
 			}),
 		},
 
@@ -61,12 +334,55 @@ func TestServer(t *testing.T) {
 					gomock.Any(),
 					"1",
 				).Return(
-					&product.Product{ID: "1", Name: "p1", Price: 100, Seller: "1"},
+					&product.Product{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"},
+					nil,
+				)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   testBody(&product.Product{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"}),
+		},
+
+		{
+			name: "Should return product in display currency",
+			req:  httptest.NewRequest(http.MethodGet, "/products/1?display_currency=EUR", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				ps.EXPECT().FindOneIn(
+					gomock.Any(),
+					"1",
+					"EUR",
+				).Return(
+					&product.Product{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", DisplayPrice: testMoneyPtr(money.New(85, "EUR"))},
 					nil,
 				)
 			},
 			wantStatus: http.StatusOK,
-			wantBody:   testBody(&product.Product{ID: "1", Name: "p1", Price: 100, Seller: "1"}),
+			wantBody:   testBody(&product.Product{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", DisplayPrice: testMoneyPtr(money.New(85, "EUR"))}),
+		},
+
+		{
+			name: "Should return not found problem",
+			req:  httptest.NewRequest(http.MethodGet, "/products/1", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				ps.EXPECT().FindOne(
+					gomock.Any(),
+					"1",
+				).Return(
+					nil,
+					fmt.Errorf("get product: %w", product.ErrNotFound),
+				)
+			},
+			wantStatus: http.StatusNotFound,
+			wantBody: testBody(handler.Problem{
+				Type:   "about:blank",
+				Title:  "Not Found",
+				Status: http.StatusNotFound,
+				Detail: "get product: product not found",
+				Code:   handler.CodeNotFound,
+			}),
 		},
 
 		// POST /products/
@@ -77,7 +393,7 @@ func TestServer(t *testing.T) {
 				"/products/",
 				bytes.NewReader(testBody(product.CreateRequest{
 					Name:  "p1",
-					Price: 100,
+					Price: money.New(100, "USD"),
 				})),
 			),
 			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
@@ -87,15 +403,82 @@ func TestServer(t *testing.T) {
 					gomock.Any(),
 					product.CreateRequest{
 						Name:  "p1",
-						Price: 100,
+						Price: money.New(100, "USD"),
 					},
 				).Return(
-					&product.Product{ID: "1", Name: "p1", Price: 100, Seller: "1"},
+					&product.Product{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"},
 					nil,
 				)
 			},
 			wantStatus: http.StatusOK,
-			wantBody:   testBody(&product.Product{ID: "1", Name: "p1", Price: 100, Seller: "1"}),
+			wantBody:   testBody(&product.Product{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"}),
+		},
+
+		{
+			name: "Should return unauthenticated problem when creating without user",
+			req: httptest.NewRequest(
+				http.MethodPost,
+				"/products/",
+				bytes.NewReader(testBody(product.CreateRequest{
+					Name:  "p1",
+					Price: money.New(100, "USD"),
+				})),
+			),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				ps.EXPECT().Create(
+					gomock.Any(),
+					product.CreateRequest{
+						Name:  "p1",
+						Price: money.New(100, "USD"),
+					},
+				).Return(
+					nil,
+					fmt.Errorf("create product: %w", auth.ErrNoUser),
+				)
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBody: testBody(handler.Problem{
+				Type:   "about:blank",
+				Title:  "Unauthorized",
+				Status: http.StatusUnauthorized,
+				Detail: "create product: user not provided",
+				Code:   handler.CodeUnauthenticated,
+			}),
+		},
+		{
+			name: "Should return validation problem",
+			req: httptest.NewRequest(
+				http.MethodPost,
+				"/products/",
+				bytes.NewReader(testBody(product.CreateRequest{
+					Name:  "p1",
+					Price: money.New(-100, "USD"),
+				})),
+			),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+
+				ps.EXPECT().Create(
+					gomock.Any(),
+					product.CreateRequest{
+						Name:  "p1",
+						Price: money.New(-100, "USD"),
+					},
+				).Return(
+					nil,
+					fmt.Errorf("create product: %w", product.ErrValidation{Resource: product.Resource, Field: "price", Reason: "must not be negative"}),
+				)
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody: testBody(handler.Problem{
+				Type:   "about:blank",
+				Title:  "Unprocessable Entity",
+				Status: http.StatusUnprocessableEntity,
+				Detail: "create product: invalid price: must not be negative",
+				Code:   handler.CodeValidation,
+			}),
 		},
 
 		// PATCH /products/{id}
@@ -118,12 +501,45 @@ func TestServer(t *testing.T) {
 						Name: testStringPtr("p2"),
 					},
 				).Return(
-					&product.Product{ID: "1", Name: "p2", Price: 100, Seller: "1"},
+					&product.Product{ID: "1", Name: "p2", Price: money.New(100, "USD"), Seller: "1"},
 					nil,
 				)
 			},
 			wantStatus: http.StatusOK,
-			wantBody:   testBody(&product.Product{ID: "1", Name: "p2", Price: 100, Seller: "1"}),
+			wantBody:   testBody(&product.Product{ID: "1", Name: "p2", Price: money.New(100, "USD"), Seller: "1"}),
+		},
+
+		{
+			name: "Should return permission problem when updating not own product",
+			req: httptest.NewRequest(
+				http.MethodPatch,
+				"/products/1",
+				bytes.NewReader(testBody(product.UpdateRequest{
+					Name: testStringPtr("p2"),
+				})),
+			),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "2"}, nil)
+
+				ps.EXPECT().Update(
+					gomock.Any(),
+					product.UpdateRequest{
+						ID:   "1",
+						Name: testStringPtr("p2"),
+					},
+				).Return(
+					nil,
+					fmt.Errorf("update product: %w", auth.ErrPermission{Reason: "only own products allowed to update"}),
+				)
+			},
+			wantStatus: http.StatusForbidden,
+			wantBody: testBody(handler.Problem{
+				Type:   "about:blank",
+				Title:  "Forbidden",
+				Status: http.StatusForbidden,
+				Detail: "update product: permission denied: only own products allowed to update",
+				Code:   handler.CodePermissionDenied,
+			}),
 		},
 
 		// DELETE /products/{id}
@@ -141,30 +557,828 @@ func TestServer(t *testing.T) {
 					gomock.Any(),
 					"1",
 				).Return(
-					&product.Product{ID: "1", Name: "p1", Price: 100, Seller: "1"},
+					&product.Product{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"},
 					nil,
 				)
 			},
 			wantStatus: http.StatusOK,
-			wantBody:   testBody(&product.Product{ID: "1", Name: "p1", Price: 100, Seller: "1"}),
+			wantBody:   testBody(&product.Product{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1"}),
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			ctrl := gomock.NewController(t)
-			defer ctrl.Finish()
 
-			as := mock.NewHTTPAuthService(ctrl)
-			ps := mock.NewProductService(ctrl)
+		// POST /products/{id}/restore
+		{
+			name: "Should restore product",
+			req:  httptest.NewRequest(http.MethodPost, "/products/1/restore", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
 
-			if tt.setupMocks != nil {
-				tt.setupMocks(as, ps)
-			}
+				ps.EXPECT().Restore(
+					gomock.Any(),
+					"1",
+				).Return(
+					&product.Product{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", Version: 1},
+					nil,
+				)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   testBody(&product.Product{ID: "1", Name: "p1", Price: money.New(100, "USD"), Seller: "1", Version: 1}),
+		},
+		{
+			name: "Should return not found problem for product which is not deleted",
+			req:  httptest.NewRequest(http.MethodPost, "/products/1/restore", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
 
-			s := &Server{
-				AuthService:    as,
-				ProductService: ps,
-			}
+				ps.EXPECT().Restore(
+					gomock.Any(),
+					"1",
+				).Return(
+					nil,
+					fmt.Errorf("restore product: %w", product.ErrNotFound),
+				)
+			},
+			wantStatus: http.StatusNotFound,
+			wantBody: testBody(handler.NewProblem(
+				http.StatusNotFound, handler.CodeNotFound, "restore product: product not found",
+			)),
+		},
+
+		// GET /products/{id}/history
+		{
+			name: "Should return product history",
+			req:  httptest.NewRequest(http.MethodGet, "/products/1/history?offset=0&limit=10", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+
+				ps.EXPECT().History(
+					gomock.Any(),
+					audit.FindRequest{Product: "1", Offset: 0, Limit: 10},
+				).Return(
+					[]*audit.Entry{{
+						ID: "1", Product: "1", Seller: "1", Actor: "1", Operation: audit.OperationUpdate, Version: 2,
+						Changes:   []audit.Change{{Field: "price", Before: "100", After: "150"}},
+						CreatedAt: time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC),
+					}},
+					nil,
+				)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: testBody([]*audit.Entry{{
+				ID: "1", Product: "1", Seller: "1", Actor: "1", Operation: audit.OperationUpdate, Version: 2,
+				Changes:   []audit.Change{{Field: "price", Before: "100", After: "150"}},
+				CreatedAt: time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC),
+			}}),
+		},
+		{
+			name: "Should return bad request problem for history without limit",
+			req:  httptest.NewRequest(http.MethodGet, "/products/1/history?offset=0", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody: testBody(handler.NewProblem(
+				http.StatusBadRequest, handler.CodeBadRequest, "valid limit query parameter required",
+			)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			as := mock.NewHTTPAuthService(ctrl)
+			ps := mock.NewProductService(ctrl)
+
+			if tt.setupMocks != nil {
+				tt.setupMocks(as, ps)
+			}
+
+			s := &Server{
+				AuthService:    as,
+				ProductService: ps,
+			}
+
+			w := httptest.NewRecorder()
+			s.Handler().ServeHTTP(w, tt.req)
+			res := w.Result()
+
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("got status %v, want %v", res.StatusCode, tt.wantStatus)
+			}
+
+			body, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("error reading response body: %v", err)
+			}
+			if !reflect.DeepEqual(body, tt.wantBody) {
+				t.Errorf("got body %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServer_Categories(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        *http.Request
+		setupMocks func(as *mock.HTTPAuthService, cs *mock.CategoryService)
+		wantStatus int
+		wantBody   []byte
+	}{
+		{
+			name: "Should return categories",
+			req:  httptest.NewRequest(http.MethodGet, "/categories", nil),
+			setupMocks: func(as *mock.HTTPAuthService, cs *mock.CategoryService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				cs.EXPECT().Find(gomock.Any()).Return([]*category.Category{
+					{ID: "1", Slug: "food", Name: "Food"},
+					{ID: "2", Slug: "fruits", Name: "Fruits", Parent: testStringPtr("1")},
+				}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: testBody([]*category.Category{
+				{ID: "1", Slug: "food", Name: "Food"},
+				{ID: "2", Slug: "fruits", Name: "Fruits", Parent: testStringPtr("1")},
+			}),
+		},
+		{
+			name: "Should return descendants",
+			req:  httptest.NewRequest(http.MethodGet, "/categories/1/descendants", nil),
+			setupMocks: func(as *mock.HTTPAuthService, cs *mock.CategoryService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				cs.EXPECT().Descendants(gomock.Any(), "1").Return([]*category.Category{
+					{ID: "2", Slug: "fruits", Name: "Fruits", Parent: testStringPtr("1")},
+				}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: testBody([]*category.Category{
+				{ID: "2", Slug: "fruits", Name: "Fruits", Parent: testStringPtr("1")},
+			}),
+		},
+		{
+			name: "Should return not found problem",
+			req:  httptest.NewRequest(http.MethodGet, "/categories/1", nil),
+			setupMocks: func(as *mock.HTTPAuthService, cs *mock.CategoryService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				cs.EXPECT().FindOne(gomock.Any(), "1").Return(nil, fmt.Errorf("get category: %w", category.ErrNotFound))
+			},
+			wantStatus: http.StatusNotFound,
+			wantBody: testBody(handler.NewProblem(
+				http.StatusNotFound, handler.CodeNotFound, "get category: category not found",
+			)),
+		},
+		{
+			name: "Should create category",
+			req: httptest.NewRequest(
+				http.MethodPost, "/categories",
+				bytes.NewReader(testBody(category.CreateRequest{Slug: "fruits", Name: "Fruits", Parent: testStringPtr("1")})),
+			),
+			setupMocks: func(as *mock.HTTPAuthService, cs *mock.CategoryService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+
+				cs.EXPECT().Create(
+					gomock.Any(),
+					category.CreateRequest{Slug: "fruits", Name: "Fruits", Parent: testStringPtr("1")},
+				).Return(&category.Category{ID: "2", Slug: "fruits", Name: "Fruits", Parent: testStringPtr("1")}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   testBody(&category.Category{ID: "2", Slug: "fruits", Name: "Fruits", Parent: testStringPtr("1")}),
+		},
+		{
+			name: "Should return validation problem when moving under descendant",
+			req:  httptest.NewRequest(http.MethodPatch, "/categories/1", bytes.NewReader([]byte(`{"parent":"2"}`))),
+			setupMocks: func(as *mock.HTTPAuthService, cs *mock.CategoryService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+
+				cs.EXPECT().Update(
+					gomock.Any(),
+					category.UpdateRequest{ID: "1", Parent: testStringPtr("2")},
+				).Return(nil, fmt.Errorf("update category: %w", category.ErrValidation{
+					Resource: category.Resource,
+					Field:    "parent", Reason: "must not be a descendant",
+				}))
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody: testBody(handler.NewProblem(
+				http.StatusUnprocessableEntity, handler.CodeValidation,
+				"update category: invalid parent: must not be a descendant",
+			)),
+		},
+		{
+			name: "Should delete category",
+			req:  httptest.NewRequest(http.MethodDelete, "/categories/2", nil),
+			setupMocks: func(as *mock.HTTPAuthService, cs *mock.CategoryService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+
+				cs.EXPECT().Delete(gomock.Any(), "2").Return(&category.Category{ID: "2", Slug: "fruits", Name: "Fruits"}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   testBody(&category.Category{ID: "2", Slug: "fruits", Name: "Fruits"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			as := mock.NewHTTPAuthService(ctrl)
+			cs := mock.NewCategoryService(ctrl)
+
+			if tt.setupMocks != nil {
+				tt.setupMocks(as, cs)
+			}
+
+			s := &Server{
+				AuthService:     as,
+				CategoryService: cs,
+			}
+
+			w := httptest.NewRecorder()
+			s.Handler().ServeHTTP(w, tt.req)
+			res := w.Result()
+
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("got status %v, want %v", res.StatusCode, tt.wantStatus)
+			}
+
+			body, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("error reading response body: %v", err)
+			}
+			if !reflect.DeepEqual(body, tt.wantBody) {
+				t.Errorf("got body %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServer_ProductVersions(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        *http.Request
+		setupMocks setupMocks
+		wantStatus int
+		wantETag   string
+		wantBody   []byte
+	}{
+		{
+			name: "Should return product with entity tag",
+			req:  httptest.NewRequest(http.MethodGet, "/products/1", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				ps.EXPECT().FindOne(gomock.Any(), "1").Return(&product.Product{ID: "1", Name: "p1", Seller: "1", Version: 2}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantETag:   `"2"`,
+			wantBody:   testBody(&product.Product{ID: "1", Name: "p1", Seller: "1", Version: 2}),
+		},
+		{
+			name: "Should update product matching entity tag",
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPatch, "/products/1", bytes.NewReader([]byte(`{"name":"p2"}`)))
+				r.Header.Set("If-Match", `"2"`)
+				return r
+			}(),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+
+				ps.EXPECT().Update(
+					gomock.Any(),
+					product.UpdateRequest{ID: "1", Name: testStringPtr("p2"), ExpectedVersion: testInt64Ptr(2)},
+				).Return(&product.Product{ID: "1", Name: "p2", Seller: "1", Version: 3}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantETag:   `"3"`,
+			wantBody:   testBody(&product.Product{ID: "1", Name: "p2", Seller: "1", Version: 3}),
+		},
+		{
+			name: "Should update product matching any entity tag",
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPatch, "/products/1", bytes.NewReader([]byte(`{"name":"p2"}`)))
+				r.Header.Set("If-Match", "*")
+				return r
+			}(),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+
+				ps.EXPECT().Update(
+					gomock.Any(),
+					product.UpdateRequest{ID: "1", Name: testStringPtr("p2")},
+				).Return(&product.Product{ID: "1", Name: "p2", Seller: "1", Version: 3}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantETag:   `"3"`,
+			wantBody:   testBody(&product.Product{ID: "1", Name: "p2", Seller: "1", Version: 3}),
+		},
+		{
+			name: "Should return precondition failed problem on version conflict",
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPatch, "/products/1", bytes.NewReader([]byte(`{"name":"p2"}`)))
+				r.Header.Set("If-Match", `"1"`)
+				return r
+			}(),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+
+				ps.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("update product: %w", product.ErrConflict))
+			},
+			wantStatus: http.StatusPreconditionFailed,
+			wantBody: testBody(handler.NewProblem(
+				http.StatusPreconditionFailed, handler.CodeVersionConflict, "update product: product version conflict",
+			)),
+		},
+		{
+			name: "Should return bad request problem for malformed entity tag",
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPatch, "/products/1", bytes.NewReader([]byte(`{"name":"p2"}`)))
+				r.Header.Set("If-Match", "2")
+				return r
+			}(),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody: testBody(handler.NewProblem(
+				http.StatusBadRequest, handler.CodeBadRequest, "invalid If-Match header, a single quoted version required",
+			)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			as := mock.NewHTTPAuthService(ctrl)
+			ps := mock.NewProductService(ctrl)
+
+			if tt.setupMocks != nil {
+				tt.setupMocks(as, ps)
+			}
+
+			s := &Server{
+				AuthService:    as,
+				ProductService: ps,
+			}
+
+			w := httptest.NewRecorder()
+			s.Handler().ServeHTTP(w, tt.req)
+			res := w.Result()
+
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("got status %v, want %v", res.StatusCode, tt.wantStatus)
+			}
+			if etag := res.Header.Get("ETag"); etag != tt.wantETag {
+				t.Errorf("got ETag %q, want %q", etag, tt.wantETag)
+			}
+
+			body, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("error reading response body: %v", err)
+			}
+			if !reflect.DeepEqual(body, tt.wantBody) {
+				t.Errorf("got body %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServer_Orders(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        *http.Request
+		setupMocks func(as *mock.HTTPAuthService, os *mock.OrderService)
+		wantStatus int
+		wantBody   []byte
+	}{
+		{
+			name: "Should purchase with idempotency key header",
+			req: func() *http.Request {
+				r := httptest.NewRequest(
+					http.MethodPost, "/orders",
+					bytes.NewReader([]byte(`{"product":"1","quantity":2,"idempotency_key":"body"}`)),
+				)
+				r.Header.Set("Idempotency-Key", "header")
+				return r
+			}(),
+			setupMocks: func(as *mock.HTTPAuthService, os *mock.OrderService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "2"}, nil)
+
+				os.EXPECT().Purchase(
+					gomock.Any(),
+					order.PurchaseRequest{Product: "1", Quantity: 2, IdempotencyKey: "header"},
+				).Return(&order.Order{ID: "1", Product: "1", Buyer: "2", Seller: "1", Quantity: 2, Price: 100, Total: 200}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: testBody(&order.Order{
+				ID: "1", Product: "1", Buyer: "2", Seller: "1", Quantity: 2, Price: 100, Total: 200,
+			}),
+		},
+		{
+			name: "Should return payment required problem",
+			req: httptest.NewRequest(
+				http.MethodPost, "/orders",
+				bytes.NewReader([]byte(`{"product":"1","quantity":2,"idempotency_key":"k"}`)),
+			),
+			setupMocks: func(as *mock.HTTPAuthService, os *mock.OrderService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "2"}, nil)
+
+				os.EXPECT().Purchase(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("purchase: %w", order.ErrInsufficientFunds))
+			},
+			wantStatus: http.StatusPaymentRequired,
+			wantBody: testBody(handler.NewProblem(
+				http.StatusPaymentRequired, handler.CodeInsufficientFunds, "purchase: insufficient funds",
+			)),
+		},
+		{
+			name: "Should return orders",
+			req:  httptest.NewRequest(http.MethodGet, "/orders?offset=0&limit=10&seller=1", nil),
+			setupMocks: func(as *mock.HTTPAuthService, os *mock.OrderService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+
+				os.EXPECT().Find(
+					gomock.Any(),
+					order.FindRequest{Offset: 0, Limit: 10, Seller: testStringPtr("1")},
+				).Return([]*order.Order{{ID: "1", Product: "1", Buyer: "2", Seller: "1"}}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   testBody([]*order.Order{{ID: "1", Product: "1", Buyer: "2", Seller: "1"}}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			as := mock.NewHTTPAuthService(ctrl)
+			os := mock.NewOrderService(ctrl)
+
+			if tt.setupMocks != nil {
+				tt.setupMocks(as, os)
+			}
+
+			s := &Server{
+				AuthService:  as,
+				OrderService: os,
+			}
+
+			w := httptest.NewRecorder()
+			s.Handler().ServeHTTP(w, tt.req)
+			res := w.Result()
+
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("got status %v, want %v", res.StatusCode, tt.wantStatus)
+			}
+
+			body, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("error reading response body: %v", err)
+			}
+			if !reflect.DeepEqual(body, tt.wantBody) {
+				t.Errorf("got body %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServer_Reviews(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        *http.Request
+		setupMocks func(as *mock.HTTPAuthService, rs *mock.ReviewService)
+		wantStatus int
+		wantBody   []byte
+	}{
+		{
+			name: "Should return reviews of the product in path",
+			req:  httptest.NewRequest(http.MethodGet, "/products/1/reviews?offset=0&limit=10", nil),
+			setupMocks: func(as *mock.HTTPAuthService, rs *mock.ReviewService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				rs.EXPECT().Find(
+					gomock.Any(),
+					review.FindRequest{Offset: 0, Limit: 10, Product: testStringPtr("1")},
+				).Return([]*review.Review{{ID: "1", Product: "1", Author: "2", Rating: 4}}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   testBody([]*review.Review{{ID: "1", Product: "1", Author: "2", Rating: 4}}),
+		},
+		{
+			name: "Should create review of the product in path",
+			req: httptest.NewRequest(
+				http.MethodPost, "/products/1/reviews",
+				bytes.NewReader([]byte(`{"rating":4,"text":"Tasty"}`)),
+			),
+			setupMocks: func(as *mock.HTTPAuthService, rs *mock.ReviewService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "2"}, nil)
+
+				rs.EXPECT().Create(
+					gomock.Any(),
+					review.CreateRequest{Product: "1", Rating: 4, Text: "Tasty"},
+				).Return(&review.Review{ID: "1", Product: "1", Author: "2", Rating: 4, Text: "Tasty"}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   testBody(&review.Review{ID: "1", Product: "1", Author: "2", Rating: 4, Text: "Tasty"}),
+		},
+		{
+			name: "Should return conflict problem for second review",
+			req: httptest.NewRequest(
+				http.MethodPost, "/products/1/reviews",
+				bytes.NewReader([]byte(`{"rating":4}`)),
+			),
+			setupMocks: func(as *mock.HTTPAuthService, rs *mock.ReviewService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "2"}, nil)
+
+				rs.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("create review: %w", review.ErrDuplicate))
+			},
+			wantStatus: http.StatusConflict,
+			wantBody: testBody(handler.NewProblem(
+				http.StatusConflict, handler.CodeAlreadyReviewed, "create review: product already reviewed",
+			)),
+		},
+		{
+			name: "Should update review",
+			req: httptest.NewRequest(
+				http.MethodPatch, "/products/1/reviews/3",
+				bytes.NewReader([]byte(`{"rating":2}`)),
+			),
+			setupMocks: func(as *mock.HTTPAuthService, rs *mock.ReviewService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "2"}, nil)
+
+				rs.EXPECT().Update(
+					gomock.Any(),
+					review.UpdateRequest{Product: "1", ID: "3", Rating: testInt64Ptr(2)},
+				).Return(&review.Review{ID: "3", Product: "1", Author: "2", Rating: 2}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   testBody(&review.Review{ID: "3", Product: "1", Author: "2", Rating: 2}),
+		},
+		{
+			name: "Should return not found problem for review of another product",
+			req:  httptest.NewRequest(http.MethodDelete, "/products/1/reviews/3", nil),
+			setupMocks: func(as *mock.HTTPAuthService, rs *mock.ReviewService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "2"}, nil)
+
+				rs.EXPECT().Delete(gomock.Any(), "1", "3").Return(nil, fmt.Errorf("delete review: %w", review.ErrNotFound))
+			},
+			wantStatus: http.StatusNotFound,
+			wantBody: testBody(handler.NewProblem(
+				http.StatusNotFound, handler.CodeNotFound, "delete review: review not found",
+			)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			as := mock.NewHTTPAuthService(ctrl)
+			rs := mock.NewReviewService(ctrl)
+
+			if tt.setupMocks != nil {
+				tt.setupMocks(as, rs)
+			}
+
+			s := &Server{
+				AuthService:   as,
+				ReviewService: rs,
+			}
+
+			w := httptest.NewRecorder()
+			s.Handler().ServeHTTP(w, tt.req)
+			res := w.Result()
+
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("got status %v, want %v", res.StatusCode, tt.wantStatus)
+			}
+
+			body, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("error reading response body: %v", err)
+			}
+			if !reflect.DeepEqual(body, tt.wantBody) {
+				t.Errorf("got body %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServer_Cart(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        *http.Request
+		setupMocks func(as *mock.HTTPAuthService, cs *mock.CartService)
+		wantStatus int
+		wantBody   []byte
+	}{
+		{
+			name: "Should update item of the product in path",
+			req:  httptest.NewRequest(http.MethodPatch, "/cart/items/1", bytes.NewReader([]byte(`{"quantity":3}`))),
+			setupMocks: func(as *mock.HTTPAuthService, cs *mock.CartService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "2"}, nil)
+
+				cs.EXPECT().UpdateItem(
+					gomock.Any(),
+					cart.UpdateRequest{Product: "1", Quantity: 3},
+				).Return(&cart.Summary{
+					User:  "2",
+					Lines: []cart.Line{{Item: cart.Item{Product: "1", Quantity: 3, Price: 100}, CurrentPrice: 100, Subtotal: 300}},
+					Total: 300,
+				}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: testBody(&cart.Summary{
+				User:  "2",
+				Lines: []cart.Line{{Item: cart.Item{Product: "1", Quantity: 3, Price: 100}, CurrentPrice: 100, Subtotal: 300}},
+				Total: 300,
+			}),
+		},
+		{
+			name: "Should return not found problem for missing item",
+			req:  httptest.NewRequest(http.MethodDelete, "/cart/items/1", nil),
+			setupMocks: func(as *mock.HTTPAuthService, cs *mock.CartService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "2"}, nil)
+
+				cs.EXPECT().RemoveItem(gomock.Any(), "1").Return(nil, fmt.Errorf("remove cart item: %w", cart.ErrItemNotFound))
+			},
+			wantStatus: http.StatusNotFound,
+			wantBody: testBody(handler.NewProblem(
+				http.StatusNotFound, handler.CodeNotFound, "remove cart item: cart item not found",
+			)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			as := mock.NewHTTPAuthService(ctrl)
+			cs := mock.NewCartService(ctrl)
+
+			if tt.setupMocks != nil {
+				tt.setupMocks(as, cs)
+			}
+
+			s := &Server{
+				AuthService: as,
+				CartService: cs,
+			}
+
+			w := httptest.NewRecorder()
+			s.Handler().ServeHTTP(w, tt.req)
+			res := w.Result()
+
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("got status %v, want %v", res.StatusCode, tt.wantStatus)
+			}
+
+			body, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("error reading response body: %v", err)
+			}
+			if !reflect.DeepEqual(body, tt.wantBody) {
+				t.Errorf("got body %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServer_Media(t *testing.T) {
+	m := media.Media{ID: "a1", ContentType: "image/png", Size: 5, Width: 2, Height: 1}
+
+	tests := []struct {
+		name            string
+		req             *http.Request
+		setupMocks      setupMocks
+		wantStatus      int
+		wantContentType string
+		wantBody        []byte
+	}{
+		{
+			name: "Should add uploaded media",
+			req:  testMediaUpload("file", "image/png", "image"),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+
+				ps.EXPECT().AddMedia(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, r product.AddMediaRequest) (*product.Product, error) {
+						body, err := ioutil.ReadAll(r.Body)
+						if err != nil {
+							return nil, err
+						}
+						if r.Product != "1" || r.ContentType != "image/png" || string(body) != "image" {
+							t.Errorf("AddMedia() got %s %s %q, want 1 image/png %q", r.Product, r.ContentType, body, "image")
+						}
+						return &product.Product{ID: "1", Name: "p1", Seller: "1", Media: []media.Media{m}, Version: 2}, nil
+					},
+				)
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        testBody(&product.Product{ID: "1", Name: "p1", Seller: "1", Media: []media.Media{m}, Version: 2}),
+		},
+		{
+			name: "Should return bad request problem for form without file",
+			req:  testMediaUpload("image", "image/png", "image"),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+			},
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/problem+json",
+			wantBody: testBody(handler.NewProblem(
+				http.StatusBadRequest, handler.CodeBadRequest, "no file field in the form",
+			)),
+		},
+		{
+			name: "Should return validation problem for invalid media",
+			req:  testMediaUpload("file", "text/plain", "text"),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+
+				ps.EXPECT().AddMedia(gomock.Any(), gomock.Any()).Return(
+					nil, product.ErrValidation{Resource: product.Resource, Field: "content_type", Reason: "must be an image"},
+				)
+			},
+			wantStatus:      http.StatusUnprocessableEntity,
+			wantContentType: "application/problem+json",
+			wantBody: testBody(handler.NewProblem(
+				http.StatusUnprocessableEntity, handler.CodeValidation, "invalid content_type: must be an image",
+			)),
+		},
+		{
+			name: "Should remove media",
+			req:  httptest.NewRequest(http.MethodDelete, "/products/1/media/a1", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(&user.User{ID: "1"}, nil)
+
+				ps.EXPECT().RemoveMedia(gomock.Any(), "1", "a1").Return(&product.Product{ID: "1", Name: "p1", Seller: "1", Version: 3}, nil)
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        testBody(&product.Product{ID: "1", Name: "p1", Seller: "1", Version: 3}),
+		},
+		{
+			name: "Should return media",
+			req:  httptest.NewRequest(http.MethodGet, "/products/1/media/a1", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				ps.EXPECT().OpenMedia(gomock.Any(), "1", "a1", false).Return(
+					&media.Content{ContentType: "image/png", Body: ioutil.NopCloser(strings.NewReader("image"))}, nil,
+				)
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "image/png",
+			wantBody:        []byte("image"),
+		},
+		{
+			name: "Should return thumbnail",
+			req:  httptest.NewRequest(http.MethodGet, "/products/1/media/a1/thumbnail", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				ps.EXPECT().OpenMedia(gomock.Any(), "1", "a1", true).Return(
+					&media.Content{ContentType: "image/jpeg", Body: ioutil.NopCloser(strings.NewReader("thumbnail"))}, nil,
+				)
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "image/jpeg",
+			wantBody:        []byte("thumbnail"),
+		},
+		{
+			name: "Should return not found problem for unknown media",
+			req:  httptest.NewRequest(http.MethodGet, "/products/1/media/b2", nil),
+			setupMocks: func(as *mock.HTTPAuthService, ps *mock.ProductService) {
+				as.EXPECT().Authorize(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+				ps.EXPECT().OpenMedia(gomock.Any(), "1", "b2", false).Return(nil, fmt.Errorf("get media: %w", product.ErrMediaNotFound))
+			},
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "application/problem+json",
+			wantBody: testBody(handler.NewProblem(
+				http.StatusNotFound, handler.CodeNotFound, "get media: media not found",
+			)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			as := mock.NewHTTPAuthService(ctrl)
+			ps := mock.NewProductService(ctrl)
+
+			if tt.setupMocks != nil {
+				tt.setupMocks(as, ps)
+			}
+
+			s := &Server{
+				AuthService:    as,
+				ProductService: ps,
+			}
 
 			w := httptest.NewRecorder()
 			s.Handler().ServeHTTP(w, tt.req)
@@ -173,6 +1387,9 @@ func TestServer(t *testing.T) {
 			if res.StatusCode != tt.wantStatus {
 				t.Errorf("got status %v, want %v", res.StatusCode, tt.wantStatus)
 			}
+			if got := res.Header.Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("got content type %q, want %q", got, tt.wantContentType)
+			}
 
 			body, err := ioutil.ReadAll(res.Body)
 			if err != nil {
@@ -185,6 +1402,30 @@ func TestServer(t *testing.T) {
 	}
 }
 
+// testMediaUpload returns a request uploading the data of the content type
+// in the form field to the product "1".
+func testMediaUpload(field, contentType, data string) *http.Request {
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename="image"`, field))
+	h.Set("Content-Type", contentType)
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		log.Fatalf("error creating test form: %v", err)
+	}
+	if _, err := part.Write([]byte(data)); err != nil {
+		log.Fatalf("error writing test form: %v", err)
+	}
+	if err := mw.Close(); err != nil {
+		log.Fatalf("error closing test form: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/products/1/media", &b)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
 func testBody(v interface{}) []byte {
 	var b bytes.Buffer
 	err := json.NewEncoder(&b).Encode(v)
@@ -194,10 +1435,25 @@ func testBody(v interface{}) []byte {
 	return b.Bytes()
 }
 
+// testDeletedAt is the deletion time of deleted products.
+var testDeletedAt = time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+
 func testStringPtr(s string) *string {
 	return &s
 }
 
+func testStatusPtr(s product.Status) *product.Status {
+	return &s
+}
+
+func testMoneyPtr(m money.Money) *money.Money {
+	return &m
+}
+
+func testFloat64Ptr(f float64) *float64 {
+	return &f
+}
+
 func testInt64Ptr(i int64) *int64 {
 	return &i
 }
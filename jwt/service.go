@@ -8,11 +8,13 @@ import (
 	"github.com/ortymid/market/market/user"
 	"io/ioutil"
 	"net/http"
+	"sync"
 )
 
 type Service struct {
 	URL string
 
+	mu          sync.Mutex
 	cacheSecret interface{}
 }
 
@@ -32,6 +34,9 @@ func (s *Service) Authorize(ctx context.Context, token interface{}) (*user.User,
 
 func (s *Service) SecretContext(ctx context.Context) func() (interface{}, error) {
 	return func() (interface{}, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
 		if s.cacheSecret == nil {
 			secret, err := s.fetchSecret(ctx)
 			if err != nil {
@@ -0,0 +1,57 @@
+// Package audit keeps the history of product changes, so it can be told who
+// changed a product and when.
+package audit
+
+import "time"
+
+type Operation string
+
+const (
+	OperationCreate  Operation = "create"
+	OperationUpdate  Operation = "update"
+	OperationDelete  Operation = "delete"
+	OperationRestore Operation = "restore"
+)
+
+// Change is a change of a product field. Before and After are JSON encoded
+// values of the field, Before is empty for created and restored products and
+// After is empty for deleted ones.
+type Change struct {
+	Field  string `json:"field"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// Entry records an operation on a product.
+type Entry struct {
+	ID      string `json:"id" bson:"_id,omitempty"`
+	Product string `json:"product"`
+	// Seller is the seller of the product, who may see its history.
+	Seller string `json:"seller"`
+	// Actor is the user who made the change.
+	Actor     string    `json:"actor"`
+	Operation Operation `json:"operation"`
+	// Version is the version of the product after the change, or of the
+	// deleted product.
+	Version   int64     `json:"version"`
+	Changes   []Change  `json:"changes"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+type FindRequest struct {
+	Product string
+	Offset  int64
+	Limit   int64
+}
+
+// Page returns the entries of the page requested by offset and limit.
+func (r FindRequest) Page(es []*Entry) []*Entry {
+	if r.Offset >= int64(len(es)) {
+		return []*Entry{}
+	}
+	es = es[r.Offset:]
+	if r.Limit < int64(len(es)) {
+		es = es[:r.Limit]
+	}
+	return es
+}
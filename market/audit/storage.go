@@ -0,0 +1,18 @@
+package audit
+
+import "context"
+
+//go:generate mockgen -destination=../../mock/audit_storage.go -package mock -mock_names=Storage=AuditStorage . Storage
+
+// Sink receives the entries of product changes.
+type Sink interface {
+	// Record stores the entry assigning it an id.
+	Record(ctx context.Context, e Entry) error
+}
+
+// Storage is a sink which keeps the entries, so they can be found later.
+type Storage interface {
+	Sink
+	// Find returns entries of the product in the order of recording.
+	Find(ctx context.Context, r FindRequest) ([]*Entry, error)
+}
@@ -2,15 +2,14 @@ package auth
 
 import (
 	"context"
-	"errors"
 	"github.com/ortymid/market/market/user"
 )
 
-type contextKey struct{}
+type contextKey int
 
-var (
-	ContextKeyUser  contextKey
-	ContextKeyToken contextKey
+const (
+	ContextKeyUser contextKey = iota
+	ContextKeyToken
 )
 
 func NewContextWithUser(ctx context.Context, u *user.User) context.Context {
@@ -20,7 +19,7 @@ func NewContextWithUser(ctx context.Context, u *user.User) context.Context {
 func UserFromContext(ctx context.Context) (*user.User, error) {
 	u, ok := ctx.Value(ContextKeyUser).(*user.User)
 	if !ok {
-		return u, errors.New("user not provided")
+		return u, ErrNoUser
 	}
 	return u, nil
 }
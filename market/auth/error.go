@@ -1,6 +1,13 @@
 package auth
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoUser is returned when an operation requires an authenticated user,
+// but none is provided.
+var ErrNoUser = errors.New("user not provided")
 
 type ErrPermission struct {
 	Reason string
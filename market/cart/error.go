@@ -0,0 +1,20 @@
+package cart
+
+import "github.com/ortymid/market/market/errs"
+
+// Resource names the carts in the shared errors.
+const Resource = "cart"
+
+// ErrNotFound is returned by storages when the user has no cart.
+var ErrNotFound error = errs.NotFound{Resource: Resource}
+
+// ErrItemNotFound is returned when the product is not in the cart.
+var ErrItemNotFound error = errs.NotFound{Resource: "cart item"}
+
+// ErrValidation is returned when a request contains invalid data.
+type ErrValidation = errs.Validation
+
+// invalid returns ErrValidation of the cart field.
+func invalid(field, reason string) ErrValidation {
+	return ErrValidation{Resource: Resource, Field: field, Reason: reason}
+}
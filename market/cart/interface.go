@@ -0,0 +1,16 @@
+package cart
+
+import "context"
+
+//go:generate mockgen -destination=../../mock/cart_service.go -package mock -mock_names=Interface=CartService . Interface
+
+// Interface manages the cart of the current user. Every method returns the
+// cart priced against the current product prices.
+type Interface interface {
+	Get(ctx context.Context) (*Summary, error)
+	// AddItem adds the quantity of the product to the cart.
+	AddItem(ctx context.Context, r AddRequest) (*Summary, error)
+	// UpdateItem sets the quantity of the product in the cart.
+	UpdateItem(ctx context.Context, r UpdateRequest) (*Summary, error)
+	RemoveItem(ctx context.Context, product string) (*Summary, error)
+}
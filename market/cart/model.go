@@ -0,0 +1,102 @@
+// Package cart provides shopping carts. Every user has one cart collecting
+// products before checkout. Carts expire when they are not changed for a
+// while. Carts are priced in money.DefaultCurrency, the currency of the
+// balances.
+package cart
+
+import "time"
+
+// Limits of a cart.
+const (
+	MaxItems    = 100
+	MaxQuantity = 1000
+)
+
+// Item is a product in the cart.
+type Item struct {
+	Product  string `json:"product"`
+	Quantity int64  `json:"quantity"`
+	// Price of a unit when the product was added.
+	Price   int64     `json:"price"`
+	AddedAt time.Time `json:"added_at" bson:"added_at"`
+}
+
+// Cart is the stored cart of a user.
+type Cart struct {
+	User      string    `json:"user" bson:"_id"`
+	Items     []Item    `json:"items"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// Expired reports whether the cart is expired at the time.
+func (c *Cart) Expired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
+
+// item returns the index of the item with the product, or -1.
+func (c *Cart) item(product string) int {
+	for i, it := range c.Items {
+		if it.Product == product {
+			return i
+		}
+	}
+	return -1
+}
+
+// Line is an item of the cart priced against the current product.
+type Line struct {
+	Item
+	// CurrentPrice is the price of a unit now. It is zero for deleted
+	// products and products repriced in another currency.
+	CurrentPrice int64 `json:"current_price"`
+	Subtotal     int64 `json:"subtotal"`
+	// Deleted is set if the product does not exist anymore.
+	Deleted bool `json:"deleted"`
+	// Repriced is set if the price changed since the product was added. A
+	// product repriced in another currency is not counted in the total.
+	Repriced bool `json:"repriced"`
+}
+
+// Summary is the cart of a user with its totals. Deleted products are not
+// counted in the total.
+type Summary struct {
+	User  string `json:"user"`
+	Lines []Line `json:"lines"`
+	Total int64  `json:"total"`
+	// ExpiresAt is not set for carts which are not stored.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type AddRequest struct {
+	Product  string `json:"product"`
+	Quantity int64  `json:"quantity"`
+}
+
+// Validate checks that the request contains valid item data.
+func (r AddRequest) Validate() error {
+	if len(r.Product) == 0 {
+		return invalid("product", "must not be empty")
+	}
+	return validateQuantity(r.Quantity)
+}
+
+type UpdateRequest struct {
+	Product  string `json:"product"` // Required to find the item.
+	Quantity int64  `json:"quantity"`
+}
+
+// Validate checks that the request contains valid item data.
+func (r UpdateRequest) Validate() error {
+	return validateQuantity(r.Quantity)
+}
+
+func validateQuantity(q int64) error {
+	if q <= 0 {
+		return invalid("quantity", "must be positive")
+	}
+	if q > MaxQuantity {
+		return invalid("quantity", "must not be greater than 1000")
+	}
+	return nil
+}
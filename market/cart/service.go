@@ -0,0 +1,265 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/user"
+	"math"
+	"time"
+)
+
+// DefaultTTL is the time a cart is kept after its last change.
+const DefaultTTL = 7 * 24 * time.Hour
+
+type Service struct {
+	Storage Storage
+	// Products price the items of the cart.
+	Products product.Interface
+	// TTL is the time a cart is kept after its last change, DefaultTTL if
+	// zero.
+	TTL time.Duration
+}
+
+// Get returns the cart of the current user. A user without a cart, or with
+// an expired one, gets an empty cart.
+func (s *Service) Get(ctx context.Context) (*Summary, error) {
+	u, err := currentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get cart: %w", err)
+	}
+
+	c, err := s.get(ctx, u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get cart: %w", err)
+	}
+
+	sum, err := s.summarize(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("get cart: %w", err)
+	}
+
+	return sum, nil
+}
+
+// AddItem adds the quantity of the product to the cart of the current user.
+// The item is priced at the current price of the product, so adding a
+// repriced product clears its repriced flag.
+func (s *Service) AddItem(ctx context.Context, r AddRequest) (*Summary, error) {
+	u, err := currentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("add cart item: %w", err)
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("add cart item: %w", err)
+	}
+
+	p, err := s.Products.FindOne(ctx, r.Product)
+	if err != nil {
+		return nil, fmt.Errorf("add cart item: %w", err)
+	}
+
+	if p.Seller == u.ID {
+		err := auth.ErrPermission{Reason: "own products are not allowed to add to cart"}
+		return nil, fmt.Errorf("add cart item: %w", err)
+	}
+	if p.Price.Currency != money.DefaultCurrency {
+		err := invalid("product", fmt.Sprintf("must be priced in %s", money.DefaultCurrency))
+		return nil, fmt.Errorf("add cart item: %w", err)
+	}
+
+	c, err := s.get(ctx, u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("add cart item: %w", err)
+	}
+
+	if i := c.item(p.ID); i >= 0 {
+		quantity := c.Items[i].Quantity + r.Quantity
+		if err := validateQuantity(quantity); err != nil {
+			return nil, fmt.Errorf("add cart item: %w", err)
+		}
+		c.Items[i].Quantity = quantity
+		c.Items[i].Price = p.Price.Amount
+	} else {
+		if len(c.Items) >= MaxItems {
+			err := invalid("items", "must not be more than 100")
+			return nil, fmt.Errorf("add cart item: %w", err)
+		}
+		c.Items = append(c.Items, Item{
+			Product:  p.ID,
+			Quantity: r.Quantity,
+			Price:    p.Price.Amount,
+			AddedAt:  now(),
+		})
+	}
+
+	sum, err := s.save(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("add cart item: %w", err)
+	}
+
+	return sum, nil
+}
+
+// UpdateItem sets the quantity of the product in the cart of the current
+// user. It returns ErrItemNotFound if the product is not in the cart.
+func (s *Service) UpdateItem(ctx context.Context, r UpdateRequest) (*Summary, error) {
+	u, err := currentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("update cart item: %w", err)
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("update cart item: %w", err)
+	}
+
+	c, err := s.get(ctx, u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("update cart item: %w", err)
+	}
+
+	i := c.item(r.Product)
+	if i < 0 {
+		return nil, fmt.Errorf("update cart item: %w", ErrItemNotFound)
+	}
+	c.Items[i].Quantity = r.Quantity
+
+	sum, err := s.save(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("update cart item: %w", err)
+	}
+
+	return sum, nil
+}
+
+// RemoveItem removes the product from the cart of the current user. It
+// returns ErrItemNotFound if the product is not in the cart.
+func (s *Service) RemoveItem(ctx context.Context, product string) (*Summary, error) {
+	u, err := currentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("remove cart item: %w", err)
+	}
+
+	c, err := s.get(ctx, u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("remove cart item: %w", err)
+	}
+
+	i := c.item(product)
+	if i < 0 {
+		return nil, fmt.Errorf("remove cart item: %w", ErrItemNotFound)
+	}
+	c.Items = append(c.Items[:i], c.Items[i+1:]...)
+
+	sum, err := s.save(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("remove cart item: %w", err)
+	}
+
+	return sum, nil
+}
+
+// get returns the cart of the user. Expired carts are removed and an empty
+// cart is returned instead of them.
+func (s *Service) get(ctx context.Context, user string) (*Cart, error) {
+	c, err := s.Storage.Get(ctx, user)
+	if errors.Is(err, ErrNotFound) {
+		return &Cart{User: user}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Expired(now()) {
+		_, err := s.Storage.Delete(ctx, user)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		return &Cart{User: user}, nil
+	}
+
+	return c, nil
+}
+
+// save prolongs and stores the cart. The cart is priced before it is stored,
+// so carts with a too large total are not saved.
+func (s *Service) save(ctx context.Context, c *Cart) (*Summary, error) {
+	ttl := s.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+
+	c.UpdatedAt = now()
+	c.ExpiresAt = c.UpdatedAt.Add(ttl)
+
+	sum, err := s.summarize(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.Storage.Save(ctx, *c); err != nil {
+		return nil, err
+	}
+
+	return sum, nil
+}
+
+// summarize prices the items of the cart against the current products and
+// flags the deleted and repriced ones.
+func (s *Service) summarize(ctx context.Context, c *Cart) (*Summary, error) {
+	sum := &Summary{User: c.User, Lines: make([]Line, len(c.Items))}
+	if !c.ExpiresAt.IsZero() {
+		expiresAt := c.ExpiresAt
+		sum.ExpiresAt = &expiresAt
+	}
+
+	for i, it := range c.Items {
+		l := Line{Item: it}
+
+		p, err := s.Products.FindOne(ctx, it.Product)
+		switch {
+		case errors.Is(err, product.ErrNotFound):
+			l.Deleted = true
+		case err != nil:
+			return nil, err
+		case p.Price.Currency != money.DefaultCurrency:
+			l.Repriced = true
+		default:
+			l.CurrentPrice = p.Price.Amount
+			l.Repriced = p.Price.Amount != it.Price
+
+			if p.Price.Amount > 0 && it.Quantity > math.MaxInt64/p.Price.Amount {
+				return nil, invalid("quantity", "total is too large")
+			}
+			l.Subtotal = p.Price.Amount * it.Quantity
+
+			if sum.Total > math.MaxInt64-l.Subtotal {
+				return nil, invalid("quantity", "total is too large")
+			}
+			sum.Total += l.Subtotal
+		}
+
+		sum.Lines[i] = l
+	}
+
+	return sum, nil
+}
+
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+func currentUser(ctx context.Context) (*user.User, error) {
+	u, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, auth.ErrNoUser
+	}
+	return u, nil
+}
@@ -0,0 +1,272 @@
+package cart_test
+
+import (
+	"context"
+	"errors"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/cart"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/user"
+	"github.com/ortymid/market/storage/memory"
+	"testing"
+	"time"
+)
+
+// testEnv is a cart service with in-memory dependencies.
+type testEnv struct {
+	service  *cart.Service
+	products *memory.ProductStorage
+	carts    *memory.CartStorage
+}
+
+func newTestEnv() *testEnv {
+	env := &testEnv{
+		products: memory.NewProductStorage(),
+		carts:    memory.NewCartStorage(),
+	}
+	env.service = &cart.Service{
+		Storage:  env.carts,
+		Products: &product.Service{Storage: env.products},
+	}
+	return env
+}
+
+// mustCreateProduct creates a product of the "seller" with the price.
+func (env *testEnv) mustCreateProduct(t *testing.T, price int64) string {
+	t.Helper()
+
+	p, err := env.products.Create(context.Background(), product.CreateRequest{
+		Name: "Banana", Price: money.New(price, money.DefaultCurrency), Seller: "seller",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.ID
+}
+
+func userContext(id string) context.Context {
+	return auth.NewContextWithUser(context.Background(), &user.User{ID: id})
+}
+
+func TestService_AddItem(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		quantity  int64
+		times     int
+		wantErr   error
+		wantQty   int64
+		wantTotal int64
+	}{
+		{
+			name:      "Should add item",
+			ctx:       userContext("buyer"),
+			quantity:  2,
+			times:     1,
+			wantQty:   2,
+			wantTotal: 200,
+		},
+		{
+			name:      "Should merge quantities of the same product",
+			ctx:       userContext("buyer"),
+			quantity:  2,
+			times:     2,
+			wantQty:   4,
+			wantTotal: 400,
+		},
+		{
+			name:     "Should not allow to exceed max quantity",
+			ctx:      userContext("buyer"),
+			quantity: 600,
+			times:    2,
+			wantErr:  cart.ErrValidation{Resource: cart.Resource, Field: "quantity", Reason: "must not be greater than 1000"},
+		},
+		{
+			name:     "Should not allow to add own product",
+			ctx:      userContext("seller"),
+			quantity: 2,
+			times:    1,
+			wantErr:  auth.ErrPermission{Reason: "own products are not allowed to add to cart"},
+		},
+		{
+			name:     "Should require user",
+			ctx:      context.Background(),
+			quantity: 2,
+			times:    1,
+			wantErr:  auth.ErrNoUser,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv()
+			id := env.mustCreateProduct(t, 100)
+
+			var got *cart.Summary
+			var err error
+			for i := 0; i < tt.times && err == nil; i++ {
+				got, err = env.service.AddItem(tt.ctx, cart.AddRequest{Product: id, Quantity: tt.quantity})
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AddItem() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if len(got.Lines) != 1 || got.Lines[0].Quantity != tt.wantQty {
+				t.Fatalf("AddItem() got lines = %+v, want one with quantity %d", got.Lines, tt.wantQty)
+			}
+			if got.Total != tt.wantTotal {
+				t.Errorf("AddItem() got total = %d, want %d", got.Total, tt.wantTotal)
+			}
+			if got.ExpiresAt == nil || !got.ExpiresAt.After(time.Now()) {
+				t.Errorf("AddItem() got expires at = %v, want a future time", got.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestService_AddItemOtherCurrency(t *testing.T) {
+	env := newTestEnv()
+	p, err := env.products.Create(context.Background(), product.CreateRequest{
+		Name: "Banana", Price: money.New(100, "EUR"), Seller: "seller",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = env.service.AddItem(userContext("buyer"), cart.AddRequest{Product: p.ID, Quantity: 1})
+	want := cart.ErrValidation{Resource: cart.Resource, Field: "product", Reason: "must be priced in USD"}
+	if !errors.Is(err, want) {
+		t.Errorf("AddItem() error = %v, want %v", err, want)
+	}
+}
+
+func TestService_UpdateItem(t *testing.T) {
+	env := newTestEnv()
+	id := env.mustCreateProduct(t, 100)
+	ctx := userContext("buyer")
+
+	_, err := env.service.UpdateItem(ctx, cart.UpdateRequest{Product: id, Quantity: 3})
+	if !errors.Is(err, cart.ErrItemNotFound) {
+		t.Fatalf("UpdateItem() of missing item error = %v, want %v", err, cart.ErrItemNotFound)
+	}
+
+	if _, err := env.service.AddItem(ctx, cart.AddRequest{Product: id, Quantity: 1}); err != nil {
+		t.Fatalf("AddItem() error = %v", err)
+	}
+
+	got, err := env.service.UpdateItem(ctx, cart.UpdateRequest{Product: id, Quantity: 3})
+	if err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+	if got.Lines[0].Quantity != 3 || got.Total != 300 {
+		t.Errorf("UpdateItem() got quantity = %d, total = %d, want 3, 300", got.Lines[0].Quantity, got.Total)
+	}
+}
+
+func TestService_RemoveItem(t *testing.T) {
+	env := newTestEnv()
+	id := env.mustCreateProduct(t, 100)
+	other := env.mustCreateProduct(t, 50)
+	ctx := userContext("buyer")
+
+	for _, p := range []string{id, other} {
+		if _, err := env.service.AddItem(ctx, cart.AddRequest{Product: p, Quantity: 1}); err != nil {
+			t.Fatalf("AddItem() error = %v", err)
+		}
+	}
+
+	got, err := env.service.RemoveItem(ctx, id)
+	if err != nil {
+		t.Fatalf("RemoveItem() error = %v", err)
+	}
+	if len(got.Lines) != 1 || got.Lines[0].Product != other || got.Total != 50 {
+		t.Errorf("RemoveItem() got = %+v, want only product %s", got, other)
+	}
+
+	if _, err := env.service.RemoveItem(ctx, id); !errors.Is(err, cart.ErrItemNotFound) {
+		t.Errorf("RemoveItem() repeated error = %v, want %v", err, cart.ErrItemNotFound)
+	}
+}
+
+func TestService_GetFlagsChangedProducts(t *testing.T) {
+	env := newTestEnv()
+	kept := env.mustCreateProduct(t, 100)
+	repriced := env.mustCreateProduct(t, 100)
+	deleted := env.mustCreateProduct(t, 100)
+	converted := env.mustCreateProduct(t, 100)
+	ctx := userContext("buyer")
+
+	for _, p := range []string{kept, repriced, deleted, converted} {
+		if _, err := env.service.AddItem(ctx, cart.AddRequest{Product: p, Quantity: 2}); err != nil {
+			t.Fatalf("AddItem() error = %v", err)
+		}
+	}
+
+	price := money.New(120, money.DefaultCurrency)
+	if _, err := env.products.Update(context.Background(), product.UpdateRequest{ID: repriced, Price: &price}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.products.Delete(context.Background(), deleted, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	euros := money.New(90, "EUR")
+	if _, err := env.products.Update(context.Background(), product.UpdateRequest{ID: converted, Price: &euros}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := env.service.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	want := []struct {
+		currentPrice int64
+		deleted      bool
+		repriced     bool
+	}{
+		{currentPrice: 100},
+		{currentPrice: 120, repriced: true},
+		{deleted: true},
+		{repriced: true}, // not counted, as it is priced in another currency
+	}
+	for i, w := range want {
+		l := got.Lines[i]
+		if l.CurrentPrice != w.currentPrice || l.Deleted != w.deleted || l.Repriced != w.repriced || l.Price != 100 {
+			t.Errorf("Get() got line %d = %+v, want %+v", i, l, w)
+		}
+	}
+	if got.Total != 440 {
+		t.Errorf("Get() got total = %d, want 440", got.Total)
+	}
+}
+
+func TestService_GetExpired(t *testing.T) {
+	env := newTestEnv()
+	id := env.mustCreateProduct(t, 100)
+
+	now := time.Now().UTC()
+	_, err := env.carts.Save(context.Background(), cart.Cart{
+		User:      "buyer",
+		Items:     []cart.Item{{Product: id, Quantity: 1, Price: 100, AddedAt: now.Add(-2 * time.Hour)}},
+		UpdatedAt: now.Add(-2 * time.Hour),
+		ExpiresAt: now.Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := env.service.Get(userContext("buyer"))
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(got.Lines) != 0 || got.Total != 0 || got.ExpiresAt != nil {
+		t.Errorf("Get() got = %+v, want an empty cart", got)
+	}
+
+	if _, err := env.carts.Get(context.Background(), "buyer"); !errors.Is(err, cart.ErrNotFound) {
+		t.Errorf("Get() expired cart from storage error = %v, want %v", err, cart.ErrNotFound)
+	}
+}
@@ -0,0 +1,15 @@
+package cart
+
+import "context"
+
+//go:generate mockgen -destination=../../mock/cart_storage.go -package mock -mock_names=Storage=CartStorage . Storage
+
+type Storage interface {
+	// Get returns the cart of the user. Expired carts may still be returned
+	// until they are removed.
+	Get(ctx context.Context, user string) (*Cart, error)
+	// Save creates or replaces the cart of the user. Storages may remove the
+	// cart once it expires.
+	Save(ctx context.Context, c Cart) (*Cart, error)
+	Delete(ctx context.Context, user string) (*Cart, error)
+}
@@ -0,0 +1,16 @@
+package category
+
+import "github.com/ortymid/market/market/errs"
+
+// Resource names the categories in the shared errors.
+const Resource = "category"
+
+var ErrNotFound error = errs.NotFound{Resource: Resource}
+
+// ErrValidation is returned when a request contains invalid data.
+type ErrValidation = errs.Validation
+
+// invalid returns ErrValidation of the category field.
+func invalid(field, reason string) ErrValidation {
+	return ErrValidation{Resource: Resource, Field: field, Reason: reason}
+}
@@ -0,0 +1,14 @@
+package category
+
+import "context"
+
+//go:generate mockgen -destination=../../mock/category_service.go -package mock -mock_names=Interface=CategoryService . Interface
+
+type Interface interface {
+	Find(ctx context.Context) ([]*Category, error)
+	FindOne(ctx context.Context, id string) (*Category, error)
+	Descendants(ctx context.Context, id string) ([]*Category, error)
+	Create(ctx context.Context, r CreateRequest) (*Category, error)
+	Update(ctx context.Context, r UpdateRequest) (*Category, error)
+	Delete(ctx context.Context, id string) (*Category, error)
+}
@@ -0,0 +1,56 @@
+// Package category provides the taxonomy of products. Categories form a tree,
+// every category has an optional parent.
+package category
+
+import "regexp"
+
+type Category struct {
+	ID     string  `json:"id" bson:"_id"`
+	Slug   string  `json:"slug"`
+	Name   string  `json:"name"`
+	Parent *string `json:"parent,omitempty" bson:"parent,omitempty"` // nil for root categories
+}
+
+type CreateRequest struct {
+	Slug   string  `json:"slug"`
+	Name   string  `json:"name"`
+	Parent *string `json:"parent,omitempty" bson:"parent,omitempty"`
+}
+
+type UpdateRequest struct {
+	ID   string  `json:"-" bson:"-"`                           // Required to find the category.
+	Slug *string `json:"slug,omitempty" bson:"slug,omitempty"` // Optional.
+	Name *string `json:"name,omitempty" bson:"name,omitempty"` // Optional.
+	// Parent moves the category under another one. An empty string moves it
+	// to the root. Optional.
+	Parent *string `json:"parent,omitempty" bson:"parent,omitempty"`
+}
+
+// slugPattern allows lowercase words of letters and digits joined with
+// hyphens, e.g. "home-appliances".
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Validate checks that the request contains a valid category data.
+func (r CreateRequest) Validate() error {
+	if !slugPattern.MatchString(r.Slug) {
+		return invalid("slug", "must be lowercase words joined with hyphens")
+	}
+	if len(r.Name) == 0 {
+		return invalid("name", "must not be empty")
+	}
+	if r.Parent != nil && len(*r.Parent) == 0 {
+		return invalid("parent", "must not be empty")
+	}
+	return nil
+}
+
+// Validate checks that the provided fields contain a valid category data.
+func (r UpdateRequest) Validate() error {
+	if r.Slug != nil && !slugPattern.MatchString(*r.Slug) {
+		return invalid("slug", "must be lowercase words joined with hyphens")
+	}
+	if r.Name != nil && len(*r.Name) == 0 {
+		return invalid("name", "must not be empty")
+	}
+	return nil
+}
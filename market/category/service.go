@@ -0,0 +1,262 @@
+package category
+
+import (
+	"context"
+	"fmt"
+	"github.com/ortymid/market/market/auth"
+	"sort"
+)
+
+type Service struct {
+	Storage Storage
+}
+
+// Find returns all the categories. Parents go before their children, siblings
+// are sorted by name.
+func (s *Service) Find(ctx context.Context) ([]*Category, error) {
+	cs, err := s.Storage.Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list categories: %w", err)
+	}
+
+	return sortTree(cs), nil
+}
+
+// FindOne returns a category for the given id. It returns category.ErrNotFound
+// error if there is no category with such id.
+func (s *Service) FindOne(ctx context.Context, id string) (*Category, error) {
+	c, err := s.Storage.FindOne(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get category: %w", err)
+	}
+
+	return c, nil
+}
+
+// Descendants returns all the descendants of a category in the order of Find.
+// It returns category.ErrNotFound error if there is no category with such id.
+func (s *Service) Descendants(ctx context.Context, id string) ([]*Category, error) {
+	if _, err := s.Storage.FindOne(ctx, id); err != nil {
+		return nil, fmt.Errorf("list descendants: %w", err)
+	}
+
+	cs, err := s.Storage.Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list descendants: %w", err)
+	}
+
+	return descendants(sortTree(cs), id), nil
+}
+
+// Create creates a new category and returns it.
+func (s *Service) Create(ctx context.Context, r CreateRequest) (*Category, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, fmt.Errorf("create category: %w", err)
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("create category: %w", err)
+	}
+
+	cs, err := s.Storage.Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create category: %w", err)
+	}
+
+	if err := checkSlug(cs, "", r.Slug); err != nil {
+		return nil, fmt.Errorf("create category: %w", err)
+	}
+	if r.Parent != nil && find(cs, *r.Parent) == nil {
+		err := invalid("parent", "unknown category")
+		return nil, fmt.Errorf("create category: %w", err)
+	}
+
+	c, err := s.Storage.Create(ctx, r)
+	if err != nil {
+		return nil, fmt.Errorf("create category: %w", err)
+	}
+
+	return c, nil
+}
+
+// Update updates a category for the given id and returns it. It returns
+// category.ErrNotFound error if there is no category with such id.
+func (s *Service) Update(ctx context.Context, r UpdateRequest) (*Category, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, fmt.Errorf("update category: %w", err)
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("update category: %w", err)
+	}
+
+	if _, err := s.Storage.FindOne(ctx, r.ID); err != nil {
+		return nil, fmt.Errorf("update category: %w", err)
+	}
+
+	cs, err := s.Storage.Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("update category: %w", err)
+	}
+
+	if r.Slug != nil {
+		if err := checkSlug(cs, r.ID, *r.Slug); err != nil {
+			return nil, fmt.Errorf("update category: %w", err)
+		}
+	}
+	if r.Parent != nil && len(*r.Parent) > 0 {
+		if err := checkParent(cs, r.ID, *r.Parent); err != nil {
+			return nil, fmt.Errorf("update category: %w", err)
+		}
+	}
+
+	c, err := s.Storage.Update(ctx, r)
+	if err != nil {
+		return nil, fmt.Errorf("update category: %w", err)
+	}
+
+	return c, nil
+}
+
+// Delete deletes a category for the given id. Categories with subcategories
+// cannot be deleted. It returns category.ErrNotFound error if there is no
+// category with such id.
+func (s *Service) Delete(ctx context.Context, id string) (*Category, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, fmt.Errorf("delete category: %w", err)
+	}
+
+	if _, err := s.Storage.FindOne(ctx, id); err != nil {
+		return nil, fmt.Errorf("delete category: %w", err)
+	}
+
+	cs, err := s.Storage.Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("delete category: %w", err)
+	}
+
+	if len(descendants(cs, id)) > 0 {
+		err := invalid("id", "category has subcategories")
+		return nil, fmt.Errorf("delete category: %w", err)
+	}
+
+	c, err := s.Storage.Delete(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("delete category: %w", err)
+	}
+
+	return c, nil
+}
+
+// requireUser returns auth.ErrNoUser if the context has no user.
+func requireUser(ctx context.Context) error {
+	u, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return auth.ErrNoUser
+	}
+	return nil
+}
+
+// checkSlug checks that the slug is not taken by a category other than the
+// one with the given id.
+func checkSlug(cs []*Category, id, slug string) error {
+	for _, c := range cs {
+		if c.Slug == slug && c.ID != id {
+			return invalid("slug", "already taken")
+		}
+	}
+	return nil
+}
+
+// checkParent checks that the category with the given id may be moved under
+// the parent without making a cycle.
+func checkParent(cs []*Category, id, parent string) error {
+	if find(cs, parent) == nil {
+		return invalid("parent", "unknown category")
+	}
+	if parent == id {
+		return invalid("parent", "must not be the category itself")
+	}
+	for _, c := range descendants(cs, id) {
+		if c.ID == parent {
+			return invalid("parent", "must not be a descendant of the category")
+		}
+	}
+	return nil
+}
+
+func find(cs []*Category, id string) *Category {
+	for _, c := range cs {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// sortTree orders categories depth-first, so parents go before their
+// children. Siblings are sorted by name. Categories with unknown parents are
+// treated as roots.
+func sortTree(cs []*Category) []*Category {
+	children := childrenOf(cs)
+
+	var roots []*Category
+	for _, c := range cs {
+		if c.Parent == nil || find(cs, *c.Parent) == nil {
+			roots = append(roots, c)
+		}
+	}
+	sortByName(roots)
+
+	sorted := make([]*Category, 0, len(cs))
+	for _, root := range roots {
+		sorted = walk(sorted, children, root)
+	}
+	return sorted
+}
+
+// descendants returns the descendants of the category depth-first.
+func descendants(cs []*Category, id string) []*Category {
+	children := childrenOf(cs)
+
+	var ds []*Category
+	for _, child := range children[id] {
+		ds = walk(ds, children, child)
+	}
+	return ds
+}
+
+// childrenOf groups categories by their parents. Children are sorted by name.
+func childrenOf(cs []*Category) map[string][]*Category {
+	children := make(map[string][]*Category)
+	for _, c := range cs {
+		if c.Parent != nil {
+			children[*c.Parent] = append(children[*c.Parent], c)
+		}
+	}
+	for _, cs := range children {
+		sortByName(cs)
+	}
+	return children
+}
+
+// walk appends the category and its descendants depth-first.
+func walk(dst []*Category, children map[string][]*Category, c *Category) []*Category {
+	dst = append(dst, c)
+	for _, child := range children[c.ID] {
+		dst = walk(dst, children, child)
+	}
+	return dst
+}
+
+func sortByName(cs []*Category) {
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].Name != cs[j].Name {
+			return cs[i].Name < cs[j].Name
+		}
+		return cs[i].ID < cs[j].ID
+	})
+}
@@ -0,0 +1,318 @@
+package category_test
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/market/user"
+	"github.com/ortymid/market/mock"
+	"reflect"
+	"testing"
+)
+
+type setupMocks func(m *mock.CategoryStorage)
+
+// testTree is:
+//
+//	food
+//	  fruits
+//	    apples
+//	  vegetables
+//	toys
+func testTree() []*category.Category {
+	return []*category.Category{
+		{ID: "4", Slug: "apples", Name: "Apples", Parent: testStringPtr("2")},
+		{ID: "5", Slug: "toys", Name: "Toys"},
+		{ID: "3", Slug: "vegetables", Name: "Vegetables", Parent: testStringPtr("1")},
+		{ID: "2", Slug: "fruits", Name: "Fruits", Parent: testStringPtr("1")},
+		{ID: "1", Slug: "food", Name: "Food"},
+	}
+}
+
+func TestService_Find(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := mock.NewCategoryStorage(ctrl)
+	storage.EXPECT().Find(gomock.Any()).Return(testTree(), nil)
+
+	s := &category.Service{Storage: storage}
+	cs, err := s.Find(context.Background())
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	want := []string{"1", "2", "4", "3", "5"}
+	if got := testIDs(cs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() got ids %v, want %v", got, want)
+	}
+}
+
+func TestService_Descendants(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		setupMocks setupMocks
+		want       []string
+		wantErr    error
+	}{
+		{
+			name: "Should return descendants depth-first",
+			id:   "1",
+			setupMocks: func(m *mock.CategoryStorage) {
+				m.EXPECT().FindOne(gomock.Any(), "1").Return(testTree()[4], nil)
+				m.EXPECT().Find(gomock.Any()).Return(testTree(), nil)
+			},
+			want: []string{"2", "4", "3"},
+		},
+		{
+			name: "Should return no descendants for leaf",
+			id:   "5",
+			setupMocks: func(m *mock.CategoryStorage) {
+				m.EXPECT().FindOne(gomock.Any(), "5").Return(testTree()[1], nil)
+				m.EXPECT().Find(gomock.Any()).Return(testTree(), nil)
+			},
+			want: []string{},
+		},
+		{
+			name: "Should error for unknown category",
+			id:   "6",
+			setupMocks: func(m *mock.CategoryStorage) {
+				m.EXPECT().FindOne(gomock.Any(), "6").Return(nil, category.ErrNotFound)
+			},
+			wantErr: category.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			storage := mock.NewCategoryStorage(ctrl)
+			tt.setupMocks(storage)
+
+			s := &category.Service{Storage: storage}
+			cs, err := s.Descendants(context.Background(), tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Descendants() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := testIDs(cs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Descendants() got ids %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_Create(t *testing.T) {
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		r          category.CreateRequest
+		setupMocks setupMocks
+		want       *category.Category
+		wantErr    bool
+	}{
+		{
+			name: "Should create category",
+			ctx:  ctx,
+			r:    category.CreateRequest{Slug: "pears", Name: "Pears", Parent: testStringPtr("2")},
+			setupMocks: func(m *mock.CategoryStorage) {
+				m.EXPECT().Find(ctx).Return(testTree(), nil)
+				m.EXPECT().Create(ctx, category.CreateRequest{Slug: "pears", Name: "Pears", Parent: testStringPtr("2")}).
+					Return(&category.Category{ID: "6", Slug: "pears", Name: "Pears", Parent: testStringPtr("2")}, nil)
+			},
+			want: &category.Category{ID: "6", Slug: "pears", Name: "Pears", Parent: testStringPtr("2")},
+		},
+		{
+			name:    "Should error when context without user",
+			ctx:     context.Background(),
+			r:       category.CreateRequest{Slug: "pears", Name: "Pears"},
+			wantErr: true,
+		},
+		{
+			name:    "Should error for invalid slug",
+			ctx:     ctx,
+			r:       category.CreateRequest{Slug: "Pears and apples", Name: "Pears"},
+			wantErr: true,
+		},
+		{
+			name: "Should error for taken slug",
+			ctx:  ctx,
+			r:    category.CreateRequest{Slug: "fruits", Name: "Fruits"},
+			setupMocks: func(m *mock.CategoryStorage) {
+				m.EXPECT().Find(ctx).Return(testTree(), nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should error for unknown parent",
+			ctx:  ctx,
+			r:    category.CreateRequest{Slug: "pears", Name: "Pears", Parent: testStringPtr("6")},
+			setupMocks: func(m *mock.CategoryStorage) {
+				m.EXPECT().Find(ctx).Return(testTree(), nil)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			storage := mock.NewCategoryStorage(ctrl)
+			if tt.setupMocks != nil {
+				tt.setupMocks(storage)
+			}
+
+			s := &category.Service{Storage: storage}
+			got, err := s.Create(tt.ctx, tt.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Create() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_Update(t *testing.T) {
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+
+	tests := []struct {
+		name       string
+		r          category.UpdateRequest
+		setupMocks setupMocks
+		want       *category.Category
+		wantErr    bool
+	}{
+		{
+			name: "Should move category to root",
+			r:    category.UpdateRequest{ID: "2", Parent: testStringPtr("")},
+			setupMocks: func(m *mock.CategoryStorage) {
+				m.EXPECT().FindOne(ctx, "2").Return(testTree()[3], nil)
+				m.EXPECT().Find(ctx).Return(testTree(), nil)
+				m.EXPECT().Update(ctx, category.UpdateRequest{ID: "2", Parent: testStringPtr("")}).
+					Return(&category.Category{ID: "2", Slug: "fruits", Name: "Fruits"}, nil)
+			},
+			want: &category.Category{ID: "2", Slug: "fruits", Name: "Fruits"},
+		},
+		{
+			name: "Should error when moving category under its descendant",
+			r:    category.UpdateRequest{ID: "1", Parent: testStringPtr("4")},
+			setupMocks: func(m *mock.CategoryStorage) {
+				m.EXPECT().FindOne(ctx, "1").Return(testTree()[4], nil)
+				m.EXPECT().Find(ctx).Return(testTree(), nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should error when moving category under itself",
+			r:    category.UpdateRequest{ID: "1", Parent: testStringPtr("1")},
+			setupMocks: func(m *mock.CategoryStorage) {
+				m.EXPECT().FindOne(ctx, "1").Return(testTree()[4], nil)
+				m.EXPECT().Find(ctx).Return(testTree(), nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should error for slug of another category",
+			r:    category.UpdateRequest{ID: "1", Slug: testStringPtr("toys")},
+			setupMocks: func(m *mock.CategoryStorage) {
+				m.EXPECT().FindOne(ctx, "1").Return(testTree()[4], nil)
+				m.EXPECT().Find(ctx).Return(testTree(), nil)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			storage := mock.NewCategoryStorage(ctrl)
+			tt.setupMocks(storage)
+
+			s := &category.Service{Storage: storage}
+			got, err := s.Update(ctx, tt.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Update() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_Delete(t *testing.T) {
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+
+	tests := []struct {
+		name       string
+		id         string
+		setupMocks setupMocks
+		want       *category.Category
+		wantErr    bool
+	}{
+		{
+			name: "Should delete leaf category",
+			id:   "4",
+			setupMocks: func(m *mock.CategoryStorage) {
+				m.EXPECT().FindOne(ctx, "4").Return(testTree()[0], nil)
+				m.EXPECT().Find(ctx).Return(testTree(), nil)
+				m.EXPECT().Delete(ctx, "4").Return(testTree()[0], nil)
+			},
+			want: testTree()[0],
+		},
+		{
+			name: "Should error for category with subcategories",
+			id:   "2",
+			setupMocks: func(m *mock.CategoryStorage) {
+				m.EXPECT().FindOne(ctx, "2").Return(testTree()[3], nil)
+				m.EXPECT().Find(ctx).Return(testTree(), nil)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			storage := mock.NewCategoryStorage(ctrl)
+			tt.setupMocks(storage)
+
+			s := &category.Service{Storage: storage}
+			got, err := s.Delete(ctx, tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Delete() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Delete() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testIDs(cs []*category.Category) []string {
+	ids := make([]string, len(cs))
+	for i, c := range cs {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+func testStringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,17 @@
+package category
+
+import "context"
+
+//go:generate mockgen -destination=../../mock/category_storage.go -package mock -mock_names=Storage=CategoryStorage . Storage
+
+// Storage keeps categories. Trees are small, so they are loaded as a whole to
+// be walked by the service.
+type Storage interface {
+	// Find returns all the categories in any order.
+	Find(ctx context.Context) ([]*Category, error)
+	FindOne(ctx context.Context, id string) (*Category, error)
+	Create(ctx context.Context, r CreateRequest) (*Category, error)
+	// Update updates not-nil fields. An empty parent is stored as nil.
+	Update(ctx context.Context, r UpdateRequest) (*Category, error)
+	Delete(ctx context.Context, id string) (*Category, error)
+}
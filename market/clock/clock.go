@@ -0,0 +1,23 @@
+// Package clock abstracts the time, so that background jobs can be tested
+// without waiting for real time to pass.
+package clock
+
+import "time"
+
+type Clock interface {
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time
+	// on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// Real is the clock of the time package.
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+func (Real) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,29 @@
+// Package errs holds the errors shared by the market services. They carry the
+// name of the resource they are about, so that transports map them once for
+// every service.
+package errs
+
+import "fmt"
+
+// NotFound is returned when the resource does not exist. Services declare
+// their values of it, e.g. product.ErrNotFound, to be compared with
+// errors.Is.
+type NotFound struct {
+	Resource string
+}
+
+func (e NotFound) Error() string {
+	return e.Resource + " not found"
+}
+
+// Validation is returned when a request for the resource contains invalid
+// data.
+type Validation struct {
+	Resource string
+	Field    string
+	Reason   string
+}
+
+func (e Validation) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
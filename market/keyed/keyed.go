@@ -0,0 +1,44 @@
+// Package keyed serialises work by key, e.g. the changes of a user or a
+// product, without holding a lock for every key.
+package keyed
+
+import "sync"
+
+// Mutex serialises work by key. The locks of keys nobody holds or waits for
+// are dropped. The zero value is ready to use.
+type Mutex struct {
+	mu    sync.Mutex
+	locks map[string]*lock
+}
+
+type lock struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock locks the key and returns the function unlocking it.
+func (m *Mutex) Lock(key string) (unlock func()) {
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = make(map[string]*lock)
+	}
+	l, ok := m.locks[key]
+	if !ok {
+		l = &lock{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		m.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
+	}
+}
@@ -0,0 +1,140 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"strings"
+)
+
+// ThumbnailSize is the maximum width and height of thumbnails.
+const ThumbnailSize = 256
+
+// ThumbnailContentType is the content type of thumbnails.
+const ThumbnailContentType = "image/jpeg"
+
+// MaxPixels limits the size of decoded images, so that a small file cannot
+// take a lot of memory. A decoded image takes up to 4 bytes per pixel, about
+// 100 MB at the limit.
+const MaxPixels = 24_000_000
+
+// contentTypes are the accepted content types by the image formats.
+var contentTypes = map[string]string{
+	"gif":  "image/gif",
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+}
+
+// ErrUnsupported is returned for data which is not an image of a supported
+// format.
+var ErrUnsupported = fmt.Errorf("must be one of %s", strings.Join(ContentTypes(), ", "))
+
+// ErrTooLarge is returned for images having more than MaxPixels pixels.
+var ErrTooLarge = fmt.Errorf("image must not have more than %d pixels", MaxPixels)
+
+// ContentTypes returns the supported content types in order.
+func ContentTypes() []string {
+	return []string{"image/gif", "image/jpeg", "image/png"}
+}
+
+// Image is a decoded media item.
+type Image struct {
+	image.Image
+	ContentType string
+}
+
+// Decode decodes the image detecting its content type from the data. It
+// returns ErrUnsupported if the data is not an image of a supported format
+// and ErrTooLarge if it has too many pixels.
+func Decode(data []byte) (*Image, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decoding image: %w", err)
+	}
+	contentType, ok := contentTypes[format]
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding image: %w", err)
+	}
+	return &Image{Image: img, ContentType: contentType}, nil
+}
+
+// Thumbnail scales the image down to fit into a size by size square keeping
+// the aspect ratio. Smaller images are not scaled up. Every pixel of the
+// thumbnail is the average of the pixels it covers.
+func Thumbnail(img image.Image, size int) *image.RGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, maxInt(1, h*size/w)
+		} else {
+			w, h = maxInt(1, w*size/h), size
+		}
+	}
+
+	// Converting to RGBA first makes the pixels cheap to read.
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	sw, sh := b.Dx(), b.Dy()
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, maxInt((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, maxInt((x+1)*sw/w, x*sw/w+1)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					i := src.PixOffset(sx, sy)
+					r += uint64(src.Pix[i])
+					g += uint64(src.Pix[i+1])
+					bl += uint64(src.Pix[i+2])
+					a += uint64(src.Pix[i+3])
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(bl / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// EncodeThumbnail writes the thumbnail in ThumbnailContentType. Transparent
+// pixels are put on white, as JPEG has no transparency.
+func EncodeThumbnail(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	canvas := image.NewRGBA(b)
+	draw.Draw(canvas, b, image.White, image.Point{}, draw.Src)
+	draw.Draw(canvas, b, img, b.Min, draw.Over)
+	return jpeg.Encode(w, canvas, &jpeg.Options{Quality: 85})
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,125 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage returns an image of the size filled with the color.
+func testImage(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestDecode(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, testImage(4, 2, color.White)); err != nil {
+		t.Fatal(err)
+	}
+	var jpegData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, testImage(2, 4, color.White), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		data            []byte
+		wantContentType string
+		wantSize        image.Point
+		wantErr         error
+	}{
+		{name: "png", data: pngData.Bytes(), wantContentType: "image/png", wantSize: image.Pt(4, 2)},
+		{name: "jpeg", data: jpegData.Bytes(), wantContentType: "image/jpeg", wantSize: image.Pt(2, 4)},
+		{name: "text", data: []byte("not an image"), wantErr: ErrUnsupported},
+		{
+			// The GIF header of a 5000x5000 image, the pixels are not needed
+			// to reject it.
+			name:    "too large",
+			data:    []byte("GIF89a\x88\x13\x88\x13\x00\x00\x00"),
+			wantErr: ErrTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got.ContentType != tt.wantContentType || got.Bounds().Size() != tt.wantSize {
+				t.Errorf("Decode() got %s of %v, want %s of %v",
+					got.ContentType, got.Bounds().Size(), tt.wantContentType, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+		want image.Point
+	}{
+		{name: "landscape", img: testImage(1000, 500, color.White), want: image.Pt(256, 128)},
+		{name: "portrait", img: testImage(300, 600, color.White), want: image.Pt(128, 256)},
+		{name: "thin", img: testImage(2000, 1, color.White), want: image.Pt(256, 1)},
+		{name: "small", img: testImage(100, 50, color.White), want: image.Pt(100, 50)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Thumbnail(tt.img, ThumbnailSize).Bounds().Size(); got != tt.want {
+				t.Errorf("Thumbnail() got size %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThumbnail_Average(t *testing.T) {
+	// Black and white columns average to grey.
+	img := image.NewRGBA(image.Rect(0, 0, 512, 512))
+	for y := 0; y < 512; y++ {
+		for x := 0; x < 512; x++ {
+			if x%2 == 0 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	got := Thumbnail(img, ThumbnailSize).RGBAAt(10, 10)
+	if want := (color.RGBA{R: 127, G: 127, B: 127, A: 255}); got != want {
+		t.Errorf("Thumbnail() got pixel %v, want %v", got, want)
+	}
+}
+
+func TestEncodeThumbnail(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeThumbnail(&buf, testImage(10, 10, color.Transparent)); err != nil {
+		t.Fatalf("EncodeThumbnail() error = %v", err)
+	}
+
+	got, err := Decode(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.ContentType != ThumbnailContentType {
+		t.Errorf("EncodeThumbnail() got content type %s, want %s", got.ContentType, ThumbnailContentType)
+	}
+	// Transparent pixels are put on white.
+	if r, g, b, _ := got.At(5, 5).RGBA(); r>>8 < 250 || g>>8 < 250 || b>>8 < 250 {
+		t.Errorf("EncodeThumbnail() got pixel %v, want white", got.At(5, 5))
+	}
+}
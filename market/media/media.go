@@ -0,0 +1,43 @@
+// Package media describes images attached to products and the blob stores
+// keeping their bytes.
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+//go:generate mockgen -destination=../../mock/blob_store.go -package mock -mock_names=BlobStore=BlobStore . BlobStore
+
+// ErrNotFound is returned by blob stores when there is no blob with the key.
+var ErrNotFound = errors.New("blob not found")
+
+// Media is an image of a product. Its bytes are kept in a BlobStore along
+// with a thumbnail.
+type Media struct {
+	ID          string `json:"id"`
+	ContentType string `json:"content_type" bson:"content_type"`
+	Size        int64  `json:"size"` // in bytes
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+}
+
+// Content is the bytes of a media item or of its thumbnail. The body must be
+// closed.
+type Content struct {
+	ContentType string
+	Body        io.ReadCloser
+}
+
+// BlobStore keeps blobs by keys. Keys are slash-separated paths, e.g.
+// "products/1/a1b2c3".
+type BlobStore interface {
+	// Put stores the blob replacing the one with the same key.
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Get returns the blob for reading, the reader must be closed. It returns
+	// ErrNotFound if there is no blob with the key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete deletes the blob, deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,100 @@
+// Package money provides amounts of money labeled with their currency, so
+// that amounts in different currencies are never mixed up.
+package money
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency of the balances of the users. Prices stored
+// before currencies were introduced are in it.
+const DefaultCurrency = "USD"
+
+// Money is an amount of money in a currency.
+type Money struct {
+	// Amount is in the minor units of the currency, e.g. cents.
+	Amount int64 `json:"amount" bson:"amount"`
+	// Currency is an ISO 4217 code, e.g. "USD".
+	Currency string `json:"currency" bson:"currency"`
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// currencies are the supported ISO 4217 currencies with the number of digits
+// of their minor units.
+var currencies = map[string]int{
+	"CHF": 2,
+	"CZK": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"JPY": 0,
+	"PLN": 2,
+	"UAH": 2,
+	"USD": 2,
+}
+
+// ValidCurrency reports whether the code is a supported currency.
+func ValidCurrency(code string) bool {
+	_, ok := currencies[code]
+	return ok
+}
+
+// Currencies returns the codes of the supported currencies in order.
+func Currencies() []string {
+	codes := make([]string, 0, len(currencies))
+	for code := range currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Digits returns the number of digits of the minor units of the currency,
+// e.g. 2 for cents. It is 0 for unknown currencies.
+func Digits(code string) int {
+	return currencies[code]
+}
+
+// Convert returns the money in the currency at the rate, the amount is
+// rounded to the minor units of the currency. The rate is the price of a unit
+// of the currency of the money in the other currency.
+func (m Money) Convert(to string, rate float64) Money {
+	return Money{Amount: int64(math.Round(m.ConvertedAmount(to, rate))), Currency: to}
+}
+
+// ConvertedAmount returns the amount converted to the minor units of the
+// currency at the rate without rounding.
+func (m Money) ConvertedAmount(to string, rate float64) float64 {
+	amount := float64(m.Amount) * rate
+	// Dividing by a power of ten avoids the error of its inexact inverse.
+	d := Digits(to) - Digits(m.Currency)
+	if d < 0 {
+		return amount / math.Pow10(-d)
+	}
+	return amount * math.Pow10(d)
+}
+
+// String formats the amount in major units with the currency, e.g.
+// "15.00 USD".
+func (m Money) String() string {
+	digits := Digits(m.Currency)
+
+	s := strconv.FormatInt(m.Amount, 10)
+	sign := ""
+	if m.Amount < 0 {
+		sign, s = "-", s[1:]
+	}
+	if digits == 0 {
+		return fmt.Sprintf("%s%s %s", sign, s, m.Currency)
+	}
+	if len(s) <= digits {
+		s = strings.Repeat("0", digits-len(s)+1) + s
+	}
+	return fmt.Sprintf("%s%s.%s %s", sign, s[:len(s)-digits], s[len(s)-digits:], m.Currency)
+}
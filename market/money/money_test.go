@@ -0,0 +1,23 @@
+package money
+
+import "testing"
+
+func TestMoney_String(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{m: New(1500, "USD"), want: "15.00 USD"},
+		{m: New(5, "EUR"), want: "0.05 EUR"},
+		{m: New(-120, "GBP"), want: "-1.20 GBP"},
+		{m: New(0, "UAH"), want: "0.00 UAH"},
+		{m: New(1500, "JPY"), want: "1500 JPY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.m.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package money
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ortymid/market/market/clock"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrNoRate is returned by rate providers which have no rate for the
+// currencies.
+var ErrNoRate = errors.New("no exchange rate")
+
+// RateProvider provides exchange rates between currencies.
+type RateProvider interface {
+	// Rate returns the price of a unit of the from currency in the to
+	// currency, e.g. 0.85 for USD to EUR. It returns ErrNoRate if the
+	// currencies cannot be exchanged.
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+// Exchange converts the money to the currency at the rate of the provider.
+func Exchange(ctx context.Context, p RateProvider, m Money, to string) (Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+	rate, err := p.Rate(ctx, m.Currency, to)
+	if err != nil {
+		return Money{}, err
+	}
+	return m.Convert(to, rate), nil
+}
+
+// StaticRates provides fixed rates of the currencies against the base
+// currency. Rates between other currencies are derived from them.
+type StaticRates struct {
+	Base string `json:"base"`
+	// Rates are the prices of a unit of the base currency in the currencies.
+	Rates map[string]float64 `json:"rates"`
+}
+
+func (r *StaticRates) Rate(ctx context.Context, from, to string) (float64, error) {
+	f, ok := r.rate(from)
+	if !ok {
+		return 0, fmt.Errorf("%s to %s: %w", from, to, ErrNoRate)
+	}
+	t, ok := r.rate(to)
+	if !ok {
+		return 0, fmt.Errorf("%s to %s: %w", from, to, ErrNoRate)
+	}
+	return t / f, nil
+}
+
+// rate returns the rate of the currency against the base.
+func (r *StaticRates) rate(currency string) (float64, bool) {
+	if currency == r.Base {
+		return 1, true
+	}
+	rate, ok := r.Rates[currency]
+	return rate, ok
+}
+
+// Validate checks that the rates are positive and of known currencies.
+func (r *StaticRates) Validate() error {
+	if !ValidCurrency(r.Base) {
+		return fmt.Errorf("unknown base currency %q", r.Base)
+	}
+	for currency, rate := range r.Rates {
+		if !ValidCurrency(currency) {
+			return fmt.Errorf("unknown currency %q", currency)
+		}
+		if rate <= 0 || rate > 1e9 {
+			return fmt.Errorf("invalid rate of %s: %v", currency, rate)
+		}
+	}
+	return nil
+}
+
+// LoadRates reads static rates from the JSON file, e.g.
+// {"base": "USD", "rates": {"EUR": 0.85, "JPY": 105.5}}.
+func LoadRates(path string) (*StaticRates, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading rates: %w", err)
+	}
+
+	var r StaticRates
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("parsing rates: %w", err)
+	}
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("parsing rates: %w", err)
+	}
+	return &r, nil
+}
+
+// FileRates provides the rates of the file in the format of LoadRates. The
+// file is parsed again only when its modification time changes, so that it
+// may be updated while running. It is safe for concurrent use.
+type FileRates struct {
+	Path string
+
+	mu      sync.Mutex
+	rates   *StaticRates
+	modTime time.Time
+}
+
+func NewFileRates(path string) *FileRates {
+	return &FileRates{Path: path}
+}
+
+func (r *FileRates) Rate(ctx context.Context, from, to string) (float64, error) {
+	rates, err := r.load()
+	if err != nil {
+		return 0, err
+	}
+	return rates.Rate(ctx, from, to)
+}
+
+// load returns the rates parsed last, or parses the file if it has been
+// modified since.
+func (r *FileRates) load() (*StaticRates, error) {
+	fi, err := os.Stat(r.Path)
+	if err != nil {
+		return nil, fmt.Errorf("reading rates: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.rates != nil && fi.ModTime().Equal(r.modTime) {
+		return r.rates, nil
+	}
+
+	rates, err := LoadRates(r.Path)
+	if err != nil {
+		return nil, err
+	}
+	r.rates, r.modTime = rates, fi.ModTime()
+	return rates, nil
+}
+
+// CachedRates keeps the rates of the provider for the TTL. Errors are kept
+// too, so that a failing provider is not asked on every call, except for the
+// errors of the context of the call. It is safe for concurrent use.
+type CachedRates struct {
+	Provider RateProvider
+	TTL      time.Duration
+	// Clock is used to expire the rates, the real clock is used if it is
+	// nil.
+	Clock clock.Clock
+
+	mu    sync.Mutex
+	rates map[ratePair]cachedRate
+}
+
+type ratePair struct {
+	from, to string
+}
+
+type cachedRate struct {
+	rate      float64
+	err       error
+	expiresAt time.Time
+}
+
+func NewCachedRates(p RateProvider, ttl time.Duration) *CachedRates {
+	return &CachedRates{Provider: p, TTL: ttl}
+}
+
+func (r *CachedRates) Rate(ctx context.Context, from, to string) (float64, error) {
+	now := r.now()
+	pair := ratePair{from: from, to: to}
+
+	r.mu.Lock()
+	c, ok := r.rates[pair]
+	r.mu.Unlock()
+	if ok && now.Before(c.expiresAt) {
+		return c.rate, c.err
+	}
+
+	// Concurrent misses may ask the provider more than once, which is cheaper
+	// than holding the lock during the call.
+	rate, err := r.Provider.Rate(ctx, from, to)
+	if err != nil && ctx.Err() != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	if r.rates == nil {
+		r.rates = make(map[ratePair]cachedRate)
+	}
+	r.rates[pair] = cachedRate{rate: rate, err: err, expiresAt: now.Add(r.TTL)}
+	r.mu.Unlock()
+
+	return rate, err
+}
+
+func (r *CachedRates) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock.Now()
+}
@@ -0,0 +1,182 @@
+package money
+
+import (
+	"context"
+	"errors"
+	"github.com/ortymid/market/market/clock/clocktest"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMoney_Convert(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Money
+		to   string
+		rate float64
+		want Money
+	}{
+		{name: "USD to EUR", m: New(1000, "USD"), to: "EUR", rate: 0.85, want: New(850, "EUR")},
+		{name: "rounds", m: New(999, "USD"), to: "EUR", rate: 0.855, want: New(854, "EUR")},
+		{name: "USD to JPY", m: New(1000, "USD"), to: "JPY", rate: 105.5, want: New(1055, "JPY")},
+		{name: "JPY to USD", m: New(1055, "JPY"), to: "USD", rate: 1 / 105.5, want: New(1000, "USD")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.Convert(tt.to, tt.rate); got != tt.want {
+				t.Errorf("Convert() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticRates_Rate(t *testing.T) {
+	rates := &StaticRates{Base: "USD", Rates: map[string]float64{"EUR": 0.8, "JPY": 100}}
+	tests := []struct {
+		from, to string
+		want     float64
+		wantErr  error
+	}{
+		{from: "USD", to: "EUR", want: 0.8},
+		{from: "EUR", to: "USD", want: 1.25},
+		{from: "EUR", to: "JPY", want: 125},
+		{from: "USD", to: "USD", want: 1},
+		{from: "USD", to: "GBP", wantErr: ErrNoRate},
+		{from: "GBP", to: "USD", wantErr: ErrNoRate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			got, err := rates.Rate(context.Background(), tt.from, tt.to)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Rate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Rate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileRates_Rate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rates.json")
+
+	rates := NewFileRates(path)
+	if _, err := rates.Rate(context.Background(), "USD", "EUR"); err == nil {
+		t.Errorf("Rate() of missing file error = nil, want an error")
+	}
+
+	// The modification time is set explicitly, as writes may happen within
+	// its resolution.
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	write := func(s string, modTime time.Time) {
+		if err := ioutil.WriteFile(path, []byte(s), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"base": "USD", "rates": {"EUR": 0.8}}`, modTime)
+	if got, err := rates.Rate(context.Background(), "USD", "EUR"); err != nil || got != 0.8 {
+		t.Errorf("Rate() = %v, %v, want 0.8", got, err)
+	}
+
+	write(`{"base": "USD", "rates": {"EUR": 0.7}}`, modTime)
+	if got, err := rates.Rate(context.Background(), "USD", "EUR"); err != nil || got != 0.8 {
+		t.Errorf("Rate() of file with the same modification time = %v, %v, want 0.8 parsed before", got, err)
+	}
+
+	modTime = modTime.Add(time.Second)
+	write(`{"base": "USD", "rates": {"EUR": 0.9}}`, modTime)
+	if got, err := rates.Rate(context.Background(), "USD", "EUR"); err != nil || got != 0.9 {
+		t.Errorf("Rate() of updated file = %v, %v, want 0.9", got, err)
+	}
+
+	modTime = modTime.Add(time.Second)
+	write(`{"base": "USD", "rates": {"XXX": 0.9}}`, modTime)
+	if _, err := rates.Rate(context.Background(), "USD", "EUR"); err == nil {
+		t.Errorf("Rate() of unknown currency error = nil, want an error")
+	}
+}
+
+// countingRates counts the calls of the provider.
+type countingRates struct {
+	RateProvider
+	calls int
+}
+
+func (r *countingRates) Rate(ctx context.Context, from, to string) (float64, error) {
+	r.calls++
+	return r.RateProvider.Rate(ctx, from, to)
+}
+
+// contextRates fails with the error of the context.
+type contextRates struct{}
+
+func (contextRates) Rate(ctx context.Context, from, to string) (float64, error) {
+	return 0, ctx.Err()
+}
+
+func TestCachedRates_Rate(t *testing.T) {
+	static := &StaticRates{Base: "USD", Rates: map[string]float64{"EUR": 0.8}}
+	provider := &countingRates{RateProvider: static}
+	clock := clocktest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	rates := &CachedRates{Provider: provider, TTL: time.Hour, Clock: clock}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if got, err := rates.Rate(ctx, "USD", "EUR"); err != nil || got != 0.8 {
+			t.Fatalf("Rate() = %v, %v, want 0.8", got, err)
+		}
+	}
+	if provider.calls != 1 {
+		t.Errorf("Rate() got provider calls = %d, want 1", provider.calls)
+	}
+
+	static.Rates["EUR"] = 0.9
+	clock.Advance(30 * time.Minute)
+	if got, _ := rates.Rate(ctx, "USD", "EUR"); got != 0.8 {
+		t.Errorf("Rate() before expiration = %v, want 0.8", got)
+	}
+	clock.Advance(30 * time.Minute)
+	if got, _ := rates.Rate(ctx, "USD", "EUR"); got != 0.9 {
+		t.Errorf("Rate() after expiration = %v, want 0.9", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := rates.Rate(ctx, "USD", "GBP"); !errors.Is(err, ErrNoRate) {
+			t.Fatalf("Rate() error = %v, want %v", err, ErrNoRate)
+		}
+	}
+	if provider.calls != 3 {
+		t.Errorf("Rate() got provider calls = %d, want 3 as errors are cached", provider.calls)
+	}
+
+	static.Rates["GBP"] = 0.7
+	clock.Advance(time.Hour)
+	if got, err := rates.Rate(ctx, "USD", "GBP"); err != nil || got != 0.7 {
+		t.Errorf("Rate() after error expiration = %v, %v, want 0.7", got, err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	provider.RateProvider = contextRates{}
+	for i := 0; i < 2; i++ {
+		if _, err := rates.Rate(canceled, "USD", "JPY"); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Rate() error = %v, want %v", err, context.Canceled)
+		}
+	}
+	if provider.calls != 6 {
+		t.Errorf("Rate() got provider calls = %d, want 6 as errors of the context are not cached", provider.calls)
+	}
+}
@@ -0,0 +1,31 @@
+package order
+
+import (
+	"errors"
+	"github.com/ortymid/market/market/errs"
+)
+
+// Resource names the orders in the shared errors.
+const Resource = "order"
+
+var ErrNotFound error = errs.NotFound{Resource: Resource}
+
+// ErrInsufficientFunds is returned when the balance of the buyer is less than
+// the total of the order.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrInProgress is returned when the order with the idempotency key of the
+// purchase is still being paid.
+var ErrInProgress = errors.New("purchase in progress")
+
+// ErrDuplicate is returned by storages when the buyer already has an order
+// with the same idempotency key.
+var ErrDuplicate = errors.New("duplicate idempotency key")
+
+// ErrValidation is returned when a request contains invalid data.
+type ErrValidation = errs.Validation
+
+// invalid returns ErrValidation of the order field.
+func invalid(field, reason string) ErrValidation {
+	return ErrValidation{Resource: Resource, Field: field, Reason: reason}
+}
@@ -0,0 +1,13 @@
+package order
+
+import "context"
+
+//go:generate mockgen -destination=../../mock/order_service.go -package mock -mock_names=Interface=OrderService . Interface
+
+type Interface interface {
+	// Purchase buys the product for the current user. Purchases with the
+	// same idempotency key return the same order.
+	Purchase(ctx context.Context, r PurchaseRequest) (*Order, error)
+	Find(ctx context.Context, r FindRequest) ([]*Order, error)
+	FindOne(ctx context.Context, id string) (*Order, error)
+}
@@ -0,0 +1,106 @@
+// Package order provides purchases of products. A purchase moves the price
+// from the balance of the buyer to the balance of the seller and keeps the
+// Order as a record of it. Balances are in money.DefaultCurrency, so only
+// products priced in it may be purchased.
+package order
+
+import "time"
+
+type Status string
+
+const (
+	// StatusPending orders are being purchased. They are removed if the
+	// purchase fails.
+	StatusPending Status = "pending"
+	// StatusCompleted orders are paid and their units are sold.
+	StatusCompleted Status = "completed"
+)
+
+type Order struct {
+	ID      string `json:"id" bson:"_id,omitempty"`
+	Product string `json:"product"`
+	// Variant is the SKU of the variant of the product bought, it is empty
+	// for products without variants.
+	Variant  string `json:"variant,omitempty" bson:"variant,omitempty"`
+	Buyer    string `json:"buyer"`
+	Seller   string `json:"seller"`
+	Quantity int64  `json:"quantity"`
+	Price    int64  `json:"price"` // price of a unit at the time of purchase, in minor units
+	Total    int64  `json:"total"`
+	Status   Status `json:"status"`
+	// IdempotencyKey is chosen by the buyer, purchases with the same key
+	// return the same order.
+	IdempotencyKey string    `json:"idempotency_key" bson:"idempotency_key"`
+	CreatedAt      time.Time `json:"created_at" bson:"created_at"`
+}
+
+type FindRequest struct {
+	Offset int64
+	Limit  int64
+	// Filters, at least one of them must be the current user.
+	Buyer  *string
+	Seller *string
+}
+
+type PurchaseRequest struct {
+	Product string `json:"product"`
+	// Variant is the SKU of the variant to buy, it is required for products
+	// with variants.
+	Variant        string `json:"variant,omitempty"`
+	Quantity       int64  `json:"quantity"`
+	IdempotencyKey string `json:"idempotency_key"`
+}
+
+// maxIdempotencyKey is the maximum length of the idempotency key.
+const maxIdempotencyKey = 255
+
+// Validate checks that the request contains valid purchase data.
+func (r PurchaseRequest) Validate() error {
+	if len(r.Product) == 0 {
+		return invalid("product", "must not be empty")
+	}
+	if r.Quantity <= 0 {
+		return invalid("quantity", "must be positive")
+	}
+	if len(r.IdempotencyKey) == 0 {
+		return invalid("idempotency_key", "must not be empty")
+	}
+	if len(r.IdempotencyKey) > maxIdempotencyKey {
+		return invalid("idempotency_key", "must not be longer than 255 bytes")
+	}
+	return nil
+}
+
+// Match reports whether the order matches the request filters.
+func (r FindRequest) Match(o *Order) bool {
+	if r.Buyer != nil && o.Buyer != *r.Buyer {
+		return false
+	}
+	if r.Seller != nil && o.Seller != *r.Seller {
+		return false
+	}
+	return true
+}
+
+// Page returns the orders of the page requested by offset and limit.
+func (r FindRequest) Page(os []*Order) []*Order {
+	if r.Offset >= int64(len(os)) {
+		return []*Order{}
+	}
+	os = os[r.Offset:]
+	if r.Limit < int64(len(os)) {
+		os = os[:r.Limit]
+	}
+	return os
+}
+
+// Validate checks that the request contains valid pagination.
+func (r FindRequest) Validate() error {
+	if r.Offset < 0 {
+		return invalid("offset", "must not be negative")
+	}
+	if r.Limit < 0 {
+		return invalid("limit", "must not be negative")
+	}
+	return nil
+}
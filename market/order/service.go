@@ -0,0 +1,323 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/keyed"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/user"
+	"log"
+	"math"
+	"time"
+)
+
+type Service struct {
+	Storage  Storage
+	Products product.Interface
+	// Stock holds the units for the buyer until the order is paid.
+	Stock product.Reserver
+	// Users hold the balances the price is moved between.
+	Users user.Service
+
+	// balances serialises the balance changes of each user, as the users are
+	// read and updated as a whole.
+	balances keyed.Mutex
+}
+
+// Purchase buys the product or its variant for the current user and returns
+// the completed order. The units are reserved, the total is debited from the
+// buyer and credited to the seller, then the units are sold. If any step
+// fails, the previous ones are rolled back and the order is removed.
+//
+// Purchases with the idempotency key of an existing order of the buyer return
+// that order without buying anything, or ErrInProgress while it is pending.
+func (s *Service) Purchase(ctx context.Context, r PurchaseRequest) (o *Order, err error) {
+	buyer, err := currentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("purchase: %w", err)
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("purchase: %w", err)
+	}
+
+	o, err = s.findByKey(ctx, buyer.ID, r)
+	if err == nil {
+		return o, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("purchase: %w", err)
+	}
+
+	p, err := s.Products.FindOne(ctx, r.Product)
+	if err != nil {
+		return nil, fmt.Errorf("purchase: %w", err)
+	}
+
+	if p.Seller == buyer.ID {
+		err := auth.ErrPermission{Reason: "own products are not allowed to purchase"}
+		return nil, fmt.Errorf("purchase: %w", err)
+	}
+	if p.Price.Currency != money.DefaultCurrency {
+		err := invalid("product", fmt.Sprintf("must be priced in %s", money.DefaultCurrency))
+		return nil, fmt.Errorf("purchase: %w", err)
+	}
+	price, err := variantPrice(p, r.Variant)
+	if err != nil {
+		return nil, fmt.Errorf("purchase: %w", err)
+	}
+	if price > 0 && r.Quantity > math.MaxInt64/price {
+		err := invalid("quantity", "total is too large")
+		return nil, fmt.Errorf("purchase: %w", err)
+	}
+
+	// The pending order claims the idempotency key, so concurrent purchases
+	// with the same key do not buy twice.
+	o, err = s.Storage.Create(ctx, Order{
+		Product:        p.ID,
+		Variant:        r.Variant,
+		Buyer:          buyer.ID,
+		Seller:         p.Seller,
+		Quantity:       r.Quantity,
+		Price:          price,
+		Total:          price * r.Quantity,
+		Status:         StatusPending,
+		IdempotencyKey: r.IdempotencyKey,
+		CreatedAt:      time.Now().UTC().Truncate(time.Millisecond),
+	})
+	if errors.Is(err, ErrDuplicate) {
+		// A concurrent purchase with the same key claimed it first.
+		o, err = s.findByKey(ctx, buyer.ID, r)
+		if err != nil {
+			return nil, fmt.Errorf("purchase: %w", err)
+		}
+		return o, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("purchase: %w", err)
+	}
+
+	o, err = s.pay(ctx, o)
+	if err != nil {
+		return nil, fmt.Errorf("purchase: %w", err)
+	}
+
+	return o, nil
+}
+
+// pay moves the units and the total of the pending order and completes it.
+// The steps done are undone in reverse order if a step fails. The rollback
+// does not depend on the request context, as it must finish even if the
+// purchase failed because the request was cancelled.
+func (s *Service) pay(ctx context.Context, o *Order) (completed *Order, err error) {
+	var undo []func(ctx context.Context) error
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(detachedContext{ctx}, rollbackTimeout)
+		defer cancel()
+		for i := len(undo) - 1; i >= 0; i-- {
+			if err := undo[i](ctx); err != nil {
+				log.Printf("rolling back order %s: %v", o.ID, err)
+			}
+		}
+	}()
+
+	undo = append(undo, func(ctx context.Context) error {
+		_, err := s.Storage.Delete(ctx, o.ID)
+		return err
+	})
+
+	if _, err := s.Stock.Reserve(ctx, o.Product, o.Variant, o.Quantity); err != nil {
+		return nil, err
+	}
+	undo = append(undo, func(ctx context.Context) error {
+		_, err := s.Stock.ReleaseReservation(ctx, o.Product, o.Variant, o.Quantity)
+		return err
+	})
+
+	if err := s.addBalance(ctx, o.Buyer, -o.Total); err != nil {
+		return nil, err
+	}
+	undo = append(undo, func(ctx context.Context) error {
+		return s.addBalance(ctx, o.Buyer, o.Total)
+	})
+
+	if err := s.addBalance(ctx, o.Seller, o.Total); err != nil {
+		return nil, err
+	}
+	undo = append(undo, func(ctx context.Context) error {
+		return s.addBalance(ctx, o.Seller, -o.Total)
+	})
+
+	completed, err = s.Storage.UpdateStatus(ctx, o.ID, StatusCompleted)
+	if err != nil {
+		return nil, err
+	}
+
+	// The units are sold last, as it cannot be undone.
+	if _, err := s.Stock.CommitReservation(ctx, o.Product, o.Quantity); err != nil {
+		return nil, err
+	}
+
+	return completed, nil
+}
+
+// findByKey returns the order of the buyer with the idempotency key of the
+// request. The key must not be reused for another purchase. It returns
+// ErrInProgress if the order is not paid yet.
+func (s *Service) findByKey(ctx context.Context, buyer string, r PurchaseRequest) (*Order, error) {
+	o, err := s.Storage.FindByKey(ctx, buyer, r.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Product != r.Product || o.Variant != r.Variant || o.Quantity != r.Quantity {
+		return nil, invalid("idempotency_key", "used for another purchase")
+	}
+	if o.Status == StatusPending {
+		return nil, ErrInProgress
+	}
+	return o, nil
+}
+
+// variantPrice returns the price of a unit of the variant of the product, or
+// of the product if it has no variants. Variants are priced in the currency
+// of the product.
+func variantPrice(p *product.Product, variant string) (int64, error) {
+	if len(p.Variants) == 0 {
+		if variant != "" {
+			return 0, invalid("variant", "product has no variants")
+		}
+		return p.Price.Amount, nil
+	}
+
+	if variant == "" {
+		return 0, invalid("variant", "must be set for products with variants")
+	}
+	v, ok := p.Variant(variant)
+	if !ok {
+		return 0, product.ErrVariantNotFound
+	}
+	return v.Price.Amount, nil
+}
+
+// addBalance adds delta to the balance of the user. It returns
+// ErrInsufficientFunds if a negative delta leaves the balance negative.
+//
+// The user service has no conditional updates, so the balance is read and
+// written back under a lock of the user held by this service only. Orders
+// must therefore be served by a single instance of the server.
+func (s *Service) addBalance(ctx context.Context, id string, delta int64) error {
+	unlock := s.balances.Lock(id)
+	defer unlock()
+
+	u, err := s.Users.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if delta > 0 && u.Balance > math.MaxInt64-delta {
+		return invalid("total", "makes the balance too large")
+	}
+	balance := u.Balance + delta
+	if delta < 0 && balance < 0 {
+		return ErrInsufficientFunds
+	}
+
+	_, err = s.Users.Update(ctx, user.UpdateRequest{ID: id, Balance: &balance})
+	return err
+}
+
+// Find returns orders of the current user. Orders bought by the user are
+// returned if no filters are provided.
+func (s *Service) Find(ctx context.Context, r FindRequest) ([]*Order, error) {
+	u, err := currentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+
+	if r.Buyer == nil && r.Seller == nil {
+		r.Buyer = &u.ID
+	}
+	isBuyer := r.Buyer != nil && *r.Buyer == u.ID
+	isSeller := r.Seller != nil && *r.Seller == u.ID
+	if !isBuyer && !isSeller {
+		err := auth.ErrPermission{Reason: "only own orders allowed to list"}
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+
+	os, err := s.Storage.Find(ctx, r)
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+
+	return os, nil
+}
+
+// FindOne returns an order for the given id. Only the buyer and the seller
+// may see the order. It returns order.ErrNotFound error if there is no order
+// with such id.
+func (s *Service) FindOne(ctx context.Context, id string) (*Order, error) {
+	u, err := currentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get order: %w", err)
+	}
+
+	o, err := s.Storage.FindOne(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get order: %w", err)
+	}
+
+	if u.ID != o.Buyer && u.ID != o.Seller {
+		err := auth.ErrPermission{Reason: "only own orders allowed to get"}
+		return nil, fmt.Errorf("get order: %w", err)
+	}
+
+	return o, nil
+}
+
+func currentUser(ctx context.Context) (*user.User, error) {
+	u, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, auth.ErrNoUser
+	}
+	return u, nil
+}
+
+// rollbackTimeout bounds the rollback of a failed purchase.
+const rollbackTimeout = 10 * time.Second
+
+// detachedContext keeps the values of its parent, such as the user, but not
+// its deadline and cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
@@ -0,0 +1,529 @@
+package order_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/order"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/user"
+	"github.com/ortymid/market/storage/memory"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testEnv is an order service with in-memory dependencies.
+type testEnv struct {
+	service  *order.Service
+	products *memory.ProductStorage
+	users    *memory.UserService
+	orders   *memory.OrderStorage
+
+	productID string
+}
+
+// newTestEnv returns an environment with a product of the "seller" priced 100
+// with 5 units in stock.
+func newTestEnv(t *testing.T, users ...user.User) *testEnv {
+	env := &testEnv{
+		products: memory.NewProductStorage(),
+		users:    memory.NewUserService(users...),
+		orders:   memory.NewOrderStorage(),
+	}
+	products := &product.Service{Storage: env.products}
+	env.service = &order.Service{
+		Storage:  env.orders,
+		Products: products,
+		Stock:    products.Reservations(),
+		Users:    env.users,
+	}
+
+	p, err := env.products.Create(context.Background(), product.CreateRequest{
+		Name: "Banana", Price: money.New(100, "USD"), Seller: "seller", Stock: 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.productID = p.ID
+
+	return env
+}
+
+func (env *testEnv) checkBalance(t *testing.T, id string, want int64) {
+	t.Helper()
+
+	u, err := env.users.Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Get() user error = %v", err)
+	}
+	if u.Balance != want {
+		t.Errorf("balance of %s = %d, want %d", id, u.Balance, want)
+	}
+}
+
+func (env *testEnv) checkStock(t *testing.T, stock int64, reserved int64) {
+	t.Helper()
+
+	p, err := env.products.FindOne(context.Background(), env.productID)
+	if err != nil {
+		t.Fatalf("FindOne() product error = %v", err)
+	}
+	if p.Stock != stock || p.Reserved != reserved {
+		t.Errorf("stock = %d, reserved = %d, want %d, %d", p.Stock, p.Reserved, stock, reserved)
+	}
+}
+
+func buyerContext(id string) context.Context {
+	return auth.NewContextWithUser(context.Background(), &user.User{ID: id})
+}
+
+func TestService_Purchase(t *testing.T) {
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		users        []user.User
+		quantity     int64
+		wantErr      error
+		wantBuyer    int64
+		wantSeller   int64
+		wantStock    int64
+		wantReserved int64
+	}{
+		{
+			name:       "Should purchase product",
+			ctx:        buyerContext("buyer"),
+			users:      []user.User{{ID: "buyer", Balance: 1000}, {ID: "seller", Balance: 10}},
+			quantity:   2,
+			wantBuyer:  800,
+			wantSeller: 210,
+			wantStock:  3,
+		},
+		{
+			name:       "Should roll back when funds are insufficient",
+			ctx:        buyerContext("buyer"),
+			users:      []user.User{{ID: "buyer", Balance: 150}, {ID: "seller", Balance: 10}},
+			quantity:   2,
+			wantErr:    order.ErrInsufficientFunds,
+			wantBuyer:  150,
+			wantSeller: 10,
+			wantStock:  5,
+		},
+		{
+			name:       "Should roll back when stock is insufficient",
+			ctx:        buyerContext("buyer"),
+			users:      []user.User{{ID: "buyer", Balance: 1000}, {ID: "seller", Balance: 10}},
+			quantity:   6,
+			wantErr:    product.ErrInsufficientStock,
+			wantBuyer:  1000,
+			wantSeller: 10,
+			wantStock:  5,
+		},
+		{
+			name:      "Should roll back when seller cannot be credited",
+			ctx:       buyerContext("buyer"),
+			users:     []user.User{{ID: "buyer", Balance: 1000}},
+			quantity:  2,
+			wantErr:   user.ErrNotFound,
+			wantBuyer: 1000,
+			wantStock: 5,
+		},
+		{
+			name:       "Should roll back when balance of seller would overflow",
+			ctx:        buyerContext("buyer"),
+			users:      []user.User{{ID: "buyer", Balance: 1000}, {ID: "seller", Balance: math.MaxInt64}},
+			quantity:   2,
+			wantErr:    order.ErrValidation{Resource: order.Resource, Field: "total", Reason: "makes the balance too large"},
+			wantBuyer:  1000,
+			wantSeller: math.MaxInt64,
+			wantStock:  5,
+		},
+		{
+			name:       "Should not allow to purchase own product",
+			ctx:        buyerContext("seller"),
+			users:      []user.User{{ID: "buyer", Balance: 1000}, {ID: "seller", Balance: 10}},
+			quantity:   2,
+			wantErr:    auth.ErrPermission{Reason: "own products are not allowed to purchase"},
+			wantBuyer:  1000,
+			wantSeller: 10,
+			wantStock:  5,
+		},
+		{
+			name:       "Should require user",
+			ctx:        context.Background(),
+			users:      []user.User{{ID: "buyer", Balance: 1000}, {ID: "seller", Balance: 10}},
+			quantity:   2,
+			wantErr:    auth.ErrNoUser,
+			wantBuyer:  1000,
+			wantSeller: 10,
+			wantStock:  5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, tt.users...)
+
+			r := order.PurchaseRequest{Product: env.productID, Quantity: tt.quantity, IdempotencyKey: "key"}
+			got, err := env.service.Purchase(tt.ctx, r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Purchase() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil {
+				want := &order.Order{
+					ID:             got.ID,
+					Product:        env.productID,
+					Buyer:          "buyer",
+					Seller:         "seller",
+					Quantity:       tt.quantity,
+					Price:          100,
+					Total:          100 * tt.quantity,
+					Status:         order.StatusCompleted,
+					IdempotencyKey: "key",
+					CreatedAt:      got.CreatedAt,
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Purchase() got = %v, want %v", got, want)
+				}
+			} else if _, err := env.orders.FindByKey(context.Background(), "buyer", "key"); !errors.Is(err, order.ErrNotFound) {
+				t.Errorf("FindByKey() after failed purchase error = %v, want %v", err, order.ErrNotFound)
+			}
+
+			env.checkBalance(t, "buyer", tt.wantBuyer)
+			if tt.wantSeller != 0 {
+				env.checkBalance(t, "seller", tt.wantSeller)
+			}
+			env.checkStock(t, tt.wantStock, tt.wantReserved)
+		})
+	}
+}
+
+func TestService_PurchaseIdempotent(t *testing.T) {
+	env := newTestEnv(t, user.User{ID: "buyer", Balance: 1000}, user.User{ID: "seller"})
+	ctx := buyerContext("buyer")
+
+	r := order.PurchaseRequest{Product: env.productID, Quantity: 2, IdempotencyKey: "key"}
+	first, err := env.service.Purchase(ctx, r)
+	if err != nil {
+		t.Fatalf("Purchase() error = %v", err)
+	}
+
+	second, err := env.service.Purchase(ctx, r)
+	if err != nil {
+		t.Fatalf("Purchase() repeated error = %v", err)
+	}
+	if !reflect.DeepEqual(second, first) {
+		t.Errorf("Purchase() repeated got = %v, want %v", second, first)
+	}
+
+	env.checkBalance(t, "buyer", 800)
+	env.checkBalance(t, "seller", 200)
+	env.checkStock(t, 3, 0)
+
+	r.Quantity = 1
+	_, err = env.service.Purchase(ctx, r)
+	var errValidation order.ErrValidation
+	if !errors.As(err, &errValidation) || errValidation.Field != "idempotency_key" {
+		t.Errorf("Purchase() with reused key error = %v, want invalid idempotency_key", err)
+	}
+}
+
+func TestService_PurchasePending(t *testing.T) {
+	env := newTestEnv(t, user.User{ID: "buyer", Balance: 1000}, user.User{ID: "seller"})
+
+	_, err := env.orders.Create(context.Background(), order.Order{
+		Product: env.productID, Buyer: "buyer", Seller: "seller", Quantity: 2, Price: 100, Total: 200,
+		Status: order.StatusPending, IdempotencyKey: "key",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := order.PurchaseRequest{Product: env.productID, Quantity: 2, IdempotencyKey: "key"}
+	if _, err := env.service.Purchase(buyerContext("buyer"), r); !errors.Is(err, order.ErrInProgress) {
+		t.Errorf("Purchase() error = %v, want %v", err, order.ErrInProgress)
+	}
+	env.checkBalance(t, "buyer", 1000)
+	env.checkStock(t, 5, 0)
+}
+
+// slowUsers delays returning the users, so that concurrent balance changes
+// overlap.
+type slowUsers struct {
+	user.Service
+}
+
+func (s slowUsers) Get(ctx context.Context, id string) (user.User, error) {
+	u, err := s.Service.Get(ctx, id)
+	time.Sleep(time.Millisecond)
+	return u, err
+}
+
+func TestService_PurchaseConcurrent(t *testing.T) {
+	env := newTestEnv(t, user.User{ID: "buyer", Balance: 300}, user.User{ID: "seller"})
+	env.service.Users = slowUsers{env.users}
+	ctx := buyerContext("buyer")
+
+	const n = 5
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			r := order.PurchaseRequest{Product: env.productID, Quantity: 1, IdempotencyKey: fmt.Sprint("key", i)}
+			_, err := env.service.Purchase(ctx, r)
+			errs <- err
+		}(i)
+	}
+
+	var completed int
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			completed++
+		} else if !errors.Is(err, order.ErrInsufficientFunds) {
+			t.Errorf("Purchase() error = %v, want %v", err, order.ErrInsufficientFunds)
+		}
+	}
+	if completed != 3 {
+		t.Errorf("Purchase() completed %d times, want 3", completed)
+	}
+	env.checkBalance(t, "buyer", 0)
+	env.checkBalance(t, "seller", 300)
+	env.checkStock(t, 2, 0)
+}
+
+func TestService_PurchaseRetryAfterFailure(t *testing.T) {
+	env := newTestEnv(t, user.User{ID: "buyer", Balance: 100}, user.User{ID: "seller"})
+	ctx := buyerContext("buyer")
+
+	r := order.PurchaseRequest{Product: env.productID, Quantity: 2, IdempotencyKey: "key"}
+	if _, err := env.service.Purchase(ctx, r); !errors.Is(err, order.ErrInsufficientFunds) {
+		t.Fatalf("Purchase() error = %v, want %v", err, order.ErrInsufficientFunds)
+	}
+
+	balance := int64(1000)
+	if _, err := env.users.Update(ctx, user.UpdateRequest{ID: "buyer", Balance: &balance}); err != nil {
+		t.Fatal(err)
+	}
+
+	o, err := env.service.Purchase(ctx, r)
+	if err != nil {
+		t.Fatalf("Purchase() retry error = %v", err)
+	}
+	if o.Status != order.StatusCompleted {
+		t.Errorf("Purchase() retry status = %v, want %v", o.Status, order.StatusCompleted)
+	}
+	env.checkBalance(t, "buyer", 800)
+}
+
+// cancellingUsers cancels the context when the seller is credited, as if the
+// client went away in the middle of a purchase. Calls fail once the context
+// is done.
+type cancellingUsers struct {
+	user.Service
+	cancel context.CancelFunc
+}
+
+func (s cancellingUsers) Get(ctx context.Context, id string) (user.User, error) {
+	if err := ctx.Err(); err != nil {
+		return user.User{}, err
+	}
+	return s.Service.Get(ctx, id)
+}
+
+func (s cancellingUsers) Update(ctx context.Context, r user.UpdateRequest) (user.User, error) {
+	if r.ID == "seller" {
+		s.cancel()
+	}
+	if err := ctx.Err(); err != nil {
+		return user.User{}, err
+	}
+	return s.Service.Update(ctx, r)
+}
+
+func TestService_PurchaseCancelled(t *testing.T) {
+	env := newTestEnv(t, user.User{ID: "buyer", Balance: 1000}, user.User{ID: "seller", Balance: 10})
+	ctx, cancel := context.WithCancel(buyerContext("buyer"))
+	defer cancel()
+	env.service.Users = cancellingUsers{Service: env.users, cancel: cancel}
+
+	r := order.PurchaseRequest{Product: env.productID, Quantity: 2, IdempotencyKey: "key"}
+	if _, err := env.service.Purchase(ctx, r); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Purchase() error = %v, want %v", err, context.Canceled)
+	}
+
+	env.checkBalance(t, "buyer", 1000)
+	env.checkBalance(t, "seller", 10)
+	env.checkStock(t, 5, 0)
+	if _, err := env.orders.FindByKey(context.Background(), "buyer", "key"); !errors.Is(err, order.ErrNotFound) {
+		t.Errorf("FindByKey() after cancelled purchase error = %v, want %v", err, order.ErrNotFound)
+	}
+}
+
+func TestService_PurchaseOtherCurrency(t *testing.T) {
+	env := newTestEnv(t, user.User{ID: "buyer", Balance: 1000}, user.User{ID: "seller", Balance: 10})
+	price := money.New(100, "EUR")
+	if _, err := env.products.Update(context.Background(), product.UpdateRequest{ID: env.productID, Price: &price}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := order.PurchaseRequest{Product: env.productID, Quantity: 2, IdempotencyKey: "key"}
+	_, err := env.service.Purchase(buyerContext("buyer"), r)
+	want := order.ErrValidation{Resource: order.Resource, Field: "product", Reason: "must be priced in USD"}
+	if !errors.Is(err, want) {
+		t.Fatalf("Purchase() error = %v, want %v", err, want)
+	}
+
+	env.checkBalance(t, "buyer", 1000)
+	env.checkBalance(t, "seller", 10)
+	env.checkStock(t, 5, 0)
+}
+
+func TestService_PurchaseVariant(t *testing.T) {
+	env := newTestEnv(t, user.User{ID: "buyer", Balance: 1000}, user.User{ID: "seller", Balance: 10})
+	attributes := []product.Attribute{{Name: "size", Type: product.AttributeString}}
+	variants := []product.Variant{
+		{SKU: "S", Attributes: map[string]string{"size": "S"}, Price: money.New(100, "USD"), Stock: 5},
+		{SKU: "L", Attributes: map[string]string{"size": "L"}, Price: money.New(150, "USD"), Stock: 3},
+	}
+	_, err := env.products.Update(context.Background(), product.UpdateRequest{
+		ID: env.productID, Attributes: &attributes, Variants: &variants,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		variant string
+		wantErr error
+	}{
+		{
+			name:    "Should require variant",
+			wantErr: order.ErrValidation{Resource: order.Resource, Field: "variant", Reason: "must be set for products with variants"},
+		},
+		{
+			name:    "Should error when variant is unknown",
+			variant: "XL",
+			wantErr: product.ErrVariantNotFound,
+		},
+		{
+			name:    "Should purchase variant",
+			variant: "L",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := order.PurchaseRequest{Product: env.productID, Variant: tt.variant, Quantity: 2, IdempotencyKey: tt.name}
+			got, err := env.service.Purchase(buyerContext("buyer"), r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Purchase() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Variant != "L" || got.Price != 150 || got.Total != 300 {
+				t.Errorf("Purchase() got variant %q priced %d for %d, want %q priced 150 for 300", got.Variant, got.Price, got.Total, "L")
+			}
+		})
+	}
+
+	env.checkBalance(t, "buyer", 700)
+	env.checkBalance(t, "seller", 310)
+	p, err := env.products.FindOne(context.Background(), env.productID)
+	if err != nil {
+		t.Fatalf("FindOne() product error = %v", err)
+	}
+	if p.Variants[0].Stock != 5 || p.Variants[1].Stock != 1 || p.Reserved != 0 {
+		t.Errorf("variant stock = %d, %d, reserved = %d, want 5, 1, 0", p.Variants[0].Stock, p.Variants[1].Stock, p.Reserved)
+	}
+}
+
+func TestService_FindOne(t *testing.T) {
+	env := newTestEnv(t, user.User{ID: "buyer", Balance: 1000}, user.User{ID: "seller"})
+
+	r := order.PurchaseRequest{Product: env.productID, Quantity: 1, IdempotencyKey: "key"}
+	o, err := env.service.Purchase(buyerContext("buyer"), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		id      string
+		wantErr error
+	}{
+		{name: "Should return order to buyer", ctx: buyerContext("buyer"), id: o.ID},
+		{name: "Should return order to seller", ctx: buyerContext("seller"), id: o.ID},
+		{
+			name:    "Should not return order to others",
+			ctx:     buyerContext("other"),
+			id:      o.ID,
+			wantErr: auth.ErrPermission{Reason: "only own orders allowed to get"},
+		},
+		{name: "Should return not found", ctx: buyerContext("buyer"), id: "999", wantErr: order.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := env.service.FindOne(tt.ctx, tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FindOne() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, o) {
+				t.Errorf("FindOne() got = %v, want %v", got, o)
+			}
+		})
+	}
+}
+
+func TestService_Find(t *testing.T) {
+	env := newTestEnv(t, user.User{ID: "buyer", Balance: 1000}, user.User{ID: "seller"})
+
+	r := order.PurchaseRequest{Product: env.productID, Quantity: 1, IdempotencyKey: "key"}
+	o, err := env.service.Purchase(buyerContext("buyer"), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seller := "seller"
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		r       order.FindRequest
+		want    []*order.Order
+		wantErr error
+	}{
+		{
+			name: "Should find bought orders by default",
+			ctx:  buyerContext("buyer"),
+			r:    order.FindRequest{Limit: 10},
+			want: []*order.Order{o},
+		},
+		{
+			name: "Should find sold orders",
+			ctx:  buyerContext("seller"),
+			r:    order.FindRequest{Limit: 10, Seller: &seller},
+			want: []*order.Order{o},
+		},
+		{
+			name:    "Should not find orders of others",
+			ctx:     buyerContext("buyer"),
+			r:       order.FindRequest{Limit: 10, Seller: &seller},
+			wantErr: auth.ErrPermission{Reason: "only own orders allowed to list"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := env.service.Find(tt.ctx, tt.r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Find() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
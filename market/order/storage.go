@@ -0,0 +1,19 @@
+package order
+
+import "context"
+
+//go:generate mockgen -destination=../../mock/order_storage.go -package mock -mock_names=Storage=OrderStorage . Storage
+
+type Storage interface {
+	// Find returns orders matching the not-nil filters in the order of
+	// creation.
+	Find(ctx context.Context, r FindRequest) ([]*Order, error)
+	FindOne(ctx context.Context, id string) (*Order, error)
+	// FindByKey returns the order of the buyer with the idempotency key.
+	FindByKey(ctx context.Context, buyer string, key string) (*Order, error)
+	// Create stores the order and returns it with an assigned id. It returns
+	// ErrDuplicate if the buyer has an order with the same idempotency key.
+	Create(ctx context.Context, o Order) (*Order, error)
+	UpdateStatus(ctx context.Context, id string, status Status) (*Order, error)
+	Delete(ctx context.Context, id string) (*Order, error)
+}
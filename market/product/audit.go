@@ -0,0 +1,99 @@
+package product
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ortymid/market/market/audit"
+	"log"
+)
+
+// auditFields are the audited fields of products with their values.
+var auditFields = []struct {
+	name  string
+	value func(p *Product) interface{}
+}{
+	{name: "name", value: func(p *Product) interface{} { return p.Name }},
+	{name: "price", value: func(p *Product) interface{} { return p.Price }},
+	{name: "seller", value: func(p *Product) interface{} { return p.Seller }},
+	{name: "categories", value: func(p *Product) interface{} {
+		if len(p.Categories) == 0 {
+			return []string{}
+		}
+		return p.Categories
+	}},
+	{name: "attributes", value: func(p *Product) interface{} {
+		if len(p.Attributes) == 0 {
+			return []Attribute{}
+		}
+		return p.Attributes
+	}},
+	{name: "variants", value: func(p *Product) interface{} {
+		if len(p.Variants) == 0 {
+			return []Variant{}
+		}
+		return p.Variants
+	}},
+	{name: "stock", value: func(p *Product) interface{} { return p.Stock }},
+	{name: "low_stock_threshold", value: func(p *Product) interface{} { return p.LowStockThreshold }},
+	{name: "status", value: func(p *Product) interface{} { return p.Status }},
+	{name: "publish_at", value: func(p *Product) interface{} { return p.PublishAt }},
+	{name: "media", value: func(p *Product) interface{} {
+		ids := []string{}
+		for _, m := range p.Media {
+			ids = append(ids, m.ID)
+		}
+		return ids
+	}},
+}
+
+// diff returns changes of the audited fields. Before is nil for created and
+// restored products and after is nil for deleted ones.
+func diff(before, after *Product) []audit.Change {
+	changes := []audit.Change{}
+	for _, f := range auditFields {
+		b, a := auditValue(before, f.value), auditValue(after, f.value)
+		if b != a {
+			changes = append(changes, audit.Change{Field: f.name, Before: b, After: a})
+		}
+	}
+	return changes
+}
+
+// auditValue returns the JSON encoded value of the product field, it is empty
+// if there is no product.
+func auditValue(p *Product, value func(p *Product) interface{}) string {
+	if p == nil {
+		return ""
+	}
+	b, err := json.Marshal(value(p))
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// record records the operation made by the actor. The product is already
+// changed, so a failure to record it is logged rather than returned.
+func (s *Service) record(ctx context.Context, actor string, op audit.Operation, before, after *Product) {
+	if s.Audit == nil {
+		return
+	}
+
+	p := after
+	if p == nil {
+		p = before
+	}
+
+	e := audit.Entry{
+		Product:   p.ID,
+		Seller:    p.Seller,
+		Actor:     actor,
+		Operation: op,
+		Version:   p.Version,
+		Changes:   diff(before, after),
+		CreatedAt: s.now(),
+	}
+	if err := s.Audit.Record(ctx, e); err != nil {
+		log.Printf("recording %s of product %s: %v", op, p.ID, err)
+	}
+}
@@ -0,0 +1,178 @@
+package product
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/ortymid/market/market/money"
+	"sort"
+	"strings"
+)
+
+// cursor is a decoded cursor. Values are specific to a storage, usually they
+// are values of the sort keys of the last product of a page. The sort is kept
+// to reject cursors used with another sort, as values make sense only for the
+// sort they were taken for.
+type cursor struct {
+	Sort   string          `json:"s,omitempty"`
+	Values json.RawMessage `json:"v"`
+}
+
+// EncodeCursor makes an opaque cursor holding the values for the sort keys.
+func EncodeCursor(sorts []Sort, values interface{}) (string, error) {
+	v, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(cursor{Sort: FormatSort(sorts), Values: v})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor decodes the values of a cursor made by EncodeCursor. It returns
+// ErrValidation if the cursor is malformed or was made for another sort.
+func DecodeCursor(s string, sorts []Sort, values interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return invalid("cursor", "malformed")
+	}
+
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return invalid("cursor", "malformed")
+	}
+
+	if c.Sort != FormatSort(sorts) {
+		return invalid("cursor", "made for another sort")
+	}
+
+	if err := json.Unmarshal(c.Values, values); err != nil {
+		return invalid("cursor", "malformed")
+	}
+	return nil
+}
+
+// cursorProduct is the last product of a page in a cursor. It holds only the
+// values compared by the sort keys and the id, which breaks ties.
+type cursorProduct struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"n,omitempty"`
+	Price  int64   `json:"p,omitempty"`
+	Rating float64 `json:"r,omitempty"`
+}
+
+// CursorValues returns the values of the product to continue after it in the
+// order of the sort keys. They are decoded with DecodeProductCursor.
+func CursorValues(p *Product, sorts []Sort) interface{} {
+	c := cursorProduct{ID: p.ID}
+	for _, s := range sorts {
+		switch s.Key {
+		case SortKeyName:
+			c.Name = p.Name
+		case SortKeyPrice:
+			c.Price = p.Price.Amount
+		case SortKeyRating:
+			c.Rating = p.Rating.Average
+		}
+	}
+	return c
+}
+
+// DecodeProductCursor decodes a cursor made of CursorValues. The returned
+// product has only the id and the values of the sort keys.
+func DecodeProductCursor(s string, sorts []Sort) (*Product, error) {
+	var c cursorProduct
+	if err := DecodeCursor(s, sorts, &c); err != nil {
+		return nil, err
+	}
+
+	return &Product{
+		ID:     c.ID,
+		Name:   c.Name,
+		Price:  money.Money{Amount: c.Price},
+		Rating: Rating{Average: c.Rating},
+	}, nil
+}
+
+// FormatSort formats sort keys the way ParseSort parses them.
+func FormatSort(sorts []Sort) string {
+	fields := make([]string, len(sorts))
+	for i, s := range sorts {
+		fields[i] = string(s.Key)
+		if s.Desc {
+			fields[i] = "-" + fields[i]
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// PageProducts sorts the products and returns a page of them for the request
+// for storages which cannot paginate natively. The page starts after the
+// request cursor, or at the offset if there is no cursor. Cursors hold the
+// sort values of the last product of a page, so the listing continues
+// correctly even if products were added or deleted in between. See
+// SortProducts for the created function.
+func PageProducts(ps []*Product, r FindRequest, created func(p *Product) int64) (*FindResult, error) {
+	SortProducts(ps, r.Sort, created)
+
+	start := r.Offset
+	if r.Cursor != "" {
+		last, err := DecodeProductCursor(r.Cursor, r.Sort)
+		if err != nil {
+			return nil, err
+		}
+
+		start = int64(sort.Search(len(ps), func(i int) bool {
+			return CompareProducts(last, ps[i], r.Sort, created) < 0
+		}))
+	}
+
+	page := pageOf(ps, start, r.Limit)
+	res, err := MakeFindResult(page, r.Sort, r.Limit, func(last int) interface{} {
+		return CursorValues(page[last], r.Sort)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res.Total = int64(len(ps))
+	return res, nil
+}
+
+// MakeFindResult makes a result of up to limit products. Storages request one
+// product more than the limit to find out whether there are more products.
+// The total is left for storages to count.
+// The values function returns the cursor values for the last product of the
+// page given its index.
+func MakeFindResult(ps []*Product, sorts []Sort, limit int64, values func(last int) interface{}) (*FindResult, error) {
+	if limit <= 0 || len(ps) == 0 {
+		return &FindResult{Products: []*Product{}}, nil
+	}
+	if int64(len(ps)) <= limit {
+		return &FindResult{Products: ps}, nil
+	}
+
+	ps = ps[:limit]
+	next, err := EncodeCursor(sorts, values(len(ps)-1))
+	if err != nil {
+		return nil, err
+	}
+
+	return &FindResult{Products: ps, HasMore: true, NextCursor: next}, nil
+}
+
+// pageOf returns up to limit+1 products starting at start.
+func pageOf(ps []*Product, start, limit int64) []*Product {
+	if start >= int64(len(ps)) || limit <= 0 {
+		return []*Product{}
+	}
+
+	end := start + limit + 1
+	if end > int64(len(ps)) {
+		end = int64(len(ps))
+	}
+	return ps[start:end]
+}
@@ -0,0 +1,137 @@
+package product_test
+
+import (
+	"errors"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/product"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	sorts := []product.Sort{{Key: product.SortKeyPrice, Desc: true}, {Key: product.SortKeyName}}
+	cursor, err := product.EncodeCursor(sorts, []int64{1, 2})
+	if err != nil {
+		t.Fatalf("EncodeCursor() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		cursor  string
+		sorts   []product.Sort
+		want    []int64
+		wantErr bool
+	}{
+		{
+			name:   "Should decode values",
+			cursor: cursor,
+			sorts:  sorts,
+			want:   []int64{1, 2},
+		},
+		{
+			name:    "Should error for another sort",
+			cursor:  cursor,
+			sorts:   []product.Sort{{Key: product.SortKeyPrice}},
+			wantErr: true,
+		},
+		{
+			name:    "Should error for malformed cursor",
+			cursor:  "not a cursor",
+			sorts:   sorts,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int64
+			err := product.DecodeCursor(tt.cursor, tt.sorts, &got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DecodeCursor() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				if !errors.As(err, &product.ErrValidation{}) {
+					t.Errorf("DecodeCursor() error = %v, want ErrValidation", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeCursor() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageProducts(t *testing.T) {
+	created := func(p *product.Product) int64 {
+		id, _ := strconv.ParseInt(p.ID, 10, 64)
+		return id
+	}
+	products := func() []*product.Product {
+		return []*product.Product{
+			{ID: "1", Name: "a", Price: money.New(300, "USD")},
+			{ID: "2", Name: "b", Price: money.New(100, "USD")},
+			{ID: "3", Name: "c", Price: money.New(200, "USD")},
+			{ID: "4", Name: "d", Price: money.New(100, "USD")},
+		}
+	}
+	sorts := []product.Sort{{Key: product.SortKeyPrice}}
+
+	r := product.FindRequest{Limit: 3, Sort: sorts}
+	got, err := product.PageProducts(products(), r, created)
+	if err != nil {
+		t.Fatalf("PageProducts() error = %v", err)
+	}
+	if ids := productIDs(got.Products); !reflect.DeepEqual(ids, []string{"2", "4", "3"}) {
+		t.Errorf("PageProducts() first page = %v, want [2 4 3]", ids)
+	}
+	if got.NextCursor == "" || !got.HasMore {
+		t.Fatalf("PageProducts() next cursor = %q, has more = %v, want more", got.NextCursor, got.HasMore)
+	}
+	if got.Total != 4 {
+		t.Errorf("PageProducts() total = %d, want 4", got.Total)
+	}
+
+	r.Cursor = got.NextCursor
+	got, err = product.PageProducts(products(), r, created)
+	if err != nil {
+		t.Fatalf("PageProducts() error = %v", err)
+	}
+	if ids := productIDs(got.Products); !reflect.DeepEqual(ids, []string{"1"}) {
+		t.Errorf("PageProducts() second page = %v, want [1]", ids)
+	}
+	if got.NextCursor != "" || got.HasMore {
+		t.Errorf("PageProducts() next cursor = %q, has more = %v, want no more", got.NextCursor, got.HasMore)
+	}
+	if got.Total != 4 {
+		t.Errorf("PageProducts() total = %d, want 4", got.Total)
+	}
+}
+
+func TestDecodeProductCursor(t *testing.T) {
+	sorts := []product.Sort{{Key: product.SortKeyPrice, Desc: true}}
+	p := &product.Product{ID: "1", Name: "a", Price: money.New(300, "USD"), Seller: "2", Stock: 5, Reserved: 2}
+
+	cursor, err := product.EncodeCursor(sorts, product.CursorValues(p, sorts))
+	if err != nil {
+		t.Fatalf("EncodeCursor() error = %v", err)
+	}
+
+	got, err := product.DecodeProductCursor(cursor, sorts)
+	if err != nil {
+		t.Fatalf("DecodeProductCursor() error = %v", err)
+	}
+	want := &product.Product{ID: "1", Price: money.Money{Amount: 300}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeProductCursor() got = %v, want only the id and the sort values %v", got, want)
+	}
+}
+
+func productIDs(ps []*product.Product) []string {
+	ids := make([]string, len(ps))
+	for i, p := range ps {
+		ids[i] = p.ID
+	}
+	return ids
+}
@@ -1,5 +1,35 @@
 package product
 
-import "errors"
+import (
+	"errors"
+	"github.com/ortymid/market/market/errs"
+)
 
-var ErrNotFound = errors.New("product not found")
+// Resource names the products in the shared errors.
+const Resource = "product"
+
+var ErrNotFound error = errs.NotFound{Resource: Resource}
+
+// ErrMediaNotFound is returned when the product has no media item with the
+// id.
+var ErrMediaNotFound error = errs.NotFound{Resource: "media"}
+
+// ErrVariantNotFound is returned when the product has no variant with the
+// SKU.
+var ErrVariantNotFound error = errs.NotFound{Resource: "variant"}
+
+// ErrInsufficientStock is returned when a stock change would leave fewer than
+// zero available or reserved units.
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// ErrConflict is returned when a conditional update expects another version
+// of the product.
+var ErrConflict = errors.New("product version conflict")
+
+// ErrValidation is returned when a request contains invalid data.
+type ErrValidation = errs.Validation
+
+// invalid returns ErrValidation of the product field.
+func invalid(field, reason string) ErrValidation {
+	return ErrValidation{Resource: Resource, Field: field, Reason: reason}
+}
@@ -1,13 +1,58 @@
 package product
 
-import "context"
+import (
+	"context"
+	"github.com/ortymid/market/market/audit"
+	"github.com/ortymid/market/market/media"
+)
 
 //go:generate mockgen -destination=../../mock/product_service.go -package mock -mock_names=Interface=ProductService . Interface
 
 type Interface interface {
-	Find(ctx context.Context, r FindRequest) ([]*Product, error)
+	Find(ctx context.Context, r FindRequest) (*FindResult, error)
 	FindOne(ctx context.Context, id string) (*Product, error)
+	// FindOneIn returns the product with the price converted to the currency
+	// as its display price.
+	FindOneIn(ctx context.Context, id string, currency string) (*Product, error)
 	Create(ctx context.Context, r CreateRequest) (*Product, error)
 	Update(ctx context.Context, r UpdateRequest) (*Product, error)
 	Delete(ctx context.Context, id string) (*Product, error)
+	// Restore brings back a deleted product which is not purged yet. Only
+	// the seller may restore it.
+	Restore(ctx context.Context, id string) (*Product, error)
+	// History returns the recorded changes of the product.
+	History(ctx context.Context, r audit.FindRequest) ([]*audit.Entry, error)
+
+	// AddMedia appends an image to the media of the product. Only the seller
+	// may add media.
+	AddMedia(ctx context.Context, r AddMediaRequest) (*Product, error)
+	// RemoveMedia removes the media item from the product. Only the seller
+	// may remove media.
+	RemoveMedia(ctx context.Context, id string, mediaID string) (*Product, error)
+	// OpenMedia returns the content of the media item or of its thumbnail.
+	OpenMedia(ctx context.Context, id string, mediaID string, thumbnail bool) (*media.Content, error)
+
+	// AdjustStock adds delta units to the stock of the product or of its
+	// variant with the SKU, a negative delta removes them. Products with
+	// variants are adjusted by variant only. Only the seller may adjust the
+	// stock.
+	AdjustStock(ctx context.Context, id string, variant string, delta int64) (*Product, error)
+}
+
+//go:generate mockgen -destination=../../mock/product_reserver.go -package mock -mock_names=Reserver=ProductReserver . Reserver
+
+// Reserver holds units of the stock for orders. It is not exposed by the
+// transports, the orders get it from Service.Reservations to reserve the
+// units and release or commit them.
+type Reserver interface {
+	// Reserve holds units of the stock for a buyer, so that they cannot be
+	// sold to others. The units are taken from the stock of the variant with
+	// the SKU, products with variants are reserved by variant only.
+	Reserve(ctx context.Context, id string, variant string, quantity int64) (*Product, error)
+	// ReleaseReservation returns reserved units to the stock of the product
+	// or of its variant.
+	ReleaseReservation(ctx context.Context, id string, variant string, quantity int64) (*Product, error)
+	// CommitReservation removes reserved units as sold, the reserved units
+	// of the variants are counted on the product.
+	CommitReservation(ctx context.Context, id string, quantity int64) (*Product, error)
 }
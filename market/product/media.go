@@ -0,0 +1,313 @@
+package product
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/ortymid/market/market/audit"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/media"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+)
+
+// MaxMedia is the number of media items a product may have.
+const MaxMedia = 10
+
+// DefaultMaxMediaSize is the size in bytes media items may have if the
+// service has no MaxMediaSize.
+const DefaultMaxMediaSize = 10 << 20
+
+// mediaRetries is the number of times a media change is retried when the
+// product is changed concurrently.
+const mediaRetries = 3
+
+// AddMediaRequest adds an image to the product.
+type AddMediaRequest struct {
+	Product string
+	// ContentType is the declared content type of the body. It must match the
+	// detected one if it is not empty.
+	ContentType string
+	Body        io.Reader
+}
+
+// AddMedia stores the image along with its thumbnail and appends it to the
+// media of the product. Only the seller may add media.
+func (s *Service) AddMedia(ctx context.Context, r AddMediaRequest) (*Product, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("add media: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("add media: %w", auth.ErrNoUser)
+	}
+	if err := s.checkBlobs(); err != nil {
+		return nil, fmt.Errorf("add media: %w", err)
+	}
+
+	p, err := s.findOne(ctx, r.Product)
+	if err != nil {
+		return nil, fmt.Errorf("add media: %w", err)
+	}
+	if user.ID != p.Seller {
+		err := auth.ErrPermission{Reason: "only own products allowed to update"}
+		return nil, fmt.Errorf("add media: %w", err)
+	}
+	if err := checkMediaCount(p.Media); err != nil {
+		return nil, fmt.Errorf("add media: %w", err)
+	}
+
+	data, err := s.readMedia(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("add media: %w", err)
+	}
+	img, err := decodeMedia(data, r.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("add media: %w", err)
+	}
+	var thumbnail bytes.Buffer
+	if err := media.EncodeThumbnail(&thumbnail, media.Thumbnail(img, media.ThumbnailSize)); err != nil {
+		return nil, fmt.Errorf("add media: %w", err)
+	}
+
+	bounds := img.Bounds()
+	m := media.Media{
+		ID:          newMediaID(),
+		ContentType: img.ContentType,
+		Size:        int64(len(data)),
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+	}
+	if err := s.putMedia(ctx, p.ID, m, data, thumbnail.Bytes()); err != nil {
+		return nil, fmt.Errorf("add media: %w", err)
+	}
+
+	updated, err := s.updateMedia(ctx, p, func(items []media.Media) ([]media.Media, error) {
+		if err := checkMediaCount(items); err != nil {
+			return nil, err
+		}
+		return append(items, m), nil
+	})
+	if err != nil {
+		s.deleteMedia(ctx, p.ID, m.ID)
+		return nil, fmt.Errorf("add media: %w", err)
+	}
+	s.record(ctx, user.ID, audit.OperationUpdate, p, updated)
+
+	return updated, nil
+}
+
+// RemoveMedia removes the media item from the product and deletes its blobs.
+// It returns product.ErrMediaNotFound error if the product has no such item.
+func (s *Service) RemoveMedia(ctx context.Context, id string, mediaID string) (*Product, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("remove media: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("remove media: %w", auth.ErrNoUser)
+	}
+	if err := s.checkBlobs(); err != nil {
+		return nil, fmt.Errorf("remove media: %w", err)
+	}
+
+	p, err := s.findOne(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("remove media: %w", err)
+	}
+	if user.ID != p.Seller {
+		err := auth.ErrPermission{Reason: "only own products allowed to update"}
+		return nil, fmt.Errorf("remove media: %w", err)
+	}
+
+	updated, err := s.updateMedia(ctx, p, func(items []media.Media) ([]media.Media, error) {
+		i := findMedia(items, mediaID)
+		if i < 0 {
+			return nil, ErrMediaNotFound
+		}
+		return append(items[:i], items[i+1:]...), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("remove media: %w", err)
+	}
+	s.deleteMedia(ctx, p.ID, mediaID)
+	s.record(ctx, user.ID, audit.OperationUpdate, p, updated)
+
+	return updated, nil
+}
+
+// OpenMedia returns the content of the media item or of its thumbnail. The
+// media are visible to the users the product is visible to. It returns
+// product.ErrMediaNotFound error if the product has no such item.
+func (s *Service) OpenMedia(ctx context.Context, id string, mediaID string, thumbnail bool) (*media.Content, error) {
+	if err := s.checkBlobs(); err != nil {
+		return nil, fmt.Errorf("get media: %w", err)
+	}
+
+	p, err := s.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	i := findMedia(p.Media, mediaID)
+	if i < 0 {
+		return nil, fmt.Errorf("get media: %w", ErrMediaNotFound)
+	}
+
+	contentType := p.Media[i].ContentType
+	if thumbnail {
+		contentType = media.ThumbnailContentType
+	}
+	body, err := s.Blobs.Get(ctx, mediaKey(p.ID, mediaID, thumbnail))
+	if errors.Is(err, media.ErrNotFound) {
+		return nil, fmt.Errorf("get media: %w", ErrMediaNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get media: %w", err)
+	}
+
+	return &media.Content{ContentType: contentType, Body: body}, nil
+}
+
+// checkBlobs checks that the service stores media.
+func (s *Service) checkBlobs() error {
+	if s.Blobs == nil {
+		return invalid("media", "media are not supported")
+	}
+	return nil
+}
+
+// maxMediaSize returns the size in bytes media items may have.
+func (s *Service) maxMediaSize() int64 {
+	if s.MaxMediaSize <= 0 {
+		return DefaultMaxMediaSize
+	}
+	return s.MaxMediaSize
+}
+
+// readMedia reads the body checking its size.
+func (s *Service) readMedia(body io.Reader) ([]byte, error) {
+	limit := s.maxMediaSize()
+	data, err := ioutil.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading media: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, invalid("size", "must not be empty")
+	}
+	if int64(len(data)) > limit {
+		return nil, invalid("size", fmt.Sprintf("must not be larger than %d bytes", limit))
+	}
+	return data, nil
+}
+
+// decodeMedia decodes the image checking that it is of the declared content
+// type.
+func decodeMedia(data []byte, contentType string) (*media.Image, error) {
+	img, err := media.Decode(data)
+	if errors.Is(err, media.ErrTooLarge) {
+		return nil, invalid("size", err.Error())
+	}
+	if errors.Is(err, media.ErrUnsupported) {
+		return nil, invalid("content_type", err.Error())
+	}
+	if err != nil {
+		return nil, invalid("content_type", "must be a valid image")
+	}
+
+	if contentType != "" {
+		declared, _, err := mime.ParseMediaType(contentType)
+		if err != nil || declared != img.ContentType {
+			reason := fmt.Sprintf("%q does not match the detected %s", contentType, img.ContentType)
+			return nil, invalid("content_type", reason)
+		}
+	}
+	return img, nil
+}
+
+// checkMediaCount checks that one more item may be added to the media.
+func checkMediaCount(items []media.Media) error {
+	if len(items) >= MaxMedia {
+		return invalid("media", fmt.Sprintf("must not have more than %d items", MaxMedia))
+	}
+	return nil
+}
+
+// updateMedia replaces the media of the product with the changed ones. The
+// change is applied again to the current media if the product is changed
+// concurrently.
+func (s *Service) updateMedia(ctx context.Context, p *Product, change func(items []media.Media) ([]media.Media, error)) (*Product, error) {
+	for attempt := 0; ; attempt++ {
+		items, err := change(append([]media.Media{}, p.Media...))
+		if err != nil {
+			return nil, err
+		}
+		version := p.Version
+		updated, err := s.Storage.Update(ctx, UpdateRequest{ID: p.ID, Media: &items, ExpectedVersion: &version})
+		if !errors.Is(err, ErrConflict) || attempt == mediaRetries {
+			return updated, err
+		}
+
+		p, err = s.findOne(ctx, p.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// putMedia stores the media item and its thumbnail.
+func (s *Service) putMedia(ctx context.Context, productID string, m media.Media, data, thumbnail []byte) error {
+	if err := s.Blobs.Put(ctx, mediaKey(productID, m.ID, false), bytes.NewReader(data), m.ContentType); err != nil {
+		return fmt.Errorf("storing media: %w", err)
+	}
+	err := s.Blobs.Put(ctx, mediaKey(productID, m.ID, true), bytes.NewReader(thumbnail), media.ThumbnailContentType)
+	if err != nil {
+		s.deleteMedia(ctx, productID, m.ID)
+		return fmt.Errorf("storing thumbnail: %w", err)
+	}
+	return nil
+}
+
+// deleteMedia deletes the blobs of the media item. The item is already
+// removed from the product or never added to it, so failures are logged
+// rather than returned.
+func (s *Service) deleteMedia(ctx context.Context, productID, mediaID string) {
+	for _, thumbnail := range []bool{false, true} {
+		key := mediaKey(productID, mediaID, thumbnail)
+		if err := s.Blobs.Delete(ctx, key); err != nil {
+			log.Printf("deleting media %s: %v", key, err)
+		}
+	}
+}
+
+// findMedia returns the index of the media item with the id or -1.
+func findMedia(items []media.Media, id string) int {
+	for i, m := range items {
+		if m.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// mediaKey returns the blob key of the media item or of its thumbnail.
+func mediaKey(productID, mediaID string, thumbnail bool) string {
+	key := "products/" + productID + "/" + mediaID
+	if thumbnail {
+		key += ".thumbnail"
+	}
+	return key
+}
+
+// newMediaID returns a random media id.
+func newMediaID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("reading random bytes: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
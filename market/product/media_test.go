@@ -0,0 +1,358 @@
+package product_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/media"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/user"
+	"github.com/ortymid/market/mock"
+	"github.com/ortymid/market/storage/fs"
+	"github.com/ortymid/market/storage/memory"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testPNG returns a PNG image of the size.
+func testPNG(t *testing.T, w, h int) []byte {
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatalf("encoding image: %v", err)
+	}
+	return b.Bytes()
+}
+
+// newMediaService returns a service storing media in a temporary directory
+// with a product of the seller "1".
+func newMediaService(t *testing.T) (*product.Service, *product.Product) {
+	blobs, err := fs.NewBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewBlobStore() error = %v", err)
+	}
+	s := &product.Service{Storage: memory.NewProductStorage(), Blobs: blobs, MaxMediaSize: 1 << 20}
+
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+	p, err := s.Create(ctx, product.CreateRequest{Name: "Banana", Price: money.New(1500, "USD")})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return s, p
+}
+
+func TestService_AddMedia(t *testing.T) {
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+	s, p := newMediaService(t)
+
+	data := testPNG(t, 640, 480)
+	got, err := s.AddMedia(ctx, product.AddMediaRequest{Product: p.ID, ContentType: "image/png", Body: bytes.NewReader(data)})
+	if err != nil {
+		t.Fatalf("AddMedia() error = %v", err)
+	}
+	if len(got.Media) != 1 {
+		t.Fatalf("AddMedia() got %d media, want 1", len(got.Media))
+	}
+	m := got.Media[0]
+	want := media.Media{ID: m.ID, ContentType: "image/png", Size: int64(len(data)), Width: 640, Height: 480}
+	if m.ID == "" || !reflect.DeepEqual(m, want) {
+		t.Errorf("AddMedia() got media %v, want %v", m, want)
+	}
+	if got.Version != p.Version+1 {
+		t.Errorf("AddMedia() got version %d, want %d", got.Version, p.Version+1)
+	}
+
+	c, err := s.OpenMedia(context.Background(), p.ID, m.ID, false)
+	if err != nil {
+		t.Fatalf("OpenMedia() error = %v", err)
+	}
+	body, err := ioutil.ReadAll(c.Body)
+	c.Body.Close()
+	if err != nil {
+		t.Fatalf("reading media: %v", err)
+	}
+	if c.ContentType != "image/png" || !bytes.Equal(body, data) {
+		t.Errorf("OpenMedia() got %s of %d bytes, want image/png of %d bytes", c.ContentType, len(body), len(data))
+	}
+
+	c, err = s.OpenMedia(context.Background(), p.ID, m.ID, true)
+	if err != nil {
+		t.Fatalf("OpenMedia() error = %v", err)
+	}
+	defer c.Body.Close()
+	thumbnail, err := jpeg.DecodeConfig(c.Body)
+	if err != nil {
+		t.Fatalf("decoding thumbnail: %v", err)
+	}
+	if c.ContentType != media.ThumbnailContentType || thumbnail.Width != 256 || thumbnail.Height != 192 {
+		t.Errorf("OpenMedia() got %s thumbnail of %dx%d, want %s of 256x192",
+			c.ContentType, thumbnail.Width, thumbnail.Height, media.ThumbnailContentType)
+	}
+
+	// Media are appended in order.
+	got, err = s.AddMedia(ctx, product.AddMediaRequest{Product: p.ID, Body: bytes.NewReader(testPNG(t, 10, 10))})
+	if err != nil {
+		t.Fatalf("AddMedia() error = %v", err)
+	}
+	if len(got.Media) != 2 || got.Media[0].ID != m.ID {
+		t.Errorf("AddMedia() got media %v, want %s first of 2", got.Media, m.ID)
+	}
+}
+
+func TestService_AddMediaErrors(t *testing.T) {
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		r       func(id string) product.AddMediaRequest
+		wantErr error
+	}{
+		{
+			name: "Should error when data is not image",
+			ctx:  ctx,
+			r: func(id string) product.AddMediaRequest {
+				return product.AddMediaRequest{Product: id, Body: strings.NewReader("not an image")}
+			},
+			wantErr: product.ErrValidation{Resource: product.Resource, Field: "content_type", Reason: media.ErrUnsupported.Error()},
+		},
+		{
+			name: "Should error when content type does not match",
+			ctx:  ctx,
+			r: func(id string) product.AddMediaRequest {
+				return product.AddMediaRequest{Product: id, ContentType: "image/jpeg", Body: bytes.NewReader(testPNG(t, 2, 2))}
+			},
+			wantErr: product.ErrValidation{Resource: product.Resource, Field: "content_type", Reason: `"image/jpeg" does not match the detected image/png`},
+		},
+		{
+			name: "Should error when body is empty",
+			ctx:  ctx,
+			r: func(id string) product.AddMediaRequest {
+				return product.AddMediaRequest{Product: id, Body: strings.NewReader("")}
+			},
+			wantErr: product.ErrValidation{Resource: product.Resource, Field: "size", Reason: "must not be empty"},
+		},
+		{
+			name: "Should error when body is too large",
+			ctx:  ctx,
+			r: func(id string) product.AddMediaRequest {
+				return product.AddMediaRequest{Product: id, Body: io.LimitReader(zeros{}, 2<<20)}
+			},
+			wantErr: product.ErrValidation{Resource: product.Resource, Field: "size", Reason: fmt.Sprintf("must not be larger than %d bytes", 1<<20)},
+		},
+		{
+			name: "Should error when product is not found",
+			ctx:  ctx,
+			r: func(id string) product.AddMediaRequest {
+				return product.AddMediaRequest{Product: "404", Body: bytes.NewReader(testPNG(t, 2, 2))}
+			},
+			wantErr: product.ErrNotFound,
+		},
+		{
+			name: "Should error when user is not seller",
+			ctx:  auth.NewContextWithUser(context.Background(), &user.User{ID: "2"}),
+			r: func(id string) product.AddMediaRequest {
+				return product.AddMediaRequest{Product: id, Body: bytes.NewReader(testPNG(t, 2, 2))}
+			},
+			wantErr: auth.ErrPermission{Reason: "only own products allowed to update"},
+		},
+		{
+			name: "Should error when context without user",
+			ctx:  context.Background(),
+			r: func(id string) product.AddMediaRequest {
+				return product.AddMediaRequest{Product: id, Body: bytes.NewReader(testPNG(t, 2, 2))}
+			},
+			wantErr: auth.ErrNoUser,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, p := newMediaService(t)
+
+			got, err := s.AddMedia(tt.ctx, tt.r(p.ID))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("AddMedia() error = %v, want %v", err, tt.wantErr)
+				return
+			}
+			if got != nil {
+				t.Errorf("AddMedia() got = %v, want nil", got)
+			}
+		})
+	}
+}
+
+func TestService_AddMediaLimit(t *testing.T) {
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+	s, p := newMediaService(t)
+
+	for i := 0; i < product.MaxMedia; i++ {
+		if _, err := s.AddMedia(ctx, product.AddMediaRequest{Product: p.ID, Body: bytes.NewReader(testPNG(t, 2, 2))}); err != nil {
+			t.Fatalf("AddMedia() error = %v", err)
+		}
+	}
+
+	_, err := s.AddMedia(ctx, product.AddMediaRequest{Product: p.ID, Body: bytes.NewReader(testPNG(t, 2, 2))})
+	wantErr := product.ErrValidation{Resource: product.Resource, Field: "media", Reason: fmt.Sprintf("must not have more than %d items", product.MaxMedia)}
+	if !errors.Is(err, wantErr) {
+		t.Errorf("AddMedia() error = %v, want %v", err, wantErr)
+	}
+}
+
+func TestService_AddMediaDeletesBlobsOnFailure(t *testing.T) {
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := mock.NewProductStorage(ctrl)
+	blobs := mock.NewBlobStore(ctrl)
+
+	p := &product.Product{ID: "1", Seller: "1", Version: 1}
+	storage.EXPECT().FindOne(ctx, "1").Return(p, nil)
+	storage.EXPECT().Update(ctx, gomock.Any()).Return(nil, errors.New("storage failure"))
+	var keys []string
+	blobs.EXPECT().Put(ctx, gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, key string, r io.Reader, contentType string) error {
+			keys = append(keys, key)
+			return nil
+		},
+	).Times(2)
+	var deleted []string
+	blobs.EXPECT().Delete(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, key string) error {
+		deleted = append(deleted, key)
+		return nil
+	}).Times(2)
+
+	s := &product.Service{Storage: storage, Blobs: blobs}
+	if _, err := s.AddMedia(ctx, product.AddMediaRequest{Product: "1", Body: bytes.NewReader(testPNG(t, 2, 2))}); err == nil {
+		t.Fatalf("AddMedia() error = nil, want error")
+	}
+	if !reflect.DeepEqual(deleted, keys) {
+		t.Errorf("Delete() got keys %v, want %v", deleted, keys)
+	}
+}
+
+func TestService_AddMediaRetriesConflict(t *testing.T) {
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := mock.NewProductStorage(ctrl)
+	blobs := mock.NewBlobStore(ctrl)
+	blobs.EXPECT().Put(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+
+	// The product gets media concurrently after it is found.
+	existing := media.Media{ID: "a1", ContentType: "image/png", Size: 10, Width: 1, Height: 1}
+	storage.EXPECT().FindOne(ctx, "1").Return(&product.Product{ID: "1", Seller: "1", Version: 1}, nil)
+	storage.EXPECT().Update(ctx, gomock.Any()).Return(nil, product.ErrConflict)
+	storage.EXPECT().FindOne(ctx, "1").Return(&product.Product{ID: "1", Seller: "1", Media: []media.Media{existing}, Version: 2}, nil)
+	storage.EXPECT().Update(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, r product.UpdateRequest) (*product.Product, error) {
+		if *r.ExpectedVersion != 2 || len(*r.Media) != 2 || (*r.Media)[0] != existing {
+			t.Errorf("Update() got version %d and media %v, want version 2 and %s first of 2", *r.ExpectedVersion, *r.Media, existing.ID)
+		}
+		return &product.Product{ID: "1", Seller: "1", Media: *r.Media, Version: 3}, nil
+	})
+
+	s := &product.Service{Storage: storage, Blobs: blobs}
+	got, err := s.AddMedia(ctx, product.AddMediaRequest{Product: "1", Body: bytes.NewReader(testPNG(t, 2, 2))})
+	if err != nil {
+		t.Fatalf("AddMedia() error = %v", err)
+	}
+	if len(got.Media) != 2 {
+		t.Errorf("AddMedia() got media %v, want 2", got.Media)
+	}
+}
+
+func TestService_RemoveMedia(t *testing.T) {
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+	s, p := newMediaService(t)
+
+	p, err := s.AddMedia(ctx, product.AddMediaRequest{Product: p.ID, Body: bytes.NewReader(testPNG(t, 2, 2))})
+	if err != nil {
+		t.Fatalf("AddMedia() error = %v", err)
+	}
+	p, err = s.AddMedia(ctx, product.AddMediaRequest{Product: p.ID, Body: bytes.NewReader(testPNG(t, 4, 4))})
+	if err != nil {
+		t.Fatalf("AddMedia() error = %v", err)
+	}
+	removed, kept := p.Media[0], p.Media[1]
+
+	if _, err := s.RemoveMedia(auth.NewContextWithUser(context.Background(), &user.User{ID: "2"}), p.ID, removed.ID); !errors.As(err, &auth.ErrPermission{}) {
+		t.Errorf("RemoveMedia() error = %v, want permission error", err)
+	}
+
+	got, err := s.RemoveMedia(ctx, p.ID, removed.ID)
+	if err != nil {
+		t.Fatalf("RemoveMedia() error = %v", err)
+	}
+	if !reflect.DeepEqual(got.Media, []media.Media{kept}) {
+		t.Errorf("RemoveMedia() got media %v, want %v", got.Media, []media.Media{kept})
+	}
+
+	if _, err := s.RemoveMedia(ctx, p.ID, removed.ID); !errors.Is(err, product.ErrMediaNotFound) {
+		t.Errorf("RemoveMedia() error = %v, want %v", err, product.ErrMediaNotFound)
+	}
+	if _, err := s.OpenMedia(ctx, p.ID, removed.ID, false); !errors.Is(err, product.ErrMediaNotFound) {
+		t.Errorf("OpenMedia() error = %v, want %v", err, product.ErrMediaNotFound)
+	}
+}
+
+func TestService_OpenMediaUnpublished(t *testing.T) {
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+	s, p := newMediaService(t)
+
+	p, err := s.AddMedia(ctx, product.AddMediaRequest{Product: p.ID, Body: bytes.NewReader(testPNG(t, 2, 2))})
+	if err != nil {
+		t.Fatalf("AddMedia() error = %v", err)
+	}
+	archived := product.StatusArchived
+	if _, err := s.Update(ctx, product.UpdateRequest{ID: p.ID, Status: &archived}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	other := auth.NewContextWithUser(context.Background(), &user.User{ID: "2"})
+	if _, err := s.OpenMedia(other, p.ID, p.Media[0].ID, false); !errors.Is(err, product.ErrNotFound) {
+		t.Errorf("OpenMedia() error = %v, want %v", err, product.ErrNotFound)
+	}
+	c, err := s.OpenMedia(ctx, p.ID, p.Media[0].ID, false)
+	if err != nil {
+		t.Fatalf("OpenMedia() error = %v", err)
+	}
+	c.Body.Close()
+}
+
+func TestService_MediaNotSupported(t *testing.T) {
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+	s := &product.Service{Storage: memory.NewProductStorage()}
+	wantErr := product.ErrValidation{Resource: product.Resource, Field: "media", Reason: "media are not supported"}
+
+	if _, err := s.AddMedia(ctx, product.AddMediaRequest{Product: "1", Body: strings.NewReader("")}); !errors.Is(err, wantErr) {
+		t.Errorf("AddMedia() error = %v, want %v", err, wantErr)
+	}
+	if _, err := s.RemoveMedia(ctx, "1", "a1"); !errors.Is(err, wantErr) {
+		t.Errorf("RemoveMedia() error = %v, want %v", err, wantErr)
+	}
+	if _, err := s.OpenMedia(ctx, "1", "a1", false); !errors.Is(err, wantErr) {
+		t.Errorf("OpenMedia() error = %v, want %v", err, wantErr)
+	}
+}
+
+// zeros reads zero bytes endlessly.
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
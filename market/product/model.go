@@ -1,35 +1,521 @@
 package product
 
+import (
+	"fmt"
+	"github.com/ortymid/market/market/media"
+	"github.com/ortymid/market/market/money"
+	"strings"
+	"time"
+)
+
 type Product struct {
-	ID     string `json:"id" bson:"_id"`
-	Name   string `json:"name"`
-	Price  int64  `json:"price"`
-	Seller string `json:"seller"`
+	ID         string      `json:"id" bson:"_id"`
+	Name       string      `json:"name"`
+	Price      money.Money `json:"price"`
+	Seller     string      `json:"seller"`
+	Categories []string    `json:"categories,omitempty" bson:"categories,omitempty"` // ids of the categories
+	// Media are the images of the product in the order they are shown.
+	Media []media.Media `json:"media,omitempty" bson:"media,omitempty"`
+	// Attributes define the properties the variants differ in, and Variants
+	// are the versions of the product sold under their own SKUs.
+	Attributes []Attribute `json:"attributes,omitempty" bson:"attributes,omitempty"`
+	Variants   []Variant   `json:"variants,omitempty" bson:"variants,omitempty"`
+
+	// Rating aggregates the reviews of the product. It is kept up to date by
+	// the review service and is not changed by updates.
+	Rating Rating `json:"rating" bson:"rating"`
+
+	// Stock is the number of units available to buy. Reserved units are held
+	// for buyers and are not included. The reserved units of the variants
+	// are counted here too.
+	Stock    int64 `json:"stock" bson:"stock"`
+	Reserved int64 `json:"reserved" bson:"reserved"`
+	// LowStockThreshold is the stock at which the product is running low.
+	// Zero disables the threshold.
+	LowStockThreshold int64 `json:"low_stock_threshold,omitempty" bson:"low_stock_threshold"`
+
+	// Status is the stage of the product lifecycle. Only published products
+	// are shown to buyers.
+	Status Status `json:"status" bson:"status"`
+	// PublishAt is the time a draft is published at automatically. Optional.
+	PublishAt *time.Time `json:"publish_at,omitempty" bson:"publish_at,omitempty"`
+
+	// Version is 1 for new products and grows with every update of the
+	// product data. Stock operations do not change it.
+	Version int64 `json:"version" bson:"version"`
+
+	// DeletedAt is the time the product was deleted at, it is nil for
+	// products which are not deleted. Deleted products are purged after the
+	// retention period.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+
+	// DisplayPrice is the price converted to the currency requested for
+	// display. It is not stored and is nil if no currency was requested or
+	// there is no exchange rate for the price.
+	DisplayPrice *money.Money `json:"display_price,omitempty" bson:"-"`
+}
+
+// MaxRating is the highest rating of a product, the lowest one is 1.
+const MaxRating = 5
+
+// Rating is the average rating of the reviews of a product and their number.
+// Products without reviews have zero average.
+type Rating struct {
+	Average float64 `json:"average" bson:"average"`
+	Count   int64   `json:"count" bson:"count"`
+}
+
+// SellerSummary aggregates the published products of a seller which are not
+// deleted.
+type SellerSummary struct {
+	Products int64 // number of the products
+	// Rating is the average rating of all the reviews of the products.
+	Rating Rating
+}
+
+// NewSellerSummary returns the summary of the products, the sum of the
+// average ratings of the products weighted by their counts and the number of
+// their reviews.
+func NewSellerSummary(products int64, ratingSum float64, ratingCount int64) SellerSummary {
+	s := SellerSummary{Products: products}
+	if ratingCount > 0 {
+		s.Rating = Rating{Average: ratingSum / float64(ratingCount), Count: ratingCount}
+	}
+	return s
+}
+
+// SummarizeSellers aggregates the products by seller. Deleted and unpublished
+// products are skipped.
+func SummarizeSellers(ps []*Product) map[string]SellerSummary {
+	type sums struct {
+		products int64
+		rating   float64
+		count    int64
+	}
+
+	bySeller := make(map[string]*sums)
+	for _, p := range ps {
+		if p.Deleted() || p.Status != StatusPublished {
+			continue
+		}
+
+		s, ok := bySeller[p.Seller]
+		if !ok {
+			s = &sums{}
+			bySeller[p.Seller] = s
+		}
+		s.products++
+		s.rating += p.Rating.Average * float64(p.Rating.Count)
+		s.count += p.Rating.Count
+	}
+
+	summaries := make(map[string]SellerSummary, len(bySeller))
+	for seller, s := range bySeller {
+		summaries[seller] = NewSellerSummary(s.products, s.rating, s.count)
+	}
+	return summaries
+}
+
+// Status is a stage of the product lifecycle.
+type Status string
+
+const (
+	// StatusDraft is a product being prepared by the seller, it is shown to
+	// the seller only.
+	StatusDraft Status = "draft"
+	// StatusPublished is a product on sale.
+	StatusPublished Status = "published"
+	// StatusArchived is a product no longer sold, it is shown to the seller
+	// only.
+	StatusArchived Status = "archived"
+)
+
+// Valid reports whether the status is one of the known statuses.
+func (s Status) Valid() bool {
+	switch s {
+	case StatusDraft, StatusPublished, StatusArchived:
+		return true
+	default:
+		return false
+	}
+}
+
+// transitions are the statuses a product may change to from a status.
+var transitions = map[Status][]Status{
+	StatusDraft:     {StatusPublished, StatusArchived},
+	StatusPublished: {StatusArchived},
+	StatusArchived:  {StatusDraft, StatusPublished},
+}
+
+// CanChange reports whether a product may change from the status to the
+// other one. Keeping the status is always allowed.
+func (s Status) CanChange(to Status) bool {
+	if s == to {
+		return true
+	}
+	for _, t := range transitions[s] {
+		if t == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Published reports whether the product is on sale.
+func (p *Product) Published() bool {
+	return p.Status == StatusPublished
+}
+
+// Deleted reports whether the product is deleted and waits to be purged.
+func (p *Product) Deleted() bool {
+	return p.DeletedAt != nil
+}
+
+// TotalStock returns the units of the product and of its variants available
+// to buy.
+func (p *Product) TotalStock() int64 {
+	n := p.Stock
+	for _, v := range p.Variants {
+		n += v.Stock
+	}
+	return n
 }
 
+// LowStock reports whether the total stock has fallen to the threshold.
+func (p *Product) LowStock() bool {
+	return p.LowStockThreshold > 0 && p.TotalStock() <= p.LowStockThreshold
+}
+
+// MaxLimit is the number of products a page may have at most.
+const MaxLimit = 1000
+
 type FindRequest struct {
 	Offset int64
-	Limit  int64
+	Limit  int64 // At most MaxLimit.
+
+	// Cursor continues a listing after the page it was returned with. Offset
+	// is ignored if a cursor is provided. It must be used with the same sort.
+	Cursor string
 
 	// Optional filters.
-	Name       *string
+	Name *string // case-insensitive substring of the name
+	// PriceRange finds products priced within it. Its currency may be omitted
+	// if DisplayCurrency is set, prices in other currencies are converted to
+	// it then.
 	PriceRange *PriceRange
-	Seller     *string
+	// PriceRanges finds products priced within any of them, along with
+	// PriceRange. The service fills them with the price range converted to
+	// every currency.
+	PriceRanges []PriceRange
+	Seller      *string
+	// Categories finds products of any of the categories. The categories are
+	// extended with their descendants if IncludeDescendants is set.
+	Categories         []string
+	IncludeDescendants bool
+	// Attributes finds products with a variant having all the attribute
+	// values by name, e.g. {"color": "red", "size": "42"}.
+	Attributes map[string]string
+	// InStock finds products with available units if true, and sold out
+	// products if false.
+	InStock *bool
+	// MinRating finds products with the average rating at or above it.
+	// Products without reviews are not rated and never match.
+	MinRating *float64
+	// Status finds products with the status.
+	Status *Status
+	// IncludeDeleted lists deleted products along with the others.
+	IncludeDeleted bool
+
+	// Sort lists keys to sort products by in order of priority. Products are
+	// sorted by creation time if no keys provided or to break ties.
+	Sort []Sort
+
+	// DisplayCurrency is the currency to show prices in, see
+	// Product.DisplayPrice. Optional. Sorting by price still compares the
+	// amounts of the original prices.
+	DisplayCurrency string
+}
+
+// Validate checks that the request contains a valid page, sort keys, status,
+// currencies, minimal rating and attribute names.
+func (r FindRequest) Validate() error {
+	if r.Offset < 0 {
+		return invalid("offset", "must not be negative")
+	}
+	if r.Limit < 0 {
+		return invalid("limit", "must not be negative")
+	}
+	if r.Limit > MaxLimit {
+		return invalid("limit", fmt.Sprintf("must not be more than %d", MaxLimit))
+	}
+	for _, s := range r.Sort {
+		if !s.Key.Valid() {
+			return invalid("sort", fmt.Sprintf("unknown key %q", s.Key))
+		}
+	}
+	if r.Status != nil && !r.Status.Valid() {
+		return invalid("status", fmt.Sprintf("unknown status %q", *r.Status))
+	}
+	if r.DisplayCurrency != "" && !money.ValidCurrency(r.DisplayCurrency) {
+		return invalid("display_currency", fmt.Sprintf("unknown currency %q", r.DisplayCurrency))
+	}
+	if r.PriceRange != nil && (r.PriceRange.Currency != "" || r.DisplayCurrency == "") && !money.ValidCurrency(r.PriceRange.Currency) {
+		// The range is in the display currency if its currency is omitted.
+		return invalid("currency", fmt.Sprintf("unknown currency %q", r.PriceRange.Currency))
+	}
+	for _, pr := range r.PriceRanges {
+		if !money.ValidCurrency(pr.Currency) {
+			return invalid("currency", fmt.Sprintf("unknown currency %q", pr.Currency))
+		}
+	}
+	if r.MinRating != nil && (*r.MinRating <= 0 || *r.MinRating > MaxRating) {
+		return invalid("min_rating", fmt.Sprintf("must be above 0 and at most %d", MaxRating))
+	}
+	return validateAttributeFilter(r.Attributes)
+}
+
+// Ranges returns the price ranges to find products within any of.
+func (r FindRequest) Ranges() []PriceRange {
+	if r.PriceRange == nil {
+		return r.PriceRanges
+	}
+	return append([]PriceRange{*r.PriceRange}, r.PriceRanges...)
+}
+
+// Match reports whether the product satisfies the request filters. It is
+// meant for storages which cannot apply the filters natively.
+func (r FindRequest) Match(p *Product) bool {
+	if r.Name != nil && !strings.Contains(strings.ToLower(p.Name), strings.ToLower(*r.Name)) {
+		return false
+	}
+	if ranges := r.Ranges(); len(ranges) > 0 && !matchAnyRange(p.Price, ranges) {
+		return false
+	}
+	if r.Seller != nil && p.Seller != *r.Seller {
+		return false
+	}
+	if len(r.Categories) > 0 && !containsAny(p.Categories, r.Categories) {
+		return false
+	}
+	if len(r.Attributes) > 0 && !matchVariant(p.Variants, r.Attributes) {
+		return false
+	}
+	if r.InStock != nil && (p.TotalStock() > 0) != *r.InStock {
+		return false
+	}
+	if r.MinRating != nil && (p.Rating.Count == 0 || p.Rating.Average < *r.MinRating) {
+		return false
+	}
+	if r.Status != nil && p.Status != *r.Status {
+		return false
+	}
+	if !r.IncludeDeleted && p.Deleted() {
+		return false
+	}
+	return true
+}
+
+// matchAnyRange reports whether the price is within any of the ranges.
+func matchAnyRange(price money.Money, ranges []PriceRange) bool {
+	for _, pr := range ranges {
+		if pr.Match(price) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny reports whether any of the values is in the slice.
+func containsAny(s []string, values []string) bool {
+	for _, v := range values {
+		for _, e := range s {
+			if e == v {
+				return true
+			}
+		}
+	}
+	return false
 }
 
+// FindResult is a page of products found for a FindRequest.
+type FindResult struct {
+	Products []*Product `json:"products"`
+	// Total is the number of products matching the request filters. Storages
+	// which cannot count them cheaply return an estimate and set
+	// TotalEstimated.
+	Total          int64 `json:"total"`
+	TotalEstimated bool  `json:"total_estimated,omitempty"`
+	// HasMore reports whether there are products after the page.
+	HasMore bool `json:"has_more"`
+	// NextCursor continues the listing after the page. It is empty if there
+	// are no more products.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// PriceRange finds products priced in the currency within the limits. The
+// limits are in the minor units of the currency, prices in other currencies
+// never match.
 type PriceRange struct {
-	From *int64 // nil means no lower limit
-	To   *int64 // nil means no upper limit
+	From     *int64 // nil means no lower limit
+	To       *int64 // nil means no upper limit
+	Currency string // required
+}
+
+// Match reports whether the price is within the range.
+func (r PriceRange) Match(price money.Money) bool {
+	if price.Currency != r.Currency {
+		return false
+	}
+	if r.From != nil && price.Amount < *r.From {
+		return false
+	}
+	if r.To != nil && price.Amount > *r.To {
+		return false
+	}
+	return true
 }
 
 type CreateRequest struct {
-	Name   string `json:"name"`
-	Price  int64  `json:"price"`
-	Seller string `json:"seller"`
+	Name       string      `json:"name"`
+	Price      money.Money `json:"price"`
+	Seller     string      `json:"seller"`
+	Categories []string    `json:"categories,omitempty" bson:"categories,omitempty"`
+	Attributes []Attribute `json:"attributes,omitempty" bson:"attributes,omitempty"`
+	Variants   []Variant   `json:"variants,omitempty" bson:"variants,omitempty"` // priced in the currency of the product
+
+	Stock             int64 `json:"stock" bson:"stock"` // initial stock
+	LowStockThreshold int64 `json:"low_stock_threshold,omitempty" bson:"low_stock_threshold"`
+
+	// Status is either draft or published, products are published if it is
+	// empty.
+	Status    Status     `json:"status,omitempty" bson:"status"`
+	PublishAt *time.Time `json:"publish_at,omitempty" bson:"publish_at,omitempty"` // Optional, for drafts only.
 }
 
+// UpdateRequest updates product data. The stock is changed with stock
+// operations only, so that concurrent changes are not lost.
 type UpdateRequest struct {
-	ID    string  `json:"-" bson:"-"`                             // Required to find the product.
-	Name  *string `json:"name,omitempty" bson:"name,omitempty"`   // Optional.
-	Price *int64  `json:"price,omitempty" bson:"price,omitempty"` // Optional.
+	ID    string       `json:"-" bson:"-"`                             // Required to find the product.
+	Name  *string      `json:"name,omitempty" bson:"name,omitempty"`   // Optional.
+	Price *money.Money `json:"price,omitempty" bson:"price,omitempty"` // Optional.
+	// Categories replaces the categories of the product, an empty list removes
+	// the product from all of them. Optional.
+	Categories *[]string `json:"categories,omitempty" bson:"categories,omitempty"`
+	// Attributes and Variants replace the ones of the product. The variants
+	// must match the attributes and the price currency after the update, so
+	// they are usually replaced together. Optional.
+	Attributes *[]Attribute `json:"attributes,omitempty" bson:"attributes,omitempty"`
+	Variants   *[]Variant   `json:"variants,omitempty" bson:"variants,omitempty"`
+	// LowStockThreshold sets the threshold, zero disables it. Optional.
+	LowStockThreshold *int64 `json:"low_stock_threshold,omitempty" bson:"low_stock_threshold,omitempty"`
+	// Status changes the status if the transition is allowed. Optional.
+	Status *Status `json:"status,omitempty" bson:"status,omitempty"`
+	// PublishAt schedules publishing of a draft. Optional.
+	PublishAt *time.Time `json:"publish_at,omitempty" bson:"publish_at,omitempty"`
+	// ExpectedVersion makes the update conditional, the product is updated
+	// only if it has this version. Optional.
+	ExpectedVersion *int64 `json:"-" bson:"-"`
+	// Media replaces the media of the product. It is set by the service when
+	// media are added or removed, updates of products cannot set it.
+	Media *[]media.Media `json:"-" bson:"media,omitempty"`
+}
+
+// StockRequest changes the stock of a product. The changes are applied
+// atomically, and only if neither the stock nor the reserved units become
+// negative.
+type StockRequest struct {
+	ID string
+	// Variant is the SKU of the variant whose stock is changed instead of
+	// the product stock, if it is not empty. The reserved units of the
+	// variants are counted on the product. Storages return
+	// ErrVariantNotFound if the product has no such variant.
+	Variant  string
+	Stock    int64 // change of the available units
+	Reserved int64 // change of the reserved units
+}
+
+// Validate checks that the request contains a valid product data.
+func (r CreateRequest) Validate() error {
+	if len(r.Name) == 0 {
+		return invalid("name", "must not be empty")
+	}
+	if err := validatePrice(r.Price); err != nil {
+		return err
+	}
+	if r.Stock < 0 {
+		return invalid("stock", "must not be negative")
+	}
+	if r.LowStockThreshold < 0 {
+		return invalid("low_stock_threshold", "must not be negative")
+	}
+	switch r.Status {
+	case "", StatusPublished:
+		if r.PublishAt != nil {
+			return invalid("publish_at", "only drafts may be scheduled")
+		}
+	case StatusDraft:
+	default:
+		return invalid("status", "must be draft or published")
+	}
+	if err := validateAttributes(r.Attributes); err != nil {
+		return err
+	}
+	if err := validateVariants(r.Attributes, r.Variants, r.Price.Currency); err != nil {
+		return err
+	}
+	return validateCategories(r.Categories)
+}
+
+// Validate checks that the provided fields contain a valid product data.
+func (r UpdateRequest) Validate() error {
+	if r.Name != nil && len(*r.Name) == 0 {
+		return invalid("name", "must not be empty")
+	}
+	if r.Price != nil {
+		if err := validatePrice(*r.Price); err != nil {
+			return err
+		}
+	}
+	if r.LowStockThreshold != nil && *r.LowStockThreshold < 0 {
+		return invalid("low_stock_threshold", "must not be negative")
+	}
+	if r.Status != nil && !r.Status.Valid() {
+		return invalid("status", fmt.Sprintf("unknown status %q", *r.Status))
+	}
+	if r.Attributes != nil {
+		if err := validateAttributes(*r.Attributes); err != nil {
+			return err
+		}
+	}
+	if r.Categories != nil {
+		return validateCategories(*r.Categories)
+	}
+	return nil
+}
+
+// validatePrice checks that the price is not negative and is in a known
+// currency.
+func validatePrice(m money.Money) error {
+	if m.Amount < 0 {
+		return invalid("price", "must not be negative")
+	}
+	if !money.ValidCurrency(m.Currency) {
+		return invalid("currency", fmt.Sprintf("unknown currency %q", m.Currency))
+	}
+	return nil
+}
+
+// validateCategories checks that category ids are not empty and not repeated.
+func validateCategories(ids []string) error {
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if len(id) == 0 {
+			return invalid("categories", "must not contain empty ids")
+		}
+		if seen[id] {
+			return invalid("categories", fmt.Sprintf("category %q is repeated", id))
+		}
+		seen[id] = true
+	}
+	return nil
 }
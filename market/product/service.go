@@ -2,35 +2,304 @@ package product
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/ortymid/market/market/audit"
 	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/market/clock"
+	"github.com/ortymid/market/market/media"
+	"github.com/ortymid/market/market/money"
+	"log"
+	"math"
+	"time"
 )
 
 type Service struct {
 	Storage Storage
+	// Categories is used to check categories of products and to find products
+	// of subcategories. Requests with categories are rejected if it is nil.
+	Categories category.Interface
+	// Audit records creates, updates and deletes of products. Changes are not
+	// recorded if it is nil.
+	Audit audit.Sink
+	// AuditStorage finds the recorded changes for the history of products,
+	// usually it is the storage Audit records to. History requests are
+	// rejected if it is nil.
+	AuditStorage audit.Storage
+	// Auditors are ids of the users allowed to see the history of any product,
+	// e.g. support staff. Sellers see the history of their own products.
+	Auditors []string
+	// Admins are ids of the users allowed to list deleted and unpublished
+	// products of any seller. Sellers list their own products.
+	Admins []string
+	// Clock is used to delete and publish products, the real clock is used if
+	// it is nil.
+	Clock clock.Clock
+	// Rates converts prices to the display currencies. Requests with a
+	// display currency are rejected if it is nil.
+	Rates money.RateProvider
+	// Blobs keeps the media of products and their thumbnails. Media requests
+	// are rejected if it is nil.
+	Blobs media.BlobStore
+	// MaxMediaSize is the size in bytes media items may have,
+	// DefaultMaxMediaSize is used if it is zero.
+	MaxMediaSize int64
 }
 
-// Find returns a list of products for the given request.
-func (s *Service) Find(ctx context.Context, r FindRequest) ([]*Product, error) {
-	ps, err := s.Storage.Find(ctx, r)
+// clock returns the clock of the service.
+func (s *Service) clock() clock.Clock {
+	if s.Clock == nil {
+		return clock.Real{}
+	}
+	return s.Clock
+}
+
+// now returns the current time as it is stored.
+func (s *Service) now() time.Time {
+	return s.clock().Now().UTC().Truncate(time.Millisecond)
+}
+
+// Find returns a page of products for the given request. Only published
+// products are listed if the request has no status, unless it includes the
+// deleted products, which lists products of any status. Deleted and
+// unpublished products are listed only if requested by an admin or by the
+// seller of the products.
+func (s *Service) Find(ctx context.Context, r FindRequest) (*FindResult, error) {
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("list products: %w", err)
+	}
+	if r.IncludeDeleted {
+		if err := s.checkIncludeDeleted(ctx, r); err != nil {
+			return nil, fmt.Errorf("list products: %w", err)
+		}
+	}
+	if r.Status == nil && !r.IncludeDeleted {
+		published := StatusPublished
+		r.Status = &published
+	}
+	if r.Status != nil && *r.Status != StatusPublished {
+		if err := s.checkUnpublished(ctx, r); err != nil {
+			return nil, fmt.Errorf("list products: %w", err)
+		}
+	}
+
+	if r.IncludeDescendants {
+		ids, err := s.withDescendants(ctx, r.Categories)
+		if err != nil {
+			return nil, fmt.Errorf("list products: %w", err)
+		}
+		r.Categories, r.IncludeDescendants = ids, false
+	}
+
+	if r.DisplayCurrency != "" {
+		if err := s.checkRates(); err != nil {
+			return nil, fmt.Errorf("list products: %w", err)
+		}
+		if r.PriceRange != nil {
+			pr := *r.PriceRange
+			if pr.Currency == "" {
+				pr.Currency = r.DisplayCurrency
+			}
+			ranges, err := s.convertRange(ctx, pr)
+			if err != nil {
+				return nil, fmt.Errorf("list products: %w", err)
+			}
+			r.PriceRange, r.PriceRanges = nil, append(ranges, r.PriceRanges...)
+		}
+	}
+
+	res, err := s.Storage.Find(ctx, r)
 	if err != nil {
 		return nil, fmt.Errorf("list products: %w", err)
 	}
 
-	return ps, nil
+	if r.DisplayCurrency != "" {
+		if err := s.setDisplayPrices(ctx, res.Products, r.DisplayCurrency); err != nil {
+			return nil, fmt.Errorf("list products: %w", err)
+		}
+	}
+
+	return res, nil
+}
+
+// checkRates checks that the service converts prices.
+func (s *Service) checkRates() error {
+	if s.Rates == nil {
+		return invalid("display_currency", "currency conversion is not supported")
+	}
+	return nil
+}
+
+// convertRange returns the price range along with the same range converted to
+// every currency having an exchange rate.
+func (s *Service) convertRange(ctx context.Context, pr PriceRange) ([]PriceRange, error) {
+	ranges := []PriceRange{pr}
+	for _, currency := range money.Currencies() {
+		if currency == pr.Currency {
+			continue
+		}
+		rate, err := s.Rates.Rate(ctx, pr.Currency, currency)
+		if errors.Is(err, money.ErrNoRate) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// The limits are rounded inwards, so that the converted prices stay
+		// within the range.
+		converted := PriceRange{Currency: currency}
+		if pr.From != nil {
+			from := clampAmount(math.Ceil(money.New(*pr.From, pr.Currency).ConvertedAmount(currency, rate)))
+			converted.From = &from
+		}
+		if pr.To != nil {
+			to := clampAmount(math.Floor(money.New(*pr.To, pr.Currency).ConvertedAmount(currency, rate)))
+			converted.To = &to
+		}
+		if converted.From != nil && converted.To != nil && *converted.From > *converted.To {
+			continue
+		}
+		ranges = append(ranges, converted)
+	}
+	return ranges, nil
+}
+
+// clampAmount converts the amount to int64 limiting it to the int64 range.
+func clampAmount(amount float64) int64 {
+	switch {
+	case amount >= math.MaxInt64:
+		return math.MaxInt64
+	case amount <= math.MinInt64:
+		return math.MinInt64
+	default:
+		return int64(amount)
+	}
+}
+
+// setDisplayPrices converts the prices of the products to the currency.
+// Products priced in currencies without an exchange rate are left without a
+// display price.
+func (s *Service) setDisplayPrices(ctx context.Context, products []*Product, currency string) error {
+	rates := make(map[string]float64)
+	for _, p := range products {
+		rate, ok := rates[p.Price.Currency]
+		if !ok {
+			var err error
+			rate, err = s.displayRate(ctx, p.Price.Currency, currency)
+			if errors.Is(err, money.ErrNoRate) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			rates[p.Price.Currency] = rate
+		}
+
+		price := p.Price.Convert(currency, rate)
+		p.DisplayPrice = &price
+	}
+	return nil
+}
+
+// displayRate returns the exchange rate between the currencies.
+func (s *Service) displayRate(ctx context.Context, from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	return s.Rates.Rate(ctx, from, to)
+}
+
+// checkIncludeDeleted checks that the user may list deleted products.
+func (s *Service) checkIncludeDeleted(ctx context.Context, r FindRequest) error {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return auth.ErrNoUser
+	}
+
+	if !s.isAdmin(user.ID) && (r.Seller == nil || *r.Seller != user.ID) {
+		return auth.ErrPermission{Reason: "only own deleted products allowed to list"}
+	}
+	return nil
+}
+
+// checkUnpublished checks that the user may list unpublished products.
+func (s *Service) checkUnpublished(ctx context.Context, r FindRequest) error {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return auth.ErrNoUser
+	}
+
+	if !s.isAdmin(user.ID) && (r.Seller == nil || *r.Seller != user.ID) {
+		return auth.ErrPermission{Reason: "only own unpublished products allowed to list"}
+	}
+	return nil
 }
 
 // FindOne returns a product for the given id. It returns product.ErrNotFound error if
-// there is no product with such id.
+// there is no product with such id or it is deleted. Unpublished products are
+// found only for their seller and the admins.
 func (s *Service) FindOne(ctx context.Context, id string) (*Product, error) {
-	p, err := s.Storage.FindOne(ctx, id)
+	p, err := s.findOne(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get product: %w", err)
+	}
+
+	if !p.Published() {
+		user, err := auth.UserFromContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get product: %w", err)
+		}
+		if user == nil || (user.ID != p.Seller && !s.isAdmin(user.ID)) {
+			return nil, fmt.Errorf("get product: %w", ErrNotFound)
+		}
+	}
+
+	return p, nil
+}
+
+// FindOneIn returns a product for the given id as FindOne does with the price
+// converted to the currency for display.
+func (s *Service) FindOneIn(ctx context.Context, id string, currency string) (*Product, error) {
+	if !money.ValidCurrency(currency) {
+		err := invalid("display_currency", fmt.Sprintf("unknown currency %q", currency))
+		return nil, fmt.Errorf("get product: %w", err)
+	}
+	if err := s.checkRates(); err != nil {
+		return nil, fmt.Errorf("get product: %w", err)
+	}
+
+	p, err := s.FindOne(ctx, id)
 	if err != nil {
+		return nil, err
+	}
+	if err := s.setDisplayPrices(ctx, []*Product{p}, currency); err != nil {
 		return nil, fmt.Errorf("get product: %w", err)
 	}
 
 	return p, nil
 }
 
+// findOne returns the product from the storage hiding deleted products.
+func (s *Service) findOne(ctx context.Context, id string) (*Product, error) {
+	p, err := s.Storage.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Deleted() {
+		return nil, ErrNotFound
+	}
+
+	return p, nil
+}
+
 // Create creates a new product and returns it.
 func (s *Service) Create(ctx context.Context, r CreateRequest) (p *Product, err error) {
 	user, err := auth.UserFromContext(ctx)
@@ -38,7 +307,13 @@ func (s *Service) Create(ctx context.Context, r CreateRequest) (p *Product, err
 		return nil, fmt.Errorf("create product: %w", err)
 	}
 	if user == nil {
-		err := auth.ErrPermission{Reason: "user not provided"}
+		return nil, fmt.Errorf("create product: %w", auth.ErrNoUser)
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("create product: %w", err)
+	}
+	if err := s.checkCategories(ctx, r.Categories); err != nil {
 		return nil, fmt.Errorf("create product: %w", err)
 	}
 
@@ -47,23 +322,37 @@ func (s *Service) Create(ctx context.Context, r CreateRequest) (p *Product, err
 	if err != nil {
 		return nil, fmt.Errorf("create product: %w", err)
 	}
+	s.record(ctx, user.ID, audit.OperationCreate, nil, p)
 
 	return p, nil
 }
 
 // Update updates a product for the given id and returns it. It returns product.ErrNotFound
-// error if there is no product with such id.
+// error if there is no product with such id, and product.ErrConflict error if
+// the request expects another version of the product.
 func (s *Service) Update(ctx context.Context, r UpdateRequest) (*Product, error) {
 	user, err := auth.UserFromContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("update product: %w", err)
 	}
 	if user == nil {
-		err := auth.ErrPermission{Reason: "user not provided"}
+		return nil, fmt.Errorf("update product: %w", auth.ErrNoUser)
+	}
+
+	if err := r.Validate(); err != nil {
 		return nil, fmt.Errorf("update product: %w", err)
 	}
+	if r.Media != nil {
+		err := invalid("media", "must be changed with AddMedia and RemoveMedia")
+		return nil, fmt.Errorf("update product: %w", err)
+	}
+	if r.Categories != nil {
+		if err := s.checkCategories(ctx, *r.Categories); err != nil {
+			return nil, fmt.Errorf("update product: %w", err)
+		}
+	}
 
-	p, err := s.Storage.FindOne(ctx, r.ID)
+	p, err := s.findOne(ctx, r.ID)
 	if err != nil {
 		return nil, fmt.Errorf("update product: %w", err)
 	}
@@ -73,27 +362,77 @@ func (s *Service) Update(ctx context.Context, r UpdateRequest) (*Product, error)
 		return nil, fmt.Errorf("update product: %w", err)
 	}
 
-	p, err = s.Storage.Update(ctx, r)
+	if err := checkStatus(p, r); err != nil {
+		return nil, fmt.Errorf("update product: %w", err)
+	}
+	if err := checkVariants(p, r); err != nil {
+		return nil, fmt.Errorf("update product: %w", err)
+	}
+
+	// The storage checks the version again, as the product may be changed
+	// concurrently.
+	if r.ExpectedVersion != nil && *r.ExpectedVersion != p.Version {
+		return nil, fmt.Errorf("update product: %w", ErrConflict)
+	}
+
+	updated, err := s.Storage.Update(ctx, r)
 	if err != nil {
 		return nil, fmt.Errorf("update product: %w", err)
 	}
+	s.record(ctx, user.ID, audit.OperationUpdate, p, updated)
 
-	return p, nil
+	return updated, nil
 }
 
-// Delete deletes a product for the given id. It returns product.ErrNotFound
-// error if there is no product with such id.
+// checkStatus checks that the request changes the status of the product
+// with an allowed transition and that only drafts are scheduled.
+func checkStatus(p *Product, r UpdateRequest) error {
+	status := p.Status
+	if r.Status != nil {
+		if !p.Status.CanChange(*r.Status) {
+			reason := fmt.Sprintf("cannot change from %s to %s", p.Status, *r.Status)
+			return invalid("status", reason)
+		}
+		status = *r.Status
+	}
+	if r.PublishAt != nil && status != StatusDraft {
+		return invalid("publish_at", "only drafts may be scheduled")
+	}
+	return nil
+}
+
+// checkVariants checks that the variants of the product match its attributes
+// and price currency after the update.
+func checkVariants(p *Product, r UpdateRequest) error {
+	if r.Attributes == nil && r.Variants == nil && r.Price == nil {
+		return nil
+	}
+	attrs, variants, currency := p.Attributes, p.Variants, p.Price.Currency
+	if r.Attributes != nil {
+		attrs = *r.Attributes
+	}
+	if r.Variants != nil {
+		variants = *r.Variants
+	}
+	if r.Price != nil {
+		currency = r.Price.Currency
+	}
+	return validateVariants(attrs, variants, currency)
+}
+
+// Delete deletes a product for the given id. The product is kept until it is
+// purged, so it can be restored. It returns product.ErrNotFound error if there
+// is no product with such id.
 func (s *Service) Delete(ctx context.Context, id string) (*Product, error) {
 	user, err := auth.UserFromContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("delete product: %w", err)
 	}
 	if user == nil {
-		err := auth.ErrPermission{Reason: "user not provided"}
-		return nil, fmt.Errorf("delete product: %w", err)
+		return nil, fmt.Errorf("delete product: %w", auth.ErrNoUser)
 	}
 
-	p, err := s.Storage.FindOne(ctx, id)
+	p, err := s.findOne(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("delete product: %w", err)
 	}
@@ -103,10 +442,358 @@ func (s *Service) Delete(ctx context.Context, id string) (*Product, error) {
 		return nil, fmt.Errorf("delete product: %w", err)
 	}
 
-	p, err = s.Storage.Delete(ctx, id)
+	p, err = s.Storage.Delete(ctx, id, s.now())
 	if err != nil {
 		return nil, fmt.Errorf("delete product: %w", err)
 	}
+	s.record(ctx, user.ID, audit.OperationDelete, p, nil)
+
+	return p, nil
+}
+
+// Restore brings back a deleted product for the given id and returns it. It
+// returns product.ErrNotFound error if there is no deleted product with such
+// id, e.g. it is already purged.
+func (s *Service) Restore(ctx context.Context, id string) (*Product, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("restore product: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("restore product: %w", auth.ErrNoUser)
+	}
+
+	p, err := s.Storage.FindOne(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("restore product: %w", err)
+	}
+	if !p.Deleted() {
+		return nil, fmt.Errorf("restore product: %w", ErrNotFound)
+	}
+
+	if user.ID != p.Seller {
+		err := auth.ErrPermission{Reason: "only own products allowed to restore"}
+		return nil, fmt.Errorf("restore product: %w", err)
+	}
+
+	p, err = s.Storage.Restore(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("restore product: %w", err)
+	}
+	s.record(ctx, user.ID, audit.OperationRestore, nil, p)
 
 	return p, nil
 }
+
+// Purge permanently removes the products deleted more than retention ago and
+// returns the number of removed products.
+func (s *Service) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	n, err := s.Storage.Purge(ctx, s.now().Add(-retention))
+	if err != nil {
+		return n, fmt.Errorf("purge products: %w", err)
+	}
+
+	return n, nil
+}
+
+// RunPurge purges deleted products every interval until the context is done.
+// Failures are logged, the next run purges the products left.
+func (s *Service) RunPurge(ctx context.Context, retention, interval time.Duration) {
+	for {
+		n, err := s.Purge(ctx, retention)
+		if err != nil {
+			log.Printf("purging deleted products: %v", err)
+		} else if n > 0 {
+			log.Printf("purged %d deleted products", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock().After(interval):
+		}
+	}
+}
+
+// PublishDue publishes the drafts which are scheduled to be published by now
+// and returns them.
+func (s *Service) PublishDue(ctx context.Context) ([]*Product, error) {
+	ps, err := s.Storage.PublishDue(ctx, s.now())
+	if err != nil {
+		return nil, fmt.Errorf("publish products: %w", err)
+	}
+
+	for _, p := range ps {
+		before := *p
+		before.Status, before.PublishAt = StatusDraft, nil
+		s.record(ctx, schedulerActor, audit.OperationUpdate, &before, p)
+	}
+
+	return ps, nil
+}
+
+// schedulerActor is the actor of the changes made by the scheduler.
+const schedulerActor = "scheduler"
+
+// RunScheduler publishes scheduled drafts every interval until the context is
+// done. Failures are logged, the drafts are published by the next run.
+func (s *Service) RunScheduler(ctx context.Context, interval time.Duration) {
+	for {
+		ps, err := s.PublishDue(ctx)
+		if err != nil {
+			log.Printf("publishing scheduled products: %v", err)
+		} else if len(ps) > 0 {
+			log.Printf("published %d scheduled products", len(ps))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock().After(interval):
+		}
+	}
+}
+
+// History returns a page of the recorded changes of the product in the order
+// they were made. The history of deleted products is kept. Only the seller of
+// the product and the auditors may see it.
+func (s *Service) History(ctx context.Context, r audit.FindRequest) ([]*audit.Entry, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get product history: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("get product history: %w", auth.ErrNoUser)
+	}
+
+	if r.Offset < 0 {
+		return nil, fmt.Errorf("get product history: %w", invalid("offset", "must not be negative"))
+	}
+	if r.Limit < 0 {
+		return nil, fmt.Errorf("get product history: %w", invalid("limit", "must not be negative"))
+	}
+	if s.AuditStorage == nil {
+		return nil, errors.New("get product history: history is not recorded")
+	}
+
+	es, err := s.AuditStorage.Find(ctx, r)
+	if err != nil {
+		return nil, fmt.Errorf("get product history: %w", err)
+	}
+
+	// All entries of a product have the same seller.
+	if !s.isAuditor(user.ID) && len(es) > 0 && es[0].Seller != user.ID {
+		err := auth.ErrPermission{Reason: "only own products allowed to get history"}
+		return nil, fmt.Errorf("get product history: %w", err)
+	}
+
+	return es, nil
+}
+
+// isAdmin reports whether the user may list deleted products of any seller.
+func (s *Service) isAdmin(id string) bool {
+	for _, a := range s.Admins {
+		if a == id {
+			return true
+		}
+	}
+	return false
+}
+
+// isAuditor reports whether the user may see the history of any product.
+func (s *Service) isAuditor(id string) bool {
+	for _, a := range s.Auditors {
+		if a == id {
+			return true
+		}
+	}
+	return false
+}
+
+// AdjustStock adds delta units to the stock of the product or of its variant
+// and returns it. It returns product.ErrInsufficientStock error if fewer than
+// -delta units are available.
+func (s *Service) AdjustStock(ctx context.Context, id string, variant string, delta int64) (*Product, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("adjust stock: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("adjust stock: %w", auth.ErrNoUser)
+	}
+
+	if delta == 0 {
+		return nil, fmt.Errorf("adjust stock: %w", invalid("delta", "must not be zero"))
+	}
+
+	p, err := s.findOne(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("adjust stock: %w", err)
+	}
+
+	if user.ID != p.Seller {
+		err := auth.ErrPermission{Reason: "only own products allowed to adjust stock"}
+		return nil, fmt.Errorf("adjust stock: %w", err)
+	}
+
+	if err := checkVariant(p, variant); err != nil {
+		return nil, fmt.Errorf("adjust stock: %w", err)
+	}
+
+	p, err = s.Storage.UpdateStock(ctx, StockRequest{ID: id, Variant: variant, Stock: delta})
+	if err != nil {
+		return nil, fmt.Errorf("adjust stock: %w", err)
+	}
+
+	return p, nil
+}
+
+// Reservations returns the reservations of the stock of the products for the
+// orders. They are not methods of the service, so that only the code given
+// the Reserver, and not the transports, may hold and sell units.
+func (s *Service) Reservations() Reserver {
+	return reservations{s: s}
+}
+
+// reservations implements Reserver on the products of the service.
+type reservations struct {
+	s *Service
+}
+
+// Reserve moves units from the stock of the product or of its variant to the
+// reserved units of the product and returns it. Only published products may
+// be reserved, and products with variants must be reserved by variant. It
+// returns product.ErrInsufficientStock error if fewer units are available.
+func (r reservations) Reserve(ctx context.Context, id string, variant string, quantity int64) (*Product, error) {
+	if err := checkReservation(ctx, quantity); err != nil {
+		return nil, fmt.Errorf("reserve stock: %w", err)
+	}
+
+	p, err := r.s.findOne(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("reserve stock: %w", err)
+	}
+	if !p.Published() {
+		return nil, fmt.Errorf("reserve stock: %w", ErrNotFound)
+	}
+	if err := checkVariant(p, variant); err != nil {
+		return nil, fmt.Errorf("reserve stock: %w", err)
+	}
+
+	p, err = r.s.Storage.UpdateStock(ctx, StockRequest{ID: id, Variant: variant, Stock: -quantity, Reserved: quantity})
+	if err != nil {
+		return nil, fmt.Errorf("reserve stock: %w", err)
+	}
+
+	return p, nil
+}
+
+// ReleaseReservation moves reserved units of the product back to the stock of
+// the product or of its variant and returns it. It returns
+// product.ErrInsufficientStock error if fewer units are reserved.
+func (r reservations) ReleaseReservation(ctx context.Context, id string, variant string, quantity int64) (*Product, error) {
+	if err := checkReservation(ctx, quantity); err != nil {
+		return nil, fmt.Errorf("release reservation: %w", err)
+	}
+
+	p, err := r.s.Storage.UpdateStock(ctx, StockRequest{ID: id, Variant: variant, Stock: quantity, Reserved: -quantity})
+	if err != nil {
+		return nil, fmt.Errorf("release reservation: %w", err)
+	}
+
+	return p, nil
+}
+
+// CommitReservation removes sold units from the reserved units of the product
+// and returns it. It returns product.ErrInsufficientStock error if fewer units
+// are reserved.
+func (r reservations) CommitReservation(ctx context.Context, id string, quantity int64) (*Product, error) {
+	if err := checkReservation(ctx, quantity); err != nil {
+		return nil, fmt.Errorf("commit reservation: %w", err)
+	}
+
+	p, err := r.s.Storage.UpdateStock(ctx, StockRequest{ID: id, Reserved: -quantity})
+	if err != nil {
+		return nil, fmt.Errorf("commit reservation: %w", err)
+	}
+
+	return p, nil
+}
+
+// checkVariant checks that the variant is one of the product, or that there
+// is no variant if the product has none.
+func checkVariant(p *Product, variant string) error {
+	if variant == "" {
+		if len(p.Variants) > 0 {
+			return invalid("variant", "must be set for products with variants")
+		}
+		return nil
+	}
+	if _, ok := p.Variant(variant); !ok {
+		return ErrVariantNotFound
+	}
+	return nil
+}
+
+// checkReservation checks that there is a user, any user may reserve units,
+// and that the quantity is positive.
+func checkReservation(ctx context.Context, quantity int64) error {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return auth.ErrNoUser
+	}
+
+	if quantity <= 0 {
+		return invalid("quantity", "must be positive")
+	}
+	return nil
+}
+
+// checkCategories checks that the categories exist.
+func (s *Service) checkCategories(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if s.Categories == nil {
+		return invalid("categories", "categories are not supported")
+	}
+
+	for _, id := range ids {
+		if _, err := s.Categories.FindOne(ctx, id); err != nil {
+			if errors.Is(err, category.ErrNotFound) {
+				return invalid("categories", fmt.Sprintf("unknown category %q", id))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// withDescendants returns the categories with all their descendants.
+func (s *Service) withDescendants(ctx context.Context, ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	if s.Categories == nil {
+		return nil, invalid("categories", "categories are not supported")
+	}
+
+	all := append([]string{}, ids...)
+	for _, id := range ids {
+		ds, err := s.Categories.Descendants(ctx, id)
+		if err != nil {
+			if errors.Is(err, category.ErrNotFound) {
+				return nil, invalid("categories", fmt.Sprintf("unknown category %q", id))
+			}
+			return nil, err
+		}
+
+		for _, d := range ds {
+			all = append(all, d.ID)
+		}
+	}
+	return all, nil
+}
@@ -4,12 +4,17 @@ import (
 	"context"
 	"errors"
 	"github.com/golang/mock/gomock"
+	"github.com/ortymid/market/market/audit"
 	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/market/clock/clocktest"
+	"github.com/ortymid/market/market/money"
 	"github.com/ortymid/market/market/product"
 	"github.com/ortymid/market/market/user"
 	"github.com/ortymid/market/mock"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type setupMocks func(m *mock.ProductStorage)
@@ -30,24 +35,40 @@ func TestService_Create(t *testing.T) {
 			name: "Should create product",
 			args: args{
 				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
-				r:   product.CreateRequest{Name: "name", Price: 100},
+				r:   product.CreateRequest{Name: "name", Price: money.New(100, "USD")},
 			},
 			setupMockProductStorage: func(m *mock.ProductStorage) {
 				m.EXPECT().Create(
 					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
-					product.CreateRequest{Name: "name", Price: 100, Seller: "1"},
+					product.CreateRequest{Name: "name", Price: money.New(100, "USD"), Seller: "1"},
 				).Return(
-					&product.Product{ID: "1", Name: "name", Price: 100, Seller: "1"},
+					&product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "1"},
 					nil,
 				)
 			},
-			wantP: &product.Product{ID: "1", Name: "name", Price: 100, Seller: "1"},
+			wantP: &product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "1"},
 		},
 		{
 			name: "Should error when context without user",
 			args: args{
 				ctx: context.Background(),
-				r:   product.CreateRequest{Name: "name", Price: 100},
+				r:   product.CreateRequest{Name: "name", Price: money.New(100, "USD")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should error when request is invalid",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+				r:   product.CreateRequest{Name: "", Price: money.New(100, "USD")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should error when currency is unknown",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+				r:   product.CreateRequest{Name: "name", Price: money.New(100, "XXX")},
 			},
 			wantErr: true,
 		},
@@ -55,12 +76,12 @@ func TestService_Create(t *testing.T) {
 			name: "Should error when storage returns error",
 			args: args{
 				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
-				r:   product.CreateRequest{Name: "name", Price: 100},
+				r:   product.CreateRequest{Name: "name", Price: money.New(100, "USD")},
 			},
 			setupMockProductStorage: func(m *mock.ProductStorage) {
 				m.EXPECT().Create(
 					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
-					product.CreateRequest{Name: "name", Price: 100, Seller: "1"},
+					product.CreateRequest{Name: "name", Price: money.New(100, "USD"), Seller: "1"},
 				).Return(
 					nil,
 					errors.New("test error"),
@@ -113,23 +134,41 @@ func TestService_Delete(t *testing.T) {
 				id:  "1",
 			},
 			setupMockProductStorage: func(m *mock.ProductStorage) {
-				m.EXPECT().Get(
+				m.EXPECT().FindOne(
 					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
 					"1",
 				).Return(
-					&product.Product{ID: "1", Name: "name", Price: 100, Seller: "1"},
+					&product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "1"},
 					nil,
 				)
 
 				m.EXPECT().Delete(
 					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
 					"1",
+					gomock.Any(),
+				).Return(
+					&product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "1", DeletedAt: &testTime},
+					nil,
+				)
+			},
+			want: &product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "1", DeletedAt: &testTime},
+		},
+		{
+			name: "Should error when product is already deleted",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+				id:  "1",
+			},
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(
+					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+					"1",
 				).Return(
-					&product.Product{ID: "1", Name: "name", Price: 100, Seller: "1"},
+					&product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "1", DeletedAt: &testTime},
 					nil,
 				)
 			},
-			want: &product.Product{ID: "1", Name: "name", Price: 100, Seller: "1"},
+			wantErr: true,
 		},
 		{
 			name: "Should error when product not found",
@@ -138,7 +177,7 @@ func TestService_Delete(t *testing.T) {
 				id:  "1",
 			},
 			setupMockProductStorage: func(m *mock.ProductStorage) {
-				m.EXPECT().Get(
+				m.EXPECT().FindOne(
 					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
 					"1",
 				).Return(
@@ -163,11 +202,11 @@ func TestService_Delete(t *testing.T) {
 				id:  "1",
 			},
 			setupMockProductStorage: func(m *mock.ProductStorage) {
-				m.EXPECT().Get(
+				m.EXPECT().FindOne(
 					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
 					"1",
 				).Return(
-					&product.Product{ID: "1", Name: "name", Price: 100, Seller: "2"},
+					&product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "2"},
 					nil,
 				)
 			},
@@ -199,7 +238,7 @@ func TestService_Delete(t *testing.T) {
 	}
 }
 
-func TestService_Get(t *testing.T) {
+func TestService_Restore(t *testing.T) {
 	type args struct {
 		ctx context.Context
 		id  string
@@ -209,41 +248,72 @@ func TestService_Get(t *testing.T) {
 		args                    args
 		setupMockProductStorage setupMocks
 		want                    *product.Product
-		wantErr                 bool
+		wantErr                 error
 	}{
 		{
-			name: "Should get product",
+			name: "Should restore product",
 			args: args{
-				ctx: context.Background(),
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
 				id:  "1",
 			},
 			setupMockProductStorage: func(m *mock.ProductStorage) {
-				m.EXPECT().Get(
-					context.Background(),
-					"1",
-				).Return(
-					&product.Product{ID: "1", Name: "name", Price: 100, Seller: "1"},
+				m.EXPECT().FindOne(gomock.Any(), "1").Return(
+					&product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "1", DeletedAt: &testTime},
+					nil,
+				)
+				m.EXPECT().Restore(gomock.Any(), "1").Return(
+					&product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "1"},
 					nil,
 				)
 			},
-			want: &product.Product{ID: "1", Name: "name", Price: 100, Seller: "1"},
+			want: &product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "1"},
 		},
 		{
-			name: "Should error when product not found",
+			name: "Should error when product is not deleted",
 			args: args{
-				ctx: context.Background(),
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
 				id:  "1",
 			},
 			setupMockProductStorage: func(m *mock.ProductStorage) {
-				m.EXPECT().Get(
-					context.Background(),
-					"1",
-				).Return(
+				m.EXPECT().FindOne(gomock.Any(), "1").Return(
+					&product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "1"},
 					nil,
-					product.ErrNotFound,
 				)
 			},
-			wantErr: true,
+			wantErr: product.ErrNotFound,
+		},
+		{
+			name: "Should error when product is purged",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+				id:  "1",
+			},
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(gomock.Any(), "1").Return(nil, product.ErrNotFound)
+			},
+			wantErr: product.ErrNotFound,
+		},
+		{
+			name: "Should error when user is not seller",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "2"}),
+				id:  "1",
+			},
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(gomock.Any(), "1").Return(
+					&product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "1", DeletedAt: &testTime},
+					nil,
+				)
+			},
+			wantErr: auth.ErrPermission{Reason: "only own products allowed to restore"},
+		},
+		{
+			name: "Should error when context without user",
+			args: args{
+				ctx: context.Background(),
+				id:  "1",
+			},
+			wantErr: auth.ErrNoUser,
 		},
 	}
 	for _, tt := range tests {
@@ -259,52 +329,203 @@ func TestService_Get(t *testing.T) {
 			s := &product.Service{
 				Storage: storage,
 			}
-			got, err := s.FindOne(tt.args.ctx, tt.args.id)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("FindOne() error = %v, wantErr %v", err, tt.wantErr)
+			got, err := s.Restore(tt.args.ctx, tt.args.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Restore() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("FindOne() got = %v, want %v", got, tt.want)
+				t.Errorf("Restore() got = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestService_List(t *testing.T) {
+func TestService_Purge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := mock.NewProductStorage(ctrl)
+	storage.EXPECT().Purge(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, before time.Time) (int64, error) {
+		if d := time.Since(before); d < time.Hour || d > time.Hour+time.Minute {
+			t.Errorf("Purge() got time %v before now, want %v", d, time.Hour)
+		}
+		return 2, nil
+	})
+
+	s := &product.Service{Storage: storage}
+	n, err := s.Purge(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Purge() got %d, want %d", n, 2)
+	}
+}
+
+func TestService_RunScheduler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := testTime
+	clk := clocktest.NewClock(start)
+	published := &product.Product{ID: "1", Seller: "1", Status: product.StatusPublished, Version: 2}
+
+	storage := mock.NewProductStorage(ctrl)
+	audits := mock.NewAuditStorage(ctrl)
+	gomock.InOrder(
+		storage.EXPECT().PublishDue(gomock.Any(), start).Return(nil, nil),
+		storage.EXPECT().PublishDue(gomock.Any(), start.Add(time.Minute)).Return([]*product.Product{published}, nil),
+	)
+	recorded := make(chan audit.Entry, 1)
+	audits.EXPECT().Record(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, e audit.Entry) error {
+		recorded <- e
+		return nil
+	})
+
+	s := &product.Service{Storage: storage, Audit: audits, Clock: clk}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.RunScheduler(ctx, time.Minute)
+		close(done)
+	}()
+
+	// Nothing is due at the first run, the scheduler waits for the next one.
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+
+	e := <-recorded
+	want := audit.Entry{
+		Product: "1", Seller: "1", Actor: "scheduler", Operation: audit.OperationUpdate, Version: 2,
+		Changes: []audit.Change{
+			{Field: "status", Before: `"draft"`, After: `"published"`},
+		},
+		CreatedAt: start.Add(time.Minute),
+	}
+	if !reflect.DeepEqual(e, want) {
+		t.Errorf("Record() got = %v, want %v", e, want)
+	}
+
+	clk.BlockUntil(1)
+	cancel()
+	<-done
+}
+
+func TestService_Get(t *testing.T) {
 	type args struct {
 		ctx context.Context
-		r   product.FindRequest
+		id  string
 	}
 	tests := []struct {
 		name                    string
 		args                    args
 		setupMockProductStorage setupMocks
-		want                    []*product.Product
+		want                    *product.Product
 		wantErr                 bool
 	}{
 		{
-			name: "Should list products",
+			name: "Should get product",
 			args: args{
 				ctx: context.Background(),
-				r:   product.FindRequest{Offset: 2, Limit: 2},
+				id:  "1",
 			},
 			setupMockProductStorage: func(m *mock.ProductStorage) {
-				m.EXPECT().List(
+				m.EXPECT().FindOne(
 					context.Background(),
-					product.FindRequest{Offset: 2, Limit: 2},
+					"1",
+				).Return(
+					&product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "1", Status: product.StatusPublished},
+					nil,
+				)
+			},
+			want: &product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "1", Status: product.StatusPublished},
+		},
+		{
+			name: "Should get own draft",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+				id:  "1",
+			},
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(
+					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+					"1",
+				).Return(
+					&product.Product{ID: "1", Seller: "1", Status: product.StatusDraft},
+					nil,
+				)
+			},
+			want: &product.Product{ID: "1", Seller: "1", Status: product.StatusDraft},
+		},
+		{
+			name: "Should error when draft is got by others",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "2"}),
+				id:  "1",
+			},
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(
+					auth.NewContextWithUser(context.Background(), &user.User{ID: "2"}),
+					"1",
+				).Return(
+					&product.Product{ID: "1", Seller: "1", Status: product.StatusDraft},
+					nil,
+				)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should error when archived product is got without user",
+			args: args{
+				ctx: context.Background(),
+				id:  "1",
+			},
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(
+					context.Background(),
+					"1",
+				).Return(
+					&product.Product{ID: "1", Seller: "1", Status: product.StatusArchived},
+					nil,
+				)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should error when product not found",
+			args: args{
+				ctx: context.Background(),
+				id:  "1",
+			},
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(
+					context.Background(),
+					"1",
 				).Return(
-					[]*product.Product{
-						{ID: "1", Name: "name1", Price: 100, Seller: "1"},
-						{ID: "2", Name: "name2", Price: 200, Seller: "2"},
-					},
 					nil,
+					product.ErrNotFound,
 				)
 			},
-			want: []*product.Product{
-				{ID: "1", Name: "name1", Price: 100, Seller: "1"},
-				{ID: "2", Name: "name2", Price: 200, Seller: "2"},
+			wantErr: true,
+		},
+		{
+			name: "Should error when product is deleted",
+			args: args{
+				ctx: context.Background(),
+				id:  "1",
+			},
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(
+					context.Background(),
+					"1",
+				).Return(
+					&product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "1", DeletedAt: &testTime},
+					nil,
+				)
 			},
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
@@ -320,116 +541,403 @@ func TestService_List(t *testing.T) {
 			s := &product.Service{
 				Storage: storage,
 			}
-			got, err := s.Find(tt.args.ctx, tt.args.r)
+			got, err := s.FindOne(tt.args.ctx, tt.args.id)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("Find() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("FindOne() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("Find() got = %v, want %v", got, tt.want)
+				t.Errorf("FindOne() got = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestService_Update(t *testing.T) {
+func TestService_List(t *testing.T) {
 	type args struct {
 		ctx context.Context
-		r   product.UpdateRequest
+		r   product.FindRequest
 	}
 	tests := []struct {
-		name       string
-		args       args
-		setupMocks setupMocks
-		want       *product.Product
-		wantErr    bool
+		name                    string
+		args                    args
+		setupMockProductStorage setupMocks
+		want                    *product.FindResult
+		wantErr                 bool
 	}{
 		{
-			name: "Should update product",
+			name: "Should list products",
 			args: args{
-				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
-				r: product.UpdateRequest{
-					ID:    "1",
-					Name:  testStringPtr("new name"),
-					Price: testInt64Ptr(100),
-				},
+				ctx: context.Background(),
+				r:   product.FindRequest{Offset: 2, Limit: 2},
 			},
-			setupMocks: func(m *mock.ProductStorage) {
-				m.EXPECT().Get(
-					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
-					"1",
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().Find(
+					context.Background(),
+					product.FindRequest{Offset: 2, Limit: 2, Status: testStatusPtr(product.StatusPublished)},
 				).Return(
-					&product.Product{ID: "1", Name: "name", Price: 10, Seller: "1"},
-					nil,
-				)
-
-				m.EXPECT().Update(
-					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
-					product.UpdateRequest{
-						ID:    "1",
-						Name:  testStringPtr("new name"),
-						Price: testInt64Ptr(100),
+					&product.FindResult{
+						Products: []*product.Product{
+							{ID: "1", Name: "name1", Price: money.New(100, "USD"), Seller: "1"},
+							{ID: "2", Name: "name2", Price: money.New(200, "USD"), Seller: "2"},
+						},
+						NextCursor: "cursor",
 					},
-				).Return(
-					&product.Product{ID: "1", Name: "new name", Price: 100, Seller: "1"},
 					nil,
 				)
 			},
-			want: &product.Product{ID: "1", Name: "new name", Price: 100, Seller: "1"},
+			want: &product.FindResult{
+				Products: []*product.Product{
+					{ID: "1", Name: "name1", Price: money.New(100, "USD"), Seller: "1"},
+					{ID: "2", Name: "name2", Price: money.New(200, "USD"), Seller: "2"},
+				},
+				NextCursor: "cursor",
+			},
 		},
 		{
-			name: "Should error when user is not seller",
+			name: "Should list products in price range of the currency",
 			args: args{
-				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
-				r: product.UpdateRequest{
-					ID:    "1",
-					Name:  testStringPtr("new name"),
-					Price: testInt64Ptr(100),
+				ctx: context.Background(),
+				r: product.FindRequest{
+					Limit:      2,
+					PriceRange: &product.PriceRange{From: testInt64Ptr(100), Currency: "EUR"},
 				},
 			},
-			setupMocks: func(m *mock.ProductStorage) {
-				m.EXPECT().Get(
-					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
-					"1",
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().Find(
+					context.Background(),
+					product.FindRequest{
+						Limit:      2,
+						PriceRange: &product.PriceRange{From: testInt64Ptr(100), Currency: "EUR"},
+						Status:     testStatusPtr(product.StatusPublished),
+					},
 				).Return(
-					&product.Product{ID: "1", Name: "name", Price: 10, Seller: "2"},
+					&product.FindResult{Products: []*product.Product{{ID: "1", Price: money.New(200, "EUR"), Seller: "1"}}},
 					nil,
 				)
 			},
+			want: &product.FindResult{Products: []*product.Product{{ID: "1", Price: money.New(200, "EUR"), Seller: "1"}}},
+		},
+		{
+			name: "Should error for negative offset",
+			args: args{
+				ctx: context.Background(),
+				r:   product.FindRequest{Offset: -1, Limit: 2},
+			},
 			wantErr: true,
 		},
 		{
-			name: "Should error when context without user",
+			name: "Should error for negative limit",
 			args: args{
 				ctx: context.Background(),
-				r: product.UpdateRequest{
-					ID:    "1",
-					Name:  testStringPtr("new name"),
-					Price: testInt64Ptr(100),
-				},
+				r:   product.FindRequest{Limit: -1},
 			},
 			wantErr: true,
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			ctrl := gomock.NewController(t)
-			defer ctrl.Finish()
-
-			storage := mock.NewProductStorage(ctrl)
-			if tt.setupMocks != nil {
-				tt.setupMocks(storage)
-			}
-
-			s := &product.Service{
-				Storage: storage,
-			}
-			got, err := s.Update(tt.args.ctx, tt.args.r)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
+		{
+			name: "Should error for limit above maximum",
+			args: args{
+				ctx: context.Background(),
+				r:   product.FindRequest{Limit: product.MaxLimit + 1},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should error when price range has no currency",
+			args: args{
+				ctx: context.Background(),
+				r:   product.FindRequest{Limit: 2, PriceRange: &product.PriceRange{From: testInt64Ptr(100)}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should list deleted products for admin",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "admin"}),
+				r:   product.FindRequest{Limit: 2, IncludeDeleted: true},
+			},
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().Find(
+					auth.NewContextWithUser(context.Background(), &user.User{ID: "admin"}),
+					product.FindRequest{Limit: 2, IncludeDeleted: true},
+				).Return(
+					&product.FindResult{Products: []*product.Product{{ID: "1", Seller: "1", DeletedAt: &testTime}}},
+					nil,
+				)
+			},
+			want: &product.FindResult{Products: []*product.Product{{ID: "1", Seller: "1", DeletedAt: &testTime}}},
+		},
+		{
+			name: "Should list own deleted products for seller",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+				r:   product.FindRequest{Limit: 2, Seller: testStringPtr("1"), IncludeDeleted: true},
+			},
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().Find(
+					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+					product.FindRequest{Limit: 2, Seller: testStringPtr("1"), IncludeDeleted: true},
+				).Return(
+					&product.FindResult{Products: []*product.Product{{ID: "1", Seller: "1", DeletedAt: &testTime}}},
+					nil,
+				)
+			},
+			want: &product.FindResult{Products: []*product.Product{{ID: "1", Seller: "1", DeletedAt: &testTime}}},
+		},
+		{
+			name: "Should list own drafts for seller",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+				r:   product.FindRequest{Limit: 2, Seller: testStringPtr("1"), Status: testStatusPtr(product.StatusDraft)},
+			},
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().Find(
+					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+					product.FindRequest{Limit: 2, Seller: testStringPtr("1"), Status: testStatusPtr(product.StatusDraft)},
+				).Return(
+					&product.FindResult{Products: []*product.Product{{ID: "1", Seller: "1", Status: product.StatusDraft}}},
+					nil,
+				)
+			},
+			want: &product.FindResult{Products: []*product.Product{{ID: "1", Seller: "1", Status: product.StatusDraft}}},
+		},
+		{
+			name: "Should error when seller lists drafts of others",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+				r:   product.FindRequest{Limit: 2, Seller: testStringPtr("2"), Status: testStatusPtr(product.StatusDraft)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should error when listing drafts without user",
+			args: args{
+				ctx: context.Background(),
+				r:   product.FindRequest{Limit: 2, Status: testStatusPtr(product.StatusDraft)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should error when seller lists deleted products of others",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+				r:   product.FindRequest{Limit: 2, Seller: testStringPtr("2"), IncludeDeleted: true},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should error when listing deleted products without user",
+			args: args{
+				ctx: context.Background(),
+				r:   product.FindRequest{Limit: 2, IncludeDeleted: true},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			storage := mock.NewProductStorage(ctrl)
+			if tt.setupMockProductStorage != nil {
+				tt.setupMockProductStorage(storage)
+			}
+
+			s := &product.Service{
+				Storage: storage,
+				Admins:  []string{"admin"},
+			}
+			got, err := s.Find(tt.args.ctx, tt.args.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Find() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_Update(t *testing.T) {
+	type args struct {
+		ctx context.Context
+		r   product.UpdateRequest
+	}
+	tests := []struct {
+		name       string
+		args       args
+		setupMocks setupMocks
+		want       *product.Product
+		wantErr    bool
+	}{
+		{
+			name: "Should update product",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+				r: product.UpdateRequest{
+					ID:    "1",
+					Name:  testStringPtr("new name"),
+					Price: testMoneyPtr(money.New(100, "USD")),
+				},
+			},
+			setupMocks: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(
+					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+					"1",
+				).Return(
+					&product.Product{ID: "1", Name: "name", Price: money.New(10, "USD"), Seller: "1"},
+					nil,
+				)
+
+				m.EXPECT().Update(
+					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+					product.UpdateRequest{
+						ID:    "1",
+						Name:  testStringPtr("new name"),
+						Price: testMoneyPtr(money.New(100, "USD")),
+					},
+				).Return(
+					&product.Product{ID: "1", Name: "new name", Price: money.New(100, "USD"), Seller: "1"},
+					nil,
+				)
+			},
+			want: &product.Product{ID: "1", Name: "new name", Price: money.New(100, "USD"), Seller: "1"},
+		},
+		{
+			name: "Should error when user is not seller",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+				r: product.UpdateRequest{
+					ID:    "1",
+					Name:  testStringPtr("new name"),
+					Price: testMoneyPtr(money.New(100, "USD")),
+				},
+			},
+			setupMocks: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(
+					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+					"1",
+				).Return(
+					&product.Product{ID: "1", Name: "name", Price: money.New(10, "USD"), Seller: "2"},
+					nil,
+				)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should publish draft",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+				r:   product.UpdateRequest{ID: "1", Status: testStatusPtr(product.StatusPublished)},
+			},
+			setupMocks: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(
+					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+					"1",
+				).Return(
+					&product.Product{ID: "1", Seller: "1", Status: product.StatusDraft, Version: 1},
+					nil,
+				)
+
+				m.EXPECT().Update(
+					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+					product.UpdateRequest{ID: "1", Status: testStatusPtr(product.StatusPublished)},
+				).Return(
+					&product.Product{ID: "1", Seller: "1", Status: product.StatusPublished, Version: 2},
+					nil,
+				)
+			},
+			want: &product.Product{ID: "1", Seller: "1", Status: product.StatusPublished, Version: 2},
+		},
+		{
+			name: "Should error when published product becomes draft",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+				r:   product.UpdateRequest{ID: "1", Status: testStatusPtr(product.StatusDraft)},
+			},
+			setupMocks: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(
+					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+					"1",
+				).Return(
+					&product.Product{ID: "1", Seller: "1", Status: product.StatusPublished, Version: 1},
+					nil,
+				)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should error when published product is scheduled",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+				r:   product.UpdateRequest{ID: "1", PublishAt: &testTime},
+			},
+			setupMocks: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(
+					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+					"1",
+				).Return(
+					&product.Product{ID: "1", Seller: "1", Status: product.StatusPublished, Version: 1},
+					nil,
+				)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should error when version differs",
+			args: args{
+				ctx: auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+				r: product.UpdateRequest{
+					ID:              "1",
+					Name:            testStringPtr("new name"),
+					ExpectedVersion: testInt64Ptr(1),
+				},
+			},
+			setupMocks: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(
+					auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+					"1",
+				).Return(
+					&product.Product{ID: "1", Name: "name", Price: money.New(10, "USD"), Seller: "1", Version: 2},
+					nil,
+				)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should error when context without user",
+			args: args{
+				ctx: context.Background(),
+				r: product.UpdateRequest{
+					ID:    "1",
+					Name:  testStringPtr("new name"),
+					Price: testMoneyPtr(money.New(100, "USD")),
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			storage := mock.NewProductStorage(ctrl)
+			if tt.setupMocks != nil {
+				tt.setupMocks(storage)
+			}
+
+			s := &product.Service{
+				Storage: storage,
+			}
+			got, err := s.Update(tt.args.ctx, tt.args.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Update() got = %v, want %v", got, tt.want)
 			}
 		})
@@ -440,6 +948,649 @@ func testStringPtr(s string) *string {
 	return &s
 }
 
+func testStatusPtr(s product.Status) *product.Status {
+	return &s
+}
+
 func testInt64Ptr(i int64) *int64 {
 	return &i
 }
+
+func testMoneyPtr(m money.Money) *money.Money {
+	return &m
+}
+
+// testTime is the deletion time of deleted products.
+var testTime = time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func TestService_FindWithDescendants(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	categories := mock.NewCategoryService(ctrl)
+	categories.EXPECT().Descendants(gomock.Any(), "1").Return([]*category.Category{
+		{ID: "2", Slug: "fruits", Name: "Fruits", Parent: testStringPtr("1")},
+		{ID: "3", Slug: "apples", Name: "Apples", Parent: testStringPtr("2")},
+	}, nil)
+	categories.EXPECT().Descendants(gomock.Any(), "4").Return(nil, category.ErrNotFound)
+
+	storage := mock.NewProductStorage(ctrl)
+	storage.EXPECT().Find(
+		gomock.Any(),
+		product.FindRequest{Limit: 10, Categories: []string{"1", "2", "3"}, Status: testStatusPtr(product.StatusPublished)},
+	).Return(&product.FindResult{Products: []*product.Product{}}, nil)
+
+	s := &product.Service{Storage: storage, Categories: categories}
+
+	_, err := s.Find(context.Background(), product.FindRequest{
+		Limit:              10,
+		Categories:         []string{"1"},
+		IncludeDescendants: true,
+	})
+	if err != nil {
+		t.Errorf("Find() error = %v", err)
+	}
+
+	_, err = s.Find(context.Background(), product.FindRequest{
+		Limit:              10,
+		Categories:         []string{"4"},
+		IncludeDescendants: true,
+	})
+	if !errors.As(err, &product.ErrValidation{}) {
+		t.Errorf("Find() for unknown category error = %v, want product.ErrValidation", err)
+	}
+}
+
+func TestService_FindInDisplayCurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := mock.NewProductStorage(ctrl)
+	storage.EXPECT().Find(
+		gomock.Any(),
+		product.FindRequest{
+			Limit:  10,
+			Status: testStatusPtr(product.StatusPublished),
+			PriceRanges: []product.PriceRange{
+				{From: testInt64Ptr(1001), To: testInt64Ptr(5000), Currency: "EUR"},
+				{From: testInt64Ptr(2002), To: testInt64Ptr(10000), Currency: "JPY"},
+				{From: testInt64Ptr(2002), To: testInt64Ptr(10000), Currency: "USD"},
+			},
+			DisplayCurrency: "EUR",
+		},
+	).Return(&product.FindResult{Products: []*product.Product{
+		{ID: "1", Price: money.New(3000, "USD")},
+		{ID: "2", Price: money.New(4000, "JPY")},
+		{ID: "3", Price: money.New(1500, "EUR")},
+		{ID: "4", Price: money.New(1500, "GBP")},
+	}}, nil)
+
+	rates := &money.StaticRates{Base: "USD", Rates: map[string]float64{"EUR": 0.5, "JPY": 100}}
+	s := &product.Service{Storage: storage, Rates: rates}
+
+	got, err := s.Find(context.Background(), product.FindRequest{
+		Limit:           10,
+		PriceRange:      &product.PriceRange{From: testInt64Ptr(1001), To: testInt64Ptr(5000)},
+		DisplayCurrency: "EUR",
+	})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	want := []*money.Money{
+		testMoneyPtr(money.New(1500, "EUR")),
+		testMoneyPtr(money.New(2000, "EUR")),
+		testMoneyPtr(money.New(1500, "EUR")),
+		nil, // no exchange rate
+	}
+	for i, p := range got.Products {
+		if !reflect.DeepEqual(p.DisplayPrice, want[i]) {
+			t.Errorf("Find() got display price of product %s = %v, want %v", p.ID, p.DisplayPrice, want[i])
+		}
+	}
+}
+
+func TestService_FindInDisplayCurrencyErrors(t *testing.T) {
+	rates := &money.StaticRates{Base: "USD", Rates: map[string]float64{"EUR": 0.5}}
+	tests := []struct {
+		name    string
+		rates   money.RateProvider
+		r       product.FindRequest
+		wantErr error
+	}{
+		{
+			name:    "Should error for unknown display currency",
+			rates:   rates,
+			r:       product.FindRequest{Limit: 10, DisplayCurrency: "XXX"},
+			wantErr: product.ErrValidation{Resource: product.Resource, Field: "display_currency", Reason: `unknown currency "XXX"`},
+		},
+		{
+			name:    "Should error without rate provider",
+			r:       product.FindRequest{Limit: 10, DisplayCurrency: "EUR"},
+			wantErr: product.ErrValidation{Resource: product.Resource, Field: "display_currency", Reason: "currency conversion is not supported"},
+		},
+		{
+			name:    "Should error for price range without currency and display currency",
+			rates:   rates,
+			r:       product.FindRequest{Limit: 10, PriceRange: &product.PriceRange{To: testInt64Ptr(100)}},
+			wantErr: product.ErrValidation{Resource: product.Resource, Field: "currency", Reason: `unknown currency ""`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &product.Service{Rates: tt.rates}
+			if _, err := s.Find(context.Background(), tt.r); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Find() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestService_FindOneIn(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := mock.NewProductStorage(ctrl)
+	storage.EXPECT().FindOne(gomock.Any(), "1").Return(
+		&product.Product{ID: "1", Price: money.New(1000, "USD"), Status: product.StatusPublished}, nil,
+	)
+
+	rates := &money.StaticRates{Base: "USD", Rates: map[string]float64{"JPY": 105.5}}
+	s := &product.Service{Storage: storage, Rates: rates}
+
+	got, err := s.FindOneIn(context.Background(), "1", "JPY")
+	if err != nil {
+		t.Fatalf("FindOneIn() error = %v", err)
+	}
+	if want := money.New(1055, "JPY"); got.DisplayPrice == nil || *got.DisplayPrice != want {
+		t.Errorf("FindOneIn() got display price = %v, want %v", got.DisplayPrice, want)
+	}
+
+	want := product.ErrValidation{Resource: product.Resource, Field: "display_currency", Reason: `unknown currency "XXX"`}
+	if _, err := s.FindOneIn(context.Background(), "1", "XXX"); !errors.Is(err, want) {
+		t.Errorf("FindOneIn() error = %v, want %v", err, want)
+	}
+}
+
+func TestService_CreateWithCategories(t *testing.T) {
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+
+	tests := []struct {
+		name       string
+		categories []string
+		setupMocks func(cs *mock.CategoryService, ps *mock.ProductStorage)
+		wantErr    bool
+	}{
+		{
+			name:       "Should create product in existing categories",
+			categories: []string{"1", "2"},
+			setupMocks: func(cs *mock.CategoryService, ps *mock.ProductStorage) {
+				cs.EXPECT().FindOne(ctx, "1").Return(&category.Category{ID: "1"}, nil)
+				cs.EXPECT().FindOne(ctx, "2").Return(&category.Category{ID: "2"}, nil)
+				ps.EXPECT().Create(ctx, product.CreateRequest{
+					Name: "name", Price: money.New(100, "USD"), Seller: "1", Categories: []string{"1", "2"},
+				}).Return(&product.Product{ID: "1"}, nil)
+			},
+		},
+		{
+			name:       "Should error for unknown category",
+			categories: []string{"1", "5"},
+			setupMocks: func(cs *mock.CategoryService, ps *mock.ProductStorage) {
+				cs.EXPECT().FindOne(ctx, "1").Return(&category.Category{ID: "1"}, nil)
+				cs.EXPECT().FindOne(ctx, "5").Return(nil, category.ErrNotFound)
+			},
+			wantErr: true,
+		},
+		{
+			name:       "Should error for repeated category",
+			categories: []string{"1", "1"},
+			setupMocks: func(cs *mock.CategoryService, ps *mock.ProductStorage) {},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			categories := mock.NewCategoryService(ctrl)
+			storage := mock.NewProductStorage(ctrl)
+			tt.setupMocks(categories, storage)
+
+			s := &product.Service{Storage: storage, Categories: categories}
+			_, err := s.Create(ctx, product.CreateRequest{Name: "name", Price: money.New(100, "USD"), Categories: tt.categories})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestService_AdjustStock(t *testing.T) {
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+
+	tests := []struct {
+		name                    string
+		ctx                     context.Context
+		variant                 string
+		delta                   int64
+		setupMockProductStorage setupMocks
+		want                    *product.Product
+		wantErr                 error
+	}{
+		{
+			name:  "Should add units to stock",
+			ctx:   ctx,
+			delta: 5,
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(ctx, "1").Return(&product.Product{ID: "1", Seller: "1", Stock: 1}, nil)
+				m.EXPECT().UpdateStock(ctx, product.StockRequest{ID: "1", Stock: 5}).Return(
+					&product.Product{ID: "1", Seller: "1", Stock: 6}, nil,
+				)
+			},
+			want: &product.Product{ID: "1", Seller: "1", Stock: 6},
+		},
+		{
+			name:  "Should error when removing more units than available",
+			ctx:   ctx,
+			delta: -5,
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(ctx, "1").Return(&product.Product{ID: "1", Seller: "1", Stock: 1}, nil)
+				m.EXPECT().UpdateStock(ctx, product.StockRequest{ID: "1", Stock: -5}).Return(
+					nil, product.ErrInsufficientStock,
+				)
+			},
+			wantErr: product.ErrInsufficientStock,
+		},
+		{
+			name:    "Should add units to stock of variant",
+			ctx:     ctx,
+			variant: "S",
+			delta:   5,
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(ctx, "1").Return(&product.Product{ID: "1", Seller: "1", Variants: []product.Variant{{SKU: "S", Stock: 1}}}, nil)
+				m.EXPECT().UpdateStock(ctx, product.StockRequest{ID: "1", Variant: "S", Stock: 5}).Return(
+					&product.Product{ID: "1", Seller: "1", Variants: []product.Variant{{SKU: "S", Stock: 6}}}, nil,
+				)
+			},
+			want: &product.Product{ID: "1", Seller: "1", Variants: []product.Variant{{SKU: "S", Stock: 6}}},
+		},
+		{
+			name:  "Should error when variant is not set for product with variants",
+			ctx:   ctx,
+			delta: 5,
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(ctx, "1").Return(&product.Product{ID: "1", Seller: "1", Variants: []product.Variant{{SKU: "S", Stock: 1}}}, nil)
+			},
+			wantErr: product.ErrValidation{Resource: product.Resource, Field: "variant", Reason: "must be set for products with variants"},
+		},
+		{
+			name:    "Should error when variant is not found",
+			ctx:     ctx,
+			variant: "M",
+			delta:   5,
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(ctx, "1").Return(&product.Product{ID: "1", Seller: "1", Variants: []product.Variant{{SKU: "S", Stock: 1}}}, nil)
+			},
+			wantErr: product.ErrVariantNotFound,
+		},
+		{
+			name:    "Should error when delta is zero",
+			ctx:     ctx,
+			delta:   0,
+			wantErr: product.ErrValidation{Resource: product.Resource, Field: "delta", Reason: "must not be zero"},
+		},
+		{
+			name:  "Should error when user is not seller",
+			ctx:   ctx,
+			delta: 5,
+			setupMockProductStorage: func(m *mock.ProductStorage) {
+				m.EXPECT().FindOne(ctx, "1").Return(&product.Product{ID: "1", Seller: "2", Stock: 1}, nil)
+			},
+			wantErr: auth.ErrPermission{Reason: "only own products allowed to adjust stock"},
+		},
+		{
+			name:    "Should error when context without user",
+			ctx:     context.Background(),
+			delta:   5,
+			wantErr: auth.ErrNoUser,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			storage := mock.NewProductStorage(ctrl)
+			if tt.setupMockProductStorage != nil {
+				tt.setupMockProductStorage(storage)
+			}
+
+			s := &product.Service{
+				Storage: storage,
+			}
+			got, err := s.AdjustStock(tt.ctx, "1", tt.variant, tt.delta)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("AdjustStock() error = %v, want %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AdjustStock() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_Reservations(t *testing.T) {
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "2"})
+	deletedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		call     func(s *product.Service) (*product.Product, error)
+		stored   *product.Product
+		wantReq  *product.StockRequest
+		storeErr error
+		wantErr  error
+	}{
+		{
+			name: "Should reserve units",
+			call: func(s *product.Service) (*product.Product, error) {
+				return s.Reservations().Reserve(ctx, "1", "", 2)
+			},
+			stored:  &product.Product{ID: "1", Status: product.StatusPublished, Stock: 5},
+			wantReq: &product.StockRequest{ID: "1", Stock: -2, Reserved: 2},
+		},
+		{
+			name: "Should error when reserving more units than available",
+			call: func(s *product.Service) (*product.Product, error) {
+				return s.Reservations().Reserve(ctx, "1", "", 2)
+			},
+			stored:   &product.Product{ID: "1", Status: product.StatusPublished, Stock: 1},
+			wantReq:  &product.StockRequest{ID: "1", Stock: -2, Reserved: 2},
+			storeErr: product.ErrInsufficientStock,
+			wantErr:  product.ErrInsufficientStock,
+		},
+		{
+			name: "Should error when reserving unpublished product",
+			call: func(s *product.Service) (*product.Product, error) {
+				return s.Reservations().Reserve(ctx, "1", "", 2)
+			},
+			stored:  &product.Product{ID: "1", Status: product.StatusDraft, Stock: 5},
+			wantErr: product.ErrNotFound,
+		},
+		{
+			name: "Should error when reserving deleted product",
+			call: func(s *product.Service) (*product.Product, error) {
+				return s.Reservations().Reserve(ctx, "1", "", 2)
+			},
+			stored:  &product.Product{ID: "1", Status: product.StatusPublished, Stock: 5, DeletedAt: &deletedAt},
+			wantErr: product.ErrNotFound,
+		},
+		{
+			name: "Should reserve units of variant",
+			call: func(s *product.Service) (*product.Product, error) {
+				return s.Reservations().Reserve(ctx, "1", "SKU-1", 2)
+			},
+			stored: &product.Product{
+				ID: "1", Status: product.StatusPublished, Stock: 5,
+				Variants: []product.Variant{{SKU: "SKU-1", Stock: 5}},
+			},
+			wantReq: &product.StockRequest{ID: "1", Variant: "SKU-1", Stock: -2, Reserved: 2},
+		},
+		{
+			name: "Should error when reserving unknown variant",
+			call: func(s *product.Service) (*product.Product, error) {
+				return s.Reservations().Reserve(ctx, "1", "SKU-2", 2)
+			},
+			stored: &product.Product{
+				ID: "1", Status: product.StatusPublished, Stock: 5,
+				Variants: []product.Variant{{SKU: "SKU-1", Stock: 5}},
+			},
+			wantErr: product.ErrVariantNotFound,
+		},
+		{
+			name: "Should error when reserving product with variants without variant",
+			call: func(s *product.Service) (*product.Product, error) {
+				return s.Reservations().Reserve(ctx, "1", "", 2)
+			},
+			stored: &product.Product{
+				ID: "1", Status: product.StatusPublished, Stock: 5,
+				Variants: []product.Variant{{SKU: "SKU-1", Stock: 5}},
+			},
+			wantErr: product.ErrValidation{Resource: product.Resource, Field: "variant", Reason: "must be set for products with variants"},
+		},
+		{
+			name: "Should release reservation of variant",
+			call: func(s *product.Service) (*product.Product, error) {
+				return s.Reservations().ReleaseReservation(ctx, "1", "SKU-1", 2)
+			},
+			wantReq: &product.StockRequest{ID: "1", Variant: "SKU-1", Stock: 2, Reserved: -2},
+		},
+		{
+			name: "Should release reservation",
+			call: func(s *product.Service) (*product.Product, error) {
+				return s.Reservations().ReleaseReservation(ctx, "1", "", 2)
+			},
+			wantReq: &product.StockRequest{ID: "1", Stock: 2, Reserved: -2},
+		},
+		{
+			name: "Should commit reservation",
+			call: func(s *product.Service) (*product.Product, error) {
+				return s.Reservations().CommitReservation(ctx, "1", 2)
+			},
+			wantReq: &product.StockRequest{ID: "1", Reserved: -2},
+		},
+		{
+			name: "Should error when quantity is not positive",
+			call: func(s *product.Service) (*product.Product, error) {
+				return s.Reservations().Reserve(ctx, "1", "", 0)
+			},
+			wantErr: product.ErrValidation{Resource: product.Resource, Field: "quantity", Reason: "must be positive"},
+		},
+		{
+			name: "Should error when context without user",
+			call: func(s *product.Service) (*product.Product, error) {
+				return s.Reservations().Reserve(context.Background(), "1", "", 2)
+			},
+			wantErr: auth.ErrNoUser,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			want := &product.Product{ID: "1", Stock: 3, Reserved: 2}
+			storage := mock.NewProductStorage(ctrl)
+			if tt.stored != nil {
+				storage.EXPECT().FindOne(ctx, "1").Return(tt.stored, nil)
+			}
+			if tt.wantReq != nil {
+				var p *product.Product
+				if tt.storeErr == nil {
+					p = want
+				}
+				storage.EXPECT().UpdateStock(ctx, *tt.wantReq).Return(p, tt.storeErr)
+			}
+
+			got, err := tt.call(&product.Service{Storage: storage})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, want) {
+				t.Errorf("got = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestService_Audit(t *testing.T) {
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := mock.NewProductStorage(ctrl)
+	audits := mock.NewAuditStorage(ctrl)
+
+	var got []audit.Entry
+	audits.EXPECT().Record(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, e audit.Entry) error {
+		if e.CreatedAt.IsZero() {
+			t.Errorf("Record() got zero time")
+		}
+		e.CreatedAt = time.Time{}
+		got = append(got, e)
+		return nil
+	}).Times(4)
+
+	created := &product.Product{ID: "1", Name: "name", Price: money.New(100, "USD"), Seller: "1", Status: product.StatusPublished, Version: 1}
+	storage.EXPECT().Create(ctx, product.CreateRequest{Name: "name", Price: money.New(100, "USD"), Seller: "1"}).Return(created, nil)
+	updated := &product.Product{ID: "1", Name: "name", Price: money.New(150, "USD"), Seller: "1", Status: product.StatusPublished, Version: 2}
+	storage.EXPECT().FindOne(ctx, "1").Return(created, nil)
+	storage.EXPECT().Update(ctx, product.UpdateRequest{ID: "1", Price: testMoneyPtr(money.New(150, "USD"))}).Return(updated, nil)
+	storage.EXPECT().FindOne(ctx, "1").Return(updated, nil)
+	deleted := &product.Product{ID: "1", Name: "name", Price: money.New(150, "USD"), Seller: "1", Status: product.StatusPublished, Version: 2, DeletedAt: &testTime}
+	storage.EXPECT().Delete(ctx, "1", gomock.Any()).Return(deleted, nil)
+	storage.EXPECT().FindOne(ctx, "1").Return(deleted, nil)
+	storage.EXPECT().Restore(ctx, "1").Return(updated, nil)
+
+	s := &product.Service{Storage: storage, Audit: audits}
+	if _, err := s.Create(ctx, product.CreateRequest{Name: "name", Price: money.New(100, "USD")}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := s.Update(ctx, product.UpdateRequest{ID: "1", Price: testMoneyPtr(money.New(150, "USD"))}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := s.Delete(ctx, "1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Restore(ctx, "1"); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	want := []audit.Entry{
+		{
+			Product: "1", Seller: "1", Actor: "1", Operation: audit.OperationCreate, Version: 1,
+			Changes: []audit.Change{
+				{Field: "name", After: `"name"`},
+				{Field: "price", After: `{"amount":100,"currency":"USD"}`},
+				{Field: "seller", After: `"1"`},
+				{Field: "categories", After: "[]"},
+				{Field: "attributes", After: "[]"},
+				{Field: "variants", After: "[]"},
+				{Field: "stock", After: "0"},
+				{Field: "low_stock_threshold", After: "0"},
+				{Field: "status", After: `"published"`},
+				{Field: "publish_at", After: "null"},
+				{Field: "media", After: "[]"},
+			},
+		},
+		{
+			Product: "1", Seller: "1", Actor: "1", Operation: audit.OperationUpdate, Version: 2,
+			Changes: []audit.Change{{Field: "price", Before: `{"amount":100,"currency":"USD"}`, After: `{"amount":150,"currency":"USD"}`}},
+		},
+		{
+			Product: "1", Seller: "1", Actor: "1", Operation: audit.OperationDelete, Version: 2,
+			Changes: []audit.Change{
+				{Field: "name", Before: `"name"`},
+				{Field: "price", Before: `{"amount":150,"currency":"USD"}`},
+				{Field: "seller", Before: `"1"`},
+				{Field: "categories", Before: "[]"},
+				{Field: "attributes", Before: "[]"},
+				{Field: "variants", Before: "[]"},
+				{Field: "stock", Before: "0"},
+				{Field: "low_stock_threshold", Before: "0"},
+				{Field: "status", Before: `"published"`},
+				{Field: "publish_at", Before: "null"},
+				{Field: "media", Before: "[]"},
+			},
+		},
+		{
+			Product: "1", Seller: "1", Actor: "1", Operation: audit.OperationRestore, Version: 2,
+			Changes: []audit.Change{
+				{Field: "name", After: `"name"`},
+				{Field: "price", After: `{"amount":150,"currency":"USD"}`},
+				{Field: "seller", After: `"1"`},
+				{Field: "categories", After: "[]"},
+				{Field: "attributes", After: "[]"},
+				{Field: "variants", After: "[]"},
+				{Field: "stock", After: "0"},
+				{Field: "low_stock_threshold", After: "0"},
+				{Field: "status", After: `"published"`},
+				{Field: "publish_at", After: "null"},
+				{Field: "media", After: "[]"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Record() got = %v, want %v", got, want)
+	}
+}
+
+func TestService_History(t *testing.T) {
+	entries := []*audit.Entry{
+		{ID: "1", Product: "1", Seller: "1", Actor: "1", Operation: audit.OperationCreate},
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		want    []*audit.Entry
+		wantErr error
+	}{
+		{
+			name: "Should return history to seller",
+			ctx:  auth.NewContextWithUser(context.Background(), &user.User{ID: "1"}),
+			want: entries,
+		},
+		{
+			name: "Should return history to auditor",
+			ctx:  auth.NewContextWithUser(context.Background(), &user.User{ID: "support"}),
+			want: entries,
+		},
+		{
+			name:    "Should error when user is not seller",
+			ctx:     auth.NewContextWithUser(context.Background(), &user.User{ID: "2"}),
+			wantErr: auth.ErrPermission{Reason: "only own products allowed to get history"},
+		},
+		{
+			name:    "Should error when context without user",
+			ctx:     context.Background(),
+			wantErr: auth.ErrNoUser,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			audits := mock.NewAuditStorage(ctrl)
+			r := audit.FindRequest{Product: "1", Limit: 10}
+			audits.EXPECT().Find(tt.ctx, r).Return(entries, nil).MaxTimes(1)
+
+			s := &product.Service{Storage: mock.NewProductStorage(ctrl), AuditStorage: audits, Auditors: []string{"support"}}
+			got, err := s.History(tt.ctx, r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("History() error = %v, want %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("History() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_HistoryNotRecorded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The sink records the changes, but there is no storage to find them.
+	s := &product.Service{Storage: mock.NewProductStorage(ctrl), Audit: mock.NewAuditStorage(ctrl)}
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+	if _, err := s.History(ctx, audit.FindRequest{Product: "1", Limit: 10}); err == nil {
+		t.Errorf("History() error = nil, want an error")
+	}
+}
@@ -0,0 +1,124 @@
+package product
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortKey is a product property products can be sorted by.
+type SortKey string
+
+const (
+	// SortKeyPrice sorts by the amount of the price. Prices in different
+	// currencies are not comparable, so it is meant to be used with a price
+	// range.
+	SortKeyPrice   SortKey = "price"
+	SortKeyName    SortKey = "name"
+	SortKeyCreated SortKey = "created"
+	// SortKeyRating sorts by the average rating, products without reviews
+	// have the lowest one.
+	SortKeyRating SortKey = "rating"
+	// SortKeyRelevance sorts by relevance to the name filter, the most relevant
+	// products go first in ascending order. It is ignored by storages not
+	// supporting full-text search.
+	SortKeyRelevance SortKey = "relevance"
+)
+
+// Valid reports whether the key is one of the known sort keys.
+func (k SortKey) Valid() bool {
+	switch k {
+	case SortKeyPrice, SortKeyName, SortKeyCreated, SortKeyRating, SortKeyRelevance:
+		return true
+	default:
+		return false
+	}
+}
+
+// Sort is a sort key with the direction.
+type Sort struct {
+	Key  SortKey
+	Desc bool
+}
+
+// ParseSort parses a comma-separated list of sort keys. A key prefixed with
+// "-" means descending direction, e.g. "-price,name".
+func ParseSort(s string) ([]Sort, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	var sorts []Sort
+	for _, field := range strings.Split(s, ",") {
+		var srt Sort
+		if strings.HasPrefix(field, "-") {
+			srt.Desc = true
+			field = field[1:]
+		}
+		srt.Key = SortKey(field)
+
+		if !srt.Key.Valid() {
+			return nil, invalid("sort", fmt.Sprintf("unknown key %q", field))
+		}
+		sorts = append(sorts, srt)
+	}
+	return sorts, nil
+}
+
+// SortProducts sorts products by the sort keys for storages which cannot sort
+// natively. The created function must return a value increasing with creation
+// time, it is also used to break ties. Relevance is ignored.
+func SortProducts(ps []*Product, sorts []Sort, created func(p *Product) int64) {
+	sort.SliceStable(ps, func(i, j int) bool {
+		return CompareProducts(ps[i], ps[j], sorts, created) < 0
+	})
+}
+
+// CompareProducts compares products in the order given by the sort keys. It
+// returns -1 if a goes before b, 1 if a goes after b, and 0 if they are equal.
+// See SortProducts for the created function.
+func CompareProducts(a, b *Product, sorts []Sort, created func(p *Product) int64) int {
+	for _, s := range sorts {
+		var c int
+		switch s.Key {
+		case SortKeyPrice:
+			c = compareInt64(a.Price.Amount, b.Price.Amount)
+		case SortKeyName:
+			c = strings.Compare(a.Name, b.Name)
+		case SortKeyCreated:
+			c = compareInt64(created(a), created(b))
+		case SortKeyRating:
+			c = compareFloat64(a.Rating.Average, b.Rating.Average)
+		}
+		if s.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	return compareInt64(created(a), created(b))
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareFloat64(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
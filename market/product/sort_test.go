@@ -0,0 +1,52 @@
+package product_test
+
+import (
+	"github.com/ortymid/market/market/product"
+	"reflect"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []product.Sort
+		wantErr bool
+	}{
+		{
+			name: "Should parse empty string",
+			s:    "",
+			want: nil,
+		},
+		{
+			name: "Should parse keys with directions",
+			s:    "-price,name",
+			want: []product.Sort{
+				{Key: product.SortKeyPrice, Desc: true},
+				{Key: product.SortKeyName},
+			},
+		},
+		{
+			name: "Should parse rating",
+			s:    "-rating",
+			want: []product.Sort{{Key: product.SortKeyRating, Desc: true}},
+		},
+		{
+			name:    "Should error for unknown key",
+			s:       "price,seller",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := product.ParseSort(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSort() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSort() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
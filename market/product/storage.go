@@ -1,6 +1,9 @@
 package product
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 //go:generate mockgen -destination=../../mock/product_storage.go -package mock -mock_names=Storage=ProductStorage . Storage
 
@@ -9,21 +12,72 @@ type Storage interface {
 	Creater
 	Updater
 	Deleter
+	Stocker
+	Publisher
+	Rater
+	Summarizer
 }
 
 type Finder interface {
-	Find(ctx context.Context, r FindRequest) ([]*Product, error)
+	Find(ctx context.Context, r FindRequest) (*FindResult, error)
+	// FindOne returns deleted products too, it is up to the caller to hide
+	// them.
 	FindOne(ctx context.Context, id string) (*Product, error)
 }
 
 type Creater interface {
+	// Create stores a published product if the request has no status.
 	Create(ctx context.Context, r CreateRequest) (*Product, error)
 }
 
 type Updater interface {
+	// Update changes the provided fields and increments the version
+	// atomically, so concurrent updates are not lost. It returns ErrConflict
+	// if the request expects another version, the product is left unchanged
+	// then. Deleted products are not found. The publish time is cleared if
+	// the status changes to other than draft.
 	Update(ctx context.Context, r UpdateRequest) (*Product, error)
 }
 
+// Deleter deletes products softly: a deleted product is hidden from Find and
+// is kept until it is purged, so it can be restored.
 type Deleter interface {
-	Delete(ctx context.Context, id string) (*Product, error)
+	// Delete marks the product deleted at the given time. Deleted products
+	// are not found.
+	Delete(ctx context.Context, id string, at time.Time) (*Product, error)
+	// Restore brings a deleted product back. Products which are not deleted
+	// are not found.
+	Restore(ctx context.Context, id string) (*Product, error)
+	// Purge removes the products deleted before the given time permanently
+	// and returns the number of removed products.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Stocker interface {
+	// UpdateStock applies the stock changes atomically. It returns
+	// ErrInsufficientStock if the stock or the reserved units would become
+	// negative, the product is left unchanged then. Deleted products are not
+	// found.
+	UpdateStock(ctx context.Context, r StockRequest) (*Product, error)
+}
+
+type Publisher interface {
+	// PublishDue publishes the drafts scheduled to be published at or before
+	// the given time, clears their publish time and increments their version.
+	// It returns the published products. Deleted products are not published.
+	PublishDue(ctx context.Context, now time.Time) ([]*Product, error)
+}
+
+type Rater interface {
+	// UpdateRating sets the rating of the product. It does not change the
+	// version, and deleted products are updated too, so that their rating is
+	// right if they are restored.
+	UpdateRating(ctx context.Context, id string, rating Rating) (*Product, error)
+}
+
+type Summarizer interface {
+	// SummarizeSellers aggregates the published products of the sellers which
+	// are not deleted. Sellers without such products are missing from the
+	// result.
+	SummarizeSellers(ctx context.Context, sellers []string) (map[string]SellerSummary, error)
 }
@@ -0,0 +1,214 @@
+package product
+
+import (
+	"fmt"
+	"github.com/ortymid/market/market/money"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxAttributes is the number of attributes a product may define.
+const MaxAttributes = 10
+
+// MaxVariants is the number of variants a product may have.
+const MaxVariants = 100
+
+// AttributeType is the type of the values of an attribute.
+type AttributeType string
+
+const (
+	AttributeString AttributeType = "string"
+	// AttributeNumber values are decimal numbers written in the shortest
+	// form, e.g. "42" or "1.5".
+	AttributeNumber AttributeType = "number"
+	// AttributeBool values are either "true" or "false".
+	AttributeBool AttributeType = "bool"
+)
+
+// Valid reports whether the type is one of the known attribute types.
+func (t AttributeType) Valid() bool {
+	switch t {
+	case AttributeString, AttributeNumber, AttributeBool:
+		return true
+	default:
+		return false
+	}
+}
+
+// Attribute is a property the variants of a product differ in, e.g. size or
+// color.
+type Attribute struct {
+	Name string        `json:"name" bson:"name"`
+	Type AttributeType `json:"type" bson:"type"`
+}
+
+// Variant is a version of the product sold under its own SKU, e.g. a shirt of
+// a size and color.
+type Variant struct {
+	SKU string `json:"sku" bson:"sku"`
+	// Attributes are the values of every attribute of the product by name.
+	// Values of all types are kept as strings.
+	Attributes map[string]string `json:"attributes" bson:"attributes"`
+	// Price is in the currency of the product price.
+	Price money.Money `json:"price" bson:"price"`
+	// Stock is the number of units of the variant available to buy. Units
+	// reserved by orders of the variant are counted in the reserved units of
+	// the product.
+	Stock int64 `json:"stock" bson:"stock"`
+}
+
+// attributeName is the form of attribute names. They are used in filters and
+// storage keys, so they are kept simple.
+var attributeName = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// ValidAttributeName reports whether the attribute name has a valid form.
+func ValidAttributeName(name string) bool {
+	return attributeName.MatchString(name)
+}
+
+// validateAttributes checks that attributes have valid unique names and known
+// types.
+func validateAttributes(attrs []Attribute) error {
+	if len(attrs) > MaxAttributes {
+		return invalid("attributes", fmt.Sprintf("must not have more than %d items", MaxAttributes))
+	}
+	seen := make(map[string]bool, len(attrs))
+	for _, a := range attrs {
+		if !ValidAttributeName(a.Name) {
+			reason := fmt.Sprintf("name %q must be 1 to 32 lowercase letters, digits or underscores", a.Name)
+			return invalid("attributes", reason)
+		}
+		if seen[a.Name] {
+			return invalid("attributes", fmt.Sprintf("attribute %q is repeated", a.Name))
+		}
+		seen[a.Name] = true
+		if !a.Type.Valid() {
+			return invalid("attributes", fmt.Sprintf("unknown type %q of %q", a.Type, a.Name))
+		}
+	}
+	return nil
+}
+
+// validateVariants checks that variants have unique SKUs and unique values of
+// exactly the attributes, and that they are priced in the currency.
+func validateVariants(attrs []Attribute, variants []Variant, currency string) error {
+	if len(variants) > MaxVariants {
+		return invalid("variants", fmt.Sprintf("must not have more than %d items", MaxVariants))
+	}
+	skus := make(map[string]bool, len(variants))
+	combinations := make(map[string]bool, len(variants))
+	for _, v := range variants {
+		if len(v.SKU) == 0 {
+			return invalid("variants", "must not contain empty SKUs")
+		}
+		if skus[v.SKU] {
+			return invalid("variants", fmt.Sprintf("SKU %q is repeated", v.SKU))
+		}
+		skus[v.SKU] = true
+
+		if err := validateVariantAttributes(attrs, v); err != nil {
+			return err
+		}
+		key := variantKey(attrs, v)
+		if combinations[key] {
+			return invalid("variants", fmt.Sprintf("variant %q repeats the attributes of another one", v.SKU))
+		}
+		combinations[key] = true
+
+		if v.Price.Amount < 0 {
+			return invalid("variants", fmt.Sprintf("price of %q must not be negative", v.SKU))
+		}
+		if v.Price.Currency != currency {
+			return invalid("variants", fmt.Sprintf("price of %q must be in %s", v.SKU, currency))
+		}
+		if v.Stock < 0 {
+			return invalid("variants", fmt.Sprintf("stock of %q must not be negative", v.SKU))
+		}
+	}
+	return nil
+}
+
+// validateVariantAttributes checks that the variant has a value of the type
+// of every attribute and no other values.
+func validateVariantAttributes(attrs []Attribute, v Variant) error {
+	if len(v.Attributes) != len(attrs) {
+		return invalid("variants", fmt.Sprintf("%q must have a value of every attribute and no others", v.SKU))
+	}
+	for _, a := range attrs {
+		value, ok := v.Attributes[a.Name]
+		if !ok {
+			return invalid("variants", fmt.Sprintf("%q has no value of %q", v.SKU, a.Name))
+		}
+		if !validAttributeValue(a.Type, value) {
+			return invalid("variants", fmt.Sprintf("value %q of %q in %q is not a valid %s", value, a.Name, v.SKU, a.Type))
+		}
+	}
+	return nil
+}
+
+// validAttributeValue reports whether the value is of the type. Numbers and
+// bools must be in their canonical form, so that filters compare them as
+// strings.
+func validAttributeValue(t AttributeType, value string) bool {
+	switch t {
+	case AttributeNumber:
+		f, err := strconv.ParseFloat(value, 64)
+		return err == nil && strconv.FormatFloat(f, 'f', -1, 64) == value
+	case AttributeBool:
+		return value == "true" || value == "false"
+	default:
+		return len(value) > 0
+	}
+}
+
+// variantKey returns the values of the attributes of the variant joined in
+// the order of the attributes.
+func variantKey(attrs []Attribute, v Variant) string {
+	values := make([]string, 0, len(attrs))
+	for _, a := range attrs {
+		values = append(values, strconv.Quote(v.Attributes[a.Name]))
+	}
+	return strings.Join(values, ",")
+}
+
+// validateAttributeFilter checks the names of the attributes filtered by.
+func validateAttributeFilter(values map[string]string) error {
+	for name := range values {
+		if !ValidAttributeName(name) {
+			return invalid("attributes", fmt.Sprintf("invalid attribute name %q", name))
+		}
+	}
+	return nil
+}
+
+// Variant returns the variant of the product with the SKU.
+func (p *Product) Variant(sku string) (Variant, bool) {
+	for _, v := range p.Variants {
+		if v.SKU == sku {
+			return v, true
+		}
+	}
+	return Variant{}, false
+}
+
+// matchVariant reports whether any of the variants has all the attribute
+// values.
+func matchVariant(variants []Variant, values map[string]string) bool {
+	for _, v := range variants {
+		if v.Match(values) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether the variant has all the attribute values.
+func (v Variant) Match(values map[string]string) bool {
+	for name, value := range values {
+		if got, ok := v.Attributes[name]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
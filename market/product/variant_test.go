@@ -0,0 +1,197 @@
+package product_test
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/user"
+	"github.com/ortymid/market/mock"
+	"testing"
+)
+
+// testShirt returns a request for a shirt with variants of two sizes.
+func testShirt() product.CreateRequest {
+	return product.CreateRequest{
+		Name:  "shirt",
+		Price: money.New(1000, "USD"),
+		Attributes: []product.Attribute{
+			{Name: "color", Type: product.AttributeString},
+			{Name: "size", Type: product.AttributeNumber},
+		},
+		Variants: []product.Variant{
+			{SKU: "shirt-red-42", Attributes: map[string]string{"color": "red", "size": "42"}, Price: money.New(1000, "USD"), Stock: 3},
+			{SKU: "shirt-red-44", Attributes: map[string]string{"color": "red", "size": "44"}, Price: money.New(1200, "USD")},
+		},
+	}
+}
+
+func TestCreateRequest_ValidateVariants(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(r *product.CreateRequest)
+		wantErr bool
+	}{
+		{
+			name:   "Should accept valid variants",
+			change: func(r *product.CreateRequest) {},
+		},
+		{
+			name:    "Should error for invalid attribute name",
+			change:  func(r *product.CreateRequest) { r.Attributes[0].Name = "Color" },
+			wantErr: true,
+		},
+		{
+			name:    "Should error for repeated attribute",
+			change:  func(r *product.CreateRequest) { r.Attributes[1].Name = "color" },
+			wantErr: true,
+		},
+		{
+			name:    "Should error for unknown attribute type",
+			change:  func(r *product.CreateRequest) { r.Attributes[0].Type = "date" },
+			wantErr: true,
+		},
+		{
+			name:    "Should error for empty SKU",
+			change:  func(r *product.CreateRequest) { r.Variants[0].SKU = "" },
+			wantErr: true,
+		},
+		{
+			name:    "Should error for repeated SKU",
+			change:  func(r *product.CreateRequest) { r.Variants[1].SKU = "shirt-red-42" },
+			wantErr: true,
+		},
+		{
+			name:    "Should error for missing value",
+			change:  func(r *product.CreateRequest) { delete(r.Variants[0].Attributes, "size") },
+			wantErr: true,
+		},
+		{
+			name:    "Should error for undefined attribute",
+			change:  func(r *product.CreateRequest) { r.Variants[0].Attributes["fit"] = "slim" },
+			wantErr: true,
+		},
+		{
+			name:    "Should error for number not in canonical form",
+			change:  func(r *product.CreateRequest) { r.Variants[0].Attributes["size"] = "42.0" },
+			wantErr: true,
+		},
+		{
+			name:    "Should error for repeated attribute values",
+			change:  func(r *product.CreateRequest) { r.Variants[1].Attributes["size"] = "42" },
+			wantErr: true,
+		},
+		{
+			name:    "Should error for price in another currency",
+			change:  func(r *product.CreateRequest) { r.Variants[0].Price = money.New(1000, "EUR") },
+			wantErr: true,
+		},
+		{
+			name:    "Should error for negative stock",
+			change:  func(r *product.CreateRequest) { r.Variants[0].Stock = -1 },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testShirt()
+			tt.change(&r)
+			err := r.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var verr product.ErrValidation
+			if err != nil && !errors.As(err, &verr) {
+				t.Errorf("Validate() error = %v, want product.ErrValidation", err)
+			}
+		})
+	}
+}
+
+func TestFindRequest_MatchAttributes(t *testing.T) {
+	r := testShirt()
+	p := &product.Product{Name: r.Name, Price: r.Price, Attributes: r.Attributes, Variants: r.Variants}
+
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   bool
+	}{
+		{name: "Should match one attribute", values: map[string]string{"color": "red"}, want: true},
+		{name: "Should match attributes of one variant", values: map[string]string{"color": "red", "size": "44"}, want: true},
+		{name: "Should not match other value", values: map[string]string{"color": "blue"}},
+		{name: "Should not match undefined attribute", values: map[string]string{"fit": "slim"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fr := product.FindRequest{Attributes: tt.values}
+			if got := fr.Match(p); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProduct_StockOfVariants(t *testing.T) {
+	r := testShirt()
+	p := &product.Product{Name: r.Name, Price: r.Price, Attributes: r.Attributes, Variants: r.Variants, LowStockThreshold: 2}
+
+	if got := p.TotalStock(); got != 3 {
+		t.Errorf("TotalStock() = %d, want 3", got)
+	}
+	if p.LowStock() {
+		t.Errorf("LowStock() = true, want false")
+	}
+	inStock := true
+	if !(product.FindRequest{InStock: &inStock}).Match(p) {
+		t.Errorf("Match() in stock = false, want true")
+	}
+
+	p.Variants[0].Stock = 1
+	if !p.LowStock() {
+		t.Errorf("LowStock() = false, want true")
+	}
+}
+
+func TestService_UpdateVariants(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	r := testShirt()
+	p := &product.Product{ID: "1", Name: r.Name, Price: r.Price, Seller: "1", Attributes: r.Attributes, Variants: r.Variants}
+	storage := mock.NewProductStorage(ctrl)
+	storage.EXPECT().FindOne(gomock.Any(), "1").Return(p, nil).AnyTimes()
+
+	s := &product.Service{Storage: storage}
+	ctx := auth.NewContextWithUser(context.Background(), &user.User{ID: "1"})
+
+	// The variants are priced in the old currency.
+	price := money.New(900, "EUR")
+	_, err := s.Update(ctx, product.UpdateRequest{ID: "1", Price: &price})
+	var verr product.ErrValidation
+	if !errors.As(err, &verr) || verr.Field != "variants" {
+		t.Errorf("Update() error = %v, want variants validation error", err)
+	}
+
+	// The variants have no value of the new attribute.
+	attrs := append([]product.Attribute{{Name: "fit", Type: product.AttributeString}}, r.Attributes...)
+	_, err = s.Update(ctx, product.UpdateRequest{ID: "1", Attributes: &attrs})
+	if !errors.As(err, &verr) || verr.Field != "variants" {
+		t.Errorf("Update() error = %v, want variants validation error", err)
+	}
+
+	variants := []product.Variant{
+		{SKU: "shirt-slim-red-42", Attributes: map[string]string{"fit": "slim", "color": "red", "size": "42"}, Price: r.Price},
+	}
+	want := &product.Product{ID: "1", Name: r.Name, Price: r.Price, Seller: "1", Attributes: attrs, Variants: variants}
+	storage.EXPECT().Update(gomock.Any(), product.UpdateRequest{ID: "1", Attributes: &attrs, Variants: &variants}).Return(want, nil)
+	got, err := s.Update(ctx, product.UpdateRequest{ID: "1", Attributes: &attrs, Variants: &variants})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("Update() got = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,23 @@
+package review
+
+import (
+	"errors"
+	"github.com/ortymid/market/market/errs"
+)
+
+// Resource names the reviews in the shared errors.
+const Resource = "review"
+
+var ErrNotFound error = errs.NotFound{Resource: Resource}
+
+// ErrDuplicate is returned when the author already has a review of the
+// product.
+var ErrDuplicate = errors.New("product already reviewed")
+
+// ErrValidation is returned when a request contains invalid data.
+type ErrValidation = errs.Validation
+
+// invalid returns ErrValidation of the review field.
+func invalid(field, reason string) ErrValidation {
+	return ErrValidation{Resource: Resource, Field: field, Reason: reason}
+}
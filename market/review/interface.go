@@ -0,0 +1,16 @@
+package review
+
+import "context"
+
+//go:generate mockgen -destination=../../mock/review_service.go -package mock -mock_names=Interface=ReviewService . Interface
+
+// Interface addresses reviews by the product and the id, as reviews are
+// parts of products.
+type Interface interface {
+	Find(ctx context.Context, r FindRequest) ([]*Review, error)
+	FindOne(ctx context.Context, product string, id string) (*Review, error)
+	// Create reviews the product for the current user.
+	Create(ctx context.Context, r CreateRequest) (*Review, error)
+	Update(ctx context.Context, r UpdateRequest) (*Review, error)
+	Delete(ctx context.Context, product string, id string) (*Review, error)
+}
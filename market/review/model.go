@@ -0,0 +1,130 @@
+// Package review provides reviews of products. Every user may review a
+// product once with a rating and a text, and the average rating of the
+// reviews is kept on the product.
+package review
+
+import (
+	"github.com/ortymid/market/market/product"
+	"time"
+)
+
+// MaxText is the length in bytes the text of a review may have.
+const MaxText = 5000
+
+type Review struct {
+	ID      string `json:"id" bson:"_id,omitempty"`
+	Product string `json:"product"`
+	Author  string `json:"author"`
+	// Rating is from 1 to product.MaxRating.
+	Rating    int64     `json:"rating"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// Summary is the aggregate of the reviews of a product.
+type Summary struct {
+	Count int64 // number of reviews
+	Sum   int64 // sum of the ratings
+}
+
+// Rating returns the product rating of the summary.
+func (s Summary) Rating() product.Rating {
+	if s.Count == 0 {
+		return product.Rating{}
+	}
+	return product.Rating{Average: float64(s.Sum) / float64(s.Count), Count: s.Count}
+}
+
+type FindRequest struct {
+	Offset int64
+	Limit  int64
+	// Filters.
+	Product *string
+	Author  *string
+}
+
+type CreateRequest struct {
+	Product string `json:"-"` // Required, the product reviewed.
+	Rating  int64  `json:"rating"`
+	Text    string `json:"text"`
+}
+
+type UpdateRequest struct {
+	Product string  `json:"-" bson:"-"`                               // Required to find the review.
+	ID      string  `json:"-" bson:"-"`                               // Required to find the review.
+	Rating  *int64  `json:"rating,omitempty" bson:"rating,omitempty"` // Optional.
+	Text    *string `json:"text,omitempty" bson:"text,omitempty"`     // Optional.
+}
+
+// Validate checks that the request contains a valid review data.
+func (r CreateRequest) Validate() error {
+	if len(r.Product) == 0 {
+		return invalid("product", "must not be empty")
+	}
+	if err := validateRating(r.Rating); err != nil {
+		return err
+	}
+	return validateText(r.Text)
+}
+
+// Validate checks that the provided fields contain a valid review data.
+func (r UpdateRequest) Validate() error {
+	if r.Rating != nil {
+		if err := validateRating(*r.Rating); err != nil {
+			return err
+		}
+	}
+	if r.Text != nil {
+		return validateText(*r.Text)
+	}
+	return nil
+}
+
+func validateRating(rating int64) error {
+	if rating < 1 || rating > product.MaxRating {
+		return invalid("rating", "must be from 1 to 5")
+	}
+	return nil
+}
+
+func validateText(text string) error {
+	if len(text) > MaxText {
+		return invalid("text", "must not be longer than 5000 bytes")
+	}
+	return nil
+}
+
+// Match reports whether the review matches the request filters.
+func (r FindRequest) Match(rv *Review) bool {
+	if r.Product != nil && rv.Product != *r.Product {
+		return false
+	}
+	if r.Author != nil && rv.Author != *r.Author {
+		return false
+	}
+	return true
+}
+
+// Page returns the reviews of the page requested by offset and limit.
+func (r FindRequest) Page(rvs []*Review) []*Review {
+	if r.Offset >= int64(len(rvs)) {
+		return []*Review{}
+	}
+	rvs = rvs[r.Offset:]
+	if r.Limit < int64(len(rvs)) {
+		rvs = rvs[:r.Limit]
+	}
+	return rvs
+}
+
+// Validate checks that the request contains valid pagination.
+func (r FindRequest) Validate() error {
+	if r.Offset < 0 {
+		return invalid("offset", "must not be negative")
+	}
+	if r.Limit < 0 {
+		return invalid("limit", "must not be negative")
+	}
+	return nil
+}
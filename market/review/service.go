@@ -0,0 +1,241 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/clock"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/user"
+	"log"
+	"sync"
+	"time"
+)
+
+type Service struct {
+	Storage Storage
+	// Products are checked to be visible to the author before they are
+	// reviewed.
+	Products product.Interface
+	// Ratings keeps the rating of the reviewed products.
+	Ratings product.Rater
+	// Clock is used to set the creation and update times, the real clock is
+	// used if it is nil.
+	Clock clock.Clock
+
+	// ratings waits for the rating updates in the background.
+	ratings sync.WaitGroup
+}
+
+// now returns the current time as it is stored.
+func (s *Service) now() time.Time {
+	c := s.Clock
+	if c == nil {
+		c = clock.Real{}
+	}
+	return c.Now().UTC().Truncate(time.Millisecond)
+}
+
+// Find returns a page of reviews in the order of creation.
+func (s *Service) Find(ctx context.Context, r FindRequest) ([]*Review, error) {
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("list reviews: %w", err)
+	}
+
+	rvs, err := s.Storage.Find(ctx, r)
+	if err != nil {
+		return nil, fmt.Errorf("list reviews: %w", err)
+	}
+
+	return rvs, nil
+}
+
+// FindOne returns a review of the product for the given id. It returns
+// review.ErrNotFound error if the product has no review with such id.
+func (s *Service) FindOne(ctx context.Context, productID string, id string) (*Review, error) {
+	rv, err := s.findOne(ctx, productID, id)
+	if err != nil {
+		return nil, fmt.Errorf("get review: %w", err)
+	}
+
+	return rv, nil
+}
+
+// Create reviews the product for the current user and updates the rating of
+// the product in the background. Sellers cannot review their own products,
+// and every user may review a product once.
+func (s *Service) Create(ctx context.Context, r CreateRequest) (*Review, error) {
+	u, err := currentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create review: %w", err)
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("create review: %w", err)
+	}
+
+	p, err := s.Products.FindOne(ctx, r.Product)
+	if err != nil {
+		return nil, fmt.Errorf("create review: %w", err)
+	}
+
+	if p.Seller == u.ID {
+		err := auth.ErrPermission{Reason: "own products are not allowed to review"}
+		return nil, fmt.Errorf("create review: %w", err)
+	}
+
+	now := s.now()
+	rv, err := s.Storage.Create(ctx, Review{
+		Product:   p.ID,
+		Author:    u.ID,
+		Rating:    r.Rating,
+		Text:      r.Text,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create review: %w", err)
+	}
+
+	s.updateRating(rv.Product)
+	return rv, nil
+}
+
+// Update updates a review of the product for the given id and returns it.
+// Only the author may update the review. It returns review.ErrNotFound error
+// if the product has no review with such id.
+func (s *Service) Update(ctx context.Context, r UpdateRequest) (*Review, error) {
+	if _, err := s.authorReview(ctx, r.Product, r.ID); err != nil {
+		return nil, fmt.Errorf("update review: %w", err)
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("update review: %w", err)
+	}
+
+	rv, err := s.Storage.Update(ctx, r, s.now())
+	if err != nil {
+		return nil, fmt.Errorf("update review: %w", err)
+	}
+
+	if r.Rating != nil {
+		s.updateRating(rv.Product)
+	}
+	return rv, nil
+}
+
+// Delete deletes a review of the product for the given id and updates the
+// rating of the product in the background. Only the author may delete the
+// review. It returns review.ErrNotFound error if the product has no review
+// with such id.
+func (s *Service) Delete(ctx context.Context, productID string, id string) (*Review, error) {
+	if _, err := s.authorReview(ctx, productID, id); err != nil {
+		return nil, fmt.Errorf("delete review: %w", err)
+	}
+
+	rv, err := s.Storage.Delete(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("delete review: %w", err)
+	}
+
+	s.updateRating(rv.Product)
+	return rv, nil
+}
+
+// findOne returns the review hiding reviews of other products.
+func (s *Service) findOne(ctx context.Context, productID string, id string) (*Review, error) {
+	rv, err := s.Storage.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rv.Product != productID {
+		return nil, ErrNotFound
+	}
+
+	return rv, nil
+}
+
+// authorReview returns the review if the current user is its author.
+func (s *Service) authorReview(ctx context.Context, productID string, id string) (*Review, error) {
+	u, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rv, err := s.findOne(ctx, productID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if rv.Author != u.ID {
+		return nil, auth.ErrPermission{Reason: "only own reviews allowed to change"}
+	}
+	return rv, nil
+}
+
+// Wait waits for the rating updates started by the changes of the reviews.
+func (s *Service) Wait() {
+	s.ratings.Wait()
+}
+
+// updateRating sets the rating of the product from its reviews in the
+// background, so the change of the review is kept and reported even if the
+// update fails. Failures are logged, and the rating is fixed by the next
+// change of the reviews of the product.
+func (s *Service) updateRating(productID string) {
+	s.ratings.Add(1)
+	go func() {
+		defer s.ratings.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), ratingTimeout)
+		defer cancel()
+		if err := s.rate(ctx, productID); err != nil {
+			log.Printf("updating rating of product %s: %v", productID, err)
+		}
+	}()
+}
+
+// rate writes the rating of the summary of the reviews and summarizes them
+// again, until the written rating is the one of the reviews. A rating
+// summarized before a change of the reviews, here or by another server, may
+// overwrite a later one, but then the writer sees the change and writes again.
+func (s *Service) rate(ctx context.Context, productID string) error {
+	sum, err := s.Storage.Summarize(ctx, productID)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < ratingAttempts; i++ {
+		if _, err := s.Ratings.UpdateRating(ctx, productID, sum.Rating()); err != nil {
+			return err
+		}
+
+		written := sum
+		sum, err = s.Storage.Summarize(ctx, productID)
+		if err != nil {
+			return err
+		}
+		if sum == written {
+			return nil
+		}
+	}
+	return fmt.Errorf("reviews changed %d times while rating", ratingAttempts)
+}
+
+func currentUser(ctx context.Context) (*user.User, error) {
+	u, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, auth.ErrNoUser
+	}
+	return u, nil
+}
+
+const (
+	// ratingTimeout bounds the update of the rating of a product.
+	ratingTimeout = 10 * time.Second
+	// ratingAttempts bounds the writes of the rating of a product, which
+	// are repeated while its reviews are changed.
+	ratingAttempts = 5
+)
@@ -0,0 +1,303 @@
+package review_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/clock/clocktest"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/review"
+	"github.com/ortymid/market/market/user"
+	"github.com/ortymid/market/storage/memory"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testEnv is a review service with in-memory dependencies.
+type testEnv struct {
+	service  *review.Service
+	products *memory.ProductStorage
+	reviews  *memory.ReviewStorage
+	clock    *clocktest.Clock
+
+	productID string
+}
+
+// newTestEnv returns an environment with a product of the "seller".
+func newTestEnv(t *testing.T) *testEnv {
+	env := &testEnv{
+		products: memory.NewProductStorage(),
+		reviews:  memory.NewReviewStorage(),
+		clock:    clocktest.NewClock(time.Date(2020, 10, 1, 12, 30, 0, 0, time.UTC)),
+	}
+	env.service = &review.Service{
+		Storage:  env.reviews,
+		Products: &product.Service{Storage: env.products},
+		Ratings:  env.products,
+		Clock:    env.clock,
+	}
+
+	p, err := env.products.Create(context.Background(), product.CreateRequest{
+		Name: "Banana", Price: money.New(100, "USD"), Seller: "seller",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.productID = p.ID
+
+	return env
+}
+
+// checkRating checks the rating of the product once it is updated.
+func (env *testEnv) checkRating(t *testing.T, want product.Rating) {
+	t.Helper()
+
+	env.service.Wait()
+	p, err := env.products.FindOne(context.Background(), env.productID)
+	if err != nil {
+		t.Fatalf("FindOne() product error = %v", err)
+	}
+	if p.Rating != want {
+		t.Errorf("rating = %+v, want %+v", p.Rating, want)
+	}
+}
+
+func userContext(id string) context.Context {
+	return auth.NewContextWithUser(context.Background(), &user.User{ID: id})
+}
+
+func TestService_Create(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		r       func(productID string) review.CreateRequest
+		wantErr error
+	}{
+		{
+			name: "Should create review",
+			ctx:  userContext("buyer"),
+			r: func(productID string) review.CreateRequest {
+				return review.CreateRequest{Product: productID, Rating: 4, Text: "Tasty"}
+			},
+		},
+		{
+			name: "Should not allow to review own product",
+			ctx:  userContext("seller"),
+			r: func(productID string) review.CreateRequest {
+				return review.CreateRequest{Product: productID, Rating: 4}
+			},
+			wantErr: auth.ErrPermission{Reason: "own products are not allowed to review"},
+		},
+		{
+			name: "Should error for unknown product",
+			ctx:  userContext("buyer"),
+			r: func(productID string) review.CreateRequest {
+				return review.CreateRequest{Product: "unknown", Rating: 4}
+			},
+			wantErr: product.ErrNotFound,
+		},
+		{
+			name: "Should error for invalid rating",
+			ctx:  userContext("buyer"),
+			r: func(productID string) review.CreateRequest {
+				return review.CreateRequest{Product: productID, Rating: 6}
+			},
+			wantErr: review.ErrValidation{Resource: review.Resource, Field: "rating", Reason: "must be from 1 to 5"},
+		},
+		{
+			name: "Should require user",
+			ctx:  context.Background(),
+			r: func(productID string) review.CreateRequest {
+				return review.CreateRequest{Product: productID, Rating: 4}
+			},
+			wantErr: auth.ErrNoUser,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+
+			got, err := env.service.Create(tt.ctx, tt.r(env.productID))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				env.checkRating(t, product.Rating{})
+				return
+			}
+
+			want := &review.Review{
+				ID:        got.ID,
+				Product:   env.productID,
+				Author:    "buyer",
+				Rating:    4,
+				Text:      "Tasty",
+				CreatedAt: env.clock.Now(),
+				UpdatedAt: env.clock.Now(),
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Create() got = %v, want %v", got, want)
+			}
+			env.checkRating(t, product.Rating{Average: 4, Count: 1})
+		})
+	}
+}
+
+func TestService_CreateDuplicate(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := userContext("buyer")
+
+	r := review.CreateRequest{Product: env.productID, Rating: 4}
+	if _, err := env.service.Create(ctx, r); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	_, err := env.service.Create(ctx, r)
+	if !errors.Is(err, review.ErrDuplicate) {
+		t.Errorf("Create() error = %v, want %v", err, review.ErrDuplicate)
+	}
+	env.checkRating(t, product.Rating{Average: 4, Count: 1})
+}
+
+// staleReviews returns the summaries of fewer reviews later, so that
+// concurrent rating updates, as of several servers, finish in the reverse
+// order.
+type staleReviews struct {
+	review.Storage
+}
+
+func (s staleReviews) Summarize(ctx context.Context, product string) (review.Summary, error) {
+	sum, err := s.Storage.Summarize(ctx, product)
+	time.Sleep(time.Duration(10-sum.Count) * time.Millisecond)
+	return sum, err
+}
+
+func TestService_CreateConcurrent(t *testing.T) {
+	env := newTestEnv(t)
+	env.service.Storage = staleReviews{env.reviews}
+
+	const n = 5
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			_, err := env.service.Create(userContext(fmt.Sprint("buyer", i)), review.CreateRequest{Product: env.productID, Rating: 4})
+			errs <- err
+		}(i)
+	}
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Create() error = %v", err)
+		}
+	}
+
+	env.checkRating(t, product.Rating{Average: 4, Count: n})
+}
+
+// staleOnce returns the summary of no reviews the first time, as if it was
+// summarized by another server before the review was created.
+type staleOnce struct {
+	review.Storage
+	stale bool
+}
+
+func (s *staleOnce) Summarize(ctx context.Context, product string) (review.Summary, error) {
+	if !s.stale {
+		s.stale = true
+		return review.Summary{}, nil
+	}
+	return s.Storage.Summarize(ctx, product)
+}
+
+func TestService_CreateStaleSummary(t *testing.T) {
+	env := newTestEnv(t)
+	env.service.Storage = &staleOnce{Storage: env.reviews}
+
+	if _, err := env.service.Create(userContext("buyer"), review.CreateRequest{Product: env.productID, Rating: 4}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	env.checkRating(t, product.Rating{Average: 4, Count: 1})
+}
+
+// failingRater fails to update the ratings.
+type failingRater struct{}
+
+var errRater = errors.New("rater failed")
+
+func (failingRater) UpdateRating(context.Context, string, product.Rating) (*product.Product, error) {
+	return nil, errRater
+}
+
+func TestService_CreateRatingError(t *testing.T) {
+	env := newTestEnv(t)
+	env.service.Ratings = failingRater{}
+
+	// The review is created, the rating is fixed by the next change.
+	if _, err := env.service.Create(userContext("buyer"), review.CreateRequest{Product: env.productID, Rating: 4}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	env.checkRating(t, product.Rating{})
+
+	rvs, err := env.reviews.Find(context.Background(), review.FindRequest{Limit: 10, Product: &env.productID})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(rvs) != 1 {
+		t.Errorf("Find() got %d reviews, want the created one", len(rvs))
+	}
+}
+
+func TestService_UpdateDelete(t *testing.T) {
+	env := newTestEnv(t)
+
+	first, err := env.service.Create(userContext("first"), review.CreateRequest{Product: env.productID, Rating: 5})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := env.service.Create(userContext("second"), review.CreateRequest{Product: env.productID, Rating: 2}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	env.checkRating(t, product.Rating{Average: 3.5, Count: 2})
+
+	rating := int64(3)
+	r := review.UpdateRequest{Product: env.productID, ID: first.ID, Rating: &rating}
+
+	_, err = env.service.Update(userContext("second"), r)
+	var perr auth.ErrPermission
+	if !errors.As(err, &perr) {
+		t.Errorf("Update() by another user error = %v, want auth.ErrPermission", err)
+	}
+
+	_, err = env.service.Update(userContext("first"), review.UpdateRequest{Product: "other", ID: first.ID, Rating: &rating})
+	if !errors.Is(err, review.ErrNotFound) {
+		t.Errorf("Update() of another product error = %v, want %v", err, review.ErrNotFound)
+	}
+
+	env.clock.Advance(time.Hour)
+	got, err := env.service.Update(userContext("first"), r)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got.Rating != 3 || !got.UpdatedAt.Equal(env.clock.Now()) {
+		t.Errorf("Update() got = %v, want rating 3 updated at %v", got, env.clock.Now())
+	}
+	env.checkRating(t, product.Rating{Average: 2.5, Count: 2})
+
+	_, err = env.service.Delete(userContext("second"), env.productID, first.ID)
+	if !errors.As(err, &perr) {
+		t.Errorf("Delete() by another user error = %v, want auth.ErrPermission", err)
+	}
+
+	if _, err := env.service.Delete(userContext("first"), env.productID, first.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	env.checkRating(t, product.Rating{Average: 2, Count: 1})
+
+	if _, err := env.service.FindOne(context.Background(), env.productID, first.ID); !errors.Is(err, review.ErrNotFound) {
+		t.Errorf("FindOne() after Delete() error = %v, want %v", err, review.ErrNotFound)
+	}
+}
@@ -0,0 +1,24 @@
+package review
+
+import (
+	"context"
+	"time"
+)
+
+//go:generate mockgen -destination=../../mock/review_storage.go -package mock -mock_names=Storage=ReviewStorage . Storage
+
+type Storage interface {
+	// Find returns reviews matching the not-nil filters in the order of
+	// creation.
+	Find(ctx context.Context, r FindRequest) ([]*Review, error)
+	FindOne(ctx context.Context, id string) (*Review, error)
+	// Create stores the review and returns it with an assigned id. It returns
+	// ErrDuplicate if the author has a review of the same product.
+	Create(ctx context.Context, rv Review) (*Review, error)
+	// Update changes the provided fields and sets the update time.
+	Update(ctx context.Context, r UpdateRequest, at time.Time) (*Review, error)
+	Delete(ctx context.Context, id string) (*Review, error)
+	// Summarize returns the number and the sum of the ratings of the reviews
+	// of the product.
+	Summarize(ctx context.Context, product string) (Summary, error)
+}
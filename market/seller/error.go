@@ -0,0 +1,14 @@
+package seller
+
+import "github.com/ortymid/market/market/errs"
+
+// Resource names the sellers in the shared errors.
+const Resource = "seller"
+
+// ErrValidation is returned when a request contains invalid data.
+type ErrValidation = errs.Validation
+
+// invalid returns ErrValidation of the seller field.
+func invalid(field, reason string) ErrValidation {
+	return ErrValidation{Resource: Resource, Field: field, Reason: reason}
+}
@@ -0,0 +1,11 @@
+package seller
+
+import "context"
+
+//go:generate mockgen -destination=../../mock/seller_service.go -package mock -mock_names=Interface=SellerService . Interface
+
+type Interface interface {
+	// Find returns the sellers with the ids in the same order. Every id
+	// is a seller, the ones without products have zero summaries.
+	Find(ctx context.Context, ids []string) ([]*Seller, error)
+}
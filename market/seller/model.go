@@ -0,0 +1,18 @@
+// Package seller provides the public profiles of the users selling products:
+// their names kept by the user service and the summary of their products.
+package seller
+
+import "github.com/ortymid/market/market/product"
+
+// MaxFind is the number of sellers Find returns at most.
+const MaxFind = 100
+
+type Seller struct {
+	ID string `json:"id"`
+	// Name is empty if the user is not known to the user service.
+	Name string `json:"name"`
+	// Products is the number of published products of the seller.
+	Products int64 `json:"products"`
+	// Rating is the average rating of all the reviews of the products.
+	Rating product.Rating `json:"rating"`
+}
@@ -0,0 +1,83 @@
+package seller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/user"
+	"sync"
+)
+
+type Service struct {
+	// Users gives the names of the sellers.
+	Users user.Service
+	// Products summarizes the products of the sellers.
+	Products product.Summarizer
+}
+
+// Find gets the products of all the sellers at once and the users
+// concurrently, as the user service has no batch requests.
+func (s *Service) Find(ctx context.Context, ids []string) ([]*Seller, error) {
+	if len(ids) > MaxFind {
+		return nil, invalid("ids", fmt.Sprintf("must not be more than %d", MaxFind))
+	}
+	if len(ids) == 0 {
+		return []*Seller{}, nil
+	}
+
+	summaries, err := s.Products.SummarizeSellers(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("summarizing products: %w", err)
+	}
+
+	names, err := s.names(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	sellers := make([]*Seller, len(ids))
+	for i, id := range ids {
+		sum := summaries[id]
+		sellers[i] = &Seller{ID: id, Name: names[id], Products: sum.Products, Rating: sum.Rating}
+	}
+	return sellers, nil
+}
+
+// names returns the names of the users by id. Unknown users have no names.
+func (s *Service) names(ctx context.Context, ids []string) (map[string]string, error) {
+	unique := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		unique[id] = true
+	}
+
+	var mu sync.Mutex
+	var firstErr error
+	names := make(map[string]string, len(unique))
+
+	var wg sync.WaitGroup
+	for id := range unique {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+
+			u, err := s.Users.Get(ctx, id)
+			if errors.Is(err, user.ErrNotFound) {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("getting user %s: %w", id, err)
+				}
+				return
+			}
+			names[id] = u.Name
+		}(id)
+	}
+	wg.Wait()
+
+	return names, firstErr
+}
@@ -0,0 +1,108 @@
+package seller_test
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/market/seller"
+	"github.com/ortymid/market/market/user"
+	"github.com/ortymid/market/mock"
+	"github.com/ortymid/market/storage/memory"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestService_Find(t *testing.T) {
+	ctx := context.Background()
+
+	products := memory.NewProductStorage()
+	for _, r := range []product.CreateRequest{
+		{Name: "Banana", Price: money.New(100, "USD"), Seller: "1"},
+		{Name: "Carrot", Price: money.New(100, "USD"), Seller: "1"},
+		{Name: "Date", Price: money.New(100, "USD"), Seller: "2"},
+	} {
+		p, err := products.Create(ctx, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := products.UpdateRating(ctx, p.ID, product.Rating{Average: 4, Count: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &seller.Service{
+		Users: memory.NewUserService(
+			user.User{ID: "1", Name: "Alice"},
+			user.User{ID: "3", Name: "Carol"},
+		),
+		Products: products,
+	}
+
+	got, err := s.Find(ctx, []string{"3", "1", "2", "1"})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	want := []*seller.Seller{
+		{ID: "3", Name: "Carol"},
+		{ID: "1", Name: "Alice", Products: 2, Rating: product.Rating{Average: 4, Count: 2}},
+		{ID: "2", Products: 1, Rating: product.Rating{Average: 4, Count: 1}},
+		{ID: "1", Name: "Alice", Products: 2, Rating: product.Rating{Average: 4, Count: 2}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() got = %v, want %v", got, want)
+	}
+}
+
+func TestService_FindErrors(t *testing.T) {
+	errTest := errors.New("test error")
+
+	tests := []struct {
+		name       string
+		ids        []string
+		setupMocks func(us *mock.UserService, ps *mock.ProductStorage)
+		wantErr    error
+	}{
+		{
+			name:       "Should reject too many ids",
+			ids:        strings.Split(strings.Repeat("1,", seller.MaxFind), ","),
+			setupMocks: func(us *mock.UserService, ps *mock.ProductStorage) {},
+			wantErr:    seller.ErrValidation{Resource: seller.Resource, Field: "ids", Reason: "must not be more than 100"},
+		},
+		{
+			name: "Should return error of user service",
+			ids:  []string{"1"},
+			setupMocks: func(us *mock.UserService, ps *mock.ProductStorage) {
+				ps.EXPECT().SummarizeSellers(gomock.Any(), []string{"1"}).Return(nil, nil)
+				us.EXPECT().Get(gomock.Any(), "1").Return(user.User{}, errTest)
+			},
+			wantErr: errTest,
+		},
+		{
+			name: "Should return error of product storage",
+			ids:  []string{"1"},
+			setupMocks: func(us *mock.UserService, ps *mock.ProductStorage) {
+				ps.EXPECT().SummarizeSellers(gomock.Any(), []string{"1"}).Return(nil, errTest)
+			},
+			wantErr: errTest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			us := mock.NewUserService(ctrl)
+			ps := mock.NewProductStorage(ctrl)
+			tt.setupMocks(us, ps)
+
+			s := &seller.Service{Users: us, Products: ps}
+			_, err := s.Find(context.Background(), tt.ids)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Find() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
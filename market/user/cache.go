@@ -0,0 +1,80 @@
+package user
+
+import (
+	"context"
+	"github.com/ortymid/market/market/clock"
+	"sync"
+	"time"
+)
+
+// CachedService keeps the users of the service for the TTL. Errors are not
+// cached, and updated users replace the cached ones. Users changed elsewhere
+// may be stale until they expire, so balances are meant to be changed
+// through the service itself. It is safe for concurrent use.
+type CachedService struct {
+	Service Service
+	TTL     time.Duration
+	// Clock is used to expire the users, the real clock is used if it is
+	// nil.
+	Clock clock.Clock
+
+	mu    sync.Mutex
+	users map[string]cachedUser
+}
+
+type cachedUser struct {
+	user      User
+	expiresAt time.Time
+}
+
+func NewCachedService(s Service, ttl time.Duration) *CachedService {
+	return &CachedService{Service: s, TTL: ttl}
+}
+
+func (s *CachedService) Get(ctx context.Context, id string) (User, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	c, ok := s.users[id]
+	s.mu.Unlock()
+	if ok && now.Before(c.expiresAt) {
+		return c.user, nil
+	}
+
+	u, err := s.Service.Get(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+
+	s.put(u, now)
+	return u, nil
+}
+
+func (s *CachedService) Update(ctx context.Context, r UpdateRequest) (User, error) {
+	u, err := s.Service.Update(ctx, r)
+	if err != nil {
+		s.mu.Lock()
+		delete(s.users, r.ID)
+		s.mu.Unlock()
+		return User{}, err
+	}
+
+	s.put(u, s.now())
+	return u, nil
+}
+
+func (s *CachedService) put(u User, now time.Time) {
+	s.mu.Lock()
+	if s.users == nil {
+		s.users = make(map[string]cachedUser)
+	}
+	s.users[u.ID] = cachedUser{user: u, expiresAt: now.Add(s.TTL)}
+	s.mu.Unlock()
+}
+
+func (s *CachedService) now() time.Time {
+	if s.Clock == nil {
+		return time.Now()
+	}
+	return s.Clock.Now()
+}
@@ -0,0 +1,68 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"github.com/ortymid/market/market/clock/clocktest"
+	"github.com/ortymid/market/market/user"
+	"github.com/ortymid/market/storage/memory"
+	"testing"
+	"time"
+)
+
+type countingService struct {
+	user.Service
+	calls int
+}
+
+func (s *countingService) Get(ctx context.Context, id string) (user.User, error) {
+	s.calls++
+	return s.Service.Get(ctx, id)
+}
+
+func TestCachedService(t *testing.T) {
+	users := memory.NewUserService(user.User{ID: "1", Name: "Alice", Balance: 100})
+	service := &countingService{Service: users}
+	clock := clocktest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := &user.CachedService{Service: service, TTL: time.Minute, Clock: clock}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if got, err := s.Get(ctx, "1"); err != nil || got.Balance != 100 {
+			t.Fatalf("Get() = %v, %v, want balance 100", got, err)
+		}
+	}
+	if service.calls != 1 {
+		t.Errorf("Get() got service calls = %d, want 1", service.calls)
+	}
+
+	balance := int64(50)
+	if _, err := s.Update(ctx, user.UpdateRequest{ID: "1", Balance: &balance}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got, _ := s.Get(ctx, "1"); got.Balance != 50 || service.calls != 1 {
+		t.Errorf("Get() after Update() = %v with %d service calls, want balance 50 cached", got, service.calls)
+	}
+
+	balance = 10
+	if _, err := users.Update(ctx, user.UpdateRequest{ID: "1", Balance: &balance}); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(30 * time.Second)
+	if got, _ := s.Get(ctx, "1"); got.Balance != 50 {
+		t.Errorf("Get() before expiration = %v, want balance 50", got)
+	}
+	clock.Advance(30 * time.Second)
+	if got, _ := s.Get(ctx, "1"); got.Balance != 10 {
+		t.Errorf("Get() after expiration = %v, want balance 10", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.Get(ctx, "2"); !errors.Is(err, user.ErrNotFound) {
+			t.Fatalf("Get() error = %v, want %v", err, user.ErrNotFound)
+		}
+	}
+	if service.calls != 4 {
+		t.Errorf("Get() got service calls = %d, want 4 as errors are not cached", service.calls)
+	}
+}
@@ -0,0 +1,8 @@
+package user
+
+import "github.com/ortymid/market/market/errs"
+
+// Resource names the users in the shared errors.
+const Resource = "user"
+
+var ErrNotFound error = errs.NotFound{Resource: Resource}
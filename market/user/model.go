@@ -7,5 +7,6 @@ type User struct {
 }
 
 type UpdateRequest struct {
-	Balance *int64
+	ID      string // Required to find the user.
+	Balance *int64 // Optional.
 }
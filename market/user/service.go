@@ -2,7 +2,15 @@ package user
 
 import "context"
 
+//go:generate mockgen -destination=../../mock/user_service.go -package mock -mock_names=Service=UserService . Service
+
+// Service gives access to the users of the market. Balances are updated as
+// a whole, so concurrent updates of the same user may overwrite each other.
 type Service interface {
+	// Get returns the user with the id. It returns ErrNotFound if there is
+	// no such user.
 	Get(ctx context.Context, id string) (User, error)
+	// Update updates not-nil fields of the user. It returns ErrNotFound if
+	// there is no such user.
 	Update(ctx context.Context, r UpdateRequest) (User, error)
 }
@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ortymid/market/market/audit (interfaces: Storage)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	audit "github.com/ortymid/market/market/audit"
+	reflect "reflect"
+)
+
+// AuditStorage is a mock of Storage interface
+type AuditStorage struct {
+	ctrl     *gomock.Controller
+	recorder *AuditStorageMockRecorder
+}
+
+// AuditStorageMockRecorder is the mock recorder for AuditStorage
+type AuditStorageMockRecorder struct {
+	mock *AuditStorage
+}
+
+// NewAuditStorage creates a new mock instance
+func NewAuditStorage(ctrl *gomock.Controller) *AuditStorage {
+	mock := &AuditStorage{ctrl: ctrl}
+	mock.recorder = &AuditStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *AuditStorage) EXPECT() *AuditStorageMockRecorder {
+	return m.recorder
+}
+
+// Find mocks base method
+func (m *AuditStorage) Find(arg0 context.Context, arg1 audit.FindRequest) ([]*audit.Entry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", arg0, arg1)
+	ret0, _ := ret[0].([]*audit.Entry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find
+func (mr *AuditStorageMockRecorder) Find(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*AuditStorage)(nil).Find), arg0, arg1)
+}
+
+// Record mocks base method
+func (m *AuditStorage) Record(arg0 context.Context, arg1 audit.Entry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Record", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Record indicates an expected call of Record
+func (mr *AuditStorageMockRecorder) Record(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*AuditStorage)(nil).Record), arg0, arg1)
+}
@@ -0,0 +1,78 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ortymid/market/market/media (interfaces: BlobStore)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	io "io"
+	reflect "reflect"
+)
+
+// BlobStore is a mock of BlobStore interface
+type BlobStore struct {
+	ctrl     *gomock.Controller
+	recorder *BlobStoreMockRecorder
+}
+
+// BlobStoreMockRecorder is the mock recorder for BlobStore
+type BlobStoreMockRecorder struct {
+	mock *BlobStore
+}
+
+// NewBlobStore creates a new mock instance
+func NewBlobStore(ctrl *gomock.Controller) *BlobStore {
+	mock := &BlobStore{ctrl: ctrl}
+	mock.recorder = &BlobStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *BlobStore) EXPECT() *BlobStoreMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method
+func (m *BlobStore) Delete(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete
+func (mr *BlobStoreMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*BlobStore)(nil).Delete), arg0, arg1)
+}
+
+// Get mocks base method
+func (m *BlobStore) Get(arg0 context.Context, arg1 string) (io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get
+func (mr *BlobStoreMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*BlobStore)(nil).Get), arg0, arg1)
+}
+
+// Put mocks base method
+func (m *BlobStore) Put(arg0 context.Context, arg1 string, arg2 io.Reader, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Put", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Put indicates an expected call of Put
+func (mr *BlobStoreMockRecorder) Put(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*BlobStore)(nil).Put), arg0, arg1, arg2, arg3)
+}
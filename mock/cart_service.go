@@ -0,0 +1,95 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ortymid/market/market/cart (interfaces: Interface)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	cart "github.com/ortymid/market/market/cart"
+	reflect "reflect"
+)
+
+// CartService is a mock of Interface interface
+type CartService struct {
+	ctrl     *gomock.Controller
+	recorder *CartServiceMockRecorder
+}
+
+// CartServiceMockRecorder is the mock recorder for CartService
+type CartServiceMockRecorder struct {
+	mock *CartService
+}
+
+// NewCartService creates a new mock instance
+func NewCartService(ctrl *gomock.Controller) *CartService {
+	mock := &CartService{ctrl: ctrl}
+	mock.recorder = &CartServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *CartService) EXPECT() *CartServiceMockRecorder {
+	return m.recorder
+}
+
+// AddItem mocks base method
+func (m *CartService) AddItem(arg0 context.Context, arg1 cart.AddRequest) (*cart.Summary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddItem", arg0, arg1)
+	ret0, _ := ret[0].(*cart.Summary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddItem indicates an expected call of AddItem
+func (mr *CartServiceMockRecorder) AddItem(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddItem", reflect.TypeOf((*CartService)(nil).AddItem), arg0, arg1)
+}
+
+// Get mocks base method
+func (m *CartService) Get(arg0 context.Context) (*cart.Summary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0)
+	ret0, _ := ret[0].(*cart.Summary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get
+func (mr *CartServiceMockRecorder) Get(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*CartService)(nil).Get), arg0)
+}
+
+// RemoveItem mocks base method
+func (m *CartService) RemoveItem(arg0 context.Context, arg1 string) (*cart.Summary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveItem", arg0, arg1)
+	ret0, _ := ret[0].(*cart.Summary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveItem indicates an expected call of RemoveItem
+func (mr *CartServiceMockRecorder) RemoveItem(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveItem", reflect.TypeOf((*CartService)(nil).RemoveItem), arg0, arg1)
+}
+
+// UpdateItem mocks base method
+func (m *CartService) UpdateItem(arg0 context.Context, arg1 cart.UpdateRequest) (*cart.Summary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateItem", arg0, arg1)
+	ret0, _ := ret[0].(*cart.Summary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateItem indicates an expected call of UpdateItem
+func (mr *CartServiceMockRecorder) UpdateItem(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateItem", reflect.TypeOf((*CartService)(nil).UpdateItem), arg0, arg1)
+}
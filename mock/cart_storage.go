@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ortymid/market/market/cart (interfaces: Storage)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	cart "github.com/ortymid/market/market/cart"
+	reflect "reflect"
+)
+
+// CartStorage is a mock of Storage interface
+type CartStorage struct {
+	ctrl     *gomock.Controller
+	recorder *CartStorageMockRecorder
+}
+
+// CartStorageMockRecorder is the mock recorder for CartStorage
+type CartStorageMockRecorder struct {
+	mock *CartStorage
+}
+
+// NewCartStorage creates a new mock instance
+func NewCartStorage(ctrl *gomock.Controller) *CartStorage {
+	mock := &CartStorage{ctrl: ctrl}
+	mock.recorder = &CartStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *CartStorage) EXPECT() *CartStorageMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method
+func (m *CartStorage) Delete(arg0 context.Context, arg1 string) (*cart.Cart, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(*cart.Cart)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete
+func (mr *CartStorageMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*CartStorage)(nil).Delete), arg0, arg1)
+}
+
+// Get mocks base method
+func (m *CartStorage) Get(arg0 context.Context, arg1 string) (*cart.Cart, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(*cart.Cart)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get
+func (mr *CartStorageMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*CartStorage)(nil).Get), arg0, arg1)
+}
+
+// Save mocks base method
+func (m *CartStorage) Save(arg0 context.Context, arg1 cart.Cart) (*cart.Cart, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", arg0, arg1)
+	ret0, _ := ret[0].(*cart.Cart)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Save indicates an expected call of Save
+func (mr *CartStorageMockRecorder) Save(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*CartStorage)(nil).Save), arg0, arg1)
+}
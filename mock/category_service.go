@@ -0,0 +1,125 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ortymid/market/market/category (interfaces: Interface)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	category "github.com/ortymid/market/market/category"
+	reflect "reflect"
+)
+
+// CategoryService is a mock of Interface interface
+type CategoryService struct {
+	ctrl     *gomock.Controller
+	recorder *CategoryServiceMockRecorder
+}
+
+// CategoryServiceMockRecorder is the mock recorder for CategoryService
+type CategoryServiceMockRecorder struct {
+	mock *CategoryService
+}
+
+// NewCategoryService creates a new mock instance
+func NewCategoryService(ctrl *gomock.Controller) *CategoryService {
+	mock := &CategoryService{ctrl: ctrl}
+	mock.recorder = &CategoryServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *CategoryService) EXPECT() *CategoryServiceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *CategoryService) Create(arg0 context.Context, arg1 category.CreateRequest) (*category.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(*category.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create
+func (mr *CategoryServiceMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*CategoryService)(nil).Create), arg0, arg1)
+}
+
+// Delete mocks base method
+func (m *CategoryService) Delete(arg0 context.Context, arg1 string) (*category.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(*category.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete
+func (mr *CategoryServiceMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*CategoryService)(nil).Delete), arg0, arg1)
+}
+
+// Descendants mocks base method
+func (m *CategoryService) Descendants(arg0 context.Context, arg1 string) ([]*category.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Descendants", arg0, arg1)
+	ret0, _ := ret[0].([]*category.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Descendants indicates an expected call of Descendants
+func (mr *CategoryServiceMockRecorder) Descendants(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Descendants", reflect.TypeOf((*CategoryService)(nil).Descendants), arg0, arg1)
+}
+
+// Find mocks base method
+func (m *CategoryService) Find(arg0 context.Context) ([]*category.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", arg0)
+	ret0, _ := ret[0].([]*category.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find
+func (mr *CategoryServiceMockRecorder) Find(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*CategoryService)(nil).Find), arg0)
+}
+
+// FindOne mocks base method
+func (m *CategoryService) FindOne(arg0 context.Context, arg1 string) (*category.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOne", arg0, arg1)
+	ret0, _ := ret[0].(*category.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOne indicates an expected call of FindOne
+func (mr *CategoryServiceMockRecorder) FindOne(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOne", reflect.TypeOf((*CategoryService)(nil).FindOne), arg0, arg1)
+}
+
+// Update mocks base method
+func (m *CategoryService) Update(arg0 context.Context, arg1 category.UpdateRequest) (*category.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(*category.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update
+func (mr *CategoryServiceMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*CategoryService)(nil).Update), arg0, arg1)
+}
@@ -0,0 +1,110 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ortymid/market/market/category (interfaces: Storage)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	category "github.com/ortymid/market/market/category"
+	reflect "reflect"
+)
+
+// CategoryStorage is a mock of Storage interface
+type CategoryStorage struct {
+	ctrl     *gomock.Controller
+	recorder *CategoryStorageMockRecorder
+}
+
+// CategoryStorageMockRecorder is the mock recorder for CategoryStorage
+type CategoryStorageMockRecorder struct {
+	mock *CategoryStorage
+}
+
+// NewCategoryStorage creates a new mock instance
+func NewCategoryStorage(ctrl *gomock.Controller) *CategoryStorage {
+	mock := &CategoryStorage{ctrl: ctrl}
+	mock.recorder = &CategoryStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *CategoryStorage) EXPECT() *CategoryStorageMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *CategoryStorage) Create(arg0 context.Context, arg1 category.CreateRequest) (*category.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(*category.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create
+func (mr *CategoryStorageMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*CategoryStorage)(nil).Create), arg0, arg1)
+}
+
+// Delete mocks base method
+func (m *CategoryStorage) Delete(arg0 context.Context, arg1 string) (*category.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(*category.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete
+func (mr *CategoryStorageMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*CategoryStorage)(nil).Delete), arg0, arg1)
+}
+
+// Find mocks base method
+func (m *CategoryStorage) Find(arg0 context.Context) ([]*category.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", arg0)
+	ret0, _ := ret[0].([]*category.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find
+func (mr *CategoryStorageMockRecorder) Find(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*CategoryStorage)(nil).Find), arg0)
+}
+
+// FindOne mocks base method
+func (m *CategoryStorage) FindOne(arg0 context.Context, arg1 string) (*category.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOne", arg0, arg1)
+	ret0, _ := ret[0].(*category.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOne indicates an expected call of FindOne
+func (mr *CategoryStorageMockRecorder) FindOne(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOne", reflect.TypeOf((*CategoryStorage)(nil).FindOne), arg0, arg1)
+}
+
+// Update mocks base method
+func (m *CategoryStorage) Update(arg0 context.Context, arg1 category.UpdateRequest) (*category.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(*category.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update
+func (mr *CategoryStorageMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*CategoryStorage)(nil).Update), arg0, arg1)
+}
@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ortymid/market/market/order (interfaces: Interface)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	order "github.com/ortymid/market/market/order"
+	reflect "reflect"
+)
+
+// OrderService is a mock of Interface interface
+type OrderService struct {
+	ctrl     *gomock.Controller
+	recorder *OrderServiceMockRecorder
+}
+
+// OrderServiceMockRecorder is the mock recorder for OrderService
+type OrderServiceMockRecorder struct {
+	mock *OrderService
+}
+
+// NewOrderService creates a new mock instance
+func NewOrderService(ctrl *gomock.Controller) *OrderService {
+	mock := &OrderService{ctrl: ctrl}
+	mock.recorder = &OrderServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *OrderService) EXPECT() *OrderServiceMockRecorder {
+	return m.recorder
+}
+
+// Find mocks base method
+func (m *OrderService) Find(arg0 context.Context, arg1 order.FindRequest) ([]*order.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", arg0, arg1)
+	ret0, _ := ret[0].([]*order.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find
+func (mr *OrderServiceMockRecorder) Find(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*OrderService)(nil).Find), arg0, arg1)
+}
+
+// FindOne mocks base method
+func (m *OrderService) FindOne(arg0 context.Context, arg1 string) (*order.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOne", arg0, arg1)
+	ret0, _ := ret[0].(*order.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOne indicates an expected call of FindOne
+func (mr *OrderServiceMockRecorder) FindOne(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOne", reflect.TypeOf((*OrderService)(nil).FindOne), arg0, arg1)
+}
+
+// Purchase mocks base method
+func (m *OrderService) Purchase(arg0 context.Context, arg1 order.PurchaseRequest) (*order.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purchase", arg0, arg1)
+	ret0, _ := ret[0].(*order.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purchase indicates an expected call of Purchase
+func (mr *OrderServiceMockRecorder) Purchase(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purchase", reflect.TypeOf((*OrderService)(nil).Purchase), arg0, arg1)
+}
@@ -0,0 +1,125 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ortymid/market/market/order (interfaces: Storage)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	order "github.com/ortymid/market/market/order"
+	reflect "reflect"
+)
+
+// OrderStorage is a mock of Storage interface
+type OrderStorage struct {
+	ctrl     *gomock.Controller
+	recorder *OrderStorageMockRecorder
+}
+
+// OrderStorageMockRecorder is the mock recorder for OrderStorage
+type OrderStorageMockRecorder struct {
+	mock *OrderStorage
+}
+
+// NewOrderStorage creates a new mock instance
+func NewOrderStorage(ctrl *gomock.Controller) *OrderStorage {
+	mock := &OrderStorage{ctrl: ctrl}
+	mock.recorder = &OrderStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *OrderStorage) EXPECT() *OrderStorageMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *OrderStorage) Create(arg0 context.Context, arg1 order.Order) (*order.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(*order.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create
+func (mr *OrderStorageMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*OrderStorage)(nil).Create), arg0, arg1)
+}
+
+// Delete mocks base method
+func (m *OrderStorage) Delete(arg0 context.Context, arg1 string) (*order.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(*order.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete
+func (mr *OrderStorageMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*OrderStorage)(nil).Delete), arg0, arg1)
+}
+
+// Find mocks base method
+func (m *OrderStorage) Find(arg0 context.Context, arg1 order.FindRequest) ([]*order.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", arg0, arg1)
+	ret0, _ := ret[0].([]*order.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find
+func (mr *OrderStorageMockRecorder) Find(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*OrderStorage)(nil).Find), arg0, arg1)
+}
+
+// FindByKey mocks base method
+func (m *OrderStorage) FindByKey(arg0 context.Context, arg1, arg2 string) (*order.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByKey", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*order.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByKey indicates an expected call of FindByKey
+func (mr *OrderStorageMockRecorder) FindByKey(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByKey", reflect.TypeOf((*OrderStorage)(nil).FindByKey), arg0, arg1, arg2)
+}
+
+// FindOne mocks base method
+func (m *OrderStorage) FindOne(arg0 context.Context, arg1 string) (*order.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOne", arg0, arg1)
+	ret0, _ := ret[0].(*order.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOne indicates an expected call of FindOne
+func (mr *OrderStorageMockRecorder) FindOne(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOne", reflect.TypeOf((*OrderStorage)(nil).FindOne), arg0, arg1)
+}
+
+// UpdateStatus mocks base method
+func (m *OrderStorage) UpdateStatus(arg0 context.Context, arg1 string, arg2 order.Status) (*order.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*order.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus
+func (mr *OrderStorageMockRecorder) UpdateStatus(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*OrderStorage)(nil).UpdateStatus), arg0, arg1, arg2)
+}
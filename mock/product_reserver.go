@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ortymid/market/market/product (interfaces: Reserver)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	product "github.com/ortymid/market/market/product"
+	reflect "reflect"
+)
+
+// ProductReserver is a mock of Reserver interface
+type ProductReserver struct {
+	ctrl     *gomock.Controller
+	recorder *ProductReserverMockRecorder
+}
+
+// ProductReserverMockRecorder is the mock recorder for ProductReserver
+type ProductReserverMockRecorder struct {
+	mock *ProductReserver
+}
+
+// NewProductReserver creates a new mock instance
+func NewProductReserver(ctrl *gomock.Controller) *ProductReserver {
+	mock := &ProductReserver{ctrl: ctrl}
+	mock.recorder = &ProductReserverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *ProductReserver) EXPECT() *ProductReserverMockRecorder {
+	return m.recorder
+}
+
+// CommitReservation mocks base method
+func (m *ProductReserver) CommitReservation(arg0 context.Context, arg1 string, arg2 int64) (*product.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CommitReservation", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*product.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CommitReservation indicates an expected call of CommitReservation
+func (mr *ProductReserverMockRecorder) CommitReservation(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitReservation", reflect.TypeOf((*ProductReserver)(nil).CommitReservation), arg0, arg1, arg2)
+}
+
+// ReleaseReservation mocks base method
+func (m *ProductReserver) ReleaseReservation(arg0 context.Context, arg1, arg2 string, arg3 int64) (*product.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseReservation", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*product.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseReservation indicates an expected call of ReleaseReservation
+func (mr *ProductReserverMockRecorder) ReleaseReservation(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseReservation", reflect.TypeOf((*ProductReserver)(nil).ReleaseReservation), arg0, arg1, arg2, arg3)
+}
+
+// Reserve mocks base method
+func (m *ProductReserver) Reserve(arg0 context.Context, arg1, arg2 string, arg3 int64) (*product.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*product.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reserve indicates an expected call of Reserve
+func (mr *ProductReserverMockRecorder) Reserve(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*ProductReserver)(nil).Reserve), arg0, arg1, arg2, arg3)
+}
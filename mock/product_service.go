@@ -7,6 +7,8 @@ package mock
 import (
 	context "context"
 	gomock "github.com/golang/mock/gomock"
+	audit "github.com/ortymid/market/market/audit"
+	media "github.com/ortymid/market/market/media"
 	product "github.com/ortymid/market/market/product"
 	reflect "reflect"
 )
@@ -34,6 +36,36 @@ func (m *ProductService) EXPECT() *ProductServiceMockRecorder {
 	return m.recorder
 }
 
+// AddMedia mocks base method
+func (m *ProductService) AddMedia(arg0 context.Context, arg1 product.AddMediaRequest) (*product.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddMedia", arg0, arg1)
+	ret0, _ := ret[0].(*product.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddMedia indicates an expected call of AddMedia
+func (mr *ProductServiceMockRecorder) AddMedia(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMedia", reflect.TypeOf((*ProductService)(nil).AddMedia), arg0, arg1)
+}
+
+// AdjustStock mocks base method
+func (m *ProductService) AdjustStock(arg0 context.Context, arg1, arg2 string, arg3 int64) (*product.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdjustStock", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*product.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdjustStock indicates an expected call of AdjustStock
+func (mr *ProductServiceMockRecorder) AdjustStock(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdjustStock", reflect.TypeOf((*ProductService)(nil).AdjustStock), arg0, arg1, arg2, arg3)
+}
+
 // Create mocks base method
 func (m *ProductService) Create(arg0 context.Context, arg1 product.CreateRequest) (*product.Product, error) {
 	m.ctrl.T.Helper()
@@ -65,10 +97,10 @@ func (mr *ProductServiceMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Cal
 }
 
 // Find mocks base method
-func (m *ProductService) Find(arg0 context.Context, arg1 product.FindRequest) ([]*product.Product, error) {
+func (m *ProductService) Find(arg0 context.Context, arg1 product.FindRequest) (*product.FindResult, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Find", arg0, arg1)
-	ret0, _ := ret[0].([]*product.Product)
+	ret0, _ := ret[0].(*product.FindResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -94,6 +126,81 @@ func (mr *ProductServiceMockRecorder) FindOne(arg0, arg1 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOne", reflect.TypeOf((*ProductService)(nil).FindOne), arg0, arg1)
 }
 
+// FindOneIn mocks base method
+func (m *ProductService) FindOneIn(arg0 context.Context, arg1, arg2 string) (*product.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOneIn", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*product.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOneIn indicates an expected call of FindOneIn
+func (mr *ProductServiceMockRecorder) FindOneIn(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOneIn", reflect.TypeOf((*ProductService)(nil).FindOneIn), arg0, arg1, arg2)
+}
+
+// History mocks base method
+func (m *ProductService) History(arg0 context.Context, arg1 audit.FindRequest) ([]*audit.Entry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", arg0, arg1)
+	ret0, _ := ret[0].([]*audit.Entry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// History indicates an expected call of History
+func (mr *ProductServiceMockRecorder) History(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*ProductService)(nil).History), arg0, arg1)
+}
+
+// OpenMedia mocks base method
+func (m *ProductService) OpenMedia(arg0 context.Context, arg1, arg2 string, arg3 bool) (*media.Content, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenMedia", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*media.Content)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenMedia indicates an expected call of OpenMedia
+func (mr *ProductServiceMockRecorder) OpenMedia(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenMedia", reflect.TypeOf((*ProductService)(nil).OpenMedia), arg0, arg1, arg2, arg3)
+}
+
+// RemoveMedia mocks base method
+func (m *ProductService) RemoveMedia(arg0 context.Context, arg1, arg2 string) (*product.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveMedia", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*product.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveMedia indicates an expected call of RemoveMedia
+func (mr *ProductServiceMockRecorder) RemoveMedia(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveMedia", reflect.TypeOf((*ProductService)(nil).RemoveMedia), arg0, arg1, arg2)
+}
+
+// Restore mocks base method
+func (m *ProductService) Restore(arg0 context.Context, arg1 string) (*product.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1)
+	ret0, _ := ret[0].(*product.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore
+func (mr *ProductServiceMockRecorder) Restore(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*ProductService)(nil).Restore), arg0, arg1)
+}
+
 // Update mocks base method
 func (m *ProductService) Update(arg0 context.Context, arg1 product.UpdateRequest) (*product.Product, error) {
 	m.ctrl.T.Helper()
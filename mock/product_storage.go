@@ -9,6 +9,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	product "github.com/ortymid/market/market/product"
 	reflect "reflect"
+	time "time"
 )
 
 // ProductStorage is a mock of Storage interface
@@ -50,25 +51,25 @@ func (mr *ProductStorageMockRecorder) Create(arg0, arg1 interface{}) *gomock.Cal
 }
 
 // Delete mocks base method
-func (m *ProductStorage) Delete(arg0 context.Context, arg1 string) (*product.Product, error) {
+func (m *ProductStorage) Delete(arg0 context.Context, arg1 string, arg2 time.Time) (*product.Product, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*product.Product)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Delete indicates an expected call of Delete
-func (mr *ProductStorageMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+func (mr *ProductStorageMockRecorder) Delete(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*ProductStorage)(nil).Delete), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*ProductStorage)(nil).Delete), arg0, arg1, arg2)
 }
 
 // Find mocks base method
-func (m *ProductStorage) Find(arg0 context.Context, arg1 product.FindRequest) ([]*product.Product, error) {
+func (m *ProductStorage) Find(arg0 context.Context, arg1 product.FindRequest) (*product.FindResult, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Find", arg0, arg1)
-	ret0, _ := ret[0].([]*product.Product)
+	ret0, _ := ret[0].(*product.FindResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -94,6 +95,66 @@ func (mr *ProductStorageMockRecorder) FindOne(arg0, arg1 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOne", reflect.TypeOf((*ProductStorage)(nil).FindOne), arg0, arg1)
 }
 
+// PublishDue mocks base method
+func (m *ProductStorage) PublishDue(arg0 context.Context, arg1 time.Time) ([]*product.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublishDue", arg0, arg1)
+	ret0, _ := ret[0].([]*product.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PublishDue indicates an expected call of PublishDue
+func (mr *ProductStorageMockRecorder) PublishDue(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishDue", reflect.TypeOf((*ProductStorage)(nil).PublishDue), arg0, arg1)
+}
+
+// Purge mocks base method
+func (m *ProductStorage) Purge(arg0 context.Context, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge
+func (mr *ProductStorageMockRecorder) Purge(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*ProductStorage)(nil).Purge), arg0, arg1)
+}
+
+// Restore mocks base method
+func (m *ProductStorage) Restore(arg0 context.Context, arg1 string) (*product.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1)
+	ret0, _ := ret[0].(*product.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore
+func (mr *ProductStorageMockRecorder) Restore(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*ProductStorage)(nil).Restore), arg0, arg1)
+}
+
+// SummarizeSellers mocks base method
+func (m *ProductStorage) SummarizeSellers(arg0 context.Context, arg1 []string) (map[string]product.SellerSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SummarizeSellers", arg0, arg1)
+	ret0, _ := ret[0].(map[string]product.SellerSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SummarizeSellers indicates an expected call of SummarizeSellers
+func (mr *ProductStorageMockRecorder) SummarizeSellers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SummarizeSellers", reflect.TypeOf((*ProductStorage)(nil).SummarizeSellers), arg0, arg1)
+}
+
 // Update mocks base method
 func (m *ProductStorage) Update(arg0 context.Context, arg1 product.UpdateRequest) (*product.Product, error) {
 	m.ctrl.T.Helper()
@@ -108,3 +169,33 @@ func (mr *ProductStorageMockRecorder) Update(arg0, arg1 interface{}) *gomock.Cal
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*ProductStorage)(nil).Update), arg0, arg1)
 }
+
+// UpdateRating mocks base method
+func (m *ProductStorage) UpdateRating(arg0 context.Context, arg1 string, arg2 product.Rating) (*product.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRating", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*product.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateRating indicates an expected call of UpdateRating
+func (mr *ProductStorageMockRecorder) UpdateRating(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRating", reflect.TypeOf((*ProductStorage)(nil).UpdateRating), arg0, arg1, arg2)
+}
+
+// UpdateStock mocks base method
+func (m *ProductStorage) UpdateStock(arg0 context.Context, arg1 product.StockRequest) (*product.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStock", arg0, arg1)
+	ret0, _ := ret[0].(*product.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateStock indicates an expected call of UpdateStock
+func (mr *ProductStorageMockRecorder) UpdateStock(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStock", reflect.TypeOf((*ProductStorage)(nil).UpdateStock), arg0, arg1)
+}
@@ -0,0 +1,110 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ortymid/market/market/review (interfaces: Interface)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	review "github.com/ortymid/market/market/review"
+	reflect "reflect"
+)
+
+// ReviewService is a mock of Interface interface
+type ReviewService struct {
+	ctrl     *gomock.Controller
+	recorder *ReviewServiceMockRecorder
+}
+
+// ReviewServiceMockRecorder is the mock recorder for ReviewService
+type ReviewServiceMockRecorder struct {
+	mock *ReviewService
+}
+
+// NewReviewService creates a new mock instance
+func NewReviewService(ctrl *gomock.Controller) *ReviewService {
+	mock := &ReviewService{ctrl: ctrl}
+	mock.recorder = &ReviewServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *ReviewService) EXPECT() *ReviewServiceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *ReviewService) Create(arg0 context.Context, arg1 review.CreateRequest) (*review.Review, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(*review.Review)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create
+func (mr *ReviewServiceMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*ReviewService)(nil).Create), arg0, arg1)
+}
+
+// Delete mocks base method
+func (m *ReviewService) Delete(arg0 context.Context, arg1, arg2 string) (*review.Review, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*review.Review)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete
+func (mr *ReviewServiceMockRecorder) Delete(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*ReviewService)(nil).Delete), arg0, arg1, arg2)
+}
+
+// Find mocks base method
+func (m *ReviewService) Find(arg0 context.Context, arg1 review.FindRequest) ([]*review.Review, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", arg0, arg1)
+	ret0, _ := ret[0].([]*review.Review)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find
+func (mr *ReviewServiceMockRecorder) Find(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*ReviewService)(nil).Find), arg0, arg1)
+}
+
+// FindOne mocks base method
+func (m *ReviewService) FindOne(arg0 context.Context, arg1, arg2 string) (*review.Review, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOne", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*review.Review)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOne indicates an expected call of FindOne
+func (mr *ReviewServiceMockRecorder) FindOne(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOne", reflect.TypeOf((*ReviewService)(nil).FindOne), arg0, arg1, arg2)
+}
+
+// Update mocks base method
+func (m *ReviewService) Update(arg0 context.Context, arg1 review.UpdateRequest) (*review.Review, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(*review.Review)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update
+func (mr *ReviewServiceMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*ReviewService)(nil).Update), arg0, arg1)
+}
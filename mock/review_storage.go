@@ -0,0 +1,126 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ortymid/market/market/review (interfaces: Storage)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	review "github.com/ortymid/market/market/review"
+	reflect "reflect"
+	time "time"
+)
+
+// ReviewStorage is a mock of Storage interface
+type ReviewStorage struct {
+	ctrl     *gomock.Controller
+	recorder *ReviewStorageMockRecorder
+}
+
+// ReviewStorageMockRecorder is the mock recorder for ReviewStorage
+type ReviewStorageMockRecorder struct {
+	mock *ReviewStorage
+}
+
+// NewReviewStorage creates a new mock instance
+func NewReviewStorage(ctrl *gomock.Controller) *ReviewStorage {
+	mock := &ReviewStorage{ctrl: ctrl}
+	mock.recorder = &ReviewStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *ReviewStorage) EXPECT() *ReviewStorageMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *ReviewStorage) Create(arg0 context.Context, arg1 review.Review) (*review.Review, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(*review.Review)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create
+func (mr *ReviewStorageMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*ReviewStorage)(nil).Create), arg0, arg1)
+}
+
+// Delete mocks base method
+func (m *ReviewStorage) Delete(arg0 context.Context, arg1 string) (*review.Review, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(*review.Review)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete
+func (mr *ReviewStorageMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*ReviewStorage)(nil).Delete), arg0, arg1)
+}
+
+// Find mocks base method
+func (m *ReviewStorage) Find(arg0 context.Context, arg1 review.FindRequest) ([]*review.Review, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", arg0, arg1)
+	ret0, _ := ret[0].([]*review.Review)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find
+func (mr *ReviewStorageMockRecorder) Find(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*ReviewStorage)(nil).Find), arg0, arg1)
+}
+
+// FindOne mocks base method
+func (m *ReviewStorage) FindOne(arg0 context.Context, arg1 string) (*review.Review, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOne", arg0, arg1)
+	ret0, _ := ret[0].(*review.Review)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOne indicates an expected call of FindOne
+func (mr *ReviewStorageMockRecorder) FindOne(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOne", reflect.TypeOf((*ReviewStorage)(nil).FindOne), arg0, arg1)
+}
+
+// Summarize mocks base method
+func (m *ReviewStorage) Summarize(arg0 context.Context, arg1 string) (review.Summary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Summarize", arg0, arg1)
+	ret0, _ := ret[0].(review.Summary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Summarize indicates an expected call of Summarize
+func (mr *ReviewStorageMockRecorder) Summarize(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Summarize", reflect.TypeOf((*ReviewStorage)(nil).Summarize), arg0, arg1)
+}
+
+// Update mocks base method
+func (m *ReviewStorage) Update(arg0 context.Context, arg1 review.UpdateRequest, arg2 time.Time) (*review.Review, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*review.Review)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update
+func (mr *ReviewStorageMockRecorder) Update(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*ReviewStorage)(nil).Update), arg0, arg1, arg2)
+}
@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ortymid/market/market/seller (interfaces: Interface)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	seller "github.com/ortymid/market/market/seller"
+	reflect "reflect"
+)
+
+// SellerService is a mock of Interface interface
+type SellerService struct {
+	ctrl     *gomock.Controller
+	recorder *SellerServiceMockRecorder
+}
+
+// SellerServiceMockRecorder is the mock recorder for SellerService
+type SellerServiceMockRecorder struct {
+	mock *SellerService
+}
+
+// NewSellerService creates a new mock instance
+func NewSellerService(ctrl *gomock.Controller) *SellerService {
+	mock := &SellerService{ctrl: ctrl}
+	mock.recorder = &SellerServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *SellerService) EXPECT() *SellerServiceMockRecorder {
+	return m.recorder
+}
+
+// Find mocks base method
+func (m *SellerService) Find(arg0 context.Context, arg1 []string) ([]*seller.Seller, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", arg0, arg1)
+	ret0, _ := ret[0].([]*seller.Seller)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find
+func (mr *SellerServiceMockRecorder) Find(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*SellerService)(nil).Find), arg0, arg1)
+}
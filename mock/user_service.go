@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ortymid/market/market/user (interfaces: Service)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	user "github.com/ortymid/market/market/user"
+	reflect "reflect"
+)
+
+// UserService is a mock of Service interface
+type UserService struct {
+	ctrl     *gomock.Controller
+	recorder *UserServiceMockRecorder
+}
+
+// UserServiceMockRecorder is the mock recorder for UserService
+type UserServiceMockRecorder struct {
+	mock *UserService
+}
+
+// NewUserService creates a new mock instance
+func NewUserService(ctrl *gomock.Controller) *UserService {
+	mock := &UserService{ctrl: ctrl}
+	mock.recorder = &UserServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *UserService) EXPECT() *UserServiceMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method
+func (m *UserService) Get(arg0 context.Context, arg1 string) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get
+func (mr *UserServiceMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*UserService)(nil).Get), arg0, arg1)
+}
+
+// Update mocks base method
+func (m *UserService) Update(arg0 context.Context, arg1 user.UpdateRequest) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update
+func (mr *UserServiceMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*UserService)(nil).Update), arg0, arg1)
+}
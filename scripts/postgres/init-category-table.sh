@@ -0,0 +1,11 @@
+#!/bin/bash
+set -e
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-EOSQL
+  CREATE TABLE categories (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR NOT NULL UNIQUE,
+    name VARCHAR NOT NULL,
+    parent INTEGER REFERENCES categories (id)
+  );
+EOSQL
@@ -0,0 +1,19 @@
+#!/bin/bash
+set -e
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-EOSQL
+  CREATE TABLE orders (
+    id SERIAL PRIMARY KEY,
+    product VARCHAR NOT NULL,
+    variant VARCHAR NOT NULL DEFAULT '',
+    buyer VARCHAR NOT NULL,
+    seller VARCHAR NOT NULL,
+    quantity BIGINT NOT NULL,
+    price BIGINT NOT NULL,
+    total BIGINT NOT NULL,
+    status VARCHAR NOT NULL,
+    idempotency_key VARCHAR NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (buyer, idempotency_key)
+  );
+EOSQL
@@ -0,0 +1,16 @@
+#!/bin/bash
+set -e
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-EOSQL
+  CREATE TABLE product_history (
+    id SERIAL PRIMARY KEY,
+    product VARCHAR NOT NULL,
+    seller VARCHAR NOT NULL,
+    actor VARCHAR NOT NULL,
+    operation VARCHAR NOT NULL,
+    version BIGINT NOT NULL,
+    changes JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+  );
+  CREATE INDEX product_history_product_idx ON product_history (product, id);
+EOSQL
@@ -5,6 +5,23 @@ psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-E
   CREATE TABLE products (
     id SERIAL PRIMARY KEY,
     name VARCHAR NOT NULL,
-    price INTEGER NOT NULL,
-    seller VARCHAR NOT NULL
-  );
\ No newline at end of file
+    price BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    seller VARCHAR NOT NULL,
+    categories VARCHAR[] NOT NULL DEFAULT '{}',
+    stock BIGINT NOT NULL DEFAULT 0,
+    reserved BIGINT NOT NULL DEFAULT 0,
+    low_stock_threshold BIGINT NOT NULL DEFAULT 0,
+    version BIGINT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMPTZ,
+    status VARCHAR NOT NULL DEFAULT 'published',
+    publish_at TIMESTAMPTZ,
+    media JSONB NOT NULL DEFAULT '[]',
+    attributes JSONB NOT NULL DEFAULT '[]',
+    variants JSONB NOT NULL DEFAULT '[]',
+    rating_average DOUBLE PRECISION NOT NULL DEFAULT 0,
+    rating_count BIGINT NOT NULL DEFAULT 0
+  );
+  CREATE INDEX products_deleted_at_idx ON products (deleted_at) WHERE deleted_at IS NOT NULL;
+  CREATE INDEX products_publish_at_idx ON products (publish_at) WHERE status = 'draft';
+  CREATE INDEX products_variants_idx ON products USING GIN (variants jsonb_path_ops);
@@ -0,0 +1,15 @@
+#!/bin/bash
+set -e
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-EOSQL
+  CREATE TABLE reviews (
+    id SERIAL PRIMARY KEY,
+    product VARCHAR NOT NULL,
+    author VARCHAR NOT NULL,
+    rating INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (product, author)
+  );
+EOSQL
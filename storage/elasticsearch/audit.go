@@ -0,0 +1,150 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v7"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/ortymid/market/market/audit"
+	"time"
+)
+
+type auditSource struct {
+	Product   string          `json:"product"`
+	Seller    string          `json:"seller"`
+	Actor     string          `json:"actor"`
+	Operation audit.Operation `json:"operation"`
+	Version   int64           `json:"version"`
+	Changes   []audit.Change  `json:"changes"`
+	CreatedAt time.Time       `json:"created_at"`
+	// IndexedAt is the time the document is indexed, it keeps the order of
+	// recording for entries created at the same time.
+	IndexedAt time.Time `json:"indexed_at"`
+}
+
+// entry makes the entry with the id from the source.
+func (src auditSource) entry(id string) *audit.Entry {
+	e := &audit.Entry{
+		ID:        id,
+		Product:   src.Product,
+		Seller:    src.Seller,
+		Actor:     src.Actor,
+		Operation: src.Operation,
+		Version:   src.Version,
+		Changes:   src.Changes,
+		CreatedAt: src.CreatedAt,
+	}
+	if e.Changes == nil {
+		e.Changes = []audit.Change{}
+	}
+	return e
+}
+
+type auditSearchResponse struct {
+	Hits struct {
+		Hits []struct {
+			ID     string      `json:"_id"`
+			Source auditSource `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// AuditStorage keeps entries in an index.
+type AuditStorage struct {
+	es    *elasticsearch.Client
+	index string
+}
+
+func NewAuditStorage(es *elasticsearch.Client, index string) *AuditStorage {
+	return &AuditStorage{es: es, index: index}
+}
+
+func (s *AuditStorage) Record(ctx context.Context, e audit.Entry) error {
+	b, err := json.Marshal(auditSource{
+		Product:   e.Product,
+		Seller:    e.Seller,
+		Actor:     e.Actor,
+		Operation: e.Operation,
+		Version:   e.Version,
+		Changes:   e.Changes,
+		CreatedAt: e.CreatedAt,
+		IndexedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req := esapi.IndexRequest{
+		Index:   s.index,
+		Body:    bytes.NewReader(b),
+		Refresh: refresh,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return fmt.Errorf("making elasticsearch request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+	return nil
+}
+
+func (s *AuditStorage) Find(ctx context.Context, r audit.FindRequest) ([]*audit.Entry, error) {
+	var body bytes.Buffer
+	err := json.NewEncoder(&body).Encode(map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{
+						"term": map[string]interface{}{"product.keyword": r.Product},
+					},
+				},
+			},
+		},
+		"sort": []interface{}{
+			map[string]interface{}{"created_at": "asc"},
+			map[string]interface{}{"indexed_at": "asc"},
+			map[string]interface{}{"_id": "asc"},
+		},
+		"from": r.Offset,
+		"size": r.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding elasticsearch query: %w", err)
+	}
+
+	res, err := s.es.Search(
+		s.es.Search.WithContext(ctx),
+		s.es.Search.WithIndex(s.index),
+		s.es.Search.WithBody(&body),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("searching: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		// The index is created with the first entry.
+		if res.StatusCode == 404 {
+			return []*audit.Entry{}, nil
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var sr auditSearchResponse
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
+		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	es := make([]*audit.Entry, 0, len(sr.Hits.Hits))
+	for _, hit := range sr.Hits.Hits {
+		es = append(es, hit.Source.entry(hit.ID))
+	}
+
+	return es, nil
+}
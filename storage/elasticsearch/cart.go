@@ -0,0 +1,112 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v7"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/ortymid/market/market/cart"
+)
+
+type cartGetResponse struct {
+	Found  bool      `json:"found"`
+	Source cart.Cart `json:"_source"`
+}
+
+// CartStorage keeps carts in an index with users as document ids. Expired
+// carts are kept until they are deleted.
+type CartStorage struct {
+	es    *elasticsearch.Client
+	index string
+}
+
+func NewCartStorage(es *elasticsearch.Client, index string) *CartStorage {
+	return &CartStorage{es: es, index: index}
+}
+
+func (s *CartStorage) Get(ctx context.Context, user string) (*cart.Cart, error) {
+	req := esapi.GetRequest{
+		Index:      s.index,
+		DocumentID: user,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 404 {
+			return nil, cart.ErrNotFound
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var gr cartGetResponse
+	if err := json.NewDecoder(res.Body).Decode(&gr); err != nil {
+		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	if !gr.Found {
+		return nil, cart.ErrNotFound
+	}
+
+	return &gr.Source, nil
+}
+
+func (s *CartStorage) Save(ctx context.Context, c cart.Cart) (*cart.Cart, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	req := esapi.IndexRequest{
+		Index:      s.index,
+		DocumentID: c.User,
+		Body:       bytes.NewReader(b),
+		Refresh:    refresh,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return nil, fmt.Errorf("making elasticsearch request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	return &c, nil
+}
+
+func (s *CartStorage) Delete(ctx context.Context, user string) (*cart.Cart, error) {
+	c, err := s.Get(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	req := esapi.DeleteRequest{
+		Index:      s.index,
+		DocumentID: user,
+		Refresh:    refresh,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return nil, fmt.Errorf("making elasticsearch request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 404 {
+			return nil, cart.ErrNotFound
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	return c, nil
+}
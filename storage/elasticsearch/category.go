@@ -0,0 +1,249 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v7"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/ortymid/market/market/category"
+)
+
+type categorySource struct {
+	Slug   string  `json:"slug"`
+	Name   string  `json:"name"`
+	Parent *string `json:"parent"`
+}
+
+// category makes the category with the id from the source.
+func (src categorySource) category(id string) *category.Category {
+	c := &category.Category{
+		ID:   id,
+		Slug: src.Slug,
+		Name: src.Name,
+	}
+	if src.Parent != nil && *src.Parent != "" {
+		c.Parent = src.Parent
+	}
+	return c
+}
+
+type categorySearchResponse struct {
+	Hits struct {
+		Hits []struct {
+			ID     string         `json:"_id"`
+			Source categorySource `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+type categoryGetResponse struct {
+	Found  bool           `json:"found"`
+	Source categorySource `json:"_source"`
+}
+
+type categoryUpdateResponse struct {
+	ID  string              `json:"_id"`
+	Get categoryGetResponse `json:"get"`
+}
+
+// maxCategories is the number of categories Find returns at most. It is the
+// default limit of the index result window.
+const maxCategories = 10000
+
+type CategoryStorage struct {
+	es    *elasticsearch.Client
+	index string
+}
+
+func NewCategoryStorage(es *elasticsearch.Client, index string) *CategoryStorage {
+	return &CategoryStorage{es: es, index: index}
+}
+
+func (s *CategoryStorage) Find(ctx context.Context) ([]*category.Category, error) {
+	res, err := s.es.Search(
+		s.es.Search.WithContext(ctx),
+		s.es.Search.WithIndex(s.index),
+		s.es.Search.WithSize(maxCategories),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("searching: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		// The index is created with the first category.
+		if res.StatusCode == 404 {
+			return []*category.Category{}, nil
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var sr categorySearchResponse
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
+		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	cs := make([]*category.Category, 0, len(sr.Hits.Hits))
+	for _, hit := range sr.Hits.Hits {
+		cs = append(cs, hit.Source.category(hit.ID))
+	}
+
+	return cs, nil
+}
+
+func (s *CategoryStorage) FindOne(ctx context.Context, id string) (*category.Category, error) {
+	req := esapi.GetRequest{
+		Index:      s.index,
+		DocumentID: id,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 404 {
+			return nil, category.ErrNotFound
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var gr categoryGetResponse
+	if err := json.NewDecoder(res.Body).Decode(&gr); err != nil {
+		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	if !gr.Found {
+		return nil, category.ErrNotFound
+	}
+
+	return gr.Source.category(id), nil
+}
+
+func (s *CategoryStorage) Create(ctx context.Context, r category.CreateRequest) (*category.Category, error) {
+	b, err := json.Marshal(categorySource{
+		Slug:   r.Slug,
+		Name:   r.Name,
+		Parent: r.Parent,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req := esapi.IndexRequest{
+		Index:   s.index,
+		Body:    bytes.NewReader(b),
+		Refresh: refresh,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return nil, fmt.Errorf("making elasticsearch request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var ir indexResponse
+	if err := json.NewDecoder(res.Body).Decode(&ir); err != nil {
+		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	if ir.Result != "created" {
+		return nil, fmt.Errorf("not created, result: %s", ir.Result)
+	}
+
+	c := &category.Category{
+		ID:     ir.ID,
+		Slug:   r.Slug,
+		Name:   r.Name,
+		Parent: r.Parent,
+	}
+	return c, nil
+}
+
+func (s *CategoryStorage) Update(ctx context.Context, r category.UpdateRequest) (*category.Category, error) {
+	doc := make(map[string]interface{})
+	if r.Slug != nil {
+		doc["slug"] = *r.Slug
+	}
+	if r.Name != nil {
+		doc["name"] = *r.Name
+	}
+	if r.Parent != nil {
+		// Root categories have a null parent.
+		var parent interface{}
+		if *r.Parent != "" {
+			parent = *r.Parent
+		}
+		doc["parent"] = parent
+	}
+
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(map[string]interface{}{"doc": doc})
+	if err != nil {
+		return nil, fmt.Errorf("encoding elasticsearch request: %w", err)
+	}
+
+	req := esapi.UpdateRequest{
+		Index:          s.index,
+		DocumentID:     r.ID,
+		Body:           &buf,
+		SourceIncludes: []string{"slug", "name", "parent"},
+		Refresh:        refresh,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return nil, fmt.Errorf("making elasticsearch request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 404 {
+			return nil, category.ErrNotFound
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var ur categoryUpdateResponse
+	if err := json.NewDecoder(res.Body).Decode(&ur); err != nil {
+		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	return ur.Get.Source.category(ur.ID), nil
+}
+
+func (s *CategoryStorage) Delete(ctx context.Context, id string) (*category.Category, error) {
+	c, err := s.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req := esapi.DeleteRequest{
+		Index:      s.index,
+		DocumentID: id,
+		Refresh:    refresh,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return nil, fmt.Errorf("making elasticsearch request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 404 {
+			return nil, category.ErrNotFound
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	return c, nil
+}
@@ -0,0 +1,38 @@
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v7"
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/storage/storagetest"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestCategoryStorage_Conformance runs against a real cluster, it is skipped
+// if MARKET_TEST_ELASTICSEARCH_URL is not set.
+func TestCategoryStorage_Conformance(t *testing.T) {
+	url := os.Getenv("MARKET_TEST_ELASTICSEARCH_URL")
+	if len(url) == 0 {
+		t.Skip("MARKET_TEST_ELASTICSEARCH_URL is not set")
+	}
+
+	es, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{url}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.TestCategoryStorage(t, func(t *testing.T) category.Storage {
+		index := fmt.Sprintf("categories_test_%d", time.Now().UnixNano())
+		t.Cleanup(func() {
+			res, err := es.Indices.Delete([]string{index}, es.Indices.Delete.WithContext(context.Background()))
+			if err == nil {
+				res.Body.Close()
+			}
+		})
+
+		return NewCategoryStorage(es, index)
+	})
+}
@@ -0,0 +1,296 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v7"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/ortymid/market/market/order"
+	"time"
+)
+
+type orderSource struct {
+	Product        string       `json:"product"`
+	Variant        string       `json:"variant,omitempty"`
+	Buyer          string       `json:"buyer"`
+	Seller         string       `json:"seller"`
+	Quantity       int64        `json:"quantity"`
+	Price          int64        `json:"price"`
+	Total          int64        `json:"total"`
+	Status         order.Status `json:"status"`
+	IdempotencyKey string       `json:"idempotency_key"`
+	CreatedAt      time.Time    `json:"created_at"`
+	// IndexedAt is the time the document is indexed, it keeps the order of
+	// creation.
+	IndexedAt time.Time `json:"indexed_at"`
+}
+
+// order makes the order with the id from the source.
+func (src orderSource) order(id string) *order.Order {
+	return &order.Order{
+		ID:             id,
+		Product:        src.Product,
+		Variant:        src.Variant,
+		Buyer:          src.Buyer,
+		Seller:         src.Seller,
+		Quantity:       src.Quantity,
+		Price:          src.Price,
+		Total:          src.Total,
+		Status:         src.Status,
+		IdempotencyKey: src.IdempotencyKey,
+		CreatedAt:      src.CreatedAt,
+	}
+}
+
+type orderSearchResponse struct {
+	Hits struct {
+		Hits []struct {
+			ID     string      `json:"_id"`
+			Source orderSource `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+type orderGetResponse struct {
+	Found  bool        `json:"found"`
+	Source orderSource `json:"_source"`
+}
+
+type orderUpdateResponse struct {
+	ID  string           `json:"_id"`
+	Get orderGetResponse `json:"get"`
+}
+
+// OrderStorage keeps orders in an index. Ids of the documents are derived
+// from the buyer and the idempotency key, so the index rejects duplicates.
+type OrderStorage struct {
+	es    *elasticsearch.Client
+	index string
+}
+
+func NewOrderStorage(es *elasticsearch.Client, index string) *OrderStorage {
+	return &OrderStorage{es: es, index: index}
+}
+
+// orderID returns the id of the order of the buyer with the idempotency key.
+func orderID(buyer string, key string) string {
+	sum := sha256.Sum256([]byte(buyer + "\x00" + key))
+	return hex.EncodeToString(sum[:16])
+}
+
+func (s *OrderStorage) Find(ctx context.Context, r order.FindRequest) ([]*order.Order, error) {
+	var filter []interface{}
+	if r.Buyer != nil {
+		filter = append(filter, map[string]interface{}{
+			"term": map[string]interface{}{"buyer.keyword": *r.Buyer},
+		})
+	}
+	if r.Seller != nil {
+		filter = append(filter, map[string]interface{}{
+			"term": map[string]interface{}{"seller.keyword": *r.Seller},
+		})
+	}
+
+	query := map[string]interface{}{"match_all": map[string]interface{}{}}
+	if len(filter) > 0 {
+		query = map[string]interface{}{"bool": map[string]interface{}{"filter": filter}}
+	}
+
+	var body bytes.Buffer
+	err := json.NewEncoder(&body).Encode(map[string]interface{}{
+		"query": query,
+		"sort": []interface{}{
+			map[string]interface{}{"indexed_at": "asc"},
+			map[string]interface{}{"_id": "asc"},
+		},
+		"from": r.Offset,
+		"size": r.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding elasticsearch query: %w", err)
+	}
+
+	res, err := s.es.Search(
+		s.es.Search.WithContext(ctx),
+		s.es.Search.WithIndex(s.index),
+		s.es.Search.WithBody(&body),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("searching: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		// The index is created with the first order.
+		if res.StatusCode == 404 {
+			return []*order.Order{}, nil
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var sr orderSearchResponse
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
+		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	os := make([]*order.Order, 0, len(sr.Hits.Hits))
+	for _, hit := range sr.Hits.Hits {
+		os = append(os, hit.Source.order(hit.ID))
+	}
+
+	return os, nil
+}
+
+func (s *OrderStorage) FindOne(ctx context.Context, id string) (*order.Order, error) {
+	req := esapi.GetRequest{
+		Index:      s.index,
+		DocumentID: id,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 404 {
+			return nil, order.ErrNotFound
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var gr orderGetResponse
+	if err := json.NewDecoder(res.Body).Decode(&gr); err != nil {
+		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	if !gr.Found {
+		return nil, order.ErrNotFound
+	}
+
+	return gr.Source.order(id), nil
+}
+
+func (s *OrderStorage) FindByKey(ctx context.Context, buyer string, key string) (*order.Order, error) {
+	return s.FindOne(ctx, orderID(buyer, key))
+}
+
+func (s *OrderStorage) Create(ctx context.Context, o order.Order) (*order.Order, error) {
+	b, err := json.Marshal(orderSource{
+		Product:        o.Product,
+		Variant:        o.Variant,
+		Buyer:          o.Buyer,
+		Seller:         o.Seller,
+		Quantity:       o.Quantity,
+		Price:          o.Price,
+		Total:          o.Total,
+		Status:         o.Status,
+		IdempotencyKey: o.IdempotencyKey,
+		CreatedAt:      o.CreatedAt,
+		IndexedAt:      time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Create fails if there is a document with the id.
+	req := esapi.CreateRequest{
+		Index:      s.index,
+		DocumentID: orderID(o.Buyer, o.IdempotencyKey),
+		Body:       bytes.NewReader(b),
+		Refresh:    refresh,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return nil, fmt.Errorf("making elasticsearch request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 409 {
+			return nil, order.ErrDuplicate
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var ir indexResponse
+	if err := json.NewDecoder(res.Body).Decode(&ir); err != nil {
+		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	o.ID = ir.ID
+	return &o, nil
+}
+
+func (s *OrderStorage) UpdateStatus(ctx context.Context, id string, status order.Status) (*order.Order, error) {
+	var buf bytes.Buffer
+	b := map[string]interface{}{
+		"doc": map[string]interface{}{"status": status},
+	}
+	err := json.NewEncoder(&buf).Encode(b)
+	if err != nil {
+		return nil, fmt.Errorf("encoding elasticsearch request: %w", err)
+	}
+
+	req := esapi.UpdateRequest{
+		Index:      s.index,
+		DocumentID: id,
+		Body:       &buf,
+		Source:     []string{"true"},
+		Refresh:    refresh,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return nil, fmt.Errorf("making elasticsearch request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 404 {
+			return nil, order.ErrNotFound
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var ur orderUpdateResponse
+	if err := json.NewDecoder(res.Body).Decode(&ur); err != nil {
+		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	return ur.Get.Source.order(ur.ID), nil
+}
+
+func (s *OrderStorage) Delete(ctx context.Context, id string) (*order.Order, error) {
+	o, err := s.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req := esapi.DeleteRequest{
+		Index:      s.index,
+		DocumentID: id,
+		Refresh:    refresh,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return nil, fmt.Errorf("making elasticsearch request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 404 {
+			return nil, order.ErrNotFound
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	return o, nil
+}
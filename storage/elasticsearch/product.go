@@ -4,10 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/elastic/go-elasticsearch/v7"
 	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/ortymid/market/market/media"
+	"github.com/ortymid/market/market/money"
 	"github.com/ortymid/market/market/product"
+	"sort"
+	"strings"
+	"time"
 )
 
 type indexResponse struct {
@@ -15,15 +21,11 @@ type indexResponse struct {
 	Result string `json:"result"`
 }
 
-type updateResponse struct {
-	ID     string      `json:"_id"`
-	Result string      `json:"result"`
-	Get    getResponse `json:"get"`
-}
-
 type getResponse struct {
-	Found  bool   `json:"found"`
-	Source source `json:"_source"`
+	Found       bool   `json:"found"`
+	SeqNo       int    `json:"_seq_no"`
+	PrimaryTerm int    `json:"_primary_term"`
+	Source      source `json:"_source"`
 }
 
 type searchResponse struct {
@@ -31,20 +33,96 @@ type searchResponse struct {
 }
 
 type hits struct {
-	Hits []hit `json:"hits"`
+	Total total `json:"total"`
+	Hits  []hit `json:"hits"`
+}
+
+// total is the number of hits. It is a lower bound if the relation is "gte",
+// as the hits are counted accurately up to track_total_hits only.
+type total struct {
+	Value    int64  `json:"value"`
+	Relation string `json:"relation"`
 }
 
 type hit struct {
-	ID     string `json:"_id"`
-	Source source `json:"_source"`
+	ID     string          `json:"_id"`
+	Source source          `json:"_source"`
+	Sort   json.RawMessage `json:"sort"`
 }
 
 type source struct {
-	Name   string `json:"name"`
-	Price  int64  `json:"price"`
-	Seller string `json:"seller"`
+	Name       string        `json:"name"`
+	Price      int64         `json:"price"` // amount in minor units of the currency
+	Currency   string        `json:"currency,omitempty"`
+	Seller     string        `json:"seller"`
+	Categories []string      `json:"categories,omitempty"`
+	Media      []media.Media `json:"media,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+
+	Attributes []product.Attribute `json:"attributes,omitempty"`
+	Variants   []product.Variant   `json:"variants,omitempty"`
+
+	Stock             int64 `json:"stock"`
+	Reserved          int64 `json:"reserved"`
+	LowStockThreshold int64 `json:"low_stock_threshold"`
+
+	Status    product.Status `json:"status,omitempty"`
+	PublishAt *time.Time     `json:"publish_at,omitempty"`
+
+	Rating product.Rating `json:"rating"`
+
+	Version   int64      `json:"version"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
+// product makes the product with the id from the source.
+func (src source) product(id string) *product.Product {
+	p := &product.Product{
+		ID:     id,
+		Name:   src.Name,
+		Price:  money.New(src.Price, src.Currency),
+		Seller: src.Seller,
+
+		Stock:             src.Stock,
+		Reserved:          src.Reserved,
+		LowStockThreshold: src.LowStockThreshold,
+
+		Status:    src.Status,
+		PublishAt: src.PublishAt,
+
+		Rating: src.Rating,
+
+		Version:   src.Version,
+		DeletedAt: src.DeletedAt,
+	}
+	if len(src.Categories) > 0 {
+		p.Categories = src.Categories
+	}
+	if len(src.Media) > 0 {
+		p.Media = src.Media
+	}
+	if len(src.Attributes) > 0 {
+		p.Attributes = src.Attributes
+	}
+	if len(src.Variants) > 0 {
+		p.Variants = src.Variants
+	}
+	// Products indexed before statuses were introduced are published.
+	if p.Status == "" {
+		p.Status = product.StatusPublished
+	}
+	// Products indexed before currencies were introduced are priced in the
+	// default currency.
+	if p.Price.Currency == "" {
+		p.Price.Currency = money.DefaultCurrency
+	}
+	return p
+}
+
+// refresh makes write requests wait until the changes are visible to search,
+// so products can be found right after they are written.
+const refresh = "wait_for"
+
 type ProductStorage struct {
 	es    *elasticsearch.Client
 	index string
@@ -54,12 +132,79 @@ func NewProductStorage(es *elasticsearch.Client, index string) *ProductStorage {
 	return &ProductStorage{es: es, index: index}
 }
 
-func (s *ProductStorage) Find(ctx context.Context, r product.FindRequest) ([]*product.Product, error) {
+// productMapping maps the variants as nested documents, so that a filter
+// matches the attribute values of one variant rather than of any of them.
+// The values are exact keywords. The rating average is mapped as a double, as
+// the zero rating of new products would be mapped as a long otherwise.
+const productMapping = `{
+	"dynamic_templates": [
+		{"variant_attributes": {"path_match": "variants.attributes.*", "mapping": {"type": "keyword"}}}
+	],
+	"properties": {
+		"variants": {"type": "nested"},
+		"rating": {"properties": {"average": {"type": "double"}, "count": {"type": "long"}}}
+	}
+}`
+
+// CreateMapping creates the index with the mapping of the variants, or adds
+// the mapping to the existing index. It must be called before the storage is
+// used, and fails if the index has variants mapped otherwise already.
+func (s *ProductStorage) CreateMapping(ctx context.Context) error {
+	req := esapi.IndicesCreateRequest{
+		Index: s.index,
+		Body:  strings.NewReader(`{"mappings": ` + productMapping + `}`),
+	}
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return fmt.Errorf("making elasticsearch request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if !res.IsError() {
+		return nil
+	}
+	if res.StatusCode != 400 {
+		return fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	// The index exists.
+	put := esapi.IndicesPutMappingRequest{
+		Index: []string{s.index},
+		Body:  strings.NewReader(productMapping),
+	}
+	res, err = put.Do(ctx, s.es)
+	if err != nil {
+		return fmt.Errorf("making elasticsearch request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+	return nil
+}
+
+func (s *ProductStorage) Find(ctx context.Context, r product.FindRequest) (*product.FindResult, error) {
+	// One more product tells whether there are more products.
+	size := r.Limit + 1
+	if r.Limit <= 0 {
+		size = 0
+	}
+
 	var body bytes.Buffer
 	bodyData := map[string]interface{}{
 		"query": makeSearchQuery(r),
-		"from":  r.Offset,
-		"size":  r.Limit,
+		"sort":  makeSort(r.Sort),
+		"size":  size,
+	}
+	if r.Cursor != "" {
+		after, err := decodeSearchAfter(r.Cursor, r.Sort)
+		if err != nil {
+			return nil, err
+		}
+		bodyData["search_after"] = after
+	} else {
+		bodyData["from"] = r.Offset
 	}
 	if err := json.NewEncoder(&body).Encode(bodyData); err != nil {
 		return nil, fmt.Errorf("encoding elasticsearch query: %w", err)
@@ -93,17 +238,19 @@ func (s *ProductStorage) Find(ctx context.Context, r product.FindRequest) ([]*pr
 
 	var ps []*product.Product
 	for _, hit := range sr.Hits.Hits {
-		p := &product.Product{
-			ID:     hit.ID,
-			Name:   hit.Source.Name,
-			Price:  hit.Source.Price,
-			Seller: hit.Source.Seller,
-		}
+		ps = append(ps, hit.Source.product(hit.ID))
+	}
 
-		ps = append(ps, p)
+	fr, err := product.MakeFindResult(ps, r.Sort, r.Limit, func(last int) interface{} {
+		return sr.Hits.Hits[last].Sort
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return ps, nil
+	fr.Total = sr.Hits.Total.Value
+	fr.TotalEstimated = sr.Hits.Total.Relation == "gte"
+	return fr, nil
 }
 
 func makeSearchQuery(r product.FindRequest) map[string]interface{} {
@@ -137,17 +284,45 @@ func makeSearchQuery(r product.FindRequest) map[string]interface{} {
 		q["bool"] = bl
 	}
 
-	if r.PriceRange != nil {
+	if ranges := r.Ranges(); len(ranges) > 0 {
 		match_all = false
 
-		pr := make(map[string]interface{})
-		if r.PriceRange.From != nil {
-			pr["gte"] = *r.PriceRange.From
+		bl, ok := q["bool"].(map[string]interface{})
+		if !ok {
+			bl = make(map[string]interface{})
+		}
+
+		filter, ok := bl["filter"].([]interface{})
+		if !ok {
+			filter = make([]interface{}, 0)
 		}
-		if r.PriceRange.To != nil {
-			pr["lte"] = *r.PriceRange.To
+
+		if len(ranges) == 1 {
+			filter = append(filter, priceRangeFilters(ranges[0])...)
+		} else {
+			should := make([]interface{}, 0, len(ranges))
+			for _, pr := range ranges {
+				should = append(should, map[string]interface{}{
+					"bool": map[string]interface{}{
+						"filter": priceRangeFilters(pr),
+					},
+				})
+			}
+			filter = append(filter, map[string]interface{}{
+				"bool": map[string]interface{}{
+					"should":               should,
+					"minimum_should_match": 1,
+				},
+			})
 		}
 
+		bl["filter"] = filter
+		q["bool"] = bl
+	}
+
+	if r.Seller != nil {
+		match_all = false
+
 		bl, ok := q["bool"].(map[string]interface{})
 		if !ok {
 			bl = make(map[string]interface{})
@@ -159,8 +334,8 @@ func makeSearchQuery(r product.FindRequest) map[string]interface{} {
 		}
 
 		f := map[string]interface{}{
-			"range": map[string]interface{}{
-				"price": pr,
+			"term": map[string]interface{}{
+				"seller.keyword": *r.Seller,
 			},
 		}
 
@@ -168,6 +343,200 @@ func makeSearchQuery(r product.FindRequest) map[string]interface{} {
 		q["bool"] = bl
 	}
 
+	if len(r.Categories) > 0 {
+		match_all = false
+
+		bl, ok := q["bool"].(map[string]interface{})
+		if !ok {
+			bl = make(map[string]interface{})
+		}
+
+		filter, ok := bl["filter"].([]interface{})
+		if !ok {
+			filter = make([]interface{}, 0)
+		}
+
+		f := map[string]interface{}{
+			"terms": map[string]interface{}{
+				"categories.keyword": r.Categories,
+			},
+		}
+
+		bl["filter"] = append(filter, f)
+		q["bool"] = bl
+	}
+
+	if len(r.Attributes) > 0 {
+		match_all = false
+
+		bl, ok := q["bool"].(map[string]interface{})
+		if !ok {
+			bl = make(map[string]interface{})
+		}
+
+		filter, ok := bl["filter"].([]interface{})
+		if !ok {
+			filter = make([]interface{}, 0)
+		}
+
+		f := map[string]interface{}{
+			"nested": map[string]interface{}{
+				"path": "variants",
+				"query": map[string]interface{}{
+					"bool": map[string]interface{}{
+						"filter": attributeFilters(r.Attributes),
+					},
+				},
+			},
+		}
+
+		bl["filter"] = append(filter, f)
+		q["bool"] = bl
+	}
+
+	if r.InStock != nil {
+		match_all = false
+
+		bl, ok := q["bool"].(map[string]interface{})
+		if !ok {
+			bl = make(map[string]interface{})
+		}
+
+		inStock := map[string]interface{}{
+			"bool": map[string]interface{}{
+				"should": []interface{}{
+					map[string]interface{}{
+						"range": map[string]interface{}{
+							"stock": map[string]interface{}{"gt": 0},
+						},
+					},
+					map[string]interface{}{
+						"nested": map[string]interface{}{
+							"path": "variants",
+							"query": map[string]interface{}{
+								"range": map[string]interface{}{
+									"variants.stock": map[string]interface{}{"gt": 0},
+								},
+							},
+						},
+					},
+				},
+				"minimum_should_match": 1,
+			},
+		}
+
+		// Products indexed before stock was introduced have no field, so
+		// products out of stock are selected as not in stock.
+		if *r.InStock {
+			filter, ok := bl["filter"].([]interface{})
+			if !ok {
+				filter = make([]interface{}, 0)
+			}
+			bl["filter"] = append(filter, inStock)
+		} else {
+			mustNot, ok := bl["must_not"].([]interface{})
+			if !ok {
+				mustNot = make([]interface{}, 0)
+			}
+			bl["must_not"] = append(mustNot, inStock)
+		}
+		q["bool"] = bl
+	}
+
+	if r.MinRating != nil {
+		match_all = false
+
+		bl, ok := q["bool"].(map[string]interface{})
+		if !ok {
+			bl = make(map[string]interface{})
+		}
+
+		filter, ok := bl["filter"].([]interface{})
+		if !ok {
+			filter = make([]interface{}, 0)
+		}
+
+		bl["filter"] = append(filter,
+			map[string]interface{}{
+				"range": map[string]interface{}{
+					"rating.count": map[string]interface{}{"gt": 0},
+				},
+			},
+			map[string]interface{}{
+				"range": map[string]interface{}{
+					"rating.average": map[string]interface{}{"gte": *r.MinRating},
+				},
+			},
+		)
+		q["bool"] = bl
+	}
+
+	if r.Status != nil {
+		match_all = false
+
+		bl, ok := q["bool"].(map[string]interface{})
+		if !ok {
+			bl = make(map[string]interface{})
+		}
+
+		filter, ok := bl["filter"].([]interface{})
+		if !ok {
+			filter = make([]interface{}, 0)
+		}
+
+		f := map[string]interface{}{
+			"term": map[string]interface{}{
+				"status.keyword": *r.Status,
+			},
+		}
+
+		// Products indexed before statuses were introduced have no field and
+		// are published.
+		if *r.Status == product.StatusPublished {
+			f = map[string]interface{}{
+				"bool": map[string]interface{}{
+					"should": []interface{}{
+						f,
+						map[string]interface{}{
+							"bool": map[string]interface{}{
+								"must_not": map[string]interface{}{
+									"exists": map[string]interface{}{"field": "status"},
+								},
+							},
+						},
+					},
+					"minimum_should_match": 1,
+				},
+			}
+		}
+
+		bl["filter"] = append(filter, f)
+		q["bool"] = bl
+	}
+
+	if !r.IncludeDeleted {
+		match_all = false
+
+		bl, ok := q["bool"].(map[string]interface{})
+		if !ok {
+			bl = make(map[string]interface{})
+		}
+
+		mustNot, ok := bl["must_not"].([]interface{})
+		if !ok {
+			mustNot = make([]interface{}, 0)
+		}
+
+		// Restored products have null deleted_at, which does not exist for
+		// the query.
+		deleted := map[string]interface{}{
+			"exists": map[string]interface{}{"field": "deleted_at"},
+		}
+
+		bl["must_not"] = append(mustNot, deleted)
+		q["bool"] = bl
+	}
+
 	if match_all {
 		q["match_all"] = map[string]interface{}{}
 	}
@@ -175,7 +544,172 @@ func makeSearchQuery(r product.FindRequest) map[string]interface{} {
 	return q
 }
 
+// attributeFilters makes the filters selecting variants with the attribute
+// values. The names are sorted, so that the query does not depend on the map
+// order.
+func attributeFilters(values map[string]string) []interface{} {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	filters := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{
+				"variants.attributes." + name: values[name],
+			},
+		})
+	}
+	return filters
+}
+
+// priceRangeFilters makes the filters selecting products priced within the
+// range.
+func priceRangeFilters(pr product.PriceRange) []interface{} {
+	limits := make(map[string]interface{})
+	if pr.From != nil {
+		limits["gte"] = *pr.From
+	}
+	if pr.To != nil {
+		limits["lte"] = *pr.To
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"range": map[string]interface{}{
+				"price": limits,
+			},
+		},
+		currencyFilter(pr.Currency),
+	}
+}
+
+// currencyFilter makes a filter of products priced in the currency.
+func currencyFilter(currency string) map[string]interface{} {
+	f := map[string]interface{}{
+		"term": map[string]interface{}{
+			"currency.keyword": currency,
+		},
+	}
+
+	// Products indexed before currencies were introduced have no field and
+	// are priced in the default currency.
+	if currency == money.DefaultCurrency {
+		f = map[string]interface{}{
+			"bool": map[string]interface{}{
+				"should": []interface{}{
+					f,
+					map[string]interface{}{
+						"bool": map[string]interface{}{
+							"must_not": map[string]interface{}{
+								"exists": map[string]interface{}{"field": "currency"},
+							},
+						},
+					},
+				},
+				"minimum_should_match": 1,
+			},
+		}
+	}
+	return f
+}
+
+// decodeSearchAfter decodes the sort values of the last hit of a page from
+// the cursor. The values are checked against the sort made by makeSort, so a
+// forged cursor is rejected with ErrValidation instead of failing the search.
+func decodeSearchAfter(cursor string, sorts []product.Sort) ([]json.RawMessage, error) {
+	var after []json.RawMessage
+	if err := product.DecodeCursor(cursor, sorts, &after); err != nil {
+		return nil, err
+	}
+
+	malformed := product.ErrValidation{Resource: product.Resource, Field: "cursor", Reason: "malformed"}
+	// The sort ends with the creation time and the id.
+	if len(after) != len(sorts)+2 {
+		return nil, malformed
+	}
+	for i, v := range after {
+		var ok bool
+		switch {
+		case i == len(after)-1:
+			ok = isJSONString(v)
+		case i < len(sorts) && sorts[i].Key == product.SortKeyName:
+			ok = isJSONString(v)
+		default:
+			var f float64
+			ok = json.Unmarshal(v, &f) == nil
+		}
+		if !ok {
+			return nil, malformed
+		}
+	}
+	return after, nil
+}
+
+// isJSONString reports whether the value is a JSON string.
+func isJSONString(v json.RawMessage) bool {
+	var s string
+	return json.Unmarshal(v, &s) == nil
+}
+
+// makeSort makes a sort for the sort keys. The creation time is used to break
+// ties, and the id is used to break ties of products created at the same time,
+// so every hit has a unique position for search_after.
+func makeSort(sorts []product.Sort) []interface{} {
+	srt := make([]interface{}, 0, len(sorts)+1)
+	for _, s := range sorts {
+		order := "asc"
+		if s.Desc {
+			order = "desc"
+		}
+
+		switch s.Key {
+		case product.SortKeyPrice:
+			srt = append(srt, map[string]interface{}{"price": order})
+		case product.SortKeyName:
+			srt = append(srt, map[string]interface{}{"name.keyword": order})
+		case product.SortKeyRating:
+			// Products indexed before ratings were introduced are unrated.
+			srt = append(srt, map[string]interface{}{"rating.average": map[string]interface{}{
+				"order":   order,
+				"missing": 0,
+			}})
+		case product.SortKeyCreated:
+			srt = append(srt, map[string]interface{}{"created_at": map[string]interface{}{
+				"order":         order,
+				"unmapped_type": "date",
+			}})
+		case product.SortKeyRelevance:
+			// The most relevant products go first in ascending order.
+			order := "desc"
+			if s.Desc {
+				order = "asc"
+			}
+			srt = append(srt, map[string]interface{}{"_score": order})
+		}
+	}
+
+	srt = append(srt, map[string]interface{}{"created_at": map[string]interface{}{
+		"order":         "asc",
+		"unmapped_type": "date",
+	}})
+	srt = append(srt, map[string]interface{}{"_id": "asc"})
+	return srt
+}
+
 func (s *ProductStorage) FindOne(ctx context.Context, id string) (*product.Product, error) {
+	gr, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return gr.Source.product(id), nil
+}
+
+// get gets the document with its sequence number and primary term.
+func (s *ProductStorage) get(ctx context.Context, id string) (*getResponse, error) {
 	req := esapi.GetRequest{
 		Index:      s.index,
 		DocumentID: id,
@@ -203,24 +737,45 @@ func (s *ProductStorage) FindOne(ctx context.Context, id string) (*product.Produ
 		return nil, product.ErrNotFound
 	}
 
-	p := &product.Product{
-		ID:     id,
-		Name:   gr.Source.Name,
-		Price:  gr.Source.Price,
-		Seller: gr.Source.Seller,
-	}
-	return p, nil
+	return &gr, nil
 }
 
 func (s *ProductStorage) Create(ctx context.Context, r product.CreateRequest) (*product.Product, error) {
-	b, err := json.Marshal(r)
+	if r.Status == "" {
+		r.Status = product.StatusPublished
+	}
+	if r.PublishAt != nil {
+		at := r.PublishAt.UTC()
+		r.PublishAt = &at
+	}
+
+	b, err := json.Marshal(source{
+		Name:       r.Name,
+		Price:      r.Price.Amount,
+		Currency:   r.Price.Currency,
+		Seller:     r.Seller,
+		Categories: r.Categories,
+		CreatedAt:  time.Now().UTC(),
+
+		Attributes: r.Attributes,
+		Variants:   r.Variants,
+
+		Stock:             r.Stock,
+		LowStockThreshold: r.LowStockThreshold,
+
+		Status:    r.Status,
+		PublishAt: r.PublishAt,
+
+		Version: 1,
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	req := esapi.IndexRequest{
-		Index: s.index,
-		Body:  bytes.NewReader(b),
+		Index:   s.index,
+		Body:    bytes.NewReader(b),
+		Refresh: refresh,
 	}
 
 	res, err := req.Do(ctx, s.es)
@@ -247,14 +802,164 @@ func (s *ProductStorage) Create(ctx context.Context, r product.CreateRequest) (*
 		Name:   r.Name,
 		Price:  r.Price,
 		Seller: r.Seller,
+
+		Stock:             r.Stock,
+		LowStockThreshold: r.LowStockThreshold,
+
+		Status:    r.Status,
+		PublishAt: r.PublishAt,
+
+		Version: 1,
+	}
+	if len(r.Categories) > 0 {
+		p.Categories = r.Categories
+	}
+	if len(r.Attributes) > 0 {
+		p.Attributes = r.Attributes
+	}
+	if len(r.Variants) > 0 {
+		p.Variants = r.Variants
 	}
 	return p, nil
 }
 
+// Update changes the product with optimistic concurrency control the same
+// way UpdateStock does. The expected version is checked against every read of
+// the document, so a concurrent update makes a conditional one fail.
 func (s *ProductStorage) Update(ctx context.Context, r product.UpdateRequest) (*product.Product, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		gr, err := s.get(ctx, r.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		src := gr.Source
+		if src.DeletedAt != nil {
+			return nil, product.ErrNotFound
+		}
+		if r.ExpectedVersion != nil && *r.ExpectedVersion != src.Version {
+			return nil, product.ErrConflict
+		}
+
+		doc := map[string]interface{}{}
+		if r.Name != nil {
+			src.Name = *r.Name
+			doc["name"] = src.Name
+		}
+		if r.Price != nil {
+			src.Price = r.Price.Amount
+			src.Currency = r.Price.Currency
+			doc["price"] = src.Price
+			doc["currency"] = src.Currency
+		}
+		if r.Categories != nil {
+			src.Categories = *r.Categories
+			doc["categories"] = src.Categories
+		}
+		if r.Media != nil {
+			src.Media = *r.Media
+			doc["media"] = src.Media
+		}
+		if r.Attributes != nil {
+			src.Attributes = *r.Attributes
+			doc["attributes"] = src.Attributes
+		}
+		if r.Variants != nil {
+			src.Variants = *r.Variants
+			doc["variants"] = src.Variants
+		}
+		if r.LowStockThreshold != nil {
+			src.LowStockThreshold = *r.LowStockThreshold
+			doc["low_stock_threshold"] = src.LowStockThreshold
+		}
+		if r.PublishAt != nil {
+			at := r.PublishAt.UTC()
+			src.PublishAt = &at
+			doc["publish_at"] = src.PublishAt
+		}
+		if r.Status != nil {
+			src.Status = *r.Status
+			doc["status"] = src.Status
+			if src.Status != product.StatusDraft {
+				src.PublishAt = nil
+				doc["publish_at"] = nil
+			}
+		}
+		src.Version++
+		doc["version"] = src.Version
+
+		p, err := s.updateSource(ctx, r.ID, gr, doc, src)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			return p, nil
+		}
+	}
+}
+
+// UpdateStock changes the stock counters of the document or of its variant
+// with optimistic concurrency control.
+// The document is updated only if it is not changed since it was read, and
+// the update is retried otherwise. A conflict means a concurrent update
+// succeeded, so retries make progress.
+func (s *ProductStorage) UpdateStock(ctx context.Context, r product.StockRequest) (*product.Product, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		gr, err := s.get(ctx, r.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		src := gr.Source
+		if src.DeletedAt != nil {
+			return nil, product.ErrNotFound
+		}
+		doc := map[string]interface{}{}
+		stock := &src.Stock
+		if r.Variant != "" {
+			stock = nil
+			for i := range src.Variants {
+				if src.Variants[i].SKU == r.Variant {
+					stock = &src.Variants[i].Stock
+				}
+			}
+			if stock == nil {
+				return nil, product.ErrVariantNotFound
+			}
+			doc["variants"] = src.Variants
+		}
+		*stock += r.Stock
+		src.Reserved += r.Reserved
+		if *stock < 0 || src.Reserved < 0 {
+			return nil, product.ErrInsufficientStock
+		}
+		doc["stock"] = src.Stock
+		doc["reserved"] = src.Reserved
+		p, err := s.updateSource(ctx, r.ID, gr, doc, src)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			return p, nil
+		}
+	}
+}
+
+// updateSource stores the changed fields of the source given in the doc if
+// the document is not changed since the get response. It returns nil product
+// on a sequence number conflict.
+func (s *ProductStorage) updateSource(ctx context.Context, id string, gr *getResponse, doc map[string]interface{}, src source) (*product.Product, error) {
 	var buf bytes.Buffer
 	b := map[string]interface{}{
-		"doc": r,
+		"doc": doc,
 	}
 	err := json.NewEncoder(&buf).Encode(b)
 	if err != nil {
@@ -262,60 +967,368 @@ func (s *ProductStorage) Update(ctx context.Context, r product.UpdateRequest) (*
 	}
 
 	req := esapi.UpdateRequest{
-		Index:          s.index,
-		DocumentID:     r.ID,
-		Body:           &buf,
-		SourceIncludes: []string{"name", "price", "seller"},
+		Index:         s.index,
+		DocumentID:    id,
+		Body:          &buf,
+		IfSeqNo:       &gr.SeqNo,
+		IfPrimaryTerm: &gr.PrimaryTerm,
+		Refresh:       refresh,
 	}
 
 	res, err := req.Do(ctx, s.es)
 	if err != nil {
 		return nil, fmt.Errorf("making elasticsearch request: %w", err)
 	}
+	defer res.Body.Close()
 
 	if res.IsError() {
-		if res.StatusCode == 404 {
+		switch res.StatusCode {
+		case 404:
 			return nil, product.ErrNotFound
+		case 409:
+			return nil, nil
 		}
 		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
 	}
 
-	var ur updateResponse
-	if err := json.NewDecoder(res.Body).Decode(&ur); err != nil {
+	return src.product(id), nil
+}
+
+// sellersResponse is the search response with the aggregations of
+// SummarizeSellers.
+type sellersResponse struct {
+	Aggregations struct {
+		Sellers struct {
+			Buckets []struct {
+				Key         string   `json:"key"`
+				DocCount    int64    `json:"doc_count"`
+				RatingSum   aggValue `json:"rating_sum"`
+				RatingCount aggValue `json:"rating_count"`
+			} `json:"buckets"`
+		} `json:"sellers"`
+	} `json:"aggregations"`
+}
+
+type aggValue struct {
+	Value float64 `json:"value"`
+}
+
+// SummarizeSellers aggregates the published products by seller. Products
+// indexed before ratings were introduced are counted as unrated.
+func (s *ProductStorage) SummarizeSellers(ctx context.Context, sellers []string) (map[string]product.SellerSummary, error) {
+	summaries := make(map[string]product.SellerSummary)
+	if len(sellers) == 0 {
+		return summaries, nil
+	}
+
+	published := product.StatusPublished
+	q := makeSearchQuery(product.FindRequest{Status: &published})
+	bl, ok := q["bool"].(map[string]interface{})
+	if !ok {
+		bl = make(map[string]interface{})
+	}
+	filter, ok := bl["filter"].([]interface{})
+	if !ok {
+		filter = make([]interface{}, 0)
+	}
+	bl["filter"] = append(filter, map[string]interface{}{
+		"terms": map[string]interface{}{"seller.keyword": sellers},
+	})
+	q["bool"] = bl
+
+	var body bytes.Buffer
+	bodyData := map[string]interface{}{
+		"query": q,
+		"size":  0,
+		"aggs": map[string]interface{}{
+			"sellers": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "seller.keyword", "size": len(sellers)},
+				"aggs": map[string]interface{}{
+					"rating_sum": map[string]interface{}{
+						"sum": map[string]interface{}{
+							"script": map[string]interface{}{
+								"source": "doc['rating.count'].size() == 0 ? 0 : doc['rating.average'].value * doc['rating.count'].value",
+							},
+						},
+					},
+					"rating_count": map[string]interface{}{
+						"sum": map[string]interface{}{"field": "rating.count"},
+					},
+				},
+			},
+		},
+	}
+	if err := json.NewEncoder(&body).Encode(bodyData); err != nil {
+		return nil, fmt.Errorf("encoding elasticsearch query: %w", err)
+	}
+
+	res, err := s.es.Search(
+		s.es.Search.WithContext(ctx),
+		s.es.Search.WithIndex(s.index),
+		s.es.Search.WithBody(&body),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("searching: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var sr sellersResponse
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
 		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
 	}
 
-	p := &product.Product{
-		ID:     ur.ID,
-		Name:   ur.Get.Source.Name,
-		Price:  ur.Get.Source.Price,
-		Seller: ur.Get.Source.Seller,
+	for _, b := range sr.Aggregations.Sellers.Buckets {
+		summaries[b.Key] = product.NewSellerSummary(b.DocCount, b.RatingSum.Value, int64(b.RatingCount.Value))
+	}
+	return summaries, nil
+}
+
+// UpdateRating sets the rating of the document, deleted or not, with
+// optimistic concurrency control the same way UpdateStock does.
+func (s *ProductStorage) UpdateRating(ctx context.Context, id string, rating product.Rating) (*product.Product, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		gr, err := s.get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		src := gr.Source
+		src.Rating = rating
+
+		doc := map[string]interface{}{"rating": src.Rating}
+		p, err := s.updateSource(ctx, id, gr, doc, src)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			return p, nil
+		}
+	}
+}
+
+// Delete sets the deletion time of the document with optimistic concurrency
+// control the same way UpdateStock does.
+func (s *ProductStorage) Delete(ctx context.Context, id string, at time.Time) (*product.Product, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		gr, err := s.get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		src := gr.Source
+		if src.DeletedAt != nil {
+			return nil, product.ErrNotFound
+		}
+		at := at.UTC()
+		src.DeletedAt = &at
+
+		doc := map[string]interface{}{"deleted_at": src.DeletedAt}
+		p, err := s.updateSource(ctx, id, gr, doc, src)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			return p, nil
+		}
 	}
-	return p, nil
 }
 
-func (s *ProductStorage) Delete(ctx context.Context, id string) (*product.Product, error) {
-	p, err := s.FindOne(ctx, id)
+// Restore sets the deletion time of the document to null with optimistic
+// concurrency control the same way UpdateStock does.
+func (s *ProductStorage) Restore(ctx context.Context, id string) (*product.Product, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		gr, err := s.get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		src := gr.Source
+		if src.DeletedAt == nil {
+			return nil, product.ErrNotFound
+		}
+		src.DeletedAt = nil
+
+		doc := map[string]interface{}{"deleted_at": nil}
+		p, err := s.updateSource(ctx, id, gr, doc, src)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			return p, nil
+		}
+	}
+}
+
+// publishBatch is the maximum number of drafts published at once, the rest
+// are published by the next call.
+const publishBatch = 1000
+
+// PublishDue searches the drafts and publishes them one by one with
+// optimistic concurrency control the same way UpdateStock does.
+func (s *ProductStorage) PublishDue(ctx context.Context, now time.Time) ([]*product.Product, error) {
+	var body bytes.Buffer
+	q := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{
+						"term": map[string]interface{}{"status.keyword": product.StatusDraft},
+					},
+					map[string]interface{}{
+						"range": map[string]interface{}{
+							"publish_at": map[string]interface{}{"lte": now.UTC()},
+						},
+					},
+				},
+				"must_not": []interface{}{
+					map[string]interface{}{
+						"exists": map[string]interface{}{"field": "deleted_at"},
+					},
+				},
+			},
+		},
+		"size":    publishBatch,
+		"_source": false,
+	}
+	if err := json.NewEncoder(&body).Encode(q); err != nil {
+		return nil, fmt.Errorf("encoding elasticsearch query: %w", err)
+	}
+
+	res, err := s.es.Search(
+		s.es.Search.WithContext(ctx),
+		s.es.Search.WithIndex(s.index),
+		s.es.Search.WithBody(&body),
+	)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("searching: %w", err)
 	}
+	defer res.Body.Close()
 
-	req := esapi.DeleteRequest{
-		Index:      s.index,
-		DocumentID: id,
+	if res.IsError() {
+		if res.StatusCode == 404 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var sr searchResponse
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
+		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	var ps []*product.Product
+	for _, hit := range sr.Hits.Hits {
+		p, err := s.publish(ctx, hit.ID, now)
+		if err != nil {
+			return ps, err
+		}
+		if p != nil {
+			ps = append(ps, p)
+		}
+	}
+
+	return ps, nil
+}
+
+// publish publishes the draft if it is due. It returns nil product if the
+// draft has changed and is not due anymore.
+func (s *ProductStorage) publish(ctx context.Context, id string, now time.Time) (*product.Product, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		gr, err := s.get(ctx, id)
+		if errors.Is(err, product.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		src := gr.Source
+		if src.DeletedAt != nil || src.Status != product.StatusDraft || src.PublishAt == nil || src.PublishAt.After(now) {
+			return nil, nil
+		}
+		src.Status = product.StatusPublished
+		src.PublishAt = nil
+		src.Version++
+
+		doc := map[string]interface{}{
+			"status":     src.Status,
+			"publish_at": nil,
+			"version":    src.Version,
+		}
+		p, err := s.updateSource(ctx, id, gr, doc, src)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			return p, nil
+		}
+	}
+}
+
+type deleteByQueryResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// Purge deletes the documents by query. Documents changed while the query
+// runs, e.g. restored, are skipped as conflicts.
+func (s *ProductStorage) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var body bytes.Buffer
+	q := map[string]interface{}{
+		"query": map[string]interface{}{
+			"range": map[string]interface{}{
+				"deleted_at": map[string]interface{}{"lt": before.UTC()},
+			},
+		},
+	}
+	if err := json.NewEncoder(&body).Encode(q); err != nil {
+		return 0, fmt.Errorf("encoding elasticsearch query: %w", err)
+	}
+
+	refresh := true
+	req := esapi.DeleteByQueryRequest{
+		Index:     []string{s.index},
+		Body:      &body,
+		Conflicts: "proceed",
+		Refresh:   &refresh,
 	}
 
 	res, err := req.Do(ctx, s.es)
 	if err != nil {
-		return nil, fmt.Errorf("making elasticsearch request: %w", err)
+		return 0, fmt.Errorf("making elasticsearch request: %w", err)
 	}
+	defer res.Body.Close()
 
 	if res.IsError() {
 		if res.StatusCode == 404 {
-			return nil, product.ErrNotFound
+			return 0, nil
 		}
-		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+		return 0, fmt.Errorf("elasticsearch: %s", res.Status())
 	}
 
-	return p, nil
+	var dr deleteByQueryResponse
+	if err := json.NewDecoder(res.Body).Decode(&dr); err != nil {
+		return 0, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	return dr.Deleted, nil
 }
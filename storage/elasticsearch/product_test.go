@@ -1,11 +1,49 @@
 package elasticsearch
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v7"
 	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/storage/storagetest"
+	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
+// TestProductStorage_Conformance runs against a real cluster, it is skipped
+// if MARKET_TEST_ELASTICSEARCH_URL is not set.
+func TestProductStorage_Conformance(t *testing.T) {
+	url := os.Getenv("MARKET_TEST_ELASTICSEARCH_URL")
+	if len(url) == 0 {
+		t.Skip("MARKET_TEST_ELASTICSEARCH_URL is not set")
+	}
+
+	es, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{url}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.TestProductStorage(t, func(t *testing.T) product.Storage {
+		index := fmt.Sprintf("products_test_%d", time.Now().UnixNano())
+		t.Cleanup(func() {
+			res, err := es.Indices.Delete([]string{index}, es.Indices.Delete.WithContext(context.Background()))
+			if err == nil {
+				res.Body.Close()
+			}
+		})
+
+		s := NewProductStorage(es, index)
+		if err := s.CreateMapping(context.Background()); err != nil {
+			t.Fatalf("creating mapping: %v", err)
+		}
+		return s
+	})
+}
+
 func Test_makeSearchQuery(t *testing.T) {
 	type args struct {
 		r product.FindRequest
@@ -18,11 +56,12 @@ func Test_makeSearchQuery(t *testing.T) {
 		{
 			name: "Should make query with name",
 			args: args{r: product.FindRequest{
-				Offset:     0,
-				Limit:      10,
-				Name:       testPtrString("name"),
-				PriceRange: nil,
-				Seller:     nil,
+				Offset:         0,
+				Limit:          10,
+				IncludeDeleted: true,
+				Name:           testPtrString("name"),
+				PriceRange:     nil,
+				Seller:         nil,
 			}},
 			want: map[string]interface{}{
 				"bool": map[string]interface{}{
@@ -42,12 +81,14 @@ func Test_makeSearchQuery(t *testing.T) {
 		{
 			name: "Should make query with price range",
 			args: args{r: product.FindRequest{
-				Offset: 0,
-				Limit:  10,
-				Name:   nil,
+				Offset:         0,
+				Limit:          10,
+				IncludeDeleted: true,
+				Name:           nil,
 				PriceRange: &product.PriceRange{
-					From: testPtrInt64(10),
-					To:   testPtrInt64(100),
+					From:     testPtrInt64(10),
+					To:       testPtrInt64(100),
+					Currency: "EUR",
 				},
 				Seller: nil,
 			}},
@@ -62,6 +103,285 @@ func Test_makeSearchQuery(t *testing.T) {
 								},
 							},
 						},
+						map[string]interface{}{
+							"term": map[string]interface{}{
+								"currency.keyword": "EUR",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Should make query with price range in default currency",
+			args: args{r: product.FindRequest{
+				Limit:          10,
+				IncludeDeleted: true,
+				PriceRange: &product.PriceRange{
+					From:     testPtrInt64(10),
+					Currency: "USD",
+				},
+			}},
+			want: map[string]interface{}{
+				"bool": map[string]interface{}{
+					"filter": []interface{}{
+						map[string]interface{}{
+							"range": map[string]interface{}{
+								"price": map[string]interface{}{
+									"gte": int64(10),
+								},
+							},
+						},
+						map[string]interface{}{
+							"bool": map[string]interface{}{
+								"should": []interface{}{
+									map[string]interface{}{
+										"term": map[string]interface{}{
+											"currency.keyword": "USD",
+										},
+									},
+									map[string]interface{}{
+										"bool": map[string]interface{}{
+											"must_not": map[string]interface{}{
+												"exists": map[string]interface{}{"field": "currency"},
+											},
+										},
+									},
+								},
+								"minimum_should_match": 1,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Should make query with any of price ranges",
+			args: args{r: product.FindRequest{
+				Limit:          10,
+				IncludeDeleted: true,
+				PriceRange:     &product.PriceRange{To: testPtrInt64(100), Currency: "EUR"},
+				PriceRanges:    []product.PriceRange{{From: testPtrInt64(10), Currency: "JPY"}},
+			}},
+			want: map[string]interface{}{
+				"bool": map[string]interface{}{
+					"filter": []interface{}{
+						map[string]interface{}{
+							"bool": map[string]interface{}{
+								"should": []interface{}{
+									map[string]interface{}{
+										"bool": map[string]interface{}{
+											"filter": []interface{}{
+												map[string]interface{}{
+													"range": map[string]interface{}{
+														"price": map[string]interface{}{"lte": int64(100)},
+													},
+												},
+												map[string]interface{}{
+													"term": map[string]interface{}{"currency.keyword": "EUR"},
+												},
+											},
+										},
+									},
+									map[string]interface{}{
+										"bool": map[string]interface{}{
+											"filter": []interface{}{
+												map[string]interface{}{
+													"range": map[string]interface{}{
+														"price": map[string]interface{}{"gte": int64(10)},
+													},
+												},
+												map[string]interface{}{
+													"term": map[string]interface{}{"currency.keyword": "JPY"},
+												},
+											},
+										},
+									},
+								},
+								"minimum_should_match": 1,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Should make query with seller",
+			args: args{r: product.FindRequest{
+				Offset:         0,
+				Limit:          10,
+				IncludeDeleted: true,
+				Name:           nil,
+				PriceRange:     nil,
+				Seller:         testPtrString("1"),
+			}},
+			want: map[string]interface{}{
+				"bool": map[string]interface{}{
+					"filter": []interface{}{
+						map[string]interface{}{
+							"term": map[string]interface{}{
+								"seller.keyword": "1",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Should make query with categories",
+			args: args{r: product.FindRequest{
+				Offset:         0,
+				Limit:          10,
+				IncludeDeleted: true,
+				Categories:     []string{"1", "2"},
+			}},
+			want: map[string]interface{}{
+				"bool": map[string]interface{}{
+					"filter": []interface{}{
+						map[string]interface{}{
+							"terms": map[string]interface{}{
+								"categories.keyword": []string{"1", "2"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Should make query with attribute values of one variant",
+			args: args{r: product.FindRequest{
+				Offset:         0,
+				Limit:          10,
+				IncludeDeleted: true,
+				Attributes:     map[string]string{"size": "42", "color": "red"},
+			}},
+			want: map[string]interface{}{
+				"bool": map[string]interface{}{
+					"filter": []interface{}{
+						map[string]interface{}{
+							"nested": map[string]interface{}{
+								"path": "variants",
+								"query": map[string]interface{}{
+									"bool": map[string]interface{}{
+										"filter": []interface{}{
+											map[string]interface{}{
+												"term": map[string]interface{}{"variants.attributes.color": "red"},
+											},
+											map[string]interface{}{
+												"term": map[string]interface{}{"variants.attributes.size": "42"},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Should make query with products out of stock",
+			args: args{r: product.FindRequest{
+				Offset:         0,
+				Limit:          10,
+				IncludeDeleted: true,
+				InStock:        testPtrBool(false),
+			}},
+			want: map[string]interface{}{
+				"bool": map[string]interface{}{
+					"must_not": []interface{}{
+						map[string]interface{}{
+							"bool": map[string]interface{}{
+								"should": []interface{}{
+									map[string]interface{}{
+										"range": map[string]interface{}{
+											"stock": map[string]interface{}{"gt": 0},
+										},
+									},
+									map[string]interface{}{
+										"nested": map[string]interface{}{
+											"path": "variants",
+											"query": map[string]interface{}{
+												"range": map[string]interface{}{
+													"variants.stock": map[string]interface{}{"gt": 0},
+												},
+											},
+										},
+									},
+								},
+								"minimum_should_match": 1,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Should make query with draft products",
+			args: args{r: product.FindRequest{
+				Offset:         0,
+				Limit:          10,
+				IncludeDeleted: true,
+				Status:         testPtrStatus(product.StatusDraft),
+			}},
+			want: map[string]interface{}{
+				"bool": map[string]interface{}{
+					"filter": []interface{}{
+						map[string]interface{}{
+							"term": map[string]interface{}{
+								"status.keyword": product.StatusDraft,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Should make query with published products indexed without status",
+			args: args{r: product.FindRequest{
+				Offset:         0,
+				Limit:          10,
+				IncludeDeleted: true,
+				Status:         testPtrStatus(product.StatusPublished),
+			}},
+			want: map[string]interface{}{
+				"bool": map[string]interface{}{
+					"filter": []interface{}{
+						map[string]interface{}{
+							"bool": map[string]interface{}{
+								"should": []interface{}{
+									map[string]interface{}{
+										"term": map[string]interface{}{
+											"status.keyword": product.StatusPublished,
+										},
+									},
+									map[string]interface{}{
+										"bool": map[string]interface{}{
+											"must_not": map[string]interface{}{
+												"exists": map[string]interface{}{"field": "status"},
+											},
+										},
+									},
+								},
+								"minimum_should_match": 1,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Should make query excluding deleted products",
+			args: args{r: product.FindRequest{
+				Offset: 0,
+				Limit:  10,
+			}},
+			want: map[string]interface{}{
+				"bool": map[string]interface{}{
+					"must_not": []interface{}{
+						map[string]interface{}{
+							"exists": map[string]interface{}{"field": "deleted_at"},
+						},
 					},
 				},
 			},
@@ -83,3 +403,68 @@ func testPtrString(v string) *string {
 func testPtrInt64(v int64) *int64 {
 	return &v
 }
+
+func testPtrBool(v bool) *bool {
+	return &v
+}
+
+func testPtrStatus(v product.Status) *product.Status {
+	return &v
+}
+
+func Test_decodeSearchAfter(t *testing.T) {
+	sorts := []product.Sort{{Key: product.SortKeyName}, {Key: product.SortKeyPrice, Desc: true}}
+	tests := []struct {
+		name    string
+		values  interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "Should decode values of the sort",
+			values: []interface{}{"apple", 100, 1601554200000, "id"},
+			want:   `["apple",100,1601554200000,"id"]`,
+		},
+		{
+			name:    "Should error for missing values",
+			values:  []interface{}{"apple", 100, 1601554200000},
+			wantErr: true,
+		},
+		{
+			name:    "Should error for values of wrong types",
+			values:  []interface{}{100, "apple", 1601554200000, "id"},
+			wantErr: true,
+		},
+		{
+			name:    "Should error for values not in a list",
+			values:  map[string]interface{}{"id": "1"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := product.EncodeCursor(sorts, tt.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := decodeSearchAfter(c, sorts)
+			if tt.wantErr {
+				if !errors.As(err, &product.ErrValidation{}) {
+					t.Errorf("decodeSearchAfter() error = %v, want product.ErrValidation", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeSearchAfter() error = %v", err)
+			}
+			b, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("decodeSearchAfter() got = %s, want %s", b, tt.want)
+			}
+		})
+	}
+}
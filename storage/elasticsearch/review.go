@@ -0,0 +1,320 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v7"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/ortymid/market/market/review"
+	"time"
+)
+
+type reviewSource struct {
+	Product   string    `json:"product"`
+	Author    string    `json:"author"`
+	Rating    int64     `json:"rating"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// IndexedAt is the time the document is indexed, it keeps the order of
+	// creation.
+	IndexedAt time.Time `json:"indexed_at"`
+}
+
+// review makes the review with the id from the source.
+func (src reviewSource) review(id string) *review.Review {
+	return &review.Review{
+		ID:        id,
+		Product:   src.Product,
+		Author:    src.Author,
+		Rating:    src.Rating,
+		Text:      src.Text,
+		CreatedAt: src.CreatedAt,
+		UpdatedAt: src.UpdatedAt,
+	}
+}
+
+type reviewSearchResponse struct {
+	Hits struct {
+		Total total `json:"total"`
+		Hits  []struct {
+			ID     string       `json:"_id"`
+			Source reviewSource `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+	Aggregations struct {
+		Sum struct {
+			Value float64 `json:"value"`
+		} `json:"sum"`
+	} `json:"aggregations"`
+}
+
+type reviewGetResponse struct {
+	Found  bool         `json:"found"`
+	Source reviewSource `json:"_source"`
+}
+
+type reviewUpdateResponse struct {
+	ID  string            `json:"_id"`
+	Get reviewGetResponse `json:"get"`
+}
+
+// ReviewStorage keeps reviews in an index. Ids of the documents are derived
+// from the product and the author, so the index rejects duplicates.
+type ReviewStorage struct {
+	es    *elasticsearch.Client
+	index string
+}
+
+func NewReviewStorage(es *elasticsearch.Client, index string) *ReviewStorage {
+	return &ReviewStorage{es: es, index: index}
+}
+
+// reviewID returns the id of the review of the product by the author.
+func reviewID(product string, author string) string {
+	sum := sha256.Sum256([]byte(product + "\x00" + author))
+	return hex.EncodeToString(sum[:16])
+}
+
+func (s *ReviewStorage) Find(ctx context.Context, r review.FindRequest) ([]*review.Review, error) {
+	var filter []interface{}
+	if r.Product != nil {
+		filter = append(filter, map[string]interface{}{
+			"term": map[string]interface{}{"product.keyword": *r.Product},
+		})
+	}
+	if r.Author != nil {
+		filter = append(filter, map[string]interface{}{
+			"term": map[string]interface{}{"author.keyword": *r.Author},
+		})
+	}
+
+	query := map[string]interface{}{"match_all": map[string]interface{}{}}
+	if len(filter) > 0 {
+		query = map[string]interface{}{"bool": map[string]interface{}{"filter": filter}}
+	}
+
+	sr, err := s.search(ctx, map[string]interface{}{
+		"query": query,
+		"sort": []interface{}{
+			map[string]interface{}{"indexed_at": "asc"},
+			map[string]interface{}{"_id": "asc"},
+		},
+		"from": r.Offset,
+		"size": r.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rvs := make([]*review.Review, 0, len(sr.Hits.Hits))
+	for _, hit := range sr.Hits.Hits {
+		rvs = append(rvs, hit.Source.review(hit.ID))
+	}
+
+	return rvs, nil
+}
+
+// search runs the query. The index is created with the first review, so a
+// missing index gives an empty response.
+func (s *ReviewStorage) search(ctx context.Context, q map[string]interface{}) (*reviewSearchResponse, error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(q); err != nil {
+		return nil, fmt.Errorf("encoding elasticsearch query: %w", err)
+	}
+
+	res, err := s.es.Search(
+		s.es.Search.WithContext(ctx),
+		s.es.Search.WithIndex(s.index),
+		s.es.Search.WithBody(&body),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("searching: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 404 {
+			return &reviewSearchResponse{}, nil
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var sr reviewSearchResponse
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
+		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	return &sr, nil
+}
+
+func (s *ReviewStorage) FindOne(ctx context.Context, id string) (*review.Review, error) {
+	req := esapi.GetRequest{
+		Index:      s.index,
+		DocumentID: id,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 404 {
+			return nil, review.ErrNotFound
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var gr reviewGetResponse
+	if err := json.NewDecoder(res.Body).Decode(&gr); err != nil {
+		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	if !gr.Found {
+		return nil, review.ErrNotFound
+	}
+
+	return gr.Source.review(id), nil
+}
+
+func (s *ReviewStorage) Create(ctx context.Context, rv review.Review) (*review.Review, error) {
+	b, err := json.Marshal(reviewSource{
+		Product:   rv.Product,
+		Author:    rv.Author,
+		Rating:    rv.Rating,
+		Text:      rv.Text,
+		CreatedAt: rv.CreatedAt,
+		UpdatedAt: rv.UpdatedAt,
+		IndexedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Create fails if there is a document with the id.
+	req := esapi.CreateRequest{
+		Index:      s.index,
+		DocumentID: reviewID(rv.Product, rv.Author),
+		Body:       bytes.NewReader(b),
+		Refresh:    refresh,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return nil, fmt.Errorf("making elasticsearch request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 409 {
+			return nil, review.ErrDuplicate
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var ir indexResponse
+	if err := json.NewDecoder(res.Body).Decode(&ir); err != nil {
+		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	rv.ID = ir.ID
+	return &rv, nil
+}
+
+func (s *ReviewStorage) Update(ctx context.Context, r review.UpdateRequest, at time.Time) (*review.Review, error) {
+	doc := map[string]interface{}{"updated_at": at.UTC()}
+	if r.Rating != nil {
+		doc["rating"] = *r.Rating
+	}
+	if r.Text != nil {
+		doc["text"] = *r.Text
+	}
+
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(map[string]interface{}{"doc": doc})
+	if err != nil {
+		return nil, fmt.Errorf("encoding elasticsearch request: %w", err)
+	}
+
+	req := esapi.UpdateRequest{
+		Index:      s.index,
+		DocumentID: r.ID,
+		Body:       &buf,
+		Source:     []string{"true"},
+		Refresh:    refresh,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return nil, fmt.Errorf("making elasticsearch request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 404 {
+			return nil, review.ErrNotFound
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	var ur reviewUpdateResponse
+	if err := json.NewDecoder(res.Body).Decode(&ur); err != nil {
+		return nil, fmt.Errorf("parsing elasticseach response body: %w", err)
+	}
+
+	return ur.Get.Source.review(ur.ID), nil
+}
+
+func (s *ReviewStorage) Delete(ctx context.Context, id string) (*review.Review, error) {
+	rv, err := s.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req := esapi.DeleteRequest{
+		Index:      s.index,
+		DocumentID: id,
+		Refresh:    refresh,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return nil, fmt.Errorf("making elasticsearch request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 404 {
+			return nil, review.ErrNotFound
+		}
+		return nil, fmt.Errorf("elasticsearch: %s", res.Status())
+	}
+
+	return rv, nil
+}
+
+// Summarize counts the reviews of the product exactly and sums their ratings
+// with an aggregation.
+func (s *ReviewStorage) Summarize(ctx context.Context, product string) (review.Summary, error) {
+	sr, err := s.search(ctx, map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{"product.keyword": product},
+		},
+		"aggs": map[string]interface{}{
+			"sum": map[string]interface{}{"sum": map[string]interface{}{"field": "rating"}},
+		},
+		"size":             0,
+		"track_total_hits": true,
+	})
+	if err != nil {
+		return review.Summary{}, err
+	}
+
+	return review.Summary{Count: sr.Hits.Total.Value, Sum: int64(sr.Aggregations.Sum.Value)}, nil
+}
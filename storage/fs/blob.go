@@ -0,0 +1,102 @@
+// Package fs provides a blob store keeping blobs in files of a local
+// directory.
+package fs
+
+import (
+	"context"
+	"fmt"
+	"github.com/ortymid/market/market/media"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// BlobStore keeps every blob in a file named by its key under the directory.
+// Blobs are written to temporary files first and renamed, so readers never
+// see partially written blobs.
+type BlobStore struct {
+	dir string
+}
+
+// NewBlobStore returns a store of the directory, creating it if needed.
+func NewBlobStore(dir string) (*BlobStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating blob directory: %w", err)
+	}
+	return &BlobStore{dir: dir}, nil
+}
+
+func (s *BlobStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(name), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // fails harmlessly after the rename
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+func (s *BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, media.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A key of a directory holding other blobs is not a blob.
+	if fi, err := f.Stat(); err != nil || fi.IsDir() {
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		return nil, media.ErrNotFound
+	}
+	return f, nil
+}
+
+func (s *BlobStore) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(name)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// path returns the file name of the key. Keys must be clean relative paths,
+// so that they cannot point outside of the directory.
+func (s *BlobStore) path(key string) (string, error) {
+	if key == "" || path.IsAbs(key) || path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,43 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"github.com/ortymid/market/market/media"
+	"github.com/ortymid/market/storage/storagetest"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func newTestBlobStore(t *testing.T) *BlobStore {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "blobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	s, err := NewBlobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestBlobStore_Conformance(t *testing.T) {
+	storagetest.TestBlobStore(t, func(t *testing.T) media.BlobStore {
+		return newTestBlobStore(t)
+	})
+}
+
+func TestBlobStore_InvalidKey(t *testing.T) {
+	s := newTestBlobStore(t)
+
+	for _, key := range []string{"", "/etc/passwd", "../blob", "products/../../blob", "products//1", "products/1/"} {
+		if err := s.Put(context.Background(), key, bytes.NewReader(nil), ""); err == nil {
+			t.Errorf("Put(%q) error = nil, want an error", key)
+		}
+	}
+}
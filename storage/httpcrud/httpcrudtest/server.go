@@ -0,0 +1,128 @@
+// Package httpcrudtest provides a fake of the users API of httpCRUD for
+// tests.
+package httpcrudtest
+
+import (
+	"encoding/json"
+	"github.com/ortymid/market/market/user"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Server serves GET and PUT /users/{id} with the users kept in memory. Like
+// httpCRUD, it sends the ids as integers if they are numbers. It is safe for
+// concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	users      map[string]user.User
+	failures   []int
+	requests   int
+	authHeader string
+}
+
+// NewServer starts a server with the users. It should be closed when it is
+// not needed anymore.
+func NewServer(users ...user.User) *Server {
+	s := &Server{users: make(map[string]user.User)}
+	for _, u := range users {
+		s.users[u.ID] = u
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// User returns the user with the id as it is stored in the server.
+func (s *Server) User(id string) (user.User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	return u, ok
+}
+
+// Fail makes the next requests fail with the status codes, one for each
+// request.
+func (s *Server) Fail(codes ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, codes...)
+}
+
+// Requests returns the number of requests served, including the failed ones.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+// Authorization returns the Authorization header of the last request.
+func (s *Server) Authorization() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.authHeader
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	s.authHeader = r.Header.Get("Authorization")
+
+	if len(s.failures) > 0 {
+		code := s.failures[0]
+		s.failures = s.failures[1:]
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/users/")
+	if id == r.URL.Path || id == "" {
+		http.NotFound(w, r)
+		return
+	}
+	u, ok := s.users[id]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var d struct {
+			Name    string `json:"name"`
+			Balance int64  `json:"balance"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		u.Name = d.Name
+		u.Balance = d.Balance
+		s.users[id] = u
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var userID interface{} = u.ID
+	if n, err := strconv.ParseInt(u.ID, 10, 64); err == nil {
+		userID = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      userID,
+		"name":    u.Name,
+		"balance": u.Balance,
+	})
+}
@@ -0,0 +1,197 @@
+// Package httpcrud gives access to the users of the AIexMoran/httpCRUD
+// service, which issues the tokens of the market users.
+package httpcrud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/user"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultRetries is the number of times failed requests are retried by
+// services made with NewUserService.
+const DefaultRetries = 2
+
+// DefaultRetryWait is the wait before the first retry of services made with
+// NewUserService, it doubles with every retry.
+const DefaultRetryWait = 100 * time.Millisecond
+
+// UserService implements user.Service with the users API of httpCRUD:
+// GET /users/{id} returns the user and PUT /users/{id} replaces it. The token
+// stored in the context by auth.NewContextWithToken is forwarded, so that the
+// users are requested on behalf of the caller.
+//
+// Transient failures, which are network errors and 429 and 5xx responses, are
+// retried. Users are not cached, user.CachedService may be used for it.
+type UserService struct {
+	// URL is the base URL of the service, e.g. "http://localhost:9090".
+	URL string
+	// Client makes the requests, http.DefaultClient is used if it is nil.
+	Client *http.Client
+	// Retries is the number of times a failed request is retried.
+	Retries int
+	// RetryWait is the wait before the first retry, it doubles with every
+	// retry.
+	RetryWait time.Duration
+}
+
+func NewUserService(url string) *UserService {
+	return &UserService{URL: url, Retries: DefaultRetries, RetryWait: DefaultRetryWait}
+}
+
+// userData is the user in the API. Ids are integers in httpCRUD, strings are
+// accepted as well.
+type userData struct {
+	ID      json.RawMessage `json:"id,omitempty"`
+	Name    string          `json:"name"`
+	Balance int64           `json:"balance"`
+}
+
+func (d *userData) user() (user.User, error) {
+	var id string
+	if err := json.Unmarshal(d.ID, &id); err != nil {
+		var n int64
+		if err := json.Unmarshal(d.ID, &n); err != nil {
+			return user.User{}, fmt.Errorf("parsing user id %s: %w", d.ID, err)
+		}
+		id = strconv.FormatInt(n, 10)
+	}
+
+	return user.User{ID: id, Name: d.Name, Balance: d.Balance}, nil
+}
+
+func (s *UserService) Get(ctx context.Context, id string) (user.User, error) {
+	var d userData
+	if err := s.do(ctx, http.MethodGet, id, nil, &d); err != nil {
+		return user.User{}, fmt.Errorf("getting user: %w", err)
+	}
+
+	return d.user()
+}
+
+// Update reads the user and replaces it with the updated one, see
+// user.Service.
+func (s *UserService) Update(ctx context.Context, r user.UpdateRequest) (user.User, error) {
+	var d userData
+	if err := s.do(ctx, http.MethodGet, r.ID, nil, &d); err != nil {
+		return user.User{}, fmt.Errorf("getting user: %w", err)
+	}
+
+	if r.Balance != nil {
+		d.Balance = *r.Balance
+	}
+
+	if err := s.do(ctx, http.MethodPut, r.ID, &d, &d); err != nil {
+		return user.User{}, fmt.Errorf("updating user: %w", err)
+	}
+
+	return d.user()
+}
+
+// do makes the request to the user with the id sending the body as JSON and
+// decoding the response into out. It returns user.ErrNotFound if there is no
+// such user.
+func (s *UserService) do(ctx context.Context, method string, id string, body interface{}, out interface{}) error {
+	var reqBody []byte
+	if body != nil {
+		var err error
+		reqBody, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+	}
+
+	wait := s.RetryWait
+	for attempt := 0; ; attempt++ {
+		respBody, err := s.send(ctx, method, id, reqBody)
+		if err == nil {
+			if err := json.Unmarshal(respBody, out); err != nil {
+				return fmt.Errorf("parsing response: %w", err)
+			}
+			return nil
+		}
+		if !isTransient(err) || attempt == s.Retries {
+			return err
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+		wait *= 2
+	}
+}
+
+// send makes a single request and returns the body of the response.
+func (s *UserService) send(ctx context.Context, method string, id string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.URL+"/users/"+url.PathEscape(id), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token, ok := auth.TokenFromContext(ctx).(string); ok && len(token) != 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, user.ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, &statusError{Code: resp.StatusCode, Status: resp.Status, Body: string(bytes.TrimSpace(respBody))}
+	}
+	return respBody, nil
+}
+
+// statusError is an unexpected response of the service.
+type statusError struct {
+	Code   int
+	Status string
+	Body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("httpcrud: %s: %s", e.Status, e.Body)
+}
+
+// isTransient reports whether the request failed for a reason which may go
+// away by itself. Requests canceled by the caller are not retried.
+func isTransient(err error) bool {
+	var serr *statusError
+	if errors.As(err, &serr) {
+		return serr.Code == http.StatusTooManyRequests || serr.Code >= 500
+	}
+	if errors.Is(err, user.ErrNotFound) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var uerr *url.Error
+	return errors.As(err, &uerr)
+}
@@ -0,0 +1,137 @@
+package httpcrud_test
+
+import (
+	"context"
+	"errors"
+	"github.com/ortymid/market/market/auth"
+	"github.com/ortymid/market/market/user"
+	"github.com/ortymid/market/storage/httpcrud"
+	"github.com/ortymid/market/storage/httpcrud/httpcrudtest"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestUserService_Get(t *testing.T) {
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		id           string
+		failures     []int
+		want         user.User
+		wantErr      error
+		wantRequests int
+		wantAuth     string
+	}{
+		{
+			name:         "Should get user forwarding token",
+			ctx:          auth.NewContextWithToken(context.Background(), "token"),
+			id:           "1",
+			want:         user.User{ID: "1", Name: "Alice", Balance: 100},
+			wantRequests: 1,
+			wantAuth:     "Bearer token",
+		},
+		{
+			name:         "Should get user with string id anonymously",
+			ctx:          context.Background(),
+			id:           "bob",
+			want:         user.User{ID: "bob", Name: "Bob"},
+			wantRequests: 1,
+		},
+		{
+			name:         "Should return ErrNotFound for unknown user",
+			ctx:          context.Background(),
+			id:           "2",
+			wantErr:      user.ErrNotFound,
+			wantRequests: 1,
+		},
+		{
+			name:         "Should retry transient failures",
+			ctx:          context.Background(),
+			id:           "1",
+			failures:     []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			want:         user.User{ID: "1", Name: "Alice", Balance: 100},
+			wantRequests: 3,
+		},
+		{
+			name:         "Should give up after retries",
+			ctx:          context.Background(),
+			id:           "1",
+			failures:     []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			wantErr:      errAny,
+			wantRequests: 3,
+		},
+		{
+			name:         "Should not retry other failures",
+			ctx:          context.Background(),
+			id:           "1",
+			failures:     []int{http.StatusUnauthorized},
+			wantErr:      errAny,
+			wantRequests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httpcrudtest.NewServer(
+				user.User{ID: "1", Name: "Alice", Balance: 100},
+				user.User{ID: "bob", Name: "Bob"},
+			)
+			defer srv.Close()
+			srv.Fail(tt.failures...)
+
+			s := &httpcrud.UserService{URL: srv.URL, Retries: 2}
+			got, err := s.Get(tt.ctx, tt.id)
+			if !matchErr(err, tt.wantErr) {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get() got = %v, want %v", got, tt.want)
+			}
+			if n := srv.Requests(); n != tt.wantRequests {
+				t.Errorf("Get() requests = %d, want %d", n, tt.wantRequests)
+			}
+			if a := srv.Authorization(); a != tt.wantAuth {
+				t.Errorf("Get() authorization = %q, want %q", a, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestUserService_Update(t *testing.T) {
+	srv := httpcrudtest.NewServer(user.User{ID: "1", Name: "Alice", Balance: 100})
+	defer srv.Close()
+	srv.Fail(http.StatusInternalServerError)
+
+	s := &httpcrud.UserService{URL: srv.URL, Retries: 1}
+	ctx := auth.NewContextWithToken(context.Background(), "token")
+
+	balance := int64(50)
+	got, err := s.Update(ctx, user.UpdateRequest{ID: "1", Balance: &balance})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	want := user.User{ID: "1", Name: "Alice", Balance: 50}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Update() got = %v, want %v", got, want)
+	}
+	if stored, _ := srv.User("1"); !reflect.DeepEqual(stored, want) {
+		t.Errorf("Update() stored = %v, want %v", stored, want)
+	}
+	if a := srv.Authorization(); a != "Bearer token" {
+		t.Errorf("Update() authorization = %q, want the token forwarded", a)
+	}
+
+	if _, err := s.Update(ctx, user.UpdateRequest{ID: "2", Balance: &balance}); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("Update() error = %v, want %v", err, user.ErrNotFound)
+	}
+}
+
+// errAny matches any error.
+var errAny = errors.New("any error")
+
+func matchErr(err, want error) bool {
+	if want == errAny {
+		return err != nil
+	}
+	return errors.Is(err, want)
+}
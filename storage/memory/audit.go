@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"github.com/ortymid/market/market/audit"
+	"strconv"
+	"sync"
+)
+
+// AuditStorage implements audit.Storage keeping entries in memory.
+// It is safe for concurrent use.
+type AuditStorage struct {
+	mu sync.RWMutex
+
+	lastID  int64
+	entries map[string][]audit.Entry // by product in order of recording
+}
+
+func NewAuditStorage() *AuditStorage {
+	return &AuditStorage{entries: make(map[string][]audit.Entry)}
+}
+
+func (s *AuditStorage) Record(ctx context.Context, e audit.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	e.ID = strconv.FormatInt(s.lastID, 10)
+	e.Changes = append([]audit.Change{}, e.Changes...)
+	s.entries[e.Product] = append(s.entries[e.Product], e)
+
+	return nil
+}
+
+func (s *AuditStorage) Find(ctx context.Context, r audit.FindRequest) ([]*audit.Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := s.entries[r.Product]
+	es := make([]*audit.Entry, 0, len(entries))
+	for i := range entries {
+		e := entries[i]
+		e.Changes = append([]audit.Change{}, e.Changes...)
+		es = append(es, &e)
+	}
+
+	return r.Page(es), nil
+}
@@ -0,0 +1,13 @@
+package memory
+
+import (
+	"github.com/ortymid/market/market/audit"
+	"github.com/ortymid/market/storage/storagetest"
+	"testing"
+)
+
+func TestAuditStorage_Conformance(t *testing.T) {
+	storagetest.TestAuditStorage(t, func(t *testing.T) audit.Storage {
+		return NewAuditStorage()
+	})
+}
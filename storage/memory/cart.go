@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"github.com/ortymid/market/market/cart"
+	"sync"
+)
+
+// CartStorage implements cart.Storage keeping carts in memory. Expired carts
+// are kept until they are deleted. It is safe for concurrent use.
+type CartStorage struct {
+	mu    sync.RWMutex
+	carts map[string]cart.Cart
+}
+
+func NewCartStorage() *CartStorage {
+	return &CartStorage{carts: make(map[string]cart.Cart)}
+}
+
+func (s *CartStorage) Get(ctx context.Context, user string) (*cart.Cart, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.carts[user]
+	if !ok {
+		return nil, cart.ErrNotFound
+	}
+
+	return copyCart(c), nil
+}
+
+func (s *CartStorage) Save(ctx context.Context, c cart.Cart) (*cart.Cart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.carts[c.User] = *copyCart(c)
+
+	return copyCart(c), nil
+}
+
+func (s *CartStorage) Delete(ctx context.Context, user string) (*cart.Cart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.carts[user]
+	if !ok {
+		return nil, cart.ErrNotFound
+	}
+
+	delete(s.carts, user)
+
+	return &c, nil
+}
+
+// copyCart copies the items, so the stored cart is not changed by callers.
+func copyCart(c cart.Cart) *cart.Cart {
+	if c.Items != nil {
+		c.Items = append([]cart.Item(nil), c.Items...)
+	}
+	return &c
+}
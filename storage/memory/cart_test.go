@@ -0,0 +1,13 @@
+package memory
+
+import (
+	"github.com/ortymid/market/market/cart"
+	"github.com/ortymid/market/storage/storagetest"
+	"testing"
+)
+
+func TestCartStorage_Conformance(t *testing.T) {
+	storagetest.TestCartStorage(t, func(t *testing.T) cart.Storage {
+		return NewCartStorage()
+	})
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"github.com/ortymid/market/market/category"
+	"strconv"
+	"sync"
+)
+
+// CategoryStorage implements category.Storage keeping categories in memory.
+// It is safe for concurrent use.
+type CategoryStorage struct {
+	mu sync.RWMutex
+
+	lastID     int64
+	categories map[string]category.Category
+}
+
+func NewCategoryStorage() *CategoryStorage {
+	return &CategoryStorage{categories: make(map[string]category.Category)}
+}
+
+func (s *CategoryStorage) Find(ctx context.Context) ([]*category.Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cs := make([]*category.Category, 0, len(s.categories))
+	for _, c := range s.categories {
+		c := c
+		cs = append(cs, &c)
+	}
+
+	return cs, nil
+}
+
+func (s *CategoryStorage) FindOne(ctx context.Context, id string) (*category.Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.categories[id]
+	if !ok {
+		return nil, category.ErrNotFound
+	}
+
+	return &c, nil
+}
+
+func (s *CategoryStorage) Create(ctx context.Context, r category.CreateRequest) (*category.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	c := category.Category{
+		ID:     strconv.FormatInt(s.lastID, 10),
+		Slug:   r.Slug,
+		Name:   r.Name,
+		Parent: cloneString(r.Parent),
+	}
+
+	s.categories[c.ID] = c
+
+	return &c, nil
+}
+
+func (s *CategoryStorage) Update(ctx context.Context, r category.UpdateRequest) (*category.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.categories[r.ID]
+	if !ok {
+		return nil, category.ErrNotFound
+	}
+
+	// Update not-nil fields.
+	if r.Slug != nil {
+		c.Slug = *r.Slug
+	}
+	if r.Name != nil {
+		c.Name = *r.Name
+	}
+	if r.Parent != nil {
+		c.Parent = cloneString(r.Parent)
+		if *r.Parent == "" {
+			c.Parent = nil
+		}
+	}
+
+	s.categories[c.ID] = c
+
+	return &c, nil
+}
+
+func (s *CategoryStorage) Delete(ctx context.Context, id string) (*category.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.categories[id]
+	if !ok {
+		return nil, category.ErrNotFound
+	}
+
+	delete(s.categories, id)
+
+	return &c, nil
+}
+
+// cloneString returns a copy of the string pointed to by p, so that the
+// stored value is not shared with the caller.
+func cloneString(p *string) *string {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
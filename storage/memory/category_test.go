@@ -0,0 +1,13 @@
+package memory
+
+import (
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/storage/storagetest"
+	"testing"
+)
+
+func TestCategoryStorage_Conformance(t *testing.T) {
+	storagetest.TestCategoryStorage(t, func(t *testing.T) category.Storage {
+		return NewCategoryStorage()
+	})
+}
@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"github.com/ortymid/market/market/order"
+	"strconv"
+	"sync"
+)
+
+// OrderStorage implements order.Storage keeping orders in memory.
+// It is safe for concurrent use.
+type OrderStorage struct {
+	mu sync.RWMutex
+
+	lastID int64
+	ids    []string // in order of insertion
+	orders map[string]order.Order
+	keys   map[orderKey]string // ids of the orders by buyer and idempotency key
+}
+
+type orderKey struct {
+	buyer string
+	key   string
+}
+
+func NewOrderStorage() *OrderStorage {
+	return &OrderStorage{
+		orders: make(map[string]order.Order),
+		keys:   make(map[orderKey]string),
+	}
+}
+
+func (s *OrderStorage) Find(ctx context.Context, r order.FindRequest) ([]*order.Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var os []*order.Order
+	for _, id := range s.ids {
+		o := s.orders[id]
+		if r.Match(&o) {
+			os = append(os, &o)
+		}
+	}
+
+	return r.Page(os), nil
+}
+
+func (s *OrderStorage) FindOne(ctx context.Context, id string) (*order.Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	o, ok := s.orders[id]
+	if !ok {
+		return nil, order.ErrNotFound
+	}
+
+	return &o, nil
+}
+
+func (s *OrderStorage) FindByKey(ctx context.Context, buyer string, key string) (*order.Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.keys[orderKey{buyer: buyer, key: key}]
+	if !ok {
+		return nil, order.ErrNotFound
+	}
+
+	o := s.orders[id]
+	return &o, nil
+}
+
+func (s *OrderStorage) Create(ctx context.Context, o order.Order) (*order.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := orderKey{buyer: o.Buyer, key: o.IdempotencyKey}
+	if _, ok := s.keys[k]; ok {
+		return nil, order.ErrDuplicate
+	}
+
+	s.lastID++
+	o.ID = strconv.FormatInt(s.lastID, 10)
+
+	s.orders[o.ID] = o
+	s.ids = append(s.ids, o.ID)
+	s.keys[k] = o.ID
+
+	return &o, nil
+}
+
+func (s *OrderStorage) UpdateStatus(ctx context.Context, id string, status order.Status) (*order.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[id]
+	if !ok {
+		return nil, order.ErrNotFound
+	}
+
+	o.Status = status
+	s.orders[id] = o
+
+	return &o, nil
+}
+
+func (s *OrderStorage) Delete(ctx context.Context, id string) (*order.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[id]
+	if !ok {
+		return nil, order.ErrNotFound
+	}
+
+	delete(s.orders, id)
+	delete(s.keys, orderKey{buyer: o.Buyer, key: o.IdempotencyKey})
+	for i, v := range s.ids {
+		if v == id {
+			s.ids = append(s.ids[:i], s.ids[i+1:]...)
+			break
+		}
+	}
+
+	return &o, nil
+}
@@ -0,0 +1,13 @@
+package memory
+
+import (
+	"github.com/ortymid/market/market/order"
+	"github.com/ortymid/market/storage/storagetest"
+	"testing"
+)
+
+func TestOrderStorage_Conformance(t *testing.T) {
+	storagetest.TestOrderStorage(t, func(t *testing.T) order.Storage {
+		return NewOrderStorage()
+	})
+}
@@ -0,0 +1,327 @@
+// Package memory provides in-memory storages. They are meant for tests and
+// local development, as nothing is persisted.
+package memory
+
+import (
+	"context"
+	"github.com/ortymid/market/market/media"
+	"github.com/ortymid/market/market/product"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ProductStorage implements product.Storage keeping products in memory.
+// It is safe for concurrent use.
+type ProductStorage struct {
+	mu sync.RWMutex
+
+	lastID   int64
+	ids      []string // in order of insertion
+	products map[string]product.Product
+}
+
+func NewProductStorage() *ProductStorage {
+	return &ProductStorage{products: make(map[string]product.Product)}
+}
+
+func (s *ProductStorage) Find(ctx context.Context, r product.FindRequest) (*product.FindResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ps []*product.Product
+	for _, id := range s.ids {
+		p := s.products[id]
+		if r.Match(&p) {
+			ps = append(ps, &p)
+		}
+	}
+
+	return product.PageProducts(ps, r, created)
+}
+
+// created returns the creation order of the product. Ids are assigned
+// sequentially, so they are used as the order.
+func created(p *product.Product) int64 {
+	id, _ := strconv.ParseInt(p.ID, 10, 64)
+	return id
+}
+
+func (s *ProductStorage) FindOne(ctx context.Context, id string) (*product.Product, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.products[id]
+	if !ok {
+		return nil, product.ErrNotFound
+	}
+
+	return &p, nil
+}
+
+func (s *ProductStorage) Create(ctx context.Context, r product.CreateRequest) (*product.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	p := product.Product{
+		ID:         strconv.FormatInt(s.lastID, 10),
+		Name:       r.Name,
+		Price:      r.Price,
+		Seller:     r.Seller,
+		Categories: cloneStrings(r.Categories),
+		Attributes: cloneAttributes(r.Attributes),
+		Variants:   cloneVariants(r.Variants),
+
+		Stock:             r.Stock,
+		LowStockThreshold: r.LowStockThreshold,
+
+		Status:    r.Status,
+		PublishAt: r.PublishAt,
+
+		Version: 1,
+	}
+	if p.Status == "" {
+		p.Status = product.StatusPublished
+	}
+
+	s.ids = append(s.ids, p.ID)
+	s.products[p.ID] = p
+
+	return &p, nil
+}
+
+func (s *ProductStorage) Update(ctx context.Context, r product.UpdateRequest) (*product.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.products[r.ID]
+	if !ok || p.Deleted() {
+		return nil, product.ErrNotFound
+	}
+	if r.ExpectedVersion != nil && *r.ExpectedVersion != p.Version {
+		return nil, product.ErrConflict
+	}
+
+	// Update not-nil fields.
+	if r.Name != nil {
+		p.Name = *r.Name
+	}
+	if r.Price != nil {
+		p.Price = *r.Price
+	}
+	if r.Categories != nil {
+		p.Categories = cloneStrings(*r.Categories)
+	}
+	if r.Attributes != nil {
+		p.Attributes = cloneAttributes(*r.Attributes)
+	}
+	if r.Variants != nil {
+		p.Variants = cloneVariants(*r.Variants)
+	}
+	if r.Media != nil {
+		p.Media = cloneMedia(*r.Media)
+	}
+	if r.LowStockThreshold != nil {
+		p.LowStockThreshold = *r.LowStockThreshold
+	}
+	if r.PublishAt != nil {
+		p.PublishAt = r.PublishAt
+	}
+	if r.Status != nil {
+		p.Status = *r.Status
+		if p.Status != product.StatusDraft {
+			p.PublishAt = nil
+		}
+	}
+	p.Version++
+
+	s.products[p.ID] = p
+
+	return &p, nil
+}
+
+func (s *ProductStorage) UpdateStock(ctx context.Context, r product.StockRequest) (*product.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.products[r.ID]
+	if !ok || p.Deleted() {
+		return nil, product.ErrNotFound
+	}
+
+	stock := &p.Stock
+	if r.Variant != "" {
+		// The stored variants are shared with the products returned before.
+		p.Variants = cloneVariants(p.Variants)
+		stock = nil
+		for i := range p.Variants {
+			if p.Variants[i].SKU == r.Variant {
+				stock = &p.Variants[i].Stock
+			}
+		}
+		if stock == nil {
+			return nil, product.ErrVariantNotFound
+		}
+	}
+
+	if *stock+r.Stock < 0 || p.Reserved+r.Reserved < 0 {
+		return nil, product.ErrInsufficientStock
+	}
+	*stock += r.Stock
+	p.Reserved += r.Reserved
+
+	s.products[p.ID] = p
+
+	return &p, nil
+}
+
+func (s *ProductStorage) Delete(ctx context.Context, id string, at time.Time) (*product.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.products[id]
+	if !ok || p.Deleted() {
+		return nil, product.ErrNotFound
+	}
+
+	p.DeletedAt = &at
+	s.products[id] = p
+
+	return &p, nil
+}
+
+func (s *ProductStorage) Restore(ctx context.Context, id string) (*product.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.products[id]
+	if !ok || !p.Deleted() {
+		return nil, product.ErrNotFound
+	}
+
+	p.DeletedAt = nil
+	s.products[id] = p
+
+	return &p, nil
+}
+
+func (s *ProductStorage) Purge(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	ids := s.ids[:0]
+	for _, id := range s.ids {
+		p := s.products[id]
+		if p.Deleted() && p.DeletedAt.Before(before) {
+			delete(s.products, id)
+			n++
+			continue
+		}
+		ids = append(ids, id)
+	}
+	s.ids = ids
+
+	return n, nil
+}
+
+func (s *ProductStorage) PublishDue(ctx context.Context, now time.Time) ([]*product.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ps []*product.Product
+	for _, id := range s.ids {
+		p := s.products[id]
+		if p.Deleted() || p.Status != product.StatusDraft || p.PublishAt == nil || p.PublishAt.After(now) {
+			continue
+		}
+
+		p.Status = product.StatusPublished
+		p.PublishAt = nil
+		p.Version++
+		s.products[id] = p
+
+		ps = append(ps, &p)
+	}
+
+	return ps, nil
+}
+
+func (s *ProductStorage) UpdateRating(ctx context.Context, id string, rating product.Rating) (*product.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.products[id]
+	if !ok {
+		return nil, product.ErrNotFound
+	}
+
+	p.Rating = rating
+	s.products[id] = p
+
+	return &p, nil
+}
+
+func (s *ProductStorage) SummarizeSellers(ctx context.Context, sellers []string) (map[string]product.SellerSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(sellers))
+	for _, seller := range sellers {
+		wanted[seller] = true
+	}
+
+	var ps []*product.Product
+	for _, id := range s.ids {
+		p := s.products[id]
+		if wanted[p.Seller] {
+			ps = append(ps, &p)
+		}
+	}
+
+	return product.SummarizeSellers(ps), nil
+}
+
+// cloneMedia returns a copy of ms as cloneStrings does.
+func cloneMedia(ms []media.Media) []media.Media {
+	if len(ms) == 0 {
+		return nil
+	}
+	return append([]media.Media(nil), ms...)
+}
+
+// cloneAttributes returns a copy of attrs as cloneStrings does.
+func cloneAttributes(attrs []product.Attribute) []product.Attribute {
+	if len(attrs) == 0 {
+		return nil
+	}
+	return append([]product.Attribute(nil), attrs...)
+}
+
+// cloneVariants returns a copy of variants along with their attribute values
+// as cloneStrings does.
+func cloneVariants(variants []product.Variant) []product.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+	cloned := make([]product.Variant, 0, len(variants))
+	for _, v := range variants {
+		values := make(map[string]string, len(v.Attributes))
+		for name, value := range v.Attributes {
+			values[name] = value
+		}
+		v.Attributes = values
+		cloned = append(cloned, v)
+	}
+	return cloned
+}
+
+// cloneStrings returns a copy of ss, so that the stored value is not shared
+// with the caller. Empty slices are returned as nil.
+func cloneStrings(ss []string) []string {
+	if len(ss) == 0 {
+		return nil
+	}
+	return append([]string(nil), ss...)
+}
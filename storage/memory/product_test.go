@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/storage/storagetest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProductStorage_Find(t *testing.T) {
+	seed := []product.CreateRequest{
+		{Name: "Banana", Price: money.New(1500, "USD"), Seller: "1"},
+		{Name: "Carrot", Price: money.New(1400, "USD"), Seller: "bunny"},
+		{Name: "Green banana", Price: money.New(1000, "USD"), Seller: "1"},
+		{Name: "Apple", Price: money.New(2000, "USD"), Seller: "2"},
+	}
+
+	tests := []struct {
+		name string
+		r    product.FindRequest
+		want []string // ids
+	}{
+		{
+			name: "Should find products in order of insertion",
+			r:    product.FindRequest{Offset: 0, Limit: 10},
+			want: []string{"1", "2", "3", "4"},
+		},
+		{
+			name: "Should find products for offset and limit",
+			r:    product.FindRequest{Offset: 1, Limit: 2},
+			want: []string{"2", "3"},
+		},
+		{
+			name: "Should find products by name",
+			r:    product.FindRequest{Offset: 0, Limit: 10, Name: testPtrString("banana")},
+			want: []string{"1", "3"},
+		},
+		{
+			name: "Should find products by price range",
+			r: product.FindRequest{Offset: 0, Limit: 10, PriceRange: &product.PriceRange{
+				From:     testPtrInt64(1400),
+				To:       testPtrInt64(1500),
+				Currency: "USD",
+			}},
+			want: []string{"1", "2"},
+		},
+		{
+			name: "Should find products by seller",
+			r:    product.FindRequest{Offset: 0, Limit: 10, Seller: testPtrString("1")},
+			want: []string{"1", "3"},
+		},
+		{
+			name: "Should apply offset after filters",
+			r:    product.FindRequest{Offset: 1, Limit: 10, Seller: testPtrString("1")},
+			want: []string{"3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewProductStorage()
+			for _, r := range seed {
+				if _, err := s.Create(context.Background(), r); err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+			}
+
+			res, err := s.Find(context.Background(), tt.r)
+			if err != nil {
+				t.Errorf("Find() error = %v", err)
+				return
+			}
+			ps := res.Products
+
+			got := make([]string, len(ps))
+			for i, p := range ps {
+				got[i] = p.ID
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProductStorage_Concurrent(t *testing.T) {
+	s := NewProductStorage()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p, err := s.Create(context.Background(), product.CreateRequest{Name: "p", Price: money.New(100, "USD"), Seller: "1"})
+			if err != nil {
+				t.Errorf("Create() error = %v", err)
+				return
+			}
+			if _, err := s.Delete(context.Background(), p.ID, time.Now()); err != nil {
+				t.Errorf("Delete() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	res, _ := s.Find(context.Background(), product.FindRequest{Limit: 100})
+	if len(res.Products) != 0 {
+		t.Errorf("Find() got %d products, want 0", len(res.Products))
+	}
+}
+
+func testPtrString(v string) *string {
+	return &v
+}
+
+func testPtrInt64(v int64) *int64 {
+	return &v
+}
+
+func TestProductStorage_Conformance(t *testing.T) {
+	storagetest.TestProductStorage(t, func(t *testing.T) product.Storage {
+		return NewProductStorage()
+	})
+}
@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"context"
+	"github.com/ortymid/market/market/review"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ReviewStorage implements review.Storage keeping reviews in memory.
+// It is safe for concurrent use.
+type ReviewStorage struct {
+	mu sync.RWMutex
+
+	lastID  int64
+	ids     []string // in order of insertion
+	reviews map[string]review.Review
+	keys    map[reviewKey]string // ids of the reviews by product and author
+}
+
+type reviewKey struct {
+	product string
+	author  string
+}
+
+func NewReviewStorage() *ReviewStorage {
+	return &ReviewStorage{
+		reviews: make(map[string]review.Review),
+		keys:    make(map[reviewKey]string),
+	}
+}
+
+func (s *ReviewStorage) Find(ctx context.Context, r review.FindRequest) ([]*review.Review, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var rvs []*review.Review
+	for _, id := range s.ids {
+		rv := s.reviews[id]
+		if r.Match(&rv) {
+			rvs = append(rvs, &rv)
+		}
+	}
+
+	return r.Page(rvs), nil
+}
+
+func (s *ReviewStorage) FindOne(ctx context.Context, id string) (*review.Review, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rv, ok := s.reviews[id]
+	if !ok {
+		return nil, review.ErrNotFound
+	}
+
+	return &rv, nil
+}
+
+func (s *ReviewStorage) Create(ctx context.Context, rv review.Review) (*review.Review, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := reviewKey{product: rv.Product, author: rv.Author}
+	if _, ok := s.keys[k]; ok {
+		return nil, review.ErrDuplicate
+	}
+
+	s.lastID++
+	rv.ID = strconv.FormatInt(s.lastID, 10)
+
+	s.reviews[rv.ID] = rv
+	s.ids = append(s.ids, rv.ID)
+	s.keys[k] = rv.ID
+
+	return &rv, nil
+}
+
+func (s *ReviewStorage) Update(ctx context.Context, r review.UpdateRequest, at time.Time) (*review.Review, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rv, ok := s.reviews[r.ID]
+	if !ok {
+		return nil, review.ErrNotFound
+	}
+
+	if r.Rating != nil {
+		rv.Rating = *r.Rating
+	}
+	if r.Text != nil {
+		rv.Text = *r.Text
+	}
+	rv.UpdatedAt = at
+	s.reviews[r.ID] = rv
+
+	return &rv, nil
+}
+
+func (s *ReviewStorage) Delete(ctx context.Context, id string) (*review.Review, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rv, ok := s.reviews[id]
+	if !ok {
+		return nil, review.ErrNotFound
+	}
+
+	delete(s.reviews, id)
+	delete(s.keys, reviewKey{product: rv.Product, author: rv.Author})
+	for i, v := range s.ids {
+		if v == id {
+			s.ids = append(s.ids[:i], s.ids[i+1:]...)
+			break
+		}
+	}
+
+	return &rv, nil
+}
+
+func (s *ReviewStorage) Summarize(ctx context.Context, product string) (review.Summary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var sum review.Summary
+	for _, rv := range s.reviews {
+		if rv.Product == product {
+			sum.Count++
+			sum.Sum += rv.Rating
+		}
+	}
+
+	return sum, nil
+}
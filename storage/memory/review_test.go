@@ -0,0 +1,13 @@
+package memory
+
+import (
+	"github.com/ortymid/market/market/review"
+	"github.com/ortymid/market/storage/storagetest"
+	"testing"
+)
+
+func TestReviewStorage_Conformance(t *testing.T) {
+	storagetest.TestReviewStorage(t, func(t *testing.T) review.Storage {
+		return NewReviewStorage()
+	})
+}
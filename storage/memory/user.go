@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"github.com/ortymid/market/market/user"
+	"sync"
+)
+
+// UserService implements user.Service keeping users in memory. It stands in
+// for the user service in tests and local development. It is safe for
+// concurrent use.
+type UserService struct {
+	mu    sync.RWMutex
+	users map[string]user.User
+}
+
+// NewUserService returns a service with the users.
+func NewUserService(users ...user.User) *UserService {
+	s := &UserService{users: make(map[string]user.User)}
+	for _, u := range users {
+		s.users[u.ID] = u
+	}
+	return s
+}
+
+func (s *UserService) Get(ctx context.Context, id string) (user.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return user.User{}, user.ErrNotFound
+	}
+
+	return u, nil
+}
+
+func (s *UserService) Update(ctx context.Context, r user.UpdateRequest) (user.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[r.ID]
+	if !ok {
+		return user.User{}, user.ErrNotFound
+	}
+
+	if r.Balance != nil {
+		u.Balance = *r.Balance
+	}
+
+	s.users[u.ID] = u
+
+	return u, nil
+}
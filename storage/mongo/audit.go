@@ -0,0 +1,72 @@
+package mongo
+
+import (
+	"context"
+	"github.com/ortymid/market/market/audit"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditStorage keeps entries in a collection. Entries are found by product,
+// the index is created by CreateIndexes.
+type AuditStorage struct {
+	col *mongo.Collection
+}
+
+func NewAuditStorage(col *mongo.Collection) *AuditStorage {
+	return &AuditStorage{col: col}
+}
+
+// CreateIndexes creates the index of entries by product. It must be called
+// before the storage is used, it does nothing if the index exists.
+func (s *AuditStorage) CreateIndexes(ctx context.Context) error {
+	_, err := s.col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "product", Value: 1}, {Key: "_id", Value: 1}},
+	})
+	return err
+}
+
+func (s *AuditStorage) Record(ctx context.Context, e audit.Entry) error {
+	e.ID = ""
+	if e.Changes == nil {
+		e.Changes = []audit.Change{}
+	}
+
+	_, err := s.col.InsertOne(ctx, e)
+	return err
+}
+
+func (s *AuditStorage) Find(ctx context.Context, r audit.FindRequest) ([]*audit.Entry, error) {
+	es := make([]*audit.Entry, 0)
+	if r.Limit <= 0 {
+		return es, nil
+	}
+
+	// Object ids grow with creation time, so they are used as the order of
+	// recording.
+	f := bson.D{{Key: "product", Value: r.Product}}
+	opts := options.Find().SetSkip(r.Offset).SetLimit(r.Limit).SetSort(bson.D{{Key: "_id", Value: 1}})
+	cur, err := s.col.Find(ctx, f, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		e := &audit.Entry{}
+
+		if err := cur.Decode(e); err != nil {
+			return nil, err
+		}
+		// Times are decoded in the local time zone.
+		e.CreatedAt = e.CreatedAt.UTC()
+
+		es = append(es, e)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	return es, nil
+}
@@ -0,0 +1,70 @@
+package mongo
+
+import (
+	"context"
+	"github.com/ortymid/market/market/cart"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CartStorage keeps carts in a collection with users as ids. Expired carts
+// are removed by the TTL index created by CreateIndexes.
+type CartStorage struct {
+	col *mongo.Collection
+}
+
+func NewCartStorage(col *mongo.Collection) *CartStorage {
+	return &CartStorage{col: col}
+}
+
+// CreateIndexes creates the TTL index on the expiration time. It must be
+// called before the storage is used, it does nothing if the index exists.
+func (s *CartStorage) CreateIndexes(ctx context.Context) error {
+	_, err := s.col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+func (s *CartStorage) Get(ctx context.Context, user string) (*cart.Cart, error) {
+	return decodeCart(s.col.FindOne(ctx, bson.D{{Key: "_id", Value: user}}))
+}
+
+func (s *CartStorage) Save(ctx context.Context, c cart.Cart) (*cart.Cart, error) {
+	opts := options.Replace().SetUpsert(true)
+	_, err := s.col.ReplaceOne(ctx, bson.D{{Key: "_id", Value: c.User}}, c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (s *CartStorage) Delete(ctx context.Context, user string) (*cart.Cart, error) {
+	return decodeCart(s.col.FindOneAndDelete(ctx, bson.D{{Key: "_id", Value: user}}))
+}
+
+// decodeCart decodes the cart of the result. It returns cart.ErrNotFound if
+// there is no document.
+func decodeCart(res *mongo.SingleResult) (*cart.Cart, error) {
+	c := &cart.Cart{}
+
+	err := res.Decode(c)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, cart.ErrNotFound
+		}
+		return nil, err
+	}
+
+	// Times are decoded in the local time zone.
+	c.UpdatedAt = c.UpdatedAt.UTC()
+	c.ExpiresAt = c.ExpiresAt.UTC()
+	for i := range c.Items {
+		c.Items[i].AddedAt = c.Items[i].AddedAt.UTC()
+	}
+
+	return c, nil
+}
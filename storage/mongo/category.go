@@ -0,0 +1,130 @@
+package mongo
+
+import (
+	"context"
+	"github.com/ortymid/market/market/category"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CategoryStorage keeps categories in a collection. Parents are stored as hex
+// ids of the parent documents.
+type CategoryStorage struct {
+	col *mongo.Collection
+}
+
+func NewCategoryStorage(col *mongo.Collection) *CategoryStorage {
+	return &CategoryStorage{col: col}
+}
+
+func (s *CategoryStorage) Find(ctx context.Context) ([]*category.Category, error) {
+	cur, err := s.col.Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	cs := make([]*category.Category, 0)
+	for cur.Next(ctx) {
+		c := &category.Category{}
+
+		if err := cur.Decode(c); err != nil {
+			return nil, err
+		}
+
+		cs = append(cs, c)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+func (s *CategoryStorage) FindOne(ctx context.Context, id string) (*category.Category, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, category.ErrNotFound
+	}
+
+	c := &category.Category{}
+
+	err = s.col.FindOne(ctx, bson.D{{Key: "_id", Value: oid}}).Decode(c)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, category.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (s *CategoryStorage) Create(ctx context.Context, r category.CreateRequest) (*category.Category, error) {
+	res, err := s.col.InsertOne(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	oid, _ := res.InsertedID.(primitive.ObjectID)
+	c := &category.Category{
+		ID:     oid.Hex(),
+		Slug:   r.Slug,
+		Name:   r.Name,
+		Parent: r.Parent,
+	}
+	return c, nil
+}
+
+func (s *CategoryStorage) Update(ctx context.Context, r category.UpdateRequest) (*category.Category, error) {
+	oid, err := primitive.ObjectIDFromHex(r.ID)
+	if err != nil {
+		return nil, category.ErrNotFound
+	}
+
+	// Root categories have no parent field.
+	var unset bson.D
+	if r.Parent != nil && *r.Parent == "" {
+		r.Parent = nil
+		unset = append(unset, bson.E{Key: "parent", Value: ""})
+	}
+
+	c := &category.Category{}
+	f := bson.D{{Key: "_id", Value: oid}}
+	u := bson.D{{Key: "$set", Value: r}}
+	if len(unset) > 0 {
+		u = append(u, bson.E{Key: "$unset", Value: unset})
+	}
+	o := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	err = s.col.FindOneAndUpdate(ctx, f, u, o).Decode(c)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, category.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (s *CategoryStorage) Delete(ctx context.Context, id string) (*category.Category, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, category.ErrNotFound
+	}
+
+	c := &category.Category{}
+
+	err = s.col.FindOneAndDelete(ctx, bson.D{{Key: "_id", Value: oid}}).Decode(c)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, category.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,43 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/storage/storagetest"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestCategoryStorage_Conformance runs against a real database, it is skipped
+// if MARKET_TEST_MONGODB_URL is not set.
+func TestCategoryStorage_Conformance(t *testing.T) {
+	url := os.Getenv("MARKET_TEST_MONGODB_URL")
+	if len(url) == 0 {
+		t.Skip("MARKET_TEST_MONGODB_URL is not set")
+	}
+
+	client, err := NewClientFromURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	storagetest.TestCategoryStorage(t, func(t *testing.T) category.Storage {
+		name := fmt.Sprintf("categories_test_%d", time.Now().UnixNano())
+		col := client.Database("market_test").Collection(name)
+		t.Cleanup(func() {
+			col.Drop(context.Background())
+		})
+
+		return NewCategoryStorage(col)
+	})
+}
@@ -0,0 +1,163 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"github.com/ortymid/market/market/order"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// duplicateKey is the error code of unique index violations.
+const duplicateKey = 11000
+
+// OrderStorage keeps orders in a collection. Idempotency keys are unique per
+// buyer, the index is created by CreateIndexes.
+type OrderStorage struct {
+	col *mongo.Collection
+}
+
+func NewOrderStorage(col *mongo.Collection) *OrderStorage {
+	return &OrderStorage{col: col}
+}
+
+// CreateIndexes creates the unique index of idempotency keys. It must be
+// called before the storage is used, it does nothing if the index exists.
+func (s *OrderStorage) CreateIndexes(ctx context.Context) error {
+	_, err := s.col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "buyer", Value: 1}, {Key: "idempotency_key", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (s *OrderStorage) Find(ctx context.Context, r order.FindRequest) ([]*order.Order, error) {
+	f := bson.D{}
+	if r.Buyer != nil {
+		f = append(f, bson.E{Key: "buyer", Value: *r.Buyer})
+	}
+	if r.Seller != nil {
+		f = append(f, bson.E{Key: "seller", Value: *r.Seller})
+	}
+
+	os := make([]*order.Order, 0)
+	if r.Limit <= 0 {
+		return os, nil
+	}
+
+	// Object ids grow with creation time, so they are used as the order of
+	// creation.
+	opts := options.Find().SetSkip(r.Offset).SetLimit(r.Limit).SetSort(bson.D{{Key: "_id", Value: 1}})
+	cur, err := s.col.Find(ctx, f, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		o := &order.Order{}
+
+		if err := cur.Decode(o); err != nil {
+			return nil, err
+		}
+
+		os = append(os, o)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	return os, nil
+}
+
+func (s *OrderStorage) FindOne(ctx context.Context, id string) (*order.Order, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, order.ErrNotFound
+	}
+
+	return decodeOrder(s.col.FindOne(ctx, bson.D{{Key: "_id", Value: oid}}))
+}
+
+func (s *OrderStorage) FindByKey(ctx context.Context, buyer string, key string) (*order.Order, error) {
+	f := bson.D{{Key: "buyer", Value: buyer}, {Key: "idempotency_key", Value: key}}
+
+	return decodeOrder(s.col.FindOne(ctx, f))
+}
+
+func (s *OrderStorage) Create(ctx context.Context, o order.Order) (*order.Order, error) {
+	o.ID = ""
+
+	res, err := s.col.InsertOne(ctx, o)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return nil, order.ErrDuplicate
+		}
+		return nil, err
+	}
+
+	oid, ok := res.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("inserted id is not an object id")
+	}
+	o.ID = oid.Hex()
+
+	return &o, nil
+}
+
+func (s *OrderStorage) UpdateStatus(ctx context.Context, id string, status order.Status) (*order.Order, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, order.ErrNotFound
+	}
+
+	f := bson.D{{Key: "_id", Value: oid}}
+	u := bson.D{{Key: "$set", Value: bson.D{{Key: "status", Value: status}}}}
+	o := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	return decodeOrder(s.col.FindOneAndUpdate(ctx, f, u, o))
+}
+
+func (s *OrderStorage) Delete(ctx context.Context, id string) (*order.Order, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, order.ErrNotFound
+	}
+
+	return decodeOrder(s.col.FindOneAndDelete(ctx, bson.D{{Key: "_id", Value: oid}}))
+}
+
+// decodeOrder decodes the order of the result. It returns order.ErrNotFound
+// if there is no document.
+func decodeOrder(res *mongo.SingleResult) (*order.Order, error) {
+	o := &order.Order{}
+
+	err := res.Decode(o)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, order.ErrNotFound
+		}
+		return nil, err
+	}
+
+	// Times are decoded in the local time zone.
+	o.CreatedAt = o.CreatedAt.UTC()
+
+	return o, nil
+}
+
+// isDuplicateKey reports whether the error is a unique index violation.
+func isDuplicateKey(err error) bool {
+	var we mongo.WriteException
+	if !errors.As(err, &we) {
+		return false
+	}
+	for _, e := range we.WriteErrors {
+		if e.Code == duplicateKey {
+			return true
+		}
+	}
+	return false
+}
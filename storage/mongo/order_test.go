@@ -0,0 +1,47 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"github.com/ortymid/market/market/order"
+	"github.com/ortymid/market/storage/storagetest"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestOrderStorage_Conformance runs against a real database, it is skipped if
+// MARKET_TEST_MONGODB_URL is not set.
+func TestOrderStorage_Conformance(t *testing.T) {
+	url := os.Getenv("MARKET_TEST_MONGODB_URL")
+	if len(url) == 0 {
+		t.Skip("MARKET_TEST_MONGODB_URL is not set")
+	}
+
+	client, err := NewClientFromURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	storagetest.TestOrderStorage(t, func(t *testing.T) order.Storage {
+		name := fmt.Sprintf("orders_test_%d", time.Now().UnixNano())
+		col := client.Database("market_test").Collection(name)
+		t.Cleanup(func() {
+			col.Drop(context.Background())
+		})
+
+		s := NewOrderStorage(col)
+		if err := s.CreateIndexes(context.Background()); err != nil {
+			t.Fatalf("creating indexes: %v", err)
+		}
+		return s
+	})
+}
@@ -2,11 +2,18 @@ package mongo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/ortymid/market/market/money"
 	"github.com/ortymid/market/market/product"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"sort"
+	"time"
 )
 
 type ProductStorage struct {
@@ -17,18 +24,42 @@ func NewProductStorage(col *mongo.Collection) *ProductStorage {
 	return &ProductStorage{col: col}
 }
 
-func (s *ProductStorage) Find(ctx context.Context, r product.FindRequest) ([]*product.Product, error) {
-	opts := options.Find().SetSkip(r.Offset).SetLimit(r.Limit)
-	cur, err := s.col.Find(ctx, bson.D{}, opts)
+func (s *ProductStorage) Find(ctx context.Context, r product.FindRequest) (*product.FindResult, error) {
+	total, estimated, err := s.count(ctx, r)
 	if err != nil {
 		return nil, err
 	}
 
+	if r.Limit <= 0 {
+		return &product.FindResult{Products: []*product.Product{}, Total: total, TotalEstimated: estimated}, nil
+	}
+
+	// Cursors hold the sort values of the last product of a page, the page goes
+	// after it.
+	var after *product.Product
+	if r.Cursor != "" {
+		after, err = product.DecodeProductCursor(r.Cursor, r.Sort)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := primitive.ObjectIDFromHex(after.ID); err != nil {
+			return nil, product.ErrValidation{Resource: product.Resource, Field: "cursor", Reason: "malformed"}
+		}
+		r.Offset = 0
+	}
+
+	// One more product tells whether there are more products.
+	opts := options.Find().SetSkip(r.Offset).SetLimit(r.Limit + 1).SetSort(makeSort(r.Sort))
+	cur, err := s.col.Find(ctx, makeFilter(r, after), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
 	var ps []*product.Product
 	for cur.Next(ctx) {
-		p := &product.Product{}
-
-		if err := cur.Decode(&p); err != nil {
+		p, err := decodeProduct(cur)
+		if err != nil {
 			return nil, err
 		}
 
@@ -38,18 +69,294 @@ func (s *ProductStorage) Find(ctx context.Context, r product.FindRequest) ([]*pr
 		return nil, err
 	}
 
-	return ps, nil
+	res, err := product.MakeFindResult(ps, r.Sort, r.Limit, func(last int) interface{} {
+		return product.CursorValues(ps[last], r.Sort)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res.Total, res.TotalEstimated = total, estimated
+	return res, nil
 }
 
-func (s *ProductStorage) FindOne(ctx context.Context, id string) (*product.Product, error) {
-	oid, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
+// count returns the number of products matching the request filters. Without
+// filters the collection metadata is used, so the number is an estimate.
+func (s *ProductStorage) count(ctx context.Context, r product.FindRequest) (total int64, estimated bool, err error) {
+	f := makeFilter(r, nil)
+	if len(f) == 0 {
+		total, err = s.col.EstimatedDocumentCount(ctx)
+		return total, true, err
+	}
+
+	total, err = s.col.CountDocuments(ctx, f)
+	return total, false, err
+}
+
+// makeFilter makes a query filter applying the request filters. If after is
+// not nil, only documents going after the product in the sort order are
+// selected.
+func makeFilter(r product.FindRequest, after *product.Product) bson.D {
+	f := bson.D{}
+	// ors are the alternatives every of which must match, a document cannot
+	// have several $or keys.
+	var ors []bson.A
+
+	if r.Name != nil {
+		name := primitive.Regex{Pattern: regexp.QuoteMeta(*r.Name), Options: "i"}
+		f = append(f, bson.E{Key: "name", Value: name})
+	}
+	if ranges := r.Ranges(); len(ranges) == 1 {
+		f = append(f, makePriceRange(ranges[0])...)
+	} else if len(ranges) > 1 {
+		var inRanges bson.A
+		for _, pr := range ranges {
+			inRanges = append(inRanges, makePriceRange(pr))
+		}
+		ors = append(ors, inRanges)
+	}
+	if r.Seller != nil {
+		f = append(f, bson.E{Key: "seller", Value: *r.Seller})
+	}
+	if len(r.Categories) > 0 {
+		f = append(f, bson.E{Key: "categories", Value: bson.D{{Key: "$in", Value: r.Categories}}})
+	}
+	if len(r.Attributes) > 0 {
+		f = append(f, bson.E{Key: "variants", Value: bson.D{{Key: "$elemMatch", Value: makeAttributes(r.Attributes)}}})
+	}
+	if r.InStock != nil {
+		// Products stored before stock was introduced have no field, $nor
+		// selects them as out of stock.
+		inStock := bson.A{
+			bson.D{{Key: "stock", Value: bson.D{{Key: "$gt", Value: 0}}}},
+			bson.D{{Key: "variants.stock", Value: bson.D{{Key: "$gt", Value: 0}}}},
+		}
+		if *r.InStock {
+			ors = append(ors, inStock)
+		} else {
+			f = append(f, bson.E{Key: "$nor", Value: inStock})
+		}
+	}
+	if r.MinRating != nil {
+		f = append(f,
+			bson.E{Key: "rating.count", Value: bson.D{{Key: "$gt", Value: 0}}},
+			bson.E{Key: "rating.average", Value: bson.D{{Key: "$gte", Value: *r.MinRating}}},
+		)
+	}
+	if r.Status != nil {
+		f = append(f, statusFilter(*r.Status))
+	}
+	if !r.IncludeDeleted {
+		f = append(f, notDeleted)
+	}
+	if after != nil {
+		ors = append(ors, makeAfter(sortFields(r.Sort), after))
+	}
+
+	if len(ors) == 1 {
+		f = append(f, bson.E{Key: "$or", Value: ors[0]})
+	} else if len(ors) > 1 {
+		var all bson.A
+		for _, or := range ors {
+			all = append(all, bson.D{{Key: "$or", Value: or}})
+		}
+		f = append(f, bson.E{Key: "$and", Value: all})
+	}
+
+	return f
+}
+
+// makeAttributes makes a filter selecting variants with the attribute values.
+// The names are sorted, so that the filter does not depend on the map order.
+func makeAttributes(values map[string]string) bson.D {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	f := make(bson.D, 0, len(names))
+	for _, name := range names {
+		f = append(f, bson.E{Key: "attributes." + name, Value: values[name]})
+	}
+	return f
+}
+
+// makePriceRange makes a filter selecting documents priced within the range.
+func makePriceRange(pr product.PriceRange) bson.D {
+	f := bson.D{{Key: "price.currency", Value: pr.Currency}}
+	amount := bson.D{}
+	if pr.From != nil {
+		amount = append(amount, bson.E{Key: "$gte", Value: *pr.From})
+	}
+	if pr.To != nil {
+		amount = append(amount, bson.E{Key: "$lte", Value: *pr.To})
+	}
+	if len(amount) > 0 {
+		f = append(f, bson.E{Key: "price.amount", Value: amount})
+	}
+	return f
+}
+
+// notDeleted selects documents without the deleted_at field, it is
+// removed when products are restored.
+var notDeleted = bson.E{Key: "deleted_at", Value: nil}
+
+// statusFilter selects documents with the status. Products stored before
+// statuses were introduced have no field and are published.
+func statusFilter(status product.Status) bson.E {
+	if status == product.StatusPublished {
+		return bson.E{Key: "status", Value: bson.D{{Key: "$in", Value: bson.A{status, nil}}}}
+	}
+	return bson.E{Key: "status", Value: status}
+}
+
+// decoder is implemented by *mongo.SingleResult and *mongo.Cursor.
+type decoder interface {
+	Decode(v interface{}) error
+}
+
+// decodeProduct decodes a product document. Products stored before statuses
+// were introduced are published, and products stored before currencies were
+// introduced have a bare amount priced in the default currency.
+func decodeProduct(d decoder) (*product.Product, error) {
+	var raw bson.Raw
+	if err := d.Decode(&raw); err != nil {
 		return nil, err
 	}
+	if price, err := raw.LookupErr("price"); err == nil && price.Type != bsontype.EmbeddedDocument {
+		raw, err = upgradePrice(raw)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	p := &product.Product{}
+	if err := bson.Unmarshal(raw, p); err != nil {
+		return nil, err
+	}
+	if p.Status == "" {
+		p.Status = product.StatusPublished
+	}
+	return p, nil
+}
+
+// upgradePrice replaces the bare amount of the document price with the amount
+// in the default currency.
+func upgradePrice(raw bson.Raw) (bson.Raw, error) {
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	for i, e := range doc {
+		if e.Key != "price" {
+			continue
+		}
+		var amount int64
+		switch v := e.Value.(type) {
+		case int32:
+			amount = int64(v)
+		case int64:
+			amount = v
+		case float64:
+			amount = int64(v)
+		default:
+			return nil, fmt.Errorf("unexpected price type %T", e.Value)
+		}
+		doc[i].Value = money.New(amount, money.DefaultCurrency)
+	}
+	return bson.Marshal(doc)
+}
 
-	err = s.col.FindOne(ctx, bson.D{{"_id", oid}}).Decode(p)
+// makeAfter makes alternatives selecting documents going after the product in
+// the order of the fields, e.g. for fields a and b:
+// [{a: {$gt: 1}}, {a: 1, b: {$gt: 2}}].
+func makeAfter(fields []sortField, after *product.Product) bson.A {
+	var equal bson.D
+	var alts bson.A
+	for _, field := range fields {
+		op := "$gt"
+		if field.desc {
+			op = "$lt"
+		}
+
+		alt := append(bson.D{}, equal...)
+		alt = append(alt, bson.E{Key: field.key, Value: bson.D{{Key: op, Value: field.value(after)}}})
+		alts = append(alts, alt)
+
+		equal = append(equal, bson.E{Key: field.key, Value: field.value(after)})
+	}
+	return alts
+}
+
+// makeSort makes a sort document for the sort keys.
+func makeSort(sorts []product.Sort) bson.D {
+	d := bson.D{}
+	for _, field := range sortFields(sorts) {
+		dir := 1
+		if field.desc {
+			dir = -1
+		}
+		d = append(d, bson.E{Key: field.key, Value: dir})
+	}
+	return d
+}
+
+// sortField is a field documents are sorted by.
+type sortField struct {
+	key   string
+	desc  bool
+	value func(p *product.Product) interface{} // value of the field for the product
+}
+
+var (
+	priceField  = sortField{key: "price.amount", value: func(p *product.Product) interface{} { return p.Price.Amount }}
+	nameField   = sortField{key: "name", value: func(p *product.Product) interface{} { return p.Name }}
+	ratingField = sortField{key: "rating.average", value: func(p *product.Product) interface{} { return p.Rating.Average }}
+	idField     = sortField{key: "_id", value: func(p *product.Product) interface{} {
+		oid, _ := primitive.ObjectIDFromHex(p.ID)
+		return oid
+	}}
+)
+
+// sortFields returns the fields for the sort keys. Object ids grow with
+// creation time, so they are used as the creation order. They also break ties.
+// Relevance is not supported.
+func sortFields(sorts []product.Sort) []sortField {
+	var fields []sortField
+	for _, s := range sorts {
+		var field sortField
+		switch s.Key {
+		case product.SortKeyPrice:
+			field = priceField
+		case product.SortKeyName:
+			field = nameField
+		case product.SortKeyRating:
+			field = ratingField
+		case product.SortKeyCreated:
+			field = idField
+		default:
+			continue
+		}
+		field.desc = s.Desc
+		fields = append(fields, field)
+
+		if s.Key == product.SortKeyCreated {
+			// Ids are unique, next keys have no effect.
+			return fields
+		}
+	}
+
+	return append(fields, idField)
+}
+
+func (s *ProductStorage) FindOne(ctx context.Context, id string) (*product.Product, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, product.ErrNotFound
+	}
+
+	p, err := decodeProduct(s.col.FindOne(ctx, bson.D{{Key: "_id", Value: oid}}))
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, product.ErrNotFound
@@ -61,7 +368,16 @@ func (s *ProductStorage) FindOne(ctx context.Context, id string) (*product.Produ
 }
 
 func (s *ProductStorage) Create(ctx context.Context, r product.CreateRequest) (*product.Product, error) {
-	res, err := s.col.InsertOne(ctx, r)
+	if r.Status == "" {
+		r.Status = product.StatusPublished
+	}
+	// The zero rating is stored, so that unrated products are sorted by it.
+	doc := struct {
+		product.CreateRequest `bson:",inline"`
+		Version               int64          `bson:"version"`
+		Rating                product.Rating `bson:"rating"`
+	}{CreateRequest: r, Version: 1}
+	res, err := s.col.InsertOne(ctx, doc)
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +391,23 @@ func (s *ProductStorage) Create(ctx context.Context, r product.CreateRequest) (*
 		Name:   r.Name,
 		Price:  r.Price,
 		Seller: r.Seller,
+
+		Stock:             r.Stock,
+		LowStockThreshold: r.LowStockThreshold,
+
+		Status:    r.Status,
+		PublishAt: r.PublishAt,
+
+		Version: 1,
+	}
+	if len(r.Categories) > 0 {
+		p.Categories = r.Categories
+	}
+	if len(r.Attributes) > 0 {
+		p.Attributes = r.Attributes
+	}
+	if len(r.Variants) > 0 {
+		p.Variants = r.Variants
 	}
 	return p, nil
 }
@@ -82,34 +415,202 @@ func (s *ProductStorage) Create(ctx context.Context, r product.CreateRequest) (*
 func (s *ProductStorage) Update(ctx context.Context, r product.UpdateRequest) (*product.Product, error) {
 	oid, err := primitive.ObjectIDFromHex(r.ID)
 	if err != nil {
-		return nil, err
+		return nil, product.ErrNotFound
 	}
 
-	p := &product.Product{}
-	f := bson.D{{"_id", oid}}
-	u := bson.D{{"$set", r}}
+	// Empty categories, media, attributes and variants are removed, so that they are not stored as
+	// empty arrays.
+	var unset bson.D
+	if r.Categories != nil && len(*r.Categories) == 0 {
+		r.Categories = nil
+		unset = append(unset, bson.E{Key: "categories", Value: ""})
+	}
+	if r.Media != nil && len(*r.Media) == 0 {
+		r.Media = nil
+		unset = append(unset, bson.E{Key: "media", Value: ""})
+	}
+	if r.Attributes != nil && len(*r.Attributes) == 0 {
+		r.Attributes = nil
+		unset = append(unset, bson.E{Key: "attributes", Value: ""})
+	}
+	if r.Variants != nil && len(*r.Variants) == 0 {
+		r.Variants = nil
+		unset = append(unset, bson.E{Key: "variants", Value: ""})
+	}
+	if r.Status != nil && *r.Status != product.StatusDraft {
+		r.PublishAt = nil
+		unset = append(unset, bson.E{Key: "publish_at", Value: ""})
+	}
+
+	f := bson.D{{Key: "_id", Value: oid}, notDeleted}
+	if r.ExpectedVersion != nil {
+		f = append(f, bson.E{Key: "version", Value: *r.ExpectedVersion})
+	}
+	u := bson.D{
+		{Key: "$set", Value: r},
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: int64(1)}}},
+	}
+	if len(unset) > 0 {
+		u = append(u, bson.E{Key: "$unset", Value: unset})
+	}
 	o := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
-	err = s.col.FindOneAndUpdate(ctx, f, u, o).Decode(p)
+	p, err := decodeProduct(s.col.FindOneAndUpdate(ctx, f, u, o))
+	if err == nil {
+		return p, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	// Nothing matched, either there is no product, it is deleted or the
+	// version differs.
+	p, err = s.FindOne(ctx, r.ID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, product.ErrNotFound
+		return nil, err
+	}
+	if p.Deleted() {
+		return nil, product.ErrNotFound
+	}
+	return nil, product.ErrConflict
+}
+
+// UpdateStock increments the stock counters of the document or of its
+// variant. Negative deltas are guarded in the filter, so the document is
+// updated only if the counters stay non-negative.
+func (s *ProductStorage) UpdateStock(ctx context.Context, r product.StockRequest) (*product.Product, error) {
+	oid, err := primitive.ObjectIDFromHex(r.ID)
+	if err != nil {
+		return nil, product.ErrNotFound
+	}
+
+	f := bson.D{{Key: "_id", Value: oid}, notDeleted}
+	stock := "stock"
+	if r.Variant != "" {
+		// The variant is matched along with its guard, so that the
+		// positional operator updates it.
+		v := bson.D{{Key: "sku", Value: r.Variant}}
+		if r.Stock < 0 {
+			v = append(v, bson.E{Key: "stock", Value: bson.D{{Key: "$gte", Value: -r.Stock}}})
 		}
+		f = append(f, bson.E{Key: "variants", Value: bson.D{{Key: "$elemMatch", Value: v}}})
+		stock = "variants.$.stock"
+	} else if r.Stock < 0 {
+		f = append(f, bson.E{Key: "stock", Value: bson.D{{Key: "$gte", Value: -r.Stock}}})
+	}
+	if r.Reserved < 0 {
+		f = append(f, bson.E{Key: "reserved", Value: bson.D{{Key: "$gte", Value: -r.Reserved}}})
+	}
+	u := bson.D{{Key: "$inc", Value: bson.D{
+		{Key: stock, Value: r.Stock},
+		{Key: "reserved", Value: r.Reserved},
+	}}}
+	o := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	p, err := decodeProduct(s.col.FindOneAndUpdate(ctx, f, u, o))
+	if err == nil {
+		return p, nil
+	}
+	if err != mongo.ErrNoDocuments {
 		return nil, err
 	}
 
-	return p, nil
+	// Nothing matched, either there is no product, it is deleted, it has no
+	// such variant or the guard failed.
+	p, err = s.FindOne(ctx, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Deleted() {
+		return nil, product.ErrNotFound
+	}
+	if _, ok := p.Variant(r.Variant); r.Variant != "" && !ok {
+		return nil, product.ErrVariantNotFound
+	}
+	return nil, product.ErrInsufficientStock
 }
 
-func (s *ProductStorage) Delete(ctx context.Context, id string) (*product.Product, error) {
+// UpdateRating sets the rating of the document, deleted or not.
+func (s *ProductStorage) UpdateRating(ctx context.Context, id string, rating product.Rating) (*product.Product, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, product.ErrNotFound
+	}
+
+	f := bson.D{{Key: "_id", Value: oid}}
+	u := bson.D{{Key: "$set", Value: bson.D{{Key: "rating", Value: rating}}}}
+	return s.findOneAndUpdate(ctx, f, u)
+}
+
+// SummarizeSellers groups the products by seller. Products stored before
+// ratings were introduced are counted as unrated.
+func (s *ProductStorage) SummarizeSellers(ctx context.Context, sellers []string) (map[string]product.SellerSummary, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "seller", Value: bson.D{{Key: "$in", Value: sellers}}},
+			statusFilter(product.StatusPublished),
+			notDeleted,
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$seller"},
+			{Key: "products", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "rating_sum", Value: bson.D{{Key: "$sum", Value: bson.D{
+				{Key: "$multiply", Value: bson.A{"$rating.average", "$rating.count"}},
+			}}}},
+			{Key: "rating_count", Value: bson.D{{Key: "$sum", Value: "$rating.count"}}},
+		}}},
+	}
+	cur, err := s.col.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
+	defer cur.Close(ctx)
 
-	p := &product.Product{}
+	var groups []struct {
+		Seller      string  `bson:"_id"`
+		Products    int64   `bson:"products"`
+		RatingSum   float64 `bson:"rating_sum"`
+		RatingCount int64   `bson:"rating_count"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		return nil, err
+	}
 
-	err = s.col.FindOneAndDelete(ctx, bson.D{{"_id", oid}}).Decode(p)
+	summaries := make(map[string]product.SellerSummary, len(groups))
+	for _, g := range groups {
+		summaries[g.Seller] = product.NewSellerSummary(g.Products, g.RatingSum, g.RatingCount)
+	}
+	return summaries, nil
+}
+
+func (s *ProductStorage) Delete(ctx context.Context, id string, at time.Time) (*product.Product, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, product.ErrNotFound
+	}
+
+	f := bson.D{{Key: "_id", Value: oid}, notDeleted}
+	u := bson.D{{Key: "$set", Value: bson.D{{Key: "deleted_at", Value: at}}}}
+	return s.findOneAndUpdate(ctx, f, u)
+}
+
+func (s *ProductStorage) Restore(ctx context.Context, id string) (*product.Product, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, product.ErrNotFound
+	}
+
+	f := bson.D{{Key: "_id", Value: oid}, {Key: "deleted_at", Value: bson.D{{Key: "$ne", Value: nil}}}}
+	u := bson.D{{Key: "$unset", Value: bson.D{{Key: "deleted_at", Value: ""}}}}
+	return s.findOneAndUpdate(ctx, f, u)
+}
+
+// findOneAndUpdate updates the document matching the filter and returns the
+// updated product. It returns product.ErrNotFound if nothing matched.
+func (s *ProductStorage) findOneAndUpdate(ctx context.Context, f, u bson.D) (*product.Product, error) {
+	o := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	p, err := decodeProduct(s.col.FindOneAndUpdate(ctx, f, u, o))
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, product.ErrNotFound
@@ -119,3 +620,56 @@ func (s *ProductStorage) Delete(ctx context.Context, id string) (*product.Produc
 
 	return p, nil
 }
+
+func (s *ProductStorage) Purge(ctx context.Context, before time.Time) (int64, error) {
+	f := bson.D{{Key: "deleted_at", Value: bson.D{{Key: "$lt", Value: before}}}}
+	res, err := s.col.DeleteMany(ctx, f)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
+
+// PublishDue publishes the drafts found one by one. Every draft is updated
+// with the filter it is found with, so a draft changed concurrently is
+// skipped.
+func (s *ProductStorage) PublishDue(ctx context.Context, now time.Time) ([]*product.Product, error) {
+	due := bson.D{
+		{Key: "status", Value: product.StatusDraft},
+		{Key: "publish_at", Value: bson.D{{Key: "$lte", Value: now}}},
+		notDeleted,
+	}
+	cur, err := s.col.Find(ctx, due, options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var docs []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	u := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "status", Value: product.StatusPublished}}},
+		{Key: "$unset", Value: bson.D{{Key: "publish_at", Value: ""}}},
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: int64(1)}}},
+	}
+
+	var ps []*product.Product
+	for _, doc := range docs {
+		f := append(bson.D{{Key: "_id", Value: doc.ID}}, due...)
+		p, err := s.findOneAndUpdate(ctx, f, u)
+		if errors.Is(err, product.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return ps, err
+		}
+
+		ps = append(ps, p)
+	}
+
+	return ps, nil
+}
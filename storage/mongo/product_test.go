@@ -0,0 +1,240 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/storage/storagetest"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestProductStorage_Conformance runs against a real database, it is skipped
+// if MARKET_TEST_MONGODB_URL is not set.
+func TestProductStorage_Conformance(t *testing.T) {
+	url := os.Getenv("MARKET_TEST_MONGODB_URL")
+	if len(url) == 0 {
+		t.Skip("MARKET_TEST_MONGODB_URL is not set")
+	}
+
+	client, err := NewClientFromURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	storagetest.TestProductStorage(t, func(t *testing.T) product.Storage {
+		name := fmt.Sprintf("products_test_%d", time.Now().UnixNano())
+		col := client.Database("market_test").Collection(name)
+		t.Cleanup(func() {
+			col.Drop(context.Background())
+		})
+
+		return NewProductStorage(col)
+	})
+}
+
+func Test_makeFilter(t *testing.T) {
+	oid := primitive.NewObjectID()
+
+	tests := []struct {
+		name  string
+		r     product.FindRequest
+		after *product.Product
+		want  bson.D
+	}{
+		{
+			name: "Should exclude deleted products without filters",
+			r:    product.FindRequest{Offset: 0, Limit: 10},
+			want: bson.D{{Key: "deleted_at", Value: nil}},
+		},
+		{
+			name: "Should make empty filter including deleted products",
+			r:    product.FindRequest{Offset: 0, Limit: 10, IncludeDeleted: true},
+			want: bson.D{},
+		},
+		{
+			name: "Should make filter with all filters",
+			r: product.FindRequest{
+				Name: testPtrString("a.b"),
+				PriceRange: &product.PriceRange{
+					From:     testPtrInt64(10),
+					To:       testPtrInt64(100),
+					Currency: "EUR",
+				},
+				Seller:     testPtrString("1"),
+				Categories: []string{"1", "2"},
+			},
+			want: bson.D{
+				{Key: "name", Value: primitive.Regex{Pattern: `a\.b`, Options: "i"}},
+				{Key: "price.currency", Value: "EUR"},
+				{Key: "price.amount", Value: bson.D{{Key: "$gte", Value: int64(10)}, {Key: "$lte", Value: int64(100)}}},
+				{Key: "seller", Value: "1"},
+				{Key: "categories", Value: bson.D{{Key: "$in", Value: []string{"1", "2"}}}},
+				{Key: "deleted_at", Value: nil},
+			},
+		},
+		{
+			name: "Should make filter with attribute values of one variant",
+			r:    product.FindRequest{Attributes: map[string]string{"size": "42", "color": "red"}},
+			want: bson.D{
+				{Key: "variants", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+					{Key: "attributes.color", Value: "red"},
+					{Key: "attributes.size", Value: "42"},
+				}}}},
+				{Key: "deleted_at", Value: nil},
+			},
+		},
+		{
+			name: "Should make filter with published products stored without status",
+			r:    product.FindRequest{Status: testPtrStatus(product.StatusPublished)},
+			want: bson.D{
+				{Key: "status", Value: bson.D{{Key: "$in", Value: bson.A{product.StatusPublished, nil}}}},
+				{Key: "deleted_at", Value: nil},
+			},
+		},
+		{
+			name: "Should make filter with draft products",
+			r:    product.FindRequest{Status: testPtrStatus(product.StatusDraft)},
+			want: bson.D{
+				{Key: "status", Value: product.StatusDraft},
+				{Key: "deleted_at", Value: nil},
+			},
+		},
+		{
+			name: "Should make filter selecting documents after the product in sort order",
+			r: product.FindRequest{
+				Seller: testPtrString("1"),
+				Sort:   []product.Sort{{Key: product.SortKeyPrice, Desc: true}},
+			},
+			after: &product.Product{ID: oid.Hex(), Price: money.New(100, "USD")},
+			want: bson.D{
+				{Key: "seller", Value: "1"},
+				{Key: "deleted_at", Value: nil},
+				{Key: "$or", Value: bson.A{
+					bson.D{{Key: "price.amount", Value: bson.D{{Key: "$lt", Value: int64(100)}}}},
+					bson.D{
+						{Key: "price.amount", Value: int64(100)},
+						{Key: "_id", Value: bson.D{{Key: "$gt", Value: oid}}},
+					},
+				}},
+			},
+		},
+		{
+			name: "Should make filter with any of price ranges after the product",
+			r: product.FindRequest{
+				PriceRange:  &product.PriceRange{To: testPtrInt64(100), Currency: "EUR"},
+				PriceRanges: []product.PriceRange{{Currency: "JPY"}},
+				Sort:        []product.Sort{{Key: product.SortKeyPrice}},
+			},
+			after: &product.Product{ID: oid.Hex(), Price: money.New(100, "EUR")},
+			want: bson.D{
+				{Key: "deleted_at", Value: nil},
+				{Key: "$and", Value: bson.A{
+					bson.D{{Key: "$or", Value: bson.A{
+						bson.D{
+							{Key: "price.currency", Value: "EUR"},
+							{Key: "price.amount", Value: bson.D{{Key: "$lte", Value: int64(100)}}},
+						},
+						bson.D{{Key: "price.currency", Value: "JPY"}},
+					}}},
+					bson.D{{Key: "$or", Value: bson.A{
+						bson.D{{Key: "price.amount", Value: bson.D{{Key: "$gt", Value: int64(100)}}}},
+						bson.D{
+							{Key: "price.amount", Value: int64(100)},
+							{Key: "_id", Value: bson.D{{Key: "$gt", Value: oid}}},
+						},
+					}}},
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := makeFilter(tt.r, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("makeFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// rawDecoder decodes the document it holds.
+type rawDecoder bson.Raw
+
+func (d rawDecoder) Decode(v interface{}) error {
+	return bson.Unmarshal(d, v)
+}
+
+func Test_decodeProduct(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  bson.D
+		want *product.Product
+	}{
+		{
+			name: "Should decode price with currency",
+			doc: bson.D{
+				{Key: "name", Value: "Banana"},
+				{Key: "price", Value: bson.D{{Key: "amount", Value: int64(1500)}, {Key: "currency", Value: "EUR"}}},
+				{Key: "status", Value: "draft"},
+			},
+			want: &product.Product{Name: "Banana", Price: money.New(1500, "EUR"), Status: product.StatusDraft},
+		},
+		{
+			name: "Should decode bare price of products stored before currencies",
+			doc: bson.D{
+				{Key: "name", Value: "Banana"},
+				{Key: "price", Value: int64(1500)},
+			},
+			want: &product.Product{Name: "Banana", Price: money.New(1500, "USD"), Status: product.StatusPublished},
+		},
+		{
+			name: "Should decode bare 32-bit price",
+			doc: bson.D{
+				{Key: "name", Value: "Banana"},
+				{Key: "price", Value: int32(1500)},
+			},
+			want: &product.Product{Name: "Banana", Price: money.New(1500, "USD"), Status: product.StatusPublished},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := bson.Marshal(tt.doc)
+			if err != nil {
+				t.Fatalf("marshaling document: %v", err)
+			}
+
+			got, err := decodeProduct(rawDecoder(b))
+			if err != nil {
+				t.Fatalf("decodeProduct() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeProduct() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testPtrString(v string) *string {
+	return &v
+}
+
+func testPtrInt64(v int64) *int64 {
+	return &v
+}
+
+func testPtrStatus(v product.Status) *product.Status {
+	return &v
+}
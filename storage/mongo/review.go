@@ -0,0 +1,183 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"github.com/ortymid/market/market/review"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// ReviewStorage keeps reviews in a collection. Reviews are unique per product
+// and author, the index is created by CreateIndexes.
+type ReviewStorage struct {
+	col *mongo.Collection
+}
+
+func NewReviewStorage(col *mongo.Collection) *ReviewStorage {
+	return &ReviewStorage{col: col}
+}
+
+// CreateIndexes creates the unique index of reviews by product and author. It
+// must be called before the storage is used, it does nothing if the index
+// exists.
+func (s *ReviewStorage) CreateIndexes(ctx context.Context) error {
+	_, err := s.col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "product", Value: 1}, {Key: "author", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (s *ReviewStorage) Find(ctx context.Context, r review.FindRequest) ([]*review.Review, error) {
+	f := bson.D{}
+	if r.Product != nil {
+		f = append(f, bson.E{Key: "product", Value: *r.Product})
+	}
+	if r.Author != nil {
+		f = append(f, bson.E{Key: "author", Value: *r.Author})
+	}
+
+	rvs := make([]*review.Review, 0)
+	if r.Limit <= 0 {
+		return rvs, nil
+	}
+
+	// Object ids grow with creation time, so they are used as the order of
+	// creation.
+	opts := options.Find().SetSkip(r.Offset).SetLimit(r.Limit).SetSort(bson.D{{Key: "_id", Value: 1}})
+	cur, err := s.col.Find(ctx, f, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		rv := &review.Review{}
+
+		if err := cur.Decode(rv); err != nil {
+			return nil, err
+		}
+		rv.CreatedAt, rv.UpdatedAt = rv.CreatedAt.UTC(), rv.UpdatedAt.UTC()
+
+		rvs = append(rvs, rv)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	return rvs, nil
+}
+
+func (s *ReviewStorage) FindOne(ctx context.Context, id string) (*review.Review, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, review.ErrNotFound
+	}
+
+	return decodeReview(s.col.FindOne(ctx, bson.D{{Key: "_id", Value: oid}}))
+}
+
+func (s *ReviewStorage) Create(ctx context.Context, rv review.Review) (*review.Review, error) {
+	rv.ID = ""
+
+	res, err := s.col.InsertOne(ctx, rv)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return nil, review.ErrDuplicate
+		}
+		return nil, err
+	}
+
+	oid, ok := res.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("inserted id is not an object id")
+	}
+	rv.ID = oid.Hex()
+
+	return &rv, nil
+}
+
+func (s *ReviewStorage) Update(ctx context.Context, r review.UpdateRequest, at time.Time) (*review.Review, error) {
+	oid, err := primitive.ObjectIDFromHex(r.ID)
+	if err != nil {
+		return nil, review.ErrNotFound
+	}
+
+	set := bson.D{{Key: "updated_at", Value: at}}
+	if r.Rating != nil {
+		set = append(set, bson.E{Key: "rating", Value: *r.Rating})
+	}
+	if r.Text != nil {
+		set = append(set, bson.E{Key: "text", Value: *r.Text})
+	}
+
+	f := bson.D{{Key: "_id", Value: oid}}
+	u := bson.D{{Key: "$set", Value: set}}
+	o := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	return decodeReview(s.col.FindOneAndUpdate(ctx, f, u, o))
+}
+
+func (s *ReviewStorage) Delete(ctx context.Context, id string) (*review.Review, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, review.ErrNotFound
+	}
+
+	return decodeReview(s.col.FindOneAndDelete(ctx, bson.D{{Key: "_id", Value: oid}}))
+}
+
+// Summarize groups the reviews of the product. There are no groups if the
+// product has no reviews.
+func (s *ReviewStorage) Summarize(ctx context.Context, product string) (review.Summary, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "product", Value: product}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "sum", Value: bson.D{{Key: "$sum", Value: "$rating"}}},
+		}}},
+	}
+	cur, err := s.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return review.Summary{}, err
+	}
+	defer cur.Close(ctx)
+
+	var groups []struct {
+		Count int64 `bson:"count"`
+		Sum   int64 `bson:"sum"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		return review.Summary{}, err
+	}
+	if len(groups) == 0 {
+		return review.Summary{}, nil
+	}
+
+	return review.Summary{Count: groups[0].Count, Sum: groups[0].Sum}, nil
+}
+
+// decodeReview decodes the review of the result. It returns
+// review.ErrNotFound if there is no document.
+func decodeReview(res *mongo.SingleResult) (*review.Review, error) {
+	rv := &review.Review{}
+
+	err := res.Decode(rv)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, review.ErrNotFound
+		}
+		return nil, err
+	}
+
+	// Times are decoded in the local time zone.
+	rv.CreatedAt = rv.CreatedAt.UTC()
+	rv.UpdatedAt = rv.UpdatedAt.UTC()
+
+	return rv, nil
+}
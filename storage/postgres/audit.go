@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/ortymid/market/market/audit"
+)
+
+// auditColumns are the columns entries are selected with, in the order
+// scanEntry expects them.
+const auditColumns = "id, product, seller, actor, operation, version, changes, created_at"
+
+// AuditStorage keeps entries in a table with changes in a JSONB column.
+type AuditStorage struct {
+	db    *sql.DB
+	table string
+}
+
+func NewAuditStorage(db *sql.DB, table string) *AuditStorage {
+	return &AuditStorage{db: db, table: table}
+}
+
+func (s *AuditStorage) Record(ctx context.Context, e audit.Entry) error {
+	changes := e.Changes
+	if changes == nil {
+		changes = []audit.Change{}
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("encoding changes: %w", err)
+	}
+
+	query := fmt.Sprintf(
+		`INSERT INTO %s (product, seller, actor, operation, version, changes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		s.table,
+	)
+
+	_, err = s.db.ExecContext(ctx, query, e.Product, e.Seller, e.Actor, e.Operation, e.Version, data, e.CreatedAt)
+	return err
+}
+
+func (s *AuditStorage) Find(ctx context.Context, r audit.FindRequest) ([]*audit.Entry, error) {
+	query := fmt.Sprintf(
+		`SELECT %s FROM %s WHERE product = $1 ORDER BY id LIMIT $2 OFFSET $3`,
+		auditColumns, s.table,
+	)
+
+	rows, err := s.db.QueryContext(ctx, query, r.Product, r.Limit, r.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	es := make([]*audit.Entry, 0, r.Limit)
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		es = append(es, e)
+	}
+
+	err = rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return es, nil
+}
+
+// scanEntry scans an entry selected with auditColumns.
+func scanEntry(row scanner) (*audit.Entry, error) {
+	var e audit.Entry
+	var changes []byte
+	err := row.Scan(&e.ID, &e.Product, &e.Seller, &e.Actor, &e.Operation, &e.Version, &changes, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(changes, &e.Changes); err != nil {
+		return nil, fmt.Errorf("decoding changes: %w", err)
+	}
+	e.CreatedAt = e.CreatedAt.UTC()
+
+	return &e, nil
+}
@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"github.com/ortymid/market/market/audit"
+	"github.com/ortymid/market/storage/storagetest"
+	"os"
+	"testing"
+)
+
+// TestAuditStorage_Conformance runs against a real database, it is skipped if
+// MARKET_TEST_POSTGRES_URL is not set.
+func TestAuditStorage_Conformance(t *testing.T) {
+	url := os.Getenv("MARKET_TEST_POSTGRES_URL")
+	if len(url) == 0 {
+		t.Skip("MARKET_TEST_POSTGRES_URL is not set")
+	}
+
+	db, err := NewDBFromURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	storagetest.TestAuditStorage(t, func(t *testing.T) audit.Storage {
+		table := createTable(t, db, "init-product-history-table.sh", "product_history")
+		return NewAuditStorage(db, table)
+	})
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ortymid/market/market/cart"
+)
+
+// cartColumns are the columns carts are selected with, in the order scanCart
+// expects them.
+const cartColumns = "user_id, items, updated_at, expires_at"
+
+// CartStorage keeps carts in a table keyed by user_id. Items of a cart are
+// kept as a JSON array. Expired carts are kept until they are deleted.
+type CartStorage struct {
+	db    *sql.DB
+	table string
+}
+
+func NewCartStorage(db *sql.DB, table string) *CartStorage {
+	return &CartStorage{db: db, table: table}
+}
+
+func (s *CartStorage) Get(ctx context.Context, user string) (*cart.Cart, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE user_id = $1`, cartColumns, s.table)
+
+	c, err := scanCart(s.db.QueryRowContext(ctx, query, user))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, cart.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (s *CartStorage) Save(ctx context.Context, c cart.Cart) (*cart.Cart, error) {
+	items, err := json.Marshal(itemsOrEmpty(c.Items))
+	if err != nil {
+		return nil, fmt.Errorf("encoding items: %w", err)
+	}
+
+	query := fmt.Sprintf(
+		`INSERT INTO %s (%s) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET items = $2, updated_at = $3, expires_at = $4`,
+		s.table, cartColumns,
+	)
+
+	_, err = s.db.ExecContext(ctx, query, c.User, items, c.UpdatedAt, c.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (s *CartStorage) Delete(ctx context.Context, user string) (*cart.Cart, error) {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE user_id = $1 RETURNING %s`, s.table, cartColumns)
+
+	c, err := scanCart(s.db.QueryRowContext(ctx, query, user))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, cart.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func scanCart(row scanner) (*cart.Cart, error) {
+	var c cart.Cart
+	var items []byte
+	err := row.Scan(&c.User, &items, &c.UpdatedAt, &c.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	c.UpdatedAt = c.UpdatedAt.UTC()
+	c.ExpiresAt = c.ExpiresAt.UTC()
+
+	if err := json.Unmarshal(items, &c.Items); err != nil {
+		return nil, fmt.Errorf("decoding items: %w", err)
+	}
+
+	return &c, nil
+}
+
+// itemsOrEmpty returns an empty slice for nil items, so they are stored as an
+// empty array rather than null.
+func itemsOrEmpty(items []cart.Item) []cart.Item {
+	if items == nil {
+		return []cart.Item{}
+	}
+	return items
+}
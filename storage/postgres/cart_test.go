@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"github.com/ortymid/market/market/cart"
+	"github.com/ortymid/market/storage/storagetest"
+	"os"
+	"testing"
+)
+
+// TestCartStorage_Conformance runs against a real database, it is skipped if
+// MARKET_TEST_POSTGRES_URL is not set.
+func TestCartStorage_Conformance(t *testing.T) {
+	url := os.Getenv("MARKET_TEST_POSTGRES_URL")
+	if len(url) == 0 {
+		t.Skip("MARKET_TEST_POSTGRES_URL is not set")
+	}
+
+	db, err := NewDBFromURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	storagetest.TestCartStorage(t, func(t *testing.T) cart.Storage {
+		table := createTable(t, db, "init-cart-table.sh", "carts")
+		return NewCartStorage(db, table)
+	})
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ortymid/market/market/category"
+)
+
+// categoryColumns are the columns categories are selected with, in the order
+// scanCategory expects them.
+const categoryColumns = "id, slug, name, parent"
+
+// CategoryStorage keeps categories in a table referencing parents by id.
+type CategoryStorage struct {
+	db    *sql.DB
+	table string
+}
+
+func NewCategoryStorage(db *sql.DB, table string) *CategoryStorage {
+	return &CategoryStorage{db: db, table: table}
+}
+
+func (s *CategoryStorage) Find(ctx context.Context) ([]*category.Category, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s`, categoryColumns, s.table)
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	cs := make([]*category.Category, 0)
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		cs = append(cs, c)
+	}
+
+	err = rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+func (s *CategoryStorage) FindOne(ctx context.Context, id string) (*category.Category, error) {
+	if !isValidID(id) {
+		return nil, category.ErrNotFound
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1`, categoryColumns, s.table)
+
+	c, err := scanCategory(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, category.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (s *CategoryStorage) Create(ctx context.Context, r category.CreateRequest) (*category.Category, error) {
+	query := fmt.Sprintf(
+		`INSERT INTO %s (slug, name, parent) VALUES ($1, $2, $3) RETURNING %s`,
+		s.table, categoryColumns,
+	)
+
+	row := s.db.QueryRowContext(ctx, query, r.Slug, r.Name, parentValue(r.Parent))
+	return scanCategory(row)
+}
+
+func (s *CategoryStorage) Update(ctx context.Context, r category.UpdateRequest) (*category.Category, error) {
+	c, err := s.FindOne(ctx, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Slug != nil {
+		c.Slug = *r.Slug
+	}
+	if r.Name != nil {
+		c.Name = *r.Name
+	}
+	if r.Parent != nil {
+		c.Parent = r.Parent
+	}
+
+	query := fmt.Sprintf(
+		`UPDATE %s SET slug = $2, name = $3, parent = $4 WHERE id = $1 RETURNING %s`,
+		s.table, categoryColumns,
+	)
+	c, err = scanCategory(s.db.QueryRowContext(ctx, query, c.ID, c.Slug, c.Name, parentValue(c.Parent)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, category.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (s *CategoryStorage) Delete(ctx context.Context, id string) (*category.Category, error) {
+	if !isValidID(id) {
+		return nil, category.ErrNotFound
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1 RETURNING %s`, s.table, categoryColumns)
+
+	c, err := scanCategory(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, category.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// parentValue returns the value of the parent column. Root categories have
+// NULL parents.
+func parentValue(parent *string) sql.NullString {
+	if parent == nil || *parent == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *parent, Valid: true}
+}
+
+// scanCategory scans a category selected with categoryColumns.
+func scanCategory(row scanner) (*category.Category, error) {
+	var c category.Category
+	var parent sql.NullString
+	if err := row.Scan(&c.ID, &c.Slug, &c.Name, &parent); err != nil {
+		return nil, err
+	}
+	if parent.Valid {
+		c.Parent = &parent.String
+	}
+
+	return &c, nil
+}
@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"github.com/ortymid/market/market/category"
+	"github.com/ortymid/market/storage/storagetest"
+	"os"
+	"testing"
+)
+
+// TestCategoryStorage_Conformance runs against a real database, it is skipped
+// if MARKET_TEST_POSTGRES_URL is not set.
+func TestCategoryStorage_Conformance(t *testing.T) {
+	url := os.Getenv("MARKET_TEST_POSTGRES_URL")
+	if len(url) == 0 {
+		t.Skip("MARKET_TEST_POSTGRES_URL is not set")
+	}
+
+	db, err := NewDBFromURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	storagetest.TestCategoryStorage(t, func(t *testing.T) category.Storage {
+		table := createTable(t, db, "init-category-table.sh", "categories")
+		return NewCategoryStorage(db, table)
+	})
+}
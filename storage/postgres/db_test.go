@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createTable creates a table with the schema of the init script from
+// scripts/postgres, so the tests run against the schema the server is
+// deployed with. The table and its indexes are renamed after name, and the
+// table is dropped after the test.
+func createTable(t *testing.T, db *sql.DB, script string, name string) string {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(filepath.Join("..", "..", "scripts", "postgres", script))
+	if err != nil {
+		t.Fatalf("reading init script: %v", err)
+	}
+
+	table := fmt.Sprintf("%s_test_%d", name, time.Now().UnixNano())
+	query := regexp.MustCompile(`\b`+regexp.QuoteMeta(name)).ReplaceAllString(scriptSQL(string(b)), table)
+	if _, err := db.Exec(query); err != nil {
+		t.Fatalf("creating table: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Exec(fmt.Sprintf(`DROP TABLE %s`, table))
+	})
+
+	return table
+}
+
+// scriptSQL returns the SQL of the EOSQL here-document of an init script.
+func scriptSQL(script string) string {
+	var lines []string
+	in := false
+	for _, line := range strings.Split(script, "\n") {
+		switch {
+		case !in:
+			in = strings.HasSuffix(line, "<<-EOSQL")
+		case strings.TrimSpace(line) == "EOSQL":
+			return strings.Join(lines, "\n")
+		default:
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func Test_scriptSQL(t *testing.T) {
+	scripts, err := filepath.Glob(filepath.Join("..", "..", "scripts", "postgres", "*.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) == 0 {
+		t.Fatal("no init scripts found")
+	}
+	for _, script := range scripts {
+		t.Run(filepath.Base(script), func(t *testing.T) {
+			b, err := ioutil.ReadFile(script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.TrimSpace(scriptSQL(string(b)))
+			if !strings.HasPrefix(got, "CREATE TABLE") || strings.Contains(got, "EOSQL") {
+				t.Errorf("scriptSQL() = %q, want the statements of the script", got)
+			}
+		})
+	}
+}
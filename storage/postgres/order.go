@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/ortymid/market/market/order"
+	"strings"
+)
+
+// orderColumns are the columns orders are selected with, in the order
+// scanOrder expects them.
+const orderColumns = "id, product, variant, buyer, seller, quantity, price, total, status, idempotency_key, created_at"
+
+// uniqueViolation is the error code of unique constraint violations.
+const uniqueViolation = "23505"
+
+// OrderStorage keeps orders in a table with a unique constraint on buyer and
+// idempotency_key.
+type OrderStorage struct {
+	db    *sql.DB
+	table string
+}
+
+func NewOrderStorage(db *sql.DB, table string) *OrderStorage {
+	return &OrderStorage{db: db, table: table}
+}
+
+func (s *OrderStorage) Find(ctx context.Context, r order.FindRequest) ([]*order.Order, error) {
+	var conds []string
+	var args []interface{}
+	if r.Buyer != nil {
+		args = append(args, *r.Buyer)
+		conds = append(conds, fmt.Sprintf("buyer = $%d", len(args)))
+	}
+	if r.Seller != nil {
+		args = append(args, *r.Seller)
+		conds = append(conds, fmt.Sprintf("seller = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	query := fmt.Sprintf(
+		`SELECT %s FROM %s %s ORDER BY id LIMIT $%d OFFSET $%d`,
+		orderColumns, s.table, where, len(args)+1, len(args)+2,
+	)
+	args = append(args, r.Limit, r.Offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	os := make([]*order.Order, 0, r.Limit)
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		os = append(os, o)
+	}
+
+	err = rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return os, nil
+}
+
+func (s *OrderStorage) FindOne(ctx context.Context, id string) (*order.Order, error) {
+	if !isValidID(id) {
+		return nil, order.ErrNotFound
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1`, orderColumns, s.table)
+
+	return s.queryOrder(ctx, query, id)
+}
+
+func (s *OrderStorage) FindByKey(ctx context.Context, buyer string, key string) (*order.Order, error) {
+	query := fmt.Sprintf(
+		`SELECT %s FROM %s WHERE buyer = $1 AND idempotency_key = $2`,
+		orderColumns, s.table,
+	)
+
+	return s.queryOrder(ctx, query, buyer, key)
+}
+
+func (s *OrderStorage) Create(ctx context.Context, o order.Order) (*order.Order, error) {
+	query := fmt.Sprintf(
+		`INSERT INTO %s (product, variant, buyer, seller, quantity, price, total, status, idempotency_key, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING %s`,
+		s.table, orderColumns,
+	)
+
+	created, err := scanOrder(s.db.QueryRowContext(
+		ctx, query,
+		o.Product, o.Variant, o.Buyer, o.Seller, o.Quantity, o.Price, o.Total, o.Status, o.IdempotencyKey, o.CreatedAt,
+	))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return nil, order.ErrDuplicate
+		}
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (s *OrderStorage) UpdateStatus(ctx context.Context, id string, status order.Status) (*order.Order, error) {
+	if !isValidID(id) {
+		return nil, order.ErrNotFound
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET status = $2 WHERE id = $1 RETURNING %s`, s.table, orderColumns)
+
+	return s.queryOrder(ctx, query, id, status)
+}
+
+func (s *OrderStorage) Delete(ctx context.Context, id string) (*order.Order, error) {
+	if !isValidID(id) {
+		return nil, order.ErrNotFound
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1 RETURNING %s`, s.table, orderColumns)
+
+	return s.queryOrder(ctx, query, id)
+}
+
+// queryOrder scans the order returned by the query. It returns
+// order.ErrNotFound if there are no rows.
+func (s *OrderStorage) queryOrder(ctx context.Context, query string, args ...interface{}) (*order.Order, error) {
+	o, err := scanOrder(s.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, order.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// scanOrder scans an order selected with orderColumns.
+func scanOrder(row scanner) (*order.Order, error) {
+	var o order.Order
+	err := row.Scan(
+		&o.ID, &o.Product, &o.Variant, &o.Buyer, &o.Seller, &o.Quantity, &o.Price, &o.Total,
+		&o.Status, &o.IdempotencyKey, &o.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	o.CreatedAt = o.CreatedAt.UTC()
+
+	return &o, nil
+}
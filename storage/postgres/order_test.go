@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"github.com/ortymid/market/market/order"
+	"github.com/ortymid/market/storage/storagetest"
+	"os"
+	"testing"
+)
+
+// TestOrderStorage_Conformance runs against a real database, it is skipped if
+// MARKET_TEST_POSTGRES_URL is not set.
+func TestOrderStorage_Conformance(t *testing.T) {
+	url := os.Getenv("MARKET_TEST_POSTGRES_URL")
+	if len(url) == 0 {
+		t.Skip("MARKET_TEST_POSTGRES_URL is not set")
+	}
+
+	db, err := NewDBFromURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	storagetest.TestOrderStorage(t, func(t *testing.T) order.Storage {
+		table := createTable(t, db, "init-order-table.sh", "orders")
+		return NewOrderStorage(db, table)
+	})
+}
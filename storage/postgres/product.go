@@ -3,10 +3,20 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/lib/pq"
 	"github.com/ortymid/market/market/product"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// productColumns are the columns products are selected with, in the order
+// scanProduct expects them.
+const productColumns = "id, name, price, seller, categories, stock, reserved, low_stock_threshold, version, deleted_at, status, publish_at, currency, media, attributes, variants, rating_average, rating_count"
+
 type ProductStorage struct {
 	db    *sql.DB
 	table string
@@ -16,31 +26,52 @@ func NewProductStorage(db *sql.DB, table string) *ProductStorage {
 	return &ProductStorage{db: db, table: table}
 }
 
-func (s *ProductStorage) Find(ctx context.Context, r product.FindRequest) ([]*product.Product, error) {
+func (s *ProductStorage) Find(ctx context.Context, r product.FindRequest) (*product.FindResult, error) {
+	total, err := s.count(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Limit <= 0 {
+		return &product.FindResult{Products: []*product.Product{}, Total: total}, nil
+	}
+
+	// Cursors hold the sort values of the last product of a page, the page goes
+	// after it.
+	var after *product.Product
+	if r.Cursor != "" {
+		after, err = product.DecodeProductCursor(r.Cursor, r.Sort)
+		if err != nil {
+			return nil, err
+		}
+		if !isValidID(after.ID) {
+			return nil, product.ErrValidation{Resource: product.Resource, Field: "cursor", Reason: "malformed"}
+		}
+		r.Offset = 0
+	}
+
+	where, args := makeWhere(r, after)
 	query := fmt.Sprintf(
-		`SELECT id, name, price, seller FROM %s LIMIT $1 OFFSET $2`,
-		s.table,
+		`SELECT %s FROM %s %s %s LIMIT $%d OFFSET $%d`,
+		productColumns, s.table, where, makeOrderBy(r.Sort), len(args)+1, len(args)+2,
 	)
+	// One more product tells whether there are more products.
+	args = append(args, r.Limit+1, r.Offset)
 
-	rows, err := s.db.QueryContext(ctx, query, r.Limit, r.Offset)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	ps := make([]*product.Product, 0, r.Limit)
+	ps := make([]*product.Product, 0, r.Limit+1)
 	for rows.Next() {
-		var id, name, seller string
-		var price int64
-		if err := rows.Scan(&id, &name, &price, &seller); err != nil {
-			return ps, err
+		p, err := scanProduct(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
 		}
 
-		ps = append(ps, &product.Product{
-			ID:     id,
-			Name:   name,
-			Price:  price,
-			Seller: seller,
-		})
+		ps = append(ps, p)
 	}
 
 	err = rows.Close()
@@ -52,93 +83,641 @@ func (s *ProductStorage) Find(ctx context.Context, r product.FindRequest) ([]*pr
 		return nil, err
 	}
 
-	return ps, nil
+	res, err := product.MakeFindResult(ps, r.Sort, r.Limit, func(last int) interface{} {
+		return product.CursorValues(ps[last], r.Sort)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res.Total = total
+	return res, nil
+}
+
+// count returns the number of products matching the request filters.
+func (s *ProductStorage) count(ctx context.Context, r product.FindRequest) (int64, error) {
+	where, args := makeWhere(r, nil)
+	query := fmt.Sprintf(`SELECT count(*) FROM %s %s`, s.table, where)
+
+	var total int64
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// makePriceRange makes the conditions of the price range appending their
+// values to the arguments.
+func makePriceRange(pr product.PriceRange, args []interface{}) ([]string, []interface{}) {
+	args = append(args, pr.Currency)
+	conds := []string{fmt.Sprintf("currency = $%d", len(args))}
+	if pr.From != nil {
+		args = append(args, *pr.From)
+		conds = append(conds, fmt.Sprintf("price >= $%d", len(args)))
+	}
+	if pr.To != nil {
+		args = append(args, *pr.To)
+		conds = append(conds, fmt.Sprintf("price <= $%d", len(args)))
+	}
+	return conds, args
+}
+
+// inStock selects products with units of the product or of any variant.
+const inStock = `(stock > 0 OR EXISTS (SELECT 1 FROM jsonb_array_elements(variants) v WHERE (v->>'stock')::BIGINT > 0))`
+
+// makeWhere makes a WHERE clause applying the request filters. If after is
+// not nil, only rows going after the product in the sort order are selected.
+// The values are returned as arguments for the clause placeholders.
+func makeWhere(r product.FindRequest, after *product.Product) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	if r.Name != nil {
+		args = append(args, "%"+likeEscaper.Replace(*r.Name)+"%")
+		conds = append(conds, fmt.Sprintf("name ILIKE $%d", len(args)))
+	}
+	if ranges := r.Ranges(); len(ranges) == 1 {
+		var rangeConds []string
+		rangeConds, args = makePriceRange(ranges[0], args)
+		conds = append(conds, rangeConds...)
+	} else if len(ranges) > 1 {
+		var inRanges []string
+		for _, pr := range ranges {
+			var rangeConds []string
+			rangeConds, args = makePriceRange(pr, args)
+			inRanges = append(inRanges, "("+strings.Join(rangeConds, " AND ")+")")
+		}
+		conds = append(conds, "("+strings.Join(inRanges, " OR ")+")")
+	}
+	if r.Seller != nil {
+		args = append(args, *r.Seller)
+		conds = append(conds, fmt.Sprintf("seller = $%d", len(args)))
+	}
+	if len(r.Categories) > 0 {
+		args = append(args, pq.StringArray(r.Categories))
+		conds = append(conds, fmt.Sprintf("categories && $%d", len(args)))
+	}
+	if len(r.Attributes) > 0 {
+		// The values must be contained in one variant.
+		b, _ := json.Marshal([]map[string]interface{}{{"attributes": r.Attributes}}) // strings always encode
+		args = append(args, string(b))
+		conds = append(conds, fmt.Sprintf("variants @> $%d::JSONB", len(args)))
+	}
+	if r.InStock != nil {
+		if *r.InStock {
+			conds = append(conds, inStock)
+		} else {
+			conds = append(conds, "NOT "+inStock)
+		}
+	}
+	if r.MinRating != nil {
+		args = append(args, *r.MinRating)
+		conds = append(conds, fmt.Sprintf("rating_count > 0 AND rating_average >= $%d", len(args)))
+	}
+	if r.Status != nil {
+		args = append(args, string(*r.Status))
+		conds = append(conds, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if !r.IncludeDeleted {
+		conds = append(conds, "deleted_at IS NULL")
+	}
+	if after != nil {
+		var cond string
+		cond, args = makeAfter(sortColumns(r.Sort), after, args)
+		conds = append(conds, cond)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+// makeAfter makes a condition selecting rows going after the product in the
+// order of the columns, e.g. for columns a and b:
+// (a > $1 OR (a = $1 AND b > $2)).
+func makeAfter(cols []sortColumn, after *product.Product, args []interface{}) (string, []interface{}) {
+	var equal, alts []string
+	for _, col := range cols {
+		args = append(args, col.value(after))
+
+		op := ">"
+		if col.desc {
+			op = "<"
+		}
+		cmp := fmt.Sprintf("%s %s $%d", col.expr, op, len(args))
+
+		if len(equal) == 0 {
+			alts = append(alts, cmp)
+		} else {
+			alts = append(alts, "("+strings.Join(append(equal, cmp), " AND ")+")")
+		}
+		equal = append(equal, fmt.Sprintf("%s = $%d", col.expr, len(args)))
+	}
+
+	return "(" + strings.Join(alts, " OR ") + ")", args
+}
+
+// makeOrderBy makes an ORDER BY clause for the sort keys.
+func makeOrderBy(sorts []product.Sort) string {
+	var terms []string
+	for _, col := range sortColumns(sorts) {
+		dir := "ASC"
+		if col.desc {
+			dir = "DESC"
+		}
+		terms = append(terms, col.expr+" "+dir)
+	}
+
+	return "ORDER BY " + strings.Join(terms, ", ")
+}
+
+// sortColumn is a column rows are sorted by.
+type sortColumn struct {
+	expr  string
+	desc  bool
+	value func(p *product.Product) interface{} // value of the column for the product
 }
 
+var (
+	priceColumn  = sortColumn{expr: "price", value: func(p *product.Product) interface{} { return p.Price.Amount }}
+	nameColumn   = sortColumn{expr: `name COLLATE "C"`, value: func(p *product.Product) interface{} { return p.Name }}
+	ratingColumn = sortColumn{expr: "rating_average", value: func(p *product.Product) interface{} { return p.Rating.Average }}
+	idColumn     = sortColumn{expr: "id", value: func(p *product.Product) interface{} { return p.ID }}
+)
+
+// sortColumns returns the columns for the sort keys. Serial ids are used as
+// the creation order, they also break ties. Relevance is not supported.
+func sortColumns(sorts []product.Sort) []sortColumn {
+	var cols []sortColumn
+	for _, s := range sorts {
+		var col sortColumn
+		switch s.Key {
+		case product.SortKeyPrice:
+			col = priceColumn
+		case product.SortKeyName:
+			col = nameColumn
+		case product.SortKeyRating:
+			col = ratingColumn
+		case product.SortKeyCreated:
+			col = idColumn
+		default:
+			continue
+		}
+		col.desc = s.Desc
+		cols = append(cols, col)
+
+		if s.Key == product.SortKeyCreated {
+			// Ids are unique, next keys have no effect.
+			return cols
+		}
+	}
+
+	return append(cols, idColumn)
+}
+
+// likeEscaper escapes LIKE pattern special characters.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 func (s *ProductStorage) FindOne(ctx context.Context, id string) (p *product.Product, err error) {
+	if !isValidID(id) {
+		return nil, product.ErrNotFound
+	}
+
 	query := fmt.Sprintf(
-		`SELECT id, name, price, seller FROM %s WHERE id = $1`,
-		s.table,
+		`SELECT %s FROM %s WHERE id = $1`,
+		productColumns, s.table,
 	)
 
-	var name, seller string
-	var price int64
-	err = s.db.QueryRowContext(ctx, query, id).Scan(&id, &name, &price, &seller)
+	p, err = scanProduct(s.db.QueryRowContext(ctx, query, id))
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, product.ErrNotFound
+		}
 		return p, err
 	}
 
-	return &product.Product{
-		ID:     id,
-		Name:   name,
-		Price:  price,
-		Seller: seller,
-	}, nil
+	return p, nil
 }
 
 func (s *ProductStorage) Create(ctx context.Context, r product.CreateRequest) (p *product.Product, err error) {
 	query := fmt.Sprintf(
-		`INSERT INTO %s (name, price, seller) VALUES ($1, $2, $3) RETURNING id, name, price, seller`,
-		s.table,
+		`INSERT INTO %s (name, price, seller, categories, stock, low_stock_threshold, status, publish_at, currency, attributes, variants)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING %s`,
+		s.table, productColumns,
 	)
 
-	var id, name, seller string
-	var price int64
-	err = s.db.QueryRowContext(ctx, query, r.Name, r.Price, r.Seller).Scan(&id, &name, &price, &seller)
+	categories := pq.StringArray(r.Categories)
+	if categories == nil {
+		categories = pq.StringArray{}
+	}
+	status := r.Status
+	if status == "" {
+		status = product.StatusPublished
+	}
+	attributesJSON, err := jsonList(r.Attributes)
 	if err != nil {
-		return p, err
+		return nil, fmt.Errorf("encoding attributes: %w", err)
+	}
+	variantsJSON, err := jsonList(r.Variants)
+	if err != nil {
+		return nil, fmt.Errorf("encoding variants: %w", err)
 	}
 
-	return &product.Product{
-		ID:     id,
-		Name:   name,
-		Price:  price,
-		Seller: seller,
-	}, nil
+	row := s.db.QueryRowContext(ctx, query,
+		r.Name, r.Price.Amount, r.Seller, categories, r.Stock, r.LowStockThreshold, string(status), r.PublishAt,
+		r.Price.Currency, attributesJSON, variantsJSON,
+	)
+	return scanProduct(row)
 }
 
+// Update changes the product with a single statement incrementing the
+// version, so the version check cannot be raced.
 func (s *ProductStorage) Update(ctx context.Context, r product.UpdateRequest) (*product.Product, error) {
-	p, err := s.FindOne(ctx, r.ID)
+	if !isValidID(r.ID) {
+		return nil, product.ErrNotFound
+	}
+
+	// Nil arguments leave the columns unchanged.
+	var categories interface{}
+	if r.Categories != nil {
+		a := pq.StringArray(*r.Categories)
+		if a == nil {
+			a = pq.StringArray{}
+		}
+		categories = a
+	}
+	var amount *int64
+	var currency *string
+	if r.Price != nil {
+		amount, currency = &r.Price.Amount, &r.Price.Currency
+	}
+	var status *string
+	if r.Status != nil {
+		s := string(*r.Status)
+		status = &s
+	}
+	var mediaJSON, attributesJSON, variantsJSON []byte
+	if r.Media != nil {
+		b, err := jsonList(*r.Media)
+		if err != nil {
+			return nil, fmt.Errorf("encoding media: %w", err)
+		}
+		mediaJSON = b
+	}
+	if r.Attributes != nil {
+		b, err := jsonList(*r.Attributes)
+		if err != nil {
+			return nil, fmt.Errorf("encoding attributes: %w", err)
+		}
+		attributesJSON = b
+	}
+	if r.Variants != nil {
+		b, err := jsonList(*r.Variants)
+		if err != nil {
+			return nil, fmt.Errorf("encoding variants: %w", err)
+		}
+		variantsJSON = b
+	}
+
+	query := fmt.Sprintf(
+		`UPDATE %s SET
+			name = COALESCE($2, name),
+			price = COALESCE($3, price),
+			currency = COALESCE($9, currency),
+			media = COALESCE($10, media),
+			attributes = COALESCE($11, attributes),
+			variants = COALESCE($12, variants),
+			categories = COALESCE($4, categories),
+			low_stock_threshold = COALESCE($5, low_stock_threshold),
+			status = COALESCE($7, status),
+			publish_at = CASE WHEN COALESCE($7, status) = 'draft' THEN COALESCE($8, publish_at) END,
+			version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($6::BIGINT IS NULL OR version = $6)
+		RETURNING %s`,
+		s.table, productColumns,
+	)
+	p, err := scanProduct(s.db.QueryRowContext(ctx, query,
+		r.ID, r.Name, amount, categories, r.LowStockThreshold, r.ExpectedVersion, status, r.PublishAt,
+		currency, mediaJSON, attributesJSON, variantsJSON,
+	))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		// No row is updated if there is no product, it is deleted or its
+		// version differs.
+		p, err := s.FindOne(ctx, r.ID)
+		if err != nil {
+			return nil, err
+		}
+		if p.Deleted() {
+			return nil, product.ErrNotFound
+		}
+		return nil, product.ErrConflict
+	}
+
+	return p, nil
+}
+
+// UpdateStock changes the stock counters in a transaction holding the row
+// lock, so concurrent updates cannot make them negative. The stock of a
+// variant is kept in the variants column.
+func (s *ProductStorage) UpdateStock(ctx context.Context, r product.StockRequest) (p *product.Product, err error) {
+	if !isValidID(r.ID) {
+		return nil, product.ErrNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
 
-	if r.Name != nil {
-		p.Name = *r.Name
+	query := fmt.Sprintf(`SELECT stock, reserved, variants FROM %s WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, s.table)
+
+	var stock, reserved int64
+	var variantsJSON []byte
+	err = tx.QueryRowContext(ctx, query, r.ID).Scan(&stock, &reserved, &variantsJSON)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, product.ErrNotFound
+		}
+		return nil, err
 	}
-	if r.Price != nil {
-		p.Price = *r.Price
+
+	// The stock of a variant is changed in the variants, which are written
+	// back only then.
+	var newVariantsJSON []byte
+	if r.Variant != "" {
+		var variants []product.Variant
+		if len(variantsJSON) > 0 {
+			if err = json.Unmarshal(variantsJSON, &variants); err != nil {
+				return nil, fmt.Errorf("parsing variants: %w", err)
+			}
+		}
+		i := variantIndex(variants, r.Variant)
+		if i < 0 {
+			return nil, product.ErrVariantNotFound
+		}
+		if variants[i].Stock+r.Stock < 0 || reserved+r.Reserved < 0 {
+			return nil, product.ErrInsufficientStock
+		}
+		variants[i].Stock += r.Stock
+		if newVariantsJSON, err = json.Marshal(variants); err != nil {
+			return nil, fmt.Errorf("encoding variants: %w", err)
+		}
+	} else {
+		if stock+r.Stock < 0 || reserved+r.Reserved < 0 {
+			return nil, product.ErrInsufficientStock
+		}
+		stock += r.Stock
+	}
+
+	query = fmt.Sprintf(
+		`UPDATE %s SET stock = $2, reserved = $3, variants = COALESCE($4, variants) WHERE id = $1 RETURNING %s`,
+		s.table, productColumns,
+	)
+	p, err = scanProduct(tx.QueryRowContext(ctx, query, r.ID, stock, reserved+r.Reserved, newVariantsJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *ProductStorage) UpdateRating(ctx context.Context, id string, rating product.Rating) (*product.Product, error) {
+	if !isValidID(id) {
+		return nil, product.ErrNotFound
 	}
 
 	query := fmt.Sprintf(
-		`UPDATE %s SET name = $2, price = $3 WHERE id = $1 RETURNING id, name, price, seller`,
+		`UPDATE %s SET rating_average = $2, rating_count = $3 WHERE id = $1 RETURNING %s`,
+		s.table, productColumns,
+	)
+
+	p, err := scanProduct(s.db.QueryRowContext(ctx, query, id, rating.Average, rating.Count))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, product.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *ProductStorage) SummarizeSellers(ctx context.Context, sellers []string) (map[string]product.SellerSummary, error) {
+	query := fmt.Sprintf(
+		`SELECT seller, COUNT(*), COALESCE(SUM(rating_average * rating_count), 0), COALESCE(SUM(rating_count), 0)
+		FROM %s WHERE seller = ANY($1) AND status = 'published' AND deleted_at IS NULL
+		GROUP BY seller`,
 		s.table,
 	)
-	err = s.db.QueryRowContext(ctx, query, p.ID, p.Name, p.Price).Scan(&p.ID, &p.Name, &p.Price, &p.Seller)
+
+	rows, err := s.db.QueryContext(ctx, query, pq.StringArray(sellers))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := make(map[string]product.SellerSummary)
+	for rows.Next() {
+		var seller string
+		var products, ratingCount int64
+		var ratingSum float64
+		if err := rows.Scan(&seller, &products, &ratingSum, &ratingCount); err != nil {
+			return nil, err
+		}
+		summaries[seller] = product.NewSellerSummary(products, ratingSum, ratingCount)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
+
+func (s *ProductStorage) Delete(ctx context.Context, id string, at time.Time) (p *product.Product, err error) {
+	if !isValidID(id) {
+		return nil, product.ErrNotFound
+	}
+
+	query := fmt.Sprintf(
+		`UPDATE %s SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL RETURNING %s`,
+		s.table, productColumns,
+	)
+
+	p, err = scanProduct(s.db.QueryRowContext(ctx, query, id, at))
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, product.ErrNotFound
+		}
 		return p, err
 	}
 
 	return p, nil
 }
 
-func (s *ProductStorage) Delete(ctx context.Context, id string) (p *product.Product, err error) {
+func (s *ProductStorage) Restore(ctx context.Context, id string) (p *product.Product, err error) {
+	if !isValidID(id) {
+		return nil, product.ErrNotFound
+	}
+
 	query := fmt.Sprintf(
-		`DELETE FROM %s WHERE id = $1 RETURNING id, name, price, seller`,
-		s.table,
+		`UPDATE %s SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING %s`,
+		s.table, productColumns,
 	)
 
-	var name, seller string
-	var price int64
-	err = s.db.QueryRowContext(ctx, query, id).Scan(&id, &name, &price, &seller)
+	p, err = scanProduct(s.db.QueryRowContext(ctx, query, id))
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, product.ErrNotFound
+		}
 		return p, err
 	}
 
-	return &product.Product{
-		ID:     id,
-		Name:   name,
-		Price:  price,
-		Seller: seller,
-	}, nil
+	return p, nil
+}
+
+func (s *ProductStorage) Purge(ctx context.Context, before time.Time) (int64, error) {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE deleted_at < $1`, s.table)
+
+	res, err := s.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// PublishDue publishes the drafts with a single statement, so a draft
+// changed concurrently is checked again.
+func (s *ProductStorage) PublishDue(ctx context.Context, now time.Time) ([]*product.Product, error) {
+	query := fmt.Sprintf(
+		`UPDATE %s SET status = 'published', publish_at = NULL, version = version + 1
+		WHERE status = 'draft' AND publish_at <= $1 AND deleted_at IS NULL
+		RETURNING %s`,
+		s.table, productColumns,
+	)
+
+	rows, err := s.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ps []*product.Product
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		ps = append(ps, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ps, nil
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanProduct scans a product selected with productColumns.
+func scanProduct(row scanner) (*product.Product, error) {
+	var p product.Product
+	var categories pq.StringArray
+	var mediaJSON, attributesJSON, variantsJSON []byte
+	if err := row.Scan(
+		&p.ID, &p.Name, &p.Price.Amount, &p.Seller, &categories,
+		&p.Stock, &p.Reserved, &p.LowStockThreshold, &p.Version, &p.DeletedAt,
+		&p.Status, &p.PublishAt, &p.Price.Currency, &mediaJSON, &attributesJSON, &variantsJSON,
+		&p.Rating.Average, &p.Rating.Count,
+	); err != nil {
+		return nil, err
+	}
+	if p.DeletedAt != nil {
+		deletedAt := p.DeletedAt.UTC()
+		p.DeletedAt = &deletedAt
+	}
+	if p.PublishAt != nil {
+		publishAt := p.PublishAt.UTC()
+		p.PublishAt = &publishAt
+	}
+	if len(categories) > 0 {
+		p.Categories = categories
+	}
+	if len(mediaJSON) > 0 {
+		if err := json.Unmarshal(mediaJSON, &p.Media); err != nil {
+			return nil, fmt.Errorf("parsing media: %w", err)
+		}
+		if len(p.Media) == 0 {
+			p.Media = nil
+		}
+	}
+	if len(attributesJSON) > 0 {
+		if err := json.Unmarshal(attributesJSON, &p.Attributes); err != nil {
+			return nil, fmt.Errorf("parsing attributes: %w", err)
+		}
+		if len(p.Attributes) == 0 {
+			p.Attributes = nil
+		}
+	}
+	if len(variantsJSON) > 0 {
+		if err := json.Unmarshal(variantsJSON, &p.Variants); err != nil {
+			return nil, fmt.Errorf("parsing variants: %w", err)
+		}
+		if len(p.Variants) == 0 {
+			p.Variants = nil
+		}
+	}
+
+	return &p, nil
+}
+
+// jsonList encodes the list for a JSONB column. Nil lists are encoded as
+// empty arrays, as the columns are not nullable.
+func jsonList(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if string(b) == "null" {
+		return []byte("[]"), nil
+	}
+	return b, nil
+}
+
+// isValidID reports whether the id may exist in the table. Ids are serial
+// integers, so querying other values would fail with a type error.
+func isValidID(id string) bool {
+	_, err := strconv.ParseInt(id, 10, 32)
+	return err == nil
+}
+
+// variantIndex returns the index of the variant with the SKU, or -1 if there
+// is none.
+func variantIndex(variants []product.Variant, sku string) int {
+	for i, v := range variants {
+		if v.SKU == sku {
+			return i
+		}
+	}
+	return -1
 }
@@ -0,0 +1,196 @@
+package postgres
+
+import (
+	"github.com/lib/pq"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/storage/storagetest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// TestProductStorage_Conformance runs against a real database, it is skipped
+// if MARKET_TEST_POSTGRES_URL is not set.
+func TestProductStorage_Conformance(t *testing.T) {
+	url := os.Getenv("MARKET_TEST_POSTGRES_URL")
+	if len(url) == 0 {
+		t.Skip("MARKET_TEST_POSTGRES_URL is not set")
+	}
+
+	db, err := NewDBFromURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	storagetest.TestProductStorage(t, func(t *testing.T) product.Storage {
+		table := createTable(t, db, "init-product-table.sh", "products")
+		return NewProductStorage(db, table)
+	})
+}
+
+func Test_makeWhere(t *testing.T) {
+	tests := []struct {
+		name      string
+		r         product.FindRequest
+		after     *product.Product
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "Should exclude deleted products without filters",
+			r:         product.FindRequest{Offset: 0, Limit: 10},
+			wantWhere: "WHERE deleted_at IS NULL",
+			wantArgs:  nil,
+		},
+		{
+			name:      "Should make empty clause including deleted products",
+			r:         product.FindRequest{Offset: 0, Limit: 10, IncludeDeleted: true},
+			wantWhere: "",
+			wantArgs:  nil,
+		},
+		{
+			name:      "Should make clause with escaped name",
+			r:         product.FindRequest{Name: testPtrString("100%_")},
+			wantWhere: "WHERE name ILIKE $1 AND deleted_at IS NULL",
+			wantArgs:  []interface{}{`%100\%\_%`},
+		},
+		{
+			name: "Should make clause with all filters",
+			r: product.FindRequest{
+				Name: testPtrString("name"),
+				PriceRange: &product.PriceRange{
+					From:     testPtrInt64(10),
+					To:       testPtrInt64(100),
+					Currency: "EUR",
+				},
+				Seller: testPtrString("1"),
+			},
+			wantWhere: "WHERE name ILIKE $1 AND currency = $2 AND price >= $3 AND price <= $4 AND seller = $5 AND deleted_at IS NULL",
+			wantArgs:  []interface{}{"%name%", "EUR", int64(10), int64(100), "1"},
+		},
+		{
+			name: "Should make clause with any of price ranges",
+			r: product.FindRequest{
+				PriceRange:  &product.PriceRange{To: testPtrInt64(100), Currency: "EUR"},
+				PriceRanges: []product.PriceRange{{From: testPtrInt64(10), Currency: "JPY"}},
+			},
+			wantWhere: "WHERE ((currency = $1 AND price <= $2) OR (currency = $3 AND price >= $4)) AND deleted_at IS NULL",
+			wantArgs:  []interface{}{"EUR", int64(100), "JPY", int64(10)},
+		},
+		{
+			name:      "Should make clause with categories",
+			r:         product.FindRequest{Categories: []string{"1", "2"}},
+			wantWhere: "WHERE categories && $1 AND deleted_at IS NULL",
+			wantArgs:  []interface{}{pq.StringArray{"1", "2"}},
+		},
+		{
+			name:      "Should make clause with attribute values of one variant",
+			r:         product.FindRequest{Attributes: map[string]string{"color": "red", "size": "42"}},
+			wantWhere: "WHERE variants @> $1::JSONB AND deleted_at IS NULL",
+			wantArgs:  []interface{}{`[{"attributes":{"color":"red","size":"42"}}]`},
+		},
+		{
+			name:      "Should make clause with products in stock",
+			r:         product.FindRequest{InStock: testPtrBool(true)},
+			wantWhere: "WHERE " + inStock + " AND deleted_at IS NULL",
+			wantArgs:  nil,
+		},
+		{
+			name:      "Should make clause with products out of stock",
+			r:         product.FindRequest{Seller: testPtrString("1"), InStock: testPtrBool(false)},
+			wantWhere: "WHERE seller = $1 AND NOT " + inStock + " AND deleted_at IS NULL",
+			wantArgs:  []interface{}{"1"},
+		},
+		{
+			name:      "Should make clause with status",
+			r:         product.FindRequest{Seller: testPtrString("1"), Status: testPtrStatus(product.StatusDraft)},
+			wantWhere: "WHERE seller = $1 AND status = $2 AND deleted_at IS NULL",
+			wantArgs:  []interface{}{"1", "draft"},
+		},
+		{
+			name:      "Should make clause selecting rows after the product",
+			r:         product.FindRequest{Seller: testPtrString("1")},
+			after:     &product.Product{ID: "5", Name: "name", Price: money.New(100, "USD")},
+			wantWhere: "WHERE seller = $1 AND deleted_at IS NULL AND (id > $2)",
+			wantArgs:  []interface{}{"1", "5"},
+		},
+		{
+			name: "Should make clause selecting rows after the product in sort order",
+			r: product.FindRequest{Sort: []product.Sort{
+				{Key: product.SortKeyPrice, Desc: true},
+				{Key: product.SortKeyName},
+			}},
+			after: &product.Product{ID: "5", Name: "name", Price: money.New(100, "USD")},
+			wantWhere: `WHERE deleted_at IS NULL AND (price < $1 OR (price = $1 AND name COLLATE "C" > $2) OR ` +
+				`(price = $1 AND name COLLATE "C" = $2 AND id > $3))`,
+			wantArgs: []interface{}{int64(100), "name", "5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotWhere, gotArgs := makeWhere(tt.r, tt.after)
+			if gotWhere != tt.wantWhere {
+				t.Errorf("makeWhere() where = %q, want %q", gotWhere, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+				t.Errorf("makeWhere() args = %v, want %v", gotArgs, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func Test_makeOrderBy(t *testing.T) {
+	tests := []struct {
+		name  string
+		sorts []product.Sort
+		want  string
+	}{
+		{
+			name:  "Should sort by id by default",
+			sorts: nil,
+			want:  "ORDER BY id ASC",
+		},
+		{
+			name: "Should sort by keys breaking ties by id",
+			sorts: []product.Sort{
+				{Key: product.SortKeyPrice, Desc: true},
+				{Key: product.SortKeyRelevance},
+				{Key: product.SortKeyName},
+			},
+			want: `ORDER BY price DESC, name COLLATE "C" ASC, id ASC`,
+		},
+		{
+			name: "Should stop at creation",
+			sorts: []product.Sort{
+				{Key: product.SortKeyCreated, Desc: true},
+				{Key: product.SortKeyPrice},
+			},
+			want: "ORDER BY id DESC",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := makeOrderBy(tt.sorts); got != tt.want {
+				t.Errorf("makeOrderBy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func testPtrString(v string) *string {
+	return &v
+}
+
+func testPtrInt64(v int64) *int64 {
+	return &v
+}
+
+func testPtrBool(v bool) *bool {
+	return &v
+}
+
+func testPtrStatus(v product.Status) *product.Status {
+	return &v
+}
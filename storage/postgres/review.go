@@ -0,0 +1,182 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/ortymid/market/market/review"
+	"strings"
+	"time"
+)
+
+// reviewColumns are the columns reviews are selected with, in the order
+// scanReview expects them.
+const reviewColumns = "id, product, author, rating, text, created_at, updated_at"
+
+// ReviewStorage keeps reviews in a table with a unique constraint on product
+// and author.
+type ReviewStorage struct {
+	db    *sql.DB
+	table string
+}
+
+func NewReviewStorage(db *sql.DB, table string) *ReviewStorage {
+	return &ReviewStorage{db: db, table: table}
+}
+
+func (s *ReviewStorage) Find(ctx context.Context, r review.FindRequest) ([]*review.Review, error) {
+	var conds []string
+	var args []interface{}
+	if r.Product != nil {
+		args = append(args, *r.Product)
+		conds = append(conds, fmt.Sprintf("product = $%d", len(args)))
+	}
+	if r.Author != nil {
+		args = append(args, *r.Author)
+		conds = append(conds, fmt.Sprintf("author = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	query := fmt.Sprintf(
+		`SELECT %s FROM %s %s ORDER BY id LIMIT $%d OFFSET $%d`,
+		reviewColumns, s.table, where, len(args)+1, len(args)+2,
+	)
+	args = append(args, r.Limit, r.Offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	rvs := make([]*review.Review, 0, r.Limit)
+	for rows.Next() {
+		rv, err := scanReview(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		rvs = append(rvs, rv)
+	}
+
+	err = rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rvs, nil
+}
+
+func (s *ReviewStorage) FindOne(ctx context.Context, id string) (*review.Review, error) {
+	if !isValidID(id) {
+		return nil, review.ErrNotFound
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1`, reviewColumns, s.table)
+
+	return s.queryReview(ctx, query, id)
+}
+
+func (s *ReviewStorage) Create(ctx context.Context, rv review.Review) (*review.Review, error) {
+	query := fmt.Sprintf(
+		`INSERT INTO %s (product, author, rating, text, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING %s`,
+		s.table, reviewColumns,
+	)
+
+	created, err := scanReview(s.db.QueryRowContext(
+		ctx, query,
+		rv.Product, rv.Author, rv.Rating, rv.Text, rv.CreatedAt, rv.UpdatedAt,
+	))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return nil, review.ErrDuplicate
+		}
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (s *ReviewStorage) Update(ctx context.Context, r review.UpdateRequest, at time.Time) (*review.Review, error) {
+	if !isValidID(r.ID) {
+		return nil, review.ErrNotFound
+	}
+
+	args := []interface{}{r.ID, at}
+	sets := []string{"updated_at = $2"}
+	if r.Rating != nil {
+		args = append(args, *r.Rating)
+		sets = append(sets, fmt.Sprintf("rating = $%d", len(args)))
+	}
+	if r.Text != nil {
+		args = append(args, *r.Text)
+		sets = append(sets, fmt.Sprintf("text = $%d", len(args)))
+	}
+
+	query := fmt.Sprintf(
+		`UPDATE %s SET %s WHERE id = $1 RETURNING %s`,
+		s.table, strings.Join(sets, ", "), reviewColumns,
+	)
+
+	return s.queryReview(ctx, query, args...)
+}
+
+func (s *ReviewStorage) Delete(ctx context.Context, id string) (*review.Review, error) {
+	if !isValidID(id) {
+		return nil, review.ErrNotFound
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1 RETURNING %s`, s.table, reviewColumns)
+
+	return s.queryReview(ctx, query, id)
+}
+
+func (s *ReviewStorage) Summarize(ctx context.Context, product string) (review.Summary, error) {
+	query := fmt.Sprintf(`SELECT COUNT(*), COALESCE(SUM(rating), 0) FROM %s WHERE product = $1`, s.table)
+
+	var sum review.Summary
+	if err := s.db.QueryRowContext(ctx, query, product).Scan(&sum.Count, &sum.Sum); err != nil {
+		return review.Summary{}, err
+	}
+
+	return sum, nil
+}
+
+// queryReview scans the review returned by the query. It returns
+// review.ErrNotFound if there are no rows.
+func (s *ReviewStorage) queryReview(ctx context.Context, query string, args ...interface{}) (*review.Review, error) {
+	rv, err := scanReview(s.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, review.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+// scanReview scans a review selected with reviewColumns.
+func scanReview(row scanner) (*review.Review, error) {
+	var rv review.Review
+	err := row.Scan(&rv.ID, &rv.Product, &rv.Author, &rv.Rating, &rv.Text, &rv.CreatedAt, &rv.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	rv.CreatedAt = rv.CreatedAt.UTC()
+	rv.UpdatedAt = rv.UpdatedAt.UTC()
+
+	return &rv, nil
+}
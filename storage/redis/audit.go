@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/ortymid/market/market/audit"
+	"strconv"
+)
+
+// AuditStorage keeps entries as JSON strings in lists at
+// <key>:product:<product>, one list per product in the order of recording.
+type AuditStorage struct {
+	rdb *redis.Client
+
+	baseKey string
+}
+
+func NewAuditStorage(rdb *redis.Client, key string) *AuditStorage {
+	return &AuditStorage{rdb: rdb, baseKey: key}
+}
+
+func (s *AuditStorage) Record(ctx context.Context, e audit.Entry) error {
+	id, err := s.rdb.Incr(ctx, fmt.Sprintf("%s:id", s.baseKey)).Result()
+	if err != nil {
+		return fmt.Errorf("getting new id: %w", err)
+	}
+	e.ID = strconv.FormatInt(id, 10)
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding entry: %w", err)
+	}
+
+	if err := s.rdb.RPush(ctx, s.productKey(e.Product), data).Err(); err != nil {
+		return fmt.Errorf("storing entry: %w", err)
+	}
+	return nil
+}
+
+func (s *AuditStorage) Find(ctx context.Context, r audit.FindRequest) ([]*audit.Entry, error) {
+	if r.Limit <= 0 {
+		return []*audit.Entry{}, nil
+	}
+
+	vals, err := s.rdb.LRange(ctx, s.productKey(r.Product), r.Offset, r.Offset+r.Limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	es := make([]*audit.Entry, 0, len(vals))
+	for _, v := range vals {
+		e := &audit.Entry{}
+		if err := json.Unmarshal([]byte(v), e); err != nil {
+			return nil, fmt.Errorf("decoding entry: %w", err)
+		}
+		es = append(es, e)
+	}
+
+	return es, nil
+}
+
+func (s *AuditStorage) productKey(product string) string {
+	return fmt.Sprintf("%s:product:%s", s.baseKey, product)
+}
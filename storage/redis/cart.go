@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/ortymid/market/market/cart"
+)
+
+// CartStorage keeps carts as JSON strings at <key>:<user>. The keys expire
+// with the carts, so Redis removes expired carts on its own.
+type CartStorage struct {
+	rdb *redis.Client
+
+	baseKey string
+}
+
+func NewCartStorage(rdb *redis.Client, key string) *CartStorage {
+	return &CartStorage{rdb: rdb, baseKey: key}
+}
+
+func (s *CartStorage) Get(ctx context.Context, user string) (*cart.Cart, error) {
+	data, err := s.rdb.Get(ctx, s.cartKey(user)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, cart.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return decodeCart(data)
+}
+
+func (s *CartStorage) Save(ctx context.Context, c cart.Cart) (*cart.Cart, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("encoding cart: %w", err)
+	}
+
+	key := s.cartKey(c.User)
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, data, 0)
+		pipe.PExpireAt(ctx, key, c.ExpiresAt)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (s *CartStorage) Delete(ctx context.Context, user string) (*cart.Cart, error) {
+	key := s.cartKey(user)
+
+	var get *redis.StringCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, cart.ErrNotFound
+		}
+		return nil, err
+	}
+
+	data, err := get.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeCart(data)
+}
+
+func (s *CartStorage) cartKey(user string) string {
+	return fmt.Sprintf("%s:%s", s.baseKey, user)
+}
+
+func decodeCart(data []byte) (*cart.Cart, error) {
+	var c cart.Cart
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("decoding cart: %w", err)
+	}
+	return &c, nil
+}
@@ -0,0 +1,24 @@
+package redis
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/ortymid/market/market/cart"
+	"github.com/ortymid/market/storage/storagetest"
+	"testing"
+)
+
+func TestCartStorage_Conformance(t *testing.T) {
+	storagetest.TestCartStorage(t, func(t *testing.T) cart.Storage {
+		mr, err := miniredis.Run()
+		if err != nil {
+			t.Fatalf("running miniredis: %v", err)
+		}
+		t.Cleanup(mr.Close)
+
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { rdb.Close() })
+
+		return NewCartStorage(rdb, "carts")
+	})
+}
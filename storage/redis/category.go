@@ -0,0 +1,182 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/ortymid/market/market/category"
+	"strconv"
+)
+
+// CategoryStorage keeps categories in hashes. Ids of the categories are kept
+// in the <key>:ids set.
+type CategoryStorage struct {
+	rdb *redis.Client
+
+	baseKey string
+	idsKey  string
+}
+
+func NewCategoryStorage(rdb *redis.Client, key string) *CategoryStorage {
+	idsKey := fmt.Sprintf("%s:ids", key)
+
+	return &CategoryStorage{rdb: rdb, baseKey: key, idsKey: idsKey}
+}
+
+func (s *CategoryStorage) Find(ctx context.Context) ([]*category.Category, error) {
+	ids, err := s.rdb.SMembers(ctx, s.idsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	cs := make([]*category.Category, 0, len(ids))
+	for _, id := range ids {
+		c, err := s.getCategoryFromHash(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		cs = append(cs, c)
+	}
+
+	return cs, nil
+}
+
+func (s *CategoryStorage) FindOne(ctx context.Context, id string) (*category.Category, error) {
+	return s.getCategoryFromHash(ctx, id)
+}
+
+func (s *CategoryStorage) Create(ctx context.Context, r category.CreateRequest) (*category.Category, error) {
+	// Get new id.
+	id, err := s.rdb.Incr(ctx, fmt.Sprintf("%s:id", s.baseKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("getting new id: %w", err)
+	}
+
+	c := &category.Category{
+		ID:     strconv.FormatInt(id, 10),
+		Slug:   r.Slug,
+		Name:   r.Name,
+		Parent: r.Parent,
+	}
+
+	// Store new category and its id atomically.
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		s.setCategoryToHash(ctx, pipe, c)
+		pipe.SAdd(ctx, s.idsKey, c.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storing category: %w", err)
+	}
+
+	return c, nil
+}
+
+func (s *CategoryStorage) Update(ctx context.Context, r category.UpdateRequest) (*category.Category, error) {
+	// FindOne category checking for existence.
+	c, err := s.getCategoryFromHash(ctx, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update not-nil fields.
+	if r.Slug != nil {
+		c.Slug = *r.Slug
+	}
+	if r.Name != nil {
+		c.Name = *r.Name
+	}
+	if r.Parent != nil {
+		c.Parent = r.Parent
+		if *r.Parent == "" {
+			c.Parent = nil
+		}
+	}
+
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		s.setCategoryToHash(ctx, pipe, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storing category: %w", err)
+	}
+
+	return c, nil
+}
+
+func (s *CategoryStorage) Delete(ctx context.Context, id string) (*category.Category, error) {
+	// FindOne category checking for existence.
+	c, err := s.getCategoryFromHash(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove category hash and its id atomically.
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SRem(ctx, s.idsKey, id)
+		pipe.Del(ctx, s.hashKey(id))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("deleting category: %w", err)
+	}
+
+	return c, nil
+}
+
+// setCategoryToHash queues the category hash update in the pipeline. Root
+// categories are stored with an empty parent.
+func (s *CategoryStorage) setCategoryToHash(ctx context.Context, pipe redis.Pipeliner, c *category.Category) {
+	parent := ""
+	if c.Parent != nil {
+		parent = *c.Parent
+	}
+
+	pipe.HSet(
+		ctx, s.hashKey(c.ID),
+		"slug", c.Slug,
+		"name", c.Name,
+		"parent", parent,
+	)
+}
+
+func (s *CategoryStorage) getCategoryFromHash(ctx context.Context, id string) (*category.Category, error) {
+	num, err := s.rdb.Exists(ctx, s.hashKey(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if num == 0 {
+		return nil, category.ErrNotFound
+	}
+
+	val, err := s.rdb.HMGet(ctx, s.hashKey(id), "slug", "name", "parent").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	slug, ok := val[0].(string)
+	if !ok {
+		return nil, errors.New("nil slug field in redis")
+	}
+
+	name, ok := val[1].(string)
+	if !ok {
+		return nil, errors.New("nil name field in redis")
+	}
+
+	c := &category.Category{
+		ID:   id,
+		Slug: slug,
+		Name: name,
+	}
+	if parent, ok := val[2].(string); ok && parent != "" {
+		c.Parent = &parent
+	}
+	return c, nil
+}
+
+func (s *CategoryStorage) hashKey(id string) string {
+	return fmt.Sprintf("%s:%s", s.baseKey, id)
+}
@@ -0,0 +1,281 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/ortymid/market/market/order"
+	"strconv"
+	"time"
+)
+
+// createOrderScript claims the idempotency key and stores the order with its
+// indexes. It returns 0 if the key is taken and 1 on success.
+//
+// KEYS: keys hash of the buyer, order hash, ids index, buyer index, seller index.
+// ARGV: idempotency key, id, then field-value pairs of the order hash.
+var createOrderScript = redis.NewScript(`
+if redis.call("HSETNX", KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[2], unpack(ARGV, 3))
+redis.call("ZADD", KEYS[3], ARGV[2], ARGV[2])
+redis.call("ZADD", KEYS[4], ARGV[2], ARGV[2])
+redis.call("ZADD", KEYS[5], ARGV[2], ARGV[2])
+return 1
+`)
+
+// setIfExistsScript sets the hash fields if the hash exists. It returns 0 if
+// there is no hash and 1 on success.
+//
+// ARGV: field-value pairs.
+var setIfExistsScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], unpack(ARGV))
+return 1
+`)
+
+// orderFields are the fields of the order hash.
+var orderFields = []string{
+	"product", "buyer", "seller", "quantity", "price", "total", "status", "idempotency_key", "created_at",
+	"variant",
+}
+
+// OrderStorage keeps orders in hashes. Ids of the orders are kept in sorted
+// sets scored by id to keep the order of creation:
+//   - <key>:ids for all orders;
+//   - <key>:buyer:<buyer> for orders of a buyer;
+//   - <key>:seller:<seller> for orders of a seller.
+//
+// The <key>:buyer:<buyer>:keys hash maps idempotency keys of a buyer to ids.
+type OrderStorage struct {
+	rdb *redis.Client
+
+	baseKey string
+	idsKey  string
+}
+
+func NewOrderStorage(rdb *redis.Client, key string) *OrderStorage {
+	idsKey := fmt.Sprintf("%s:ids", key)
+
+	return &OrderStorage{rdb: rdb, baseKey: key, idsKey: idsKey}
+}
+
+func (s *OrderStorage) Find(ctx context.Context, r order.FindRequest) ([]*order.Order, error) {
+	key := s.idsKey
+	switch {
+	case r.Buyer != nil:
+		key = s.buyerKey(*r.Buyer)
+	case r.Seller != nil:
+		key = s.sellerKey(*r.Seller)
+	}
+
+	// The page is taken right from the index if it is the only filter.
+	if r.Buyer == nil || r.Seller == nil {
+		if r.Limit <= 0 {
+			return []*order.Order{}, nil
+		}
+
+		ids, err := s.rdb.ZRange(ctx, key, r.Offset, r.Offset+r.Limit-1).Result()
+		if err != nil {
+			return nil, err
+		}
+		return s.getOrders(ctx, ids, r)
+	}
+
+	ids, err := s.rdb.ZRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	os, err := s.getOrders(ctx, ids, r)
+	if err != nil {
+		return nil, err
+	}
+	return r.Page(os), nil
+}
+
+// getOrders returns the orders of the ids matching the request filters.
+func (s *OrderStorage) getOrders(ctx context.Context, ids []string, r order.FindRequest) ([]*order.Order, error) {
+	os := make([]*order.Order, 0, len(ids))
+	for _, id := range ids {
+		o, err := s.getOrderFromHash(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.Match(o) {
+			os = append(os, o)
+		}
+	}
+
+	return os, nil
+}
+
+func (s *OrderStorage) FindOne(ctx context.Context, id string) (*order.Order, error) {
+	return s.getOrderFromHash(ctx, id)
+}
+
+func (s *OrderStorage) FindByKey(ctx context.Context, buyer string, key string) (*order.Order, error) {
+	id, err := s.rdb.HGet(ctx, s.keysKey(buyer), key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, order.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return s.getOrderFromHash(ctx, id)
+}
+
+func (s *OrderStorage) Create(ctx context.Context, o order.Order) (*order.Order, error) {
+	// Get new id.
+	id, err := s.rdb.Incr(ctx, fmt.Sprintf("%s:id", s.baseKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("getting new id: %w", err)
+	}
+	o.ID = strconv.FormatInt(id, 10)
+
+	keys := []string{
+		s.keysKey(o.Buyer), s.hashKey(o.ID), s.idsKey, s.buyerKey(o.Buyer), s.sellerKey(o.Seller),
+	}
+	args := []interface{}{o.IdempotencyKey, o.ID}
+	args = append(args, orderToHash(&o)...)
+
+	created, err := createOrderScript.Run(ctx, s.rdb, keys, args...).Int()
+	if err != nil {
+		return nil, fmt.Errorf("storing order: %w", err)
+	}
+	if created == 0 {
+		return nil, order.ErrDuplicate
+	}
+
+	return &o, nil
+}
+
+func (s *OrderStorage) UpdateStatus(ctx context.Context, id string, status order.Status) (*order.Order, error) {
+	updated, err := setIfExistsScript.Run(ctx, s.rdb, []string{s.hashKey(id)}, "status", string(status)).Int()
+	if err != nil {
+		return nil, fmt.Errorf("storing order: %w", err)
+	}
+	if updated == 0 {
+		return nil, order.ErrNotFound
+	}
+
+	return s.getOrderFromHash(ctx, id)
+}
+
+func (s *OrderStorage) Delete(ctx context.Context, id string) (*order.Order, error) {
+	// FindOne order checking for existence.
+	o, err := s.getOrderFromHash(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove order hash, its key and its id from indexes atomically.
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, s.idsKey, id)
+		pipe.ZRem(ctx, s.buyerKey(o.Buyer), id)
+		pipe.ZRem(ctx, s.sellerKey(o.Seller), id)
+		pipe.HDel(ctx, s.keysKey(o.Buyer), o.IdempotencyKey)
+		pipe.Del(ctx, s.hashKey(id))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("deleting order: %w", err)
+	}
+
+	return o, nil
+}
+
+// orderToHash returns field-value pairs of the order hash in the order of
+// orderFields.
+func orderToHash(o *order.Order) []interface{} {
+	values := []string{
+		o.Product, o.Buyer, o.Seller,
+		strconv.FormatInt(o.Quantity, 10),
+		strconv.FormatInt(o.Price, 10),
+		strconv.FormatInt(o.Total, 10),
+		string(o.Status),
+		o.IdempotencyKey,
+		o.CreatedAt.UTC().Format(time.RFC3339Nano),
+		o.Variant,
+	}
+
+	pairs := make([]interface{}, 0, 2*len(values))
+	for i, v := range values {
+		pairs = append(pairs, orderFields[i], v)
+	}
+	return pairs
+}
+
+func (s *OrderStorage) getOrderFromHash(ctx context.Context, id string) (*order.Order, error) {
+	val, err := s.rdb.HMGet(ctx, s.hashKey(id), orderFields...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, len(val))
+	for i, v := range val {
+		f, ok := v.(string)
+		if !ok {
+			if i == 0 {
+				// There is no hash at all.
+				return nil, order.ErrNotFound
+			}
+			if orderFields[i] == "variant" {
+				// Orders stored before variants have no variant field.
+				continue
+			}
+			return nil, fmt.Errorf("nil %s field in redis", orderFields[i])
+		}
+		fields[i] = f
+	}
+
+	var ints [3]int64
+	for i := range ints {
+		ints[i], err = strconv.ParseInt(fields[3+i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", orderFields[3+i], err)
+		}
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, fields[8])
+	if err != nil {
+		return nil, fmt.Errorf("parsing created_at: %w", err)
+	}
+
+	o := &order.Order{
+		ID:             id,
+		Product:        fields[0],
+		Variant:        fields[9],
+		Buyer:          fields[1],
+		Seller:         fields[2],
+		Quantity:       ints[0],
+		Price:          ints[1],
+		Total:          ints[2],
+		Status:         order.Status(fields[6]),
+		IdempotencyKey: fields[7],
+		CreatedAt:      createdAt,
+	}
+	return o, nil
+}
+
+func (s *OrderStorage) hashKey(id string) string {
+	return fmt.Sprintf("%s:%s", s.baseKey, id)
+}
+
+func (s *OrderStorage) buyerKey(buyer string) string {
+	return fmt.Sprintf("%s:buyer:%s", s.baseKey, buyer)
+}
+
+func (s *OrderStorage) sellerKey(seller string) string {
+	return fmt.Sprintf("%s:seller:%s", s.baseKey, seller)
+}
+
+func (s *OrderStorage) keysKey(buyer string) string {
+	return fmt.Sprintf("%s:buyer:%s:keys", s.baseKey, buyer)
+}
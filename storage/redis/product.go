@@ -2,42 +2,405 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/go-redis/redis/v8"
+	"github.com/ortymid/market/market/money"
 	"github.com/ortymid/market/market/product"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// updateStockScript changes stock and reserved fields of the product hash
+// by the deltas only if none of them becomes negative. It returns -1 if there
+// is no product or it is deleted, 0 if there is not enough stock and 1 on
+// success.
+var updateStockScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 or redis.call("HEXISTS", KEYS[1], "deleted_at") == 1 then
+	return -1
+end
+local stockKey = KEYS[1]
+if #KEYS > 1 then
+	if redis.call("HGET", KEYS[2], "sku") ~= ARGV[3] then
+		return -2
+	end
+	stockKey = KEYS[2]
+end
+local stock = tonumber(redis.call("HGET", stockKey, "stock") or "0") + tonumber(ARGV[1])
+local reserved = tonumber(redis.call("HGET", KEYS[1], "reserved") or "0") + tonumber(ARGV[2])
+if stock < 0 or reserved < 0 then
+	return 0
+end
+redis.call("HSET", stockKey, "stock", string.format("%d", stock))
+redis.call("HSET", KEYS[1], "reserved", string.format("%d", reserved))
+return 1
+`)
+
+// ProductStorage keeps products in hashes. Ids of the products are kept in
+// sorted sets used as indexes:
+//   - <key>:ids is scored by id to keep the order of insertion;
+//   - <key>:price is scored by the amount of the price to find products in a
+//     price range, the currency is checked on the products;
+//   - <key>:seller:<seller> is scored by id to find products of a seller;
+//   - <key>:category:<category> is scored by id to find products of a
+//     category;
+//   - <key>:deleted is scored by the deletion time in milliseconds to purge
+//     deleted products;
+//   - <key>:unpublished is scored by id to know whether there are products
+//     which are not published;
+//   - <key>:scheduled is scored by the publish time in milliseconds to
+//     publish scheduled drafts;
+//   - <key>:visible and <key>:seller:<seller>:visible are scored by id to
+//     find published products which are not deleted, as they are listed by
+//     default;
+//   - <key>:attr:<name>:<value> is scored by id to find products with a
+//     variant having the attribute value, the values of one variant are
+//     checked on the products.
+//
+// Variants are kept in hashes <key>:<id>:variant:<n> numbered in their order,
+// the product hash keeps their number. Ratings are kept in the rating_average
+// and rating_count fields, products are sorted and filtered by them after
+// they are read.
+//
+// Deleted products stay in the other indexes until they are purged.
+// Products stored before statuses were introduced have no status and are
+// published. Products stored before the visible indexes were introduced are
+// added to them by IndexVisible. Products stored before currencies were
+// introduced have no currency and are priced in money.DefaultCurrency.
 type ProductStorage struct {
 	rdb *redis.Client
 
-	baseKey string
-	idsKey  string
+	baseKey        string
+	idsKey         string
+	priceKey       string
+	deletedKey     string
+	unpublishedKey string
+	scheduledKey   string
+	visibleKey     string
 }
 
 func NewProductStorage(rdb *redis.Client, key string) *ProductStorage {
-	idsKey := fmt.Sprintf("%s:ids", key)
-
-	return &ProductStorage{rdb: rdb, baseKey: key, idsKey: idsKey}
+	return &ProductStorage{
+		rdb:            rdb,
+		baseKey:        key,
+		idsKey:         fmt.Sprintf("%s:ids", key),
+		priceKey:       fmt.Sprintf("%s:price", key),
+		deletedKey:     fmt.Sprintf("%s:deleted", key),
+		unpublishedKey: fmt.Sprintf("%s:unpublished", key),
+		scheduledKey:   fmt.Sprintf("%s:scheduled", key),
+		visibleKey:     fmt.Sprintf("%s:visible", key),
+	}
 }
 
-func (s *ProductStorage) Find(ctx context.Context, r product.FindRequest) ([]*product.Product, error) {
-	start, stop := r.Offset, r.Offset+r.Limit-1
+func (s *ProductStorage) Find(ctx context.Context, r product.FindRequest) (*product.FindResult, error) {
+	// Published products which are not deleted are taken from the visible
+	// indexes, products of a seller are taken from the seller indexes.
+	key, visible := s.findIndex(r)
+
+	// The page can be taken right from the index if no other filters provided
+	// and products are sorted by creation.
+	byCreation, desc := creationOrder(r.Sort)
+	if r.Name == nil && len(r.Ranges()) == 0 && len(r.Categories) == 0 && len(r.Attributes) == 0 && r.InStock == nil && r.MinRating == nil && byCreation {
+		exact := visible
+		if !exact {
+			var err error
+			exact, err = s.indexMatches(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if exact {
+			return s.findInIndex(ctx, key, r, desc)
+		}
+	}
 
-	ids, err := s.rdb.ZRange(ctx, s.idsKey, start, stop).Result()
+	ids, err := s.rdb.ZRange(ctx, key, 0, -1).Result()
 	if err != nil {
 		return nil, err
 	}
 
+	if ranges := r.Ranges(); len(ranges) > 0 {
+		ids, err = s.filterByPrice(ctx, ids, ranges)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(r.Categories) > 0 {
+		ids, err = s.filterByCategories(ctx, ids, r.Categories)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(r.Attributes) > 0 {
+		ids, err = s.filterByAttributes(ctx, ids, r.Attributes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	all, err := s.getProducts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
 	var products []*product.Product
-	for _, id := range ids {
-		p, err := s.getProductFromHash(ctx, id)
+	for _, p := range all {
+		if r.Match(p) {
+			products = append(products, p)
+		}
+	}
+
+	return product.PageProducts(products, r, created)
+}
+
+// findIndex returns the index to find the products of the request in and
+// whether it is a visible index, which holds only published products which
+// are not deleted.
+func (s *ProductStorage) findIndex(r product.FindRequest) (key string, visible bool) {
+	visible = r.Status != nil && *r.Status == product.StatusPublished && !r.IncludeDeleted
+	switch {
+	case r.Seller != nil && visible:
+		return s.sellerVisibleKey(*r.Seller), true
+	case r.Seller != nil:
+		return s.sellerKey(*r.Seller), false
+	case visible:
+		return s.visibleKey, true
+	default:
+		return s.idsKey, false
+	}
+}
+
+// indexMatches reports whether all the products of the index match the
+// deleted and status filters of the request. The index includes deleted and
+// unpublished products, so they must be requested too or there must be none.
+func (s *ProductStorage) indexMatches(ctx context.Context, r product.FindRequest) (bool, error) {
+	if r.Status != nil && *r.Status != product.StatusPublished {
+		return false, nil
+	}
+	if r.Status != nil {
+		n, err := s.rdb.ZCard(ctx, s.unpublishedKey).Result()
+		if err != nil || n > 0 {
+			return false, err
+		}
+	}
+	if !r.IncludeDeleted {
+		n, err := s.rdb.ZCard(ctx, s.deletedKey).Result()
+		if err != nil || n > 0 {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// findInIndex takes the page right from the index scored by id.
+func (s *ProductStorage) findInIndex(ctx context.Context, key string, r product.FindRequest, desc bool) (*product.FindResult, error) {
+	ids, err := s.pageOfIndex(ctx, key, r, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	ps, err := s.getProducts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := product.MakeFindResult(ps, r.Sort, r.Limit, func(last int) interface{} {
+		return product.CursorValues(ps[last], r.Sort)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res.Total, err = s.rdb.ZCard(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// pageOfIndex returns up to limit+1 ids from the index scored by id. The page
+// starts after the id of the product in the cursor, or at the offset if there
+// is no cursor.
+func (s *ProductStorage) pageOfIndex(ctx context.Context, key string, r product.FindRequest, desc bool) ([]string, error) {
+	if r.Limit <= 0 {
+		return nil, nil
+	}
+
+	if r.Cursor == "" {
+		start, stop := r.Offset, r.Offset+r.Limit
+
+		zrange := s.rdb.ZRange
+		if desc {
+			zrange = s.rdb.ZRevRange
+		}
+
+		return zrange(ctx, key, start, stop).Result()
+	}
+
+	last, err := product.DecodeProductCursor(r.Cursor, r.Sort)
+	if err != nil {
+		return nil, err
+	}
+	after := "(" + strconv.FormatInt(created(last), 10)
+
+	if desc {
+		by := &redis.ZRangeBy{Min: "-inf", Max: after, Count: r.Limit + 1}
+		return s.rdb.ZRevRangeByScore(ctx, key, by).Result()
+	}
+	by := &redis.ZRangeBy{Min: after, Max: "+inf", Count: r.Limit + 1}
+	return s.rdb.ZRangeByScore(ctx, key, by).Result()
+}
+
+// creationOrder reports whether the sort keys give the order of the ids index
+// and whether the order is descending.
+func creationOrder(sorts []product.Sort) (ok bool, desc bool) {
+	for _, s := range sorts {
+		switch s.Key {
+		case product.SortKeyCreated:
+			// Keys after creation have no effect, as it has no ties.
+			return true, s.Desc
+		case product.SortKeyRelevance:
+			continue
+		default:
+			return false, false
+		}
+	}
+	return true, false
+}
+
+// created returns the creation order of the product. Ids are assigned
+// sequentially, so they are used as the order.
+func created(p *product.Product) int64 {
+	id, _ := strconv.ParseInt(p.ID, 10, 64)
+	return id
+}
+
+// filterByPrice leaves only ids of the products with amounts in any of the
+// price ranges keeping the order of ids. Currencies are not in the index, they
+// are checked by matching the products.
+func (s *ProductStorage) filterByPrice(ctx context.Context, ids []string, ranges []product.PriceRange) ([]string, error) {
+	set := make(map[string]struct{})
+	for _, pr := range ranges {
+		min, max := "-inf", "+inf"
+		if pr.From != nil {
+			min = strconv.FormatInt(*pr.From, 10)
+		}
+		if pr.To != nil {
+			max = strconv.FormatInt(*pr.To, 10)
+		}
+
+		inRange, err := s.rdb.ZRangeByScore(ctx, s.priceKey, &redis.ZRangeBy{Min: min, Max: max}).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range inRange {
+			set[id] = struct{}{}
+		}
+	}
+
+	return filterIDs(ids, set), nil
+}
+
+// filterByCategories leaves only ids of the products of any of the categories
+// keeping the order of ids.
+func (s *ProductStorage) filterByCategories(ctx context.Context, ids []string, categories []string) ([]string, error) {
+	set := make(map[string]struct{})
+	for _, c := range categories {
+		inCategory, err := s.rdb.ZRange(ctx, s.categoryKey(c), 0, -1).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range inCategory {
+			set[id] = struct{}{}
+		}
+	}
+
+	return filterIDs(ids, set), nil
+}
+
+// filterByAttributes leaves only ids of the products having all the attribute
+// values keeping the order of ids. Whether the values are of one variant is
+// checked by matching the products.
+func (s *ProductStorage) filterByAttributes(ctx context.Context, ids []string, values map[string]string) ([]string, error) {
+	for name, value := range values {
+		withValue, err := s.rdb.ZRange(ctx, s.attributeKey(name, value), 0, -1).Result()
 		if err != nil {
 			return nil, err
 		}
 
+		set := make(map[string]struct{}, len(withValue))
+		for _, id := range withValue {
+			set[id] = struct{}{}
+		}
+		ids = filterIDs(ids, set)
+	}
+
+	return ids, nil
+}
+
+// filterIDs leaves only ids in the set keeping the order of ids.
+func filterIDs(ids []string, set map[string]struct{}) []string {
+	filtered := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := set[id]; ok {
+			filtered = append(filtered, id)
+		}
+	}
+	return filtered
+}
+
+// getProducts reads the product hashes in a pipeline keeping the order of
+// ids, then reads the variants of the products having them in another one.
+// Products purged meanwhile are skipped.
+func (s *ProductStorage) getProducts(ctx context.Context, ids []string) ([]*product.Product, error) {
+	cmds := make([]*redis.SliceCmd, len(ids))
+	_, err := s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.HMGet(ctx, s.hashKey(id), productFields...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]*product.Product, 0, len(ids))
+	counts := make([]int, 0, len(ids))
+	for i, cmd := range cmds {
+		p, n, err := parseProduct(ids[i], cmd.Val())
+		if errors.Is(err, product.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
 		products = append(products, p)
+		counts = append(counts, n)
+	}
+
+	variants := make([][]*redis.StringStringMapCmd, len(products))
+	_, err = s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, p := range products {
+			for j := 0; j < counts[i]; j++ {
+				variants[i] = append(variants[i], pipe.HGetAll(ctx, s.variantKey(p.ID, j)))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range products {
+		for _, cmd := range variants[i] {
+			v, err := parseVariant(cmd.Val())
+			if err != nil {
+				return nil, err
+			}
+			p.Variants = append(p.Variants, v)
+		}
 	}
 
 	return products, nil
@@ -48,7 +411,7 @@ func (s *ProductStorage) FindOne(ctx context.Context, id string) (*product.Produ
 }
 
 func (s *ProductStorage) Create(ctx context.Context, r product.CreateRequest) (*product.Product, error) {
-	// FindOne new id.
+	// Get new id.
 	id, err := s.rdb.Incr(ctx, fmt.Sprintf("%s:id", s.baseKey)).Result()
 	if err != nil {
 		return nil, fmt.Errorf("getting new id: %w", err)
@@ -56,42 +419,166 @@ func (s *ProductStorage) Create(ctx context.Context, r product.CreateRequest) (*
 
 	// Prepare new product.
 	p := &product.Product{
-		ID:     strconv.FormatInt(id, 10),
-		Name:   r.Name,
-		Price:  r.Price,
-		Seller: r.Seller,
+		ID:         strconv.FormatInt(id, 10),
+		Name:       r.Name,
+		Price:      r.Price,
+		Seller:     r.Seller,
+		Categories: r.Categories,
+		Attributes: r.Attributes,
+		Variants:   r.Variants,
+
+		Stock:             r.Stock,
+		LowStockThreshold: r.LowStockThreshold,
+
+		Status:    r.Status,
+		PublishAt: r.PublishAt,
+
+		Version: 1,
+	}
+	if len(p.Categories) == 0 {
+		p.Categories = nil
+	}
+	if len(p.Attributes) == 0 {
+		p.Attributes = nil
+	}
+	if len(p.Variants) == 0 {
+		p.Variants = nil
+	}
+	if p.Status == "" {
+		p.Status = product.StatusPublished
+	}
+	if p.PublishAt != nil {
+		at := p.PublishAt.UTC()
+		p.PublishAt = &at
 	}
 
-	// Store new product.
-	err = s.setProductToHash(ctx, p)
+	// Store new product and update indexes atomically.
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		s.setProductToHash(ctx, pipe, p)
+		pipe.HSet(ctx, s.hashKey(p.ID), "stock", strconv.FormatInt(p.Stock, 10), "reserved", "0")
+		pipe.ZAdd(ctx, s.idsKey, &redis.Z{Score: float64(id), Member: p.ID})
+		pipe.ZAdd(ctx, s.priceKey, &redis.Z{Score: float64(p.Price.Amount), Member: p.ID})
+		pipe.ZAdd(ctx, s.sellerKey(p.Seller), &redis.Z{Score: float64(id), Member: p.ID})
+		for _, c := range p.Categories {
+			pipe.ZAdd(ctx, s.categoryKey(c), &redis.Z{Score: float64(id), Member: p.ID})
+		}
+		s.setVariants(ctx, pipe, p, nil)
+		s.indexStatus(ctx, pipe, p)
+		return nil
+	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("storing product: %w", err)
+	}
+
+	return p, nil
+}
+
+// indexStatus queues the update of the unpublished, scheduled and visible
+// indexes for the product in the pipeline.
+func (s *ProductStorage) indexStatus(ctx context.Context, pipe redis.Pipeliner, p *product.Product) {
+	s.indexVisible(ctx, pipe, p)
+	if p.Published() {
+		pipe.ZRem(ctx, s.unpublishedKey, p.ID)
+	} else {
+		pipe.ZAdd(ctx, s.unpublishedKey, &redis.Z{Score: float64(created(p)), Member: p.ID})
 	}
+	if p.PublishAt != nil {
+		pipe.ZAdd(ctx, s.scheduledKey, &redis.Z{Score: float64(millis(*p.PublishAt)), Member: p.ID})
+	} else {
+		pipe.ZRem(ctx, s.scheduledKey, p.ID)
+	}
+}
 
-	// Update ids sorted set.
-	zID := &redis.Z{
-		Score:  float64(id),
-		Member: id,
+// indexVisible queues the update of the visible indexes for the product in
+// the pipeline.
+func (s *ProductStorage) indexVisible(ctx context.Context, pipe redis.Pipeliner, p *product.Product) {
+	if p.Published() && !p.Deleted() {
+		z := &redis.Z{Score: float64(created(p)), Member: p.ID}
+		pipe.ZAdd(ctx, s.visibleKey, z)
+		pipe.ZAdd(ctx, s.sellerVisibleKey(p.Seller), z)
+	} else {
+		pipe.ZRem(ctx, s.visibleKey, p.ID)
+		pipe.ZRem(ctx, s.sellerVisibleKey(p.Seller), p.ID)
 	}
-	err = s.rdb.ZAdd(ctx, fmt.Sprintf("%s:ids", s.baseKey), zID).Err()
+}
+
+// IndexVisible adds the products stored before the visible indexes were
+// introduced to them. Every product is indexed in a transaction watching its
+// hash, so it is safe to run along with other changes.
+func (s *ProductStorage) IndexVisible(ctx context.Context) error {
+	ids, err := s.rdb.ZRange(ctx, s.idsKey, 0, -1).Result()
 	if err != nil {
-		// Rollback.
-		err = s.rdb.Del(ctx, s.hashKey(p.ID)).Err()
+		return fmt.Errorf("indexing products: %w", err)
+	}
+
+	for _, id := range ids {
+		err := s.watchProduct(ctx, id, func(tx *redis.Tx) error {
+			p, err := s.readProductFromHash(ctx, tx, id)
+			if errors.Is(err, product.ErrNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				s.indexVisible(ctx, pipe, p)
+				return nil
+			})
+			return err
+		})
 		if err != nil {
-			return nil, fmt.Errorf("rolling back ids sorted set update: %w", err)
+			return fmt.Errorf("indexing products: %w", err)
 		}
-		return nil, fmt.Errorf("updating ids sorted set: %w", err)
 	}
 
-	return p, nil
+	return nil
 }
 
+// Update changes the product in a transaction watching the product hash, so
+// concurrent updates are retried and the version check cannot be raced.
 func (s *ProductStorage) Update(ctx context.Context, r product.UpdateRequest) (*product.Product, error) {
+	var p *product.Product
+	err := s.watchProduct(ctx, r.ID, func(tx *redis.Tx) error {
+		var err error
+		p, err = s.update(ctx, tx, r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// watchProduct runs fn in a transaction watching the product hash. It is
+// retried until the hash is not changed concurrently.
+func (s *ProductStorage) watchProduct(ctx context.Context, id string, fn func(tx *redis.Tx) error) error {
+	for {
+		err := s.rdb.Watch(ctx, fn, s.hashKey(id))
+		if errors.Is(err, redis.TxFailedErr) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			continue
+		}
+		return err
+	}
+}
+
+// update applies the request to the product hash watched by the transaction.
+func (s *ProductStorage) update(ctx context.Context, tx *redis.Tx, r product.UpdateRequest) (*product.Product, error) {
 	// FindOne product checking for existence.
-	p, err := s.getProductFromHash(ctx, r.ID)
+	p, err := s.readProductFromHash(ctx, tx, r.ID)
 	if err != nil {
 		return nil, err
 	}
+	if p.Deleted() {
+		return nil, product.ErrNotFound
+	}
+	if r.ExpectedVersion != nil && *r.ExpectedVersion != p.Version {
+		return nil, product.ErrConflict
+	}
 
 	// Update not-nil fields.
 	if r.Name != nil {
@@ -100,90 +587,687 @@ func (s *ProductStorage) Update(ctx context.Context, r product.UpdateRequest) (*
 	if r.Price != nil {
 		p.Price = *r.Price
 	}
+	oldCategories := p.Categories
+	if r.Categories != nil {
+		p.Categories = *r.Categories
+		if len(p.Categories) == 0 {
+			p.Categories = nil
+		}
+	}
+	if r.Attributes != nil {
+		p.Attributes = *r.Attributes
+		if len(p.Attributes) == 0 {
+			p.Attributes = nil
+		}
+	}
+	oldVariants := p.Variants
+	if r.Variants != nil {
+		p.Variants = *r.Variants
+		if len(p.Variants) == 0 {
+			p.Variants = nil
+		}
+	}
+	if r.LowStockThreshold != nil {
+		p.LowStockThreshold = *r.LowStockThreshold
+	}
+	if r.Media != nil {
+		p.Media = *r.Media
+		if len(p.Media) == 0 {
+			p.Media = nil
+		}
+	}
+	if r.PublishAt != nil {
+		at := r.PublishAt.UTC()
+		p.PublishAt = &at
+	}
+	if r.Status != nil {
+		p.Status = *r.Status
+		if p.Status != product.StatusDraft {
+			p.PublishAt = nil
+		}
+	}
+	p.Version++
 
-	// Store updated product.
-	err = s.setProductToHash(ctx, p)
-	if err != nil {
+	// Store updated product and update the price, category, attribute and
+	// status indexes atomically.
+	_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		s.setProductToHash(ctx, pipe, p)
+		s.indexStatus(ctx, pipe, p)
+		pipe.ZAdd(ctx, s.priceKey, &redis.Z{Score: float64(p.Price.Amount), Member: p.ID})
+		if r.Categories != nil {
+			score := float64(created(p))
+			for _, c := range oldCategories {
+				pipe.ZRem(ctx, s.categoryKey(c), p.ID)
+			}
+			for _, c := range p.Categories {
+				pipe.ZAdd(ctx, s.categoryKey(c), &redis.Z{Score: score, Member: p.ID})
+			}
+		}
+		if r.Variants != nil {
+			s.setVariants(ctx, pipe, p, oldVariants)
+		}
+		return nil
+	})
+	if errors.Is(err, redis.TxFailedErr) {
 		return nil, err
 	}
+	if err != nil {
+		return nil, fmt.Errorf("storing product: %w", err)
+	}
 
 	return p, nil
 }
 
-func (s *ProductStorage) Delete(ctx context.Context, id string) (*product.Product, error) {
-	// FindOne product checking for existence.
+// UpdateStock changes the stock counters with a script, so concurrent
+// updates cannot make them negative. The stock of a variant is kept in the
+// variant hash.
+func (s *ProductStorage) UpdateStock(ctx context.Context, r product.StockRequest) (*product.Product, error) {
+	for {
+		keys := []string{s.hashKey(r.ID)}
+		args := []interface{}{strconv.FormatInt(r.Stock, 10), strconv.FormatInt(r.Reserved, 10)}
+		if r.Variant != "" {
+			key, err := s.stockVariantKey(ctx, r.ID, r.Variant)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+			args = append(args, r.Variant)
+		}
+
+		res, err := updateStockScript.Run(ctx, s.rdb, keys, args...).Int()
+		if err != nil {
+			return nil, fmt.Errorf("updating stock: %w", err)
+		}
+
+		switch res {
+		case -2:
+			// The variants were replaced since they were read.
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			continue
+		case -1:
+			return nil, product.ErrNotFound
+		case 0:
+			return nil, product.ErrInsufficientStock
+		}
+
+		return s.getProductFromHash(ctx, r.ID)
+	}
+}
+
+// stockVariantKey returns the key of the hash of the variant with the SKU.
+// The script checks that it still has the SKU.
+func (s *ProductStorage) stockVariantKey(ctx context.Context, id string, sku string) (string, error) {
 	p, err := s.getProductFromHash(ctx, id)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	if p.Deleted() {
+		return "", product.ErrNotFound
 	}
+	for i, v := range p.Variants {
+		if v.SKU == sku {
+			return s.variantKey(id, i), nil
+		}
+	}
+	return "", product.ErrVariantNotFound
+}
+
+// UpdateRating sets the rating fields in a transaction watching the product
+// hash, so that a product purged concurrently is not recreated.
+func (s *ProductStorage) UpdateRating(ctx context.Context, id string, rating product.Rating) (*product.Product, error) {
+	var p *product.Product
+	err := s.watchProduct(ctx, id, func(tx *redis.Tx) error {
+		var err error
+		p, err = s.readProductFromHash(ctx, tx, id)
+		if err != nil {
+			return err
+		}
 
-	// Remove id from sorted set.
-	err = s.rdb.ZRem(ctx, s.idsKey, id).Err()
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(
+				ctx, s.hashKey(id),
+				"rating_average", strconv.FormatFloat(rating.Average, 'g', -1, 64),
+				"rating_count", strconv.FormatInt(rating.Count, 10),
+			)
+			return nil
+		})
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("updating rating: %w", err)
 	}
 
-	// Remove product hash.
-	err = s.rdb.Del(ctx, s.hashKey(id)).Err()
+	p.Rating = rating
+	return p, nil
+}
+
+// SummarizeSellers reads the products of the seller indexes. Products purged
+// meanwhile are skipped.
+func (s *ProductStorage) SummarizeSellers(ctx context.Context, sellers []string) (map[string]product.SellerSummary, error) {
+	var ps []*product.Product
+	seen := make(map[string]bool, len(sellers))
+	for _, seller := range sellers {
+		if seen[seller] {
+			continue
+		}
+		seen[seller] = true
+
+		ids, err := s.rdb.ZRange(ctx, s.sellerKey(seller), 0, -1).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range ids {
+			p, err := s.getProductFromHash(ctx, id)
+			if errors.Is(err, product.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			ps = append(ps, p)
+		}
+	}
+
+	return product.SummarizeSellers(ps), nil
+}
+
+// Delete marks the product hash deleted and adds it to the deleted index
+// in a transaction watching the hash.
+func (s *ProductStorage) Delete(ctx context.Context, id string, at time.Time) (*product.Product, error) {
+	var p *product.Product
+	err := s.watchProduct(ctx, id, func(tx *redis.Tx) error {
+		var err error
+		p, err = s.readProductFromHash(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if p.Deleted() {
+			return product.ErrNotFound
+		}
+
+		at = at.UTC()
+		p.DeletedAt = &at
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, s.hashKey(id), "deleted_at", at.Format(time.RFC3339Nano))
+			pipe.ZAdd(ctx, s.deletedKey, &redis.Z{Score: float64(millis(at)), Member: id})
+			s.indexVisible(ctx, pipe, p)
+			return nil
+		})
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("deleting product: %w", err)
+	}
+
+	return p, nil
+}
+
+// Restore removes the deletion mark of the product hash and removes it from
+// the deleted index in a transaction watching the hash.
+func (s *ProductStorage) Restore(ctx context.Context, id string) (*product.Product, error) {
+	var p *product.Product
+	err := s.watchProduct(ctx, id, func(tx *redis.Tx) error {
+		var err error
+		p, err = s.readProductFromHash(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !p.Deleted() {
+			return product.ErrNotFound
+		}
+
+		p.DeletedAt = nil
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HDel(ctx, s.hashKey(id), "deleted_at")
+			pipe.ZRem(ctx, s.deletedKey, id)
+			s.indexVisible(ctx, pipe, p)
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("restoring product: %w", err)
 	}
 
 	return p, nil
 }
 
-func (s *ProductStorage) setProductToHash(ctx context.Context, p *product.Product) error {
-	err := s.rdb.HSet(
+// Purge removes the products found in the deleted index. Every product is
+// removed in a transaction watching its hash, so a product restored
+// concurrently is kept.
+func (s *ProductStorage) Purge(ctx context.Context, before time.Time) (int64, error) {
+	by := &redis.ZRangeBy{Min: "-inf", Max: "(" + strconv.FormatInt(millis(before), 10)}
+	ids, err := s.rdb.ZRangeByScore(ctx, s.deletedKey, by).Result()
+	if err != nil {
+		return 0, fmt.Errorf("purging products: %w", err)
+	}
+
+	var n int64
+	for _, id := range ids {
+		purged := false
+		err := s.watchProduct(ctx, id, func(tx *redis.Tx) error {
+			purged = false
+			p, err := s.readProductFromHash(ctx, tx, id)
+			if errors.Is(err, product.ErrNotFound) {
+				// Only the index entry is left.
+				return tx.ZRem(ctx, s.deletedKey, id).Err()
+			}
+			if err != nil {
+				return err
+			}
+			if !p.Deleted() || !p.DeletedAt.Before(before) {
+				return nil
+			}
+
+			// Remove product hash and its id from indexes atomically.
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.ZRem(ctx, s.idsKey, id)
+				pipe.ZRem(ctx, s.priceKey, id)
+				pipe.ZRem(ctx, s.sellerKey(p.Seller), id)
+				for _, c := range p.Categories {
+					pipe.ZRem(ctx, s.categoryKey(c), id)
+				}
+				s.deleteVariants(ctx, pipe, p, p.Variants)
+				pipe.ZRem(ctx, s.deletedKey, id)
+				pipe.ZRem(ctx, s.unpublishedKey, id)
+				pipe.ZRem(ctx, s.scheduledKey, id)
+				pipe.ZRem(ctx, s.visibleKey, id)
+				pipe.ZRem(ctx, s.sellerVisibleKey(p.Seller), id)
+				pipe.Del(ctx, s.hashKey(id))
+				return nil
+			})
+			purged = err == nil
+			return err
+		})
+		if err != nil {
+			return n, fmt.Errorf("purging products: %w", err)
+		}
+		if purged {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// PublishDue publishes the drafts found in the scheduled index. Every draft
+// is published in a transaction watching its hash, so a draft changed
+// concurrently is checked again.
+func (s *ProductStorage) PublishDue(ctx context.Context, now time.Time) ([]*product.Product, error) {
+	by := &redis.ZRangeBy{Min: "-inf", Max: strconv.FormatInt(millis(now), 10)}
+	ids, err := s.rdb.ZRangeByScore(ctx, s.scheduledKey, by).Result()
+	if err != nil {
+		return nil, fmt.Errorf("publishing products: %w", err)
+	}
+
+	var ps []*product.Product
+	for _, id := range ids {
+		var published *product.Product
+		err := s.watchProduct(ctx, id, func(tx *redis.Tx) error {
+			published = nil
+			p, err := s.readProductFromHash(ctx, tx, id)
+			if errors.Is(err, product.ErrNotFound) {
+				// Only the index entry is left.
+				return tx.ZRem(ctx, s.scheduledKey, id).Err()
+			}
+			if err != nil {
+				return err
+			}
+			// Deleted drafts are kept scheduled, as they may be restored.
+			if p.Deleted() || p.Status != product.StatusDraft || p.PublishAt == nil || p.PublishAt.After(now) {
+				return nil
+			}
+
+			p.Status = product.StatusPublished
+			p.PublishAt = nil
+			p.Version++
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				s.setProductToHash(ctx, pipe, p)
+				s.indexStatus(ctx, pipe, p)
+				return nil
+			})
+			if err == nil {
+				published = p
+			}
+			return err
+		})
+		if err != nil {
+			return ps, fmt.Errorf("publishing products: %w", err)
+		}
+		if published != nil {
+			ps = append(ps, published)
+		}
+	}
+
+	return ps, nil
+}
+
+// millis returns t as milliseconds since the Unix epoch.
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// setProductToHash queues the product hash update in the pipeline. Stock
+// counters are changed by UpdateStock only and variants by setVariants, they
+// are not set here. Attributes are kept as a list of name:type pairs and media
+// are kept JSON encoded.
+func (s *ProductStorage) setProductToHash(ctx context.Context, pipe redis.Pipeliner, p *product.Product) {
+	pipe.HSet(
 		ctx, s.hashKey(p.ID),
 		"name", p.Name,
-		"price", strconv.FormatInt(p.Price, 10),
+		"price", strconv.FormatInt(p.Price.Amount, 10),
+		"currency", p.Price.Currency,
 		"seller", p.Seller,
-	).Err()
+		"categories", strings.Join(p.Categories, ","),
+		"attributes", formatAttributes(p.Attributes),
+		"low_stock_threshold", strconv.FormatInt(p.LowStockThreshold, 10),
+		"version", strconv.FormatInt(p.Version, 10),
+		"status", string(p.Status),
+	)
+	if p.PublishAt != nil {
+		pipe.HSet(ctx, s.hashKey(p.ID), "publish_at", p.PublishAt.UTC().Format(time.RFC3339Nano))
+	} else {
+		pipe.HDel(ctx, s.hashKey(p.ID), "publish_at")
+	}
+	if len(p.Media) > 0 {
+		b, _ := json.Marshal(p.Media) // media have no values failing to encode
+		pipe.HSet(ctx, s.hashKey(p.ID), "media", string(b))
+	} else {
+		pipe.HDel(ctx, s.hashKey(p.ID), "media")
+	}
+}
+
+// setVariants queues the replacement of the old variants of the product with
+// its current ones in the pipeline, along with the update of the attribute
+// index.
+func (s *ProductStorage) setVariants(ctx context.Context, pipe redis.Pipeliner, p *product.Product, old []product.Variant) {
+	s.deleteVariants(ctx, pipe, p, old)
+
+	score := float64(created(p))
+	for i, v := range p.Variants {
+		fields := []interface{}{
+			"sku", v.SKU,
+			"price", strconv.FormatInt(v.Price.Amount, 10),
+			"currency", v.Price.Currency,
+			"stock", strconv.FormatInt(v.Stock, 10),
+		}
+		for name, value := range v.Attributes {
+			fields = append(fields, "attr:"+name, value)
+			pipe.ZAdd(ctx, s.attributeKey(name, value), &redis.Z{Score: score, Member: p.ID})
+		}
+		pipe.HSet(ctx, s.variantKey(p.ID, i), fields...)
+	}
+	pipe.HSet(ctx, s.hashKey(p.ID), "variants", strconv.Itoa(len(p.Variants)))
+}
+
+// deleteVariants queues the removal of the variant hashes and of their
+// attribute index entries in the pipeline.
+func (s *ProductStorage) deleteVariants(ctx context.Context, pipe redis.Pipeliner, p *product.Product, variants []product.Variant) {
+	for i, v := range variants {
+		for name, value := range v.Attributes {
+			pipe.ZRem(ctx, s.attributeKey(name, value), p.ID)
+		}
+		pipe.Del(ctx, s.variantKey(p.ID, i))
+	}
+}
+
+// readVariants reads the n variant hashes of the product.
+func (s *ProductStorage) readVariants(ctx context.Context, c redis.Cmdable, id string, n int) ([]product.Variant, error) {
+	variants := make([]product.Variant, 0, n)
+	for i := 0; i < n; i++ {
+		fields, err := c.HGetAll(ctx, s.variantKey(id, i)).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := parseVariant(fields)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	return variants, nil
+}
+
+// parseVariant parses the fields of a variant hash.
+func parseVariant(fields map[string]string) (product.Variant, error) {
+	v := product.Variant{SKU: fields["sku"], Attributes: make(map[string]string)}
+	amount, err := strconv.ParseInt(fields["price"], 10, 64)
 	if err != nil {
-		return fmt.Errorf("setting product hash: %w", err)
+		return product.Variant{}, fmt.Errorf("parsing variant price: %w", err)
 	}
-	return nil
+	v.Price = money.New(amount, fields["currency"])
+	v.Stock, err = strconv.ParseInt(fields["stock"], 10, 64)
+	if err != nil {
+		return product.Variant{}, fmt.Errorf("parsing variant stock: %w", err)
+	}
+	for field, value := range fields {
+		if strings.HasPrefix(field, "attr:") {
+			v.Attributes[strings.TrimPrefix(field, "attr:")] = value
+		}
+	}
+	return v, nil
+}
+
+// formatAttributes returns the attributes as a comma-separated list of
+// name:type pairs. Attribute names and types contain neither commas nor
+// colons.
+func formatAttributes(attrs []product.Attribute) string {
+	pairs := make([]string, 0, len(attrs))
+	for _, a := range attrs {
+		pairs = append(pairs, a.Name+":"+string(a.Type))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// parseAttributes parses the attributes formatted with formatAttributes.
+func parseAttributes(s string) ([]product.Attribute, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var attrs []product.Attribute
+	for _, pair := range strings.Split(s, ",") {
+		i := strings.IndexByte(pair, ':')
+		if i < 0 {
+			return nil, fmt.Errorf("invalid attribute %q", pair)
+		}
+		attrs = append(attrs, product.Attribute{Name: pair[:i], Type: product.AttributeType(pair[i+1:])})
+	}
+	return attrs, nil
 }
 
 func (s *ProductStorage) getProductFromHash(ctx context.Context, id string) (*product.Product, error) {
-	num, err := s.rdb.Exists(ctx, s.hashKey(id)).Result()
-	if num == 0 {
-		return nil, product.ErrNotFound
+	return s.readProductFromHash(ctx, s.rdb, id)
+}
+
+// productFields are the fields of the product hash in the order
+// parseProduct expects them.
+var productFields = []string{"name", "price", "seller", "categories",
+	"stock", "reserved", "low_stock_threshold", "version", "deleted_at",
+	"status", "publish_at", "currency", "media", "attributes", "variants",
+	"rating_average", "rating_count",
+}
+
+// readProductFromHash reads the product hash with the client, which may be a
+// transaction holding its own connection.
+func (s *ProductStorage) readProductFromHash(ctx context.Context, c redis.Cmdable, id string) (*product.Product, error) {
+	val, err := c.HMGet(ctx, s.hashKey(id), productFields...).Result()
+	if err != nil {
+		return nil, err
 	}
 
-	val, err := s.rdb.HMGet(ctx, s.hashKey(id), "name", "price", "seller").Result()
+	p, n, err := parseProduct(id, val)
 	if err != nil {
 		return nil, err
 	}
+	if n > 0 {
+		p.Variants, err = s.readVariants(ctx, c, id, n)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// parseProduct parses the values of productFields read from the product hash
+// and returns the product along with the number of its variants, which are
+// kept in their own hashes. It returns product.ErrNotFound if there is no
+// hash.
+func parseProduct(id string, val []interface{}) (*product.Product, int, error) {
+	missing := true
+	for _, v := range val {
+		if v != nil {
+			missing = false
+			break
+		}
+	}
+	if missing {
+		return nil, 0, product.ErrNotFound
+	}
 
 	name, ok := val[0].(string)
 	if !ok {
-		return nil, errors.New("nil name field in redis")
+		return nil, 0, errors.New("nil name field in redis")
 	}
 
 	priceString, ok := val[1].(string)
 	if !ok {
-		return nil, errors.New("nil price field in redis")
+		return nil, 0, errors.New("nil price field in redis")
 	}
-	price, err := strconv.ParseInt(priceString, 10, 64)
+	amount, err := strconv.ParseInt(priceString, 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("parsing price: %w", err)
+		return nil, 0, fmt.Errorf("parsing price: %w", err)
+	}
+	currency, ok := val[11].(string)
+	if !ok {
+		currency = money.DefaultCurrency
 	}
 
 	seller, ok := val[2].(string)
 	if !ok {
-		return nil, errors.New("nil seller field in redis")
+		return nil, 0, errors.New("nil seller field in redis")
+	}
+
+	// Products stored before categories were introduced have no field.
+	var categories []string
+	if s, ok := val[3].(string); ok && s != "" {
+		categories = strings.Split(s, ",")
+	}
+
+	// Products stored before stock and versions were introduced have no
+	// counters.
+	var counters [4]int64
+	for i := range counters {
+		s, ok := val[4+i].(string)
+		if !ok {
+			continue
+		}
+		counters[i], err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("parsing counters: %w", err)
+		}
 	}
 
 	p := &product.Product{
-		ID:     id,
-		Name:   name,
-		Price:  price,
-		Seller: seller,
+		ID:         id,
+		Name:       name,
+		Price:      money.New(amount, currency),
+		Seller:     seller,
+		Categories: categories,
+
+		Stock:             counters[0],
+		Reserved:          counters[1],
+		LowStockThreshold: counters[2],
+
+		Version: counters[3],
 	}
-	return p, nil
+
+	if s, ok := val[8].(string); ok {
+		deletedAt, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, 0, fmt.Errorf("parsing deleted_at: %w", err)
+		}
+		p.DeletedAt = &deletedAt
+	}
+
+	// Products stored before statuses were introduced are published.
+	p.Status = product.StatusPublished
+	if s, ok := val[9].(string); ok && s != "" {
+		p.Status = product.Status(s)
+	}
+	if s, ok := val[10].(string); ok {
+		publishAt, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, 0, fmt.Errorf("parsing publish_at: %w", err)
+		}
+		p.PublishAt = &publishAt
+	}
+	if v, ok := val[12].(string); ok {
+		if err := json.Unmarshal([]byte(v), &p.Media); err != nil {
+			return nil, 0, fmt.Errorf("parsing media: %w", err)
+		}
+	}
+
+	// Products stored before variants were introduced have no fields.
+	if v, ok := val[13].(string); ok {
+		p.Attributes, err = parseAttributes(v)
+		if err != nil {
+			return nil, 0, fmt.Errorf("parsing attributes: %w", err)
+		}
+	}
+	// Products stored before variants were introduced have no field.
+	var n int
+	if v, ok := val[14].(string); ok {
+		n, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, 0, fmt.Errorf("parsing variants: %w", err)
+		}
+	}
+
+	// Products stored before ratings were introduced have no fields.
+	if v, ok := val[15].(string); ok {
+		p.Rating.Average, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("parsing rating: %w", err)
+		}
+	}
+	if v, ok := val[16].(string); ok {
+		p.Rating.Count, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("parsing rating: %w", err)
+		}
+	}
+
+	return p, n, nil
 }
 
 func (s *ProductStorage) hashKey(id string) string {
 	return fmt.Sprintf("%s:%s", s.baseKey, id)
 }
+
+func (s *ProductStorage) sellerKey(seller string) string {
+	return fmt.Sprintf("%s:seller:%s", s.baseKey, seller)
+}
+
+func (s *ProductStorage) attributeKey(name, value string) string {
+	return fmt.Sprintf("%s:attr:%s:%s", s.baseKey, name, value)
+}
+
+func (s *ProductStorage) variantKey(id string, i int) string {
+	return fmt.Sprintf("%s:%s:variant:%d", s.baseKey, id, i)
+}
+
+func (s *ProductStorage) sellerVisibleKey(seller string) string {
+	return fmt.Sprintf("%s:seller:%s:visible", s.baseKey, seller)
+}
+
+func (s *ProductStorage) categoryKey(category string) string {
+	return fmt.Sprintf("%s:category:%s", s.baseKey, category)
+}
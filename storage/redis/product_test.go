@@ -0,0 +1,107 @@
+package redis
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/product"
+	"github.com/ortymid/market/storage/storagetest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProductStorage_Conformance(t *testing.T) {
+	storagetest.TestProductStorage(t, func(t *testing.T) product.Storage {
+		s, _ := newTestProductStorage(t)
+		return s
+	})
+}
+
+func newTestProductStorage(t *testing.T) (*ProductStorage, *redis.Client) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("running miniredis: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	return NewProductStorage(rdb, "products"), rdb
+}
+
+func TestProductStorage_FindSkipsMissingHashes(t *testing.T) {
+	ctx := context.Background()
+	s, rdb := newTestProductStorage(t)
+
+	var ids []string
+	for _, name := range []string{"Banana", "Carrot"} {
+		p, err := s.Create(ctx, product.CreateRequest{Name: name, Price: money.New(100, "USD"), Seller: "1"})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		ids = append(ids, p.ID)
+	}
+	// The hash is gone while the indexes still have the id, as if it was
+	// purged between reading the index and the hash.
+	if err := rdb.Del(ctx, s.hashKey(ids[0])).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	published := product.StatusPublished
+	for _, r := range []product.FindRequest{
+		{Limit: 10, Status: &published},
+		{Limit: 10, Status: &published, Sort: []product.Sort{{Key: product.SortKeyPrice}}},
+	} {
+		res, err := s.Find(ctx, r)
+		if err != nil {
+			t.Fatalf("Find() error = %v", err)
+		}
+		if len(res.Products) != 1 || res.Products[0].ID != ids[1] {
+			t.Errorf("Find() got %v, want only product %s", res.Products, ids[1])
+		}
+	}
+}
+
+func TestProductStorage_IndexVisible(t *testing.T) {
+	ctx := context.Background()
+	s, rdb := newTestProductStorage(t)
+
+	create := func(seller string, status product.Status) string {
+		p, err := s.Create(ctx, product.CreateRequest{Name: "Banana", Price: money.New(100, "USD"), Seller: seller, Status: status})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		return p.ID
+	}
+	visible := create("1", product.StatusPublished)
+	create("1", product.StatusDraft)
+	deleted := create("2", product.StatusPublished)
+	if _, err := s.Delete(ctx, deleted, time.Now()); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	// Products stored before the visible indexes have no entries in them.
+	if err := rdb.Del(ctx, s.visibleKey, s.sellerVisibleKey("1"), s.sellerVisibleKey("2")).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.IndexVisible(ctx); err != nil {
+		t.Fatalf("IndexVisible() error = %v", err)
+	}
+
+	for key, want := range map[string][]string{
+		s.visibleKey:            {visible},
+		s.sellerVisibleKey("1"): {visible},
+		s.sellerVisibleKey("2"): {},
+	} {
+		got, err := rdb.ZRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ZRange(%s) after IndexVisible() = %v, want %v", key, got, want)
+		}
+	}
+}
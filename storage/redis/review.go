@@ -0,0 +1,290 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/ortymid/market/market/review"
+	"strconv"
+	"time"
+)
+
+// createReviewScript claims the product for the author and stores the review
+// with its indexes. It returns 0 if the author has a review of the product and
+// 1 on success.
+//
+// KEYS: authors hash of the product, review hash, ids index, product index, author index.
+// ARGV: author, id, then field-value pairs of the review hash.
+var createReviewScript = redis.NewScript(`
+if redis.call("HSETNX", KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[2], unpack(ARGV, 3))
+redis.call("ZADD", KEYS[3], ARGV[2], ARGV[2])
+redis.call("ZADD", KEYS[4], ARGV[2], ARGV[2])
+redis.call("ZADD", KEYS[5], ARGV[2], ARGV[2])
+return 1
+`)
+
+// reviewFields are the fields of the review hash.
+var reviewFields = []string{
+	"product", "author", "rating", "text", "created_at", "updated_at",
+}
+
+// ReviewStorage keeps reviews in hashes. Ids of the reviews are kept in sorted
+// sets scored by id to keep the order of creation:
+//   - <key>:ids for all reviews;
+//   - <key>:product:<product> for reviews of a product;
+//   - <key>:author:<author> for reviews of an author.
+//
+// The <key>:product:<product>:authors hash maps authors of the reviews of a
+// product to ids.
+type ReviewStorage struct {
+	rdb *redis.Client
+
+	baseKey string
+	idsKey  string
+}
+
+func NewReviewStorage(rdb *redis.Client, key string) *ReviewStorage {
+	idsKey := fmt.Sprintf("%s:ids", key)
+
+	return &ReviewStorage{rdb: rdb, baseKey: key, idsKey: idsKey}
+}
+
+func (s *ReviewStorage) Find(ctx context.Context, r review.FindRequest) ([]*review.Review, error) {
+	key := s.idsKey
+	switch {
+	case r.Product != nil:
+		key = s.productKey(*r.Product)
+	case r.Author != nil:
+		key = s.authorKey(*r.Author)
+	}
+
+	// The page is taken right from the index if it is the only filter.
+	if r.Product == nil || r.Author == nil {
+		if r.Limit <= 0 {
+			return []*review.Review{}, nil
+		}
+
+		ids, err := s.rdb.ZRange(ctx, key, r.Offset, r.Offset+r.Limit-1).Result()
+		if err != nil {
+			return nil, err
+		}
+		return s.getReviews(ctx, ids, r)
+	}
+
+	ids, err := s.rdb.ZRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	rvs, err := s.getReviews(ctx, ids, r)
+	if err != nil {
+		return nil, err
+	}
+	return r.Page(rvs), nil
+}
+
+// getReviews returns the reviews of the ids matching the request filters.
+func (s *ReviewStorage) getReviews(ctx context.Context, ids []string, r review.FindRequest) ([]*review.Review, error) {
+	rvs := make([]*review.Review, 0, len(ids))
+	for _, id := range ids {
+		rv, err := s.getReviewFromHash(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.Match(rv) {
+			rvs = append(rvs, rv)
+		}
+	}
+
+	return rvs, nil
+}
+
+func (s *ReviewStorage) FindOne(ctx context.Context, id string) (*review.Review, error) {
+	return s.getReviewFromHash(ctx, id)
+}
+
+func (s *ReviewStorage) Create(ctx context.Context, rv review.Review) (*review.Review, error) {
+	// Get new id.
+	id, err := s.rdb.Incr(ctx, fmt.Sprintf("%s:id", s.baseKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("getting new id: %w", err)
+	}
+	rv.ID = strconv.FormatInt(id, 10)
+
+	keys := []string{
+		s.authorsKey(rv.Product), s.hashKey(rv.ID), s.idsKey, s.productKey(rv.Product), s.authorKey(rv.Author),
+	}
+	args := []interface{}{rv.Author, rv.ID}
+	args = append(args, reviewToHash(&rv)...)
+
+	created, err := createReviewScript.Run(ctx, s.rdb, keys, args...).Int()
+	if err != nil {
+		return nil, fmt.Errorf("storing review: %w", err)
+	}
+	if created == 0 {
+		return nil, review.ErrDuplicate
+	}
+
+	return &rv, nil
+}
+
+func (s *ReviewStorage) Update(ctx context.Context, r review.UpdateRequest, at time.Time) (*review.Review, error) {
+	args := []interface{}{"updated_at", at.UTC().Format(time.RFC3339Nano)}
+	if r.Rating != nil {
+		args = append(args, "rating", strconv.FormatInt(*r.Rating, 10))
+	}
+	if r.Text != nil {
+		args = append(args, "text", *r.Text)
+	}
+
+	updated, err := setIfExistsScript.Run(ctx, s.rdb, []string{s.hashKey(r.ID)}, args...).Int()
+	if err != nil {
+		return nil, fmt.Errorf("storing review: %w", err)
+	}
+	if updated == 0 {
+		return nil, review.ErrNotFound
+	}
+
+	return s.getReviewFromHash(ctx, r.ID)
+}
+
+func (s *ReviewStorage) Delete(ctx context.Context, id string) (*review.Review, error) {
+	// FindOne review checking for existence.
+	rv, err := s.getReviewFromHash(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove review hash, its author and its id from indexes atomically.
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, s.idsKey, id)
+		pipe.ZRem(ctx, s.productKey(rv.Product), id)
+		pipe.ZRem(ctx, s.authorKey(rv.Author), id)
+		pipe.HDel(ctx, s.authorsKey(rv.Product), rv.Author)
+		pipe.Del(ctx, s.hashKey(id))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("deleting review: %w", err)
+	}
+
+	return rv, nil
+}
+
+// Summarize reads the ratings of the reviews of the product in one pipeline.
+// Reviews deleted meanwhile are skipped.
+func (s *ReviewStorage) Summarize(ctx context.Context, product string) (review.Summary, error) {
+	ids, err := s.rdb.ZRange(ctx, s.productKey(product), 0, -1).Result()
+	if err != nil {
+		return review.Summary{}, err
+	}
+
+	cmds := make([]*redis.StringCmd, 0, len(ids))
+	_, err = s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range ids {
+			cmds = append(cmds, pipe.HGet(ctx, s.hashKey(id), "rating"))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return review.Summary{}, err
+	}
+
+	var sum review.Summary
+	for _, cmd := range cmds {
+		rating, err := cmd.Int64()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return review.Summary{}, fmt.Errorf("parsing rating: %w", err)
+		}
+		sum.Count++
+		sum.Sum += rating
+	}
+
+	return sum, nil
+}
+
+// reviewToHash returns field-value pairs of the review hash in the order of
+// reviewFields.
+func reviewToHash(rv *review.Review) []interface{} {
+	values := []string{
+		rv.Product, rv.Author,
+		strconv.FormatInt(rv.Rating, 10),
+		rv.Text,
+		rv.CreatedAt.UTC().Format(time.RFC3339Nano),
+		rv.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	}
+
+	pairs := make([]interface{}, 0, 2*len(values))
+	for i, v := range values {
+		pairs = append(pairs, reviewFields[i], v)
+	}
+	return pairs
+}
+
+func (s *ReviewStorage) getReviewFromHash(ctx context.Context, id string) (*review.Review, error) {
+	val, err := s.rdb.HMGet(ctx, s.hashKey(id), reviewFields...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, len(val))
+	for i, v := range val {
+		f, ok := v.(string)
+		if !ok {
+			if i == 0 {
+				// There is no hash at all.
+				return nil, review.ErrNotFound
+			}
+			return nil, fmt.Errorf("nil %s field in redis", reviewFields[i])
+		}
+		fields[i] = f
+	}
+
+	rating, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parsing rating: %w", err)
+	}
+
+	var times [2]time.Time
+	for i := range times {
+		times[i], err = time.Parse(time.RFC3339Nano, fields[4+i])
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", reviewFields[4+i], err)
+		}
+	}
+
+	rv := &review.Review{
+		ID:        id,
+		Product:   fields[0],
+		Author:    fields[1],
+		Rating:    rating,
+		Text:      fields[3],
+		CreatedAt: times[0],
+		UpdatedAt: times[1],
+	}
+	return rv, nil
+}
+
+func (s *ReviewStorage) hashKey(id string) string {
+	return fmt.Sprintf("%s:%s", s.baseKey, id)
+}
+
+func (s *ReviewStorage) productKey(product string) string {
+	return fmt.Sprintf("%s:product:%s", s.baseKey, product)
+}
+
+func (s *ReviewStorage) authorKey(author string) string {
+	return fmt.Sprintf("%s:author:%s", s.baseKey, author)
+}
+
+func (s *ReviewStorage) authorsKey(product string) string {
+	return fmt.Sprintf("%s:product:%s:authors", s.baseKey, product)
+}
@@ -0,0 +1,218 @@
+// Package s3 provides a blob store keeping blobs in a bucket of an
+// S3-compatible service, e.g. Amazon S3 or MinIO. Requests are made with
+// path-style URLs and signed with AWS Signature Version 4.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/ortymid/market/market/media"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultRegion is the region requests are signed for if no region is set.
+const DefaultRegion = "us-east-1"
+
+type Config struct {
+	// Endpoint is the URL of the service, e.g. "https://s3.amazonaws.com" or
+	// "http://localhost:9000".
+	Endpoint  string
+	Bucket    string
+	Region    string // DefaultRegion if empty
+	AccessKey string
+	SecretKey string
+	// Prefix is prepended to the keys of the blobs, so that a bucket may be
+	// shared. Optional.
+	Prefix string
+}
+
+// ConfigFromURL parses the config from a URL of the form
+// s3://<access key>:<secret key>@<host>/<bucket>[/<prefix>], e.g.
+// s3://minio:minio123@minio:9000/market/media. The region is set with the
+// region query parameter, and insecure=true makes requests with HTTP.
+func ConfigFromURL(rawURL string) (Config, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Config{}, err
+	}
+	if u.Scheme != "s3" {
+		return Config{}, fmt.Errorf("unknown scheme %q", u.Scheme)
+	}
+
+	bucket, prefix := strings.TrimPrefix(u.Path, "/"), ""
+	if i := strings.IndexByte(bucket, '/'); i >= 0 {
+		bucket, prefix = bucket[:i], strings.Trim(bucket[i+1:], "/")
+	}
+	if bucket == "" {
+		return Config{}, errors.New("no bucket in url")
+	}
+
+	scheme := "https"
+	if u.Query().Get("insecure") == "true" {
+		scheme = "http"
+	}
+	secretKey, _ := u.User.Password()
+
+	return Config{
+		Endpoint:  scheme + "://" + u.Host,
+		Bucket:    bucket,
+		Region:    u.Query().Get("region"),
+		AccessKey: u.User.Username(),
+		SecretKey: secretKey,
+		Prefix:    prefix,
+	}, nil
+}
+
+// BlobStore keeps blobs as objects of the bucket. Blobs are read into memory
+// to be signed, it is meant for blobs of a few megabytes.
+type BlobStore struct {
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+	creds    credentials
+	client   *http.Client
+
+	// now is used to sign requests, it is replaced in tests.
+	now func() time.Time
+}
+
+func NewBlobStore(cfg Config) (*BlobStore, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing endpoint: %w", err)
+	}
+	if cfg.Bucket == "" {
+		return nil, errors.New("bucket is empty")
+	}
+	region := cfg.Region
+	if region == "" {
+		region = DefaultRegion
+	}
+
+	return &BlobStore{
+		endpoint: endpoint,
+		bucket:   cfg.Bucket,
+		prefix:   strings.Trim(cfg.Prefix, "/"),
+		creds:    credentials{region: region, accessKey: cfg.AccessKey, secretKey: cfg.SecretKey},
+		client:   &http.Client{Timeout: time.Minute},
+		now:      time.Now,
+	}, nil
+}
+
+func (s *BlobStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := s.do(req, hashHex(data))
+	if err != nil {
+		return fmt.Errorf("putting blob: %w", err)
+	}
+	res.Body.Close()
+	return nil
+}
+
+func (s *BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		if errors.Is(err, media.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("getting blob: %w", err)
+	}
+	return res.Body, nil
+}
+
+func (s *BlobStore) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	// Deleting a missing object succeeds.
+	res, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return fmt.Errorf("deleting blob: %w", err)
+	}
+	res.Body.Close()
+	return nil
+}
+
+// newRequest makes a request for the object of the key.
+func (s *BlobStore) newRequest(ctx context.Context, method, key string, body []byte) (*http.Request, error) {
+	if key == "" {
+		return nil, errors.New("empty blob key")
+	}
+	if s.prefix != "" {
+		key = s.prefix + "/" + key
+	}
+
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	u.RawPath = escapePath(u.Path)
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	return http.NewRequestWithContext(ctx, method, u.String(), r)
+}
+
+// do signs and sends the request. Responses with an error status are closed
+// and returned as errors, media.ErrNotFound for missing objects.
+func (s *BlobStore) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.creds.sign(req, payloadHash, s.now())
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+
+	e := responseError{Status: res.StatusCode}
+	b, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1<<16))
+	_ = xml.Unmarshal(b, &e)
+	if e.Code == "NoSuchKey" || (e.Code == "" && res.StatusCode == http.StatusNotFound) {
+		return nil, media.ErrNotFound
+	}
+	return nil, e
+}
+
+// responseError is an error returned by the service.
+type responseError struct {
+	Status  int    `xml:"-"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e responseError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("status %d", e.Status)
+	}
+	return fmt.Sprintf("status %d: %s: %s", e.Status, e.Code, e.Message)
+}
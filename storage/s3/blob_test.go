@@ -0,0 +1,243 @@
+package s3
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/ortymid/market/market/media"
+	"github.com/ortymid/market/storage/storagetest"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a stand-in for the service keeping objects of a bucket in memory.
+// It checks that requests are signed with the credentials and that the
+// payload hash matches the body.
+type fakeS3 struct {
+	bucket string
+	creds  credentials
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := f.checkSignature(r, body); err != nil {
+		writeFakeError(w, http.StatusForbidden, "SignatureDoesNotMatch", err.Error())
+		return
+	}
+
+	prefix := "/" + f.bucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		writeFakeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key] = body
+	case http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			writeFakeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// checkSignature signs a copy of the request again and compares the
+// signatures.
+func (f *fakeS3) checkSignature(r *http.Request, body []byte) error {
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash != hashHex(body) {
+		return fmt.Errorf("payload hash %s does not match the body", payloadHash)
+	}
+	t, err := time.Parse(amzDateFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return err
+	}
+
+	// Only the signed headers are copied, as the client may add others.
+	auth := r.Header.Get("Authorization")
+	i, j := strings.Index(auth, "SignedHeaders="), strings.Index(auth, ",Signature=")
+	if i < 0 || j < i {
+		return fmt.Errorf("malformed authorization %s", auth)
+	}
+	signed := r.Clone(r.Context())
+	signed.URL.Host = r.Host
+	signed.Header = http.Header{}
+	for _, name := range strings.Split(auth[i+len("SignedHeaders="):j], ";") {
+		if name != "host" {
+			signed.Header[http.CanonicalHeaderKey(name)] = r.Header[http.CanonicalHeaderKey(name)]
+		}
+	}
+	f.creds.sign(signed, payloadHash, t)
+
+	if got, want := r.Header.Get("Authorization"), signed.Header.Get("Authorization"); got != want {
+		return fmt.Errorf("authorization %s, want %s", got, want)
+	}
+	return nil
+}
+
+func writeFakeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, message)
+}
+
+// newFakeS3 starts the fake service and returns the config of its bucket.
+func newFakeS3(t *testing.T) (*fakeS3, Config) {
+	f := &fakeS3{
+		bucket:  "market",
+		creds:   credentials{region: "eu-central-1", accessKey: "key", secretKey: "secret"},
+		objects: make(map[string][]byte),
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	return f, Config{
+		Endpoint:  srv.URL,
+		Bucket:    f.bucket,
+		Region:    f.creds.region,
+		AccessKey: f.creds.accessKey,
+		SecretKey: f.creds.secretKey,
+	}
+}
+
+func TestBlobStore_Conformance(t *testing.T) {
+	storagetest.TestBlobStore(t, func(t *testing.T) media.BlobStore {
+		_, cfg := newFakeS3(t)
+		s, err := NewBlobStore(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
+
+func TestBlobStore_Prefix(t *testing.T) {
+	f, cfg := newFakeS3(t)
+	cfg.Prefix = "media/"
+	s, err := NewBlobStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Put(context.Background(), "products/1/a b", strings.NewReader("data"), "image/png"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, ok := f.objects["media/products/1/a b"]; !ok {
+		t.Errorf("Put() got objects %v, want media/products/1/a b", f.objects)
+	}
+}
+
+func TestBlobStore_Errors(t *testing.T) {
+	_, cfg := newFakeS3(t)
+
+	cfg.SecretKey = "wrong"
+	s, err := NewBlobStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Put(context.Background(), "products/1/a", strings.NewReader("data"), "")
+	if err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("Put() with wrong key error = %v, want SignatureDoesNotMatch", err)
+	}
+
+	cfg.Bucket = "other"
+	s, err = NewBlobStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Get(context.Background(), "products/1/a")
+	if err == nil || errors.Is(err, media.ErrNotFound) {
+		t.Errorf("Get() of missing bucket error = %v, want an error other than %v", err, media.ErrNotFound)
+	}
+}
+
+func TestConfigFromURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    Config
+		wantErr bool
+	}{
+		{
+			url: "s3://key:secret@minio:9000/market/media?insecure=true",
+			want: Config{
+				Endpoint: "http://minio:9000", Bucket: "market", AccessKey: "key", SecretKey: "secret", Prefix: "media",
+			},
+		},
+		{
+			url: "s3://key:secret@s3.amazonaws.com/market?region=eu-central-1",
+			want: Config{
+				Endpoint: "https://s3.amazonaws.com", Bucket: "market", Region: "eu-central-1", AccessKey: "key", SecretKey: "secret",
+			},
+		},
+		{url: "s3://key:secret@minio:9000/", wantErr: true},
+		{url: "http://minio:9000/market", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := ConfigFromURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConfigFromURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConfigFromURL() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBlobStore_Service runs the conformance suite against a real service,
+// e.g. MinIO. It is skipped if MARKET_TEST_S3_URL is not set. The bucket must
+// exist, every test uses its own prefix in it.
+func TestBlobStore_Service(t *testing.T) {
+	url := os.Getenv("MARKET_TEST_S3_URL")
+	if url == "" {
+		t.Skip("MARKET_TEST_S3_URL is not set")
+	}
+	cfg, err := ConfigFromURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.TestBlobStore(t, func(t *testing.T) media.BlobStore {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			t.Fatal(err)
+		}
+		cfg := cfg
+		cfg.Prefix = cfg.Prefix + "/test-" + hex.EncodeToString(b)
+
+		s, err := NewBlobStore(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,199 @@
+package storagetest
+
+import (
+	"context"
+	"github.com/ortymid/market/market/audit"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// NewAuditStorageFunc returns an empty storage. It is called for every test of
+// the suite, so the tests do not affect each other.
+type NewAuditStorageFunc func(t *testing.T) audit.Storage
+
+// TestAuditStorage runs the conformance suite against the storages returned by
+// newStorage.
+func TestAuditStorage(t *testing.T, newStorage NewAuditStorageFunc) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s audit.Storage)
+	}{
+		{name: "Record", test: testAuditRecord},
+		{name: "RecordConcurrent", test: testAuditRecordConcurrent},
+		{name: "Find", test: testAuditFind},
+		{name: "FindNothing", test: testAuditFindNothing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+// testEntry returns an update entry of the product with the version.
+func testEntry(product string, version int64) audit.Entry {
+	return audit.Entry{
+		Product:   product,
+		Seller:    "seller",
+		Actor:     "actor",
+		Operation: audit.OperationUpdate,
+		Version:   version,
+		Changes: []audit.Change{
+			{Field: "name", Before: `"Banana"`, After: `"Green banana"`},
+			{Field: "price", Before: "1500", After: "1000"},
+		},
+		CreatedAt: time.Date(2020, 10, 1, 12, 30, 0, 0, time.UTC).Add(time.Duration(version) * time.Second),
+	}
+}
+
+func testAuditRecord(t *testing.T, s audit.Storage) {
+	ctx := context.Background()
+
+	created := audit.Entry{
+		Product:   "1",
+		Seller:    "seller",
+		Actor:     "seller",
+		Operation: audit.OperationCreate,
+		Version:   1,
+		Changes:   []audit.Change{{Field: "name", After: `"Banana"`}},
+		CreatedAt: time.Date(2020, 10, 1, 12, 30, 0, 0, time.UTC),
+	}
+	// Updates which change nothing are recorded too.
+	updated := testEntry("1", 2)
+	updated.Changes = []audit.Change{}
+
+	mustRecord(t, s, created)
+	mustRecord(t, s, updated)
+
+	got, err := s.Find(ctx, audit.FindRequest{Product: "1", Limit: 10})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Find() got %d entries, want 2", len(got))
+	}
+	checkEntry(t, "Find()", got[0], &created)
+	checkEntry(t, "Find()", got[1], &updated)
+}
+
+func testAuditRecordConcurrent(t *testing.T, s audit.Storage) {
+	const n = 10
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := s.Record(context.Background(), testEntry("1", int64(i))); err != nil {
+				t.Errorf("Record() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	got, err := s.Find(context.Background(), audit.FindRequest{Product: "1", Limit: 2 * n})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	ids := make(map[string]bool)
+	for _, e := range got {
+		ids[e.ID] = true
+	}
+	if len(ids) != n {
+		t.Errorf("Find() got %d distinct entries, want %d", len(ids), n)
+	}
+}
+
+func testAuditFind(t *testing.T, s audit.Storage) {
+	ctx := context.Background()
+
+	for i := int64(1); i <= 4; i++ {
+		mustRecord(t, s, testEntry("1", i))
+		mustRecord(t, s, testEntry("2", i))
+	}
+
+	tests := []struct {
+		name string
+		r    audit.FindRequest
+		want []int64
+	}{
+		{
+			name: "Should find entries of product in order of recording",
+			r:    audit.FindRequest{Product: "1", Limit: 10},
+			want: []int64{1, 2, 3, 4},
+		},
+		{
+			name: "Should find page of entries",
+			r:    audit.FindRequest{Product: "2", Offset: 1, Limit: 2},
+			want: []int64{2, 3},
+		},
+		{
+			name: "Should find nothing past the end",
+			r:    audit.FindRequest{Product: "1", Offset: 4, Limit: 2},
+			want: []int64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Find(ctx, tt.r)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+
+			versions := make([]int64, 0, len(got))
+			for _, e := range got {
+				if e.Product != tt.r.Product {
+					t.Errorf("Find() got entry of product %s, want %s", e.Product, tt.r.Product)
+				}
+				versions = append(versions, e.Version)
+			}
+			if !reflect.DeepEqual(versions, tt.want) {
+				t.Errorf("Find() got versions %v, want %v", versions, tt.want)
+			}
+		})
+	}
+}
+
+func testAuditFindNothing(t *testing.T, s audit.Storage) {
+	mustRecord(t, s, testEntry("1", 1))
+
+	got, err := s.Find(context.Background(), audit.FindRequest{Product: "2", Limit: 10})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Find() got = %v, want no entries", got)
+	}
+}
+
+// checkEntry compares the entries ignoring ids, which are assigned by
+// storages. Times are compared with Equal as storages may return them in
+// another location.
+func checkEntry(t *testing.T, call string, got *audit.Entry, want *audit.Entry) {
+	t.Helper()
+
+	if len(got.ID) == 0 {
+		t.Errorf("%s got empty id", call)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Errorf("%s created at = %v, want %v", call, got.CreatedAt, want.CreatedAt)
+	}
+
+	g, w := *got, *want
+	g.ID, w.ID = "", ""
+	g.CreatedAt, w.CreatedAt = time.Time{}, time.Time{}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("%s got = %+v, want %+v", call, g, w)
+	}
+}
+
+func mustRecord(t *testing.T, s audit.Storage, e audit.Entry) {
+	t.Helper()
+
+	if err := s.Record(context.Background(), e); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+}
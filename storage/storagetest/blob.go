@@ -0,0 +1,108 @@
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/ortymid/market/market/media"
+	"io/ioutil"
+	"testing"
+)
+
+// NewBlobStoreFunc returns an empty blob store. It is called for every test
+// of the suite, so the tests do not affect each other.
+type NewBlobStoreFunc func(t *testing.T) media.BlobStore
+
+// TestBlobStore runs the conformance suite against the blob stores returned
+// by newStore.
+func TestBlobStore(t *testing.T, newStore NewBlobStoreFunc) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s media.BlobStore)
+	}{
+		{name: "PutGet", test: testBlobPutGet},
+		{name: "PutReplaces", test: testBlobPutReplaces},
+		{name: "GetNotFound", test: testBlobGetNotFound},
+		{name: "Delete", test: testBlobDelete},
+		{name: "DeleteNotFound", test: testBlobDeleteNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+func mustPutBlob(t *testing.T, s media.BlobStore, key string, data []byte) {
+	t.Helper()
+
+	if err := s.Put(context.Background(), key, bytes.NewReader(data), "application/octet-stream"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+}
+
+// checkBlob checks that the blob of the key has the data.
+func checkBlob(t *testing.T, s media.BlobStore, key string, want []byte) {
+	t.Helper()
+
+	rc, err := s.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer rc.Close()
+
+	got, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("Get() read error = %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Get() got %q, want %q", got, want)
+	}
+}
+
+func testBlobPutGet(t *testing.T, s media.BlobStore) {
+	mustPutBlob(t, s, "products/1/a", []byte("first"))
+	mustPutBlob(t, s, "products/1/a.thumbnail", []byte("thumbnail"))
+	mustPutBlob(t, s, "products/2/a", []byte{})
+
+	checkBlob(t, s, "products/1/a", []byte("first"))
+	checkBlob(t, s, "products/1/a.thumbnail", []byte("thumbnail"))
+	checkBlob(t, s, "products/2/a", []byte{})
+}
+
+func testBlobPutReplaces(t *testing.T, s media.BlobStore) {
+	mustPutBlob(t, s, "products/1/a", []byte("first"))
+	mustPutBlob(t, s, "products/1/a", []byte("second"))
+
+	checkBlob(t, s, "products/1/a", []byte("second"))
+}
+
+func testBlobGetNotFound(t *testing.T, s media.BlobStore) {
+	mustPutBlob(t, s, "products/1/a", []byte("first"))
+
+	for _, key := range []string{"products/1/b", "products/2/a", "products/1"} {
+		if _, err := s.Get(context.Background(), key); !errors.Is(err, media.ErrNotFound) {
+			t.Errorf("Get(%q) error = %v, want %v", key, err, media.ErrNotFound)
+		}
+	}
+}
+
+func testBlobDelete(t *testing.T, s media.BlobStore) {
+	mustPutBlob(t, s, "products/1/a", []byte("first"))
+	mustPutBlob(t, s, "products/1/b", []byte("second"))
+
+	if err := s.Delete(context.Background(), "products/1/a"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if _, err := s.Get(context.Background(), "products/1/a"); !errors.Is(err, media.ErrNotFound) {
+		t.Errorf("Get() of deleted blob error = %v, want %v", err, media.ErrNotFound)
+	}
+	checkBlob(t, s, "products/1/b", []byte("second"))
+}
+
+func testBlobDeleteNotFound(t *testing.T, s media.BlobStore) {
+	if err := s.Delete(context.Background(), "products/1/a"); err != nil {
+		t.Errorf("Delete() of missing blob error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,175 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"github.com/ortymid/market/market/cart"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// NewCartStorageFunc returns an empty storage. It is called for every test of
+// the suite, so the tests do not affect each other.
+type NewCartStorageFunc func(t *testing.T) cart.Storage
+
+// TestCartStorage runs the conformance suite against the storages returned by
+// newStorage.
+func TestCartStorage(t *testing.T, newStorage NewCartStorageFunc) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s cart.Storage)
+	}{
+		{name: "Save", test: testCartSave},
+		{name: "SaveReplaces", test: testCartSaveReplaces},
+		{name: "SaveEmpty", test: testCartSaveEmpty},
+		{name: "GetNotFound", test: testCartGetNotFound},
+		{name: "Delete", test: testCartDelete},
+		{name: "DeleteNotFound", test: testCartDeleteNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+// testCart returns a cart of the user with two items. It expires in an hour,
+// so storages do not remove it during the test.
+func testCart(user string) cart.Cart {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	return cart.Cart{
+		User: user,
+		Items: []cart.Item{
+			{Product: "1", Quantity: 2, Price: 150, AddedAt: now.Add(-time.Minute)},
+			{Product: "2", Quantity: 1, Price: 300, AddedAt: now},
+		},
+		UpdatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+}
+
+func testCartSave(t *testing.T, s cart.Storage) {
+	ctx := context.Background()
+
+	want := testCart("user")
+	c, err := s.Save(ctx, want)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	checkCart(t, "Save()", c, &want)
+
+	got, err := s.Get(ctx, "user")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	checkCart(t, "Get()", got, &want)
+
+	// Carts are kept per user.
+	if _, err := s.Get(ctx, "other"); !errors.Is(err, cart.ErrNotFound) {
+		t.Errorf("Get() of another user error = %v, want %v", err, cart.ErrNotFound)
+	}
+}
+
+func testCartSaveReplaces(t *testing.T, s cart.Storage) {
+	ctx := context.Background()
+
+	mustSaveCart(t, s, testCart("user"))
+
+	want := testCart("user")
+	want.Items = want.Items[1:]
+	want.Items[0].Quantity = 5
+	mustSaveCart(t, s, want)
+
+	got, err := s.Get(ctx, "user")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	checkCart(t, "Get()", got, &want)
+}
+
+func testCartSaveEmpty(t *testing.T, s cart.Storage) {
+	ctx := context.Background()
+
+	mustSaveCart(t, s, testCart("user"))
+
+	want := testCart("user")
+	want.Items = nil
+	mustSaveCart(t, s, want)
+
+	got, err := s.Get(ctx, "user")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	checkCart(t, "Get()", got, &want)
+}
+
+func testCartGetNotFound(t *testing.T, s cart.Storage) {
+	_, err := s.Get(context.Background(), "user")
+	if !errors.Is(err, cart.ErrNotFound) {
+		t.Errorf("Get() error = %v, want %v", err, cart.ErrNotFound)
+	}
+}
+
+func testCartDelete(t *testing.T, s cart.Storage) {
+	ctx := context.Background()
+
+	want := testCart("user")
+	mustSaveCart(t, s, want)
+
+	got, err := s.Delete(ctx, "user")
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	checkCart(t, "Delete()", got, &want)
+
+	if _, err := s.Get(ctx, "user"); !errors.Is(err, cart.ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want %v", err, cart.ErrNotFound)
+	}
+}
+
+func testCartDeleteNotFound(t *testing.T, s cart.Storage) {
+	_, err := s.Delete(context.Background(), "user")
+	if !errors.Is(err, cart.ErrNotFound) {
+		t.Errorf("Delete() error = %v, want %v", err, cart.ErrNotFound)
+	}
+}
+
+// checkCart compares the carts. Times are compared in UTC as storages may
+// return them in another location, and no items equal empty items.
+func checkCart(t *testing.T, call string, got *cart.Cart, want *cart.Cart) {
+	t.Helper()
+
+	if got == nil {
+		t.Fatalf("%s got nil cart", call)
+	}
+
+	g, w := normalizeCart(*got), normalizeCart(*want)
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("%s got = %+v, want %+v", call, g, w)
+	}
+}
+
+func normalizeCart(c cart.Cart) cart.Cart {
+	c.UpdatedAt = c.UpdatedAt.UTC()
+	c.ExpiresAt = c.ExpiresAt.UTC()
+
+	items := make([]cart.Item, len(c.Items))
+	for i, it := range c.Items {
+		it.AddedAt = it.AddedAt.UTC()
+		items[i] = it
+	}
+	c.Items = items
+
+	return c
+}
+
+func mustSaveCart(t *testing.T, s cart.Storage, c cart.Cart) *cart.Cart {
+	t.Helper()
+
+	saved, err := s.Save(context.Background(), c)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	return saved
+}
@@ -0,0 +1,206 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"github.com/ortymid/market/market/category"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// NewCategoryStorageFunc returns an empty storage. It is called for every test
+// of the suite, so the tests do not affect each other.
+type NewCategoryStorageFunc func(t *testing.T) category.Storage
+
+// TestCategoryStorage runs the conformance suite against the storages returned
+// by newStorage.
+func TestCategoryStorage(t *testing.T, newStorage NewCategoryStorageFunc) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s category.Storage)
+	}{
+		{name: "Create", test: testCategoryCreate},
+		{name: "FindOneNotFound", test: testCategoryFindOneNotFound},
+		{name: "Find", test: testCategoryFind},
+		{name: "Update", test: testCategoryUpdate},
+		{name: "UpdateNotFound", test: testCategoryUpdateNotFound},
+		{name: "Delete", test: testCategoryDelete},
+		{name: "DeleteNotFound", test: testCategoryDeleteNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+func testCategoryCreate(t *testing.T, s category.Storage) {
+	ctx := context.Background()
+
+	root, err := s.Create(ctx, category.CreateRequest{Slug: "food", Name: "Food"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if len(root.ID) == 0 {
+		t.Errorf("Create() got empty id")
+	}
+	want := &category.Category{ID: root.ID, Slug: "food", Name: "Food"}
+	if !reflect.DeepEqual(root, want) {
+		t.Errorf("Create() got = %v, want %v", root, want)
+	}
+
+	child, err := s.Create(ctx, category.CreateRequest{Slug: "fruits", Name: "Fruits", Parent: &root.ID})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	want = &category.Category{ID: child.ID, Slug: "fruits", Name: "Fruits", Parent: ptrString(root.ID)}
+	if !reflect.DeepEqual(child, want) {
+		t.Errorf("Create() got = %v, want %v", child, want)
+	}
+
+	got, err := s.FindOne(ctx, child.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOne() got = %v, want %v", got, want)
+	}
+}
+
+func testCategoryFindOneNotFound(t *testing.T, s category.Storage) {
+	for _, id := range notFoundIDs {
+		_, err := s.FindOne(context.Background(), id)
+		if !errors.Is(err, category.ErrNotFound) {
+			t.Errorf("FindOne(%q) error = %v, want %v", id, err, category.ErrNotFound)
+		}
+	}
+}
+
+func testCategoryFind(t *testing.T, s category.Storage) {
+	ctx := context.Background()
+
+	got, err := s.Find(ctx)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Find() got = %v, want none", got)
+	}
+
+	food := mustCreateCategory(t, s, category.CreateRequest{Slug: "food", Name: "Food"})
+	fruits := mustCreateCategory(t, s, category.CreateRequest{Slug: "fruits", Name: "Fruits", Parent: &food.ID})
+	tools := mustCreateCategory(t, s, category.CreateRequest{Slug: "tools", Name: "Tools"})
+	want := []*category.Category{food, fruits, tools}
+
+	got, err = s.Find(ctx)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	// The order is not defined.
+	sort.Slice(got, func(i, j int) bool { return got[i].Slug < got[j].Slug })
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() got = %v, want %v", got, want)
+	}
+}
+
+func testCategoryUpdate(t *testing.T, s category.Storage) {
+	ctx := context.Background()
+
+	food := mustCreateCategory(t, s, category.CreateRequest{Slug: "food", Name: "Food"})
+	fruits := mustCreateCategory(t, s, category.CreateRequest{Slug: "fruits", Name: "Fruits"})
+
+	tests := []struct {
+		name string
+		r    category.UpdateRequest
+		want *category.Category
+	}{
+		{
+			name: "Should update slug and name",
+			r:    category.UpdateRequest{ID: fruits.ID, Slug: ptrString("fruit"), Name: ptrString("Fruit")},
+			want: &category.Category{ID: fruits.ID, Slug: "fruit", Name: "Fruit"},
+		},
+		{
+			name: "Should move under the parent",
+			r:    category.UpdateRequest{ID: fruits.ID, Parent: &food.ID},
+			want: &category.Category{ID: fruits.ID, Slug: "fruit", Name: "Fruit", Parent: ptrString(food.ID)},
+		},
+		{
+			name: "Should keep fields not provided",
+			r:    category.UpdateRequest{ID: fruits.ID},
+			want: &category.Category{ID: fruits.ID, Slug: "fruit", Name: "Fruit", Parent: ptrString(food.ID)},
+		},
+		{
+			name: "Should move to the root",
+			r:    category.UpdateRequest{ID: fruits.ID, Parent: ptrString("")},
+			want: &category.Category{ID: fruits.ID, Slug: "fruit", Name: "Fruit"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Update(ctx, tt.r)
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Update() got = %v, want %v", got, tt.want)
+			}
+
+			got, err = s.FindOne(ctx, tt.r.ID)
+			if err != nil {
+				t.Fatalf("FindOne() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindOne() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testCategoryUpdateNotFound(t *testing.T, s category.Storage) {
+	for _, id := range notFoundIDs {
+		_, err := s.Update(context.Background(), category.UpdateRequest{ID: id, Name: ptrString("Food")})
+		if !errors.Is(err, category.ErrNotFound) {
+			t.Errorf("Update(%q) error = %v, want %v", id, err, category.ErrNotFound)
+		}
+	}
+}
+
+func testCategoryDelete(t *testing.T, s category.Storage) {
+	ctx := context.Background()
+
+	c := mustCreateCategory(t, s, category.CreateRequest{Slug: "food", Name: "Food"})
+
+	got, err := s.Delete(ctx, c.ID)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Errorf("Delete() got = %v, want %v", got, c)
+	}
+
+	_, err = s.FindOne(ctx, c.ID)
+	if !errors.Is(err, category.ErrNotFound) {
+		t.Errorf("FindOne() error = %v, want %v", err, category.ErrNotFound)
+	}
+}
+
+func testCategoryDeleteNotFound(t *testing.T, s category.Storage) {
+	for _, id := range notFoundIDs {
+		_, err := s.Delete(context.Background(), id)
+		if !errors.Is(err, category.ErrNotFound) {
+			t.Errorf("Delete(%q) error = %v, want %v", id, err, category.ErrNotFound)
+		}
+	}
+}
+
+func mustCreateCategory(t *testing.T, s category.Storage, r category.CreateRequest) *category.Category {
+	t.Helper()
+
+	c, err := s.Create(context.Background(), r)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return c
+}
@@ -0,0 +1,319 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"github.com/ortymid/market/market/order"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// NewOrderStorageFunc returns an empty storage. It is called for every test of
+// the suite, so the tests do not affect each other.
+type NewOrderStorageFunc func(t *testing.T) order.Storage
+
+// TestOrderStorage runs the conformance suite against the storages returned by
+// newStorage.
+func TestOrderStorage(t *testing.T, newStorage NewOrderStorageFunc) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s order.Storage)
+	}{
+		{name: "Create", test: testOrderCreate},
+		{name: "CreateVariant", test: testOrderCreateVariant},
+		{name: "CreateDuplicate", test: testOrderCreateDuplicate},
+		{name: "CreateConcurrent", test: testOrderCreateConcurrent},
+		{name: "FindOneNotFound", test: testOrderFindOneNotFound},
+		{name: "FindByKeyNotFound", test: testOrderFindByKeyNotFound},
+		{name: "Find", test: testOrderFind},
+		{name: "UpdateStatus", test: testOrderUpdateStatus},
+		{name: "UpdateStatusNotFound", test: testOrderUpdateStatusNotFound},
+		{name: "Delete", test: testOrderDelete},
+		{name: "DeleteNotFound", test: testOrderDeleteNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+// testOrder returns a pending order of the buyer with the key.
+func testOrder(buyer string, key string) order.Order {
+	return order.Order{
+		Product:        "1",
+		Buyer:          buyer,
+		Seller:         "seller",
+		Quantity:       2,
+		Price:          150,
+		Total:          300,
+		Status:         order.StatusPending,
+		IdempotencyKey: key,
+		CreatedAt:      time.Date(2020, 10, 1, 12, 30, 0, 0, time.UTC),
+	}
+}
+
+func testOrderCreate(t *testing.T, s order.Storage) {
+	ctx := context.Background()
+
+	o, err := s.Create(ctx, testOrder("buyer", "key"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if len(o.ID) == 0 {
+		t.Errorf("Create() got empty id")
+	}
+	want := testOrder("buyer", "key")
+	want.ID = o.ID
+	checkOrder(t, "Create()", o, &want)
+
+	got, err := s.FindOne(ctx, o.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	checkOrder(t, "FindOne()", got, &want)
+
+	got, err = s.FindByKey(ctx, "buyer", "key")
+	if err != nil {
+		t.Fatalf("FindByKey() error = %v", err)
+	}
+	checkOrder(t, "FindByKey()", got, &want)
+}
+
+func testOrderCreateVariant(t *testing.T, s order.Storage) {
+	ctx := context.Background()
+
+	want := testOrder("buyer", "key")
+	want.Variant = "SKU-1"
+	o := mustCreateOrder(t, s, want)
+	want.ID = o.ID
+
+	got, err := s.FindOne(ctx, o.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	checkOrder(t, "FindOne()", got, &want)
+}
+
+func testOrderCreateDuplicate(t *testing.T, s order.Storage) {
+	ctx := context.Background()
+
+	mustCreateOrder(t, s, testOrder("buyer", "key"))
+
+	_, err := s.Create(ctx, testOrder("buyer", "key"))
+	if !errors.Is(err, order.ErrDuplicate) {
+		t.Errorf("Create() error = %v, want %v", err, order.ErrDuplicate)
+	}
+
+	// Keys are unique per buyer.
+	if _, err := s.Create(ctx, testOrder("other", "key")); err != nil {
+		t.Errorf("Create() of another buyer error = %v", err)
+	}
+}
+
+func testOrderCreateConcurrent(t *testing.T, s order.Storage) {
+	const n = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Create(context.Background(), testOrder("buyer", "key"))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, order.ErrDuplicate):
+			t.Errorf("Create() error = %v", err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("Create() created %d orders with the same key, want 1", created)
+	}
+}
+
+func testOrderFindOneNotFound(t *testing.T, s order.Storage) {
+	for _, id := range notFoundIDs {
+		_, err := s.FindOne(context.Background(), id)
+		if !errors.Is(err, order.ErrNotFound) {
+			t.Errorf("FindOne(%q) error = %v, want %v", id, err, order.ErrNotFound)
+		}
+	}
+}
+
+func testOrderFindByKeyNotFound(t *testing.T, s order.Storage) {
+	ctx := context.Background()
+
+	mustCreateOrder(t, s, testOrder("buyer", "key"))
+
+	_, err := s.FindByKey(ctx, "other", "key")
+	if !errors.Is(err, order.ErrNotFound) {
+		t.Errorf("FindByKey() error = %v, want %v", err, order.ErrNotFound)
+	}
+	_, err = s.FindByKey(ctx, "buyer", "other")
+	if !errors.Is(err, order.ErrNotFound) {
+		t.Errorf("FindByKey() error = %v, want %v", err, order.ErrNotFound)
+	}
+}
+
+func testOrderFind(t *testing.T, s order.Storage) {
+	ctx := context.Background()
+
+	first := mustCreateOrder(t, s, testOrder("1", "a"))
+	second := mustCreateOrder(t, s, testOrder("2", "b"))
+	third := mustCreateOrder(t, s, testOrder("1", "c"))
+
+	sold := testOrder("2", "d")
+	sold.Seller = "1"
+	fourth := mustCreateOrder(t, s, sold)
+
+	tests := []struct {
+		name string
+		r    order.FindRequest
+		want []string
+	}{
+		{
+			name: "Should find all orders in order of creation",
+			r:    order.FindRequest{Limit: 10},
+			want: []string{first.ID, second.ID, third.ID, fourth.ID},
+		},
+		{
+			name: "Should find orders of buyer",
+			r:    order.FindRequest{Limit: 10, Buyer: ptrString("1")},
+			want: []string{first.ID, third.ID},
+		},
+		{
+			name: "Should find orders of seller",
+			r:    order.FindRequest{Limit: 10, Seller: ptrString("1")},
+			want: []string{fourth.ID},
+		},
+		{
+			name: "Should find page of orders",
+			r:    order.FindRequest{Offset: 1, Limit: 2},
+			want: []string{second.ID, third.ID},
+		},
+		{
+			name: "Should find nothing past the end",
+			r:    order.FindRequest{Offset: 4, Limit: 2},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Find(ctx, tt.r)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+
+			gotIDs := make([]string, 0, len(got))
+			for _, o := range got {
+				gotIDs = append(gotIDs, o.ID)
+			}
+			if !reflect.DeepEqual(gotIDs, tt.want) {
+				t.Errorf("Find() got = %v, want %v", gotIDs, tt.want)
+			}
+		})
+	}
+}
+
+func testOrderUpdateStatus(t *testing.T, s order.Storage) {
+	ctx := context.Background()
+
+	o := mustCreateOrder(t, s, testOrder("buyer", "key"))
+
+	got, err := s.UpdateStatus(ctx, o.ID, order.StatusCompleted)
+	if err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	want := *o
+	want.Status = order.StatusCompleted
+	checkOrder(t, "UpdateStatus()", got, &want)
+
+	got, err = s.FindOne(ctx, o.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	checkOrder(t, "FindOne()", got, &want)
+}
+
+func testOrderUpdateStatusNotFound(t *testing.T, s order.Storage) {
+	for _, id := range notFoundIDs {
+		_, err := s.UpdateStatus(context.Background(), id, order.StatusCompleted)
+		if !errors.Is(err, order.ErrNotFound) {
+			t.Errorf("UpdateStatus(%q) error = %v, want %v", id, err, order.ErrNotFound)
+		}
+	}
+}
+
+func testOrderDelete(t *testing.T, s order.Storage) {
+	ctx := context.Background()
+
+	o := mustCreateOrder(t, s, testOrder("buyer", "key"))
+
+	got, err := s.Delete(ctx, o.ID)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	checkOrder(t, "Delete()", got, o)
+
+	if _, err := s.FindOne(ctx, o.ID); !errors.Is(err, order.ErrNotFound) {
+		t.Errorf("FindOne() after Delete() error = %v, want %v", err, order.ErrNotFound)
+	}
+
+	// The key is released, so a failed purchase can be retried.
+	if _, err := s.FindByKey(ctx, "buyer", "key"); !errors.Is(err, order.ErrNotFound) {
+		t.Errorf("FindByKey() after Delete() error = %v, want %v", err, order.ErrNotFound)
+	}
+	if _, err := s.Create(ctx, testOrder("buyer", "key")); err != nil {
+		t.Errorf("Create() after Delete() error = %v", err)
+	}
+}
+
+func testOrderDeleteNotFound(t *testing.T, s order.Storage) {
+	for _, id := range notFoundIDs {
+		_, err := s.Delete(context.Background(), id)
+		if !errors.Is(err, order.ErrNotFound) {
+			t.Errorf("Delete(%q) error = %v, want %v", id, err, order.ErrNotFound)
+		}
+	}
+}
+
+// checkOrder compares the orders, times are compared with Equal as storages
+// may return them in another location.
+func checkOrder(t *testing.T, call string, got *order.Order, want *order.Order) {
+	t.Helper()
+
+	if got == nil {
+		t.Fatalf("%s got nil order", call)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Errorf("%s created at = %v, want %v", call, got.CreatedAt, want.CreatedAt)
+	}
+
+	g, w := *got, *want
+	g.CreatedAt, w.CreatedAt = time.Time{}, time.Time{}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("%s got = %+v, want %+v", call, g, w)
+	}
+}
+
+func mustCreateOrder(t *testing.T, s order.Storage, o order.Order) *order.Order {
+	t.Helper()
+
+	created, err := s.Create(context.Background(), o)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return created
+}
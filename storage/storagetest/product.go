@@ -0,0 +1,2314 @@
+// Package storagetest provides conformance test suites for product.Storage
+// and category.Storage implementations. Every storage is expected to pass them
+// to be interchangeable with the others.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ortymid/market/market/media"
+	"github.com/ortymid/market/market/money"
+	"github.com/ortymid/market/market/product"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// NewProductStorageFunc returns an empty storage. It is called for every test
+// of the suite, so the tests do not affect each other.
+type NewProductStorageFunc func(t *testing.T) product.Storage
+
+// TestProductStorage runs the conformance suite against the storages returned
+// by newStorage. Tests named in skip are skipped, it is meant for features not
+// yet supported by the storage.
+func TestProductStorage(t *testing.T, newStorage NewProductStorageFunc, skip ...string) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s product.Storage)
+	}{
+		{name: "Create", test: testCreate},
+		{name: "CreateWithCategories", test: testCreateWithCategories},
+		{name: "FindOneNotFound", test: testFindOneNotFound},
+		{name: "Update", test: testUpdate},
+		{name: "UpdateNotFound", test: testUpdateNotFound},
+		{name: "UpdateCategories", test: testUpdateCategories},
+		{name: "UpdateMedia", test: testUpdateMedia},
+		{name: "UpdateExpectedVersion", test: testUpdateExpectedVersion},
+		{name: "UpdateConcurrent", test: testUpdateConcurrent},
+		{name: "Delete", test: testDelete},
+		{name: "DeleteNotFound", test: testDeleteNotFound},
+		{name: "DeletedNotChanged", test: testDeletedNotChanged},
+		{name: "FindIncludeDeleted", test: testFindIncludeDeleted},
+		{name: "Restore", test: testRestore},
+		{name: "RestoreNotFound", test: testRestoreNotFound},
+		{name: "Purge", test: testPurge},
+		{name: "CreateDraft", test: testCreateDraft},
+		{name: "FindStatus", test: testFindStatus},
+		{name: "FindVisible", test: testFindVisible},
+		{name: "UpdateStatus", test: testUpdateStatus},
+		{name: "PublishDue", test: testPublishDue},
+		{name: "FindPagination", test: testFindPagination},
+		{name: "FindFilters", test: testFindFilters},
+		{name: "FindPriceCurrency", test: testFindPriceCurrency},
+		{name: "FindCategories", test: testFindCategories},
+		{name: "FindSort", test: testFindSort},
+		{name: "FindCursor", test: testFindCursor},
+		{name: "FindCursorChanges", test: testFindCursorChanges},
+		{name: "FindCursorInvalid", test: testFindCursorInvalid},
+		{name: "ConcurrentWrites", test: testConcurrentWrites},
+		{name: "CreateWithStock", test: testCreateWithStock},
+		{name: "UpdateLowStockThreshold", test: testUpdateLowStockThreshold},
+		{name: "UpdateStock", test: testUpdateStock},
+		{name: "UpdateStockNotFound", test: testUpdateStockNotFound},
+		{name: "UpdateStockConcurrent", test: testUpdateStockConcurrent},
+		{name: "FindInStock", test: testFindInStock},
+		{name: "CreateWithVariants", test: testCreateWithVariants},
+		{name: "UpdateVariants", test: testUpdateVariants},
+		{name: "UpdateVariantStock", test: testUpdateVariantStock},
+		{name: "FindInStockVariants", test: testFindInStockVariants},
+		{name: "FindAttributes", test: testFindAttributes},
+		{name: "UpdateRating", test: testUpdateRating},
+		{name: "UpdateRatingNotFound", test: testUpdateRatingNotFound},
+		{name: "FindRating", test: testFindRating},
+		{name: "SummarizeSellers", test: testSummarizeSellers},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range skip {
+				if name == tt.name {
+					t.Skip("not supported by the storage")
+				}
+			}
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+// notFoundIDs are the ids which must not be found in any storage. They
+// include ids in the formats of the other storages.
+var notFoundIDs = []string{"0", "999999", "unknown", "5f8b4e2d9d1e4b3a2c1d0e9f"}
+
+func testCreate(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	p, err := s.Create(ctx, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if len(p.ID) == 0 {
+		t.Errorf("Create() got empty id")
+	}
+	want := &product.Product{ID: p.ID, Name: "Banana", Price: usd(1500), Seller: "1", Status: product.StatusPublished, Version: 1}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Create() got = %v, want %v", p, want)
+	}
+
+	got, err := s.FindOne(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOne() got = %v, want %v", got, want)
+	}
+}
+
+func testCreateWithCategories(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	p, err := s.Create(ctx, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1", Categories: []string{"1", "2"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	want := &product.Product{ID: p.ID, Name: "Banana", Price: usd(1500), Seller: "1", Categories: []string{"1", "2"}, Status: product.StatusPublished, Version: 1}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Create() got = %v, want %v", p, want)
+	}
+
+	got, err := s.FindOne(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOne() got = %v, want %v", got, want)
+	}
+}
+
+func testFindOneNotFound(t *testing.T, s product.Storage) {
+	mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1"})
+
+	for _, id := range notFoundIDs {
+		_, err := s.FindOne(context.Background(), id)
+		if !errors.Is(err, product.ErrNotFound) {
+			t.Errorf("FindOne(%q) error = %v, want %v", id, err, product.ErrNotFound)
+		}
+	}
+}
+
+func testUpdate(t *testing.T, s product.Storage) {
+	tests := []struct {
+		name string
+		r    product.UpdateRequest
+		want product.Product
+	}{
+		{
+			name: "Should update name",
+			r:    product.UpdateRequest{Name: ptrString("Green banana")},
+			want: product.Product{Name: "Green banana", Price: usd(1500), Seller: "1", Status: product.StatusPublished, Version: 2},
+		},
+		{
+			name: "Should update price",
+			r:    product.UpdateRequest{Price: ptrMoney(usd(1000))},
+			want: product.Product{Name: "Banana", Price: usd(1000), Seller: "1", Status: product.StatusPublished, Version: 2},
+		},
+		{
+			name: "Should update all fields",
+			r:    product.UpdateRequest{Name: ptrString("Green banana"), Price: ptrMoney(usd(1000))},
+			want: product.Product{Name: "Green banana", Price: usd(1000), Seller: "1", Status: product.StatusPublished, Version: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			p := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1"})
+			tt.r.ID = p.ID
+			tt.want.ID = p.ID
+
+			got, err := s.Update(ctx, tt.r)
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Update() got = %v, want %v", *got, tt.want)
+			}
+
+			got, err = s.FindOne(ctx, p.ID)
+			if err != nil {
+				t.Fatalf("FindOne() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("FindOne() got = %v, want %v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func testUpdateNotFound(t *testing.T, s product.Storage) {
+	mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1"})
+
+	for _, id := range notFoundIDs {
+		_, err := s.Update(context.Background(), product.UpdateRequest{ID: id, Name: ptrString("Carrot")})
+		if !errors.Is(err, product.ErrNotFound) {
+			t.Errorf("Update(%q) error = %v, want %v", id, err, product.ErrNotFound)
+		}
+	}
+}
+
+func testUpdateCategories(t *testing.T, s product.Storage) {
+	tests := []struct {
+		name       string
+		categories []string
+		want       []string
+	}{
+		{
+			name:       "Should replace categories",
+			categories: []string{"2", "3"},
+			want:       []string{"2", "3"},
+		},
+		{
+			name:       "Should remove categories",
+			categories: []string{},
+			want:       nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			p := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1", Categories: []string{"1"}})
+			want := &product.Product{ID: p.ID, Name: "Banana", Price: usd(1500), Seller: "1", Categories: tt.want, Status: product.StatusPublished, Version: 2}
+
+			got, err := s.Update(ctx, product.UpdateRequest{ID: p.ID, Categories: &tt.categories})
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Update() got = %v, want %v", got, want)
+			}
+
+			got, err = s.FindOne(ctx, p.ID)
+			if err != nil {
+				t.Fatalf("FindOne() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("FindOne() got = %v, want %v", got, want)
+			}
+
+			// Updating other fields keeps the categories.
+			got, err = s.Update(ctx, product.UpdateRequest{ID: p.ID, Name: ptrString("Green banana")})
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			want.Name = "Green banana"
+			want.Version = 3
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Update() got = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func testUpdateMedia(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	p := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1"})
+
+	// The media are kept in the order they are given.
+	items := []media.Media{
+		{ID: "b2", ContentType: "image/png", Size: 2048, Width: 640, Height: 480},
+		{ID: "a1", ContentType: "image/jpeg", Size: 1024, Width: 320, Height: 240},
+	}
+	want := &product.Product{ID: p.ID, Name: "Banana", Price: usd(1500), Seller: "1", Media: items, Status: product.StatusPublished, Version: 2}
+	got, err := s.Update(ctx, product.UpdateRequest{ID: p.ID, Media: &items})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Update() got = %v, want %v", got, want)
+	}
+
+	// Updating other fields keeps the media.
+	got, err = s.Update(ctx, product.UpdateRequest{ID: p.ID, Name: ptrString("Green banana")})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	want.Name = "Green banana"
+	want.Version = 3
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Update() got = %v, want %v", got, want)
+	}
+
+	got, err = s.FindOne(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOne() got = %v, want %v", got, want)
+	}
+
+	got, err = s.Update(ctx, product.UpdateRequest{ID: p.ID, Media: &[]media.Media{}})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	want.Media = nil
+	want.Version = 4
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Update() got = %v, want %v", got, want)
+	}
+}
+
+func testUpdateExpectedVersion(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	p := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1"})
+
+	got, err := s.Update(ctx, product.UpdateRequest{ID: p.ID, Price: ptrMoney(usd(1000)), ExpectedVersion: ptrInt64(1)})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	want := &product.Product{ID: p.ID, Name: "Banana", Price: usd(1000), Seller: "1", Status: product.StatusPublished, Version: 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Update() got = %v, want %v", got, want)
+	}
+
+	// The product is left unchanged on a conflict.
+	_, err = s.Update(ctx, product.UpdateRequest{ID: p.ID, Price: ptrMoney(usd(500)), ExpectedVersion: ptrInt64(1)})
+	if !errors.Is(err, product.ErrConflict) {
+		t.Errorf("Update() with stale version error = %v, want %v", err, product.ErrConflict)
+	}
+	got, err = s.FindOne(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOne() after conflict got = %v, want %v", got, want)
+	}
+
+	_, err = s.Update(ctx, product.UpdateRequest{ID: notFoundIDs[0], Price: ptrMoney(usd(500)), ExpectedVersion: ptrInt64(1)})
+	if !errors.Is(err, product.ErrNotFound) {
+		t.Errorf("Update() of unknown product error = %v, want %v", err, product.ErrNotFound)
+	}
+}
+
+func testUpdateConcurrent(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	p := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1"})
+
+	// Only one of the updates expecting the same version succeeds.
+	const n = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.Update(ctx, product.UpdateRequest{ID: p.ID, Price: ptrMoney(usd(int64(i))), ExpectedVersion: ptrInt64(1)})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	updated := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			updated++
+		case !errors.Is(err, product.ErrConflict):
+			t.Errorf("Update() error = %v, want nil or %v", err, product.ErrConflict)
+		}
+	}
+	if updated != 1 {
+		t.Errorf("Update() succeeded %d times, want 1", updated)
+	}
+
+	// Unconditional updates are not lost.
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Update(ctx, product.UpdateRequest{ID: p.ID, Name: ptrString("Green banana")}); err != nil {
+				t.Errorf("Update() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := s.FindOne(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if got.Version != n+2 {
+		t.Errorf("FindOne() got version %d, want %d", got.Version, n+2)
+	}
+}
+
+func testDelete(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	p := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1"})
+	other := mustCreate(t, s, product.CreateRequest{Name: "Carrot", Price: usd(1400), Seller: "2"})
+
+	got, err := s.Delete(ctx, p.ID, now)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	want := *p
+	want.DeletedAt = &now
+	if !reflect.DeepEqual(got, &want) {
+		t.Errorf("Delete() got = %v, want %v", got, &want)
+	}
+
+	// Deleted products are kept until they are purged.
+	got, err = s.FindOne(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindOne() after Delete() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, &want) {
+		t.Errorf("FindOne() after Delete() got = %v, want %v", got, &want)
+	}
+	if _, err := s.Delete(ctx, p.ID, now); !errors.Is(err, product.ErrNotFound) {
+		t.Errorf("Delete() after Delete() error = %v, want %v", err, product.ErrNotFound)
+	}
+
+	res, err := s.Find(ctx, product.FindRequest{Offset: 0, Limit: 10})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	ps := res.Products
+	if !reflect.DeepEqual(ps, []*product.Product{other}) {
+		t.Errorf("Find() after Delete() got = %v, want %v", ps, []*product.Product{other})
+	}
+	checkTotal(t, res, 1)
+}
+
+func testDeleteNotFound(t *testing.T, s product.Storage) {
+	mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1"})
+
+	for _, id := range notFoundIDs {
+		_, err := s.Delete(context.Background(), id, time.Now())
+		if !errors.Is(err, product.ErrNotFound) {
+			t.Errorf("Delete(%q) error = %v, want %v", id, err, product.ErrNotFound)
+		}
+	}
+}
+
+func testDeletedNotChanged(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	p := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1", Stock: 5})
+	if _, err := s.Delete(ctx, p.ID, time.Now()); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	_, err := s.Update(ctx, product.UpdateRequest{ID: p.ID, Name: ptrString("Apple")})
+	if !errors.Is(err, product.ErrNotFound) {
+		t.Errorf("Update() of deleted error = %v, want %v", err, product.ErrNotFound)
+	}
+	_, err = s.UpdateStock(ctx, product.StockRequest{ID: p.ID, Stock: -1, Reserved: 1})
+	if !errors.Is(err, product.ErrNotFound) {
+		t.Errorf("UpdateStock() of deleted error = %v, want %v", err, product.ErrNotFound)
+	}
+
+	got, err := s.FindOne(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if got.Name != p.Name || got.Stock != p.Stock || got.Version != p.Version {
+		t.Errorf("FindOne() got changed deleted product %v", got)
+	}
+}
+
+func testFindIncludeDeleted(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	var created []string
+	for i := 0; i < 4; i++ {
+		p := mustCreate(t, s, product.CreateRequest{Name: fmt.Sprintf("p%d", i), Price: usd(int64(100 + i)), Seller: "1"})
+		created = append(created, p.ID)
+	}
+	for _, id := range []string{created[0], created[2]} {
+		if _, err := s.Delete(ctx, id, time.Now()); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		r    product.FindRequest
+		want []string
+	}{
+		{
+			name: "without deleted",
+			r:    product.FindRequest{Limit: 10},
+			want: []string{created[1], created[3]},
+		},
+		{
+			name: "with deleted",
+			r:    product.FindRequest{Limit: 10, IncludeDeleted: true},
+			want: created,
+		},
+		{
+			name: "with deleted of the seller",
+			r:    product.FindRequest{Limit: 10, Seller: ptrString("1"), IncludeDeleted: true},
+			want: created,
+		},
+		{
+			name: "with deleted and filters",
+			r: product.FindRequest{
+				Limit:          10,
+				PriceRange:     &product.PriceRange{To: ptrInt64(102), Currency: "USD"},
+				IncludeDeleted: true,
+			},
+			want: created[:3],
+		},
+		{
+			name: "without deleted by price",
+			r: product.FindRequest{
+				Limit: 10,
+				Sort:  []product.Sort{{Key: product.SortKeyPrice, Desc: true}},
+			},
+			want: []string{created[3], created[1]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.Find(ctx, tt.r)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			if got := ids(res.Products); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got ids %v, want %v", got, tt.want)
+			}
+			checkTotal(t, res, int64(len(tt.want)))
+		})
+	}
+}
+
+func testRestore(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	p := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1"})
+	if _, err := s.Delete(ctx, p.ID, time.Now()); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	got, err := s.Restore(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("Restore() got = %v, want %v", got, p)
+	}
+
+	got, err = s.FindOne(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindOne() after Restore() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("FindOne() after Restore() got = %v, want %v", got, p)
+	}
+
+	res, err := s.Find(ctx, product.FindRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if !reflect.DeepEqual(res.Products, []*product.Product{p}) {
+		t.Errorf("Find() after Restore() got = %v, want %v", res.Products, []*product.Product{p})
+	}
+
+	// Restored products are updated and deleted again as usual.
+	if _, err := s.Update(ctx, product.UpdateRequest{ID: p.ID, Name: ptrString("Apple")}); err != nil {
+		t.Errorf("Update() after Restore() error = %v", err)
+	}
+	if _, err := s.Delete(ctx, p.ID, time.Now()); err != nil {
+		t.Errorf("Delete() after Restore() error = %v", err)
+	}
+}
+
+func testRestoreNotFound(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	// Products which are not deleted are not found too.
+	p := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1"})
+
+	for _, id := range append([]string{p.ID}, notFoundIDs...) {
+		_, err := s.Restore(ctx, id)
+		if !errors.Is(err, product.ErrNotFound) {
+			t.Errorf("Restore(%q) error = %v, want %v", id, err, product.ErrNotFound)
+		}
+	}
+}
+
+func testPurge(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	old := mustCreate(t, s, product.CreateRequest{
+		Name: "Banana", Price: usd(1500), Seller: "1", Categories: []string{"1"},
+		Attributes: shirtAttributes, Variants: []product.Variant{shirtVariant("red", "42", 1500, 1)},
+	})
+	recent := mustCreate(t, s, product.CreateRequest{Name: "Carrot", Price: usd(1400), Seller: "1"})
+	kept := mustCreate(t, s, product.CreateRequest{Name: "Apple", Price: usd(1300), Seller: "2"})
+	if _, err := s.Delete(ctx, old.ID, now.Add(-2*time.Hour)); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Delete(ctx, recent.ID, now); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	n, err := s.Purge(ctx, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Purge() got %d, want %d", n, 1)
+	}
+
+	if _, err := s.FindOne(ctx, old.ID); !errors.Is(err, product.ErrNotFound) {
+		t.Errorf("FindOne() of purged error = %v, want %v", err, product.ErrNotFound)
+	}
+	if _, err := s.Restore(ctx, old.ID); !errors.Is(err, product.ErrNotFound) {
+		t.Errorf("Restore() of purged error = %v, want %v", err, product.ErrNotFound)
+	}
+
+	res, err := s.Find(ctx, product.FindRequest{Limit: 10, IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	want := []string{recent.ID, kept.ID}
+	if got := ids(res.Products); !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() after Purge() got ids %v, want %v", got, want)
+	}
+	checkTotal(t, res, 2)
+
+	res, err = s.Find(ctx, product.FindRequest{Limit: 10, Categories: []string{"1"}, IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(res.Products) != 0 {
+		t.Errorf("Find() of purged category got %v, want none", res.Products)
+	}
+
+	res, err = s.Find(ctx, product.FindRequest{Limit: 10, Attributes: map[string]string{"color": "red"}, IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(res.Products) != 0 {
+		t.Errorf("Find() of purged variants got %v, want none", res.Products)
+	}
+
+	// Nothing is left to purge.
+	n, err = s.Purge(ctx, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Purge() again got %d, want %d", n, 0)
+	}
+}
+
+func testCreateDraft(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+	publishAt := time.Now().UTC().Truncate(time.Millisecond).Add(time.Hour)
+
+	p, err := s.Create(ctx, product.CreateRequest{
+		Name: "Banana", Price: usd(1500), Seller: "1",
+		Status: product.StatusDraft, PublishAt: &publishAt,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	want := &product.Product{
+		ID: p.ID, Name: "Banana", Price: usd(1500), Seller: "1",
+		Status: product.StatusDraft, PublishAt: &publishAt, Version: 1,
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Create() got = %v, want %v", p, want)
+	}
+
+	got, err := s.FindOne(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOne() got = %v, want %v", got, want)
+	}
+}
+
+func testFindStatus(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	published := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1"})
+	draft := mustCreate(t, s, product.CreateRequest{Name: "Carrot", Price: usd(1400), Seller: "1", Status: product.StatusDraft})
+	archived := mustCreate(t, s, product.CreateRequest{Name: "Apple", Price: usd(1300), Seller: "2"})
+	if _, err := s.Update(ctx, product.UpdateRequest{ID: archived.ID, Status: ptrStatus(product.StatusArchived)}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		r    product.FindRequest
+		want []string
+	}{
+		{
+			name: "all",
+			r:    product.FindRequest{Limit: 10},
+			want: []string{published.ID, draft.ID, archived.ID},
+		},
+		{
+			name: "published",
+			r:    product.FindRequest{Limit: 10, Status: ptrStatus(product.StatusPublished)},
+			want: []string{published.ID},
+		},
+		{
+			name: "drafts",
+			r:    product.FindRequest{Limit: 10, Status: ptrStatus(product.StatusDraft)},
+			want: []string{draft.ID},
+		},
+		{
+			name: "archived of the seller",
+			r:    product.FindRequest{Limit: 10, Seller: ptrString("2"), Status: ptrStatus(product.StatusArchived)},
+			want: []string{archived.ID},
+		},
+		{
+			name: "published by price",
+			r: product.FindRequest{
+				Limit:  10,
+				Status: ptrStatus(product.StatusPublished),
+				Sort:   []product.Sort{{Key: product.SortKeyPrice}},
+			},
+			want: []string{published.ID},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.Find(ctx, tt.r)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			if got := ids(res.Products); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got ids %v, want %v", got, tt.want)
+			}
+			checkTotal(t, res, int64(len(tt.want)))
+		})
+	}
+}
+
+func testFindVisible(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	deleted := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1"})
+	draft := mustCreate(t, s, product.CreateRequest{Name: "Carrot", Price: usd(1400), Seller: "1", Status: product.StatusDraft})
+	archived := mustCreate(t, s, product.CreateRequest{Name: "Apple", Price: usd(1300), Seller: "2"})
+	restored := mustCreate(t, s, product.CreateRequest{Name: "Orange", Price: usd(1200), Seller: "1"})
+	purged := mustCreate(t, s, product.CreateRequest{Name: "Lemon", Price: usd(1100), Seller: "2"})
+
+	if _, err := s.Delete(ctx, deleted.ID, now); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Update(ctx, product.UpdateRequest{ID: draft.ID, Status: ptrStatus(product.StatusPublished)}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := s.Update(ctx, product.UpdateRequest{ID: archived.ID, Status: ptrStatus(product.StatusArchived)}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := s.Delete(ctx, restored.ID, now); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Restore(ctx, restored.ID); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if _, err := s.Delete(ctx, purged.ID, now.Add(-2*time.Hour)); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Purge(ctx, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+
+	published := ptrStatus(product.StatusPublished)
+	tests := []struct {
+		name      string
+		r         product.FindRequest
+		want      []string
+		wantTotal int64
+	}{
+		{
+			name:      "published",
+			r:         product.FindRequest{Limit: 10, Status: published},
+			want:      []string{draft.ID, restored.ID},
+			wantTotal: 2,
+		},
+		{
+			name:      "published newest first",
+			r:         product.FindRequest{Limit: 10, Status: published, Sort: []product.Sort{{Key: product.SortKeyCreated, Desc: true}}},
+			want:      []string{restored.ID, draft.ID},
+			wantTotal: 2,
+		},
+		{
+			name:      "published page",
+			r:         product.FindRequest{Offset: 1, Limit: 1, Status: published},
+			want:      []string{restored.ID},
+			wantTotal: 2,
+		},
+		{
+			name:      "published of the seller",
+			r:         product.FindRequest{Limit: 10, Seller: ptrString("1"), Status: published},
+			want:      []string{draft.ID, restored.ID},
+			wantTotal: 2,
+		},
+		{
+			name: "published of the seller without any",
+			r:    product.FindRequest{Limit: 10, Seller: ptrString("2"), Status: published},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.Find(ctx, tt.r)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			if got := ids(res.Products); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got ids %v, want %v", got, tt.want)
+			}
+			checkTotal(t, res, tt.wantTotal)
+		})
+	}
+}
+
+func testUpdateStatus(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+	publishAt := time.Now().UTC().Truncate(time.Millisecond).Add(time.Hour)
+
+	p := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1", Status: product.StatusDraft})
+
+	got, err := s.Update(ctx, product.UpdateRequest{ID: p.ID, PublishAt: &publishAt})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got.Status != product.StatusDraft || got.PublishAt == nil || !got.PublishAt.Equal(publishAt) || got.Version != 2 {
+		t.Errorf("Update() of publish time got = %v", got)
+	}
+
+	// The publish time is cleared when the draft is published.
+	got, err = s.Update(ctx, product.UpdateRequest{ID: p.ID, Status: ptrStatus(product.StatusPublished)})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	want := &product.Product{ID: p.ID, Name: "Banana", Price: usd(1500), Seller: "1", Status: product.StatusPublished, Version: 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Update() of status got = %v, want %v", got, want)
+	}
+
+	got, err = s.FindOne(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOne() got = %v, want %v", got, want)
+	}
+
+	res, err := s.Find(ctx, product.FindRequest{Limit: 10, Status: ptrStatus(product.StatusPublished)})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if got := ids(res.Products); !reflect.DeepEqual(got, []string{p.ID}) {
+		t.Errorf("Find() of published got ids %v, want %v", got, []string{p.ID})
+	}
+}
+
+func testPublishDue(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	due := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1", Status: product.StatusDraft, PublishAt: &past})
+	onTime := mustCreate(t, s, product.CreateRequest{Name: "Carrot", Price: usd(1400), Seller: "1", Status: product.StatusDraft, PublishAt: &now})
+	notDue := mustCreate(t, s, product.CreateRequest{Name: "Apple", Price: usd(1300), Seller: "1", Status: product.StatusDraft, PublishAt: &future})
+	unscheduled := mustCreate(t, s, product.CreateRequest{Name: "Lemon", Price: usd(1200), Seller: "1", Status: product.StatusDraft})
+	deleted := mustCreate(t, s, product.CreateRequest{Name: "Melon", Price: usd(1100), Seller: "1", Status: product.StatusDraft, PublishAt: &past})
+	if _, err := s.Delete(ctx, deleted.ID, now); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	ps, err := s.PublishDue(ctx, now)
+	if err != nil {
+		t.Fatalf("PublishDue() error = %v", err)
+	}
+	got := ids(ps)
+	sort.Strings(got)
+	want := []string{due.ID, onTime.ID}
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PublishDue() got ids %v, want %v", got, want)
+	}
+	for _, p := range ps {
+		if p.Status != product.StatusPublished || p.PublishAt != nil || p.Version != 2 {
+			t.Errorf("PublishDue() got = %v, want published with version 2", p)
+		}
+	}
+
+	res, err := s.Find(ctx, product.FindRequest{Limit: 10, Status: ptrStatus(product.StatusPublished)})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if got := ids(res.Products); !reflect.DeepEqual(got, []string{due.ID, onTime.ID}) {
+		t.Errorf("Find() of published got ids %v, want %v", got, []string{due.ID, onTime.ID})
+	}
+
+	res, err = s.Find(ctx, product.FindRequest{Limit: 10, Status: ptrStatus(product.StatusDraft)})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if got := ids(res.Products); !reflect.DeepEqual(got, []string{notDue.ID, unscheduled.ID}) {
+		t.Errorf("Find() of drafts got ids %v, want %v", got, []string{notDue.ID, unscheduled.ID})
+	}
+
+	// Published drafts are not published again.
+	ps, err = s.PublishDue(ctx, now)
+	if err != nil {
+		t.Fatalf("PublishDue() error = %v", err)
+	}
+	if len(ps) != 0 {
+		t.Errorf("PublishDue() again got %v, want none", ps)
+	}
+}
+
+func testFindPagination(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	const total = 5
+	var want []string
+	for i := 0; i < total; i++ {
+		p := mustCreate(t, s, product.CreateRequest{Name: fmt.Sprintf("p%d", i), Price: usd(100), Seller: "1"})
+		want = append(want, p.ID)
+	}
+
+	tests := []struct {
+		offset, limit int64
+		wantLen       int
+		wantNext      bool
+	}{
+		{offset: 0, limit: 0, wantLen: 0},
+		{offset: 0, limit: 2, wantLen: 2, wantNext: true},
+		{offset: 2, limit: 2, wantLen: 2, wantNext: true},
+		{offset: 3, limit: 2, wantLen: 2},
+		{offset: 4, limit: 2, wantLen: 1},
+		{offset: 5, limit: 2, wantLen: 0},
+		{offset: 0, limit: 10, wantLen: total},
+	}
+	for _, tt := range tests {
+		res, err := s.Find(ctx, product.FindRequest{Offset: tt.offset, Limit: tt.limit})
+		if err != nil {
+			t.Fatalf("Find() error = %v", err)
+		}
+		ps := res.Products
+		if len(ps) != tt.wantLen {
+			t.Errorf("Find(offset=%d, limit=%d) got %d products, want %d", tt.offset, tt.limit, len(ps), tt.wantLen)
+		}
+		if (res.NextCursor != "") != tt.wantNext || res.HasMore != tt.wantNext {
+			t.Errorf("Find(offset=%d, limit=%d) got next cursor %q, has more %v, want more %v",
+				tt.offset, tt.limit, res.NextCursor, res.HasMore, tt.wantNext)
+		}
+		checkTotal(t, res, total)
+	}
+
+	// Pages must not overlap and must cover all the products.
+	var got []string
+	for offset := int64(0); offset < total; offset += 2 {
+		res, err := s.Find(ctx, product.FindRequest{Offset: offset, Limit: 2})
+		if err != nil {
+			t.Fatalf("Find() error = %v", err)
+		}
+		ps := res.Products
+		got = append(got, ids(ps)...)
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() pages got ids %v, want %v", got, want)
+	}
+}
+
+func testFindFilters(t *testing.T, s product.Storage) {
+	for _, r := range []product.CreateRequest{
+		{Name: "Banana", Price: usd(1500), Seller: "1"},
+		{Name: "Carrot", Price: usd(1400), Seller: "bunny"},
+		{Name: "Green banana", Price: usd(1000), Seller: "1"},
+		{Name: "Apple", Price: usd(2000), Seller: "2"},
+	} {
+		mustCreate(t, s, r)
+	}
+
+	tests := []struct {
+		name string
+		r    product.FindRequest
+		want []string // names
+	}{
+		{
+			name: "Should find all products without filters",
+			r:    product.FindRequest{},
+			want: []string{"Apple", "Banana", "Carrot", "Green banana"},
+		},
+		{
+			name: "Should find products by name",
+			r:    product.FindRequest{Name: ptrString("banana")},
+			want: []string{"Banana", "Green banana"},
+		},
+		{
+			name: "Should find products by price range",
+			r: product.FindRequest{PriceRange: &product.PriceRange{
+				From:     ptrInt64(1400),
+				To:       ptrInt64(1500),
+				Currency: "USD",
+			}},
+			want: []string{"Banana", "Carrot"},
+		},
+		{
+			name: "Should find products by price lower limit",
+			r:    product.FindRequest{PriceRange: &product.PriceRange{From: ptrInt64(1500), Currency: "USD"}},
+			want: []string{"Apple", "Banana"},
+		},
+		{
+			name: "Should find products by price upper limit",
+			r:    product.FindRequest{PriceRange: &product.PriceRange{To: ptrInt64(1400), Currency: "USD"}},
+			want: []string{"Carrot", "Green banana"},
+		},
+		{
+			name: "Should find products by seller",
+			r:    product.FindRequest{Seller: ptrString("1")},
+			want: []string{"Banana", "Green banana"},
+		},
+		{
+			name: "Should find products by all filters",
+			r: product.FindRequest{
+				Name:       ptrString("banana"),
+				PriceRange: &product.PriceRange{To: ptrInt64(1200), Currency: "USD"},
+				Seller:     ptrString("1"),
+			},
+			want: []string{"Green banana"},
+		},
+		{
+			name: "Should find nothing",
+			r:    product.FindRequest{Seller: ptrString("unknown")},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.r.Limit = 10
+
+			res, err := s.Find(context.Background(), tt.r)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			ps := res.Products
+			checkTotal(t, res, int64(len(tt.want)))
+
+			got := make([]string, 0, len(ps))
+			for _, p := range ps {
+				got = append(got, p.Name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testFindPriceCurrency(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1"})
+	carrot := mustCreate(t, s, product.CreateRequest{Name: "Carrot", Price: money.New(1400, "EUR"), Seller: "1"})
+	mustCreate(t, s, product.CreateRequest{Name: "Apple", Price: money.New(1500, "EUR"), Seller: "2"})
+	mustCreate(t, s, product.CreateRequest{Name: "Lemon", Price: money.New(150000, "JPY"), Seller: "2"})
+
+	got, err := s.FindOne(ctx, carrot.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if want := money.New(1400, "EUR"); got.Price != want {
+		t.Errorf("FindOne() got price %v, want %v", got.Price, want)
+	}
+
+	tests := []struct {
+		name string
+		r    product.FindRequest
+		want []string // names
+	}{
+		{
+			name: "Should find products priced in the currency",
+			r:    product.FindRequest{PriceRange: &product.PriceRange{Currency: "EUR"}},
+			want: []string{"Apple", "Carrot"},
+		},
+		{
+			name: "Should find products in the range of the currency",
+			r:    product.FindRequest{PriceRange: &product.PriceRange{From: ptrInt64(1450), Currency: "EUR"}},
+			want: []string{"Apple"},
+		},
+		{
+			name: "Should not find products priced in other currencies",
+			r:    product.FindRequest{PriceRange: &product.PriceRange{To: ptrInt64(1500), Currency: "USD"}},
+			want: []string{"Banana"},
+		},
+		{
+			name: "Should find products in any of the ranges",
+			r: product.FindRequest{
+				PriceRange:  &product.PriceRange{To: ptrInt64(1450), Currency: "EUR"},
+				PriceRanges: []product.PriceRange{{From: ptrInt64(1500), Currency: "USD"}, {To: ptrInt64(100000), Currency: "JPY"}},
+			},
+			want: []string{"Banana", "Carrot"},
+		},
+		{
+			name: "Should find products in any of the ranges with other filters",
+			r: product.FindRequest{
+				Seller:      ptrString("2"),
+				PriceRanges: []product.PriceRange{{Currency: "USD"}, {From: ptrInt64(100000), Currency: "JPY"}},
+			},
+			want: []string{"Lemon"},
+		},
+		{
+			name: "Should find nothing in a currency without products",
+			r:    product.FindRequest{PriceRange: &product.PriceRange{Currency: "GBP"}},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.r.Limit = 10
+
+			res, err := s.Find(ctx, tt.r)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			checkTotal(t, res, int64(len(tt.want)))
+
+			got := make([]string, 0, len(res.Products))
+			for _, p := range res.Products {
+				got = append(got, p.Name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Changing the currency moves the product to the other price range.
+	_, err = s.Update(ctx, product.UpdateRequest{ID: carrot.ID, Price: ptrMoney(money.New(1300, "GBP"))})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	res, err := s.Find(ctx, product.FindRequest{Limit: 10, PriceRange: &product.PriceRange{Currency: "GBP"}})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if got := ids(res.Products); !reflect.DeepEqual(got, []string{carrot.ID}) {
+		t.Errorf("Find() after Update() got ids %v, want %v", got, []string{carrot.ID})
+	}
+}
+
+func testFindCategories(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	for _, r := range []product.CreateRequest{
+		{Name: "Banana", Price: usd(1500), Seller: "1", Categories: []string{"fruits", "yellow"}},
+		{Name: "Carrot", Price: usd(1400), Seller: "bunny", Categories: []string{"vegetables"}},
+		{Name: "Apple", Price: usd(2000), Seller: "2", Categories: []string{"fruits"}},
+		{Name: "Stone", Price: usd(100), Seller: "2"},
+	} {
+		mustCreate(t, s, r)
+	}
+
+	tests := []struct {
+		name string
+		r    product.FindRequest
+		want []string // names
+	}{
+		{
+			name: "Should find products of category",
+			r:    product.FindRequest{Categories: []string{"fruits"}},
+			want: []string{"Apple", "Banana"},
+		},
+		{
+			name: "Should find products of any of categories",
+			r:    product.FindRequest{Categories: []string{"yellow", "vegetables"}},
+			want: []string{"Banana", "Carrot"},
+		},
+		{
+			name: "Should find products of categories with other filters",
+			r:    product.FindRequest{Categories: []string{"fruits"}, Seller: ptrString("2")},
+			want: []string{"Apple"},
+		},
+		{
+			name: "Should find nothing for unknown category",
+			r:    product.FindRequest{Categories: []string{"unknown"}},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.r.Limit = 10
+
+			res, err := s.Find(ctx, tt.r)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			checkTotal(t, res, int64(len(tt.want)))
+
+			got := make([]string, 0, len(res.Products))
+			for _, p := range res.Products {
+				got = append(got, p.Name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testFindSort(t *testing.T, s product.Storage) {
+	for _, r := range []product.CreateRequest{
+		{Name: "Banana", Price: usd(1500), Seller: "1"},
+		{Name: "Carrot", Price: usd(1400), Seller: "bunny"},
+		{Name: "Apple", Price: usd(1500), Seller: "2"},
+		{Name: "Date", Price: usd(1000), Seller: "1"},
+	} {
+		mustCreate(t, s, r)
+	}
+
+	tests := []struct {
+		name string
+		r    product.FindRequest
+		want []string // names
+	}{
+		{
+			name: "Should sort by creation by default",
+			r:    product.FindRequest{Limit: 10},
+			want: []string{"Banana", "Carrot", "Apple", "Date"},
+		},
+		{
+			name: "Should sort by price breaking ties by creation",
+			r:    product.FindRequest{Limit: 10, Sort: []product.Sort{{Key: product.SortKeyPrice}}},
+			want: []string{"Date", "Carrot", "Banana", "Apple"},
+		},
+		{
+			name: "Should sort by price descending",
+			r:    product.FindRequest{Limit: 10, Sort: []product.Sort{{Key: product.SortKeyPrice, Desc: true}}},
+			want: []string{"Banana", "Apple", "Carrot", "Date"},
+		},
+		{
+			name: "Should sort by multiple keys",
+			r: product.FindRequest{Limit: 10, Sort: []product.Sort{
+				{Key: product.SortKeyPrice, Desc: true},
+				{Key: product.SortKeyName},
+			}},
+			want: []string{"Apple", "Banana", "Carrot", "Date"},
+		},
+		{
+			name: "Should sort by name",
+			r:    product.FindRequest{Limit: 10, Sort: []product.Sort{{Key: product.SortKeyName}}},
+			want: []string{"Apple", "Banana", "Carrot", "Date"},
+		},
+		{
+			name: "Should sort by creation descending",
+			r:    product.FindRequest{Limit: 10, Sort: []product.Sort{{Key: product.SortKeyCreated, Desc: true}}},
+			want: []string{"Date", "Apple", "Carrot", "Banana"},
+		},
+		{
+			name: "Should sort filtered products",
+			r: product.FindRequest{Limit: 10, Seller: ptrString("1"), Sort: []product.Sort{
+				{Key: product.SortKeyPrice},
+			}},
+			want: []string{"Date", "Banana"},
+		},
+		{
+			name: "Should paginate sorted products",
+			r:    product.FindRequest{Offset: 1, Limit: 2, Sort: []product.Sort{{Key: product.SortKeyPrice}}},
+			want: []string{"Carrot", "Banana"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.Find(context.Background(), tt.r)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			ps := res.Products
+
+			got := make([]string, 0, len(ps))
+			for _, p := range ps {
+				got = append(got, p.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testFindCursor(t *testing.T, s product.Storage) {
+	for _, r := range []product.CreateRequest{
+		{Name: "Banana", Price: usd(1500), Seller: "1"},
+		{Name: "Carrot", Price: usd(1400), Seller: "bunny"},
+		{Name: "Apple", Price: usd(1500), Seller: "2"},
+		{Name: "Date", Price: usd(1000), Seller: "1"},
+		{Name: "Eggplant", Price: usd(1500), Seller: "2"},
+	} {
+		mustCreate(t, s, r)
+	}
+
+	tests := []struct {
+		name string
+		r    product.FindRequest
+	}{
+		{
+			name: "Should page by creation",
+			r:    product.FindRequest{},
+		},
+		{
+			name: "Should page by creation descending",
+			r:    product.FindRequest{Sort: []product.Sort{{Key: product.SortKeyCreated, Desc: true}}},
+		},
+		{
+			name: "Should page by price with ties",
+			r:    product.FindRequest{Sort: []product.Sort{{Key: product.SortKeyPrice}}},
+		},
+		{
+			name: "Should page by multiple keys",
+			r: product.FindRequest{Sort: []product.Sort{
+				{Key: product.SortKeyPrice, Desc: true},
+				{Key: product.SortKeyName},
+			}},
+		},
+		{
+			name: "Should page filtered products",
+			r:    product.FindRequest{Seller: ptrString("2")},
+		},
+		{
+			name: "Should page filtered and sorted products",
+			r: product.FindRequest{
+				PriceRange: &product.PriceRange{From: ptrInt64(1400), Currency: "USD"},
+				Sort:       []product.Sort{{Key: product.SortKeyName, Desc: true}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			all := tt.r
+			all.Limit = 10
+			res, err := s.Find(ctx, all)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			want := ids(res.Products)
+
+			// Pages must follow each other in the order of the whole listing.
+			got := []string{}
+			r := tt.r
+			r.Limit = 2
+			for i := 0; ; i++ {
+				if i > len(want) {
+					t.Fatalf("Find() with cursor did not stop after %d pages", i)
+				}
+
+				res, err := s.Find(ctx, r)
+				if err != nil {
+					t.Fatalf("Find() error = %v", err)
+				}
+				got = append(got, ids(res.Products)...)
+				// The total counts all the pages.
+				checkTotal(t, res, int64(len(want)))
+
+				if res.NextCursor == "" {
+					break
+				}
+				r.Cursor = res.NextCursor
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Find() with cursor got ids %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func testFindCursorChanges(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	var created []string
+	for i := 0; i < 4; i++ {
+		p := mustCreate(t, s, product.CreateRequest{Name: fmt.Sprintf("p%d", i), Price: usd(100), Seller: "1"})
+		created = append(created, p.ID)
+	}
+
+	res, err := s.Find(ctx, product.FindRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if got := ids(res.Products); !reflect.DeepEqual(got, created[:2]) {
+		t.Fatalf("Find() got ids %v, want %v", got, created[:2])
+	}
+
+	// The listing must continue after the last product of the page even if
+	// the product is deleted and new products are added.
+	if _, err := s.Delete(ctx, created[1], time.Now()); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	p := mustCreate(t, s, product.CreateRequest{Name: "p4", Price: usd(100), Seller: "1"})
+
+	res, err = s.Find(ctx, product.FindRequest{Limit: 10, Cursor: res.NextCursor})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	want := []string{created[2], created[3], p.ID}
+	if got := ids(res.Products); !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() after changes got ids %v, want %v", got, want)
+	}
+}
+
+func testFindCursorInvalid(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		mustCreate(t, s, product.CreateRequest{Name: fmt.Sprintf("p%d", i), Price: usd(100), Seller: "1"})
+	}
+
+	res, err := s.Find(ctx, product.FindRequest{Limit: 1})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	// Cursors are opaque to clients, but they may be forged with values of
+	// the wrong number or types.
+	var forged []string
+	for _, values := range []interface{}{
+		[]interface{}{"1"},
+		map[string]interface{}{"id": 1},
+	} {
+		c, err := product.EncodeCursor(nil, values)
+		if err != nil {
+			t.Fatalf("EncodeCursor() error = %v", err)
+		}
+		forged = append(forged, c)
+	}
+
+	for _, r := range []product.FindRequest{
+		{Limit: 1, Cursor: "malformed"},
+		{Limit: 1, Cursor: res.NextCursor, Sort: []product.Sort{{Key: product.SortKeyPrice}}},
+		{Limit: 1, Cursor: forged[0]},
+		{Limit: 1, Cursor: forged[1]},
+	} {
+		_, err := s.Find(ctx, r)
+		if !errors.As(err, &product.ErrValidation{}) {
+			t.Errorf("Find(cursor=%q) error = %v, want product.ErrValidation", r.Cursor, err)
+		}
+	}
+}
+
+func testConcurrentWrites(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	const n = 20
+	var wg sync.WaitGroup
+	created := make(chan *product.Product, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p, err := s.Create(ctx, product.CreateRequest{Name: fmt.Sprintf("p%d", i), Price: usd(int64(i)), Seller: "1"})
+			if err != nil {
+				t.Errorf("Create() error = %v", err)
+				return
+			}
+			created <- p
+		}(i)
+	}
+	wg.Wait()
+	close(created)
+
+	// Concurrent updates and deletes of different products.
+	var want []string
+	for p := range created {
+		p := p
+		wg.Add(1)
+		if p.Price.Amount%2 == 0 {
+			go func() {
+				defer wg.Done()
+				if _, err := s.Delete(ctx, p.ID, time.Now()); err != nil {
+					t.Errorf("Delete() error = %v", err)
+				}
+			}()
+			continue
+		}
+		want = append(want, p.ID)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Update(ctx, product.UpdateRequest{ID: p.ID, Price: ptrMoney(usd(p.Price.Amount + 1))}); err != nil {
+				t.Errorf("Update() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	res, err := s.Find(ctx, product.FindRequest{Limit: 2 * n})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	ps := res.Products
+	got := ids(ps)
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() got ids %v, want %v", got, want)
+	}
+	for _, p := range ps {
+		if p.Price.Amount%2 != 0 {
+			t.Errorf("Find() got not updated product %v", p)
+		}
+	}
+}
+
+// checkTotal checks the total of the result. Estimated totals are only
+// checked not to be negative, as storages may count them lazily.
+func testCreateWithStock(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	p, err := s.Create(ctx, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1", Stock: 10, LowStockThreshold: 3})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	want := &product.Product{ID: p.ID, Name: "Banana", Price: usd(1500), Seller: "1", Stock: 10, LowStockThreshold: 3, Status: product.StatusPublished, Version: 1}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Create() got = %v, want %v", p, want)
+	}
+
+	got, err := s.FindOne(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOne() got = %v, want %v", got, want)
+	}
+}
+
+func testUpdateLowStockThreshold(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	p := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1", Stock: 10, LowStockThreshold: 3})
+
+	got, err := s.Update(ctx, product.UpdateRequest{ID: p.ID, LowStockThreshold: ptrInt64(5)})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	want := &product.Product{ID: p.ID, Name: "Banana", Price: usd(1500), Seller: "1", Stock: 10, LowStockThreshold: 5, Status: product.StatusPublished, Version: 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Update() got = %v, want %v", got, want)
+	}
+
+	// Updating other fields keeps the stock.
+	got, err = s.Update(ctx, product.UpdateRequest{ID: p.ID, Name: ptrString("Green banana")})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	want.Name = "Green banana"
+	want.Version = 3
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Update() got = %v, want %v", got, want)
+	}
+}
+
+func testUpdateStock(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	p := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1", Stock: 5})
+
+	// The steps are applied in order to the same product.
+	steps := []struct {
+		name         string
+		r            product.StockRequest
+		wantStock    int64
+		wantReserved int64
+		wantErr      error
+	}{
+		{
+			name:         "Should reserve units",
+			r:            product.StockRequest{Stock: -2, Reserved: 2},
+			wantStock:    3,
+			wantReserved: 2,
+		},
+		{
+			name:         "Should not reserve more units than available",
+			r:            product.StockRequest{Stock: -4, Reserved: 4},
+			wantStock:    3,
+			wantReserved: 2,
+			wantErr:      product.ErrInsufficientStock,
+		},
+		{
+			name:         "Should release reserved units",
+			r:            product.StockRequest{Stock: 1, Reserved: -1},
+			wantStock:    4,
+			wantReserved: 1,
+		},
+		{
+			name:         "Should remove sold units",
+			r:            product.StockRequest{Reserved: -1},
+			wantStock:    4,
+			wantReserved: 0,
+		},
+		{
+			name:         "Should not remove more units than reserved",
+			r:            product.StockRequest{Reserved: -1},
+			wantStock:    4,
+			wantReserved: 0,
+			wantErr:      product.ErrInsufficientStock,
+		},
+		{
+			name:         "Should add units",
+			r:            product.StockRequest{Stock: 3},
+			wantStock:    7,
+			wantReserved: 0,
+		},
+		{
+			name:         "Should remove all units",
+			r:            product.StockRequest{Stock: -7},
+			wantStock:    0,
+			wantReserved: 0,
+		},
+	}
+	for _, step := range steps {
+		step.r.ID = p.ID
+
+		got, err := s.UpdateStock(ctx, step.r)
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: UpdateStock() error = %v, want %v", step.name, err, step.wantErr)
+		}
+		if err == nil && (got.Stock != step.wantStock || got.Reserved != step.wantReserved || got.Name != p.Name) {
+			t.Errorf("%s: UpdateStock() got = %v, want stock %d and reserved %d",
+				step.name, got, step.wantStock, step.wantReserved)
+		}
+
+		got, err = s.FindOne(ctx, p.ID)
+		if err != nil {
+			t.Fatalf("%s: FindOne() error = %v", step.name, err)
+		}
+		if got.Stock != step.wantStock || got.Reserved != step.wantReserved {
+			t.Errorf("%s: FindOne() got = %v, want stock %d and reserved %d",
+				step.name, got, step.wantStock, step.wantReserved)
+		}
+	}
+}
+
+func testUpdateStockNotFound(t *testing.T, s product.Storage) {
+	for _, id := range notFoundIDs {
+		_, err := s.UpdateStock(context.Background(), product.StockRequest{ID: id, Stock: 1})
+		if !errors.Is(err, product.ErrNotFound) {
+			t.Errorf("UpdateStock(%q) error = %v, want %v", id, err, product.ErrNotFound)
+		}
+	}
+}
+
+// testUpdateStockConcurrent checks that concurrent buyers cannot reserve more
+// units than available.
+func testUpdateStockConcurrent(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	const stock, buyers = 10, 25
+	p := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1", Stock: stock})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reserved := 0
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.UpdateStock(ctx, product.StockRequest{ID: p.ID, Stock: -1, Reserved: 1})
+			if errors.Is(err, product.ErrInsufficientStock) {
+				return
+			}
+			if err != nil {
+				t.Errorf("UpdateStock() error = %v", err)
+				return
+			}
+			mu.Lock()
+			reserved++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if reserved != stock {
+		t.Errorf("got %d successful reservations, want %d", reserved, stock)
+	}
+
+	got, err := s.FindOne(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if got.Stock != 0 || got.Reserved != stock {
+		t.Errorf("FindOne() got = %v, want stock 0 and reserved %d", got, stock)
+	}
+}
+
+func testFindInStock(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	for _, r := range []product.CreateRequest{
+		{Name: "Banana", Price: usd(1500), Seller: "1", Stock: 3},
+		{Name: "Carrot", Price: usd(1400), Seller: "bunny"},
+		{Name: "Apple", Price: usd(2000), Seller: "2", Stock: 1},
+	} {
+		mustCreate(t, s, r)
+	}
+
+	tests := []struct {
+		name string
+		r    product.FindRequest
+		want []string // names
+	}{
+		{
+			name: "Should find products in stock",
+			r:    product.FindRequest{InStock: ptrBool(true)},
+			want: []string{"Apple", "Banana"},
+		},
+		{
+			name: "Should find sold out products",
+			r:    product.FindRequest{InStock: ptrBool(false)},
+			want: []string{"Carrot"},
+		},
+		{
+			name: "Should find products in stock with other filters",
+			r:    product.FindRequest{InStock: ptrBool(true), Seller: ptrString("2")},
+			want: []string{"Apple"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.r.Limit = 10
+
+			res, err := s.Find(ctx, tt.r)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			checkTotal(t, res, int64(len(tt.want)))
+
+			got := make([]string, 0, len(res.Products))
+			for _, p := range res.Products {
+				got = append(got, p.Name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// shirtAttributes are the attributes of the shirt variants.
+var shirtAttributes = []product.Attribute{
+	{Name: "color", Type: product.AttributeString},
+	{Name: "size", Type: product.AttributeNumber},
+}
+
+// shirtVariant returns a variant of the shirt of the color and size.
+func shirtVariant(color, size string, amount, stock int64) product.Variant {
+	return product.Variant{
+		SKU:        "shirt-" + color + "-" + size,
+		Attributes: map[string]string{"color": color, "size": size},
+		Price:      usd(amount),
+		Stock:      stock,
+	}
+}
+
+func testCreateWithVariants(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	// The variants are kept in the order they are given.
+	variants := []product.Variant{
+		shirtVariant("red", "44", 1200, 0),
+		shirtVariant("red", "42", 1000, 3),
+	}
+	p, err := s.Create(ctx, product.CreateRequest{Name: "Shirt", Price: usd(1000), Seller: "1", Attributes: shirtAttributes, Variants: variants})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	want := &product.Product{
+		ID: p.ID, Name: "Shirt", Price: usd(1000), Seller: "1",
+		Attributes: shirtAttributes, Variants: variants,
+		Status: product.StatusPublished, Version: 1,
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Create() got = %v, want %v", p, want)
+	}
+
+	got, err := s.FindOne(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOne() got = %v, want %v", got, want)
+	}
+}
+
+func testUpdateVariants(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	p := mustCreate(t, s, product.CreateRequest{
+		Name: "Shirt", Price: usd(1000), Seller: "1", Attributes: shirtAttributes,
+		Variants: []product.Variant{shirtVariant("red", "42", 1000, 3), shirtVariant("red", "44", 1200, 0)},
+	})
+
+	// Fewer variants replace the old ones.
+	attrs := []product.Attribute{{Name: "color", Type: product.AttributeString}}
+	variants := []product.Variant{{SKU: "shirt-blue", Attributes: map[string]string{"color": "blue"}, Price: usd(900), Stock: 5}}
+	want := &product.Product{
+		ID: p.ID, Name: "Shirt", Price: usd(1000), Seller: "1",
+		Attributes: attrs, Variants: variants,
+		Status: product.StatusPublished, Version: 2,
+	}
+	got, err := s.Update(ctx, product.UpdateRequest{ID: p.ID, Attributes: &attrs, Variants: &variants})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Update() got = %v, want %v", got, want)
+	}
+
+	// Updating other fields keeps the variants.
+	got, err = s.Update(ctx, product.UpdateRequest{ID: p.ID, Name: ptrString("Blue shirt")})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	want.Name = "Blue shirt"
+	want.Version = 3
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Update() got = %v, want %v", got, want)
+	}
+
+	got, err = s.FindOne(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOne() got = %v, want %v", got, want)
+	}
+
+	got, err = s.Update(ctx, product.UpdateRequest{ID: p.ID, Attributes: &[]product.Attribute{}, Variants: &[]product.Variant{}})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	want.Attributes, want.Variants = nil, nil
+	want.Version = 4
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Update() got = %v, want %v", got, want)
+	}
+
+	// The removed variants are not found.
+	res, err := s.Find(ctx, product.FindRequest{Limit: 10, Attributes: map[string]string{"color": "red"}})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(res.Products) != 0 {
+		t.Errorf("Find() of removed variants got %v, want none", res.Products)
+	}
+}
+
+func testFindInStockVariants(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	for _, r := range []product.CreateRequest{
+		{Name: "Shirt", Price: usd(1000), Seller: "1", Attributes: shirtAttributes, Variants: []product.Variant{
+			shirtVariant("red", "42", 1000, 0),
+			shirtVariant("red", "44", 1000, 2),
+		}},
+		{Name: "Sweater", Price: usd(3000), Seller: "1", Attributes: shirtAttributes, Variants: []product.Variant{
+			shirtVariant("red", "42", 3000, 0),
+		}},
+	} {
+		mustCreate(t, s, r)
+	}
+
+	for _, tt := range []struct {
+		inStock bool
+		want    string
+	}{
+		{inStock: true, want: "Shirt"},
+		{inStock: false, want: "Sweater"},
+	} {
+		res, err := s.Find(ctx, product.FindRequest{Limit: 10, InStock: ptrBool(tt.inStock)})
+		if err != nil {
+			t.Fatalf("Find() error = %v", err)
+		}
+		if len(res.Products) != 1 || res.Products[0].Name != tt.want {
+			t.Errorf("Find(in stock = %t) got = %v, want %s", tt.inStock, res.Products, tt.want)
+		}
+	}
+}
+
+func testUpdateVariantStock(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	variants := []product.Variant{
+		shirtVariant("red", "42", 1000, 3),
+		shirtVariant("red", "44", 1200, 1),
+	}
+	p := mustCreate(t, s, product.CreateRequest{
+		Name: "Shirt", Price: usd(1000), Seller: "1", Stock: 5, Attributes: shirtAttributes, Variants: variants,
+	})
+	sku := variants[0].SKU
+
+	tests := []struct {
+		name         string
+		r            product.StockRequest
+		wantErr      error
+		wantVariants []int64
+		wantReserved int64
+	}{
+		{
+			name:         "reserve variant",
+			r:            product.StockRequest{Variant: sku, Stock: -2, Reserved: 2},
+			wantVariants: []int64{1, 1},
+			wantReserved: 2,
+		},
+		{
+			name:         "reserve more than the variant has",
+			r:            product.StockRequest{Variant: sku, Stock: -2, Reserved: 2},
+			wantErr:      product.ErrInsufficientStock,
+			wantVariants: []int64{1, 1},
+			wantReserved: 2,
+		},
+		{
+			name:         "release to variant",
+			r:            product.StockRequest{Variant: sku, Stock: 1, Reserved: -1},
+			wantVariants: []int64{2, 1},
+			wantReserved: 1,
+		},
+		{
+			name:         "commit more than reserved",
+			r:            product.StockRequest{Variant: sku, Reserved: -2},
+			wantErr:      product.ErrInsufficientStock,
+			wantVariants: []int64{2, 1},
+			wantReserved: 1,
+		},
+		{
+			name:         "unknown variant",
+			r:            product.StockRequest{Variant: "unknown", Stock: 1},
+			wantErr:      product.ErrVariantNotFound,
+			wantVariants: []int64{2, 1},
+			wantReserved: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.r.ID = p.ID
+			got, err := s.UpdateStock(ctx, tt.r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateStock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				checkVariantStock(t, got, 5, tt.wantReserved, tt.wantVariants)
+			}
+
+			stored, err := s.FindOne(ctx, p.ID)
+			if err != nil {
+				t.Fatalf("FindOne() error = %v", err)
+			}
+			checkVariantStock(t, stored, 5, tt.wantReserved, tt.wantVariants)
+		})
+	}
+}
+
+// checkVariantStock checks the stock counters of the product and of its
+// variants.
+func checkVariantStock(t *testing.T, p *product.Product, stock, reserved int64, variants []int64) {
+	t.Helper()
+
+	if p.Stock != stock || p.Reserved != reserved {
+		t.Errorf("got stock = %d, reserved = %d, want %d, %d", p.Stock, p.Reserved, stock, reserved)
+	}
+	got := make([]int64, len(p.Variants))
+	for i, v := range p.Variants {
+		got[i] = v.Stock
+	}
+	if !reflect.DeepEqual(got, variants) {
+		t.Errorf("got variant stock %v, want %v", got, variants)
+	}
+}
+
+func testFindAttributes(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	for _, r := range []product.CreateRequest{
+		{Name: "Shirt", Price: usd(1000), Seller: "1", Attributes: shirtAttributes, Variants: []product.Variant{
+			shirtVariant("red", "42", 1000, 3),
+			shirtVariant("blue", "44", 1000, 1),
+		}},
+		{Name: "Polo", Price: usd(1500), Seller: "2", Attributes: shirtAttributes, Variants: []product.Variant{
+			shirtVariant("red", "44", 1500, 2),
+		}},
+		{Name: "Banana", Price: usd(1500), Seller: "1"},
+	} {
+		mustCreate(t, s, r)
+	}
+
+	tests := []struct {
+		name string
+		r    product.FindRequest
+		want []string // names
+	}{
+		{
+			name: "Should find products by attribute",
+			r:    product.FindRequest{Attributes: map[string]string{"color": "red"}},
+			want: []string{"Polo", "Shirt"},
+		},
+		{
+			name: "Should find products with the values in one variant",
+			r:    product.FindRequest{Attributes: map[string]string{"color": "red", "size": "44"}},
+			want: []string{"Polo"},
+		},
+		{
+			name: "Should find products by attribute with other filters",
+			r:    product.FindRequest{Attributes: map[string]string{"size": "44"}, Seller: ptrString("1")},
+			want: []string{"Shirt"},
+		},
+		{
+			name: "Should find nothing for unknown value",
+			r:    product.FindRequest{Attributes: map[string]string{"color": "green"}},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.r.Limit = 10
+
+			res, err := s.Find(ctx, tt.r)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			checkTotal(t, res, int64(len(tt.want)))
+
+			got := make([]string, 0, len(res.Products))
+			for _, p := range res.Products {
+				got = append(got, p.Name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testUpdateRating(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	p := mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1500), Seller: "1"})
+	if p.Rating != (product.Rating{}) {
+		t.Errorf("Create() got rating %v, want zero", p.Rating)
+	}
+
+	rating := product.Rating{Average: 4.5, Count: 2}
+	got, err := s.UpdateRating(ctx, p.ID, rating)
+	if err != nil {
+		t.Fatalf("UpdateRating() error = %v", err)
+	}
+	// The rating is not product data, the version is kept.
+	want := *p
+	want.Rating = rating
+	if !reflect.DeepEqual(got, &want) {
+		t.Errorf("UpdateRating() got = %v, want %v", got, &want)
+	}
+
+	got, err = s.FindOne(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, &want) {
+		t.Errorf("FindOne() got = %v, want %v", got, &want)
+	}
+
+	// Updates keep the rating.
+	updated, err := s.Update(ctx, product.UpdateRequest{ID: p.ID, Name: ptrString("Banana-nana")})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.Rating != rating {
+		t.Errorf("Update() got rating %v, want %v", updated.Rating, rating)
+	}
+
+	// Deleted products are rated too.
+	if _, err := s.Delete(ctx, p.ID, time.Now()); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	rating = product.Rating{Average: 3, Count: 3}
+	if _, err := s.UpdateRating(ctx, p.ID, rating); err != nil {
+		t.Fatalf("UpdateRating() of deleted product error = %v", err)
+	}
+	restored, err := s.Restore(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if restored.Rating != rating {
+		t.Errorf("Restore() got rating %v, want %v", restored.Rating, rating)
+	}
+}
+
+func testUpdateRatingNotFound(t *testing.T, s product.Storage) {
+	for _, id := range notFoundIDs {
+		_, err := s.UpdateRating(context.Background(), id, product.Rating{Average: 5, Count: 1})
+		if !errors.Is(err, product.ErrNotFound) {
+			t.Errorf("UpdateRating(%q) error = %v, want %v", id, err, product.ErrNotFound)
+		}
+	}
+}
+
+func testFindRating(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	ratings := map[string]product.Rating{
+		"Banana":   {Average: 4.5, Count: 2},
+		"Carrot":   {Average: 3, Count: 1},
+		"Apple":    {},
+		"Date":     {Average: 4.5, Count: 4},
+		"Eggplant": {Average: 1.25, Count: 4},
+	}
+	for _, name := range []string{"Banana", "Carrot", "Apple", "Date", "Eggplant"} {
+		p := mustCreate(t, s, product.CreateRequest{Name: name, Price: usd(1000), Seller: "1"})
+		if _, err := s.UpdateRating(ctx, p.ID, ratings[name]); err != nil {
+			t.Fatalf("UpdateRating() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		r    product.FindRequest
+		want []string // names
+	}{
+		{
+			name: "Should find products rated at least the minimum",
+			r:    product.FindRequest{MinRating: ptrFloat64(3)},
+			want: []string{"Banana", "Carrot", "Date"},
+		},
+		{
+			name: "Should not find products without reviews",
+			r:    product.FindRequest{MinRating: ptrFloat64(0.5)},
+			want: []string{"Banana", "Carrot", "Date", "Eggplant"},
+		},
+		{
+			name: "Should sort by rating breaking ties by creation",
+			r:    product.FindRequest{Sort: []product.Sort{{Key: product.SortKeyRating, Desc: true}}},
+			want: []string{"Banana", "Date", "Carrot", "Eggplant", "Apple"},
+		},
+		{
+			name: "Should sort products rated at least the minimum",
+			r: product.FindRequest{MinRating: ptrFloat64(4), Sort: []product.Sort{
+				{Key: product.SortKeyRating},
+				{Key: product.SortKeyName, Desc: true},
+			}},
+			want: []string{"Date", "Banana"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			all := tt.r
+			all.Limit = 10
+			res, err := s.Find(ctx, all)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			checkTotal(t, res, int64(len(tt.want)))
+
+			got := make([]string, 0, len(res.Products))
+			for _, p := range res.Products {
+				got = append(got, p.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got = %v, want %v", got, tt.want)
+			}
+
+			// Pages must follow each other in the same order.
+			var paged []*product.Product
+			r := tt.r
+			r.Limit = 2
+			for i := 0; ; i++ {
+				if i > len(tt.want) {
+					t.Fatalf("Find() with cursor did not stop after %d pages", i)
+				}
+
+				res, err := s.Find(ctx, r)
+				if err != nil {
+					t.Fatalf("Find() error = %v", err)
+				}
+				paged = append(paged, res.Products...)
+
+				if res.NextCursor == "" {
+					break
+				}
+				r.Cursor = res.NextCursor
+			}
+			if !reflect.DeepEqual(ids(paged), ids(res.Products)) {
+				t.Errorf("Find() with cursor got ids %v, want %v", ids(paged), ids(res.Products))
+			}
+		})
+	}
+}
+
+func checkTotal(t *testing.T, res *product.FindResult, want int64) {
+	t.Helper()
+
+	if res.TotalEstimated {
+		if res.Total < 0 {
+			t.Errorf("Find() got estimated total %d, want not negative", res.Total)
+		}
+		return
+	}
+	if res.Total != want {
+		t.Errorf("Find() got total %d, want %d", res.Total, want)
+	}
+}
+
+func mustCreate(t *testing.T, s product.Storage, r product.CreateRequest) *product.Product {
+	t.Helper()
+
+	p, err := s.Create(context.Background(), r)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return p
+}
+
+func ids(ps []*product.Product) []string {
+	ids := make([]string, len(ps))
+	for i, p := range ps {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+func ptrString(v string) *string {
+	return &v
+}
+
+func ptrInt64(v int64) *int64 {
+	return &v
+}
+
+func ptrMoney(v money.Money) *money.Money {
+	return &v
+}
+
+// usd returns the amount in the default currency.
+func usd(amount int64) money.Money {
+	return money.New(amount, money.DefaultCurrency)
+}
+
+func ptrStatus(v product.Status) *product.Status {
+	return &v
+}
+
+func ptrBool(v bool) *bool {
+	return &v
+}
+
+func ptrFloat64(v float64) *float64 {
+	return &v
+}
+
+func testSummarizeSellers(t *testing.T, s product.Storage) {
+	ctx := context.Background()
+
+	rate := func(p *product.Product, rating product.Rating) {
+		t.Helper()
+		if _, err := s.UpdateRating(ctx, p.ID, rating); err != nil {
+			t.Fatalf("UpdateRating() error = %v", err)
+		}
+	}
+
+	rate(mustCreate(t, s, product.CreateRequest{Name: "Banana", Price: usd(1000), Seller: "1"}), product.Rating{Average: 4.5, Count: 2})
+	rate(mustCreate(t, s, product.CreateRequest{Name: "Carrot", Price: usd(1000), Seller: "1"}), product.Rating{Average: 3, Count: 1})
+	mustCreate(t, s, product.CreateRequest{Name: "Apple", Price: usd(1000), Seller: "1"})
+	mustCreate(t, s, product.CreateRequest{Name: "Date", Price: usd(1000), Seller: "2"})
+
+	// Drafts and deleted products are skipped.
+	draft := mustCreate(t, s, product.CreateRequest{Name: "Draft", Price: usd(1000), Seller: "1", Status: product.StatusDraft})
+	rate(draft, product.Rating{Average: 1, Count: 5})
+	deleted := mustCreate(t, s, product.CreateRequest{Name: "Deleted", Price: usd(1000), Seller: "1"})
+	rate(deleted, product.Rating{Average: 1, Count: 5})
+	if _, err := s.Delete(ctx, deleted.ID, time.Now()); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	got, err := s.SummarizeSellers(ctx, []string{"1", "2", "3"})
+	if err != nil {
+		t.Fatalf("SummarizeSellers() error = %v", err)
+	}
+	want := map[string]product.SellerSummary{
+		"1": {Products: 3, Rating: product.Rating{Average: 4, Count: 3}},
+		"2": {Products: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SummarizeSellers() got = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,325 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"github.com/ortymid/market/market/review"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// NewReviewStorageFunc returns an empty storage. It is called for every test
+// of the suite, so the tests do not affect each other.
+type NewReviewStorageFunc func(t *testing.T) review.Storage
+
+// TestReviewStorage runs the conformance suite against the storages returned
+// by newStorage.
+func TestReviewStorage(t *testing.T, newStorage NewReviewStorageFunc) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s review.Storage)
+	}{
+		{name: "Create", test: testReviewCreate},
+		{name: "CreateDuplicate", test: testReviewCreateDuplicate},
+		{name: "CreateConcurrent", test: testReviewCreateConcurrent},
+		{name: "FindOneNotFound", test: testReviewFindOneNotFound},
+		{name: "Find", test: testReviewFind},
+		{name: "Update", test: testReviewUpdate},
+		{name: "UpdateNotFound", test: testReviewUpdateNotFound},
+		{name: "Delete", test: testReviewDelete},
+		{name: "DeleteNotFound", test: testReviewDeleteNotFound},
+		{name: "Summarize", test: testReviewSummarize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+// testReview returns a review of the product by the author.
+func testReview(product string, author string) review.Review {
+	at := time.Date(2020, 10, 1, 12, 30, 0, 0, time.UTC)
+	return review.Review{
+		Product:   product,
+		Author:    author,
+		Rating:    4,
+		Text:      "Tasty",
+		CreatedAt: at,
+		UpdatedAt: at,
+	}
+}
+
+func testReviewCreate(t *testing.T, s review.Storage) {
+	ctx := context.Background()
+
+	rv, err := s.Create(ctx, testReview("1", "author"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if len(rv.ID) == 0 {
+		t.Errorf("Create() got empty id")
+	}
+	want := testReview("1", "author")
+	want.ID = rv.ID
+	checkReview(t, "Create()", rv, &want)
+
+	got, err := s.FindOne(ctx, rv.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	checkReview(t, "FindOne()", got, &want)
+}
+
+func testReviewCreateDuplicate(t *testing.T, s review.Storage) {
+	ctx := context.Background()
+
+	mustCreateReview(t, s, testReview("1", "author"))
+
+	_, err := s.Create(ctx, testReview("1", "author"))
+	if !errors.Is(err, review.ErrDuplicate) {
+		t.Errorf("Create() error = %v, want %v", err, review.ErrDuplicate)
+	}
+
+	// Reviews are unique per product and author.
+	if _, err := s.Create(ctx, testReview("1", "other")); err != nil {
+		t.Errorf("Create() of another author error = %v", err)
+	}
+	if _, err := s.Create(ctx, testReview("2", "author")); err != nil {
+		t.Errorf("Create() of another product error = %v", err)
+	}
+}
+
+func testReviewCreateConcurrent(t *testing.T, s review.Storage) {
+	const n = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Create(context.Background(), testReview("1", "author"))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, review.ErrDuplicate):
+			t.Errorf("Create() error = %v", err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("Create() created %d reviews of the same product and author, want 1", created)
+	}
+}
+
+func testReviewFindOneNotFound(t *testing.T, s review.Storage) {
+	for _, id := range notFoundIDs {
+		_, err := s.FindOne(context.Background(), id)
+		if !errors.Is(err, review.ErrNotFound) {
+			t.Errorf("FindOne(%q) error = %v, want %v", id, err, review.ErrNotFound)
+		}
+	}
+}
+
+func testReviewFind(t *testing.T, s review.Storage) {
+	ctx := context.Background()
+
+	first := mustCreateReview(t, s, testReview("1", "a"))
+	second := mustCreateReview(t, s, testReview("2", "a"))
+	third := mustCreateReview(t, s, testReview("1", "b"))
+	fourth := mustCreateReview(t, s, testReview("2", "b"))
+
+	tests := []struct {
+		name string
+		r    review.FindRequest
+		want []string
+	}{
+		{
+			name: "Should find all reviews in order of creation",
+			r:    review.FindRequest{Limit: 10},
+			want: []string{first.ID, second.ID, third.ID, fourth.ID},
+		},
+		{
+			name: "Should find reviews of product",
+			r:    review.FindRequest{Limit: 10, Product: ptrString("1")},
+			want: []string{first.ID, third.ID},
+		},
+		{
+			name: "Should find reviews of author",
+			r:    review.FindRequest{Limit: 10, Author: ptrString("b")},
+			want: []string{third.ID, fourth.ID},
+		},
+		{
+			name: "Should find review of product and author",
+			r:    review.FindRequest{Limit: 10, Product: ptrString("2"), Author: ptrString("a")},
+			want: []string{second.ID},
+		},
+		{
+			name: "Should find page of reviews",
+			r:    review.FindRequest{Offset: 1, Limit: 2},
+			want: []string{second.ID, third.ID},
+		},
+		{
+			name: "Should find nothing past the end",
+			r:    review.FindRequest{Offset: 4, Limit: 2},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Find(ctx, tt.r)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+
+			gotIDs := make([]string, 0, len(got))
+			for _, rv := range got {
+				gotIDs = append(gotIDs, rv.ID)
+			}
+			if !reflect.DeepEqual(gotIDs, tt.want) {
+				t.Errorf("Find() got = %v, want %v", gotIDs, tt.want)
+			}
+		})
+	}
+}
+
+func testReviewUpdate(t *testing.T, s review.Storage) {
+	ctx := context.Background()
+
+	rv := mustCreateReview(t, s, testReview("1", "author"))
+	at := rv.UpdatedAt.Add(time.Hour)
+
+	rating := int64(2)
+	got, err := s.Update(ctx, review.UpdateRequest{ID: rv.ID, Rating: &rating}, at)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	want := *rv
+	want.Rating = 2
+	want.UpdatedAt = at
+	checkReview(t, "Update()", got, &want)
+
+	text := "Not so tasty"
+	got, err = s.Update(ctx, review.UpdateRequest{ID: rv.ID, Text: &text}, at)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	want.Text = text
+	checkReview(t, "Update()", got, &want)
+
+	got, err = s.FindOne(ctx, rv.ID)
+	if err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	checkReview(t, "FindOne()", got, &want)
+}
+
+func testReviewUpdateNotFound(t *testing.T, s review.Storage) {
+	rating := int64(2)
+	for _, id := range notFoundIDs {
+		_, err := s.Update(context.Background(), review.UpdateRequest{ID: id, Rating: &rating}, time.Now())
+		if !errors.Is(err, review.ErrNotFound) {
+			t.Errorf("Update(%q) error = %v, want %v", id, err, review.ErrNotFound)
+		}
+	}
+}
+
+func testReviewDelete(t *testing.T, s review.Storage) {
+	ctx := context.Background()
+
+	rv := mustCreateReview(t, s, testReview("1", "author"))
+
+	got, err := s.Delete(ctx, rv.ID)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	checkReview(t, "Delete()", got, rv)
+
+	if _, err := s.FindOne(ctx, rv.ID); !errors.Is(err, review.ErrNotFound) {
+		t.Errorf("FindOne() after Delete() error = %v, want %v", err, review.ErrNotFound)
+	}
+
+	// The author may review the product again.
+	if _, err := s.Create(ctx, testReview("1", "author")); err != nil {
+		t.Errorf("Create() after Delete() error = %v", err)
+	}
+}
+
+func testReviewDeleteNotFound(t *testing.T, s review.Storage) {
+	for _, id := range notFoundIDs {
+		_, err := s.Delete(context.Background(), id)
+		if !errors.Is(err, review.ErrNotFound) {
+			t.Errorf("Delete(%q) error = %v, want %v", id, err, review.ErrNotFound)
+		}
+	}
+}
+
+func testReviewSummarize(t *testing.T, s review.Storage) {
+	ctx := context.Background()
+
+	got, err := s.Summarize(ctx, "1")
+	if err != nil {
+		t.Fatalf("Summarize() error = %v", err)
+	}
+	if want := (review.Summary{}); got != want {
+		t.Errorf("Summarize() of unreviewed product got = %+v, want %+v", got, want)
+	}
+
+	for i, author := range []string{"a", "b", "c"} {
+		rv := testReview("1", author)
+		rv.Rating = int64(i + 3)
+		mustCreateReview(t, s, rv)
+	}
+	mustCreateReview(t, s, testReview("2", "a"))
+
+	got, err = s.Summarize(ctx, "1")
+	if err != nil {
+		t.Fatalf("Summarize() error = %v", err)
+	}
+	if want := (review.Summary{Count: 3, Sum: 12}); got != want {
+		t.Errorf("Summarize() got = %+v, want %+v", got, want)
+	}
+}
+
+// checkReview compares the reviews, times are compared with Equal as storages
+// may return them in another location.
+func checkReview(t *testing.T, call string, got *review.Review, want *review.Review) {
+	t.Helper()
+
+	if got == nil {
+		t.Fatalf("%s got nil review", call)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Errorf("%s created at = %v, want %v", call, got.CreatedAt, want.CreatedAt)
+	}
+	if !got.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("%s updated at = %v, want %v", call, got.UpdatedAt, want.UpdatedAt)
+	}
+
+	g, w := *got, *want
+	g.CreatedAt, w.CreatedAt = time.Time{}, time.Time{}
+	g.UpdatedAt, w.UpdatedAt = time.Time{}, time.Time{}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("%s got = %+v, want %+v", call, g, w)
+	}
+}
+
+func mustCreateReview(t *testing.T, s review.Storage, rv review.Review) *review.Review {
+	t.Helper()
+
+	created, err := s.Create(context.Background(), rv)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return created
+}